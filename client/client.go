@@ -0,0 +1,292 @@
+// Package client reads audit data from a running gateway over its HTTP API.
+//
+//	c := client.New("http://localhost:8080")
+//	logs, err := c.ListLogs(ctx, client.ListLogsOptions{Method: "eth_call", Limit: 100})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Aliases of the gateway API types
+type (
+	AuditLog               = types.AuditLog
+	AuditLogsResponse      = types.AuditLogsResponse
+	AuditLogsSinceResponse = types.AuditLogsSinceResponse
+	BodyPreview            = types.BodyPreview
+	Stats                  = types.Stats
+)
+
+// Client calls the audit endpoints of a gateway
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	adminToken string
+	apiKey     string
+}
+
+// Option customizes a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the client used for all calls (default 30s timeout)
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAdminToken sends token as a bearer token on audit reads
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// WithAPIKey sends key in X-API-Key on replayed calls
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// New creates a client for the gateway at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the gateway answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gateway returned %d: %s", e.StatusCode, e.Body)
+}
+
+// ListLogsOptions filters ListLogs; zero values use the gateway defaults
+type ListLogsOptions struct {
+	Limit    int
+	Offset   int
+	Method   string
+	BodyHash string            // Canonical request body hash, see types.BodyHash
+	Tags     map[string]string // Tag name -> exact value
+
+	Preview      bool // Return body snippets in AuditLog.Preview instead of the bodies; GetLog fetches them in full
+	PreviewBytes int  // Snippet length, 0 for the gateway default
+}
+
+// ListLogs returns a page of combined audit logs, newest first
+func (c *Client) ListLogs(ctx context.Context, opts ListLogsOptions) (*AuditLogsResponse, error) {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Method != "" {
+		query.Set("method", opts.Method)
+	}
+	if opts.BodyHash != "" {
+		query.Set("body_hash", opts.BodyHash)
+	}
+	for name, value := range opts.Tags {
+		query.Set("tag."+name, value)
+	}
+	if opts.Preview {
+		query.Set("preview", "true")
+		if opts.PreviewBytes > 0 {
+			query.Set("preview_bytes", strconv.Itoa(opts.PreviewBytes))
+		}
+	}
+
+	var response AuditLogsResponse
+	if err := c.get(ctx, "/audit/logs", query, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetLog returns the audit log of a single request
+func (c *Client) GetLog(ctx context.Context, requestID string) (*AuditLog, error) {
+	var auditLog AuditLog
+	if err := c.get(ctx, "/audit/logs/"+url.PathEscape(requestID), nil, &auditLog); err != nil {
+		return nil, err
+	}
+	return &auditLog, nil
+}
+
+// Stats returns the gateway's audit statistics
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	if err := c.get(ctx, "/audit/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Since returns one page of logs recorded after cursor. An empty cursor starts
+// at since, or at the beginning of the audit trail if since is zero.
+func (c *Client) Since(ctx context.Context, cursor string, since time.Time, limit int) (*AuditLogsSinceResponse, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	} else if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var response AuditLogsSinceResponse
+	if err := c.get(ctx, "/audit/logs/since", query, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Stream calls fn for every log recorded after since, then keeps polling every
+// interval for new logs and late responses until ctx is done or fn returns an error.
+func (c *Client) Stream(ctx context.Context, since time.Time, interval time.Duration, fn func(AuditLog) error) error {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	cursor := ""
+	for {
+		page, err := c.Since(ctx, cursor, since, 0)
+		if err != nil {
+			return err
+		}
+		for _, logs := range [][]AuditLog{page.Logs, page.Updates} {
+			for _, auditLog := range logs {
+				if err := fn(auditLog); err != nil {
+					return err
+				}
+			}
+		}
+		cursor = page.NextCursor
+		if page.HasMore {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Replay sends the recorded request body of requestID through the gateway
+// again at path (e.g. "/rpc") and returns the raw response. Requests audited
+// with payload redaction cannot be replayed.
+func (c *Client) Replay(ctx context.Context, requestID, path string) ([]byte, error) {
+	auditLog, err := c.GetLog(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	body := []byte(auditLog.Request)
+	if auditLog.BodyEncoding == types.BodyEncodingBase64 {
+		var encoded string
+		if err := json.Unmarshal(auditLog.Request, &encoded); err != nil {
+			return nil, fmt.Errorf("failed to decode request body: %w", err)
+		}
+		if body, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("failed to decode request body: %w", err)
+		}
+	}
+
+	contentType := auditLog.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	return c.do(req)
+}
+
+// VerifyResponse reports whether resp, whose body was read into body, carries a
+// valid gateway signature for key. On success it returns the request ID to look
+// up the call with GetLog.
+func VerifyResponse(key []byte, resp *http.Response, body []byte) (string, bool) {
+	requestID := resp.Header.Get(types.RequestIDHeader)
+	signature := resp.Header.Get(types.SignatureHeader)
+	if requestID == "" || !types.VerifyResponseSignature(key, requestID, body, signature) {
+		return "", false
+	}
+	return requestID, true
+}
+
+// get decodes the JSON response of a GET request into v
+func (c *Client) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+
+	body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends req and returns the response body, turning non-2xx answers into an APIError
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
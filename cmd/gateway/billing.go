@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/billing"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/database"
+)
+
+// runBilling writes the invoices of a month, e.g. from a cron job on the 1st:
+//
+//	gateway billing -db audit.db -config routes.json -month 2026-09 -format csv -out invoices.csv
+//
+// Prices come from the billing section of the config; without it invoices list usage only.
+func runBilling(args []string) {
+	fs := flag.NewFlagSet("billing", flag.ExitOnError)
+	dbPath := fs.String("db", "audit.db", "Path to SQLite database file, the -cold-db file of a hot/cold setup")
+	configPath := fs.String("config", "", "Path to JSON config file with the billing rates (optional)")
+	month := fs.String("month", "", "Month to invoice as YYYY-MM in UTC (default previous month)")
+	apiKey := fs.String("api-key", "", "Only invoice this API key name (default all keys with usage)")
+	format := fs.String("format", "json", "Output format: json or csv")
+	outPath := fs.String("out", "", "Write invoices to this file (default stdout)")
+	fs.Parse(args)
+
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("Invalid -format %q, use json or csv", *format)
+	}
+
+	var billingConfig *config.Billing
+	if *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		billingConfig = cfg.Billing
+	}
+
+	now := time.Now()
+	if *month == "" {
+		// Invoices are usually run once the month is over
+		*month = now.UTC().AddDate(0, 0, -now.UTC().Day()).Format(billing.PeriodLayout)
+	}
+	start, end, err := billing.Month(*month, now)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	usage, err := db.GetBillingUsage(*apiKey, start, end)
+	if err != nil {
+		log.Fatalf("Failed to compute billing usage: %v", err)
+	}
+	invoices := billing.Invoices(billingConfig, usage, start, end, now)
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *outPath, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if *format == "csv" {
+		err = billing.WriteCSV(out, invoices)
+	} else {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(invoices)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write invoices: %v", err)
+	}
+	log.Printf("Wrote %d invoices for %s", len(invoices), *month)
+}
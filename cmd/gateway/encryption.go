@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/database"
+)
+
+// loadPayloadCipher builds the audit payload cipher from a key file (e.g. a secret
+// mounted from a KMS) or the GOLF_ENCRYPTION_KEY environment variable.
+// GOLF_ENCRYPTION_OLD_KEYS holds comma-separated retired keys still needed to read
+// older rows. It returns nil when no key is configured.
+func loadPayloadCipher(keyFile string) (*database.PayloadCipher, error) {
+	keyStr := os.Getenv("GOLF_ENCRYPTION_KEY")
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		keyStr = string(data)
+	}
+	if strings.TrimSpace(keyStr) == "" {
+		return nil, nil
+	}
+
+	key, err := database.ParseKey(keyStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldKeys [][]byte
+	for _, s := range strings.Split(os.Getenv("GOLF_ENCRYPTION_OLD_KEYS"), ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		oldKey, err := database.ParseKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in GOLF_ENCRYPTION_OLD_KEYS: %w", err)
+		}
+		oldKeys = append(oldKeys, oldKey)
+	}
+
+	return database.NewPayloadCipher(key, oldKeys...)
+}
+
+// runRotateKey re-encrypts all audit payloads with a new key:
+//
+//	gateway rotate-key -db audit.db -new-key-file new.key
+//
+// The current key is taken from -encryption-key-file or GOLF_ENCRYPTION_KEY.
+func runRotateKey(args []string) {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	dbPath := fs.String("db", "audit.db", "Path to SQLite database file")
+	keyFile := fs.String("encryption-key-file", "", "File containing the current encryption key (default $GOLF_ENCRYPTION_KEY)")
+	newKeyFile := fs.String("new-key-file", "", "File containing the new encryption key (required)")
+	fs.Parse(args)
+
+	if *newKeyFile == "" {
+		log.Fatal("-new-key-file is required")
+	}
+
+	current, err := loadPayloadCipher(*keyFile)
+	if err != nil {
+		log.Fatalf("Failed to load current encryption key: %v", err)
+	}
+
+	data, err := os.ReadFile(*newKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to read new key file: %v", err)
+	}
+	newKey, err := database.ParseKey(string(data))
+	if err != nil {
+		log.Fatalf("Invalid new key: %v", err)
+	}
+	next, err := database.NewPayloadCipher(newKey)
+	if err != nil {
+		log.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if current != nil {
+		db.SetEncryption(current)
+	}
+
+	count, err := db.RotateEncryption(next)
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+
+	log.Printf("Re-encrypted %d rows; configure the new key as GOLF_ENCRYPTION_KEY", count)
+}
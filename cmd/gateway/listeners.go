@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/gateway"
+)
+
+// defaultListeners are used when the config file defines none: everything on port,
+// or the management endpoints split off onto managementAddr
+func defaultListeners(port, managementAddr string) []config.Listener {
+	if managementAddr == "" {
+		return []config.Listener{{Name: "main", Addr: ":" + port, Serve: config.ServeAll}}
+	}
+	return []config.Listener{
+		{Name: "proxy", Addr: ":" + port, Serve: config.ServeProxy},
+		{Name: "management", Addr: managementAddr, Serve: config.ServeManagement},
+	}
+}
+
+// startListeners starts one HTTP server per listener. Listeners with a socket in
+// activated, passed by systemd socket activation, serve it instead of binding.
+func startListeners(listeners []config.Listener, activated map[string]net.Listener, gw *gateway.Gateway) ([]*http.Server, error) {
+	servers := make([]*http.Server, 0, len(listeners))
+	for _, l := range listeners {
+		server := &http.Server{
+			Addr:         l.Addr,
+			Handler:      loggingMiddleware(gw, gw.Router(l.Serve)),
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+
+		if l.TLSClientCAFile != "" {
+			pem, err := os.ReadFile(l.TLSClientCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("listener %s: failed to read client CA: %w", l.Name, err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("listener %s: no certificates found in %s", l.Name, l.TLSClientCAFile)
+			}
+			server.TLSConfig = &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+		}
+
+		go func(l config.Listener, ln net.Listener) {
+			var err error
+			switch {
+			case ln != nil && l.TLSCertFile != "":
+				log.Printf("Listening on https://%s (%s, socket-activated)", ln.Addr(), l.Serve)
+				err = server.ServeTLS(ln, l.TLSCertFile, l.TLSKeyFile)
+			case ln != nil:
+				log.Printf("Listening on http://%s (%s, socket-activated)", ln.Addr(), l.Serve)
+				err = server.Serve(ln)
+			case l.TLSCertFile != "":
+				log.Printf("Listening on https://%s (%s)", l.Addr, l.Serve)
+				err = server.ListenAndServeTLS(l.TLSCertFile, l.TLSKeyFile)
+			default:
+				log.Printf("Listening on http://%s (%s)", l.Addr, l.Serve)
+				err = server.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Listener %s failed: %v", l.Name, err)
+			}
+		}(l, activated[l.Name])
+
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
@@ -1,83 +1,475 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/niki4smirn/golf/internal/accesslog"
+	"github.com/niki4smirn/golf/internal/capture"
+	"github.com/niki4smirn/golf/internal/config"
 	"github.com/niki4smirn/golf/internal/database"
 	"github.com/niki4smirn/golf/internal/gateway"
+	"github.com/niki4smirn/golf/internal/oidc"
+	"github.com/niki4smirn/golf/internal/otlp"
+	"github.com/niki4smirn/golf/internal/redis"
+	"github.com/niki4smirn/golf/internal/requestid"
+	"github.com/niki4smirn/golf/internal/types"
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rotate-key":
+			runRotateKey(os.Args[2:])
+			return
+		case "sync-tinybird":
+			runSyncTinybird(os.Args[2:])
+			return
+		case "redrive-tinybird":
+			runRedriveTinybird(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "billing":
+			runBilling(os.Args[2:])
+			return
+		}
+	}
+
 	// Command line flags
 	var (
 		port          = flag.String("port", "8080", "Port to run the server on")
-		dbPath        = flag.String("db", "audit.db", "Path to SQLite database file")
+		dbPath        = flag.String("db", "audit.db", "Path to SQLite database file, or \"none\" to run Tinybird-only")
 		targetURL     = flag.String("target", "", "Target URL for JSON-RPC forwarding (required)")
-		tinybirdToken = flag.String("tinybird-token", "", "Tinybird authentication token (optional)")
+		tinybirdToken = flag.String("tinybird-token", "", "Tinybird authentication token, or a secret reference such as vault:secret/data/golf#tinybird_token (optional)")
+		tinybirdURL   = flag.String("tinybird-url", "", "Tinybird API host (default EU region)")
+		tinybirdRow   = flag.Int("tinybird-max-row-bytes", database.DefaultTinybirdMaxRowBytes, "Compress, then truncate, the payload columns of Tinybird events encoding larger than this, flagging them oversized (0 disables)")
+		configPath    = flag.String("config", "", "Path to JSON config file with route definitions (optional)")
+		keyFile       = flag.String("encryption-key-file", "", "File with the AES-256 key for audit payload encryption (default $GOLF_ENCRYPTION_KEY)")
+		signingKey    = flag.String("signing-key-file", "", "File with the HMAC key signing proxied responses in X-Gateway-Signature (default $GOLF_SIGNING_KEY)")
+		anonymizeKey  = flag.String("anonymize-key-file", "", "File with the key for anonymized exports; keep it to get the same pseudonyms across restarts (default $GOLF_ANONYMIZE_KEY)")
+		spoolPath     = flag.String("spool-file", "", "Append-only file buffering audit events while the database is unavailable (default <db>.spool, \"none\" for memory only)")
+		spoolSize     = flag.Int("spool-size", 10000, "Maximum number of audit events buffered in memory while the database is unavailable")
+		durability    = flag.String("durability", types.DurabilityStandard, "Audit durability of routes without their own: strict (request fsynced before forwarding, calls refused otherwise), standard or relaxed (written in batches off the proxy path)")
+		maintInterval = flag.Duration("maintenance-interval", time.Hour, "How often to checkpoint the WAL and vacuum the database (0 disables)")
+		integrity     = flag.Bool("integrity-check", true, "Run PRAGMA integrity_check during database maintenance")
+		orphanGrace   = flag.Duration("orphan-grace", 15*time.Minute, "Record requests still without a response after this long as unresolved, raised above the longest route timeout (0 disables)")
+		compressMin   = flag.Int("compress-min-bytes", 0, "Gzip request, response, and header payloads of at least this many bytes in SQLite (0 disables)")
+		rotate        = flag.String("rotate", "", "Start a new SQLite file every period: hourly or daily (default off)")
+		rotateSize    = flag.Int64("rotate-size-mb", 0, "Start a new SQLite file once the active one reaches this size in MB (0 disables)")
+		coldPath      = flag.String("cold-db", "", "Also write every call without bodies or headers to this SQLite file, keeping -db small (optional)")
+		hotRetention  = flag.Duration("hot-retention", 7*24*time.Hour, "With -cold-db, remove calls older than this from -db (0 keeps them)")
+		maxReqBytes   = flag.Int64("max-request-bytes", 0, "Refuse request bodies larger than this many bytes with 413, also when sent chunked; routes may set max_request_bytes (0 disables)")
+		maxRespAudit  = flag.Int64("max-response-capture-bytes", 0, "Keep only this many bytes of larger response bodies in the audit trail, with the SHA-256 and size of the whole body, streaming them to the client; routes may set max_response_capture_bytes (0 keeps bodies whole)")
+		redisURL      = flag.String("redis-url", os.Getenv("GOLF_REDIS_URL"), "Share rate limits and idempotency keys between replicas through Redis, e.g. redis://:password@host:6379/0; falls back to local state while Redis is down (default $GOLF_REDIS_URL)")
+		redisPrefix   = flag.String("redis-prefix", "golf:", "Prefix of the keys the gateway keeps in Redis")
+		captureFile   = flag.String("capture-file", "", "Also append every call to this capture file for HTTP tooling, e.g. calls.har or calls.ndjson (optional)")
+		captureFormat = flag.String("capture-format", "", "Format of -capture-file: har or ndjson (default from the file extension, ndjson unless .har)")
+		captureSize   = flag.Int64("capture-size-mb", 100, "Rotate -capture-file once it reaches this size in MB (0 disables)")
+		captureKeep   = flag.Int("capture-keep", 10, "Rotated capture files kept next to -capture-file (0 keeps all)")
+		accessLogPath = flag.String("access-log", "", "Also write every proxied request to this file, or - for stdout, in the Common or Combined Log Format (optional)")
+		accessFormat  = flag.String("access-log-format", accesslog.FormatCombined, "Format of -access-log: common or combined")
+		mgmtAddr      = flag.String("management-addr", "", "Serve /audit, /admin and the dashboard on this address instead of -port, e.g. 127.0.0.1:9090")
+		adminToken    = flag.String("admin-token", os.Getenv("GOLF_ADMIN_TOKEN"), "Bearer token enabling the /admin API (default $GOLF_ADMIN_TOKEN)")
+		env           = flag.String("env", os.Getenv("GOLF_ENV"), "Deployment environment recorded on every audit row (default $GOLF_ENV)")
+		serviceName   = flag.String("service-name", os.Getenv("GOLF_SERVICE_NAME"), "Service name recorded on every audit row (default $GOLF_SERVICE_NAME)")
+		version       = flag.String("version", os.Getenv("GOLF_VERSION"), "Release version recorded on every audit row (default $GOLF_VERSION)")
+		logLevel      = flag.String("log-level", types.LogLevelInfo, "Log verbosity: quiet, info or debug; changeable at runtime through /admin/debug")
+		debugCapture  = flag.Bool("debug-capture", false, "Store full headers and upstream timing of every call in the audit response debug column")
+		statsOnly     = flag.Bool("stats-only", false, "Aggregation-only mode: serve stats and time series publicly, raw audit endpoints only with -admin-token")
+		minBucket     = flag.Int("stats-min-bucket", gateway.DefaultMinBucketSize, "In -stats-only mode, suppress stats buckets counting fewer calls than this")
+		rbac          = flag.Bool("rbac", false, "Require a viewer, operator or admin role, given to API keys with \"role\", for every management endpoint")
+		failFast      = flag.Bool("fail-fast", false, "Exit as soon as the database cannot be opened instead of retrying with backoff; targets are still only checked for readiness")
+		startupTime   = flag.Duration("startup-timeout", 5*time.Minute, "How long to retry opening the database on startup before giving up (0 retries forever)")
+		labels        = labelFlag{}
 	)
+	flag.Var(labels, "label", "Extra k=v label recorded on every audit row, repeatable (default $GOLF_LABELS, comma-separated)")
 	flag.Parse()
 
-	// Initialize SQLite database (primary storage)
-	db, err := database.New(*dbPath)
+	if len(labels) == 0 {
+		for _, pair := range strings.Split(os.Getenv("GOLF_LABELS"), ",") {
+			if pair != "" {
+				if err := labels.Set(pair); err != nil {
+					log.Fatalf("Invalid GOLF_LABELS: %v", err)
+				}
+			}
+		}
+	}
+
+	// Load route configuration if provided
+	cfg := &config.Config{}
+	if *configPath != "" {
+		loaded, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		cfg = loaded
+	}
+
+	// Secret references in the config and -tinybird-token are resolved now and
+	// kept up to date while running
+	secretsManager, err := newSecretsManager(cfg.Secrets)
 	if err != nil {
-		log.Fatalf("Failed to initialize SQLite database: %v", err)
+		log.Fatalf("Failed to set up secrets: %v", err)
+	}
+	if err := watchRouteSecrets(secretsManager, cfg.Routes); err != nil {
+		log.Fatalf("Failed to resolve upstream credentials: %v", err)
+	}
+	if o := cfg.OIDC; o != nil && o.ClientSecretRef != "" {
+		if err := secretsManager.Watch(o.ClientSecretRef, o.SetClientSecret); err != nil {
+			log.Fatalf("Failed to resolve OIDC client secret: %v", err)
+		}
 	}
-	defer db.Close()
 
 	// Initialize Tinybird if token provided
 	var tinybirdDB *database.TinybirdDatabase
 	if *tinybirdToken != "" {
 		log.Printf("Initializing Tinybird integration")
 		tinybirdDB = database.NewTinybirdDatabase(*tinybirdToken)
+		if secretsManager.IsRef(*tinybirdToken) {
+			tinybirdDB.SetToken("")
+			if err := secretsManager.Watch(*tinybirdToken, tinybirdDB.SetToken); err != nil {
+				log.Fatalf("Failed to resolve Tinybird token: %v", err)
+			}
+		}
+		if *tinybirdURL != "" {
+			tinybirdDB.SetBaseURL(*tinybirdURL)
+		}
+		tinybirdDB.SetMaxRowBytes(*tinybirdRow)
+	}
+
+	var db *database.Database
+	var gw *gateway.Gateway
+	var auditStore database.AuditWriter
+	var payloadCipher *database.PayloadCipher
+	if *dbPath == "none" {
+		// Tinybird-only: audit reads go through the Tinybird Query API
+		if tinybirdDB == nil {
+			log.Fatal("-db none requires -tinybird-token")
+		}
+		log.Printf("SQLite disabled, storing and reading audit data in Tinybird")
+		gw = gateway.NewWithStore(tinybirdDB, *targetURL)
+		auditStore = tinybirdDB
+	} else {
+		// Initialize SQLite database (primary storage)
+		var rotator *database.Rotator
+		var hotCold *database.DualDatabase
+		rotating := *rotate != "" || *rotateSize > 0
+		if rotating && *coldPath != "" {
+			log.Fatal("-cold-db cannot be combined with -rotate or -rotate-size-mb")
+		}
+		if rotating && cfg.CountsUsage() {
+			// Usage is counted in the active file only, rotated files would reset quotas and invoices
+			log.Fatal("quotas and billing cannot be combined with -rotate or -rotate-size-mb")
+		}
+		// The database volume may attach after the gateway starts, e.g. in Kubernetes
+		dbStep, err := waitFor("database", *failFast, *startupTime, nil, func() error {
+			var err error
+			switch {
+			case rotating:
+				// Files are named after -db, e.g. audit-2024-06-01.db, with audit-current.db linking the active one
+				db, rotator, err = database.OpenRotating(*dbPath, database.RotationPolicy{Interval: *rotate, MaxBytes: *rotateSize << 20})
+			case *coldPath != "":
+				// Recent calls with payloads in -db for the dashboard, the whole history as metadata in -cold-db
+				if hotCold, err = database.NewHotColdDatabase(*dbPath, *coldPath); err == nil {
+					db = hotCold.Primary()
+				}
+			default:
+				db, err = database.New(*dbPath)
+			}
+			return err
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize SQLite database: %v", err)
+		}
+		if hotCold != nil {
+			defer hotCold.Close()
+		} else {
+			defer db.Close()
+		}
+
+		// Enable payload encryption at rest if a key is configured
+		payloadCipher, err = loadPayloadCipher(*keyFile)
+		if err != nil {
+			log.Fatalf("Failed to load encryption key: %v", err)
+		}
+		if payloadCipher != nil {
+			log.Printf("Audit payload encryption enabled")
+			db.SetEncryption(payloadCipher)
+		}
+		if *compressMin > 0 {
+			log.Printf("Compressing audit payloads of %d bytes or more", *compressMin)
+			db.SetCompression(*compressMin)
+		}
+		if pools := cfg.Database; pools != nil {
+			db.SetPool(databasePool(pools.Pool))
+			if pools.ReadDSN != "" {
+				if rotator != nil {
+					log.Fatal("database.read_dsn cannot be combined with -rotate or -rotate-size-mb")
+				}
+				if err := db.OpenReader(pools.ReadDSN, databasePool(*pools.ReadPool)); err != nil {
+					log.Fatalf("Failed to open read database: %v", err)
+				}
+				log.Printf("Serving stats and dashboard queries from %s", pools.ReadDSN)
+			}
+		}
+
+		gw = gateway.New(db, *targetURL)
+		auditStore = db
+		gw.SetStartupStep(dbStep)
+
+		if rotator != nil {
+			rotator.Start(time.Minute)
+			defer rotator.Stop()
+			gw.SetRotator(rotator)
+		}
+		if hotCold != nil {
+			log.Printf("Writing call metadata to cold database %s", *coldPath)
+			auditStore = hotCold
+			// Quota periods and invoices reach further back than -hot-retention
+			gw.SetUsageStore(hotCold.Cold())
+			if *hotRetention > 0 {
+				pruner := database.NewPruner(db, *hotRetention)
+				pruner.Start(time.Hour)
+				defer pruner.Stop()
+			}
+		}
+	}
+
+	// Configure gateway
+	gw.SetRoutes(cfg.Routes)
+	if err := gw.LoadScripts(); err != nil {
+		log.Fatalf("Failed to load scripts: %v", err)
+	}
+	if err := watchAPIKeys(secretsManager, gw, cfg.APIKeys); err != nil {
+		log.Fatalf("Failed to resolve API keys: %v", err)
+	}
+	secretsManager.Start(15 * time.Second)
+	defer secretsManager.Stop()
+	gw.SetTenantQuotas(cfg.TenantQuotas)
+	gw.SetMaxRequestBytes(*maxReqBytes)
+	gw.SetMaxResponseCapture(*maxRespAudit)
+	if *redisURL != "" {
+		client, err := redis.New(*redisURL)
+		if err != nil {
+			log.Fatalf("Invalid -redis-url: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if _, err := client.String(ctx, "PING"); err != nil {
+			log.Printf("Redis %s unavailable, starting with local rate limits and idempotency keys: %v", client.Addr(), err)
+		} else {
+			log.Printf("Sharing rate limits and idempotency keys through Redis %s", client.Addr())
+		}
+		cancel()
+		gw.SetRedis(client, *redisPrefix)
+		defer client.Close()
+	}
+	gw.SetExtractions(cfg.Extractions)
+	gw.SetResponseHeaderFilter(cfg.ResponseHeaders)
+	gw.SetTargetOverrides(cfg.TargetOverrides)
+	gw.SetIndexedHeaders(cfg.IndexedHeaders)
+	gw.SetExtensions(cfg.Extensions)
+	gw.SetSLOs(cfg.SLOs)
+	gw.SetWebhooks(cfg.Webhooks)
+	gw.SetPII(cfg.PII)
+	gw.SetStatusPage(cfg.Status)
+	gw.SetCorrelation(cfg.Correlation)
+	gw.SetBilling(cfg.Billing)
+	if ids, err := requestid.New(cfg.RequestIDs); err != nil {
+		log.Fatalf("Invalid request_ids: %v", err)
+	} else {
+		gw.SetRequestIDGenerator(ids)
+	}
+	if key, err := loadAnonymizeKey(*anonymizeKey); err != nil {
+		log.Fatalf("Failed to load anonymization key: %v", err)
+	} else {
+		gw.SetAnonymization(cfg.Anonymize, key)
+	}
+	gw.SetAdminToken(*adminToken)
+	if err := gw.SetDebugSettings(types.DebugSettings{LogLevel: *logLevel, Capture: *debugCapture}); err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+	if *rbac {
+		log.Printf("Management API requires a role")
+		gw.SetRBAC(true)
+	}
+	if *statsOnly {
+		log.Printf("Stats-only mode, suppressing buckets of fewer than %d calls", *minBucket)
+		gw.SetStatsOnly(*minBucket)
+	}
+	gw.SetDeployment(types.Deployment{Env: *env, Service: *serviceName, Version: *version, Labels: labels})
+	if key, err := loadSigningKey(*signingKey); err != nil {
+		log.Fatalf("Failed to load signing key: %v", err)
+	} else if key != nil {
+		log.Printf("Response signing enabled")
+		gw.SetSigningKey(key)
+	}
+	if err := gw.SetResponseSchemas(cfg.ResponseSchemas); err != nil {
+		log.Fatalf("Failed to load response schemas: %v", err)
+	}
+	if err := gw.LoadOpenRPC(cfg.OpenRPC); err != nil {
+		log.Printf("OpenRPC catalog disabled: %v", err)
+	}
+	if err := gw.LoadClients(); err != nil {
+		log.Fatalf("Failed to load clients: %v", err)
+	}
+
+	// Buffer audit events when the store is unavailable and replay them on recovery
+	spoolFile := *spoolPath
+	switch {
+	case spoolFile == "none":
+		spoolFile = ""
+	case spoolFile == "" && db == nil:
+		spoolFile = "tinybird.spool"
+	case spoolFile == "":
+		spoolFile = *dbPath + ".spool"
+	}
+	spool, err := database.NewSpool(auditStore, spoolFile, *spoolSize)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit spool: %v", err)
+	}
+	if payloadCipher != nil {
+		// The spool file holds the same payloads as the store, so seal them with its key
+		spool.SetEncryption(payloadCipher)
+	}
+	spool.Start(5 * time.Second)
+	defer spool.Stop()
+	gw.SetSpool(spool)
+
+	// Commit the requests of strict routes before forwarding and batch the writes of relaxed ones
+	if err := gw.SetDurability(*durability); err != nil {
+		log.Fatalf("Invalid -durability: %v", err)
+	}
+	defer gw.StopRelaxedWrites()
+
+	// Keep the WAL and free pages in check on long-running gateways
+	if db != nil && *maintInterval > 0 {
+		maintenance := database.NewMaintenance(db, *integrity)
+		maintenance.Start(*maintInterval)
+		defer maintenance.Stop()
+		gw.SetMaintenance(maintenance)
 	}
 
-	// Create gateway
-	gw := gateway.New(db, *targetURL)
+	// Watch latency objectives and alert webhooks on burn-rate breaches
+	gw.StartSLOMonitor(time.Minute)
+	defer gw.StopSLOMonitor()
+
+	// Close out requests a crash or restart left without a response
+	gw.StartOrphanResolver(*orphanGrace, time.Minute)
+	defer gw.StopOrphanResolver()
 
 	// Add Tinybird logging to gateway if available
-	if tinybirdDB != nil {
+	if tinybirdDB != nil && db != nil {
 		gw.SetTinybirdLogger(tinybirdDB)
 	}
 
-	// Set up router
-	router := gw.SetupRoutes()
+	// Mirror every call to a capture file
+	if *captureFile != "" {
+		writer, err := capture.Open(*captureFile, capture.Options{
+			Format:   *captureFormat,
+			MaxBytes: *captureSize * 1024 * 1024,
+			Keep:     *captureKeep,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open capture file: %v", err)
+		}
+		defer writer.Close()
+		gw.SetCapture(writer)
+		log.Printf("Capturing calls to %s as %s", *captureFile, writer.Format())
+	}
+
+	// Write proxied requests to an access log for log ingestion pipelines
+	if *accessLogPath != "" {
+		writer, err := accesslog.Open(*accessLogPath, *accessFormat)
+		if err != nil {
+			log.Fatalf("Failed to open access log: %v", err)
+		}
+		defer writer.Close()
+		gw.SetAccessLog(writer)
+		log.Printf("Writing access log to %s in %s format", *accessLogPath, writer.Format())
+	}
 
-	// Configure server
-	server := &http.Server{
-		Addr:         ":" + *port,
-		Handler:      loggingMiddleware(router),
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	// Export every call to an OpenTelemetry collector
+	if cfg.OTLP != nil {
+		exporter := otlp.NewExporter(*cfg.OTLP)
+		defer exporter.Close()
+		gw.SetOTLPExporter(exporter)
+		log.Printf("Exporting calls to %s as OTLP %s", cfg.OTLP.Endpoint, cfg.OTLP.Signal)
 	}
 
-	// Validate target URL is provided
-	if *targetURL == "" {
+	// Sign operators in to the dashboard and management API with the corporate SSO
+	if cfg.OIDC != nil {
+		gw.SetAuthProvider(oidc.New(cfg.OIDC), cfg.OIDC.SignIn)
+		log.Printf("Signing users in with %s at /auth/login", cfg.OIDC.Issuer)
+	}
+
+	// Validate target URL is provided (routes from the config file carry their own targets)
+	if *targetURL == "" && len(cfg.Routes) == 0 {
 		log.Fatal("Target URL is required. Use -target flag to specify the JSON-RPC server URL.")
 	}
 
-	// Start server in goroutine
-	go func() {
-		log.Printf("Starting JSON-RPC Gateway on port %s", *port)
-		log.Printf("Database: %s", *dbPath)
-		log.Printf("Forwarding to: %s", *targetURL)
-		log.Printf("Endpoints:")
-		log.Printf("  POST /rpc           - JSON-RPC proxy")
-		log.Printf("  GET  /audit/logs    - View audit logs")
-		log.Printf("  GET  /audit/stats   - View statistics")
-		log.Printf("  GET  /health        - Health check")
-		log.Printf("  GET  /              - Dashboard")
+	// Proxy and management endpoints may be split across listeners
+	listeners := cfg.Listeners
+	if len(listeners) == 0 {
+		listeners = defaultListeners(*port, *mgmtAddr)
+	}
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+	log.Printf("Starting JSON-RPC Gateway")
+	log.Printf("Database: %s", *dbPath)
+	if len(cfg.Routes) > 0 {
+		for _, route := range cfg.Routes {
+			log.Printf("Route %s: %s -> %s%s", route.Name, route.Path, route.Target, route.UpstreamPath)
 		}
-	}()
+	} else {
+		log.Printf("Forwarding to: %s", *targetURL)
+	}
+	log.Printf("Endpoints:")
+	log.Printf("  POST /rpc           - JSON-RPC proxy")
+	log.Printf("  GET  /audit/logs    - View audit logs")
+	log.Printf("  GET  /audit/stats   - View statistics")
+	log.Printf("  GET  /audit/usage   - View quota usage")
+	log.Printf("  GET  /audit/slo     - View SLO compliance")
+	log.Printf("  GET  /health        - Health check")
+	log.Printf("  GET  /health/ready - Readiness probe, 503 until startup checks pass")
+	log.Printf("  GET  /status        - Public status page")
+	if *adminToken != "" {
+		log.Printf("  *    /admin/clients - Manage API clients")
+		log.Printf("  *    /admin/debug   - Log level and debug capture")
+		log.Printf("  GET  /admin/actions - Privileged management calls")
+	}
+	log.Printf("  GET  /              - Dashboard")
+
+	// Readiness waits until every target accepts connections
+	checkTargets(gw)
+
+	sockets, socketNames, err := activatedListeners()
+	if err != nil {
+		log.Fatalf("Failed to use activated sockets: %v", err)
+	}
+	activated, err := assignActivated(listeners, sockets, socketNames)
+	if err != nil {
+		log.Fatalf("Failed to use activated sockets: %v", err)
+	}
+	servers, err := startListeners(listeners, activated, gw)
+	if err != nil {
+		log.Fatalf("Failed to start listeners: %v", err)
+	}
+
+	if err := sdNotify("READY=1\nSTATUS=" + gw.Health().Status); err != nil {
+		log.Printf("Failed to signal readiness: %v", err)
+	}
+	stopWatchdog := startWatchdog(gw)
 
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
@@ -85,17 +477,48 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
-	if err := server.Close(); err != nil {
-		log.Printf("Error shutting down server: %v", err)
+	stopWatchdog()
+	sdNotify("STOPPING=1")
+	// Let in-flight calls finish so restarts don't fail them
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down server: %v", err)
+			server.Close()
+		}
 	}
 	log.Println("Server stopped")
 }
 
-// loggingMiddleware logs HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
+// labelFlag collects repeated -label k=v flags
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	pairs := make([]string, 0, len(l))
+	for k, v := range l {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("label %q must be k=v", value)
+	}
+	l[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	return nil
+}
+
+// loggingMiddleware logs HTTP requests unless the gateway's log level is quiet
+func loggingMiddleware(gw *gateway.Gateway, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.RequestURI, time.Since(start))
+		if gw.LogLevel() != types.LogLevelQuiet {
+			log.Printf("%s %s %s", r.Method, r.RequestURI, time.Since(start))
+		}
 	})
 }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// migrationTarget receives batches of audit rows, skipping rows already present
+type migrationTarget interface {
+	writeRequests(reqs []types.AuditRequest) (written int, err error)
+	writeResponses(resps []types.AuditResponse) (written int, err error)
+	GetStats() (*types.Stats, error)
+	Close() error
+}
+
+// runMigrate copies every audit row of a SQLite database into another backend:
+//
+//	gateway migrate --from sqlite:audit.db --to sqlite:new.db
+//	gateway migrate --from sqlite:audit.db --to tinybird:TOKEN
+//
+// Rows are streamed in id order and written in batches; rows whose request_id
+// already exists in the destination are skipped, so an interrupted migration
+// can simply be run again. Row counts of both sides are compared at the end.
+// Only SQLite and Tinybird are supported; postgres:// destinations are
+// refused since the gateway has no PostgreSQL store.
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", "sqlite:audit.db", "Source store, sqlite:PATH")
+	to := fs.String("to", "", "Destination store, sqlite:PATH or tinybird:TOKEN (required; PostgreSQL is not supported)")
+	tinybirdURL := fs.String("tinybird-url", "", "Tinybird API host for a tinybird destination (default EU region)")
+	batchSize := fs.Int("batch", 500, "Rows per batch")
+	keyFile := fs.String("encryption-key-file", "", "File containing the payload encryption key, used for both SQLite stores (default $GOLF_ENCRYPTION_KEY)")
+	compressMin := fs.Int("compress-min-bytes", 0, "Gzip payloads of at least this many bytes in a SQLite destination (0 disables)")
+	fs.Parse(args)
+
+	if *to == "" {
+		log.Fatal("-to is required")
+	}
+	if *batchSize <= 0 {
+		log.Fatal("-batch must be positive")
+	}
+
+	payloadCipher, err := loadPayloadCipher(*keyFile)
+	if err != nil {
+		log.Fatalf("Failed to load encryption key: %v", err)
+	}
+
+	kind, location := parseStoreURL(*from)
+	if kind != "sqlite" {
+		log.Fatalf("Unsupported source %q: only sqlite:PATH can be migrated from", *from)
+	}
+	// The destination is opened first so unsupported backends fail before the source is touched
+	target, err := openMigrationTarget(*to, *tinybirdURL, payloadCipher, *compressMin)
+	if err != nil {
+		log.Fatalf("Failed to open destination: %v", err)
+	}
+	defer target.Close()
+
+	source, err := database.New(location)
+	if err != nil {
+		log.Fatalf("Failed to open source database: %v", err)
+	}
+	defer source.Close()
+	if payloadCipher != nil {
+		source.SetEncryption(payloadCipher)
+	}
+
+	sourceStats, err := source.GetStats()
+	if err != nil {
+		log.Fatalf("Failed to count source rows: %v", err)
+	}
+
+	requests, err := migrateTable("audit_requests", sourceStats.TotalRequests, *batchSize, func(afterID int64) (int64, int, int, error) {
+		rows, err := source.GetAuditRequestsAfterID(afterID, *batchSize)
+		if err != nil || len(rows) == 0 {
+			return afterID, 0, 0, err
+		}
+		written, err := target.writeRequests(rows)
+		return rows[len(rows)-1].ID, len(rows), written, err
+	})
+	if err != nil {
+		log.Fatalf("Request migration failed after %d rows: %v", requests, err)
+	}
+
+	responses, err := migrateTable("audit_responses", sourceStats.TotalResponses, *batchSize, func(afterID int64) (int64, int, int, error) {
+		rows, err := source.GetAuditResponsesAfterID(afterID, *batchSize)
+		if err != nil || len(rows) == 0 {
+			return afterID, 0, 0, err
+		}
+		written, err := target.writeResponses(rows)
+		return rows[len(rows)-1].ID, len(rows), written, err
+	})
+	if err != nil {
+		log.Fatalf("Response migration failed after %d rows: %v", responses, err)
+	}
+
+	log.Printf("Migration complete: %d requests and %d responses written", requests, responses)
+
+	// Verify the destination holds at least every source row
+	targetStats, err := target.GetStats()
+	if err != nil {
+		log.Fatalf("Failed to count destination rows: %v", err)
+	}
+	log.Printf("Verification: requests %d -> %d, responses %d -> %d",
+		sourceStats.TotalRequests, targetStats.TotalRequests, sourceStats.TotalResponses, targetStats.TotalResponses)
+	if targetStats.TotalRequests < sourceStats.TotalRequests || targetStats.TotalResponses < sourceStats.TotalResponses {
+		log.Fatal("Verification failed: the destination has fewer rows than the source")
+	}
+}
+
+// migrateTable calls copyBatch until the table is exhausted, logging progress
+// against the expected total. copyBatch returns the last copied id, the rows
+// read and the rows written.
+func migrateTable(table string, total, batchSize int, copyBatch func(afterID int64) (int64, int, int, error)) (int, error) {
+	var afterID int64
+	read, written := 0, 0
+	for {
+		lastID, n, w, err := copyBatch(afterID)
+		if err != nil {
+			return written, err
+		}
+		read += n
+		written += w
+		if n > 0 {
+			log.Printf("%s: %d/%d rows (%d written, %d already present)", table, read, total, written, read-written)
+		}
+		if n < batchSize {
+			return written, nil
+		}
+		afterID = lastID
+	}
+}
+
+// parseStoreURL splits kind:location; bare paths are SQLite files
+func parseStoreURL(store string) (kind, location string) {
+	if i := strings.Index(store, "://"); i > 0 {
+		return store[:i], store[i+3:]
+	}
+	if kind, location, ok := strings.Cut(store, ":"); ok {
+		return kind, location
+	}
+	return "sqlite", store
+}
+
+// openMigrationTarget opens the destination store named by a -to value
+func openMigrationTarget(store, tinybirdURL string, payloadCipher *database.PayloadCipher, compressMin int) (migrationTarget, error) {
+	kind, location := parseStoreURL(store)
+	switch kind {
+	case "sqlite":
+		db, err := database.New(location)
+		if err != nil {
+			return nil, err
+		}
+		if payloadCipher != nil {
+			db.SetEncryption(payloadCipher)
+		}
+		db.SetCompression(compressMin)
+		return sqliteTarget{db}, nil
+	case "tinybird":
+		if location == "" {
+			return nil, fmt.Errorf("tinybird destination needs a token, tinybird:TOKEN")
+		}
+		tinybird := database.NewTinybirdDatabase(location)
+		if tinybirdURL != "" {
+			tinybird.SetBaseURL(tinybirdURL)
+		}
+		return tinybirdTarget{tinybird}, nil
+	case "postgres", "postgresql":
+		return nil, fmt.Errorf("unsupported backend %q: the gateway has no PostgreSQL store, migrate to sqlite:PATH or tinybird:TOKEN", kind)
+	}
+	return nil, fmt.Errorf("unsupported destination %q: supported stores are sqlite and tinybird", kind)
+}
+
+// sqliteTarget writes batches in one transaction per batch
+type sqliteTarget struct {
+	*database.Database
+}
+
+func (t sqliteTarget) writeRequests(reqs []types.AuditRequest) (int, error) {
+	records := make([]types.AuditRecord, len(reqs))
+	for i := range reqs {
+		records[i] = types.AuditRecord{Type: types.RecordRequest, Request: &reqs[i]}
+	}
+	result, err := t.ImportAuditRecords(records)
+	return result.Requests, err
+}
+
+func (t sqliteTarget) writeResponses(resps []types.AuditResponse) (int, error) {
+	records := make([]types.AuditRecord, len(resps))
+	for i := range resps {
+		records[i] = types.AuditRecord{Type: types.RecordResponse, Response: &resps[i]}
+	}
+	result, err := t.ImportAuditRecords(records)
+	return result.Responses, err
+}
+
+// tinybirdTarget sends batches through the Events API, skipping known request IDs
+type tinybirdTarget struct {
+	*database.TinybirdDatabase
+}
+
+func (t tinybirdTarget) writeRequests(reqs []types.AuditRequest) (int, error) {
+	ids := make([]string, len(reqs))
+	for i, req := range reqs {
+		ids[i] = req.RequestID
+	}
+	existing, err := t.ExistingRequestIDs("audit_requests", ids)
+	if err != nil {
+		return 0, err
+	}
+	var batch []types.AuditRequest
+	for _, req := range reqs {
+		if !existing[req.RequestID] {
+			batch = append(batch, req)
+		}
+	}
+	return len(batch), t.InsertAuditRequests(batch)
+}
+
+func (t tinybirdTarget) writeResponses(resps []types.AuditResponse) (int, error) {
+	ids := make([]string, len(resps))
+	for i, resp := range resps {
+		ids[i] = resp.RequestID
+	}
+	existing, err := t.ExistingRequestIDs("audit_responses", ids)
+	if err != nil {
+		return 0, err
+	}
+	var batch []types.AuditResponse
+	for _, resp := range resps {
+		if !existing[resp.RequestID] {
+			batch = append(batch, resp)
+		}
+	}
+	return len(batch), t.InsertAuditResponses(batch)
+}
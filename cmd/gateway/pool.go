@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/database"
+)
+
+// databasePool converts the pool limits of the config file
+func databasePool(p config.Pool) database.Pool {
+	return database.Pool{
+		MaxOpen:     p.MaxOpenConns,
+		MaxIdle:     p.MaxIdleConns,
+		MaxLifetime: p.ConnMaxLifetimeDuration(),
+		MaxIdleTime: p.ConnMaxIdleTimeDuration(),
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/niki4smirn/golf/internal/database"
+)
+
+// runRedriveTinybird resends events kept in the tinybird_deadletter table,
+// typically after fixing the datasource schema or quota that rejected them:
+//
+//	gateway redrive-tinybird -db audit.db -tinybird-token TOKEN
+//
+// Delivered events are removed from the table. The redrive stops at the first
+// batch Tinybird rejects again, leaving it in place with the new reason.
+func runRedriveTinybird(args []string) {
+	fs := flag.NewFlagSet("redrive-tinybird", flag.ExitOnError)
+	dbPath := fs.String("db", "audit.db", "Path to SQLite database file")
+	token := fs.String("tinybird-token", "", "Tinybird authentication token with append scope (required)")
+	tinybirdURL := fs.String("tinybird-url", "", "Tinybird API host (default EU region)")
+	datasource := fs.String("datasource", "", "Only redrive this datasource, audit_requests or audit_responses (default both)")
+	batchSize := fs.Int("batch", 500, "Events per Tinybird request")
+	keyFile := fs.String("encryption-key-file", "", "File containing the payload encryption key (default $GOLF_ENCRYPTION_KEY)")
+	fs.Parse(args)
+
+	if *token == "" {
+		log.Fatal("-tinybird-token is required")
+	}
+	if *batchSize <= 0 {
+		log.Fatal("-batch must be positive")
+	}
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Dead letters are encrypted like the audit payloads they carry
+	payloadCipher, err := loadPayloadCipher(*keyFile)
+	if err != nil {
+		log.Fatalf("Failed to load encryption key: %v", err)
+	}
+	if payloadCipher != nil {
+		db.SetEncryption(payloadCipher)
+	}
+
+	tinybird := database.NewTinybirdDatabase(*token)
+	if *tinybirdURL != "" {
+		tinybird.SetBaseURL(*tinybirdURL)
+	}
+	tinybird.SetDeadLetter(db)
+
+	datasources := []string{"audit_requests", "audit_responses"}
+	if *datasource != "" {
+		datasources = []string{*datasource}
+	}
+
+	for _, name := range datasources {
+		sent, err := tinybird.Redrive(name, *batchSize)
+		if err != nil {
+			log.Fatalf("%s: redrive stopped after %d events: %v", name, sent, err)
+		}
+		log.Printf("%s: redrove %d events", name, sent)
+	}
+
+	remaining, err := db.CountDeadLetters()
+	if err != nil {
+		log.Fatalf("Failed to count dead letters: %v", err)
+	}
+	for name, n := range remaining {
+		log.Printf("%s: %d events left in the dead-letter table", name, n)
+	}
+}
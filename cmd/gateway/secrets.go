@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/gateway"
+	"github.com/niki4smirn/golf/internal/secrets"
+)
+
+// newSecretsManager creates the manager resolving secret references, with a
+// Vault provider when the config has a vault section or $VAULT_ADDR and
+// $VAULT_TOKEN are set
+func newSecretsManager(cfg *config.Secrets) (*secrets.Manager, error) {
+	refresh := 5 * time.Minute
+	var vault *config.Vault
+	if cfg != nil {
+		refresh = cfg.RefreshIntervalDuration()
+		vault = cfg.Vault
+	}
+	if vault == nil && os.Getenv("VAULT_ADDR") != "" && os.Getenv("VAULT_TOKEN") != "" {
+		vault = &config.Vault{}
+		if err := vault.Normalize(); err != nil {
+			return nil, fmt.Errorf("invalid VAULT_ADDR: %w", err)
+		}
+	}
+
+	manager := secrets.NewManager(refresh)
+	if vault != nil {
+		provider, err := secrets.NewVault(*vault)
+		if err != nil {
+			return nil, err
+		}
+		manager.Register("vault", provider)
+		log.Printf("Reading vault: secret references from %s", vault.Address)
+	}
+	return manager, nil
+}
+
+// watchRouteSecrets resolves the secret_ref of routes' upstream credentials and keeps them up to date
+func watchRouteSecrets(manager *secrets.Manager, routes []config.Route) error {
+	for _, route := range routes {
+		auth := route.UpstreamAuth
+		if auth == nil || auth.SecretRef == "" {
+			continue
+		}
+		if err := manager.Watch(auth.SecretRef, auth.SetSecret); err != nil {
+			return fmt.Errorf("route %s: %w", route.Name, err)
+		}
+	}
+	return nil
+}
+
+// watchAPIKeys resolves the key_ref of configured API keys and hands the keys
+// to the gateway, again whenever one of them rotates
+func watchAPIKeys(manager *secrets.Manager, gw *gateway.Gateway, keys []config.APIKey) error {
+	var mu sync.Mutex
+	for i := range keys {
+		if keys[i].KeyRef == "" {
+			continue
+		}
+		i := i
+		err := manager.Watch(keys[i].KeyRef, func(key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			keys[i].Key = key
+			gw.SetAPIKeys(keys)
+		})
+		if err != nil {
+			return fmt.Errorf("api key %s: %w", keys[i].Name, err)
+		}
+	}
+	gw.SetAPIKeys(keys)
+	return nil
+}
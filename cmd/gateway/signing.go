@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadSigningKey reads the response signing key from a file or the
+// GOLF_SIGNING_KEY environment variable. It returns nil when no key is configured.
+func loadSigningKey(keyFile string) ([]byte, error) {
+	return loadSecret(keyFile, "GOLF_SIGNING_KEY", "signing")
+}
+
+// loadAnonymizeKey reads the key of anonymized exports from a file or the
+// GOLF_ANONYMIZE_KEY environment variable. It returns nil when no key is configured.
+func loadAnonymizeKey(keyFile string) ([]byte, error) {
+	return loadSecret(keyFile, "GOLF_ANONYMIZE_KEY", "anonymization")
+}
+
+// loadSecret reads an HMAC key of at least 32 characters, preferring keyFile over envVar
+func loadSecret(keyFile, envVar, name string) ([]byte, error) {
+	key := os.Getenv(envVar)
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s key file: %w", name, err)
+		}
+		key = string(data)
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, nil
+	}
+	if len(key) < 32 {
+		return nil, fmt.Errorf("%s key must be at least 32 characters", name)
+	}
+	return []byte(key), nil
+}
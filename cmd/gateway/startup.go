@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/gateway"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Backoff between attempts of a startup step
+const (
+	startupBackoffMin = 500 * time.Millisecond
+	startupBackoffMax = 30 * time.Second
+)
+
+// targetCheckTimeout bounds one attempt to connect to a target on startup
+const targetCheckTimeout = 5 * time.Second
+
+// waitFor runs check until it succeeds, doubling the wait between attempts,
+// and returns the record of the step. It gives up after the first failure
+// with failFast, or once the next attempt would start after timeout
+// (0 retries forever). update, if set, sees the record after each attempt.
+func waitFor(name string, failFast bool, timeout time.Duration, update func(types.StartupStep), check func() error) (types.StartupStep, error) {
+	step := types.StartupStep{Name: name, Status: types.StartupPending}
+	start := time.Now()
+	backoff := startupBackoffMin
+	for {
+		step.Attempts++
+		err := check()
+		if err == nil {
+			now := time.Now()
+			step.Status, step.Error, step.DoneAt = types.StartupOK, "", &now
+			if update != nil {
+				update(step)
+			}
+			if step.Attempts > 1 {
+				log.Printf("Startup step %s succeeded after %d attempts", name, step.Attempts)
+			}
+			return step, nil
+		}
+		step.Status, step.Error = types.StartupFailed, err.Error()
+		if update != nil {
+			update(step)
+		}
+		if failFast {
+			return step, err
+		}
+		if timeout > 0 && time.Since(start)+backoff > timeout {
+			return step, fmt.Errorf("%w (gave up after %d attempts in %s)", err, step.Attempts, time.Since(start).Round(time.Second))
+		}
+		log.Printf("Waiting for %s: %v, retrying in %s", name, err, backoff)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, startupBackoffMax)
+	}
+}
+
+// checkTargets connects to every target in the background until each one
+// answers, so readiness waits on the upstreams without holding up liveness
+func checkTargets(gw *gateway.Gateway) {
+	for _, target := range gw.Targets() {
+		name := "target " + target
+		gw.SetStartupStep(types.StartupStep{Name: name, Status: types.StartupPending})
+		go waitFor(name, false, 0, gw.SetStartupStep, func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), targetCheckTimeout)
+			defer cancel()
+			return gateway.CheckTarget(ctx, target)
+		})
+	}
+}
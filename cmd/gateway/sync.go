@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Checkpoint names used by sync-tinybird
+const (
+	syncRequestsCheckpoint  = "tinybird:audit_requests"
+	syncResponsesCheckpoint = "tinybird:audit_responses"
+)
+
+// runSyncTinybird streams historical audit rows from SQLite into Tinybird:
+//
+//	gateway sync-tinybird -db audit.db -tinybird-token TOKEN
+//
+// Progress is stored in the sync_checkpoints table so an interrupted sync resumes
+// where it stopped. Rows whose request_id already exists in Tinybird are skipped.
+func runSyncTinybird(args []string) {
+	fs := flag.NewFlagSet("sync-tinybird", flag.ExitOnError)
+	dbPath := fs.String("db", "audit.db", "Path to SQLite database file")
+	token := fs.String("tinybird-token", "", "Tinybird authentication token with append and read scopes (required)")
+	tinybirdURL := fs.String("tinybird-url", "", "Tinybird API host (default EU region)")
+	batchSize := fs.Int("batch", 500, "Rows per Tinybird request")
+	dedup := fs.Bool("dedup", true, "Skip rows whose request_id already exists in Tinybird")
+	reset := fs.Bool("reset", false, "Ignore saved checkpoints and start from the first row")
+	keyFile := fs.String("encryption-key-file", "", "File containing the payload encryption key (default $GOLF_ENCRYPTION_KEY)")
+	fs.Parse(args)
+
+	if *token == "" {
+		log.Fatal("-tinybird-token is required")
+	}
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Payloads are sent decrypted, matching what the live gateway emits
+	payloadCipher, err := loadPayloadCipher(*keyFile)
+	if err != nil {
+		log.Fatalf("Failed to load encryption key: %v", err)
+	}
+	if payloadCipher != nil {
+		db.SetEncryption(payloadCipher)
+	}
+
+	tinybird := database.NewTinybirdDatabase(*token)
+	if *tinybirdURL != "" {
+		tinybird.SetBaseURL(*tinybirdURL)
+	}
+
+	if *reset {
+		for _, name := range []string{syncRequestsCheckpoint, syncResponsesCheckpoint} {
+			if err := db.DeleteCheckpoint(name); err != nil {
+				log.Fatalf("Failed to reset checkpoint: %v", err)
+			}
+		}
+	}
+
+	requests, err := syncTable(db, syncRequestsCheckpoint, "audit_requests", *batchSize, *dedup,
+		func(afterID int64) ([]int64, []string, func(skip map[string]bool) error, error) {
+			rows, err := db.GetAuditRequestsAfterID(afterID, *batchSize)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			ids, requestIDs := make([]int64, len(rows)), make([]string, len(rows))
+			for i, row := range rows {
+				ids[i], requestIDs[i] = row.ID, row.RequestID
+			}
+			send := func(skip map[string]bool) error {
+				var batch []types.AuditRequest
+				for _, row := range rows {
+					if !skip[row.RequestID] {
+						batch = append(batch, row)
+					}
+				}
+				return tinybird.InsertAuditRequests(batch)
+			}
+			return ids, requestIDs, send, nil
+		}, tinybird)
+	if err != nil {
+		log.Fatalf("Request sync failed after %d rows: %v", requests, err)
+	}
+
+	responses, err := syncTable(db, syncResponsesCheckpoint, "audit_responses", *batchSize, *dedup,
+		func(afterID int64) ([]int64, []string, func(skip map[string]bool) error, error) {
+			rows, err := db.GetAuditResponsesAfterID(afterID, *batchSize)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			ids, requestIDs := make([]int64, len(rows)), make([]string, len(rows))
+			for i, row := range rows {
+				ids[i], requestIDs[i] = row.ID, row.RequestID
+			}
+			send := func(skip map[string]bool) error {
+				var batch []types.AuditResponse
+				for _, row := range rows {
+					if !skip[row.RequestID] {
+						batch = append(batch, row)
+					}
+				}
+				return tinybird.InsertAuditResponses(batch)
+			}
+			return ids, requestIDs, send, nil
+		}, tinybird)
+	if err != nil {
+		log.Fatalf("Response sync failed after %d rows: %v", responses, err)
+	}
+
+	log.Printf("Sync complete: %d requests and %d responses sent to Tinybird", requests, responses)
+}
+
+// syncTable sends batches returned by fetch until the table is exhausted,
+// saving the checkpoint after every successful batch
+func syncTable(db *database.Database, checkpoint, datasource string, batchSize int, dedup bool,
+	fetch func(afterID int64) ([]int64, []string, func(skip map[string]bool) error, error),
+	tinybird *database.TinybirdDatabase) (int, error) {
+
+	afterID, err := db.GetCheckpoint(checkpoint)
+	if err != nil {
+		return 0, err
+	}
+	if afterID > 0 {
+		log.Printf("Resuming %s sync after row %d", datasource, afterID)
+	}
+
+	sent := 0
+	for {
+		ids, requestIDs, send, err := fetch(afterID)
+		if err != nil {
+			return sent, err
+		}
+		if len(ids) == 0 {
+			return sent, nil
+		}
+
+		skip := map[string]bool{}
+		if dedup {
+			if skip, err = tinybird.ExistingRequestIDs(datasource, requestIDs); err != nil {
+				return sent, err
+			}
+		}
+
+		if err := send(skip); err != nil {
+			return sent, err
+		}
+
+		sent += len(ids) - len(skip)
+		afterID = ids[len(ids)-1]
+		if err := db.SetCheckpoint(checkpoint, afterID); err != nil {
+			return sent, err
+		}
+
+		log.Printf("%s: synced through row %d (%d sent, %d already present)", datasource, afterID, len(ids)-len(skip), len(skip))
+
+		if len(ids) < batchSize {
+			return sent, nil
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/gateway"
+)
+
+const (
+	listenFDsStart  = 3                // First file descriptor passed by systemd socket activation
+	shutdownTimeout = 30 * time.Second // In-flight requests get this long to finish on shutdown
+)
+
+// activatedListeners returns the sockets passed by systemd socket activation
+// (LISTEN_FDS), in order, with their FileDescriptorName= names. It returns
+// nil when the gateway was not socket-activated. The variables are cleared
+// so child processes don't inherit them.
+func activatedListeners() ([]net.Listener, []string, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make([]net.Listener, count)
+	fdNames := make([]string, count)
+	for i := 0; i < count; i++ {
+		fd := listenFDsStart + i
+		if i < len(names) {
+			fdNames[i] = names[i]
+		}
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("listen-fd-%d", fd))
+		ln, err := net.FileListener(f)
+		f.Close() // FileListener dups the descriptor
+		if err != nil {
+			return nil, nil, fmt.Errorf("socket %d (%s) is not a listening stream socket: %w", fd, fdNames[i], err)
+		}
+		listeners[i] = ln
+	}
+	return listeners, fdNames, nil
+}
+
+// assignActivated pairs passed sockets with listeners: by name when a socket's
+// FileDescriptorName= matches a listener name, otherwise in order. Listeners
+// left without a socket bind their own address.
+func assignActivated(listeners []config.Listener, sockets []net.Listener, names []string) (map[string]net.Listener, error) {
+	if len(sockets) == 0 {
+		return nil, nil
+	}
+
+	assigned := make(map[string]net.Listener)
+	used := make([]bool, len(sockets))
+	for _, l := range listeners {
+		for i, name := range names {
+			if !used[i] && name == l.Name {
+				assigned[l.Name], used[i] = sockets[i], true
+				break
+			}
+		}
+	}
+	next := 0
+	for _, l := range listeners {
+		if _, ok := assigned[l.Name]; ok {
+			continue
+		}
+		for next < len(sockets) && used[next] {
+			next++
+		}
+		if next == len(sockets) {
+			break
+		}
+		assigned[l.Name], used[next] = sockets[next], true
+	}
+
+	for i, ok := range used {
+		if !ok {
+			return nil, fmt.Errorf("socket %d (%s) matches no listener", listenFDsStart+i, names[i])
+		}
+	}
+	return assigned, nil
+}
+
+// sdNotify sends a state change such as READY=1 to the service manager. It is
+// a no-op outside systemd services with Type=notify.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:] // Abstract namespace
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to notify socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return nil
+}
+
+// watchdogInterval returns how often systemd expects a watchdog ping
+// (WatchdogSec=), or 0 when the watchdog is off
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// startWatchdog pings the systemd watchdog at half its interval and keeps the
+// unit's status line in sync with /health, so systemctl status shows degraded
+// storage. A hung gateway stops pinging and is restarted by systemd.
+func startWatchdog(gw *gateway.Gateway) (stop func()) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				health := gw.Health()
+				state := "WATCHDOG=1\nSTATUS=" + health.Status
+				if health.Storage != nil && health.Storage.Degraded {
+					state += fmt.Sprintf(", %d audit events pending", health.Storage.Pending)
+				}
+				if err := sdNotify(state); err != nil {
+					log.Printf("Watchdog ping failed: %v", err)
+				}
+			}
+		}
+	}()
+	log.Printf("systemd watchdog enabled, pinging every %s", interval/2)
+	return func() { close(done) }
+}
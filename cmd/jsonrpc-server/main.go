@@ -8,20 +8,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/niki4smirn/golf/internal/clock"
 	"github.com/niki4smirn/golf/internal/types"
 )
 
 // SimpleJSONRPCServer provides basic JSON-RPC responses for testing
 type SimpleJSONRPCServer struct {
 	methods map[string]func(params interface{}) (interface{}, error)
+	clock   clock.Clock // Stamps results; a stopped clock makes them reproducible
 }
 
-func NewSimpleJSONRPCServer() *SimpleJSONRPCServer {
+func NewSimpleJSONRPCServer(c clock.Clock) *SimpleJSONRPCServer {
 	server := &SimpleJSONRPCServer{
 		methods: make(map[string]func(params interface{}) (interface{}, error)),
+		clock:   c,
 	}
 
 	// Register some example methods
@@ -103,7 +107,7 @@ func (s *SimpleJSONRPCServer) sendError(w http.ResponseWriter, id interface{}, c
 func (s *SimpleJSONRPCServer) handlePing(params interface{}) (interface{}, error) {
 	return map[string]interface{}{
 		"message":   "pong",
-		"timestamp": time.Now().Unix(),
+		"timestamp": s.clock.Now().Unix(),
 		"server":    "simple-jsonrpc-server",
 	}, nil
 }
@@ -111,7 +115,7 @@ func (s *SimpleJSONRPCServer) handlePing(params interface{}) (interface{}, error
 func (s *SimpleJSONRPCServer) handleEcho(params interface{}) (interface{}, error) {
 	return map[string]interface{}{
 		"echo":      params,
-		"timestamp": time.Now().Unix(),
+		"timestamp": s.clock.Now().Unix(),
 	}, nil
 }
 
@@ -129,12 +133,12 @@ func (s *SimpleJSONRPCServer) handleGetUserInfo(params interface{}) (interface{}
 		"username":  fmt.Sprintf("user%d", userID),
 		"email":     fmt.Sprintf("user%d@example.com", userID),
 		"active":    true,
-		"createdAt": time.Now().Add(-time.Duration(userID*24) * time.Hour).Unix(),
+		"createdAt": s.clock.Now().Add(-time.Duration(userID*24) * time.Hour).Unix(),
 	}, nil
 }
 
 func (s *SimpleJSONRPCServer) handleGetTime(params interface{}) (interface{}, error) {
-	now := time.Now()
+	now := s.clock.Now()
 	return map[string]interface{}{
 		"unix":      now.Unix(),
 		"iso":       now.Format(time.RFC3339),
@@ -199,7 +203,7 @@ func (s *SimpleJSONRPCServer) handleSlowOperation(params interface{}) (interface
 	return map[string]interface{}{
 		"message":      "Slow operation completed",
 		"duration":     duration.Seconds(),
-		"completed_at": time.Now().Unix(),
+		"completed_at": s.clock.Now().Unix(),
 	}, nil
 }
 
@@ -209,9 +213,29 @@ func (s *SimpleJSONRPCServer) handleErrorTest(params interface{}) (interface{},
 
 func main() {
 	port := flag.String("port", "9000", "Port to run the JSON-RPC server on")
+	deterministic := flag.Bool("deterministic", false, "Answer every call as if it were -epoch, for reproducible integration tests")
+	epoch := flag.String("epoch", "2024-01-01T00:00:00Z", "Time reported in -deterministic mode (RFC 3339)")
+	openRPCPath := flag.String("openrpc", "", "OpenRPC document whose methods are answered with mock results matching their result schemas")
 	flag.Parse()
 
-	server := NewSimpleJSONRPCServer()
+	serverClock := clock.System
+	if *deterministic {
+		start, err := time.Parse(time.RFC3339, *epoch)
+		if err != nil {
+			log.Fatalf("Invalid -epoch: %v", err)
+		}
+		serverClock = clock.NewFake(start, 0)
+		log.Printf("Deterministic mode, reporting time as %s", start.Format(time.RFC3339))
+	}
+
+	server := NewSimpleJSONRPCServer(serverClock)
+	var mocked []string
+	if *openRPCPath != "" {
+		var err error
+		if mocked, err = server.LoadOpenRPC(*openRPCPath); err != nil {
+			log.Fatalf("Failed to load OpenRPC document: %v", err)
+		}
+	}
 
 	httpServer := &http.Server{
 		Addr:         ":" + *port,
@@ -232,6 +256,10 @@ func main() {
 		log.Printf("  - calculate: Performs math operations (params: {operation: string, a: number, b: number})")
 		log.Printf("  - slowOperation: Simulates slow operation (params: {duration: seconds})")
 		log.Printf("  - errorTest: Always returns an error for testing")
+		if len(mocked) > 0 {
+			log.Printf("  - rpc.discover: Returns the OpenRPC document")
+			log.Printf("Mocked from %s: %s", *openRPCPath, strings.Join(mocked, ", "))
+		}
 		log.Printf("")
 		log.Printf("Example usage:")
 		log.Printf("curl -X POST http://localhost:%s/rpc \\", *port)
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxMockDepth     = 8 // Stops generating nested values, so recursive schemas terminate
+	maxOptionalDepth = 2 // Optional properties are left out of objects nested deeper
+)
+
+// mockSchema is the part of JSON Schema used to generate mock results
+type mockSchema struct {
+	Ref        string                 `json:"$ref"`
+	Type       schemaType             `json:"type"`
+	Properties map[string]*mockSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Items      *mockSchema            `json:"items"`
+	Enum       []interface{}          `json:"enum"`
+	Const      interface{}            `json:"const"`
+	Default    interface{}            `json:"default"`
+	Examples   []interface{}          `json:"examples"`
+	Format     string                 `json:"format"`
+	Minimum    *float64               `json:"minimum"`
+	Maximum    *float64               `json:"maximum"`
+	MinLength  *int                   `json:"minLength"`
+	MaxLength  *int                   `json:"maxLength"`
+	MinItems   *int                   `json:"minItems"`
+	MaxItems   *int                   `json:"maxItems"`
+	OneOf      []*mockSchema          `json:"oneOf"`
+	AnyOf      []*mockSchema          `json:"anyOf"`
+	AllOf      []*mockSchema          `json:"allOf"`
+}
+
+// schemaType is the type keyword, a single name or a list of names
+type schemaType []string
+
+func (t *schemaType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaType{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = names
+	return nil
+}
+
+// openRPCDocument is the part of an OpenRPC document the mock serves
+type openRPCDocument struct {
+	Methods []struct {
+		Name   string `json:"name"`
+		Result *struct {
+			Schema *mockSchema `json:"schema"`
+		} `json:"result"`
+	} `json:"methods"`
+	Components struct {
+		Schemas map[string]*mockSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// mockGenerator produces values conforming to schemas of one document
+type mockGenerator struct {
+	schemas map[string]*mockSchema // components.schemas, the targets of $ref
+	now     func() time.Time
+}
+
+// LoadOpenRPC registers a handler returning mock results for every method of
+// the OpenRPC document at path, and rpc.discover serving the document itself.
+// Methods already registered are replaced. It returns the mocked method names.
+func (s *SimpleJSONRPCServer) LoadOpenRPC(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenRPC document: %w", err)
+	}
+	var doc openRPCDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenRPC document: %w", err)
+	}
+
+	gen := &mockGenerator{schemas: doc.Components.Schemas, now: s.clock.Now}
+	names := make([]string, 0, len(doc.Methods))
+	for _, m := range doc.Methods {
+		if m.Name == "" {
+			continue
+		}
+		var schema *mockSchema
+		if m.Result != nil {
+			schema = m.Result.Schema
+		}
+		s.RegisterMethod(m.Name, gen.handler(m.Name, schema))
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+
+	discover := json.RawMessage(data)
+	s.RegisterMethod("rpc.discover", func(params interface{}) (interface{}, error) {
+		return discover, nil
+	})
+	return names, nil
+}
+
+// handler answers calls of a method with a value of its result schema. The
+// value depends only on the method, params and clock, so repeated calls match
+// in -deterministic mode.
+func (g *mockGenerator) handler(method string, schema *mockSchema) func(params interface{}) (interface{}, error) {
+	return func(params interface{}) (interface{}, error) {
+		if schema == nil {
+			return json.RawMessage("null"), nil // Still a result member, as JSON-RPC requires
+		}
+		seed := fnv.New64a()
+		seed.Write([]byte(method))
+		encoded, _ := json.Marshal(params)
+		seed.Write(encoded)
+		rng := rand.New(rand.NewSource(int64(seed.Sum64())))
+		return g.value(schema, rng, "", 0), nil
+	}
+}
+
+// resolve follows $ref to components.schemas
+func (g *mockGenerator) resolve(s *mockSchema) *mockSchema {
+	for i := 0; s != nil && s.Ref != "" && i < maxMockDepth; i++ {
+		s = g.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// value generates a value conforming to s; name is the property holding it, a hint for strings
+func (g *mockGenerator) value(s *mockSchema, rng *rand.Rand, name string, depth int) interface{} {
+	s = g.resolve(s)
+	if s == nil || depth > maxMockDepth {
+		return nil
+	}
+
+	switch {
+	case s.Const != nil:
+		return s.Const
+	case len(s.Enum) > 0:
+		return s.Enum[rng.Intn(len(s.Enum))]
+	case len(s.Examples) > 0:
+		return s.Examples[rng.Intn(len(s.Examples))]
+	case s.Default != nil:
+		return s.Default
+	case len(s.OneOf) > 0:
+		return g.value(s.OneOf[rng.Intn(len(s.OneOf))], rng, name, depth+1)
+	case len(s.AnyOf) > 0:
+		return g.value(s.AnyOf[rng.Intn(len(s.AnyOf))], rng, name, depth+1)
+	case len(s.AllOf) > 0:
+		return g.value(g.merge(s), rng, name, depth+1)
+	}
+
+	switch g.typeOf(s, rng) {
+	case "object":
+		// Visit properties in order so the same seed gives the same object
+		props := make([]string, 0, len(s.Properties))
+		for prop := range s.Properties {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+		obj := make(map[string]interface{}, len(props))
+		for _, prop := range props {
+			if depth >= maxOptionalDepth && !contains(s.Required, prop) {
+				continue
+			}
+			obj[prop] = g.value(s.Properties[prop], rng, prop, depth+1)
+		}
+		return obj
+	case "array":
+		minItems, maxItems := bounds(s.MinItems, s.MaxItems, 1, 3)
+		items := make([]interface{}, minItems+rng.Intn(maxItems-minItems+1))
+		for i := range items {
+			items[i] = g.value(s.Items, rng, name, depth+1)
+		}
+		return items
+	case "integer":
+		low, high := numberBounds(s, 0, 1000)
+		n := int64(math.Floor(high)-math.Ceil(low)) + 1
+		if n < 1 {
+			return math.Ceil(low) // No integer in range; the schema cannot be satisfied
+		}
+		return math.Ceil(low) + float64(rng.Int63n(n))
+	case "number":
+		low, high := numberBounds(s, 0, 1000)
+		return math.Round((low+rng.Float64()*(high-low))*100) / 100
+	case "boolean":
+		return rng.Intn(2) == 1
+	case "null":
+		return nil
+	}
+	return g.text(s, rng, name)
+}
+
+// typeOf picks the type of s, inferring it from other keywords when missing
+func (g *mockGenerator) typeOf(s *mockSchema, rng *rand.Rand) string {
+	var types []string
+	for _, t := range s.Type {
+		if t != "null" || len(s.Type) == 1 {
+			types = append(types, t)
+		}
+	}
+	switch {
+	case len(types) > 0:
+		return types[rng.Intn(len(types))]
+	case s.Properties != nil:
+		return "object"
+	case s.Items != nil:
+		return "array"
+	case s.Minimum != nil || s.Maximum != nil:
+		return "number"
+	}
+	return "string"
+}
+
+// merge combines the members of allOf into one schema
+func (g *mockGenerator) merge(s *mockSchema) *mockSchema {
+	merged := *s
+	merged.AllOf = nil
+	merged.Properties = make(map[string]*mockSchema)
+	for prop, schema := range s.Properties {
+		merged.Properties[prop] = schema
+	}
+	for _, part := range s.AllOf {
+		part = g.resolve(part)
+		if part == nil {
+			continue
+		}
+		for prop, schema := range part.Properties {
+			merged.Properties[prop] = schema
+		}
+		merged.Required = append(merged.Required, part.Required...)
+		if len(merged.Type) == 0 {
+			merged.Type = part.Type
+		}
+	}
+	return &merged
+}
+
+// mockWords make up generated strings
+var mockWords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliet"}
+
+// text generates a string in the schema's format, of its allowed length
+func (g *mockGenerator) text(s *mockSchema, rng *rand.Rand, name string) string {
+	switch s.Format {
+	case "date-time":
+		return g.now().Add(-time.Duration(rng.Intn(30*24)) * time.Hour).UTC().Format(time.RFC3339)
+	case "date":
+		return g.now().AddDate(0, 0, -rng.Intn(365)).Format("2006-01-02")
+	case "email":
+		return fmt.Sprintf("%s%d@example.com", mockWords[rng.Intn(len(mockWords))], rng.Intn(1000))
+	case "uri", "url":
+		return fmt.Sprintf("https://example.com/%s/%d", mockWords[rng.Intn(len(mockWords))], rng.Intn(1000))
+	case "uuid":
+		b := make([]byte, 16)
+		rng.Read(b)
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case "ipv4":
+		return fmt.Sprintf("10.%d.%d.%d", rng.Intn(256), rng.Intn(256), 1+rng.Intn(254))
+	}
+
+	text := mockWords[rng.Intn(len(mockWords))]
+	if name != "" {
+		text = name + "-" + text
+	}
+	minLength, maxLength := bounds(s.MinLength, s.MaxLength, 0, len(text))
+	for len(text) < minLength {
+		text += "-" + mockWords[rng.Intn(len(mockWords))]
+	}
+	if len(text) > maxLength {
+		text = text[:maxLength]
+	}
+	return text
+}
+
+// bounds returns the given limits, the defaults where unset, with max at least min
+func bounds(min, max *int, defaultMin, defaultMax int) (int, int) {
+	low, high := defaultMin, defaultMax
+	if min != nil {
+		low = *min
+	}
+	if max != nil {
+		high = *max
+	}
+	if high < low {
+		high = low
+	}
+	return low, high
+}
+
+// numberBounds returns the range of a number schema, the defaults where unset
+func numberBounds(s *mockSchema, defaultMin, defaultMax float64) (float64, float64) {
+	low, high := defaultMin, defaultMax
+	if s.Minimum != nil {
+		low = *s.Minimum
+		if s.Maximum == nil && high < low {
+			high = low + defaultMax - defaultMin
+		}
+	}
+	if s.Maximum != nil {
+		high = *s.Maximum
+		if s.Minimum == nil && low > high {
+			low = high - (defaultMax - defaultMin)
+		}
+	}
+	if high < low {
+		high = low
+	}
+	return low, high
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
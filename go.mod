@@ -5,4 +5,5 @@ go 1.24.6
 require (
 	github.com/gorilla/mux v1.8.1
 	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/yuin/gopher-lua v1.1.1
 )
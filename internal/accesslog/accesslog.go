@@ -0,0 +1,193 @@
+// Package accesslog writes one line per proxied request in the Common or
+// Combined Log Format, for log ingestion pipelines that already parse web
+// server access logs. Lines are queued and written off the proxy path.
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Access log formats
+const (
+	FormatCommon   = "common"   // host ident user [time] "request" status bytes
+	FormatCombined = "combined" // common plus "referer" "user-agent"
+)
+
+// Stdout is the path writing the access log to standard output
+const Stdout = "-"
+
+// queueSize is how many lines wait for the output before new ones are dropped
+const queueSize = 10000
+
+// clfTime is the timestamp layout of access log lines
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// Entry is one proxied request
+type Entry struct {
+	RemoteHost string // Client IP
+	User       string // Name of the API key, empty when none was presented
+	Time       time.Time
+	Method     string
+	URI        string // Path and query string as requested
+	Proto      string // e.g. HTTP/1.1
+	Status     int
+	Bytes      int64 // Response body size, 0 is written as -
+	Referer    string
+	UserAgent  string
+}
+
+// Writer appends entries to a file or standard output
+type Writer struct {
+	format  string
+	out     io.Writer
+	closer  io.Closer // Nil for standard output
+	lines   chan []byte
+	done    chan struct{}
+	dropped int64
+
+	mu     sync.RWMutex
+	closed bool // Entries logged after Close are ignored
+}
+
+// Open starts writing entries in format to the file at path, appending to it,
+// or to standard output when path is Stdout. An empty format is combined.
+func Open(path, format string) (*Writer, error) {
+	if format == "" {
+		format = FormatCombined
+	}
+	if format != FormatCommon && format != FormatCombined {
+		return nil, fmt.Errorf("unknown access log format %q, expected common or combined", format)
+	}
+	w := &Writer{format: format, out: os.Stdout, lines: make(chan []byte, queueSize), done: make(chan struct{})}
+	if path != Stdout {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		w.out, w.closer = file, file
+	}
+	go w.run()
+	return w, nil
+}
+
+// Format returns the format entries are written in
+func (w *Writer) Format() string {
+	return w.format
+}
+
+// Log queues an entry, dropping it while the queue is full
+func (w *Writer) Log(e Entry) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.lines <- w.line(e):
+	default:
+		if atomic.AddInt64(&w.dropped, 1) == 1 {
+			log.Printf("Access log is falling behind, dropping entries")
+		}
+	}
+}
+
+// Dropped returns the number of entries lost while the queue was full
+func (w *Writer) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+// Close writes the queued entries and closes the file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.lines)
+	}
+	w.mu.Unlock()
+	<-w.done
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	for line := range w.lines {
+		if _, err := w.out.Write(line); err != nil {
+			log.Printf("Failed to write access log: %v", err)
+		}
+	}
+}
+
+// line formats an entry, e.g.
+// 127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "POST /rpc HTTP/1.1" 200 2326 "-" "curl/8.0"
+func (w *Writer) line(e Entry) []byte {
+	var b strings.Builder
+	b.WriteString(field(e.RemoteHost))
+	b.WriteString(" - ")
+	b.WriteString(field(e.User))
+	b.WriteString(" [")
+	b.WriteString(e.Time.Format(clfTime))
+	b.WriteString(`] "`)
+	b.WriteString(escape(e.Method + " " + e.URI + " " + e.Proto))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(e.Status))
+	b.WriteByte(' ')
+	if e.Bytes > 0 {
+		b.WriteString(strconv.FormatInt(e.Bytes, 10))
+	} else {
+		b.WriteByte('-')
+	}
+	if w.format == FormatCombined {
+		b.WriteString(` "`)
+		b.WriteString(escape(dash(e.Referer)))
+		b.WriteString(`" "`)
+		b.WriteString(escape(dash(e.UserAgent)))
+		b.WriteByte('"')
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// field writes an unquoted field, - when empty; spaces would shift the fields after it
+func field(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(escape(s), " ", `\x20`)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escape writes quotes, backslashes and control characters as escapes the way
+// Apache does, so clients cannot forge lines or fields
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
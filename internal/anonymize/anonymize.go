@@ -0,0 +1,243 @@
+// Package anonymize replaces identities in audit data with stable pseudonyms.
+// Pseudonyms are derived from the value with a keyed hash, so the same input
+// always maps to the same output under one key and joins across rows and
+// exports keep working, and they keep the shape of the original: IPs stay
+// IPs, digits stay digits and letters stay letters of the same case.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Anonymizer pseudonymizes values under a secret key
+type Anonymizer struct {
+	key []byte
+}
+
+// New creates an anonymizer; keep key secret, anyone holding it can test guesses of the originals
+func New(key []byte) *Anonymizer {
+	return &Anonymizer{key: key}
+}
+
+// stream returns n pseudorandom bytes derived from the kind of value and the value
+func (a *Anonymizer) stream(kind, value string, n int) []byte {
+	out := make([]byte, 0, n+sha256.Size)
+	var counter [4]byte
+	for i := uint32(0); len(out) < n; i++ {
+		mac := hmac.New(sha256.New, a.key)
+		mac.Write([]byte(kind))
+		mac.Write([]byte{0})
+		mac.Write([]byte(value))
+		binary.BigEndian.PutUint32(counter[:], i)
+		mac.Write(counter[:])
+		out = mac.Sum(out)
+	}
+	return out[:n]
+}
+
+// IP maps an IPv4 or IPv6 address to another address of the same family.
+// Values that are not IPs are pseudonymized as strings.
+func (a *Anonymizer) IP(value string) string {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return a.String(value)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return net.IP(a.stream("ip4", v4.String(), net.IPv4len)).String()
+	}
+	return net.IP(a.stream("ip6", ip.String(), net.IPv6len)).String()
+}
+
+// String replaces every letter and digit of value, keeping its length,
+// letter case, and punctuation, e.g. jane.doe@example.com -> qkwp.zmr@bfojxyl.hqs.
+// Hex strings such as ids and hashes stay hex strings.
+func (a *Anonymizer) String(value string) string {
+	if value == "" {
+		return ""
+	}
+	random := a.stream("str", value, len(value))
+	hex := isHex(value)
+
+	var b strings.Builder
+	b.Grow(len(value))
+	for i, c := range []byte(value) {
+		r := random[i]
+		switch {
+		case hex && (c >= '0' && c <= '9' || c >= 'a' && c <= 'f'):
+			b.WriteByte("0123456789abcdef"[r%16])
+		case hex && c >= 'A' && c <= 'F':
+			b.WriteByte("0123456789ABCDEF"[r%16])
+		case c >= '0' && c <= '9':
+			b.WriteByte('0' + r%10)
+		case c >= 'a' && c <= 'z':
+			b.WriteByte('a' + r%26)
+		case c >= 'A' && c <= 'Z':
+			b.WriteByte('A' + r%26)
+		default:
+			b.WriteByte(c) // Punctuation and multi-byte characters are kept
+		}
+	}
+	return b.String()
+}
+
+// isHex reports whether value looks like a hex id or hash rather than a word
+func isHex(value string) bool {
+	if len(value) < 8 {
+		return false
+	}
+	for _, c := range []byte(value) {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// Value pseudonymizes a decoded JSON value: strings as with String, numbers
+// keeping their digit count and sign, and objects and arrays element by element
+func (a *Anonymizer) Value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return a.String(v)
+	case json.Number:
+		return a.number(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = a.Value(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = a.Value(item)
+		}
+		return v
+	}
+	return v // Booleans and null carry no identity
+}
+
+// number replaces the digits of a JSON number without a leading zero
+func (a *Anonymizer) number(n json.Number) json.Number {
+	s := a.String(n.String())
+	digits := strings.TrimPrefix(s, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		s = strings.Replace(s, "0", "1", 1)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return n
+	}
+	return json.Number(s)
+}
+
+// JSON pseudonymizes the values at the given paths of a JSON document and
+// returns the re-encoded document. Paths are dotted names with optional array
+// indexes or [*] for every element, e.g. params.user.id, params[0] or
+// params.items[*].email. Top-level arrays, such as batches, apply paths to
+// every element. Documents that are not JSON are returned unchanged.
+func (a *Anonymizer) JSON(data json.RawMessage, paths []string) json.RawMessage {
+	if len(data) == 0 || len(paths) == 0 {
+		return data
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return data
+	}
+
+	roots := []interface{}{doc}
+	if batch, ok := doc.([]interface{}); ok {
+		roots = batch
+	}
+	for _, root := range roots {
+		for _, path := range paths {
+			a.apply(root, parsePath(path))
+		}
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// pathStep is one member name or array index of a path; index -1 means every element
+type pathStep struct {
+	name  string
+	index int
+	array bool
+}
+
+func parsePath(path string) []pathStep {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	var steps []pathStep
+	for _, segment := range strings.Split(path, ".") {
+		name := segment
+		var indexes []string
+		if i := strings.Index(segment, "["); i != -1 {
+			name = segment[:i]
+			indexes = strings.Split(segment[i+1:], "[")
+		}
+		if name != "" {
+			steps = append(steps, pathStep{name: name})
+		}
+		for _, index := range indexes {
+			index = strings.TrimSuffix(index, "]")
+			n, err := strconv.Atoi(index)
+			if index == "*" {
+				n = -1
+			} else if err != nil || n < 0 {
+				return nil
+			}
+			steps = append(steps, pathStep{index: n, array: true})
+		}
+	}
+	return steps
+}
+
+// apply pseudonymizes the values of node reached by steps in place
+func (a *Anonymizer) apply(node interface{}, steps []pathStep) {
+	if len(steps) == 0 {
+		return
+	}
+	step, rest := steps[0], steps[1:]
+
+	if !step.array {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return
+		}
+		child, ok := object[step.name]
+		if !ok {
+			return
+		}
+		if len(rest) == 0 {
+			object[step.name] = a.Value(child)
+			return
+		}
+		a.apply(child, rest)
+		return
+	}
+
+	array, ok := node.([]interface{})
+	if !ok {
+		return
+	}
+	for i := range array {
+		if step.index != -1 && i != step.index {
+			continue
+		}
+		if len(rest) == 0 {
+			array[i] = a.Value(array[i])
+		} else {
+			a.apply(array[i], rest)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package billing prices the per-key usage recorded in the audit tables into
+// monthly invoices, for charging internal teams per call through the gateway.
+package billing
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// PeriodLayout formats billing periods, e.g. 2026-10
+const PeriodLayout = "2006-01"
+
+const bytesPerMB = 1 << 20
+
+// Month returns the bounds of a calendar month in UTC given as YYYY-MM, or of
+// the month containing now when period is empty
+func Month(period string, now time.Time) (start, end time.Time, err error) {
+	if period == "" {
+		now = now.UTC()
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	} else if start, err = time.Parse(PeriodLayout, period); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", period)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// Invoice prices the usage of one key in the month starting at start
+func Invoice(cfg *config.Billing, usage types.BillingUsage, start, end, now time.Time) types.Invoice {
+	if cfg == nil {
+		cfg = &config.Billing{Currency: config.DefaultBillingCurrency}
+	}
+	rates := cfg.RatesFor(usage.APIKey)
+
+	megabytes := float64(usage.RequestBytes+usage.ResponseBytes) / bytesPerMB
+	invoice := types.Invoice{
+		Period:      start.Format(PeriodLayout),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Final:       !now.Before(end),
+		Currency:    cfg.Currency,
+		Usage:       usage,
+		Lines: []types.InvoiceLine{
+			line("Calls", float64(usage.Calls), "call", rates.PerCall),
+			line("Data transfer", roundTo(megabytes, 6), "MB", rates.PerMB),
+			line("Upstream compute", float64(usage.ComputeMs)/1000, "second", rates.PerComputeSecond),
+		},
+	}
+	for _, l := range invoice.Lines {
+		invoice.Total += l.Amount
+	}
+	invoice.Total = roundTo(invoice.Total, 2)
+	return invoice
+}
+
+// Invoices prices the usage of every key, ordered by key name
+func Invoices(cfg *config.Billing, usage []types.BillingUsage, start, end, now time.Time) []types.Invoice {
+	invoices := make([]types.Invoice, 0, len(usage))
+	for _, u := range usage {
+		invoices = append(invoices, Invoice(cfg, u, start, end, now))
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].Usage.APIKey < invoices[j].Usage.APIKey })
+	return invoices
+}
+
+func line(description string, quantity float64, unit string, unitPrice float64) types.InvoiceLine {
+	return types.InvoiceLine{
+		Description: description,
+		Quantity:    quantity,
+		Unit:        unit,
+		UnitPrice:   unitPrice,
+		Amount:      roundTo(quantity*unitPrice, 2),
+	}
+}
+
+func roundTo(x float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(x*scale) / scale
+}
+
+// csvHeader names the columns written by WriteCSV
+var csvHeader = []string{"period", "api_key", "tenant", "line", "quantity", "unit", "unit_price", "amount", "currency", "final"}
+
+// WriteCSV writes one row per invoice line, for spreadsheets and finance tools
+func WriteCSV(w io.Writer, invoices []types.Invoice) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, invoice := range invoices {
+		for _, l := range invoice.Lines {
+			err := cw.Write([]string{
+				invoice.Period,
+				invoice.Usage.APIKey,
+				invoice.Usage.Tenant,
+				l.Description,
+				strconv.FormatFloat(l.Quantity, 'f', -1, 64),
+				l.Unit,
+				strconv.FormatFloat(l.UnitPrice, 'f', -1, 64),
+				strconv.FormatFloat(l.Amount, 'f', 2, 64),
+				invoice.Currency,
+				strconv.FormatBool(invoice.Final),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,296 @@
+// Package capture mirrors proxied calls to a capture file on disk, as HAR for
+// browser devtools and HTTP tooling or as NDJSON for importing elsewhere. The
+// file is only ever appended to and rotates once it reaches a size limit.
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Capture file formats
+const (
+	FormatHAR    = "har"
+	FormatNDJSON = "ndjson"
+)
+
+// maxPending bounds the requests waiting for their response; the oldest is
+// written alone when a new one arrives at the limit
+const maxPending = 10000
+
+// Options configure a capture file
+type Options struct {
+	Format   string // har or ndjson, default from the file extension
+	MaxBytes int64  // Rotate once the file reaches this size, 0 disables
+	Keep     int    // Rotated files kept next to the active one, 0 keeps all
+}
+
+// Writer pairs audit requests with their responses and appends each call to
+// the capture file once answered. It implements database.AuditWriter.
+type Writer struct {
+	path string
+	opts Options
+	enc  encoder
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	entries int                            // Calls in the active file
+	pending map[string]*types.AuditRequest // Requests waiting for their response by request ID
+	order   []string                       // Request IDs of pending in arrival order
+}
+
+// Open opens the capture file at path, continuing a file left by a previous run
+func Open(path string, opts Options) (*Writer, error) {
+	if opts.Format == "" {
+		opts.Format = FormatNDJSON
+		if strings.EqualFold(filepath.Ext(path), ".har") {
+			opts.Format = FormatHAR
+		}
+	}
+	w := &Writer{path: path, opts: opts, pending: make(map[string]*types.AuditRequest)}
+	switch opts.Format {
+	case FormatHAR:
+		w.enc = harEncoder{}
+	case FormatNDJSON:
+		w.enc = ndjsonEncoder{}
+	default:
+		return nil, fmt.Errorf("unknown capture format %q", opts.Format)
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Format returns the format calls are written in
+func (w *Writer) Format() string {
+	return w.opts.Format
+}
+
+// InsertAuditRequest holds the request until its response arrives
+func (w *Writer) InsertAuditRequest(req *types.AuditRequest) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) >= maxPending {
+		if evicted := w.evictOldest(); evicted != nil {
+			if err := w.write(evicted, nil); err != nil {
+				return err
+			}
+		}
+	}
+	w.pending[req.RequestID] = req
+	w.order = append(w.order, req.RequestID)
+	return nil
+}
+
+// InsertAuditResponse writes the response with its request
+func (w *Writer) InsertAuditResponse(resp *types.AuditResponse) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	req := w.pending[resp.RequestID]
+	delete(w.pending, resp.RequestID)
+	// Drop ids of answered requests from the front so order stays bounded
+	for len(w.order) > 0 {
+		if _, ok := w.pending[w.order[0]]; ok {
+			break
+		}
+		w.order = w.order[1:]
+	}
+	return w.write(req, resp)
+}
+
+// Close writes the requests still waiting for a response and closes the file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for req := w.evictOldest(); req != nil; req = w.evictOldest() {
+		if err := w.write(req, nil); err != nil {
+			break
+		}
+	}
+	return w.file.Close()
+}
+
+// evictOldest removes the longest pending request; w.mu must be held
+func (w *Writer) evictOldest() *types.AuditRequest {
+	for len(w.order) > 0 {
+		id := w.order[0]
+		w.order = w.order[1:]
+		if req, ok := w.pending[id]; ok {
+			delete(w.pending, id)
+			return req
+		}
+	}
+	return nil
+}
+
+// write appends one call, rotating first when it would overflow the file; w.mu must be held
+func (w *Writer) write(req *types.AuditRequest, resp *types.AuditResponse) error {
+	data, err := w.enc.entry(req, resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode capture entry: %w", err)
+	}
+	if w.opts.MaxBytes > 0 && w.entries > 0 && w.size+int64(len(data)) > w.opts.MaxBytes {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	trailer := w.enc.trailer()
+	if w.entries > 0 {
+		data = append(w.enc.separator(), data...)
+	}
+	data = append(data, trailer...)
+	// Write over the trailer of the last call so the file stays a complete document
+	offset := w.size - int64(len(trailer))
+	if _, err := w.file.WriteAt(data, offset); err != nil {
+		return fmt.Errorf("failed to write capture file: %w", err)
+	}
+	w.size = offset + int64(len(data))
+	w.entries++
+	return nil
+}
+
+// open opens the file at w.path, moving it aside first when it cannot be continued
+func (w *Writer) open() error {
+	if info, err := os.Stat(w.path); err == nil && info.Size() > 0 && !w.continuable(info.Size()) {
+		if err := w.archive(); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open capture file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open capture file: %w", err)
+	}
+
+	w.file, w.size, w.entries = file, info.Size(), 0
+	header := w.enc.header()
+	if w.size == 0 {
+		data := append(header, w.enc.trailer()...)
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write capture file: %w", err)
+		}
+		w.size = int64(len(data))
+	} else if w.size > int64(len(header)+len(w.enc.trailer())) {
+		// Only whether the file has calls matters, for the separator of the next one
+		w.entries = 1
+	}
+	return nil
+}
+
+// continuable reports whether the existing file ends like this format's files,
+// so calls can be added to it
+func (w *Writer) continuable(size int64) bool {
+	trailer := w.enc.trailer()
+	header := w.enc.header()
+	if size < int64(len(header)+len(trailer)) {
+		return false
+	}
+	file, err := os.Open(w.path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	start := make([]byte, len(header))
+	end := make([]byte, len(trailer))
+	if _, err := file.ReadAt(start, 0); err != nil {
+		return false
+	}
+	if _, err := file.ReadAt(end, size-int64(len(trailer))); err != nil {
+		return false
+	}
+	return string(start) == string(header) && string(end) == string(trailer)
+}
+
+// rotate moves the active file aside and starts a new one; w.mu must be held
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close capture file: %w", err)
+	}
+	if err := w.archive(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// archive renames the file at w.path to <stem>-<time><ext> and removes the
+// oldest rotated files beyond Keep
+func (w *Writer) archive() error {
+	ext := filepath.Ext(w.path)
+	stem := strings.TrimSuffix(w.path, ext)
+	base := stem + "-" + time.Now().UTC().Format("20060102T150405Z")
+	archived := base + ext
+	for n := 1; ; n++ {
+		if _, err := os.Stat(archived); os.IsNotExist(err) {
+			break
+		}
+		archived = fmt.Sprintf("%s.%d%s", base, n, ext)
+	}
+	if err := os.Rename(w.path, archived); err != nil {
+		return fmt.Errorf("failed to rotate capture file: %w", err)
+	}
+
+	if w.opts.Keep <= 0 {
+		return nil
+	}
+	rotated, err := filepath.Glob(stem + "-*" + ext)
+	if err != nil {
+		return nil
+	}
+	// Names sort by rotation time
+	sort.Strings(rotated)
+	for len(rotated) > w.opts.Keep {
+		if err := os.Remove(rotated[0]); err != nil {
+			return fmt.Errorf("failed to remove rotated capture file: %w", err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// encoder lays out the calls of a capture file
+type encoder interface {
+	header() []byte    // Written once at the start of a file
+	trailer() []byte   // Closes the file after the last call
+	separator() []byte // Written between two calls
+	entry(req *types.AuditRequest, resp *types.AuditResponse) ([]byte, error)
+}
+
+// ndjsonEncoder writes one {"request":...,"response":...} line per call
+type ndjsonEncoder struct{}
+
+func (ndjsonEncoder) header() []byte    { return nil }
+func (ndjsonEncoder) trailer() []byte   { return nil }
+func (ndjsonEncoder) separator() []byte { return nil }
+
+func (ndjsonEncoder) entry(req *types.AuditRequest, resp *types.AuditResponse) ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Request  *types.AuditRequest  `json:"request,omitempty"`
+		Response *types.AuditResponse `json:"response,omitempty"`
+	}{req, resp})
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,226 @@
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// The parts of HAR 1.2 the capture writes. Fields starting with an
+// underscore are custom fields, which HAR readers ignore.
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int64       `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+
+	RequestID string            `json:"_requestId,omitempty"`
+	Method    string            `json:"_rpcMethod,omitempty"`
+	APIKey    string            `json:"_apiKey,omitempty"`
+	Error     string            `json:"_error,omitempty"`
+	Metadata  map[string]string `json:"_metadata,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []struct{}   `json:"cookies"`
+	Headers     []harHeader  `json:"headers"`
+	QueryString []harHeader  `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+	Client      *harClient   `json:"_client,omitempty"`
+}
+
+// harClient is the caller of a call, as the gateway saw it
+type harClient struct {
+	IPAddress string `json:"ipAddress,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []struct{}  `json:"cookies"`
+	Headers     []harHeader `json:"headers"`
+	Content     harContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"_encoding,omitempty"` // base64 for binary bodies, which HAR only defines for responses
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Blocked int64 `json:"blocked"`
+	DNS     int64 `json:"dns"`
+	Connect int64 `json:"connect"`
+	Send    int64 `json:"send"`
+	Wait    int64 `json:"wait"`
+	Receive int64 `json:"receive"`
+	SSL     int64 `json:"ssl"`
+}
+
+// harEncoder writes a HAR document whose entries array grows with every call
+type harEncoder struct{}
+
+func (harEncoder) header() []byte {
+	return []byte(`{"log":{"version":"1.2","creator":{"name":"golf-audit-gateway","version":"1.0"},"entries":[` + "\n")
+}
+
+func (harEncoder) trailer() []byte {
+	return []byte("\n]}}\n")
+}
+
+func (harEncoder) separator() []byte {
+	return []byte(",\n")
+}
+
+func (harEncoder) entry(req *types.AuditRequest, resp *types.AuditResponse) ([]byte, error) {
+	entry := harEntry{
+		Request: harRequest{
+			Method:      http.MethodPost,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []struct{}{},
+			Headers:     []harHeader{},
+			QueryString: []harHeader{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []struct{}{},
+			Headers:     []harHeader{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{DNS: -1, Connect: -1, SSL: -1},
+	}
+
+	if req != nil {
+		entry.StartedDateTime = req.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+		entry.RequestID = req.RequestID
+		entry.Method = req.Method
+		entry.APIKey = req.APIKey
+		if req.HTTPMethod != "" {
+			entry.Request.Method = req.HTTPMethod
+		}
+		entry.Request.URL = req.UpstreamURL
+		if u, err := url.Parse(req.UpstreamURL); err == nil {
+			for name, values := range u.Query() {
+				for _, value := range values {
+					entry.Request.QueryString = append(entry.Request.QueryString, harHeader{name, value})
+				}
+			}
+		}
+		entry.Request.Headers = harHeaders(req.Headers)
+		entry.Request.BodySize = req.RequestBytes
+		if text, encoding, ok := bodyText(req.Request, req.BodyEncoding); ok {
+			mimeType := req.ContentType
+			if mimeType == "" {
+				mimeType = "application/json"
+			}
+			entry.Request.PostData = &harPostData{MimeType: mimeType, Text: text, Encoding: encoding}
+		}
+		if req.IPAddress != "" || req.UserAgent != "" {
+			entry.Request.Client = &harClient{IPAddress: req.IPAddress, UserAgent: req.UserAgent}
+		}
+	}
+
+	if resp == nil {
+		// Never answered: HAR readers show status 0 as a failed request
+		entry.Response.StatusText = "No response"
+		entry.Response.Content.MimeType = "x-unknown"
+		return json.Marshal(entry)
+	}
+	if req == nil {
+		entry.StartedDateTime = resp.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+		entry.RequestID = resp.RequestID
+	}
+	entry.Time = resp.ProcessTime
+	entry.Timings.Blocked = resp.QueueTime
+	entry.Timings.Wait = resp.UpstreamTime
+	entry.Timings.Send = resp.ProcessTime - resp.QueueTime - resp.UpstreamTime
+	if entry.Timings.Send < 0 {
+		entry.Timings.Send = 0
+	}
+	entry.Error = resp.Error
+	entry.Metadata = resp.Metadata
+
+	entry.Response.Status = resp.StatusCode
+	entry.Response.StatusText = http.StatusText(resp.StatusCode)
+	entry.Response.BodySize = resp.ResponseBytes
+	entry.Response.Content.Size = resp.ResponseBytes
+	entry.Response.Content.MimeType = resp.ContentType
+	if entry.Response.Content.MimeType == "" {
+		entry.Response.Content.MimeType = "application/json"
+	}
+	entry.Response.Headers = append(entry.Response.Headers, harHeader{"Content-Type", entry.Response.Content.MimeType})
+	// The client got the transformed body when the method has a response transform
+	body := resp.Response
+	if len(resp.TransformedResponse) > 0 {
+		body = resp.TransformedResponse
+	}
+	if text, encoding, ok := bodyText(body, resp.BodyEncoding); ok {
+		entry.Response.Content.Text = text
+		entry.Response.Content.Encoding = encoding
+	}
+	return json.Marshal(entry)
+}
+
+// harHeaders turns recorded headers into name/value pairs sorted by name
+func harHeaders(raw json.RawMessage) []harHeader {
+	headers := []harHeader{}
+	var recorded map[string]string
+	if len(raw) == 0 || json.Unmarshal(raw, &recorded) != nil {
+		return headers
+	}
+	for name, value := range recorded {
+		headers = append(headers, harHeader{name, value})
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return strings.ToLower(headers[i].Name) < strings.ToLower(headers[j].Name)
+	})
+	return headers
+}
+
+// bodyText returns a recorded body as text: JSON as is, binary bodies as
+// their base64 string. ok is false when the body was not recorded.
+func bodyText(body json.RawMessage, bodyEncoding string) (text, encoding string, ok bool) {
+	if len(body) == 0 {
+		return "", "", false
+	}
+	if bodyEncoding == "" {
+		return string(body), "", true
+	}
+	if err := json.Unmarshal(body, &text); err != nil {
+		return string(body), "", true
+	}
+	return text, bodyEncoding, true
+}
@@ -0,0 +1,1518 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Config holds the gateway configuration loaded from a JSON file
+type Config struct {
+	Routes       []Route          `json:"routes"`
+	APIKeys      []APIKey         `json:"api_keys,omitempty"`
+	TenantQuotas map[string]Quota `json:"tenant_quotas,omitempty"`
+	Extractions  []Extraction     `json:"extractions,omitempty"`
+	SLOs         []SLO            `json:"slos,omitempty"`
+	Webhooks     []Webhook        `json:"webhooks,omitempty"`
+	Listeners    []Listener       `json:"listeners,omitempty"`
+
+	ResponseHeaders *HeaderFilter `json:"response_headers,omitempty"` // Default for routes without their own filter
+
+	TargetOverrides []string `json:"target_overrides,omitempty"` // Hosts or URL prefixes admins may send calls to with X-Golf-Target, empty disables the header
+
+	IndexedHeaders []string `json:"indexed_headers,omitempty"` // Request headers stored in their own indexed table, filterable with /audit/logs?header.<name>=
+
+	Extensions *Extensions `json:"extensions,omitempty"` // Non-standard top-level request members kept and indexed for filtering
+
+	OpenRPC *OpenRPC `json:"openrpc,omitempty"` // Method catalog served at /openrpc.json
+
+	PII *PII `json:"pii,omitempty"` // Flag audit rows whose bodies look like they contain personal data
+
+	Status *StatusPage `json:"status_page,omitempty"` // Public /status page
+
+	Correlation *Correlation `json:"correlation,omitempty"` // Link nested calls to the call that triggered them
+
+	Anonymize *Anonymize `json:"anonymize,omitempty"` // Extra fields pseudonymized by /audit/export?anonymize=true
+
+	ResponseSchemas *ResponseSchemas `json:"response_schemas,omitempty"` // Expected results, differences are reported at /audit/schema-drift
+
+	Billing *Billing `json:"billing,omitempty"` // Prices of the monthly per-key invoices at /billing
+
+	RequestIDs *RequestIDs `json:"request_ids,omitempty"` // How request IDs are generated (default timestamp)
+
+	OTLP *OTLP `json:"otlp,omitempty"` // Export every call to an OpenTelemetry collector
+
+	Secrets *Secrets `json:"secrets,omitempty"` // Where secret_ref, key_ref and -tinybird-token references are read
+
+	OIDC *OIDC `json:"oidc,omitempty"` // Sign operators in to the dashboard and management API with an OpenID Connect provider
+
+	Database *Database `json:"database,omitempty"` // Connection pools of the SQLite audit database
+}
+
+// validTargetOverride checks an entry of target_overrides: a bare host such as
+// staging.internal:8080, or an absolute http or https URL whose path is a prefix
+func validTargetOverride(target string) error {
+	if !strings.Contains(target, "://") {
+		if target == "" || strings.ContainsAny(target, "/?# ") {
+			return fmt.Errorf("invalid host %q", target)
+		}
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q must be a host or an absolute http or https URL prefix", target)
+	}
+	return nil
+}
+
+// DefaultParentHeader carries the parent request ID when Correlation sets no path or header
+const DefaultParentHeader = "X-Parent-Request-ID"
+
+// Correlation configures where a call names the request ID of the call that
+// triggered it, e.g. an MCP tool call made while serving another call.
+// The header is checked first, then the body path.
+type Correlation struct {
+	Header string `json:"header,omitempty"` // Request header holding the parent request ID
+	Path   string `json:"path,omitempty"`   // Body field holding it, e.g. params._parent_request_id
+}
+
+// DefaultAvailabilityObjective is the availability the status page budgets errors against
+const DefaultAvailabilityObjective = 0.999
+
+// StatusPage configures the public /status page
+type StatusPage struct {
+	Title     string  `json:"title,omitempty"`     // Heading of the HTML page (default "API status")
+	Objective float64 `json:"objective,omitempty"` // Fraction of calls the upstream must serve, e.g. 0.999 (default)
+}
+
+// PII detector names
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "credit_card"
+)
+
+// PII configures the content scanner flagging likely personal data in audited bodies
+type PII struct {
+	Detectors []string `json:"detectors,omitempty"` // email, phone and/or credit_card (default all)
+	Redact    bool     `json:"redact,omitempty"`    // Mask matches in the stored bodies; forwarded bodies are untouched
+}
+
+// Anonymize lists what anonymized exports pseudonymize besides client IPs,
+// fingerprints, API key names and tenants, which are always replaced.
+// Fields are paths rooted at request, response, headers or tags, e.g.
+// request.params.userId, response.result.email, headers.X-User-Id or tags.user.
+type Anonymize struct {
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ResponseSchemas registers the JSON Schema of the result of each method.
+// Upstream results are checked against them and every difference, such as a
+// new member or a changed type, is counted at /audit/schema-drift.
+type ResponseSchemas struct {
+	Methods map[string]json.RawMessage `json:"methods,omitempty"`
+	OpenRPC bool                       `json:"openrpc,omitempty"` // Also use the result schemas of the OpenRPC document for methods not listed
+}
+
+// Billing prices the usage of each API key for internal chargeback. Calls
+// rejected with 429 are not billed. Without rates invoices list usage only.
+type Billing struct {
+	Currency string                  `json:"currency,omitempty"`  // Default USD
+	Rates    BillingRates            `json:"rates"`               // Rates of keys not in KeyRates
+	KeyRates map[string]BillingRates `json:"key_rates,omitempty"` // Rates by API key name
+}
+
+// DefaultBillingCurrency is used when Billing sets no currency
+const DefaultBillingCurrency = "USD"
+
+// BillingRates are the unit prices of an invoice
+type BillingRates struct {
+	PerCall          float64 `json:"per_call,omitempty"`
+	PerMB            float64 `json:"per_mb,omitempty"`             // Per MB of request and response bodies
+	PerComputeSecond float64 `json:"per_compute_second,omitempty"` // Per second the upstream spent answering
+}
+
+// RatesFor returns the rates billed to an API key
+func (b *Billing) RatesFor(apiKey string) BillingRates {
+	if rates, ok := b.KeyRates[apiKey]; ok {
+		return rates
+	}
+	return b.Rates
+}
+
+// Request ID strategies
+const (
+	RequestIDTimestamp = "timestamp" // req_<unix nanos>_<n>, the original format
+	RequestIDUUIDv4    = "uuidv4"    // Random UUID
+	RequestIDUUIDv7    = "uuidv7"    // Time-ordered UUID
+	RequestIDULID      = "ulid"      // Time-ordered, 26 Crockford base32 characters
+	RequestIDSnowflake = "snowflake" // Time-ordered 63-bit integer with a node number
+)
+
+// MaxSnowflakeNode is the largest node number of snowflake IDs (10 bits)
+const MaxSnowflakeNode = 1023
+
+// RequestIDs selects how the request ID linking an audit request to its
+// response is generated. The time-ordered strategies sort in creation order.
+type RequestIDs struct {
+	Strategy string `json:"strategy"`
+	Node     int    `json:"node,omitempty"` // Snowflake node number, unique per gateway instance (0-1023)
+}
+
+// Secrets configures the providers resolving secret references, written as
+// vault:<path>#<field>, env:<NAME> or file:<path>. Referenced secrets are read
+// at startup and kept up to date while running, so rotated credentials take
+// effect without a restart.
+type Secrets struct {
+	Vault           *Vault `json:"vault,omitempty"`
+	RefreshInterval string `json:"refresh_interval,omitempty"` // How often secrets without a lease are read again (default 5m)
+
+	refreshInterval time.Duration
+}
+
+// RefreshIntervalDuration returns the parsed refresh_interval
+func (s *Secrets) RefreshIntervalDuration() time.Duration {
+	return s.refreshInterval
+}
+
+func (s *Secrets) normalize() error {
+	s.refreshInterval = 5 * time.Minute
+	if s.RefreshInterval != "" {
+		d, err := time.ParseDuration(s.RefreshInterval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("secrets: invalid refresh_interval %q", s.RefreshInterval)
+		}
+		s.refreshInterval = d
+	}
+	if s.Vault != nil {
+		if err := s.Vault.Normalize(); err != nil {
+			return fmt.Errorf("secrets: vault: %w", err)
+		}
+	}
+	return nil
+}
+
+// Vault reads secrets from HashiCorp Vault, logging in with a token or an
+// AppRole. The token and the leases of dynamic secrets are renewed before
+// they expire.
+type Vault struct {
+	Address      string `json:"address,omitempty"`        // Default $VAULT_ADDR
+	Namespace    string `json:"namespace,omitempty"`      // Enterprise namespace (default $VAULT_NAMESPACE)
+	TokenFile    string `json:"token_file,omitempty"`     // File holding the token (default $VAULT_TOKEN)
+	RoleID       string `json:"role_id,omitempty"`        // AppRole login instead of a token
+	SecretIDFile string `json:"secret_id_file,omitempty"` // File holding the AppRole secret ID
+	AuthPath     string `json:"auth_path,omitempty"`      // Mount of the AppRole auth method (default approle)
+}
+
+// Normalize fills in defaults from the VAULT_* environment variables and checks that a login method is set
+func (v *Vault) Normalize() error {
+	if v.Address == "" {
+		v.Address = os.Getenv("VAULT_ADDR")
+	}
+	if v.Namespace == "" {
+		v.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if v.AuthPath == "" {
+		v.AuthPath = "approle"
+	}
+	u, err := url.Parse(v.Address)
+	if v.Address == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("address must be an http or https URL")
+	}
+	if (v.RoleID == "") != (v.SecretIDFile == "") {
+		return fmt.Errorf("role_id and secret_id_file must be set together")
+	}
+	if v.RoleID == "" && v.TokenFile == "" && os.Getenv("VAULT_TOKEN") == "" {
+		return fmt.Errorf("token_file, $VAULT_TOKEN or role_id is required")
+	}
+	return nil
+}
+
+// OTLP signals a call is exported as
+const (
+	OTLPTraces = "traces" // One span per call
+	OTLPLogs   = "logs"   // One log record per call
+)
+
+// DefaultOTLPServiceName is the service.name resource attribute unless configured
+const DefaultOTLPServiceName = "golf-gateway"
+
+// OTLP exports each audit request/response pair to an OpenTelemetry collector
+// over OTLP/HTTP with JSON encoding
+type OTLP struct {
+	Endpoint      string            `json:"endpoint"`                 // Collector base URL, e.g. http://otel-collector:4318
+	Signal        string            `json:"signal,omitempty"`         // traces (default) or logs
+	Headers       map[string]string `json:"headers,omitempty"`        // Sent with every export, e.g. an authorization header
+	ServiceName   string            `json:"service_name,omitempty"`   // Default golf-gateway
+	BatchSize     int               `json:"batch_size,omitempty"`     // Calls per export request (default 100)
+	FlushInterval string            `json:"flush_interval,omitempty"` // Longest a call waits for its batch (default 5s)
+	IncludeBodies bool              `json:"include_bodies,omitempty"` // Attach the audited request and response bodies
+
+	flushInterval time.Duration
+}
+
+// FlushIntervalDuration returns the parsed flush_interval
+func (o *OTLP) FlushIntervalDuration() time.Duration {
+	return o.flushInterval
+}
+
+func (o *OTLP) normalize() error {
+	u, err := url.Parse(o.Endpoint)
+	if o.Endpoint == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("otlp: endpoint must be an http or https URL")
+	}
+	switch o.Signal {
+	case "":
+		o.Signal = OTLPTraces
+	case OTLPTraces, OTLPLogs:
+	default:
+		return fmt.Errorf("otlp: signal must be traces or logs")
+	}
+	if o.ServiceName == "" {
+		o.ServiceName = DefaultOTLPServiceName
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = 100
+	}
+	if o.BatchSize < 0 {
+		return fmt.Errorf("otlp: batch_size must be positive")
+	}
+	o.flushInterval = 5 * time.Second
+	if o.FlushInterval != "" {
+		d, err := time.ParseDuration(o.FlushInterval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("otlp: invalid flush_interval %q", o.FlushInterval)
+		}
+		o.flushInterval = d
+	}
+	return nil
+}
+
+// OIDC configures sign-in with an OpenID Connect provider, e.g. the corporate
+// SSO, through the authorization code flow. The client secret is read like
+// an upstream secret, from client_secret, client_secret_env or client_secret_ref.
+type OIDC struct {
+	Issuer          string   `json:"issuer"`                      // Provider URL serving /.well-known/openid-configuration
+	ClientID        string   `json:"client_id"`                   // Client registered with the provider
+	ClientSecret    string   `json:"client_secret,omitempty"`     // Literal secret, prefer client_secret_env or client_secret_ref; empty for public clients
+	ClientSecretEnv string   `json:"client_secret_env,omitempty"` // Environment variable holding the secret
+	ClientSecretRef string   `json:"client_secret_ref,omitempty"` // Secret reference, e.g. vault:secret/data/golf#oidc; see Secrets
+	RedirectURL     string   `json:"redirect_url"`                // Public URL of the gateway's /auth/callback, as registered with the provider
+	Scopes          []string `json:"scopes,omitempty"`            // Requested scopes (default openid, profile, email)
+	UserClaim       string   `json:"user_claim,omitempty"`        // ID token claim naming the user in admin actions (default email, else sub)
+	GroupsClaim     string   `json:"groups_claim,omitempty"`      // ID token claim listing the user's groups (default groups)
+
+	SignIn
+
+	clientSecret atomic.Pointer[string]
+}
+
+// SetClientSecret replaces the client secret, e.g. after it was rotated
+func (o *OIDC) SetClientSecret(secret string) {
+	o.clientSecret.Store(&secret)
+}
+
+// ClientSecretValue returns the current client secret
+func (o *OIDC) ClientSecretValue() string {
+	if secret := o.clientSecret.Load(); secret != nil {
+		return *secret
+	}
+	return ""
+}
+
+func (o *OIDC) normalize() error {
+	u, err := url.Parse(o.Issuer)
+	if o.Issuer == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("oidc: issuer must be an http or https URL")
+	}
+	o.Issuer = strings.TrimSuffix(o.Issuer, "/")
+	if o.ClientID == "" {
+		return fmt.Errorf("oidc: client_id is required")
+	}
+	u, err = url.Parse(o.RedirectURL)
+	if o.RedirectURL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("oidc: redirect_url must be the http or https URL of /auth/callback")
+	}
+
+	switch {
+	case o.ClientSecretRef != "":
+		// Resolved by the secrets provider at startup
+	case o.ClientSecretEnv != "":
+		o.SetClientSecret(os.Getenv(o.ClientSecretEnv))
+		if o.ClientSecretValue() == "" {
+			return fmt.Errorf("oidc: environment variable %s is not set", o.ClientSecretEnv)
+		}
+	default:
+		o.SetClientSecret(o.ClientSecret)
+	}
+
+	if len(o.Scopes) == 0 {
+		o.Scopes = []string{"openid", "profile", "email"}
+	}
+	hasOpenID := false
+	for _, scope := range o.Scopes {
+		hasOpenID = hasOpenID || scope == "openid"
+	}
+	if !hasOpenID {
+		o.Scopes = append([]string{"openid"}, o.Scopes...)
+	}
+	if o.UserClaim == "" {
+		o.UserClaim = "email"
+	}
+	if o.GroupsClaim == "" {
+		o.GroupsClaim = "groups"
+	}
+	if err := o.SignIn.normalize(); err != nil {
+		return fmt.Errorf("oidc: %w", err)
+	}
+	return nil
+}
+
+// DefaultSessionTTL is how long a sign-in to the dashboard lasts
+const DefaultSessionTTL = 12 * time.Hour
+
+// SignIn maps the groups of users signing in through an identity provider to
+// management roles
+type SignIn struct {
+	Groups      map[string]string `json:"groups,omitempty"`       // Group -> viewer, operator or admin; the highest role of the user's groups wins
+	DefaultRole string            `json:"default_role,omitempty"` // Role of users in none of the groups, empty refuses them
+	SessionTTL  string            `json:"session_ttl,omitempty"`  // How long a sign-in lasts (default 12h)
+
+	sessionTTL time.Duration
+}
+
+// SessionTTLDuration returns the parsed session_ttl
+func (s *SignIn) SessionTTLDuration() time.Duration {
+	return s.sessionTTL
+}
+
+func (s *SignIn) normalize() error {
+	for group, role := range s.Groups {
+		if role != types.RoleViewer && role != types.RoleOperator && role != types.RoleAdmin {
+			return fmt.Errorf("group %q: unknown role %q, expected viewer, operator or admin", group, role)
+		}
+	}
+	switch s.DefaultRole {
+	case "", types.RoleViewer, types.RoleOperator, types.RoleAdmin:
+	default:
+		return fmt.Errorf("default_role: unknown role %q, expected viewer, operator or admin", s.DefaultRole)
+	}
+	if len(s.Groups) == 0 && s.DefaultRole == "" {
+		return fmt.Errorf("groups or default_role is required, otherwise nobody may sign in")
+	}
+	s.sessionTTL = DefaultSessionTTL
+	if s.SessionTTL != "" {
+		d, err := time.ParseDuration(s.SessionTTL)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid session_ttl %q", s.SessionTTL)
+		}
+		s.sessionTTL = d
+	}
+	return nil
+}
+
+// Database tunes the connection pools of the SQLite audit database. Heavy
+// dashboard and stats queries can be sent to a separate read pool, e.g. a
+// replica kept by LiteFS or Litestream, so they do not hold up audit writes.
+type Database struct {
+	Pool
+
+	ReadDSN  string `json:"read_dsn,omitempty"`  // SQLite DSN of the read pool, e.g. file:/replica/audit.db?mode=ro (default: the write pool)
+	ReadPool *Pool  `json:"read_pool,omitempty"` // Limits of the read pool (default: those of the write pool)
+}
+
+// Pool limits a database/sql connection pool. Unset fields keep the defaults of database/sql.
+type Pool struct {
+	MaxOpenConns    int    `json:"max_open_conns,omitempty"`     // Most connections open at once
+	MaxIdleConns    int    `json:"max_idle_conns,omitempty"`     // Most idle connections kept
+	ConnMaxLifetime string `json:"conn_max_lifetime,omitempty"`  // Connections are closed once this old, e.g. 1h
+	ConnMaxIdleTime string `json:"conn_max_idle_time,omitempty"` // Connections are closed after idling this long, e.g. 5m
+
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+}
+
+// ConnMaxLifetimeDuration returns the parsed conn_max_lifetime, 0 when unset
+func (p *Pool) ConnMaxLifetimeDuration() time.Duration {
+	return p.connMaxLifetime
+}
+
+// ConnMaxIdleTimeDuration returns the parsed conn_max_idle_time, 0 when unset
+func (p *Pool) ConnMaxIdleTimeDuration() time.Duration {
+	return p.connMaxIdleTime
+}
+
+func (p *Pool) normalize() error {
+	if p.MaxOpenConns < 0 || p.MaxIdleConns < 0 {
+		return fmt.Errorf("max_open_conns and max_idle_conns must not be negative")
+	}
+	for _, d := range []struct {
+		name   string
+		value  string
+		parsed *time.Duration
+	}{
+		{"conn_max_lifetime", p.ConnMaxLifetime, &p.connMaxLifetime},
+		{"conn_max_idle_time", p.ConnMaxIdleTime, &p.connMaxIdleTime},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", d.name, d.value)
+		}
+		*d.parsed = parsed
+	}
+	return nil
+}
+
+func (d *Database) normalize() error {
+	if err := d.Pool.normalize(); err != nil {
+		return fmt.Errorf("database: %w", err)
+	}
+	if d.ReadPool == nil {
+		pool := d.Pool
+		d.ReadPool = &pool
+		return nil
+	}
+	if d.ReadDSN == "" {
+		return fmt.Errorf("database: read_pool requires read_dsn")
+	}
+	if err := d.ReadPool.normalize(); err != nil {
+		return fmt.Errorf("database: read_pool: %w", err)
+	}
+	return nil
+}
+
+// OpenRPC configures where the gateway gets the target's OpenRPC document
+type OpenRPC struct {
+	Document string `json:"document,omitempty"` // Path to an OpenRPC document file
+	Discover bool   `json:"discover,omitempty"` // Fetch the document with rpc.discover from the first route
+	Validate bool   `json:"validate,omitempty"` // Reject unknown methods and calls missing required params
+}
+
+// Extensions configures the non-standard top-level members some clients add
+// to JSON-RPC requests, e.g. meta or auth. They are forwarded untouched and
+// kept in the extensions audit column whenever headers are audited.
+type Extensions struct {
+	Index  []string `json:"index,omitempty"`  // Member paths promoted to ext.<path> tags for /audit/logs?ext.<path>=, e.g. meta.client
+	Redact []string `json:"redact,omitempty"` // Top-level members stored masked and never indexed, e.g. auth
+}
+
+// standardRequestMembers are the members of a JSON-RPC 2.0 request object
+var standardRequestMembers = map[string]bool{"jsonrpc": true, "id": true, "method": true, "params": true}
+
+// HeaderFilter selects the upstream response headers passed on to clients.
+// Names are case-insensitive; deny wins over allow, and an empty allow list allows all.
+type HeaderFilter struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Allows reports whether the header name passes the filter
+func (f *HeaderFilter) Allows(name string) bool {
+	if f == nil {
+		return true
+	}
+	for _, h := range f.Deny {
+		if strings.EqualFold(h, name) {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, h := range f.Allow {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// What a listener serves
+const (
+	ServeAll        = "all"
+	ServeProxy      = "proxy"      // JSON-RPC routes and /health
+	ServeManagement = "management" // /audit, /admin, dashboard and /health
+)
+
+// Listener is an address the gateway accepts connections on
+type Listener struct {
+	Name            string `json:"name,omitempty"`
+	Addr            string `json:"addr"`                    // host:port, e.g. 127.0.0.1:9090
+	Serve           string `json:"serve,omitempty"`         // proxy, management or all (default all)
+	TLSCertFile     string `json:"tls_cert_file,omitempty"` // Serve HTTPS with this certificate
+	TLSKeyFile      string `json:"tls_key_file,omitempty"`
+	TLSClientCAFile string `json:"tls_client_ca_file,omitempty"` // Require client certificates signed by this CA
+}
+
+// SLO is a latency objective, e.g. 99% of getUserInfo calls under 300ms over 30 days
+type SLO struct {
+	Name        string  `json:"name"`
+	Method      string  `json:"method,omitempty"` // JSON-RPC method (default all methods)
+	ThresholdMs int64   `json:"threshold_ms"`
+	Objective   float64 `json:"objective"`        // Fraction of calls that must be under the threshold, e.g. 0.99
+	Window      string  `json:"window,omitempty"` // Compliance window such as 30d or 12h (default 30d)
+
+	window time.Duration
+}
+
+// WindowDuration returns the parsed compliance window
+func (s SLO) WindowDuration() time.Duration {
+	return s.window
+}
+
+// Webhook receives gateway events as JSON POST requests
+type Webhook struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"` // Event names to deliver (default all)
+}
+
+// Wants reports whether the webhook subscribed to event
+func (w Webhook) Wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Extraction promotes a field of the JSON-RPC request into an indexed audit tag
+type Extraction struct {
+	Path    string   `json:"path"`              // JSONPath-like selector, e.g. $.params.userId or params.items[0].id
+	Tag     string   `json:"tag"`               // Tag name used for filtering, e.g. userId
+	Methods []string `json:"methods,omitempty"` // Only apply to these JSON-RPC methods (default all)
+}
+
+// ResponseTransform rewrites the result of a method for clients expecting an
+// older shape. Paths are dotted keys into the result, e.g. user.fullName, and
+// items[].id applies to every element of the items array. Renames run first,
+// then defaults, then strips.
+type ResponseTransform struct {
+	Rename   map[string]string      `json:"rename,omitempty"`   // Path -> the field name clients expect at the same place
+	Defaults map[string]interface{} `json:"defaults,omitempty"` // Path -> value set where an existing object lacks the field
+	Strip    []string               `json:"strip,omitempty"`    // Paths removed
+}
+
+// validate checks that every path names a field and renames stay in place
+func (t ResponseTransform) validate() error {
+	var paths []string
+	for path, name := range t.Rename {
+		if name == "" || strings.ContainsAny(name, ".[]") {
+			return fmt.Errorf("rename of %s must be a plain field name, got %q", path, name)
+		}
+		paths = append(paths, path)
+	}
+	for path := range t.Defaults {
+		paths = append(paths, path)
+	}
+	paths = append(paths, t.Strip...)
+	for _, path := range paths {
+		last := path[strings.LastIndex(path, ".")+1:]
+		if strings.TrimPrefix(path, "$.") == "" || last == "" || strings.HasSuffix(last, "[]") {
+			return fmt.Errorf("path %q must end with a field name", path)
+		}
+	}
+	return nil
+}
+
+// APIKey identifies a client calling the gateway
+type APIKey struct {
+	Key    string `json:"key"`
+	KeyRef string `json:"key_ref,omitempty"` // Secret reference the key is read from instead, see Secrets
+	Name   string `json:"name"`              // Stored in audit rows instead of the secret key
+	Tenant string `json:"tenant,omitempty"`  // Optional tenant the key belongs to
+	types.ClientPolicy
+}
+
+// Quota limits the number of calls per calendar day and month (0 means unlimited)
+type Quota = types.Quota
+
+// CountsUsage reports whether quotas or billing are configured, which both
+// count the calls of whole calendar months in the audit database
+func (c *Config) CountsUsage() bool {
+	if len(c.TenantQuotas) > 0 || c.Billing != nil {
+		return true
+	}
+	for _, key := range c.APIKeys {
+		if key.Quota.Daily > 0 || key.Quota.Monthly > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Route maps an incoming gateway path to an upstream target
+type Route struct {
+	Name         string   `json:"name"`
+	Path         string   `json:"path"`                    // Incoming path prefix, e.g. /rpc
+	Target       string   `json:"target"`                  // Upstream base URL, or tcp://host:port for raw TCP JSON-RPC
+	Secondary    string   `json:"secondary,omitempty"`     // Target retried when Target fails to connect or answers 5xx
+	UpstreamPath string   `json:"upstream_path,omitempty"` // Path appended to the target before the suffix
+	HTTPMethods  []string `json:"http_methods,omitempty"`  // Allowed HTTP methods (default POST)
+
+	// Calls matching one of these rules are sent to its target instead, see RoutingRule
+	Rules []RoutingRule `json:"rules,omitempty"`
+
+	// Accept calls as GET <path>?method=...&params=<base64 JSON>&id=..., forwarded
+	// upstream as a POST, and answer OPTIONS with the methods the route allows
+	HTTPGet bool `json:"http_get,omitempty"`
+
+	MethodAliases map[string]string `json:"method_aliases,omitempty"` // Client-facing method -> method expected upstream
+
+	ResponseTransforms map[string]ResponseTransform `json:"response_transforms,omitempty"` // Client-facing method -> rewrite of its results
+
+	AuditLevel        string            `json:"audit_level,omitempty"`         // metadata, headers or full-body (default)
+	Durability        string            `json:"durability,omitempty"`          // strict, standard or relaxed (default the gateway's -durability)
+	MethodAuditLevels map[string]string `json:"method_audit_levels,omitempty"` // Per-method overrides of AuditLevel
+
+	ResponseHeaders *HeaderFilter `json:"response_headers,omitempty"` // Upstream headers passed on to clients
+
+	UpstreamAuth *UpstreamAuth `json:"upstream_auth,omitempty"` // Credential injected when forwarding
+
+	Timeout        string            `json:"timeout,omitempty"`         // Deadline of upstream calls, e.g. 5s (default 30s)
+	MethodTimeouts map[string]string `json:"method_timeouts,omitempty"` // Per-method overrides of Timeout
+
+	SlowThreshold        string            `json:"slow_threshold,omitempty"`         // Calls taking longer are tagged slow=true
+	MethodSlowThresholds map[string]string `json:"method_slow_thresholds,omitempty"` // Per-method overrides of SlowThreshold
+
+	// Successful answers of these methods are cached for the given TTL, e.g. {"getUserInfo": "30s"}.
+	// Entries are keyed by method, params, target and tenant, so only cache methods
+	// whose result does not depend on anything else the caller sends.
+	MethodCacheTTLs map[string]string `json:"method_cache_ttls,omitempty"`
+
+	// Calls sent with an Idempotency-Key header are answered once; retries with the
+	// same key and body within this period get the stored answer, e.g. "24h".
+	// Keys are scoped to the route and API key, and shared by replicas with -redis-url.
+	IdempotencyTTL string `json:"idempotency_ttl,omitempty"`
+
+	// Lua script whose on_request(call) may deny, tag or rewrite each call before
+	// it is recorded, see package script. Calls are refused when it fails.
+	Script        string `json:"script,omitempty"`
+	ScriptTimeout string `json:"script_timeout,omitempty"` // Time budget of one on_request call (default 50ms)
+
+	MCP *MCP `json:"mcp,omitempty"` // Track the sessions of an MCP server behind the route
+
+	// Raw TCP targets, see Target
+	Framing      string `json:"framing,omitempty"`        // newline (default) or content-length
+	MaxIdleConns int    `json:"max_idle_conns,omitempty"` // Pooled connections kept open to the target (default 4)
+
+	// Concurrency limit shared by all routes with the same target
+	MaxInFlight  int    `json:"max_in_flight,omitempty"` // Concurrent calls to the target (default unlimited)
+	MaxQueue     int    `json:"max_queue,omitempty"`     // Calls waiting for a free slot before new ones are rejected
+	QueueTimeout string `json:"queue_timeout,omitempty"` // Longest wait for a free slot (default 10s)
+
+	// Calls older than this, counted from their arrival, are refused with 503
+	// instead of sent to a saturated target, rather than spending it on work
+	// the client has likely given up on; e.g. 5s (default no limit)
+	MaxAge string `json:"max_age,omitempty"`
+
+	// Send the items of batch calls to the target one by one, this many at a time,
+	// and answer with their responses in batch order. For targets answering batches
+	// serially; 0 or 1 forwards batches whole. Each item takes no slot of max_in_flight.
+	BatchConcurrency int `json:"batch_concurrency,omitempty"`
+
+	// Request bodies larger than this many bytes are refused with 413, checked
+	// while chunked bodies stream in; 0 uses the gateway's -max-request-bytes
+	MaxRequestBytes int64 `json:"max_request_bytes,omitempty"`
+
+	// Response bodies larger than this many bytes keep only their first bytes in
+	// the audit trail, along with the SHA-256 and size of the whole body, and are
+	// streamed to the client instead of buffered where the route allows it;
+	// 0 uses the gateway's -max-response-capture-bytes
+	MaxResponseCaptureBytes int64 `json:"max_response_capture_bytes,omitempty"`
+
+	// JSON-RPC error codes of failures the gateway answers itself, keyed by one of the
+	// Error* names, and of upstream HTTP errors without a JSON-RPC body, keyed by
+	// http_<status>, http_4xx or http_5xx, e.g. {"timeout": -32001, "http_429": -32005}
+	ErrorCodes map[string]int `json:"error_codes,omitempty"`
+	StrictSpec bool           `json:"strict_spec,omitempty"` // Answer with HTTP 200 and a JSON-RPC error whenever a call fails
+
+	queueTimeout   time.Duration
+	maxAge         time.Duration
+	timeout        time.Duration
+	methodTimeouts map[string]time.Duration
+	slowThreshold  time.Duration
+	methodSlow     map[string]time.Duration
+	methodCache    map[string]time.Duration
+	idempotencyTTL time.Duration
+	scriptTimeout  time.Duration
+}
+
+// RoutingRule sends the calls of a route matching all of its conditions to
+// another HTTP target, e.g. large analytical batches to a dedicated backend.
+// Rules are tried in order and the first match wins; empty conditions match
+// every call. Matched calls keep the route's upstream_path and upstream_auth,
+// are not failed over to its secondary and share the concurrency limit of
+// routes whose target is the rule's.
+type RoutingRule struct {
+	Name     string            `json:"name"`                // Recorded as the rule of matched calls
+	Target   string            `json:"target"`              // HTTP base URL the matching calls are sent to
+	Methods  []string          `json:"methods,omitempty"`   // JSON-RPC methods of single calls
+	Params   map[string]string `json:"params,omitempty"`    // Path into the request -> value, e.g. {"params.mode": "analytics"}
+	Headers  map[string]string `json:"headers,omitempty"`   // Request header -> value
+	MinBytes int64             `json:"min_bytes,omitempty"` // Request bodies of at least this many bytes
+	MaxBytes int64             `json:"max_bytes,omitempty"` // Request bodies of at most this many bytes
+	MinBatch int               `json:"min_batch,omitempty"` // Batches of at least this many calls
+}
+
+func (rule RoutingRule) validate() error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule needs a name")
+	}
+	u, err := url.Parse(rule.Target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("rule %q: target must be an http or https URL, got %q", rule.Name, rule.Target)
+	}
+	if rule.MinBytes < 0 || rule.MaxBytes < 0 || rule.MinBatch < 0 {
+		return fmt.Errorf("rule %q: min_bytes, max_bytes and min_batch must not be negative", rule.Name)
+	}
+	if rule.MaxBytes > 0 && rule.MaxBytes < rule.MinBytes {
+		return fmt.Errorf("rule %q: max_bytes is below min_bytes", rule.Name)
+	}
+	for path := range rule.Params {
+		if strings.TrimPrefix(path, "$.") == "" {
+			return fmt.Errorf("rule %q: empty params path", rule.Name)
+		}
+	}
+	return nil
+}
+
+// DefaultMCPSessionTTL is how long an unused MCP session stays valid
+const DefaultMCPSessionTTL = 24 * time.Hour
+
+// MCP configures routes serving MCP over Streamable HTTP. Sessions start with an
+// initialize call the server answers with an Mcp-Session-Id header, and end when
+// the client deletes them, the server no longer knows them or they go unused
+// for session_ttl. Event stream responses are passed on as events arrive, within
+// the route's timeout.
+type MCP struct {
+	RequireSession bool   `json:"require_session,omitempty"` // Refuse calls other than initialize without a known, active session
+	SessionTTL     string `json:"session_ttl,omitempty"`     // Unused sessions expire after this period (default 24h)
+
+	sessionTTL time.Duration
+}
+
+// SessionTTLDuration returns the parsed session TTL
+func (m *MCP) SessionTTLDuration() time.Duration {
+	return m.sessionTTL
+}
+
+// QueueTimeoutDuration returns the parsed queue timeout
+func (r Route) QueueTimeoutDuration() time.Duration {
+	return r.queueTimeout
+}
+
+// MaxAgeDuration returns the parsed max_age, 0 when calls never age out
+func (r Route) MaxAgeDuration() time.Duration {
+	return r.maxAge
+}
+
+// Load reads and validates a configuration file
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	for i := range cfg.Routes {
+		if err := cfg.Routes[i].normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, e := range cfg.Extractions {
+		if e.Path == "" || e.Tag == "" {
+			return nil, fmt.Errorf("extraction #%d: path and tag are required", i+1)
+		}
+	}
+
+	for i, target := range cfg.TargetOverrides {
+		if err := validTargetOverride(target); err != nil {
+			return nil, fmt.Errorf("target_overrides #%d: %w", i+1, err)
+		}
+	}
+
+	for i, name := range cfg.IndexedHeaders {
+		if name == "" || strings.ContainsAny(name, " :\t") {
+			return nil, fmt.Errorf("indexed_headers #%d: invalid header name %q", i+1, name)
+		}
+		cfg.IndexedHeaders[i] = http.CanonicalHeaderKey(name)
+	}
+
+	if e := cfg.Extensions; e != nil {
+		for _, path := range e.Index {
+			member, _, _ := strings.Cut(path, ".")
+			if member == "" || standardRequestMembers[member] {
+				return nil, fmt.Errorf("extensions: index %q does not start with a non-standard request member", path)
+			}
+		}
+		for _, member := range e.Redact {
+			if member == "" || strings.Contains(member, ".") || standardRequestMembers[member] {
+				return nil, fmt.Errorf("extensions: redact %q is not a non-standard top-level request member", member)
+			}
+		}
+	}
+
+	for i := range cfg.SLOs {
+		if err := cfg.SLOs[i].normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range cfg.Listeners {
+		if err := cfg.Listeners[i].normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	if o := cfg.OpenRPC; o != nil && o.Document == "" && !o.Discover {
+		return nil, fmt.Errorf("openrpc: document or discover is required")
+	}
+
+	if cfg.PII != nil {
+		if len(cfg.PII.Detectors) == 0 {
+			cfg.PII.Detectors = []string{PIIEmail, PIIPhone, PIICreditCard}
+		}
+		for _, d := range cfg.PII.Detectors {
+			if d != PIIEmail && d != PIIPhone && d != PIICreditCard {
+				return nil, fmt.Errorf("pii: unknown detector %q", d)
+			}
+		}
+	}
+
+	if s := cfg.Status; s != nil {
+		if s.Objective == 0 {
+			s.Objective = DefaultAvailabilityObjective
+		}
+		if s.Objective <= 0 || s.Objective >= 1 {
+			return nil, fmt.Errorf("status_page: objective must be between 0 and 1")
+		}
+	}
+
+	if a := cfg.Anonymize; a != nil {
+		for _, field := range a.Fields {
+			root, rest, _ := strings.Cut(field, ".")
+			if rest == "" || (root != "request" && root != "response" && root != "headers" && root != "tags") {
+				return nil, fmt.Errorf("anonymize: field %q must be a path under request, response, headers or tags", field)
+			}
+		}
+	}
+
+	if b := cfg.Billing; b != nil {
+		if b.Currency == "" {
+			b.Currency = DefaultBillingCurrency
+		}
+		for name, rates := range b.KeyRates {
+			if rates.PerCall < 0 || rates.PerMB < 0 || rates.PerComputeSecond < 0 {
+				return nil, fmt.Errorf("billing: rates of key %q must not be negative", name)
+			}
+		}
+		if b.Rates.PerCall < 0 || b.Rates.PerMB < 0 || b.Rates.PerComputeSecond < 0 {
+			return nil, fmt.Errorf("billing: rates must not be negative")
+		}
+	}
+
+	if ids := cfg.RequestIDs; ids != nil {
+		switch ids.Strategy {
+		case RequestIDTimestamp, RequestIDUUIDv4, RequestIDUUIDv7, RequestIDULID, RequestIDSnowflake:
+		default:
+			return nil, fmt.Errorf("request_ids: unknown strategy %q", ids.Strategy)
+		}
+		if ids.Node < 0 || ids.Node > MaxSnowflakeNode {
+			return nil, fmt.Errorf("request_ids: node must be between 0 and %d", MaxSnowflakeNode)
+		}
+	}
+
+	if cfg.OTLP != nil {
+		if err := cfg.OTLP.normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Secrets != nil {
+		if err := cfg.Secrets.normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.OIDC != nil {
+		if err := cfg.OIDC.normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Database != nil {
+		if err := cfg.Database.normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, h := range cfg.Webhooks {
+		if h.URL == "" {
+			return nil, fmt.Errorf("webhook #%d: url is required", i+1)
+		}
+	}
+
+	for i, k := range cfg.APIKeys {
+		if k.Key == "" && k.KeyRef == "" {
+			return nil, fmt.Errorf("api key #%d: key or key_ref is required", i+1)
+		}
+		if k.Name == "" {
+			return nil, fmt.Errorf("api key #%d: name is required", i+1)
+		}
+		if err := k.ClientPolicy.Validate(); err != nil {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// DefaultRoutes returns the built-in /rpc and /mcp routes forwarding to targetURL.
+// /mcp tracks MCP sessions and also accepts the GET event streams and DELETEs of MCP clients.
+func DefaultRoutes(targetURL string) []Route {
+	routes := []Route{
+		{Name: "rpc", Path: "/rpc", Target: targetURL},
+		{Name: "mcp", Path: "/mcp", Target: targetURL, HTTPMethods: []string{"POST", "GET", "DELETE"}, MCP: &MCP{}},
+	}
+	for i := range routes {
+		routes[i].normalize()
+	}
+	return routes
+}
+
+func (l *Listener) normalize() error {
+	if l.Addr == "" {
+		return fmt.Errorf("listener %q: addr is required", l.Name)
+	}
+	if l.Name == "" {
+		l.Name = l.Addr
+	}
+	switch l.Serve {
+	case "":
+		l.Serve = ServeAll
+	case ServeAll, ServeProxy, ServeManagement:
+	default:
+		return fmt.Errorf("listener %q: serve must be proxy, management or all", l.Name)
+	}
+	if (l.TLSCertFile == "") != (l.TLSKeyFile == "") {
+		return fmt.Errorf("listener %q: tls_cert_file and tls_key_file must be set together", l.Name)
+	}
+	if l.TLSClientCAFile != "" && l.TLSCertFile == "" {
+		return fmt.Errorf("listener %q: tls_client_ca_file requires TLS", l.Name)
+	}
+	return nil
+}
+
+func (s *SLO) normalize() error {
+	if s.Name == "" {
+		s.Name = s.Method
+	}
+	if s.Name == "" {
+		return fmt.Errorf("slo: name or method is required")
+	}
+	if s.ThresholdMs <= 0 {
+		return fmt.Errorf("slo %q: threshold_ms must be positive", s.Name)
+	}
+	if s.Objective <= 0 || s.Objective >= 1 {
+		return fmt.Errorf("slo %q: objective must be between 0 and 1", s.Name)
+	}
+	if s.Window == "" {
+		s.Window = "30d"
+	}
+	window, err := parseWindow(s.Window)
+	if err != nil {
+		return fmt.Errorf("slo %q: %w", s.Name, err)
+	}
+	s.window = window
+	return nil
+}
+
+// parseWindow parses a Go duration, additionally accepting whole days such as 30d
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", s)
+	}
+	return d, nil
+}
+
+func (r *Route) normalize() error {
+	if r.Path == "" {
+		return fmt.Errorf("route %q: path is required", r.Name)
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		r.Path = "/" + r.Path
+	}
+	if len(r.Path) > 1 {
+		r.Path = strings.TrimSuffix(r.Path, "/")
+	}
+	if r.Name == "" {
+		r.Name = strings.TrimPrefix(r.Path, "/")
+	}
+	if len(r.HTTPMethods) == 0 {
+		r.HTTPMethods = []string{"POST"}
+	}
+	for i, m := range r.HTTPMethods {
+		r.HTTPMethods[i] = strings.ToUpper(m)
+	}
+	if r.HTTPGet && !r.AllowsMethod(http.MethodGet) {
+		r.HTTPMethods = append(r.HTTPMethods, http.MethodGet)
+	}
+	if r.MaxInFlight < 0 || r.MaxQueue < 0 {
+		return fmt.Errorf("route %q: max_in_flight and max_queue must not be negative", r.Name)
+	}
+	if r.BatchConcurrency < 0 {
+		return fmt.Errorf("route %q: batch_concurrency must not be negative", r.Name)
+	}
+	if r.MaxRequestBytes < 0 {
+		return fmt.Errorf("route %q: max_request_bytes must not be negative", r.Name)
+	}
+	if r.MaxResponseCaptureBytes < 0 {
+		return fmt.Errorf("route %q: max_response_capture_bytes must not be negative", r.Name)
+	}
+	names := make(map[string]bool, len(r.Rules))
+	for _, rule := range r.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("route %q: %w", r.Name, err)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("route %q: duplicate rule %q", r.Name, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	if r.IsTCP() || strings.HasPrefix(r.Secondary, "tcp://") {
+		if r.Framing == "" {
+			r.Framing = FramingNewline
+		}
+		if r.Framing != FramingNewline && r.Framing != FramingContentLength {
+			return fmt.Errorf("route %q: unknown framing %q", r.Name, r.Framing)
+		}
+		if r.UpstreamPath != "" || r.UpstreamAuth != nil {
+			return fmt.Errorf("route %q: upstream_path and upstream_auth need HTTP targets", r.Name)
+		}
+		if r.MaxIdleConns == 0 {
+			r.MaxIdleConns = 4
+		}
+	}
+	if r.MaxIdleConns < 0 {
+		return fmt.Errorf("route %q: max_idle_conns must not be negative", r.Name)
+	}
+	if r.UpstreamAuth != nil {
+		if err := r.UpstreamAuth.normalize(); err != nil {
+			return fmt.Errorf("route %q: upstream_auth: %w", r.Name, err)
+		}
+	}
+	if err := validateAuditLevel(r.AuditLevel); err != nil {
+		return fmt.Errorf("route %q: %w", r.Name, err)
+	}
+	if r.Durability != "" {
+		if err := ValidateDurability(r.Durability); err != nil {
+			return fmt.Errorf("route %q: %w", r.Name, err)
+		}
+	}
+	for method, level := range r.MethodAuditLevels {
+		if err := validateAuditLevel(level); err != nil {
+			return fmt.Errorf("route %q, method %s: %w", r.Name, method, err)
+		}
+	}
+	r.queueTimeout = 10 * time.Second
+	if r.QueueTimeout != "" {
+		timeout, err := time.ParseDuration(r.QueueTimeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("route %q: invalid queue_timeout %q", r.Name, r.QueueTimeout)
+		}
+		r.queueTimeout = timeout
+	}
+	if r.MaxAge != "" {
+		maxAge, err := time.ParseDuration(r.MaxAge)
+		if err != nil || maxAge <= 0 {
+			return fmt.Errorf("route %q: invalid max_age %q", r.Name, r.MaxAge)
+		}
+		r.maxAge = maxAge
+	}
+	if r.Timeout != "" {
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("route %q: invalid timeout %q", r.Name, r.Timeout)
+		}
+		r.timeout = timeout
+	}
+	r.methodTimeouts = make(map[string]time.Duration, len(r.MethodTimeouts))
+	for method, value := range r.MethodTimeouts {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("route %q, method %s: invalid timeout %q", r.Name, method, value)
+		}
+		r.methodTimeouts[method] = timeout
+	}
+	if r.SlowThreshold != "" {
+		threshold, err := time.ParseDuration(r.SlowThreshold)
+		if err != nil || threshold <= 0 {
+			return fmt.Errorf("route %q: invalid slow_threshold %q", r.Name, r.SlowThreshold)
+		}
+		r.slowThreshold = threshold
+	}
+	r.methodSlow = make(map[string]time.Duration, len(r.MethodSlowThresholds))
+	for method, value := range r.MethodSlowThresholds {
+		threshold, err := time.ParseDuration(value)
+		if err != nil || threshold <= 0 {
+			return fmt.Errorf("route %q, method %s: invalid slow_threshold %q", r.Name, method, value)
+		}
+		r.methodSlow[method] = threshold
+	}
+	if r.IdempotencyTTL != "" {
+		ttl, err := time.ParseDuration(r.IdempotencyTTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("route %q: invalid idempotency_ttl %q", r.Name, r.IdempotencyTTL)
+		}
+		r.idempotencyTTL = ttl
+	}
+	if r.ScriptTimeout != "" {
+		timeout, err := time.ParseDuration(r.ScriptTimeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("route %q: invalid script_timeout %q", r.Name, r.ScriptTimeout)
+		}
+		r.scriptTimeout = timeout
+	}
+	if m := r.MCP; m != nil {
+		m.sessionTTL = DefaultMCPSessionTTL
+		if m.SessionTTL != "" {
+			ttl, err := time.ParseDuration(m.SessionTTL)
+			if err != nil || ttl <= 0 {
+				return fmt.Errorf("route %q: invalid mcp session_ttl %q", r.Name, m.SessionTTL)
+			}
+			m.sessionTTL = ttl
+		}
+	}
+	r.methodCache = make(map[string]time.Duration, len(r.MethodCacheTTLs))
+	for method, value := range r.MethodCacheTTLs {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("route %q, method %s: invalid cache ttl %q", r.Name, method, value)
+		}
+		r.methodCache[method] = ttl
+	}
+	for method, transform := range r.ResponseTransforms {
+		if err := transform.validate(); err != nil {
+			return fmt.Errorf("route %q, method %s: invalid response transform: %w", r.Name, method, err)
+		}
+	}
+	for name, code := range r.ErrorCodes {
+		if !validErrorName(name) {
+			return fmt.Errorf("route %q: unknown error_codes key %q", r.Name, name)
+		}
+		if code == 0 {
+			return fmt.Errorf("route %q: error code of %s must not be 0", r.Name, name)
+		}
+	}
+	return nil
+}
+
+// Gateway failures that error_codes can map to JSON-RPC error codes
+const (
+	ErrorTimeout     = "timeout"     // The upstream call exceeded its deadline
+	ErrorConnection  = "connection"  // The upstream could not be reached
+	ErrorUnavailable = "unavailable" // The route has no usable upstream URL for the call
+	ErrorBusy        = "busy"        // The target's concurrency queue is full
+	ErrorAgedOut     = "aged_out"    // The call waited longer than the route's max_age
+	ErrorInternal    = "internal"    // The gateway failed to build a response
+)
+
+func validErrorName(name string) bool {
+	switch name {
+	case ErrorTimeout, ErrorConnection, ErrorUnavailable, ErrorBusy, ErrorAgedOut, ErrorInternal, "http_4xx", "http_5xx":
+		return true
+	}
+	status, err := strconv.Atoi(strings.TrimPrefix(name, "http_"))
+	return strings.HasPrefix(name, "http_") && err == nil && status >= 400 && status <= 599
+}
+
+// Upstream authentication schemes
+const (
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+	AuthHeader = "header"
+)
+
+// UpstreamAuth is a credential the gateway adds to forwarded requests, so
+// clients never see the upstream's secrets. The secret is read once at startup
+// from secret, secret_env or secret_file, or kept up to date from secret_ref.
+type UpstreamAuth struct {
+	Type       string `json:"type"`                  // bearer, basic or header
+	Username   string `json:"username,omitempty"`    // basic: user name, the secret is the password
+	Header     string `json:"header,omitempty"`      // header: name of the header to set
+	Template   string `json:"template,omitempty"`    // header: value with a {secret} placeholder (default "{secret}")
+	Secret     string `json:"secret,omitempty"`      // Literal secret, prefer secret_env or secret_file
+	SecretEnv  string `json:"secret_env,omitempty"`  // Environment variable holding the secret
+	SecretFile string `json:"secret_file,omitempty"` // File holding the secret, surrounding whitespace is trimmed
+	SecretRef  string `json:"secret_ref,omitempty"`  // Secret reference, e.g. vault:secret/data/golf#upstream; see Secrets
+
+	secret atomic.Pointer[string]
+}
+
+// SetSecret replaces the secret, e.g. after it was rotated
+func (a *UpstreamAuth) SetSecret(secret string) {
+	a.secret.Store(&secret)
+}
+
+func (a *UpstreamAuth) currentSecret() string {
+	if secret := a.secret.Load(); secret != nil {
+		return *secret
+	}
+	return ""
+}
+
+func (a *UpstreamAuth) normalize() error {
+	switch {
+	case a.SecretRef != "":
+		// Resolved by the secrets provider at startup
+	case a.SecretEnv != "":
+		a.SetSecret(os.Getenv(a.SecretEnv))
+		if a.currentSecret() == "" {
+			return fmt.Errorf("environment variable %s is not set", a.SecretEnv)
+		}
+	case a.SecretFile != "":
+		data, err := os.ReadFile(a.SecretFile)
+		if err != nil {
+			return fmt.Errorf("failed to read secret: %w", err)
+		}
+		a.SetSecret(strings.TrimSpace(string(data)))
+	default:
+		a.SetSecret(a.Secret)
+	}
+
+	switch a.Type {
+	case AuthBearer, AuthBasic:
+	case AuthHeader:
+		if a.Header == "" {
+			return fmt.Errorf("header is required")
+		}
+		if a.Template == "" {
+			a.Template = "{secret}"
+		}
+	default:
+		return fmt.Errorf("unknown type %q", a.Type)
+	}
+	if a.SecretRef == "" && a.currentSecret() == "" {
+		return fmt.Errorf("secret is empty")
+	}
+	return nil
+}
+
+// HeaderName returns the header carrying the credential
+func (a *UpstreamAuth) HeaderName() string {
+	if a.Type == AuthHeader {
+		return http.CanonicalHeaderKey(a.Header)
+	}
+	return "Authorization"
+}
+
+// HeaderValue returns the credential header value
+func (a *UpstreamAuth) HeaderValue() string {
+	secret := a.currentSecret()
+	switch a.Type {
+	case AuthBearer:
+		return "Bearer " + secret
+	case AuthBasic:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+secret))
+	default:
+		return strings.ReplaceAll(a.Template, "{secret}", secret)
+	}
+}
+
+// AuditLevelFor returns how much of a call to method is recorded
+func (r *Route) AuditLevelFor(method string) string {
+	if level, ok := r.MethodAuditLevels[method]; ok && level != "" {
+		return level
+	}
+	if r.AuditLevel != "" {
+		return r.AuditLevel
+	}
+	return types.AuditLevelFullBody
+}
+
+// TimeoutFor returns the deadline of an upstream call to method, 0 when the route sets none
+func (r *Route) TimeoutFor(method string) time.Duration {
+	if timeout, ok := r.methodTimeouts[method]; ok {
+		return timeout
+	}
+	return r.timeout
+}
+
+// LongestTimeout returns the longest deadline of the route's upstream calls, 0 when the route sets none
+func (r *Route) LongestTimeout() time.Duration {
+	longest := r.timeout
+	for _, timeout := range r.methodTimeouts {
+		longest = max(longest, timeout)
+	}
+	return longest
+}
+
+// SlowThresholdFor returns the latency above which calls to method count as slow, 0 when unset
+func (r *Route) SlowThresholdFor(method string) time.Duration {
+	if threshold, ok := r.methodSlow[method]; ok {
+		return threshold
+	}
+	return r.slowThreshold
+}
+
+// CacheTTLFor returns how long answers of method are cached, 0 when they are not
+func (r *Route) CacheTTLFor(method string) time.Duration {
+	return r.methodCache[method]
+}
+
+// ScriptTimeoutDuration returns the time budget of the route's script, 0 for the default
+func (r *Route) ScriptTimeoutDuration() time.Duration {
+	return r.scriptTimeout
+}
+
+// IdempotencyTTLDuration returns how long answers to calls with an Idempotency-Key are kept, 0 when disabled
+func (r *Route) IdempotencyTTLDuration() time.Duration {
+	return r.idempotencyTTL
+}
+
+// ErrorCode returns the JSON-RPC error code configured for a gateway failure
+func (r *Route) ErrorCode(failure string) (int, bool) {
+	code, ok := r.ErrorCodes[failure]
+	return code, ok
+}
+
+// UpstreamErrorCode returns the JSON-RPC error code configured for an upstream
+// HTTP error status, preferring an exact http_<status> entry over http_4xx/http_5xx
+func (r *Route) UpstreamErrorCode(status int) (int, bool) {
+	if code, ok := r.ErrorCodes["http_"+strconv.Itoa(status)]; ok {
+		return code, true
+	}
+	code, ok := r.ErrorCodes[fmt.Sprintf("http_%dxx", status/100)]
+	return code, ok
+}
+
+// ErrorStatus returns the HTTP status of a failed call: 200 on strict-spec routes, status otherwise
+func (r *Route) ErrorStatus(status int) int {
+	if r.StrictSpec {
+		return http.StatusOK
+	}
+	return status
+}
+
+func validateAuditLevel(level string) error {
+	switch level {
+	case "", types.AuditLevelMetadata, types.AuditLevelHeaders, types.AuditLevelFullBody:
+		return nil
+	}
+	return fmt.Errorf("unknown audit level %q", level)
+}
+
+// ValidateDurability checks an audit durability mode
+func ValidateDurability(mode string) error {
+	switch mode {
+	case types.DurabilityStrict, types.DurabilityStandard, types.DurabilityRelaxed:
+		return nil
+	}
+	return fmt.Errorf("unknown durability %q, expected strict, standard or relaxed", mode)
+}
+
+// AllowsMethod reports whether the route accepts the given HTTP method
+func (r *Route) AllowsMethod(method string) bool {
+	for _, m := range r.HTTPMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Message framing of raw TCP targets
+const (
+	FramingNewline       = "newline"        // One JSON document per line
+	FramingContentLength = "content-length" // Content-Length header block before each document, as in LSP
+)
+
+// IsTCP reports whether the route forwards to a raw TCP target
+func (r *Route) IsTCP() bool {
+	return strings.HasPrefix(r.Target, "tcp://")
+}
+
+// UpstreamURL builds the upstream URL for an incoming request path and query string.
+// The part of requestPath after the route prefix is preserved, so /rpc/v2 on a route
+// with path /rpc forwards to <target><upstream_path>/v2.
+func (r *Route) UpstreamURL(requestPath, rawQuery string) (string, error) {
+	if r.Target == "" {
+		return "", fmt.Errorf("route %q has no target", r.Name)
+	}
+	return r.targetURL(r.Target, requestPath, rawQuery)
+}
+
+// SecondaryURL builds the URL of a request on the secondary target, "" when none is configured
+func (r *Route) SecondaryURL(requestPath, rawQuery string) (string, error) {
+	if r.Secondary == "" {
+		return "", nil
+	}
+	return r.targetURL(r.Secondary, requestPath, rawQuery)
+}
+
+// RuleURL builds the URL of a request on the target of one of the route's rules
+func (r *Route) RuleURL(rule *RoutingRule, requestPath, rawQuery string) (string, error) {
+	return r.targetURL(rule.Target, requestPath, rawQuery)
+}
+
+func (r *Route) targetURL(target, requestPath, rawQuery string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target URL for route %q: %w", r.Name, err)
+	}
+	if strings.HasPrefix(target, "tcp://") {
+		// Raw TCP has no paths or query strings
+		return target, nil
+	}
+
+	suffix := strings.TrimPrefix(requestPath, r.Path)
+
+	if r.UpstreamPath != "" || suffix != "" {
+		joined := path.Join("/", u.Path, r.UpstreamPath, suffix)
+		// path.Join drops trailing slashes which some targets rely on
+		if strings.HasSuffix(suffix, "/") && !strings.HasSuffix(joined, "/") {
+			joined += "/"
+		}
+		u.Path = joined
+	}
+
+	if rawQuery != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&" + rawQuery
+		} else {
+			u.RawQuery = rawQuery
+		}
+	}
+
+	return u.String(), nil
+}
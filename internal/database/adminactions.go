@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createAdminActionsTableSQL = `
+-- Privileged management API calls, who made them and their outcome
+CREATE TABLE IF NOT EXISTS admin_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp DATETIME NOT NULL,
+    actor TEXT NOT NULL,
+    role TEXT NOT NULL,
+    http_method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    ip_address TEXT
+);
+`
+
+// InsertAdminAction records a privileged management API call
+func (d *Database) InsertAdminAction(a *types.AdminAction) error {
+	_, err := d.sqlDB().Exec(`
+		INSERT INTO admin_actions (timestamp, actor, role, http_method, path, status_code, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, a.Timestamp, a.Actor, a.Role, a.HTTPMethod, a.Path, a.StatusCode, nullIfEmpty(a.IPAddress))
+	if err != nil {
+		return fmt.Errorf("failed to insert admin action: %w", err)
+	}
+	return nil
+}
+
+// GetAdminActions returns recorded admin actions, newest first, optionally of a single actor
+func (d *Database) GetAdminActions(actor string, limit, offset int) ([]types.AdminAction, error) {
+	rows, err := d.sqlDB().Query(`
+		SELECT id, timestamp, actor, role, http_method, path, status_code, ip_address
+		FROM admin_actions
+		WHERE (? = '' OR actor = ?)
+		ORDER BY id DESC LIMIT ? OFFSET ?
+	`, actor, actor, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query admin actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []types.AdminAction{}
+	for rows.Next() {
+		var a types.AdminAction
+		var ip sql.NullString
+		if err := rows.Scan(&a.ID, &a.Timestamp, &a.Actor, &a.Role, &a.HTTPMethod, &a.Path, &a.StatusCode, &ip); err != nil {
+			return nil, fmt.Errorf("failed to scan admin action: %w", err)
+		}
+		a.IPAddress = ip.String
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
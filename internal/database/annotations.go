@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createAnnotationsTableSQL = `
+-- Operator notes and soft-delete flags of audit entries
+CREATE TABLE IF NOT EXISTS audit_annotations (
+    request_id TEXT PRIMARY KEY,
+    note TEXT,
+    tags TEXT,
+    resolved INTEGER NOT NULL DEFAULT 0,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL
+);
+`
+
+// ErrRequestNotFound is returned when no audit request with the given ID exists
+var ErrRequestNotFound = errors.New("audit request not found")
+
+// GetAnnotation returns the annotation of a request, or nil if it has none
+func (d *Database) GetAnnotation(requestID string) (*types.Annotation, error) {
+	var a types.Annotation
+	var note, tags sql.NullString
+	err := d.sqlDB().QueryRow(`
+		SELECT note, tags, resolved, deleted, updated_at
+		FROM audit_annotations
+		WHERE request_id = ?
+	`, requestID).Scan(&note, &tags, &a.Resolved, &a.Deleted, &a.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+
+	a.Note = note.String
+	if tags.Valid {
+		json.Unmarshal([]byte(tags.String), &a.Tags)
+	}
+	return &a, nil
+}
+
+// SaveAnnotation creates or replaces the annotation of an existing request
+func (d *Database) SaveAnnotation(requestID string, a *types.Annotation) error {
+	var exists bool
+	if err := d.sqlDB().QueryRow("SELECT EXISTS(SELECT 1 FROM audit_requests WHERE request_id = ?)", requestID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up request: %w", err)
+	}
+	if !exists {
+		return ErrRequestNotFound
+	}
+
+	var tags interface{}
+	if len(a.Tags) > 0 {
+		encoded, err := json.Marshal(a.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		tags = string(encoded)
+	}
+
+	_, err := d.sqlDB().Exec(`
+		INSERT INTO audit_annotations (request_id, note, tags, resolved, deleted, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(request_id) DO UPDATE SET
+			note = excluded.note,
+			tags = excluded.tags,
+			resolved = excluded.resolved,
+			deleted = excluded.deleted,
+			updated_at = excluded.updated_at
+	`, requestID, nullIfEmpty(a.Note), tags, a.Resolved, a.Deleted, a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save annotation: %w", err)
+	}
+	return nil
+}
+
+// scanAnnotation builds the annotation of an audit_logs row, nil when it has none
+func scanAnnotation(note, tags sql.NullString, resolved bool, updatedAt sql.NullTime) *types.Annotation {
+	if !updatedAt.Valid {
+		return nil
+	}
+	a := &types.Annotation{Note: note.String, Resolved: resolved, UpdatedAt: updatedAt.Time}
+	if tags.Valid {
+		json.Unmarshal([]byte(tags.String), &a.Tags)
+	}
+	return a
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// batchSizeRanges are the size ranges batches are counted in, by largest size
+var batchSizeRanges = []struct {
+	max   int
+	label string
+}{
+	{2, "2"}, {5, "3-5"}, {10, "6-10"}, {50, "11-50"}, {100, "51-100"},
+}
+
+// getBatchStats summarizes the requests tagged with their batch size, nil before the first batch
+func (d *Database) getBatchStats() (*types.BatchStats, error) {
+	rows, err := d.readDB().Query(`
+		SELECT CAST(t.value AS INTEGER) AS size, COUNT(*), AVG(resp.process_time_ms),
+			AVG(CAST(resp.process_time_ms AS REAL) / CAST(t.value AS INTEGER))
+		FROM audit_tags t
+		LEFT JOIN audit_responses resp ON resp.request_id = t.request_id
+		WHERE t.name = ?
+		GROUP BY size`, types.BatchSizeTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch sizes: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &types.BatchStats{Sizes: make(map[string]int)}
+	var latencySum, callLatencySum float64
+	var timed int
+	for rows.Next() {
+		var size, count int
+		var latency, callLatency sql.NullFloat64
+		if err := rows.Scan(&size, &count, &latency, &callLatency); err != nil {
+			return nil, fmt.Errorf("failed to scan batch sizes: %w", err)
+		}
+		if size < 1 {
+			continue
+		}
+		stats.Batches += count
+		stats.Calls += size * count
+		stats.MaxSize = max(stats.MaxSize, size)
+		label := "101+"
+		for _, r := range batchSizeRanges {
+			if size <= r.max {
+				label = r.label
+				break
+			}
+		}
+		stats.Sizes[label] += count
+		if latency.Valid {
+			latencySum += latency.Float64 * float64(count)
+			callLatencySum += callLatency.Float64 * float64(count)
+			timed += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch sizes: %w", err)
+	}
+	if stats.Batches == 0 {
+		return nil, nil
+	}
+
+	stats.AvgSize = float64(stats.Calls) / float64(stats.Batches)
+	if timed > 0 {
+		stats.AvgLatencyMs = latencySum / float64(timed)
+		stats.AvgCallLatencyMs = callLatencySum / float64(timed)
+	}
+	return stats, nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetBillingUsage sums the calls, body bytes and upstream time of every API key
+// between from and to, or of a single key when apiKey is set. Only forwarded
+// calls are billed, as for quotas.
+func (d *Database) GetBillingUsage(apiKey string, from, to time.Time) ([]types.BillingUsage, error) {
+	const where = `
+		WHERE r.timestamp >= ? AND r.timestamp < ?
+		  AND r.api_key IS NOT NULL AND r.api_key != ''
+		  AND (? = '' OR r.api_key = ?)
+		  AND ` + forwardedCondition
+	args := []interface{}{from, to, apiKey, apiKey}
+
+	rows, err := d.readDB().Query(`
+		SELECT r.api_key, COALESCE(MAX(r.tenant), ''), COUNT(*),
+			COALESCE(SUM(r.request_bytes), 0),
+			COALESCE(SUM(resp.response_bytes), 0),
+			COALESCE(SUM(resp.upstream_time_ms), 0)
+		FROM audit_requests r
+		LEFT JOIN audit_responses resp ON r.request_id = resp.request_id`+where+`
+		GROUP BY r.api_key
+		ORDER BY r.api_key`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query billing usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []types.BillingUsage{}
+	byKey := make(map[string]int)
+	for rows.Next() {
+		u := types.BillingUsage{Methods: make(map[string]int64)}
+		if err := rows.Scan(&u.APIKey, &u.Tenant, &u.Calls, &u.RequestBytes, &u.ResponseBytes, &u.ComputeMs); err != nil {
+			return nil, fmt.Errorf("failed to scan billing usage: %w", err)
+		}
+		byKey[u.APIKey] = len(usage)
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read billing usage: %w", err)
+	}
+
+	methodRows, err := d.readDB().Query(`
+		SELECT r.api_key, r.method, COUNT(*)
+		FROM audit_requests r
+		LEFT JOIN audit_responses resp ON r.request_id = resp.request_id`+where+`
+		GROUP BY r.api_key, r.method`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query billed methods: %w", err)
+	}
+	defer methodRows.Close()
+
+	for methodRows.Next() {
+		var key, method string
+		var calls int64
+		if err := methodRows.Scan(&key, &method, &calls); err != nil {
+			return nil, fmt.Errorf("failed to scan billed methods: %w", err)
+		}
+		if i, ok := byKey[key]; ok {
+			usage[i].Methods[method] = calls
+		}
+	}
+	return usage, methodRows.Err()
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+const createSyncCheckpointsSQL = `
+-- Progress of resumable jobs that stream audit rows elsewhere
+CREATE TABLE IF NOT EXISTS sync_checkpoints (
+    name TEXT PRIMARY KEY,
+    last_id INTEGER NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// GetCheckpoint returns the last processed row id for a named job (0 if none)
+func (d *Database) GetCheckpoint(name string) (int64, error) {
+	var lastID int64
+	err := d.sqlDB().QueryRow("SELECT last_id FROM sync_checkpoints WHERE name = ?", name).Scan(&lastID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read checkpoint %s: %w", name, err)
+	}
+	return lastID, nil
+}
+
+// SetCheckpoint records the last processed row id for a named job
+func (d *Database) SetCheckpoint(name string, lastID int64) error {
+	_, err := d.sqlDB().Exec(`
+		INSERT INTO sync_checkpoints (name, last_id, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET last_id = excluded.last_id, updated_at = excluded.updated_at
+	`, name, lastID, d.now())
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteCheckpoint forgets the progress of a named job
+func (d *Database) DeleteCheckpoint(name string) error {
+	if _, err := d.sqlDB().Exec("DELETE FROM sync_checkpoints WHERE name = ?", name); err != nil {
+		return fmt.Errorf("failed to delete checkpoint %s: %w", name, err)
+	}
+	return nil
+}
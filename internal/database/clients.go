@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createClientsTableSQL = `
+-- API clients managed through the admin API
+CREATE TABLE IF NOT EXISTS clients (
+    name TEXT PRIMARY KEY,
+    key_hash TEXT NOT NULL UNIQUE,
+    key_prefix TEXT NOT NULL,
+    tenant TEXT,
+    policy TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+`
+
+// ErrClientNotFound is returned when no client with the given name exists
+var ErrClientNotFound = errors.New("client not found")
+
+const clientColumns = "name, key_hash, key_prefix, tenant, policy, created_at, updated_at"
+
+func scanClient(row rowScanner) (types.Client, error) {
+	var c types.Client
+	var tenant sql.NullString
+	var policy string
+	if err := row.Scan(&c.Name, &c.KeyHash, &c.KeyPrefix, &tenant, &policy, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return c, err
+	}
+	c.Tenant = tenant.String
+	if err := json.Unmarshal([]byte(policy), &c.ClientPolicy); err != nil {
+		return c, fmt.Errorf("failed to parse policy of client %s: %w", c.Name, err)
+	}
+	return c, nil
+}
+
+// ListClients returns all database-managed clients ordered by name
+func (d *Database) ListClients() ([]types.Client, error) {
+	rows, err := d.sqlDB().Query("SELECT " + clientColumns + " FROM clients ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []types.Client
+	for rows.Next() {
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan client: %w", err)
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// GetClient returns the client with the given name or ErrClientNotFound
+func (d *Database) GetClient(name string) (*types.Client, error) {
+	c, err := scanClient(d.sqlDB().QueryRow("SELECT "+clientColumns+" FROM clients WHERE name = ?", name))
+	if err == sql.ErrNoRows {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client %s: %w", name, err)
+	}
+	return &c, nil
+}
+
+// CreateClient stores a new client
+func (d *Database) CreateClient(c *types.Client) error {
+	policy, err := json.Marshal(c.ClientPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %w", err)
+	}
+
+	now := d.now()
+	_, err = d.sqlDB().Exec("INSERT INTO clients ("+clientColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+		c.Name, c.KeyHash, c.KeyPrefix, c.Tenant, string(policy), now, now)
+	if err != nil {
+		return fmt.Errorf("failed to insert client %s: %w", c.Name, err)
+	}
+	c.CreatedAt, c.UpdatedAt = now, now
+	return nil
+}
+
+// UpdateClient replaces the tenant, policy and key of an existing client
+func (d *Database) UpdateClient(c *types.Client) error {
+	policy, err := json.Marshal(c.ClientPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %w", err)
+	}
+
+	now := d.now()
+	result, err := d.sqlDB().Exec("UPDATE clients SET key_hash = ?, key_prefix = ?, tenant = ?, policy = ?, updated_at = ? WHERE name = ?",
+		c.KeyHash, c.KeyPrefix, c.Tenant, string(policy), now, c.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update client %s: %w", c.Name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrClientNotFound
+	}
+	c.UpdatedAt = now
+	return nil
+}
+
+// DeleteClient removes a client
+func (d *Database) DeleteClient(name string) error {
+	result, err := d.sqlDB().Exec("DELETE FROM clients WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete client %s: %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrClientNotFound
+	}
+	return nil
+}
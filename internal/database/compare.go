@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// WindowStats summarizes the responses completed in [from, to), overall and
+// per method. Percentiles are picked by rank in SQL, so only one row per
+// method is read however many calls the window holds.
+func (d *Database) WindowStats(from, to time.Time) (*types.WindowStats, map[string]*types.MethodWindowStats, error) {
+	// Ranks follow percentile: the ceil(p*n)-th smallest latency. Every
+	// overall percentile lands in exactly one method group, NULL in the others.
+	rows, err := d.readDB().Query(`
+		WITH calls AS (
+			SELECT r.method, resp.process_time_ms AS latency,
+				resp.error IS NOT NULL AND resp.error != '' AS failed
+			FROM audit_responses resp
+			JOIN audit_requests r ON r.request_id = resp.request_id
+			WHERE resp.timestamp >= ? AND resp.timestamp < ?
+		), ranked AS (
+			SELECT method, latency, failed,
+				ROW_NUMBER() OVER (ORDER BY latency) AS overall_rank,
+				COUNT(*) OVER () AS overall_n,
+				ROW_NUMBER() OVER (PARTITION BY method ORDER BY latency) AS method_rank,
+				COUNT(*) OVER (PARTITION BY method) AS method_n
+			FROM calls
+		)
+		SELECT method, COUNT(*), SUM(failed), SUM(latency),
+			MAX(CASE WHEN method_rank = CAST(0.95 * method_n + 0.999999 AS INTEGER) THEN latency END),
+			MAX(CASE WHEN overall_rank = CAST(0.50 * overall_n + 0.999999 AS INTEGER) THEN latency END),
+			MAX(CASE WHEN overall_rank = CAST(0.95 * overall_n + 0.999999 AS INTEGER) THEN latency END),
+			MAX(CASE WHEN overall_rank = CAST(0.99 * overall_n + 0.999999 AS INTEGER) THEN latency END)
+		FROM ranked
+		GROUP BY method`, from, to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query responses: %w", err)
+	}
+	defer rows.Close()
+
+	window := &types.WindowStats{From: from, To: to}
+	methods := make(map[string]*types.MethodWindowStats)
+	var total int64
+	for rows.Next() {
+		var method string
+		var sum, p95 int64
+		var p50, p95All, p99 sql.NullInt64
+		m := &types.MethodWindowStats{}
+		if err := rows.Scan(&method, &m.Calls, &m.Errors, &sum, &p95, &p50, &p95All, &p99); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan method stats: %w", err)
+		}
+		m.ErrorRate = float64(m.Errors) / float64(m.Calls) * 100
+		m.AvgMs = float64(sum) / float64(m.Calls)
+		m.P95Ms = float64(p95)
+		methods[method] = m
+
+		window.Calls += m.Calls
+		window.Errors += m.Errors
+		total += sum
+		if p50.Valid {
+			window.P50Ms = float64(p50.Int64)
+		}
+		if p95All.Valid {
+			window.P95Ms = float64(p95All.Int64)
+		}
+		if p99.Valid {
+			window.P99Ms = float64(p99.Int64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read method stats: %w", err)
+	}
+
+	if window.Calls > 0 {
+		window.ErrorRate = float64(window.Errors) / float64(window.Calls) * 100
+		window.AvgMs = float64(total) / float64(window.Calls)
+	}
+	return window, methods, nil
+}
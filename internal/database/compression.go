@@ -0,0 +1,93 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Bits of the compressed column recording which payload columns hold gzip data
+const (
+	compressedRequest    = 1 << iota // audit_requests.request
+	compressedHeaders                // audit_requests.headers
+	compressedExtensions             // audit_requests.extensions
+)
+
+// Bits of audit_responses.compressed
+const (
+	compressedResponse    = 1 << iota // audit_responses.response
+	compressedTransformed             // audit_responses.transformed_response
+	compressedDebug                   // audit_responses.debug
+)
+
+// SetCompression gzips request, response, and headers payloads of at least minBytes
+// before they are stored. Zero disables compression; existing rows stay readable either way.
+func (d *Database) SetCompression(minBytes int) {
+	d.compressMin = minBytes
+}
+
+// packPayload compresses a payload above the threshold and seals it when encryption
+// is enabled. It reports whether the stored value is compressed.
+func (d *Database) packPayload(data []byte) (interface{}, bool, error) {
+	compressed := false
+	if d.compressMin > 0 && len(data) >= d.compressMin {
+		packed, err := gzipBytes(data)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to compress payload: %w", err)
+		}
+		// Small or already dense bodies can grow, keep those as they are
+		if len(packed) < len(data) {
+			data = packed
+			compressed = true
+		}
+	}
+
+	if d.cipher != nil && len(data) > 0 {
+		sealed, err := d.cipher.Seal(data)
+		return sealed, compressed, err
+	}
+	if compressed {
+		// Store gzip data as a BLOB rather than TEXT with invalid UTF-8
+		return data, true, nil
+	}
+	return string(data), false, nil
+}
+
+// unpackPayload decrypts a payload read from the database and decompresses it
+// if it was stored compressed
+func (d *Database) unpackPayload(raw json.RawMessage, compressed bool) json.RawMessage {
+	raw = d.openPayload(raw)
+	if !compressed || len(raw) == 0 {
+		return raw
+	}
+
+	plain, err := gunzipBytes(raw)
+	if err != nil {
+		return json.RawMessage(strconv.Quote(fmt.Sprintf("failed to decompress payload: %v", err)))
+	}
+	return json.RawMessage(plain)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// LogCursor marks a position in the audit trail by the last request and response row ids seen
+type LogCursor struct {
+	RequestID  int64
+	ResponseID int64
+}
+
+// String encodes the cursor as "<request row id>-<response row id>"
+func (c LogCursor) String() string {
+	return fmt.Sprintf("%d-%d", c.RequestID, c.ResponseID)
+}
+
+// ParseLogCursor decodes a cursor produced by LogCursor.String
+func ParseLogCursor(s string) (LogCursor, error) {
+	var c LogCursor
+	if _, err := fmt.Sscanf(s, "%d-%d", &c.RequestID, &c.ResponseID); err != nil {
+		return c, fmt.Errorf("invalid cursor %q", s)
+	}
+	return c, nil
+}
+
+// CursorAt returns a cursor positioned just before the given time
+func (d *Database) CursorAt(t time.Time) (LogCursor, error) {
+	var c LogCursor
+	var reqID, respID sql.NullInt64
+
+	if err := d.sqlDB().QueryRow("SELECT MAX(id) FROM audit_requests WHERE timestamp < ?", t).Scan(&reqID); err != nil {
+		return c, fmt.Errorf("failed to locate request cursor: %w", err)
+	}
+	if err := d.sqlDB().QueryRow("SELECT MAX(id) FROM audit_responses WHERE timestamp < ?", t).Scan(&respID); err != nil {
+		return c, fmt.Errorf("failed to locate response cursor: %w", err)
+	}
+
+	c.RequestID, c.ResponseID = reqID.Int64, respID.Int64
+	return c, nil
+}
+
+// GetAuditLogsSince returns requests recorded after the cursor (logs) and earlier
+// requests whose response was recorded after it (updates), plus the cursor to
+// resume from. Delivery is at-least-once: a row may appear again as an update,
+// so consumers should upsert by request_id.
+func (d *Database) GetAuditLogsSince(cursor LogCursor, limit int) ([]types.AuditLog, []types.AuditLog, LogCursor, bool, error) {
+	next := cursor
+
+	logs, err := d.queryAuditLogs(`
+		SELECT `+auditLogColumns+`
+		FROM audit_logs
+		WHERE id > ?
+		ORDER BY id ASC
+		LIMIT ?
+	`, cursor.RequestID, limit)
+	if err != nil {
+		return nil, nil, cursor, false, fmt.Errorf("failed to query new audit logs: %w", err)
+	}
+
+	updates, err := d.queryAuditLogs(`
+		SELECT `+auditLogColumns+`
+		FROM audit_logs
+		WHERE id <= ? AND response_id > ?
+		ORDER BY response_id ASC
+		LIMIT ?
+	`, cursor.RequestID, cursor.ResponseID, limit)
+	if err != nil {
+		return nil, nil, cursor, false, fmt.Errorf("failed to query updated audit logs: %w", err)
+	}
+
+	hasMore := len(logs) == limit || len(updates) == limit
+
+	if len(logs) > 0 {
+		next.RequestID = logs[len(logs)-1].ID
+	}
+	for _, l := range updates {
+		if l.ResponseID > next.ResponseID {
+			next.ResponseID = l.ResponseID
+		}
+	}
+	// Skip responses already delivered with new logs, unless updates were cut off
+	// by the limit and smaller response ids are still pending
+	if len(updates) < limit {
+		for _, l := range logs {
+			if l.ResponseID > next.ResponseID {
+				next.ResponseID = l.ResponseID
+			}
+		}
+	}
+
+	return logs, updates, next, hasMore, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createDashboardsTableSQL = `
+-- Dashboard layouts defined by operators and rendered by the embedded UI
+CREATE TABLE IF NOT EXISTS dashboards (
+    name TEXT PRIMARY KEY,
+    title TEXT,
+    panels TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+`
+
+// ErrDashboardNotFound is returned when no dashboard with the given name exists
+var ErrDashboardNotFound = errors.New("dashboard not found")
+
+const dashboardColumns = "name, title, panels, created_at, updated_at"
+
+func scanDashboard(row rowScanner) (types.Dashboard, error) {
+	var d types.Dashboard
+	var title sql.NullString
+	var panels string
+	if err := row.Scan(&d.Name, &title, &panels, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return d, err
+	}
+	d.Title = title.String
+	if err := json.Unmarshal([]byte(panels), &d.Panels); err != nil {
+		return d, fmt.Errorf("failed to parse panels of dashboard %s: %w", d.Name, err)
+	}
+	return d, nil
+}
+
+// ListDashboards returns all dashboards ordered by name
+func (d *Database) ListDashboards() ([]types.Dashboard, error) {
+	rows, err := d.sqlDB().Query("SELECT " + dashboardColumns + " FROM dashboards ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboards: %w", err)
+	}
+	defer rows.Close()
+
+	var dashboards []types.Dashboard
+	for rows.Next() {
+		dashboard, err := scanDashboard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard: %w", err)
+		}
+		dashboards = append(dashboards, dashboard)
+	}
+	return dashboards, rows.Err()
+}
+
+// GetDashboard returns the dashboard with the given name or ErrDashboardNotFound
+func (d *Database) GetDashboard(name string) (*types.Dashboard, error) {
+	dashboard, err := scanDashboard(d.sqlDB().QueryRow("SELECT "+dashboardColumns+" FROM dashboards WHERE name = ?", name))
+	if err == sql.ErrNoRows {
+		return nil, ErrDashboardNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dashboard %s: %w", name, err)
+	}
+	return &dashboard, nil
+}
+
+// SaveDashboard creates a dashboard or replaces the title and panels of an
+// existing one, reporting whether it was created
+func (d *Database) SaveDashboard(dashboard *types.Dashboard) (bool, error) {
+	panels, err := json.Marshal(dashboard.Panels)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal panels: %w", err)
+	}
+
+	now := d.now()
+	result, err := d.sqlDB().Exec("UPDATE dashboards SET title = ?, panels = ?, updated_at = ? WHERE name = ?",
+		nullIfEmpty(dashboard.Title), string(panels), now, dashboard.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to update dashboard %s: %w", dashboard.Name, err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		existing, err := d.GetDashboard(dashboard.Name)
+		if err != nil {
+			return false, err
+		}
+		*dashboard = *existing
+		return false, nil
+	}
+
+	_, err = d.sqlDB().Exec("INSERT INTO dashboards ("+dashboardColumns+") VALUES (?, ?, ?, ?, ?)",
+		dashboard.Name, nullIfEmpty(dashboard.Title), string(panels), now, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert dashboard %s: %w", dashboard.Name, err)
+	}
+	dashboard.CreatedAt, dashboard.UpdatedAt = now, now
+	return true, nil
+}
+
+// DeleteDashboard removes a dashboard
+func (d *Database) DeleteDashboard(name string) error {
+	result, err := d.sqlDB().Exec("DELETE FROM dashboards WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete dashboard %s: %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDashboardNotFound
+	}
+	return nil
+}
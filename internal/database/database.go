@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
+	"math"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/niki4smirn/golf/internal/clock"
 	"github.com/niki4smirn/golf/internal/types"
 )
 
@@ -48,9 +51,13 @@ CREATE INDEX IF NOT EXISTS idx_audit_requests_request_id ON audit_requests(reque
 CREATE INDEX IF NOT EXISTS idx_audit_responses_timestamp ON audit_responses(timestamp);
 CREATE INDEX IF NOT EXISTS idx_audit_responses_request_id ON audit_responses(request_id);
 CREATE INDEX IF NOT EXISTS idx_audit_responses_status_code ON audit_responses(status_code);
+`
 
+// createViewSQL is recreated on every startup so the view picks up columns added by migrations
+const createViewSQL = `
 -- View for backward compatibility - combines requests and responses
-CREATE VIEW IF NOT EXISTS audit_logs AS
+DROP VIEW IF EXISTS audit_logs;
+CREATE VIEW audit_logs AS
 SELECT 
     r.id,
     r.timestamp,
@@ -60,74 +67,379 @@ SELECT
     r.user_agent,
     r.request,
     r.headers,
+    r.http_method,
+    r.upstream_url,
+    r.api_key,
+    r.tenant,
+    r.content_type,
+    r.body_encoding,
+    r.upstream_method,
+    r.audit_level,
+    r.body_hash,
+    r.fingerprint,
+    r.parent_request_id,
+    r.request_bytes,
+    r.env,
+    r.service,
+    r.version,
+    r.labels,
+    r.extensions,
     COALESCE(resp.response, '{}') as response,
     COALESCE(resp.status_code, 0) as status_code,
     COALESCE(resp.process_time_ms, 0) as process_time_ms,
-    resp.error
+    resp.error,
+    COALESCE(resp.malformed_upstream, 0) as malformed_upstream,
+    resp.rpc_error_code,
+    resp.id as response_id,
+    resp.content_type as response_content_type,
+    resp.body_encoding as response_body_encoding,
+    COALESCE(resp.queue_time_ms, 0) as queue_time_ms,
+    COALESCE(resp.upstream_time_ms, 0) as upstream_time_ms,
+    resp.failure_kind,
+    resp.served_by,
+    resp.cache_status,
+    COALESCE(resp.cache_age_ms, 0) as cache_age_ms,
+    COALESCE(resp.response_bytes, 0) as response_bytes,
+    resp.debug,
+    resp.dns_ms,
+    resp.connect_ms,
+    resp.tls_ms,
+    resp.ttfb_ms,
+    resp.read_ms,
+    resp.reused_conn,
+    resp.transformed_response,
+    resp.response_hash,
+    resp.metadata,
+    r.compressed as request_compressed,
+    COALESCE(resp.compressed, 0) as response_compressed,
+    a.note,
+    a.tags as annotation_tags,
+    COALESCE(a.resolved, 0) as resolved,
+    a.updated_at as annotated_at
 FROM audit_requests r
 LEFT JOIN audit_responses resp ON r.request_id = resp.request_id
+LEFT JOIN audit_annotations a ON r.request_id = a.request_id
+WHERE COALESCE(a.deleted, 0) = 0
 ORDER BY r.timestamp DESC;
 `
 
+// auxiliarySchemas create tables used by optional features
+var auxiliarySchemas = []string{
+	createTagsTableSQL,
+	createHeadersTableSQL,
+	createSyncCheckpointsSQL,
+	createClientsTableSQL,
+	createAnnotationsTableSQL,
+	createDeadLetterTableSQL,
+	createAdminActionsTableSQL,
+	createSchemaDriftTableSQL,
+	createDashboardsTableSQL,
+	createMCPSessionsTableSQL,
+}
+
+// columnMigration describes a column added after the initial schema
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations are applied on startup so existing audit databases gain new columns
+var columnMigrations = []columnMigration{
+	{"audit_requests", "http_method", "TEXT"},
+	{"audit_requests", "upstream_url", "TEXT"},
+	{"audit_requests", "api_key", "TEXT"},
+	{"audit_requests", "tenant", "TEXT"},
+	{"audit_responses", "malformed_upstream", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_responses", "rpc_error_code", "INTEGER"},
+	{"audit_requests", "content_type", "TEXT"},
+	{"audit_requests", "body_encoding", "TEXT"},
+	{"audit_requests", "upstream_method", "TEXT"},
+	{"audit_requests", "audit_level", "TEXT"},
+	{"audit_requests", "env", "TEXT"},
+	{"audit_requests", "service", "TEXT"},
+	{"audit_requests", "version", "TEXT"},
+	{"audit_requests", "labels", "TEXT"},
+	{"audit_requests", "body_hash", "TEXT"},
+	{"audit_requests", "fingerprint", "TEXT"},
+	{"audit_requests", "parent_request_id", "TEXT"},
+	{"audit_requests", "extensions", "TEXT"},
+	{"audit_responses", "content_type", "TEXT"},
+	{"audit_responses", "body_encoding", "TEXT"},
+	{"audit_responses", "queue_time_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_responses", "upstream_time_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_responses", "failure_kind", "TEXT"},
+	{"audit_responses", "served_by", "TEXT"},
+	{"audit_responses", "cache_status", "TEXT"},
+	{"audit_responses", "cache_age_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_requests", "compressed", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_responses", "compressed", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_requests", "request_bytes", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_responses", "response_bytes", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_responses", "debug", "TEXT"},
+	{"audit_responses", "dns_ms", "REAL"},
+	{"audit_responses", "connect_ms", "REAL"},
+	{"audit_responses", "tls_ms", "REAL"},
+	{"audit_responses", "ttfb_ms", "REAL"},
+	{"audit_responses", "read_ms", "REAL"},
+	{"audit_responses", "reused_conn", "INTEGER"},
+	{"audit_responses", "transformed_response", "TEXT"},
+	{"audit_responses", "response_hash", "TEXT"},
+	{"audit_responses", "metadata", "TEXT"},
+}
+
+// indexMigrations create indexes on migrated columns
+const indexMigrations = `
+CREATE INDEX IF NOT EXISTS idx_audit_requests_api_key ON audit_requests(api_key, timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_requests_tenant ON audit_requests(tenant, timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_requests_body_hash ON audit_requests(body_hash);
+CREATE INDEX IF NOT EXISTS idx_audit_requests_fingerprint ON audit_requests(fingerprint, timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_requests_parent ON audit_requests(parent_request_id);
+`
+
 // Database wraps the SQLite database connection
 type Database struct {
-	db *sql.DB
+	conn        atomic.Pointer[sql.DB] // Swapped when the file is rotated
+	cipher      *PayloadCipher
+	clock       clock.Clock     // Nil means the system clock
+	compressMin int             // Payloads of at least this many bytes are gzipped, 0 disables
+	pool        Pool            // Limits of the write pool, also applied to rotated files
+	reader      *sql.DB         // Serves heavy read queries when set, see OpenReader
+	archives    func() []string // Rotated files, newest first, searched by GetAuditLog; set by OpenRotating
 }
 
 // New creates a new database connection and initializes tables
 func New(dbPath string) (*Database, error) {
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	d := &Database{}
+	d.conn.Store(db)
+	return d, nil
+}
+
+// sqlDB returns the connection pool of the active database file
+func (d *Database) sqlDB() *sql.DB {
+	return d.conn.Load()
+}
+
+// SetClock replaces the time source of the store, e.g. with a clock.Fake in tests
+func (d *Database) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
+func (d *Database) now() time.Time {
+	if d.clock == nil {
+		return time.Now()
+	}
+	return d.clock.Now()
+}
+
+// openSQLite opens a database file and brings its schema up to date. The
+// connections are closed again on failure, so opening may be retried.
+func openSQLite(dbPath string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	if err := initSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// initSQLite sets up a freshly opened database
+func initSQLite(db *sql.DB) error {
+	// Let maintenance return free pages to the filesystem (only effective on new databases)
+	if _, err := db.Exec("PRAGMA auto_vacuum = INCREMENTAL;"); err != nil {
+		return fmt.Errorf("failed to enable incremental vacuum: %w", err)
+	}
 
 	// Enable WAL mode for better concurrent read performance
 	if _, err := db.Exec("PRAGMA journal_mode=WAL;"); err != nil {
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+		return fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
 	// Create tables and indexes
 	if _, err := db.Exec(createTableSQL); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	// Create auxiliary tables
+	for _, schema := range auxiliarySchemas {
+		if _, err := db.Exec(schema); err != nil {
+			return fmt.Errorf("failed to create auxiliary tables: %w", err)
+		}
+	}
+
+	// Add columns introduced after the initial schema
+	for _, m := range columnMigrations {
+		if err := ensureColumn(db, m); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec(indexMigrations); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	// Recreate the combined view
+	if _, err := db.Exec(createViewSQL); err != nil {
+		return fmt.Errorf("failed to create views: %w", err)
+	}
+
+	return nil
+}
+
+// ensureColumn adds a column to a table if it does not exist yet
+func ensureColumn(db *sql.DB, m columnMigration) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", m.table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", m.table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == m.column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", m.table, err)
 	}
 
-	return &Database{db: db}, nil
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", m.table, m.column, err)
+	}
+	return nil
 }
 
 // Close closes the database connection
 func (d *Database) Close() error {
-	return d.db.Close()
+	if d.reader != nil {
+		d.reader.Close()
+	}
+	return d.sqlDB().Close()
 }
 
 // InsertAuditRequest inserts a new audit request entry immediately when request is received
 func (d *Database) InsertAuditRequest(req *types.AuditRequest) error {
+	return d.insertAuditRequest(d.sqlDB(), req)
+}
+
+func (d *Database) insertAuditRequest(exec execer, req *types.AuditRequest) error {
 	query := `
 		INSERT INTO audit_requests (
-			timestamp, method, request_id, ip_address, user_agent, request, headers
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
+			timestamp, method, request_id, ip_address, user_agent, request, headers,
+			http_method, upstream_url, api_key, tenant, content_type, body_encoding,
+			upstream_method, audit_level, env, service, version, labels, body_hash, fingerprint,
+			parent_request_id, request_bytes, extensions, compressed
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	requestJSON, err := json.Marshal(req.Request)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+	// Bodies that are not valid JSON are kept as a JSON string, omitted bodies as null
+	requestJSON := []byte(req.Request)
+	var err error
+	if req.Request == nil {
+		requestJSON = []byte("null")
+	} else if !json.Valid(requestJSON) {
+		requestJSON, err = json.Marshal(string(req.Request))
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
 	}
 
+	// Headers are already JSON, re-marshaling them would only copy the bytes
 	var headersJSON []byte
 	if req.Headers != nil {
-		headersJSON, err = json.Marshal(req.Headers)
+		if !json.Valid(req.Headers) {
+			return fmt.Errorf("failed to marshal headers: invalid JSON")
+		}
+		headersJSON = req.Headers
+	}
+
+	var labelsValue interface{}
+	if len(req.Labels) > 0 {
+		labelsJSON, err := json.Marshal(req.Labels)
 		if err != nil {
-			return fmt.Errorf("failed to marshal headers: %w", err)
+			return fmt.Errorf("failed to marshal labels: %w", err)
 		}
+		labelsValue = string(labelsJSON)
+	}
+
+	requestValue, requestCompressed, err := d.packPayload(requestJSON)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	headersValue, headersCompressed, err := d.packPayload(headersJSON)
+	if err != nil {
+		return fmt.Errorf("failed to encode headers: %w", err)
+	}
+
+	// Extensions may carry credentials, they are sealed like the headers
+	var extensionsValue interface{}
+	extensionsCompressed := false
+	if len(req.Extensions) > 0 {
+		if !json.Valid(req.Extensions) {
+			return fmt.Errorf("failed to marshal extensions: invalid JSON")
+		}
+		if extensionsValue, extensionsCompressed, err = d.packPayload(req.Extensions); err != nil {
+			return fmt.Errorf("failed to encode extensions: %w", err)
+		}
+	}
+
+	compressed := 0
+	if requestCompressed {
+		compressed |= compressedRequest
+	}
+	if headersCompressed {
+		compressed |= compressedHeaders
+	}
+	if extensionsCompressed {
+		compressed |= compressedExtensions
 	}
 
-	result, err := d.db.Exec(query,
+	result, err := exec.Exec(query,
 		req.Timestamp,
 		req.Method,
 		req.RequestID,
 		req.IPAddress,
 		req.UserAgent,
-		string(requestJSON),
-		string(headersJSON),
+		requestValue,
+		headersValue,
+		req.HTTPMethod,
+		req.UpstreamURL,
+		req.APIKey,
+		req.Tenant,
+		req.ContentType,
+		req.BodyEncoding,
+		req.UpstreamMethod,
+		req.AuditLevel,
+		req.Env,
+		req.Service,
+		req.Version,
+		labelsValue,
+		nullIfEmpty(req.BodyHash),
+		nullIfEmpty(req.Fingerprint),
+		nullIfEmpty(req.ParentRequestID),
+		req.RequestBytes,
+		extensionsValue,
+		compressed,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert audit request: %w", err)
@@ -139,68 +451,128 @@ func (d *Database) InsertAuditRequest(req *types.AuditRequest) error {
 	}
 
 	req.ID = id
-	return nil
-}
-
-// unwrapSSEResponse removes SSE wrapper from response data
-func unwrapSSEResponse(data []byte) []byte {
-	dataStr := string(data)
-
-	// Check if it's SSE format (starts with "event:" or "data:")
-	if !strings.HasPrefix(dataStr, "event:") && !strings.HasPrefix(dataStr, "data:") {
-		// Not SSE format, return as-is
-		return data
-	}
-
-	// Split by lines and extract JSON from data: lines
-	lines := strings.Split(dataStr, "\n")
-	var jsonData strings.Builder
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
 
-		// Extract data from "data: " lines
-		if strings.HasPrefix(line, "data: ") {
-			jsonContent := strings.TrimPrefix(line, "data: ")
-			jsonData.WriteString(jsonContent)
-		}
+	if err := insertTags(exec, req.RequestID, req.Tags); err != nil {
+		return err
 	}
-
-	result := jsonData.String()
-
-	// If no data was found, return original
-	if result == "" {
-		return data
+	if err := insertIndexedHeaders(exec, req.RequestID, req.IndexedHeaders); err != nil {
+		return err
 	}
-
-	return []byte(result)
+	return nil
 }
 
 // InsertAuditResponse inserts a response entry linked to a request
 func (d *Database) InsertAuditResponse(resp *types.AuditResponse) error {
+	return d.insertAuditResponse(d.sqlDB(), resp)
+}
+
+func (d *Database) insertAuditResponse(exec execer, resp *types.AuditResponse) error {
+	// A late answer replaces the placeholder written by the orphan resolver
+	if resp.FailureKind != types.FailureUnresolved {
+		_, err := exec.Exec("DELETE FROM audit_responses WHERE request_id = ? AND failure_kind = ?", resp.RequestID, types.FailureUnresolved)
+		if err != nil {
+			return fmt.Errorf("failed to replace unresolved response: %w", err)
+		}
+	}
+
 	query := `
 		INSERT INTO audit_responses (
-			request_id, timestamp, response, status_code, process_time_ms, error
-		) VALUES (?, ?, ?, ?, ?, ?)
+			request_id, timestamp, response, status_code, process_time_ms, error,
+			malformed_upstream, rpc_error_code, content_type, body_encoding,
+			queue_time_ms, upstream_time_ms, failure_kind, served_by, cache_status, cache_age_ms,
+			response_bytes, debug, dns_ms, connect_ms, tls_ms, ttfb_ms, read_ms, reused_conn,
+			transformed_response, response_hash, metadata, compressed
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	var responseJSON []byte
 	if resp.Response != nil {
 		var err error
-		withoutSSE := unwrapSSEResponse(resp.Response)
-		responseJSON, err = json.Marshal(withoutSSE)
+		withoutSSE := types.UnwrapSSE(resp.Response)
+		if json.Valid(withoutSSE) {
+			responseJSON = withoutSSE
+		} else {
+			// Keep malformed upstream bodies readable as a JSON string
+			responseJSON, err = json.Marshal(string(withoutSSE))
+		}
 		if err != nil {
 			return fmt.Errorf("failed to marshal response: %w (%s)", err, resp.Response)
 		}
 	}
 
-	result, err := d.db.Exec(query,
+	responseValue, responseCompressed, err := d.packPayload(responseJSON)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	compressed := 0
+	if responseCompressed {
+		compressed |= compressedResponse
+	}
+
+	var transformedValue interface{}
+	if len(resp.TransformedResponse) > 0 {
+		value, transformedCompressed, err := d.packPayload(resp.TransformedResponse)
+		if err != nil {
+			return fmt.Errorf("failed to encode transformed response: %w", err)
+		}
+		transformedValue = value
+		if transformedCompressed {
+			compressed |= compressedTransformed
+		}
+	}
+
+	// Debug captures hold full request and response headers
+	var debugValue interface{}
+	if len(resp.Debug) > 0 {
+		value, debugCompressed, err := d.packPayload(resp.Debug)
+		if err != nil {
+			return fmt.Errorf("failed to encode debug capture: %w", err)
+		}
+		debugValue = value
+		if debugCompressed {
+			compressed |= compressedDebug
+		}
+	}
+
+	var dns, connect, tlsMs, ttfb, read, reused interface{}
+	if t := resp.Timing; t != nil {
+		dns, connect, tlsMs, ttfb, read, reused = t.DNSMs, t.ConnectMs, t.TLSMs, t.TTFBMs, t.ReadMs, t.ReusedConn
+	}
+
+	var metadata interface{}
+	if len(resp.Metadata) > 0 {
+		data, err := json.Marshal(resp.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		metadata = string(data)
+	}
+
+	result, err := exec.Exec(query,
 		resp.RequestID,
 		resp.Timestamp,
-		string(responseJSON),
+		responseValue,
 		resp.StatusCode,
 		resp.ProcessTime,
 		resp.Error,
+		resp.MalformedUpstream,
+		resp.RPCErrorCode,
+		resp.ContentType,
+		resp.BodyEncoding,
+		resp.QueueTime,
+		resp.UpstreamTime,
+		nullIfEmpty(resp.FailureKind),
+		nullIfEmpty(resp.ServedBy),
+		nullIfEmpty(resp.Cache),
+		resp.CacheAgeMs,
+		resp.ResponseBytes,
+		debugValue,
+		dns, connect, tlsMs, ttfb, read, reused,
+		transformedValue,
+		nullIfEmpty(resp.ResponseHash),
+		metadata,
+		compressed,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert audit response: %w", err)
@@ -212,6 +584,17 @@ func (d *Database) InsertAuditResponse(resp *types.AuditResponse) error {
 	}
 
 	resp.ID = id
+
+	tags := make(map[string]string)
+	if resp.Slow {
+		tags[types.SlowTag] = "true"
+	}
+	if resp.PII != "" {
+		tags[types.PIIResponseTag] = resp.PII
+	}
+	if err := insertTags(exec, resp.RequestID, tags); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -219,13 +602,29 @@ func (d *Database) InsertAuditResponse(resp *types.AuditResponse) error {
 func (d *Database) InsertAuditLog(log *types.AuditLog) error {
 	// Insert request first
 	req := &types.AuditRequest{
-		Timestamp: log.Timestamp,
-		Method:    log.Method,
-		RequestID: log.RequestID,
-		IPAddress: log.IPAddress,
-		UserAgent: log.UserAgent,
-		Request:   log.Request,
-		Headers:   log.Headers,
+		Timestamp:      log.Timestamp,
+		Method:         log.Method,
+		RequestID:      log.RequestID,
+		IPAddress:      log.IPAddress,
+		UserAgent:      log.UserAgent,
+		Request:        log.Request,
+		Headers:        log.Headers,
+		HTTPMethod:     log.HTTPMethod,
+		UpstreamURL:    log.UpstreamURL,
+		APIKey:         log.APIKey,
+		Tenant:         log.Tenant,
+		Tags:           log.Tags,
+		ContentType:    log.ContentType,
+		BodyEncoding:   log.BodyEncoding,
+		UpstreamMethod: log.UpstreamMethod,
+		AuditLevel:     log.AuditLevel,
+		BodyHash:       log.BodyHash,
+		Fingerprint:    log.Fingerprint,
+		Deployment:     log.Deployment,
+
+		ParentRequestID: log.ParentRequestID,
+		RequestBytes:    log.RequestBytes,
+		Extensions:      log.Extensions,
 	}
 
 	if err := d.InsertAuditRequest(req); err != nil {
@@ -235,12 +634,29 @@ func (d *Database) InsertAuditLog(log *types.AuditLog) error {
 	// Insert response if we have status code or response data
 	if log.StatusCode > 0 || log.Response != nil || log.Error != "" {
 		resp := &types.AuditResponse{
-			RequestID:   log.RequestID,
-			Timestamp:   log.Timestamp,
-			Response:    log.Response,
-			StatusCode:  log.StatusCode,
-			ProcessTime: log.ProcessTime,
-			Error:       log.Error,
+			RequestID:         log.RequestID,
+			Timestamp:         log.Timestamp,
+			Response:          log.Response,
+			StatusCode:        log.StatusCode,
+			ProcessTime:       log.ProcessTime,
+			Error:             log.Error,
+			MalformedUpstream: log.MalformedUpstream,
+			RPCErrorCode:      log.RPCErrorCode,
+			ContentType:       log.ResponseContentType,
+			BodyEncoding:      log.ResponseBodyEncoding,
+			QueueTime:         log.QueueTime,
+			UpstreamTime:      log.UpstreamTime,
+			FailureKind:       log.FailureKind,
+			ServedBy:          log.ServedBy,
+			Cache:             log.Cache,
+			CacheAgeMs:        log.CacheAgeMs,
+			ResponseBytes:     log.ResponseBytes,
+			Debug:             log.Debug,
+			Timing:            log.Timing,
+
+			TransformedResponse: log.TransformedResponse,
+			ResponseHash:        log.ResponseHash,
+			Metadata:            log.Metadata,
 		}
 
 		if err := d.InsertAuditResponse(resp); err != nil {
@@ -251,49 +667,447 @@ func (d *Database) InsertAuditLog(log *types.AuditLog) error {
 	return nil
 }
 
-// GetAuditRequests retrieves audit requests with pagination
-func (d *Database) GetAuditRequests(limit, offset int) ([]types.AuditRequest, error) {
-	query := `
-		SELECT id, timestamp, method, request_id, ip_address, user_agent, request, headers
-		FROM audit_requests
-		ORDER BY timestamp DESC
-		LIMIT ? OFFSET ?
-	`
+// auditRequestColumns lists the audit_requests columns read by scanAuditRequest
+const auditRequestColumns = `id, timestamp, method, request_id, ip_address, user_agent, request, headers,
+	http_method, upstream_url, api_key, tenant, content_type, body_encoding, upstream_method,
+	audit_level, env, service, version, labels, body_hash, fingerprint, parent_request_id, request_bytes, extensions, compressed`
+
+// auditResponseColumns lists the audit_responses columns read by scanAuditResponse
+const auditResponseColumns = `id, request_id, timestamp, response, status_code, process_time_ms, error,
+	malformed_upstream, rpc_error_code, content_type, body_encoding, queue_time_ms, upstream_time_ms,
+	failure_kind, served_by, cache_status, cache_age_ms, response_bytes, debug,
+	dns_ms, connect_ms, tls_ms, ttfb_ms, read_ms, reused_conn, transformed_response, response_hash, metadata, compressed`
+
+// auditLogColumns lists the audit_logs view columns read by scanAuditLog
+const auditLogColumns = `id, timestamp, method, request_id, ip_address, user_agent,
+	request, headers, http_method, upstream_url, api_key, tenant, content_type, body_encoding, upstream_method,
+	audit_level, env, service, version, labels, body_hash, fingerprint, parent_request_id, request_bytes, extensions, response, status_code, process_time_ms, error, malformed_upstream, rpc_error_code, response_id,
+	response_content_type, response_body_encoding, queue_time_ms, upstream_time_ms, failure_kind, served_by,
+	cache_status, cache_age_ms, response_bytes, debug, dns_ms, connect_ms, tls_ms, ttfb_ms, read_ms, reused_conn, transformed_response, response_hash, metadata, request_compressed, response_compressed, note, annotation_tags, resolved, annotated_at`
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
 
-	rows, err := d.db.Query(query, limit, offset)
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAuditRequest reads a row selected with auditRequestColumns along with its compressed bits
+func scanAuditRequest(row rowScanner) (types.AuditRequest, int, error) {
+	var req types.AuditRequest
+	var compressed int
+	var requestStr, headersStr, httpMethodStr, upstreamURLStr, apiKeyStr, tenantStr sql.NullString
+	var contentTypeStr, encodingStr, upstreamMethodStr, auditLevelStr sql.NullString
+	var envStr, serviceStr, versionStr, labelsStr, bodyHashStr, fingerprintStr, parentStr, extensionsStr sql.NullString
+
+	err := row.Scan(
+		&req.ID,
+		&req.Timestamp,
+		&req.Method,
+		&req.RequestID,
+		&req.IPAddress,
+		&req.UserAgent,
+		&requestStr,
+		&headersStr,
+		&httpMethodStr,
+		&upstreamURLStr,
+		&apiKeyStr,
+		&tenantStr,
+		&contentTypeStr,
+		&encodingStr,
+		&upstreamMethodStr,
+		&auditLevelStr,
+		&envStr,
+		&serviceStr,
+		&versionStr,
+		&labelsStr,
+		&bodyHashStr,
+		&fingerprintStr,
+		&parentStr,
+		&req.RequestBytes,
+		&extensionsStr,
+		&compressed,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query audit requests: %w", err)
+		return req, 0, err
+	}
+
+	if requestStr.Valid {
+		req.Request = json.RawMessage(requestStr.String)
+	}
+
+	if headersStr.Valid {
+		req.Headers = json.RawMessage(headersStr.String)
+	}
+
+	if extensionsStr.Valid {
+		req.Extensions = json.RawMessage(extensionsStr.String)
+	}
+
+	req.HTTPMethod = httpMethodStr.String
+	req.UpstreamURL = upstreamURLStr.String
+	req.APIKey = apiKeyStr.String
+	req.Tenant = tenantStr.String
+	req.ContentType = contentTypeStr.String
+	req.BodyEncoding = encodingStr.String
+	req.UpstreamMethod = upstreamMethodStr.String
+	req.AuditLevel = auditLevelStr.String
+	req.BodyHash = bodyHashStr.String
+	req.Fingerprint = fingerprintStr.String
+	req.ParentRequestID = parentStr.String
+	req.Deployment = scanDeployment(envStr, serviceStr, versionStr, labelsStr)
+
+	return req, compressed, nil
+}
+
+// scanDeployment builds the deployment metadata of a request row
+func scanDeployment(env, service, version, labels sql.NullString) types.Deployment {
+	deployment := types.Deployment{Env: env.String, Service: service.String, Version: version.String}
+	if labels.Valid {
+		json.Unmarshal([]byte(labels.String), &deployment.Labels)
+	}
+	return deployment
+}
+
+// scanMetadata decodes the metadata column of a response row
+func scanMetadata(metadata sql.NullString) map[string]string {
+	if !metadata.Valid {
+		return nil
+	}
+	var values map[string]string
+	json.Unmarshal([]byte(metadata.String), &values)
+	return values
+}
+
+// timingColumns holds the nullable upstream timing columns of a response row
+type timingColumns struct {
+	dns, connect, tls, ttfb, read sql.NullFloat64
+	reused                        sql.NullBool
+}
+
+// upstreamTiming returns the timing of the row, nil for calls that were not timed
+func (c timingColumns) upstreamTiming() *types.UpstreamTiming {
+	if !c.ttfb.Valid {
+		return nil
+	}
+	return &types.UpstreamTiming{
+		DNSMs:      c.dns.Float64,
+		ConnectMs:  c.connect.Float64,
+		TLSMs:      c.tls.Float64,
+		TTFBMs:     c.ttfb.Float64,
+		ReadMs:     c.read.Float64,
+		ReusedConn: c.reused.Bool,
+	}
+}
+
+// scanAuditResponse reads a row selected with auditResponseColumns along with its compressed bits
+func scanAuditResponse(row rowScanner) (types.AuditResponse, int, error) {
+	var resp types.AuditResponse
+	var compressed int
+	var responseStr, errorStr, contentTypeStr, encodingStr, failureKindStr, servedByStr, cacheStr sql.NullString
+	var debugStr, transformedStr, responseHashStr, metadataStr sql.NullString
+	var rpcErrorCode sql.NullInt64
+	var timing timingColumns
+
+	err := row.Scan(
+		&resp.ID,
+		&resp.RequestID,
+		&resp.Timestamp,
+		&responseStr,
+		&resp.StatusCode,
+		&resp.ProcessTime,
+		&errorStr,
+		&resp.MalformedUpstream,
+		&rpcErrorCode,
+		&contentTypeStr,
+		&encodingStr,
+		&resp.QueueTime,
+		&resp.UpstreamTime,
+		&failureKindStr,
+		&servedByStr,
+		&cacheStr,
+		&resp.CacheAgeMs,
+		&resp.ResponseBytes,
+		&debugStr,
+		&timing.dns, &timing.connect, &timing.tls, &timing.ttfb, &timing.read, &timing.reused,
+		&transformedStr,
+		&responseHashStr,
+		&metadataStr,
+		&compressed,
+	)
+	if err != nil {
+		return resp, 0, err
+	}
+
+	resp.ContentType = contentTypeStr.String
+	resp.BodyEncoding = encodingStr.String
+	resp.FailureKind = failureKindStr.String
+	resp.ServedBy = servedByStr.String
+	resp.Cache = cacheStr.String
+
+	if rpcErrorCode.Valid {
+		code := int(rpcErrorCode.Int64)
+		resp.RPCErrorCode = &code
+	}
+
+	if responseStr.Valid {
+		resp.Response = json.RawMessage(responseStr.String)
+	}
+
+	if errorStr.Valid {
+		resp.Error = errorStr.String
+	}
+
+	if debugStr.Valid {
+		resp.Debug = json.RawMessage(debugStr.String)
+	}
+	resp.Timing = timing.upstreamTiming()
+
+	if transformedStr.Valid {
+		resp.TransformedResponse = json.RawMessage(transformedStr.String)
+	}
+	resp.ResponseHash = responseHashStr.String
+	resp.Metadata = scanMetadata(metadataStr)
+
+	return resp, compressed, nil
+}
+
+// scanAuditLog reads a row selected with auditLogColumns along with the request and response compressed bits
+func scanAuditLog(row rowScanner) (types.AuditLog, int, int, error) {
+	var log types.AuditLog
+	var requestCompressed, responseCompressed int
+	var requestStr, headersStr, responseStr, errorStr sql.NullString
+	var httpMethodStr, upstreamURLStr, apiKeyStr, tenantStr sql.NullString
+	var contentTypeStr, encodingStr, upstreamMethodStr, auditLevelStr sql.NullString
+	var envStr, serviceStr, versionStr, labelsStr, bodyHashStr, fingerprintStr, parentStr, extensionsStr sql.NullString
+	var responseContentTypeStr, responseEncodingStr, failureKindStr, servedByStr, cacheStr sql.NullString
+	var rpcErrorCode, responseID sql.NullInt64
+	var noteStr, annotationTagsStr, debugStr, transformedStr, responseHashStr, metadataStr sql.NullString
+	var timing timingColumns
+	var resolved bool
+	var annotatedAt sql.NullTime
+
+	err := row.Scan(
+		&log.ID,
+		&log.Timestamp,
+		&log.Method,
+		&log.RequestID,
+		&log.IPAddress,
+		&log.UserAgent,
+		&requestStr,
+		&headersStr,
+		&httpMethodStr,
+		&upstreamURLStr,
+		&apiKeyStr,
+		&tenantStr,
+		&contentTypeStr,
+		&encodingStr,
+		&upstreamMethodStr,
+		&auditLevelStr,
+		&envStr,
+		&serviceStr,
+		&versionStr,
+		&labelsStr,
+		&bodyHashStr,
+		&fingerprintStr,
+		&parentStr,
+		&log.RequestBytes,
+		&extensionsStr,
+		&responseStr,
+		&log.StatusCode,
+		&log.ProcessTime,
+		&errorStr,
+		&log.MalformedUpstream,
+		&rpcErrorCode,
+		&responseID,
+		&responseContentTypeStr,
+		&responseEncodingStr,
+		&log.QueueTime,
+		&log.UpstreamTime,
+		&failureKindStr,
+		&servedByStr,
+		&cacheStr,
+		&log.CacheAgeMs,
+		&log.ResponseBytes,
+		&debugStr,
+		&timing.dns, &timing.connect, &timing.tls, &timing.ttfb, &timing.read, &timing.reused,
+		&transformedStr,
+		&responseHashStr,
+		&metadataStr,
+		&requestCompressed,
+		&responseCompressed,
+		&noteStr,
+		&annotationTagsStr,
+		&resolved,
+		&annotatedAt,
+	)
+	if err != nil {
+		return log, 0, 0, err
+	}
+
+	log.ResponseID = responseID.Int64
+	log.ContentType = contentTypeStr.String
+	log.BodyEncoding = encodingStr.String
+	log.UpstreamMethod = upstreamMethodStr.String
+	log.AuditLevel = auditLevelStr.String
+	log.BodyHash = bodyHashStr.String
+	log.Fingerprint = fingerprintStr.String
+	log.ParentRequestID = parentStr.String
+	log.Deployment = scanDeployment(envStr, serviceStr, versionStr, labelsStr)
+	log.ResponseContentType = responseContentTypeStr.String
+	log.ResponseBodyEncoding = responseEncodingStr.String
+	log.FailureKind = failureKindStr.String
+	log.ServedBy = servedByStr.String
+	log.Cache = cacheStr.String
+	log.Annotation = scanAnnotation(noteStr, annotationTagsStr, resolved, annotatedAt)
+
+	if rpcErrorCode.Valid {
+		code := int(rpcErrorCode.Int64)
+		log.RPCErrorCode = &code
+	}
+
+	if requestStr.Valid {
+		log.Request = json.RawMessage(requestStr.String)
+	}
+
+	if headersStr.Valid {
+		log.Headers = json.RawMessage(headersStr.String)
+	}
+
+	if extensionsStr.Valid {
+		log.Extensions = json.RawMessage(extensionsStr.String)
+	}
+
+	if responseStr.Valid {
+		log.Response = json.RawMessage(responseStr.String)
+	}
+
+	if debugStr.Valid {
+		log.Debug = json.RawMessage(debugStr.String)
+	}
+	log.Timing = timing.upstreamTiming()
+
+	if transformedStr.Valid {
+		log.TransformedResponse = json.RawMessage(transformedStr.String)
+	}
+	log.ResponseHash = responseHashStr.String
+	log.Metadata = scanMetadata(metadataStr)
+
+	if errorStr.Valid {
+		log.Error = errorStr.String
+	}
+
+	log.HTTPMethod = httpMethodStr.String
+	log.UpstreamURL = upstreamURLStr.String
+	log.APIKey = apiKeyStr.String
+	log.Tenant = tenantStr.String
+
+	return log, requestCompressed, responseCompressed, nil
+}
+
+// queryAuditRequests runs a query selecting auditRequestColumns and scans all rows
+func (d *Database) queryAuditRequests(query string, args ...interface{}) ([]types.AuditRequest, error) {
+	rows, err := d.sqlDB().Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
 	var requests []types.AuditRequest
 	for rows.Next() {
-		var req types.AuditRequest
-		var requestStr, headersStr sql.NullString
-
-		err := rows.Scan(
-			&req.ID,
-			&req.Timestamp,
-			&req.Method,
-			&req.RequestID,
-			&req.IPAddress,
-			&req.UserAgent,
-			&requestStr,
-			&headersStr,
-		)
+		req, compressed, err := scanAuditRequest(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		req.Request = d.unpackPayload(req.Request, compressed&compressedRequest != 0)
+		req.Headers = d.unpackPayload(req.Headers, compressed&compressedHeaders != 0)
+		req.Extensions = d.unpackPayload(req.Extensions, compressed&compressedExtensions != 0)
+		requests = append(requests, req)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return requests, nil
+}
+
+// queryAuditResponses runs a query selecting auditResponseColumns and scans all rows
+func (d *Database) queryAuditResponses(query string, args ...interface{}) ([]types.AuditResponse, error) {
+	rows, err := d.sqlDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		if requestStr.Valid {
-			req.Request = json.RawMessage(requestStr.String)
+	var responses []types.AuditResponse
+	for rows.Next() {
+		resp, compressed, err := scanAuditResponse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		resp.Response = d.unpackPayload(resp.Response, compressed&compressedResponse != 0)
+		resp.TransformedResponse = d.unpackPayload(resp.TransformedResponse, compressed&compressedTransformed != 0)
+		resp.Debug = d.unpackPayload(resp.Debug, compressed&compressedDebug != 0)
+		responses = append(responses, resp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return responses, nil
+}
 
-		if headersStr.Valid {
-			req.Headers = json.RawMessage(headersStr.String)
+// queryAuditLogs runs a query selecting auditLogColumns and scans all rows
+func (d *Database) queryAuditLogs(query string, args ...interface{}) ([]types.AuditLog, error) {
+	rows, err := d.sqlDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []types.AuditLog
+	for rows.Next() {
+		log, requestCompressed, responseCompressed, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		log.Request = d.unpackPayload(log.Request, requestCompressed&compressedRequest != 0)
+		log.Headers = d.unpackPayload(log.Headers, requestCompressed&compressedHeaders != 0)
+		log.Extensions = d.unpackPayload(log.Extensions, requestCompressed&compressedExtensions != 0)
+		log.Response = d.unpackPayload(log.Response, responseCompressed&compressedResponse != 0)
+		log.TransformedResponse = d.unpackPayload(log.TransformedResponse, responseCompressed&compressedTransformed != 0)
+		log.Debug = d.unpackPayload(log.Debug, responseCompressed&compressedDebug != 0)
+		logs = append(logs, log)
+	}
 
-		requests = append(requests, req)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	if err := d.attachTags(logs); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// GetAuditRequests retrieves audit requests with pagination
+func (d *Database) GetAuditRequests(limit, offset int) ([]types.AuditRequest, error) {
+	query := `
+		SELECT ` + auditRequestColumns + `
+		FROM audit_requests
+		ORDER BY timestamp DESC
+		LIMIT ? OFFSET ?
+	`
+
+	requests, err := d.queryAuditRequests(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit requests: %w", err)
 	}
 
 	return requests, nil
@@ -302,46 +1116,16 @@ func (d *Database) GetAuditRequests(limit, offset int) ([]types.AuditRequest, er
 // GetAuditResponses retrieves audit responses with pagination
 func (d *Database) GetAuditResponses(limit, offset int) ([]types.AuditResponse, error) {
 	query := `
-		SELECT id, request_id, timestamp, response, status_code, process_time_ms, error
+		SELECT ` + auditResponseColumns + `
 		FROM audit_responses
 		ORDER BY timestamp DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := d.db.Query(query, limit, offset)
+	responses, err := d.queryAuditResponses(query, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query audit responses: %w", err)
 	}
-	defer rows.Close()
-
-	var responses []types.AuditResponse
-	for rows.Next() {
-		var resp types.AuditResponse
-		var responseStr, errorStr sql.NullString
-
-		err := rows.Scan(
-			&resp.ID,
-			&resp.RequestID,
-			&resp.Timestamp,
-			&responseStr,
-			&resp.StatusCode,
-			&resp.ProcessTime,
-			&errorStr,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		if responseStr.Valid {
-			resp.Response = json.RawMessage(responseStr.String)
-		}
-
-		if errorStr.Valid {
-			resp.Error = errorStr.String
-		}
-
-		responses = append(responses, resp)
-	}
 
 	return responses, nil
 }
@@ -349,113 +1133,157 @@ func (d *Database) GetAuditResponses(limit, offset int) ([]types.AuditResponse,
 // GetOrphanedRequests retrieves requests that have no corresponding response
 func (d *Database) GetOrphanedRequests(limit, offset int) ([]types.AuditRequest, error) {
 	query := `
-		SELECT r.id, r.timestamp, r.method, r.request_id, r.ip_address, r.user_agent, r.request, r.headers
-		FROM audit_requests r
-		LEFT JOIN audit_responses resp ON r.request_id = resp.request_id
-		WHERE resp.request_id IS NULL
-		ORDER BY r.timestamp DESC
+		SELECT ` + auditRequestColumns + `
+		FROM audit_requests
+		WHERE NOT EXISTS (
+			SELECT 1 FROM audit_responses resp WHERE resp.request_id = audit_requests.request_id
+		)
+		ORDER BY timestamp DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := d.db.Query(query, limit, offset)
+	requests, err := d.queryAuditRequests(query, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query orphaned requests: %w", err)
 	}
-	defer rows.Close()
 
-	var requests []types.AuditRequest
-	for rows.Next() {
-		var req types.AuditRequest
-		var requestStr, headersStr sql.NullString
-
-		err := rows.Scan(
-			&req.ID,
-			&req.Timestamp,
-			&req.Method,
-			&req.RequestID,
-			&req.IPAddress,
-			&req.UserAgent,
-			&requestStr,
-			&headersStr,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
+	return requests, nil
+}
 
-		if requestStr.Valid {
-			req.Request = json.RawMessage(requestStr.String)
-		}
+// ResolveOrphans records a synthetic unresolved response for every request
+// made before the given time that never got one, so crashed calls stop
+// counting as orphaned. Requests in inFlight, calls the gateway is still
+// serving such as long event streams, are left alone. It returns the number
+// of requests resolved.
+func (d *Database) ResolveOrphans(before time.Time, reason string, inFlight []string) (int64, error) {
+	skip, err := json.Marshal(inFlight)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode in-flight requests: %w", err)
+	}
+	if inFlight == nil {
+		skip = []byte("[]")
+	}
+	result, err := d.sqlDB().Exec(`
+		INSERT INTO audit_responses (request_id, timestamp, status_code, process_time_ms, error, failure_kind)
+		SELECT request_id, ?, 0, 0, ?, ?
+		FROM audit_requests
+		WHERE timestamp < ? AND NOT EXISTS (
+			SELECT 1 FROM audit_responses resp WHERE resp.request_id = audit_requests.request_id
+		) AND request_id NOT IN (SELECT value FROM json_each(?))
+	`, d.now(), reason, types.FailureUnresolved, before, string(skip))
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve orphaned requests: %w", err)
+	}
+	return result.RowsAffected()
+}
 
-		if headersStr.Valid {
-			req.Headers = json.RawMessage(headersStr.String)
-		}
+// GetAuditRequestsAfterID retrieves requests with an id greater than afterID in insertion order
+func (d *Database) GetAuditRequestsAfterID(afterID int64, limit int) ([]types.AuditRequest, error) {
+	return d.GetAuditRequestsBetween(afterID, math.MaxInt64, limit)
+}
 
-		requests = append(requests, req)
+// GetAuditRequestsBetween retrieves requests with afterID < id <= untilID in insertion order
+func (d *Database) GetAuditRequestsBetween(afterID, untilID int64, limit int) ([]types.AuditRequest, error) {
+	query := `
+		SELECT ` + auditRequestColumns + `
+		FROM audit_requests
+		WHERE id > ? AND id <= ?
+		ORDER BY id ASC
+		LIMIT ?
+	`
+
+	requests, err := d.queryAuditRequests(query, afterID, untilID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit requests: %w", err)
+	}
+
+	// Carry the extracted tags and indexed headers along for copies into other stores
+	ids := make([]string, len(requests))
+	for i, req := range requests {
+		ids[i] = req.RequestID
+	}
+	tags, err := d.loadTags(ids)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := d.loadIndexedHeaders(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range requests {
+		requests[i].Tags = tags[requests[i].RequestID]
+		requests[i].IndexedHeaders = headers[requests[i].RequestID]
 	}
 
 	return requests, nil
 }
 
-// GetAuditLogs retrieves audit logs with pagination (combined view for backward compatibility)
-func (d *Database) GetAuditLogs(limit, offset int) ([]types.AuditLog, error) {
+// GetAuditResponsesAfterID retrieves responses with an id greater than afterID in insertion order
+func (d *Database) GetAuditResponsesAfterID(afterID int64, limit int) ([]types.AuditResponse, error) {
+	return d.GetAuditResponsesBetween(afterID, math.MaxInt64, limit)
+}
+
+// GetAuditResponsesBetween retrieves responses with afterID < id <= untilID in insertion order
+func (d *Database) GetAuditResponsesBetween(afterID, untilID int64, limit int) ([]types.AuditResponse, error) {
 	query := `
-		SELECT id, timestamp, method, request_id, ip_address, user_agent,
-			   request, headers, response, status_code, process_time_ms, error
-		FROM audit_logs
-		ORDER BY timestamp DESC
-		LIMIT ? OFFSET ?
+		SELECT ` + auditResponseColumns + `
+		FROM audit_responses
+		WHERE id > ? AND id <= ?
+		ORDER BY id ASC
+		LIMIT ?
 	`
 
-	rows, err := d.db.Query(query, limit, offset)
+	responses, err := d.queryAuditResponses(query, afterID, untilID, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query audit logs: %w", err)
+		return nil, fmt.Errorf("failed to query audit responses: %w", err)
 	}
-	defer rows.Close()
-
-	var logs []types.AuditLog
-	for rows.Next() {
-		var log types.AuditLog
-		var requestStr, headersStr, responseStr, errorStr sql.NullString
-
-		err := rows.Scan(
-			&log.ID,
-			&log.Timestamp,
-			&log.Method,
-			&log.RequestID,
-			&log.IPAddress,
-			&log.UserAgent,
-			&requestStr,
-			&headersStr,
-			&responseStr,
-			&log.StatusCode,
-			&log.ProcessTime,
-			&errorStr,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		if requestStr.Valid {
-			log.Request = json.RawMessage(requestStr.String)
-		}
 
-		if headersStr.Valid {
-			log.Headers = json.RawMessage(headersStr.String)
-		}
+	return responses, nil
+}
 
-		if responseStr.Valid {
-			log.Response = json.RawMessage(responseStr.String)
-		}
+// GetIDRange returns the smallest and largest id and the row count of
+// audit_requests or audit_responses
+func (d *Database) GetIDRange(table string) (minID, maxID, rows int64, err error) {
+	if table != "audit_requests" && table != "audit_responses" {
+		return 0, 0, 0, fmt.Errorf("unknown audit table %q", table)
+	}
+	err = d.sqlDB().QueryRow("SELECT COALESCE(MIN(id), 0), COALESCE(MAX(id), 0), COUNT(*) FROM "+table).Scan(&minID, &maxID, &rows)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to query %s id range: %w", table, err)
+	}
+	return minID, maxID, rows, nil
+}
 
-		if errorStr.Valid {
-			log.Error = errorStr.String
-		}
+// GetAuditLog returns the combined audit log of a single request, or nil if it does not exist
+func (d *Database) GetAuditLog(requestID string) (*types.AuditLog, error) {
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_logs
+		WHERE request_id = ?
+	`
 
-		logs = append(logs, log)
+	logs, err := d.queryAuditLogs(query, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	if len(logs) == 0 {
+		return d.archivedAuditLog(requestID)
 	}
+	return &logs[0], nil
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+// GetAuditLogs retrieves audit logs with pagination (combined view for backward compatibility)
+func (d *Database) GetAuditLogs(limit, offset int) ([]types.AuditLog, error) {
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_logs
+		ORDER BY timestamp DESC
+		LIMIT ? OFFSET ?
+	`
+
+	logs, err := d.queryAuditLogs(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit logs: %w", err)
 	}
 
 	return logs, nil
@@ -464,80 +1292,40 @@ func (d *Database) GetAuditLogs(limit, offset int) ([]types.AuditLog, error) {
 // GetAuditLogsByMethod retrieves audit logs filtered by method
 func (d *Database) GetAuditLogsByMethod(method string, limit, offset int) ([]types.AuditLog, error) {
 	query := `
-		SELECT id, timestamp, method, request_id, ip_address, user_agent,
-			   request, response, status_code, process_time_ms, error
+		SELECT ` + auditLogColumns + `
 		FROM audit_logs
 		WHERE method = ?
 		ORDER BY timestamp DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := d.db.Query(query, method, limit, offset)
+	logs, err := d.queryAuditLogs(query, method, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query audit logs by method: %w", err)
 	}
-	defer rows.Close()
-
-	var logs []types.AuditLog
-	for rows.Next() {
-		var log types.AuditLog
-		var requestStr, responseStr sql.NullString
-		var errorStr sql.NullString
-
-		err := rows.Scan(
-			&log.ID,
-			&log.Timestamp,
-			&log.Method,
-			&log.RequestID,
-			&log.IPAddress,
-			&log.UserAgent,
-			&requestStr,
-			&responseStr,
-			&log.StatusCode,
-			&log.ProcessTime,
-			&errorStr,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		if requestStr.Valid {
-			log.Request = json.RawMessage(requestStr.String)
-		}
-
-		if responseStr.Valid {
-			log.Response = json.RawMessage(responseStr.String)
-		}
-
-		if errorStr.Valid {
-			log.Error = errorStr.String
-		}
-
-		logs = append(logs, log)
-	}
 
 	return logs, nil
 }
 
 // GetStats returns statistics about the audit logs
-func (d *Database) GetStats() (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
+func (d *Database) GetStats() (*types.Stats, error) {
+	stats := &types.Stats{}
 
 	// Total request count
 	var totalRequests int
-	err := d.db.QueryRow("SELECT COUNT(*) FROM audit_requests").Scan(&totalRequests)
+	err := d.readDB().QueryRow("SELECT COUNT(*) FROM audit_requests").Scan(&totalRequests)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total request count: %w", err)
 	}
-	stats["total_requests"] = totalRequests
+	stats.TotalRequests = totalRequests
 
 	// Total response count
 	var totalResponses int
-	err = d.db.QueryRow("SELECT COUNT(*) FROM audit_responses").Scan(&totalResponses)
+	err = d.readDB().QueryRow("SELECT COUNT(*) FROM audit_responses").Scan(&totalResponses)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total response count: %w", err)
 	}
-	stats["total_responses"] = totalResponses
+	stats.TotalResponses = totalResponses
 
 	// Orphaned requests (requests without responses)
 	var orphanedCount int
@@ -547,11 +1335,11 @@ func (d *Database) GetStats() (map[string]interface{}, error) {
 		LEFT JOIN audit_responses resp ON r.request_id = resp.request_id 
 		WHERE resp.request_id IS NULL
 	`
-	err = d.db.QueryRow(orphanedQuery).Scan(&orphanedCount)
+	err = d.readDB().QueryRow(orphanedQuery).Scan(&orphanedCount)
 	if err != nil {
 		log.Printf("Failed to get orphaned count: %v", err)
 	} else {
-		stats["orphaned_requests"] = orphanedCount
+		stats.OrphanedRequests = orphanedCount
 	}
 
 	// Method distribution
@@ -562,7 +1350,7 @@ func (d *Database) GetStats() (map[string]interface{}, error) {
 		ORDER BY count DESC
 		LIMIT 10
 	`
-	rows, err := d.db.Query(methodQuery)
+	rows, err := d.readDB().Query(methodQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query method stats: %w", err)
 	}
@@ -578,7 +1366,7 @@ func (d *Database) GetStats() (map[string]interface{}, error) {
 		}
 		methodStats[method] = count
 	}
-	stats["methods"] = methodStats
+	stats.Methods = methodStats
 
 	// Status code distribution
 	statusQuery := `
@@ -588,7 +1376,7 @@ func (d *Database) GetStats() (map[string]interface{}, error) {
 		ORDER BY count DESC
 		LIMIT 10
 	`
-	statusRows, err := d.db.Query(statusQuery)
+	statusRows, err := d.readDB().Query(statusQuery)
 	if err != nil {
 		log.Printf("Failed to query status stats: %v", err)
 	} else {
@@ -603,42 +1391,100 @@ func (d *Database) GetStats() (map[string]interface{}, error) {
 			}
 			statusStats[fmt.Sprintf("%d", statusCode)] = count
 		}
-		stats["status_codes"] = statusStats
+		stats.StatusCodes = statusStats
 	}
 
 	// Recent activity (last hour)
 	var recentRequests int
-	recentQuery := "SELECT COUNT(*) FROM audit_requests WHERE timestamp > datetime('now', '-1 hour')"
-	err = d.db.QueryRow(recentQuery).Scan(&recentRequests)
+	recentQuery := "SELECT COUNT(*) FROM audit_requests WHERE timestamp > ?"
+	err = d.readDB().QueryRow(recentQuery, d.now().Add(-time.Hour)).Scan(&recentRequests)
 	if err != nil {
 		log.Printf("Failed to get recent request count: %v", err)
 	} else {
-		stats["requests_last_hour"] = recentRequests
+		stats.RequestsLastHour = recentRequests
 	}
 
 	// Error rate (responses with errors)
 	var errorCount int
 	errorQuery := "SELECT COUNT(*) FROM audit_responses WHERE error IS NOT NULL AND error != ''"
-	err = d.db.QueryRow(errorQuery).Scan(&errorCount)
+	err = d.readDB().QueryRow(errorQuery).Scan(&errorCount)
 	if err != nil {
 		log.Printf("Failed to get error count: %v", err)
 	} else {
-		stats["error_count"] = errorCount
+		stats.ErrorCount = errorCount
 		if totalResponses > 0 {
-			stats["error_rate"] = float64(errorCount) / float64(totalResponses) * 100
-		} else {
-			stats["error_rate"] = 0.0
+			stats.ErrorRate = float64(errorCount) / float64(totalResponses) * 100
+		}
+	}
+
+	err = d.readDB().QueryRow("SELECT COUNT(DISTINCT request_id) FROM audit_tags WHERE name = ? AND value = 'true'", types.SlowTag).Scan(&stats.SlowRequests)
+	if err != nil {
+		log.Printf("Failed to get slow request count: %v", err)
+	}
+
+	// Notifications and JSON-RPC batches
+	err = d.readDB().QueryRow("SELECT COALESCE(SUM(CAST(value AS INTEGER)), 0) FROM audit_tags WHERE name = ?", types.NotificationsTag).Scan(&stats.Notifications)
+	if err != nil {
+		log.Printf("Failed to get notification count: %v", err)
+	}
+	if stats.Batches, err = d.getBatchStats(); err != nil {
+		log.Printf("Failed to get batch stats: %v", err)
+	}
+
+	// Malformed upstream responses and upstream JSON-RPC error codes
+	err = d.readDB().QueryRow("SELECT COUNT(*) FROM audit_responses WHERE malformed_upstream = 1").Scan(&stats.MalformedUpstream)
+	if err != nil {
+		log.Printf("Failed to get malformed upstream count: %v", err)
+	}
+
+	codeRows, err := d.readDB().Query(`
+		SELECT rpc_error_code, COUNT(*) as count
+		FROM audit_responses
+		WHERE rpc_error_code IS NOT NULL
+		GROUP BY rpc_error_code
+		ORDER BY count DESC
+		LIMIT 10
+	`)
+	if err != nil {
+		log.Printf("Failed to query RPC error codes: %v", err)
+	} else {
+		defer codeRows.Close()
+		stats.RPCErrorCodes = make(map[string]int)
+		for codeRows.Next() {
+			var code, count int
+			if err := codeRows.Scan(&code, &count); err != nil {
+				log.Printf("Failed to scan RPC error codes: %v", err)
+				continue
+			}
+			stats.RPCErrorCodes[fmt.Sprintf("%d", code)] = count
 		}
 	}
 
 	// Average response time (in milliseconds)
 	var avgResponseTime sql.NullFloat64
 	avgQuery := "SELECT AVG(process_time_ms) FROM audit_responses WHERE process_time_ms > 0"
-	err = d.db.QueryRow(avgQuery).Scan(&avgResponseTime)
+	err = d.readDB().QueryRow(avgQuery).Scan(&avgResponseTime)
 	if err != nil {
 		log.Printf("Failed to get average response time: %v", err)
 	} else if avgResponseTime.Valid {
-		stats["avg_response_time_ms"] = avgResponseTime.Float64
+		stats.AvgResponseTimeMs = avgResponseTime.Float64
+	}
+
+	// Upstream phases of timed calls
+	var timing types.TimingStats
+	var dns, connect, tlsMs, ttfb, read, reused sql.NullFloat64
+	err = d.readDB().QueryRow(`
+		SELECT COUNT(*), AVG(dns_ms), AVG(connect_ms), AVG(tls_ms), AVG(ttfb_ms), AVG(read_ms),
+			AVG(reused_conn) * 100
+		FROM audit_responses
+		WHERE ttfb_ms IS NOT NULL
+	`).Scan(&timing.Calls, &dns, &connect, &tlsMs, &ttfb, &read, &reused)
+	if err != nil {
+		log.Printf("Failed to get upstream timing: %v", err)
+	} else if timing.Calls > 0 {
+		timing.AvgDNSMs, timing.AvgConnectMs, timing.AvgTLSMs = dns.Float64, connect.Float64, tlsMs.Float64
+		timing.AvgTTFBMs, timing.AvgReadMs, timing.ReusedConnRate = ttfb.Float64, read.Float64, reused.Float64
+		stats.Timing = &timing
 	}
 
 	return stats, nil
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+func BenchmarkInsertAuditRequest(b *testing.B) {
+	db, err := New(filepath.Join(b.TempDir(), "audit.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	req := types.AuditRequest{
+		Timestamp:  time.Now(),
+		Method:     "eth_call",
+		IPAddress:  "127.0.0.1",
+		UserAgent:  "bench",
+		Request:    json.RawMessage(`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0x0","data":"0x00"}],"id":1}`),
+		Headers:    json.RawMessage(`{"Accept":"application/json","Content-Type":"application/json","User-Agent":"bench"}`),
+		HTTPMethod: "POST",
+		AuditLevel: types.AuditLevelFullBody,
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req.RequestID = "req_bench_" + strconv.Itoa(i)
+		if err := db.InsertAuditRequest(&req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
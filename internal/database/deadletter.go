@@ -0,0 +1,220 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createDeadLetterTableSQL = `
+-- Tinybird events that could not be delivered, kept until they are redriven
+CREATE TABLE IF NOT EXISTS tinybird_deadletter (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    datasource TEXT NOT NULL,
+    request_id TEXT,
+    event TEXT NOT NULL,
+    compressed INTEGER NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL,
+    last_attempt_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tinybird_deadletter_datasource ON tinybird_deadletter(datasource, id);
+`
+
+const deadLetterColumns = "id, datasource, request_id, event, compressed, reason, status_code, attempts, created_at, last_attempt_at"
+
+// failureStatus returns the HTTP status of a Tinybird rejection, 0 for other errors
+func failureStatus(err error) int {
+	var tbErr *TinybirdError
+	if errors.As(err, &tbErr) {
+		return tbErr.StatusCode
+	}
+	return 0
+}
+
+// InsertDeadLetters stores undelivered events of a datasource with the reason they failed.
+// Events carry audit payloads, so they are compressed and encrypted like the audit tables.
+func (d *Database) InsertDeadLetters(datasource string, events []json.RawMessage, reason error) error {
+	tx, err := d.sqlDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := d.now()
+	for _, event := range events {
+		var fields struct {
+			RequestID string `json:"request_id"`
+		}
+		json.Unmarshal(event, &fields)
+
+		value, compressed, err := d.packPayload(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO tinybird_deadletter (datasource, request_id, event, compressed, reason, status_code, created_at, last_attempt_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, datasource, nullIfEmpty(fields.RequestID), value, compressed, reason.Error(), failureStatus(reason), now, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert dead letter: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dead letters: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) scanDeadLetter(row rowScanner) (types.DeadLetter, error) {
+	var dl types.DeadLetter
+	var requestID sql.NullString
+	var event string
+	var compressed bool
+	err := row.Scan(&dl.ID, &dl.Datasource, &requestID, &event, &compressed, &dl.Reason,
+		&dl.StatusCode, &dl.Attempts, &dl.CreatedAt, &dl.LastAttemptAt)
+	if err != nil {
+		return dl, err
+	}
+	dl.RequestID = requestID.String
+	dl.Event = d.unpackPayload(json.RawMessage(event), compressed)
+	return dl, nil
+}
+
+// queryDeadLetters runs a query selecting deadLetterColumns and scans all rows
+func (d *Database) queryDeadLetters(query string, args ...interface{}) ([]types.DeadLetter, error) {
+	rows, err := d.sqlDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []types.DeadLetter
+	for rows.Next() {
+		dl, err := d.scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		deadLetters = append(deadLetters, dl)
+	}
+	return deadLetters, rows.Err()
+}
+
+// GetDeadLetters returns dead letters, newest first, optionally of a single datasource
+func (d *Database) GetDeadLetters(datasource string, limit, offset int) ([]types.DeadLetter, error) {
+	return d.queryDeadLetters(`
+		SELECT `+deadLetterColumns+` FROM tinybird_deadletter
+		WHERE (? = '' OR datasource = ?)
+		ORDER BY id DESC LIMIT ? OFFSET ?
+	`, datasource, datasource, limit, offset)
+}
+
+// getDeadLettersAfterID returns up to limit dead letters of a datasource in id order
+func (d *Database) getDeadLettersAfterID(datasource string, afterID int64, limit int) ([]types.DeadLetter, error) {
+	return d.queryDeadLetters(`
+		SELECT `+deadLetterColumns+` FROM tinybird_deadletter
+		WHERE datasource = ? AND id > ?
+		ORDER BY id LIMIT ?
+	`, datasource, afterID, limit)
+}
+
+// CountDeadLetters returns the number of dead letters per datasource
+func (d *Database) CountDeadLetters() (map[string]int, error) {
+	rows, err := d.sqlDB().Query("SELECT datasource, COUNT(*) FROM tinybird_deadletter GROUP BY datasource")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var datasource string
+		var n int
+		if err := rows.Scan(&datasource, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter count: %w", err)
+		}
+		counts[datasource] = n
+	}
+	return counts, rows.Err()
+}
+
+// deadLetterIDs formats ids for an IN clause along with their arguments
+func deadLetterIDs(deadLetters []types.DeadLetter) (string, []interface{}) {
+	args := make([]interface{}, len(deadLetters))
+	for i, dl := range deadLetters {
+		args[i] = dl.ID
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(args)), ","), args
+}
+
+// deleteDeadLetters removes dead letters that were delivered
+func (d *Database) deleteDeadLetters(deadLetters []types.DeadLetter) error {
+	placeholders, args := deadLetterIDs(deadLetters)
+	if _, err := d.sqlDB().Exec("DELETE FROM tinybird_deadletter WHERE id IN ("+placeholders+")", args...); err != nil {
+		return fmt.Errorf("failed to delete dead letters: %w", err)
+	}
+	return nil
+}
+
+// recordDeadLetterAttempt notes another failed delivery of dead letters
+func (d *Database) recordDeadLetterAttempt(deadLetters []types.DeadLetter, reason error) error {
+	placeholders, ids := deadLetterIDs(deadLetters)
+	args := append([]interface{}{reason.Error(), failureStatus(reason), d.now()}, ids...)
+	_, err := d.sqlDB().Exec(`
+		UPDATE tinybird_deadletter
+		SET reason = ?, status_code = ?, last_attempt_at = ?, attempts = attempts + 1
+		WHERE id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update dead letters: %w", err)
+	}
+	return nil
+}
+
+// Redrive resends the dead letters of a datasource in batches, deleting each batch
+// once Tinybird accepts it. It stops at the first rejected batch, recording the
+// new reason on its rows, and returns how many events were delivered.
+func (t *TinybirdDatabase) Redrive(datasource string, batchSize int) (int, error) {
+	if t.deadLetter == nil {
+		return 0, fmt.Errorf("no dead-letter table configured")
+	}
+
+	sent := 0
+	var afterID int64
+	for {
+		batch, err := t.deadLetter.getDeadLettersAfterID(datasource, afterID, batchSize)
+		if err != nil {
+			return sent, err
+		}
+		if len(batch) == 0 {
+			return sent, nil
+		}
+
+		events := make([]json.RawMessage, len(batch))
+		for i, dl := range batch {
+			events[i] = dl.Event
+		}
+
+		if err := t.sendRawEvents(datasource, events); err != nil {
+			if recErr := t.deadLetter.recordDeadLetterAttempt(batch, err); recErr != nil {
+				log.Printf("Failed to record redrive attempt: %v", recErr)
+			}
+			return sent, err
+		}
+
+		if err := t.deadLetter.deleteDeadLetters(batch); err != nil {
+			return sent, err
+		}
+		sent += len(batch)
+		afterID = batch[len(batch)-1].ID
+	}
+}
@@ -1,16 +1,24 @@
 package database
 
 import (
-	"fmt"
 	"log"
 
 	"github.com/niki4smirn/golf/internal/types"
 )
 
-// DualDatabase writes to both SQLite and Tinybird
+// DualDatabase writes to a primary SQLite database and mirrors every row to a
+// secondary store: Tinybird, or a metadata-only SQLite file, see NewHotColdDatabase
 type DualDatabase struct {
-	sqlite   *Database
-	tinybird *TinybirdDatabase
+	sqlite    *Database
+	secondary mirror
+	name      string // Secondary store named in logged write errors
+}
+
+// mirror is the write side of the secondary store of a DualDatabase
+type mirror interface {
+	AuditWriter
+	InsertAuditLog(log *types.AuditLog) error
+	Close() error
 }
 
 // NewDualDatabase creates a database that writes to both SQLite and Tinybird
@@ -25,8 +33,9 @@ func NewDualDatabase(sqlitePath, tinybirdToken string) (*DualDatabase, error) {
 	tinybird := NewTinybirdDatabase(tinybirdToken)
 
 	return &DualDatabase{
-		sqlite:   sqlite,
-		tinybird: tinybird,
+		sqlite:    sqlite,
+		secondary: tinybird,
+		name:      "Tinybird",
 	}, nil
 }
 
@@ -37,9 +46,9 @@ func (d *DualDatabase) InsertAuditRequest(req *types.AuditRequest) error {
 		return err
 	}
 
-	// Write to Tinybird (best effort - log error but don't fail)
-	if err := d.tinybird.InsertAuditRequest(req); err != nil {
-		log.Printf("Failed to write request to Tinybird: %v", err)
+	// Write to the secondary store (best effort - log error but don't fail)
+	if err := d.secondary.InsertAuditRequest(req); err != nil {
+		log.Printf("Failed to write request to %s: %v", d.name, err)
 	}
 
 	return nil
@@ -52,9 +61,9 @@ func (d *DualDatabase) InsertAuditResponse(resp *types.AuditResponse) error {
 		return err
 	}
 
-	// Write to Tinybird (best effort - log error but don't fail)
-	if err := d.tinybird.InsertAuditResponse(resp); err != nil {
-		log.Printf("Failed to write response to Tinybird: %v", err)
+	// Write to the secondary store (best effort - log error but don't fail)
+	if err := d.secondary.InsertAuditResponse(resp); err != nil {
+		log.Printf("Failed to write response to %s: %v", d.name, err)
 	}
 
 	return nil
@@ -81,19 +90,19 @@ func (d *DualDatabase) GetAuditLogsByMethod(method string, limit, offset int) ([
 	return d.sqlite.GetAuditLogsByMethod(method, limit, offset)
 }
 
-func (d *DualDatabase) GetStats() (map[string]interface{}, error) {
+func (d *DualDatabase) GetStats() (*types.Stats, error) {
 	return d.sqlite.GetStats()
 }
 
-func (d *DualDatabase) InsertAuditLog(log *types.AuditLog) error {
+func (d *DualDatabase) InsertAuditLog(entry *types.AuditLog) error {
 	// Write to SQLite (primary - must succeed)
-	if err := d.sqlite.InsertAuditLog(log); err != nil {
+	if err := d.sqlite.InsertAuditLog(entry); err != nil {
 		return err
 	}
 
-	// Write to Tinybird (best effort - log error but don't fail)
-	if err := d.tinybird.InsertAuditLog(log); err != nil {
-		fmt.Printf("Failed to write audit log to Tinybird: %v", err)
+	// Write to the secondary store (best effort - log error but don't fail)
+	if err := d.secondary.InsertAuditLog(entry); err != nil {
+		log.Printf("Failed to write audit log to %s: %v", d.name, err)
 	}
 
 	return nil
@@ -101,6 +110,8 @@ func (d *DualDatabase) InsertAuditLog(log *types.AuditLog) error {
 
 // Close both connections
 func (d *DualDatabase) Close() error {
-	d.tinybird.Close() // No-op for Tinybird
+	if err := d.secondary.Close(); err != nil {
+		log.Printf("Failed to close %s: %v", d.name, err)
+	}
 	return d.sqlite.Close()
 }
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetResponseDuplicates counts, per method, the successful responses in
+// [from, to) sharing a response hash with another one. Ratios and the
+// candidate flag are left to the caller.
+func (d *Database) GetResponseDuplicates(from, to time.Time) ([]types.ResponseDuplicates, error) {
+	rows, err := d.readDB().Query(`
+		SELECT r.method, resp.response_hash, COUNT(*),
+			SUM(CASE WHEN resp.cache_status = ? THEN 1 ELSE 0 END)
+		FROM audit_responses resp
+		JOIN audit_requests r ON r.request_id = resp.request_id
+		WHERE resp.response_hash IS NOT NULL AND resp.status_code = 200
+			AND resp.timestamp >= ? AND resp.timestamp < ?
+		GROUP BY r.method, resp.response_hash`, types.CacheHit, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query response hashes: %w", err)
+	}
+	defer rows.Close()
+
+	byMethod := make(map[string]*types.ResponseDuplicates)
+	top := make(map[string]int)
+	for rows.Next() {
+		var method, hash string
+		var count, cached int
+		if err := rows.Scan(&method, &hash, &count, &cached); err != nil {
+			return nil, fmt.Errorf("failed to scan response hashes: %w", err)
+		}
+
+		m, ok := byMethod[method]
+		if !ok {
+			m = &types.ResponseDuplicates{Method: method}
+			byMethod[method] = m
+		}
+		m.Calls += count
+		m.DistinctResponses++
+		m.DuplicateCalls += count - 1
+		m.CachedCalls += cached
+		if count > top[method] {
+			top[method] = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response hashes: %w", err)
+	}
+
+	methods := make([]types.ResponseDuplicates, 0, len(byMethod))
+	for method, m := range byMethod {
+		m.DuplicateRatio = float64(m.DuplicateCalls) / float64(m.Calls)
+		m.TopResponseShare = float64(top[method]) / float64(m.Calls)
+		methods = append(methods, *m)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].DuplicateCalls != methods[j].DuplicateCalls {
+			return methods[i].DuplicateCalls > methods[j].DuplicateCalls
+		}
+		return methods[i].Method < methods[j].Method
+	})
+	return methods, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// InsertAuditRequestDurable inserts an audit request with synchronous=FULL, so
+// the commit is fsynced and survives a power loss once it returns
+func (d *Database) InsertAuditRequestDurable(req *types.AuditRequest) error {
+	ctx := context.Background()
+	conn, err := d.sqlDB().Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for durable insert: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA synchronous = FULL"); err != nil {
+		return fmt.Errorf("failed to enable synchronous commits: %w", err)
+	}
+	// The connection goes back to the pool, where writes are not fsynced on commit
+	defer conn.ExecContext(ctx, "PRAGMA synchronous = NORMAL")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin durable insert: %w", err)
+	}
+	defer tx.Rollback()
+	if err := d.insertAuditRequest(tx, req); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit durable insert: %w", err)
+	}
+	return nil
+}
+
+// InsertAuditBatch writes records in a single transaction, in order
+func (d *Database) InsertAuditBatch(records []types.AuditRecord) error {
+	tx, err := d.sqlDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin audit batch: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, record := range records {
+		if record.Request != nil {
+			err = d.insertAuditRequest(tx, record.Request)
+		} else {
+			err = d.insertAuditResponse(tx, record.Response)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit audit batch: %w", err)
+	}
+	return nil
+}
+
+// InsertAuditRequestDurable commits the request to SQLite durably, then writes
+// it to the secondary store on a best-effort basis
+func (d *DualDatabase) InsertAuditRequestDurable(req *types.AuditRequest) error {
+	if err := d.sqlite.InsertAuditRequestDurable(req); err != nil {
+		return err
+	}
+	if err := d.secondary.InsertAuditRequest(req); err != nil {
+		log.Printf("Failed to write request to %s: %v", d.name, err)
+	}
+	return nil
+}
+
+// InsertAuditBatch writes the batch to SQLite, then to the secondary store on a best-effort basis
+func (d *DualDatabase) InsertAuditBatch(records []types.AuditRecord) error {
+	if err := d.sqlite.InsertAuditBatch(records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		var err error
+		if record.Request != nil {
+			err = d.secondary.InsertAuditRequest(record.Request)
+		} else {
+			err = d.secondary.InsertAuditResponse(record.Response)
+		}
+		if err != nil {
+			log.Printf("Failed to write %s to %s: %v", record.Type, d.name, err)
+		}
+	}
+	return nil
+}
+
+// InsertAuditRequestDurable writes the request durably through to the target.
+// Unlike other writes it is never spooled, since the caller must learn that the
+// request did not reach the disk.
+func (s *Spool) InsertAuditRequestDurable(req *types.AuditRequest) error {
+	durable, ok := s.target.(DurableWriter)
+	if !ok {
+		return fmt.Errorf("audit store cannot commit requests durably")
+	}
+	return durable.InsertAuditRequestDurable(req)
+}
+
+// InsertAuditBatch writes the batch through to the target in one go when
+// nothing is pending, spooling it on failure, and record by record otherwise
+func (s *Spool) InsertAuditBatch(records []types.AuditRecord) error {
+	if batch, ok := s.target.(BatchWriter); ok {
+		s.mu.Lock()
+		if s.pending == 0 {
+			defer s.mu.Unlock()
+			if err := batch.InsertAuditBatch(records); err != nil {
+				s.markFailed(err)
+				for _, record := range records {
+					s.enqueue(spoolRecord{Type: record.Type, Request: record.Request, Response: record.Response})
+				}
+			}
+			return nil
+		}
+		s.mu.Unlock()
+	}
+
+	for _, record := range records {
+		if record.Request != nil {
+			s.InsertAuditRequest(record.Request)
+		} else {
+			s.InsertAuditResponse(record.Response)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// encryptedPrefix marks payload columns sealed with AES-GCM: enc:v1:<key id>:<base64(nonce|ciphertext)>
+const encryptedPrefix = "enc:v1:"
+
+// PayloadCipher encrypts audit payload columns with AES-256-GCM.
+// Additional keys are kept for decrypting rows written before a key rotation.
+type PayloadCipher struct {
+	keyID string
+	aead  cipher.AEAD
+	keys  map[string]cipher.AEAD
+}
+
+// ParseKey decodes a 32-byte key given as base64 or hex
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(s); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be 32 bytes encoded as base64 or hex")
+}
+
+// NewPayloadCipher creates a cipher sealing with key and able to open data sealed with any of oldKeys
+func NewPayloadCipher(key []byte, oldKeys ...[]byte) (*PayloadCipher, error) {
+	c := &PayloadCipher{keys: make(map[string]cipher.AEAD)}
+
+	for i, k := range append([][]byte{key}, oldKeys...) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCM: %w", err)
+		}
+
+		id := keyID(k)
+		c.keys[id] = aead
+		if i == 0 {
+			c.keyID = id
+			c.aead = aead
+		}
+	}
+
+	return c, nil
+}
+
+// keyID derives a short non-secret identifier for a key
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Seal encrypts plaintext with the primary key
+func (c *PayloadCipher) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return encryptedPrefix + c.keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a sealed value; values that are not sealed are returned unchanged
+func (c *PayloadCipher) Open(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return []byte(value), nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedPrefix), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+
+	aead, ok := c.keys[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("no key available for key id %s", parts[0])
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted value too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// SetEncryption enables encryption of the request, response, headers, extensions and debug columns
+func (d *Database) SetEncryption(c *PayloadCipher) {
+	d.cipher = c
+}
+
+// openPayload decrypts a payload read from the database. Sealed values that
+// cannot be opened are returned as a JSON string so API responses stay valid.
+func (d *Database) openPayload(raw json.RawMessage) json.RawMessage {
+	if !strings.HasPrefix(string(raw), encryptedPrefix) {
+		return raw
+	}
+	if d.cipher != nil {
+		if plain, err := d.cipher.Open(string(raw)); err == nil {
+			return json.RawMessage(plain)
+		}
+	}
+	return json.RawMessage(strconv.Quote(string(raw)))
+}
+
+// RotateEncryption re-encrypts every payload column with newCipher's primary key.
+// Rows are opened with the current cipher, so it must hold the keys in use;
+// plaintext rows written before encryption was enabled are sealed as well.
+// Rows are rewritten in batches of rotateBatchSize, each in its own transaction.
+func (d *Database) RotateEncryption(newCipher *PayloadCipher) (int, error) {
+	current := d.cipher
+	if current == nil {
+		current = newCipher
+	}
+
+	tables := []struct {
+		table   string
+		columns []string
+	}{
+		{"audit_requests", []string{"request", "headers", "extensions"}},
+		{"audit_responses", []string{"response", "transformed_response", "debug"}},
+	}
+
+	total := 0
+	for _, t := range tables {
+		n, err := d.rotateTable(t.table, t.columns, current, newCipher)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	d.cipher = newCipher
+	return total, nil
+}
+
+// rotateBatchSize is the number of rows re-encrypted per transaction, so
+// rotating a large database neither holds every row in memory nor keeps the
+// write lock for the whole run
+const rotateBatchSize = 1000
+
+// rotateTable re-encrypts columns of table in id order, one transaction per
+// batch. An interrupted rotation can be run again with both keys: rows
+// already sealed with next are opened with it and sealed again.
+func (d *Database) rotateTable(table string, columns []string, current, next *PayloadCipher) (int, error) {
+	total := 0
+	var afterID int64
+	for {
+		n, lastID, err := d.rotateBatch(table, columns, current, next, afterID)
+		total += n
+		if err != nil || n < rotateBatchSize {
+			return total, err
+		}
+		afterID = lastID
+	}
+}
+
+// rotateBatch re-encrypts up to rotateBatchSize rows with an id greater than
+// afterID and returns how many it rotated and the last id
+func (d *Database) rotateBatch(table string, columns []string, current, next *PayloadCipher, afterID int64) (int, int64, error) {
+	tx, err := d.sqlDB().Begin()
+	if err != nil {
+		return 0, afterID, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("SELECT id, %s FROM %s WHERE id > ? ORDER BY id LIMIT ?", strings.Join(columns, ", "), table)
+	rows, err := tx.Query(query, afterID, rotateBatchSize)
+	if err != nil {
+		return 0, afterID, fmt.Errorf("failed to query %s: %w", table, err)
+	}
+
+	type row struct {
+		id     int64
+		values []sql.NullString
+	}
+	var pending []row
+	for rows.Next() {
+		r := row{values: make([]sql.NullString, len(columns))}
+		dest := []interface{}{&r.id}
+		for i := range r.values {
+			dest = append(dest, &r.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, afterID, fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, afterID, fmt.Errorf("row iteration error: %w", err)
+	}
+	if len(pending) == 0 {
+		return 0, afterID, nil
+	}
+
+	assignments := make([]string, len(columns))
+	for i, c := range columns {
+		assignments[i] = c + " = ?"
+	}
+	update, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", table, strings.Join(assignments, ", ")))
+	if err != nil {
+		return 0, afterID, fmt.Errorf("failed to prepare update: %w", err)
+	}
+	defer update.Close()
+
+	for _, r := range pending {
+		args := make([]interface{}, 0, len(columns)+1)
+		for _, v := range r.values {
+			if !v.Valid || v.String == "" {
+				args = append(args, v)
+				continue
+			}
+			plain, err := current.Open(v.String)
+			if err != nil {
+				if plain, err = next.Open(v.String); err != nil {
+					return 0, afterID, fmt.Errorf("failed to decrypt %s row %d: %w", table, r.id, err)
+				}
+			}
+			sealed, err := next.Seal(plain)
+			if err != nil {
+				return 0, afterID, err
+			}
+			args = append(args, sealed)
+		}
+		args = append(args, r.id)
+		if _, err := update.Exec(args...); err != nil {
+			return 0, afterID, fmt.Errorf("failed to update %s row %d: %w", table, r.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, afterID, fmt.Errorf("failed to commit rotation: %w", err)
+	}
+	return len(pending), pending[len(pending)-1].id, nil
+}
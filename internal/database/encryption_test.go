@@ -0,0 +1,121 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+func testCipher(t *testing.T, key byte, oldKeys ...byte) *PayloadCipher {
+	t.Helper()
+	var old [][]byte
+	for _, k := range oldKeys {
+		old = append(old, bytes.Repeat([]byte{k}, 32))
+	}
+	c, err := NewPayloadCipher(bytes.Repeat([]byte{key}, 32), old...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// rawColumns returns the stored values of the payload columns of a call
+func rawColumns(t *testing.T, db *Database, requestID string) []string {
+	t.Helper()
+	var request, headers, response, debug string
+	err := db.sqlDB().QueryRow(`
+		SELECT r.request, r.headers, resp.response, resp.debug
+		FROM audit_requests r JOIN audit_responses resp ON resp.request_id = r.request_id
+		WHERE r.request_id = ?`, requestID).Scan(&request, &headers, &response, &debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []string{request, headers, response, debug}
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetEncryption(testCipher(t, 1))
+
+	const secret = "0xsecretcalldata"
+	ts := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	err = db.InsertAuditRequest(&types.AuditRequest{
+		RequestID: "req-1",
+		Timestamp: ts,
+		Method:    "eth_call",
+		Request:   json.RawMessage(`{"jsonrpc":"2.0","method":"eth_call","params":["` + secret + `"],"id":1}`),
+		Headers:   json.RawMessage(`{"X-Trace":"` + secret + `"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.InsertAuditResponse(&types.AuditResponse{
+		RequestID:  "req-1",
+		Timestamp:  ts,
+		StatusCode: 200,
+		Response:   json.RawMessage(`{"jsonrpc":"2.0","result":"` + secret + `","id":1}`),
+		Debug:      json.RawMessage(`{"request_headers":{"X-Trace":"` + secret + `"}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, value := range rawColumns(t, db, "req-1") {
+		if !strings.HasPrefix(value, encryptedPrefix) || strings.Contains(value, secret) {
+			t.Errorf("payload column %d stored in the clear: %s", i, value)
+		}
+	}
+
+	entry, err := db.GetAuditLog("req-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, payload := range map[string]json.RawMessage{"request": entry.Request, "headers": entry.Headers, "response": entry.Response, "debug": entry.Debug} {
+		if !strings.Contains(string(payload), secret) {
+			t.Errorf("%s not decrypted: %s", name, payload)
+		}
+	}
+
+	// Rotate to a new key, after which the old one is no longer needed
+	before := rawColumns(t, db, "req-1")
+	if _, err := db.RotateEncryption(testCipher(t, 2, 1)); err != nil {
+		t.Fatal(err)
+	}
+	for i, value := range rawColumns(t, db, "req-1") {
+		if value == before[i] || !strings.HasPrefix(value, encryptedPrefix) {
+			t.Errorf("payload column %d not re-encrypted: %s", i, value)
+		}
+	}
+
+	reopened, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	reopened.SetEncryption(testCipher(t, 2))
+	if entry, err = reopened.GetAuditLog("req-1"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(entry.Response), secret) || !strings.Contains(string(entry.Debug), secret) {
+		t.Errorf("rotated payloads not readable with the new key: %s %s", entry.Response, entry.Debug)
+	}
+
+	// A wrong key leaves the payloads sealed
+	reopened.SetEncryption(testCipher(t, 1))
+	if entry, err = reopened.GetAuditLog("req-1"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(entry.Request), secret) {
+		t.Errorf("payload opened with the retired key: %s", entry.Request)
+	}
+}
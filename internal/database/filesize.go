@@ -0,0 +1,28 @@
+package database
+
+import (
+	"fmt"
+	"os"
+)
+
+// FileSizes returns the size on disk of the active database file and of its
+// write-ahead log. The WAL size is 0 while it does not exist.
+func (d *Database) FileSizes() (dbBytes, walBytes int64, err error) {
+	var seq int
+	var name, path string
+	if err := d.sqlDB().QueryRow("PRAGMA database_list").Scan(&seq, &name, &path); err != nil {
+		return 0, 0, fmt.Errorf("failed to locate database file: %w", err)
+	}
+	if path == "" {
+		return 0, 0, nil // In-memory database
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stat database file: %w", err)
+	}
+	if wal, err := os.Stat(path + "-wal"); err == nil {
+		walBytes = wal.Size()
+	}
+	return info.Size(), walBytes, nil
+}
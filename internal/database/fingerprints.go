@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetSeenClients lists the distinct client fingerprints, most recently seen
+// first. With unidentified set only clients calling without an API key are listed.
+func (d *Database) GetSeenClients(unidentified bool, limit, offset int) ([]types.SeenClient, error) {
+	where := "WHERE fingerprint IS NOT NULL"
+	if unidentified {
+		where += " AND (api_key IS NULL OR api_key = '')"
+	}
+
+	rows, err := d.readDB().Query(`
+		SELECT fingerprint, MAX(ip_address), MAX(user_agent), COALESCE(MAX(api_key), ''), COALESCE(MAX(tenant), ''),
+			MIN(timestamp), MAX(timestamp), COUNT(*)
+		FROM audit_requests
+		`+where+`
+		GROUP BY fingerprint
+		ORDER BY MAX(timestamp) DESC
+		LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []types.SeenClient{}
+	index := make(map[string]int)
+	for rows.Next() {
+		c := types.SeenClient{Methods: make(map[string]int)}
+		var firstSeen, lastSeen string
+		if err := rows.Scan(&c.Fingerprint, &c.IPAddress, &c.UserAgent, &c.APIKey, &c.Tenant, &firstSeen, &lastSeen, &c.Requests); err != nil {
+			return nil, fmt.Errorf("failed to scan client: %w", err)
+		}
+		c.FirstSeen, c.LastSeen = parseSQLiteTime(firstSeen), parseSQLiteTime(lastSeen)
+		index[c.Fingerprint] = len(clients)
+		clients = append(clients, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read clients: %w", err)
+	}
+	if len(clients) == 0 {
+		return clients, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(clients)), ",")
+	args := make([]interface{}, 0, len(clients))
+	for _, c := range clients {
+		args = append(args, c.Fingerprint)
+	}
+	methodRows, err := d.readDB().Query(`
+		SELECT fingerprint, method, COUNT(*)
+		FROM audit_requests
+		WHERE fingerprint IN (`+placeholders+`)
+		GROUP BY fingerprint, method`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query client methods: %w", err)
+	}
+	defer methodRows.Close()
+
+	for methodRows.Next() {
+		var fingerprint, method string
+		var count int
+		if err := methodRows.Scan(&fingerprint, &method, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan client methods: %w", err)
+		}
+		clients[index[fingerprint]].Methods[method] = count
+	}
+	return clients, methodRows.Err()
+}
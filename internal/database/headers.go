@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+const createHeadersTableSQL = `
+-- Request headers listed in indexed_headers, kept apart from the headers blob for indexed filtering
+CREATE TABLE IF NOT EXISTS audit_headers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    FOREIGN KEY (request_id) REFERENCES audit_requests(request_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_headers_name_value ON audit_headers(name, value);
+CREATE INDEX IF NOT EXISTS idx_audit_headers_request_id ON audit_headers(request_id);
+`
+
+// insertIndexedHeaders stores the indexed headers of a request
+func insertIndexedHeaders(exec execer, requestID string, headers map[string]string) error {
+	for name, value := range headers {
+		_, err := exec.Exec("INSERT INTO audit_headers (request_id, name, value) VALUES (?, ?, ?)", requestID, name, value)
+		if err != nil {
+			return fmt.Errorf("failed to insert indexed header %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// loadIndexedHeaders returns the indexed headers for the given request IDs
+func (d *Database) loadIndexedHeaders(requestIDs []string) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	if len(requestIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(requestIDs)), ",")
+	args := make([]interface{}, len(requestIDs))
+	for i, id := range requestIDs {
+		args[i] = id
+	}
+
+	rows, err := d.sqlDB().Query("SELECT request_id, name, value FROM audit_headers WHERE request_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexed headers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var requestID, name, value string
+		if err := rows.Scan(&requestID, &name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan indexed header: %w", err)
+		}
+		if result[requestID] == nil {
+			result[requestID] = make(map[string]string)
+		}
+		result[requestID][name] = value
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LatencyHeatmap counts responses completed in [from, to) per time bucket of the
+// given interval and per latency bucket. bounds are ascending inclusive upper
+// bounds in milliseconds; each row has an extra trailing bucket for slower calls.
+// An empty method matches all methods.
+func (d *Database) LatencyHeatmap(method string, from, to time.Time, interval time.Duration, bounds []int64) ([][]int, error) {
+	if interval <= 0 || !to.After(from) {
+		return nil, fmt.Errorf("invalid heatmap range")
+	}
+
+	rows := int((to.Sub(from) + interval - 1) / interval)
+	counts := make([][]int, rows)
+	for i := range counts {
+		counts[i] = make([]int, len(bounds)+1)
+	}
+
+	query := `
+		SELECT resp.timestamp, resp.process_time_ms
+		FROM audit_responses resp
+		JOIN audit_requests r ON r.request_id = resp.request_id
+		WHERE resp.timestamp >= ? AND resp.timestamp < ?`
+	args := []interface{}{from, to}
+	if method != "" {
+		query += " AND r.method = ?"
+		args = append(args, method)
+	}
+
+	result, err := d.readDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latencies: %w", err)
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var timestamp time.Time
+		var latency int64
+		if err := result.Scan(&timestamp, &latency); err != nil {
+			return nil, fmt.Errorf("failed to scan latency: %w", err)
+		}
+
+		row := int(timestamp.Sub(from) / interval)
+		if row < 0 || row >= rows {
+			continue
+		}
+		column := sort.Search(len(bounds), func(i int) bool { return latency <= bounds[i] })
+		counts[row][column]++
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read latencies: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// NewHotColdDatabase creates a database keeping recent calls with their full
+// payloads in a small hot SQLite file, which serves the dashboard and audit
+// API, and every call without payloads in a cold file keeping the long
+// history cheaply. Start pruning to keep the hot file small.
+func NewHotColdDatabase(hotPath, coldPath string) (*DualDatabase, error) {
+	hot, err := New(hotPath)
+	if err != nil {
+		return nil, err
+	}
+	cold, err := New(coldPath)
+	if err != nil {
+		hot.Close()
+		return nil, fmt.Errorf("failed to open cold database: %w", err)
+	}
+
+	return &DualDatabase{
+		sqlite:    hot,
+		secondary: metadataOnly{cold},
+		name:      "cold database",
+	}, nil
+}
+
+// Primary returns the SQLite database reads are served from, the hot one of a hot/cold pair
+func (d *DualDatabase) Primary() *Database {
+	return d.sqlite
+}
+
+// Cold returns the database holding the whole history of a hot/cold pair, nil for other pairs
+func (d *DualDatabase) Cold() *Database {
+	if cold, ok := d.secondary.(metadataOnly); ok {
+		return cold.db
+	}
+	return nil
+}
+
+// metadataOnly stores calls at the metadata audit level: method, timing,
+// status, errors and tags, without bodies, headers or debug captures
+type metadataOnly struct {
+	db *Database
+}
+
+func (m metadataOnly) InsertAuditRequest(req *types.AuditRequest) error {
+	stripped := *req
+	stripped.Request, stripped.Headers, stripped.Extensions = nil, nil, nil
+	stripped.BodyEncoding = ""
+	stripped.AuditLevel = types.AuditLevelMetadata
+	return m.db.InsertAuditRequest(&stripped)
+}
+
+func (m metadataOnly) InsertAuditResponse(resp *types.AuditResponse) error {
+	stripped := *resp
+	stripped.Response, stripped.BodyEncoding = nil, ""
+	stripped.TransformedResponse, stripped.Debug = nil, nil
+	return m.db.InsertAuditResponse(&stripped)
+}
+
+func (m metadataOnly) InsertAuditLog(entry *types.AuditLog) error {
+	stripped := *entry
+	stripped.Request, stripped.Response, stripped.Headers, stripped.Extensions = nil, nil, nil, nil
+	stripped.BodyEncoding, stripped.ResponseBodyEncoding = "", ""
+	stripped.TransformedResponse, stripped.Debug = nil, nil
+	stripped.AuditLevel = types.AuditLevelMetadata
+	return m.db.InsertAuditLog(&stripped)
+}
+
+func (m metadataOnly) Close() error {
+	return m.db.Close()
+}
+
+// DeleteBefore removes the calls received before cutoff with their responses,
+// tags and indexed headers, returning the number of requests removed
+func (d *Database) DeleteBefore(cutoff time.Time) (int64, error) {
+	tx, err := d.sqlDB().Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"audit_tags", "audit_headers", "audit_responses"} {
+		query := fmt.Sprintf("DELETE FROM %s WHERE request_id IN (SELECT request_id FROM audit_requests WHERE timestamp < ?)", table)
+		if _, err := tx.Exec(query, cutoff); err != nil {
+			return 0, fmt.Errorf("failed to prune %s: %w", table, err)
+		}
+	}
+	result, err := tx.Exec("DELETE FROM audit_requests WHERE timestamp < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune audit_requests: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit pruning: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Pruner periodically removes calls older than a retention period from a database
+type Pruner struct {
+	db        *Database
+	retention time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPruner creates a pruner keeping the calls of the last retention period in db
+func NewPruner(db *Database, retention time.Duration) *Pruner {
+	return &Pruner{db: db, retention: retention}
+}
+
+// Start prunes now and then every interval in the background
+func (p *Pruner) Start(interval time.Duration) {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			p.Run()
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the pruning loop
+func (p *Pruner) Stop() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+	}
+}
+
+// Run removes the calls older than the retention period once
+func (p *Pruner) Run() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	removed, err := p.db.DeleteBefore(p.db.now().Add(-p.retention))
+	if err != nil {
+		log.Printf("Failed to prune calls older than %s: %v", p.retention, err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Pruned %d calls older than %s", removed, p.retention)
+	}
+}
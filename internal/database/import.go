@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// ImportResult counts the outcome of an audit record import
+type ImportResult struct {
+	Requests   int
+	Responses  int
+	Duplicates int
+}
+
+// ImportAuditRecords stores records in a single transaction, skipping requests and
+// responses whose request_id is already present
+func (d *Database) ImportAuditRecords(records []types.AuditRecord) (ImportResult, error) {
+	var result ImportResult
+
+	tx, err := d.sqlDB().Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to begin import: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, record := range records {
+		switch record.Type {
+		case types.RecordRequest:
+			var exists bool
+			if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM audit_requests WHERE request_id = ?)", record.Request.RequestID).Scan(&exists); err != nil {
+				return ImportResult{}, fmt.Errorf("failed to check request %s: %w", record.Request.RequestID, err)
+			}
+			if exists {
+				result.Duplicates++
+				continue
+			}
+			if err := d.insertAuditRequest(tx, record.Request); err != nil {
+				return ImportResult{}, err
+			}
+			result.Requests++
+		case types.RecordResponse:
+			var exists bool
+			if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM audit_responses WHERE request_id = ?)", record.Response.RequestID).Scan(&exists); err != nil {
+				return ImportResult{}, fmt.Errorf("failed to check response %s: %w", record.Response.RequestID, err)
+			}
+			if exists {
+				result.Duplicates++
+				continue
+			}
+			if err := d.insertAuditResponse(tx, record.Response); err != nil {
+				return ImportResult{}, err
+			}
+			result.Responses++
+		default:
+			return ImportResult{}, fmt.Errorf("unknown record type %q", record.Type)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return result, nil
+}
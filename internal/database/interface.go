@@ -11,6 +11,23 @@ type AuditDatabase interface {
 	GetOrphanedRequests(limit, offset int) ([]types.AuditRequest, error)
 	GetAuditLogs(limit, offset int) ([]types.AuditLog, error)
 	GetAuditLogsByMethod(method string, limit, offset int) ([]types.AuditLog, error)
-	GetStats() (map[string]interface{}, error)
+	GetStats() (*types.Stats, error)
 	Close() error
 }
+
+// AuditWriter is the write side of an audit store
+type AuditWriter interface {
+	InsertAuditRequest(req *types.AuditRequest) error
+	InsertAuditResponse(resp *types.AuditResponse) error
+}
+
+// DurableWriter commits audit requests to disk before returning, for calls
+// with strict durability
+type DurableWriter interface {
+	InsertAuditRequestDurable(req *types.AuditRequest) error
+}
+
+// BatchWriter writes many audit records at once, for calls with relaxed durability
+type BatchWriter interface {
+	InsertAuditBatch(records []types.AuditRecord) error
+}
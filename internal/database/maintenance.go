@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// autoVacuumIncremental is the PRAGMA auto_vacuum value of incrementally vacuumed databases
+const autoVacuumIncremental = 2
+
+// maxIntegrityErrors caps the problems reported by integrity_check
+const maxIntegrityErrors = 10
+
+// Maintenance periodically truncates the WAL, returns free pages to the
+// filesystem and checks database integrity, so long-running gateways do not
+// accumulate huge WAL files or fragmented databases after pruning.
+type Maintenance struct {
+	db             *Database
+	integrityCheck bool
+
+	mu     sync.Mutex
+	status types.MaintenanceStatus
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewMaintenance creates a maintenance runner for db
+func NewMaintenance(db *Database, integrityCheck bool) *Maintenance {
+	return &Maintenance{db: db, integrityCheck: integrityCheck}
+}
+
+// Start runs maintenance every interval in the background
+func (m *Maintenance) Start(interval time.Duration) {
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.Run()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the maintenance loop
+func (m *Maintenance) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+		<-m.done
+	}
+}
+
+// Status returns the result of the last run
+func (m *Maintenance) Status() types.MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Run performs one maintenance pass and records its result
+func (m *Maintenance) Run() types.MaintenanceStatus {
+	start := m.db.now()
+
+	m.mu.Lock()
+	status := types.MaintenanceStatus{Runs: m.status.Runs + 1, LastRunAt: &start}
+	m.mu.Unlock()
+
+	if err := m.run(&status); err != nil {
+		status.LastError = err.Error()
+		log.Printf("Database maintenance failed: %v", err)
+	}
+	if status.IntegrityOK != nil && !*status.IntegrityOK {
+		log.Printf("Database integrity check failed: %v", status.IntegrityErrors)
+	}
+	status.DurationMs = m.db.now().Sub(start).Milliseconds()
+
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+	return status
+}
+
+func (m *Maintenance) run(status *types.MaintenanceStatus) error {
+	db := m.db.sqlDB()
+
+	var busy int
+	if err := db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &status.WALFrames, &status.CheckpointedFrames); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	status.CheckpointBusy = busy != 0
+
+	freeBefore, err := pragmaInt(m.db, "freelist_count")
+	if err != nil {
+		return err
+	}
+
+	mode, err := pragmaInt(m.db, "auto_vacuum")
+	if err != nil {
+		return err
+	}
+	if mode == autoVacuumIncremental {
+		// incremental_vacuum frees pages while it is stepped, so drain its rows
+		rows, err := db.Query("PRAGMA incremental_vacuum")
+		if err != nil {
+			return fmt.Errorf("failed to vacuum: %w", err)
+		}
+		for rows.Next() {
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to vacuum: %w", err)
+		}
+	} else if freeBefore > 0 {
+		// Databases created before incremental vacuum need one full VACUUM to switch modes
+		log.Printf("Converting audit database to incremental vacuum, reclaiming %d pages", freeBefore)
+		if err := m.convertToIncremental(); err != nil {
+			return err
+		}
+	}
+
+	if status.FreelistPages, err = pragmaInt(m.db, "freelist_count"); err != nil {
+		return err
+	}
+	status.FreedPages = freeBefore - status.FreelistPages
+
+	if m.integrityCheck {
+		problems, err := m.db.IntegrityCheck()
+		if err != nil {
+			return err
+		}
+		ok := len(problems) == 0
+		status.IntegrityOK = &ok
+		status.IntegrityErrors = problems
+	}
+	return nil
+}
+
+// convertToIncremental switches auto_vacuum modes, which only takes effect
+// through a VACUUM on the same connection
+func (m *Maintenance) convertToIncremental() error {
+	conn, err := m.db.sqlDB().Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return fmt.Errorf("failed to enable incremental vacuum: %w", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	return nil
+}
+
+// IntegrityCheck runs PRAGMA integrity_check and returns the problems it found
+func (d *Database) IntegrityCheck() ([]string, error) {
+	rows, err := d.sqlDB().Query(fmt.Sprintf("PRAGMA integrity_check(%d)", maxIntegrityErrors))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("failed to read integrity check: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	return problems, rows.Err()
+}
+
+func pragmaInt(d *Database, name string) (int, error) {
+	var value int
+	if err := d.sqlDB().QueryRow("PRAGMA " + name).Scan(&value); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return value, nil
+}
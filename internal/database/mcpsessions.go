@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createMCPSessionsTableSQL = `
+-- MCP sessions started through routes tracking them, kept after they end
+CREATE TABLE IF NOT EXISTS mcp_sessions (
+    id TEXT PRIMARY KEY,
+    route TEXT NOT NULL,
+    protocol_version TEXT,
+    client_name TEXT,
+    client_version TEXT,
+    server_name TEXT,
+    server_version TEXT,
+    api_key TEXT,
+    tenant TEXT,
+    ip_address TEXT,
+    initialize_request_id TEXT NOT NULL,
+    initialized INTEGER NOT NULL DEFAULT 0,
+    calls INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    ended_at DATETIME,
+    end_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_sessions_last_seen ON mcp_sessions(last_seen_at);
+`
+
+// ErrMCPSessionNotFound is returned when no MCP session with the given id exists
+var ErrMCPSessionNotFound = errors.New("mcp session not found")
+
+const mcpSessionColumns = `id, route, protocol_version, client_name, client_version, server_name, server_version,
+	api_key, tenant, ip_address, initialize_request_id, initialized, calls, created_at, last_seen_at, expires_at,
+	ended_at, end_reason`
+
+func scanMCPSession(row rowScanner) (types.MCPSession, error) {
+	var s types.MCPSession
+	var protocolVersion, clientName, clientVersion, serverName, serverVersion sql.NullString
+	var apiKey, tenant, ipAddress, endReason sql.NullString
+	var endedAt sql.NullTime
+	err := row.Scan(&s.ID, &s.Route, &protocolVersion, &clientName, &clientVersion, &serverName, &serverVersion,
+		&apiKey, &tenant, &ipAddress, &s.InitializeRequestID, &s.Initialized, &s.Calls, &s.CreatedAt, &s.LastSeenAt,
+		&s.ExpiresAt, &endedAt, &endReason)
+	if err != nil {
+		return s, err
+	}
+	s.ProtocolVersion, s.ClientName, s.ClientVersion = protocolVersion.String, clientName.String, clientVersion.String
+	s.ServerName, s.ServerVersion = serverName.String, serverVersion.String
+	s.APIKey, s.Tenant, s.IPAddress, s.EndReason = apiKey.String, tenant.String, ipAddress.String, endReason.String
+	if endedAt.Valid {
+		s.EndedAt = &endedAt.Time
+	}
+	return s, nil
+}
+
+// SaveMCPSession inserts an MCP session or updates the stored one
+func (d *Database) SaveMCPSession(s *types.MCPSession) error {
+	var endedAt interface{}
+	if s.EndedAt != nil {
+		endedAt = *s.EndedAt
+	}
+	_, err := d.sqlDB().Exec(`
+		INSERT INTO mcp_sessions (`+mcpSessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			protocol_version = excluded.protocol_version,
+			server_name = excluded.server_name,
+			server_version = excluded.server_version,
+			initialized = excluded.initialized,
+			calls = excluded.calls,
+			last_seen_at = excluded.last_seen_at,
+			expires_at = excluded.expires_at,
+			ended_at = excluded.ended_at,
+			end_reason = excluded.end_reason`,
+		s.ID, s.Route, nullIfEmpty(s.ProtocolVersion), nullIfEmpty(s.ClientName), nullIfEmpty(s.ClientVersion),
+		nullIfEmpty(s.ServerName), nullIfEmpty(s.ServerVersion), nullIfEmpty(s.APIKey), nullIfEmpty(s.Tenant),
+		nullIfEmpty(s.IPAddress), s.InitializeRequestID, s.Initialized, s.Calls, s.CreatedAt, s.LastSeenAt,
+		s.ExpiresAt, endedAt, nullIfEmpty(s.EndReason))
+	if err != nil {
+		return fmt.Errorf("failed to save mcp session %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+// GetMCPSession returns the MCP session with the given id or ErrMCPSessionNotFound
+func (d *Database) GetMCPSession(id string) (*types.MCPSession, error) {
+	s, err := scanMCPSession(d.sqlDB().QueryRow("SELECT "+mcpSessionColumns+" FROM mcp_sessions WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrMCPSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mcp session %s: %w", id, err)
+	}
+	s.Status = s.StatusAt(d.now())
+	return &s, nil
+}
+
+// ListMCPSessions returns MCP sessions matching filter, most recently seen first
+func (d *Database) ListMCPSessions(filter types.MCPSessionFilter, limit, offset int) ([]types.MCPSession, error) {
+	now := d.now()
+	var conditions []string
+	var args []interface{}
+	if filter.Route != "" {
+		conditions = append(conditions, "route = ?")
+		args = append(args, filter.Route)
+	}
+	if filter.APIKey != "" {
+		conditions = append(conditions, "api_key = ?")
+		args = append(args, filter.APIKey)
+	}
+	switch filter.Status {
+	case types.MCPSessionActive:
+		conditions = append(conditions, "ended_at IS NULL AND expires_at > ?")
+		args = append(args, now)
+	case types.MCPSessionExpired:
+		conditions = append(conditions, "ended_at IS NULL AND expires_at <= ?")
+		args = append(args, now)
+	case types.MCPSessionEnded:
+		conditions = append(conditions, "ended_at IS NOT NULL")
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := d.sqlDB().Query("SELECT "+mcpSessionColumns+" FROM mcp_sessions "+where+
+		" ORDER BY last_seen_at DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mcp sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []types.MCPSession{}
+	for rows.Next() {
+		s, err := scanMCPSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mcp session: %w", err)
+		}
+		s.Status = s.StatusAt(now)
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// paramValueMaxBytes caps the values shown as top values; longer ones are
+// still counted by their full text
+const paramValueMaxBytes = 128
+
+// GetParamStats reads the params of the newest calls in [from, to), at most
+// sample of them, and returns per method and top-level param how many
+// distinct values were passed and the top most frequent ones. Object params
+// are keyed by field name, positional params as [0], [1], ... It also returns
+// the number of calls read; calls stored without their body are skipped.
+func (d *Database) GetParamStats(method string, from, to time.Time, top, sample int) ([]types.MethodParamStats, int, error) {
+	where := "WHERE timestamp >= ? AND timestamp < ?"
+	args := []interface{}{from, to}
+	if method != "" {
+		where += " AND method = ?"
+		args = append(args, method)
+	}
+	rows, err := d.readDB().Query(`
+		SELECT method, request, compressed
+		FROM audit_requests
+		`+where+`
+		ORDER BY id DESC
+		LIMIT ?`, append(args, sample)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query requests: %w", err)
+	}
+	defer rows.Close()
+
+	type paramCounts struct {
+		calls  int
+		values map[string]int
+	}
+	byMethod := make(map[string]map[string]*paramCounts)
+	calls := make(map[string]int)
+	read := 0
+	for rows.Next() {
+		var method string
+		var request []byte
+		var compressed int
+		if err := rows.Scan(&method, &request, &compressed); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan request: %w", err)
+		}
+		read++
+
+		var call struct {
+			Params json.RawMessage `json:"params"`
+		}
+		body := d.unpackPayload(request, compressed&compressedRequest != 0)
+		if json.Unmarshal(body, &call) != nil || len(call.Params) == 0 {
+			continue
+		}
+		params, ok := splitParams(call.Params)
+		if !ok {
+			continue
+		}
+		calls[method]++
+		counts, ok := byMethod[method]
+		if !ok {
+			counts = make(map[string]*paramCounts)
+			byMethod[method] = counts
+		}
+		for name, value := range params {
+			c, ok := counts[name]
+			if !ok {
+				c = &paramCounts{values: make(map[string]int)}
+				counts[name] = c
+			}
+			c.calls++
+			c.values[compactJSON(value)]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read requests: %w", err)
+	}
+
+	methods := make([]types.MethodParamStats, 0, len(byMethod))
+	for method, counts := range byMethod {
+		m := types.MethodParamStats{Method: method, Calls: calls[method], Params: make([]types.ParamStats, 0, len(counts))}
+		for name, c := range counts {
+			p := types.ParamStats{Name: name, Calls: c.calls, Distinct: len(c.values)}
+			for value, count := range c.values {
+				p.TopValues = append(p.TopValues, types.ParamValueCount{Value: value, Count: count})
+			}
+			sort.Slice(p.TopValues, func(i, j int) bool {
+				if p.TopValues[i].Count != p.TopValues[j].Count {
+					return p.TopValues[i].Count > p.TopValues[j].Count
+				}
+				return p.TopValues[i].Value < p.TopValues[j].Value
+			})
+			if len(p.TopValues) > top {
+				p.TopValues = p.TopValues[:top]
+			}
+			for i := range p.TopValues {
+				p.TopValues[i].Share = float64(p.TopValues[i].Count) / float64(c.calls)
+				p.TopValues[i].Value = truncateText(p.TopValues[i].Value, paramValueMaxBytes)
+			}
+			m.Params = append(m.Params, p)
+		}
+		sort.Slice(m.Params, func(i, j int) bool { return m.Params[i].Name < m.Params[j].Name })
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Calls != methods[j].Calls {
+			return methods[i].Calls > methods[j].Calls
+		}
+		return methods[i].Method < methods[j].Method
+	})
+	return methods, read, nil
+}
+
+// splitParams returns the top-level params of a call by name, or by position
+// as [0], [1], ... It reports false for params that are neither an object nor an array.
+func splitParams(raw json.RawMessage) (map[string]json.RawMessage, bool) {
+	var named map[string]json.RawMessage
+	if json.Unmarshal(raw, &named) == nil {
+		return named, named != nil
+	}
+	var positional []json.RawMessage
+	if json.Unmarshal(raw, &positional) != nil {
+		return nil, false
+	}
+	params := make(map[string]json.RawMessage, len(positional))
+	for i, value := range positional {
+		params["["+strconv.Itoa(i)+"]"] = value
+	}
+	return params, true
+}
+
+// compactJSON returns value without insignificant whitespace so equal values count together
+func compactJSON(value json.RawMessage) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, value) != nil {
+		return string(value)
+	}
+	return buf.String()
+}
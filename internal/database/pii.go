@@ -0,0 +1,81 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetPIIReport summarizes the pii.request and pii.response tags per method
+func (d *Database) GetPIIReport() ([]types.PIIMethod, error) {
+	rows, err := d.readDB().Query(`
+		SELECT r.method, t.name, t.value, COUNT(*), MAX(r.timestamp)
+		FROM audit_tags t
+		JOIN audit_requests r ON r.request_id = t.request_id
+		WHERE t.name IN (?, ?)
+		GROUP BY r.method, t.name, t.value`, types.PIIRequestTag, types.PIIResponseTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query PII tags: %w", err)
+	}
+	defer rows.Close()
+
+	byMethod := make(map[string]*types.PIIMethod)
+	for rows.Next() {
+		var method, name, value, lastSeen string
+		var count int
+		if err := rows.Scan(&method, &name, &value, &count, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan PII tags: %w", err)
+		}
+
+		m, ok := byMethod[method]
+		if !ok {
+			m = &types.PIIMethod{Method: method, RequestKinds: map[string]int{}, ResponseKinds: map[string]int{}}
+			byMethod[method] = m
+		}
+		kinds := m.RequestKinds
+		if name == types.PIIResponseTag {
+			kinds = m.ResponseKinds
+		}
+		for _, kind := range strings.Split(value, ",") {
+			kinds[kind] += count
+		}
+		if seen := parseSQLiteTime(lastSeen); seen.After(m.LastSeen) {
+			m.LastSeen = seen
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read PII tags: %w", err)
+	}
+
+	// A call flagged on both sides counts once
+	callRows, err := d.readDB().Query(`
+		SELECT r.method, COUNT(DISTINCT t.request_id)
+		FROM audit_tags t
+		JOIN audit_requests r ON r.request_id = t.request_id
+		WHERE t.name IN (?, ?)
+		GROUP BY r.method`, types.PIIRequestTag, types.PIIResponseTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count PII calls: %w", err)
+	}
+	defer callRows.Close()
+
+	for callRows.Next() {
+		var method string
+		var calls int
+		if err := callRows.Scan(&method, &calls); err != nil {
+			return nil, fmt.Errorf("failed to scan PII calls: %w", err)
+		}
+		if m, ok := byMethod[method]; ok {
+			m.Calls = calls
+		}
+	}
+
+	methods := make([]types.PIIMethod, 0, len(byMethod))
+	for _, m := range byMethod {
+		methods = append(methods, *m)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Calls > methods[j].Calls })
+	return methods, nil
+}
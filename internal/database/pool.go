@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Pool limits a connection pool. Zero fields keep the defaults of database/sql.
+type Pool struct {
+	MaxOpen     int
+	MaxIdle     int
+	MaxLifetime time.Duration
+	MaxIdleTime time.Duration
+}
+
+func (p Pool) apply(db *sql.DB) {
+	if p.MaxOpen > 0 {
+		db.SetMaxOpenConns(p.MaxOpen)
+	}
+	if p.MaxIdle > 0 {
+		db.SetMaxIdleConns(p.MaxIdle)
+	}
+	if p.MaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.MaxLifetime)
+	}
+	if p.MaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.MaxIdleTime)
+	}
+}
+
+// SetPool limits the connection pool of the database, including the files
+// it rotates to
+func (d *Database) SetPool(p Pool) {
+	d.pool = p
+	p.apply(d.sqlDB())
+}
+
+// OpenReader sends the heavy read queries of the dashboard and the stats
+// endpoints to the database at dsn, e.g. a replica of the audit database
+// or the same file opened read-only, so they do not hold up audit writes.
+// Reads of single calls stay on the write pool, a replica may lag behind.
+func (d *Database) OpenReader(dsn string, p Pool) error {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open read database: %w", err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM audit_requests LIMIT 1"); err != nil {
+		db.Close()
+		return fmt.Errorf("read database %s has no audit tables: %w", dsn, err)
+	}
+	p.apply(db)
+	d.reader = db
+	return nil
+}
+
+// readDB returns the pool serving heavy read queries, the read replica when one is open
+func (d *Database) readDB() *sql.DB {
+	if d.reader != nil {
+		return d.reader
+	}
+	return d.sqlDB()
+}
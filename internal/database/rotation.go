@@ -0,0 +1,355 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Rotation intervals
+const (
+	RotateHourly = "hourly"
+	RotateDaily  = "daily"
+)
+
+// closeDelay lets queries that picked up the previous file finish before it is closed
+const closeDelay = 10 * time.Second
+
+// RotationPolicy decides when audit writes move to a fresh SQLite file
+type RotationPolicy struct {
+	Interval string // hourly, daily, or empty for size-only rotation
+	MaxBytes int64  // Rotate once the file and its WAL reach this size, 0 disables
+}
+
+// Rotator moves audit writes to a new SQLite file every period or once the
+// active file grows too large. Files are named <stem>-<period>[.<n>].db next to
+// the base path and <stem>-current.db links to the active one. The Database
+// keeps its identity across rotations, so the audit API serves the active file
+// while older files stay in place for archiving; only single calls looked up
+// by request ID are also searched for in the rotated files.
+type Rotator struct {
+	db     *Database
+	dir    string
+	stem   string
+	policy RotationPolicy
+
+	mu     sync.Mutex
+	active string // Path of the active file
+	period string // Period label of the active file
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenRotating opens the active file of a rotating database named after basePath,
+// e.g. audit.db rotates through audit-2024-06-01.db, audit-2024-06-02.db, ...
+func OpenRotating(basePath string, policy RotationPolicy) (*Database, *Rotator, error) {
+	switch policy.Interval {
+	case "", RotateHourly, RotateDaily:
+	default:
+		return nil, nil, fmt.Errorf("unknown rotation interval %q", policy.Interval)
+	}
+	if policy.Interval == "" && policy.MaxBytes <= 0 {
+		return nil, nil, fmt.Errorf("rotation needs an interval or a maximum size")
+	}
+
+	ext := filepath.Ext(basePath)
+	r := &Rotator{
+		dir:    filepath.Dir(basePath),
+		stem:   strings.TrimSuffix(filepath.Base(basePath), ext),
+		policy: policy,
+	}
+	r.db = &Database{archives: r.archived}
+
+	// Keep writing to the file of a previous run while it is still current
+	period := r.periodOf(r.db.now())
+	path, previous := "", ""
+	if target, err := os.Readlink(r.linkPath()); err == nil {
+		previous = filepath.Join(r.dir, target)
+		label := r.fileOf(filepath.Base(previous))
+		if label != "" && (policy.Interval == "" || label == period) && !r.full(previous) {
+			path = previous
+		}
+	}
+	if path == "" {
+		path = r.nextPath(period)
+	}
+
+	conn, err := openSQLite(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if previous != "" && previous != path {
+		if err := copyClients(conn, previous); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	r.db.conn.Store(conn)
+	r.active, r.period = path, period
+	if err := r.link(path); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	log.Printf("Audit database rotation enabled, active file %s", path)
+	return r.db, r, nil
+}
+
+// periodOf returns the period label of files created at t
+func (r *Rotator) periodOf(t time.Time) string {
+	t = t.UTC()
+	switch r.policy.Interval {
+	case RotateHourly:
+		return t.Format("2006-01-02T15")
+	case RotateDaily:
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02T150405")
+}
+
+// fileOf returns the period label of a rotated file name, "" for other files
+func (r *Rotator) fileOf(name string) string {
+	label, ok := strings.CutPrefix(strings.TrimSuffix(name, ".db"), r.stem+"-")
+	if !ok || label == "current" {
+		return ""
+	}
+	// Strip the .<n> suffix of files created by size rotation within a period
+	if i := strings.LastIndex(label, "."); i > 0 {
+		if _, err := strconv.Atoi(label[i+1:]); err == nil {
+			label = label[:i]
+		}
+	}
+	return label
+}
+
+func (r *Rotator) linkPath() string {
+	return filepath.Join(r.dir, r.stem+"-current.db")
+}
+
+// nextPath returns the first unused file name for period
+func (r *Rotator) nextPath(period string) string {
+	path := filepath.Join(r.dir, fmt.Sprintf("%s-%s.db", r.stem, period))
+	for n := 1; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(r.dir, fmt.Sprintf("%s-%s.%d.db", r.stem, period, n))
+	}
+}
+
+// full reports whether path reached the size limit, counting its WAL
+func (r *Rotator) full(path string) bool {
+	if r.policy.MaxBytes <= 0 {
+		return false
+	}
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			size += info.Size()
+		}
+	}
+	return size >= r.policy.MaxBytes
+}
+
+// link points <stem>-current.db at path, replacing the previous link atomically
+func (r *Rotator) link(path string) error {
+	tmp := r.linkPath() + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(path), tmp); err != nil {
+		return fmt.Errorf("failed to link active database: %w", err)
+	}
+	if err := os.Rename(tmp, r.linkPath()); err != nil {
+		return fmt.Errorf("failed to link active database: %w", err)
+	}
+	return nil
+}
+
+// Active returns the path of the file currently receiving audit rows
+func (r *Rotator) Active() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active
+}
+
+// Rotate switches audit writes to a new file. Managed API clients are copied
+// over so keys keep working; audit rows, annotations and sync checkpoints stay
+// with the file they belong to.
+func (r *Rotator) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	period := r.periodOf(r.db.now())
+	path := r.nextPath(period)
+	conn, err := openSQLite(path)
+	if err != nil {
+		return err
+	}
+	if err := copyClients(conn, r.active); err != nil {
+		conn.Close()
+		return err
+	}
+	if err := r.link(path); err != nil {
+		conn.Close()
+		return err
+	}
+
+	r.db.pool.apply(conn)
+	previous := r.db.conn.Swap(conn)
+	time.AfterFunc(closeDelay, func() {
+		if err := previous.Close(); err != nil {
+			log.Printf("Failed to close rotated database: %v", err)
+		}
+	})
+
+	log.Printf("Rotated audit database from %s to %s", r.active, path)
+	r.active, r.period = path, period
+	return nil
+}
+
+// copyClients copies the managed API clients of the file at previous into conn
+func copyClients(conn *sql.DB, previous string) error {
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to copy clients: %w", err)
+	}
+	defer c.Close()
+
+	if _, err := c.ExecContext(ctx, "ATTACH DATABASE ? AS previous", previous); err != nil {
+		return fmt.Errorf("failed to attach previous database: %w", err)
+	}
+	defer c.ExecContext(ctx, "DETACH DATABASE previous")
+
+	_, err = c.ExecContext(ctx, "INSERT OR IGNORE INTO clients ("+clientColumns+") SELECT "+clientColumns+" FROM previous.clients")
+	if err != nil {
+		return fmt.Errorf("failed to copy clients: %w", err)
+	}
+	return nil
+}
+
+// due reports whether the active file should be rotated
+func (r *Rotator) due(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.policy.Interval != "" && r.periodOf(now) != r.period {
+		return true
+	}
+	return r.full(r.active)
+}
+
+// Start checks every interval whether the active file is due for rotation
+func (r *Rotator) Start(interval time.Duration) {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if r.due(r.db.now()) {
+					if err := r.Rotate(); err != nil {
+						log.Printf("Failed to rotate audit database: %v", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the rotation checks
+func (r *Rotator) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+// archived returns the paths of the rotated files other than the active one, newest first
+func (r *Rotator) archived() []string {
+	paths, err := filepath.Glob(filepath.Join(r.dir, r.stem+"-*.db"))
+	if err != nil {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	active := r.Active()
+	var archived []string
+	for _, path := range paths {
+		if path != active && r.fileOf(filepath.Base(path)) != "" {
+			archived = append(archived, path)
+		}
+	}
+	return archived
+}
+
+// archivedAuditLog looks a call up in the rotated files, newest first, so
+// links to calls keep working after the file holding them was rotated out
+func (d *Database) archivedAuditLog(requestID string) (*types.AuditLog, error) {
+	if d.archives == nil {
+		return nil, nil
+	}
+	for _, path := range d.archives() {
+		conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open rotated database: %w", err)
+		}
+		archive := &Database{cipher: d.cipher}
+		archive.conn.Store(conn)
+		entry, err := archive.GetAuditLog(requestID)
+		conn.Close()
+		if err != nil {
+			log.Printf("Skipping rotated database %s: %v", path, err)
+			continue
+		}
+		if entry != nil {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// Files lists the rotated files, oldest first
+func (r *Rotator) Files() ([]types.DatabaseFile, error) {
+	paths, err := filepath.Glob(filepath.Join(r.dir, r.stem+"-*.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list database files: %w", err)
+	}
+	sort.Strings(paths)
+
+	active := r.Active()
+	files := []types.DatabaseFile{}
+	for _, path := range paths {
+		if r.fileOf(filepath.Base(path)) == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, types.DatabaseFile{
+			Name:       info.Name(),
+			SizeBytes:  info.Size(),
+			ModifiedAt: info.ModTime(),
+			Active:     path == active,
+		})
+	}
+	return files, nil
+}
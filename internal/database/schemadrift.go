@@ -0,0 +1,91 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createSchemaDriftTableSQL = `
+-- Differences between upstream results and the registered response schemas
+CREATE TABLE IF NOT EXISTS schema_drift (
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    expected TEXT NOT NULL DEFAULT '',
+    observed TEXT NOT NULL DEFAULT '',
+    count INTEGER NOT NULL DEFAULT 0,
+    first_seen DATETIME NOT NULL,
+    last_seen DATETIME NOT NULL,
+    sample_request_id TEXT,
+    PRIMARY KEY (method, path, kind, observed)
+);
+`
+
+// RecordSchemaDrift adds observations to the drift table, counting repeated
+// differences on their existing row. It returns the differences not seen before.
+func (d *Database) RecordSchemaDrift(drift []types.SchemaDrift) ([]types.SchemaDrift, error) {
+	tx, err := d.sqlDB().Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var added []types.SchemaDrift
+	for _, s := range drift {
+		var exists bool
+		err := tx.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM schema_drift WHERE method = ? AND path = ? AND kind = ? AND observed = ?)
+		`, s.Method, s.Path, s.Kind, s.Observed).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up schema drift: %w", err)
+		}
+		if !exists {
+			added = append(added, s)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO schema_drift (method, path, kind, expected, observed, count, first_seen, last_seen, sample_request_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(method, path, kind, observed) DO UPDATE SET
+				expected = excluded.expected,
+				count = count + excluded.count,
+				last_seen = MAX(last_seen, excluded.last_seen),
+				sample_request_id = excluded.sample_request_id
+		`, s.Method, s.Path, s.Kind, s.Expected, s.Observed, s.Count, s.FirstSeen, s.LastSeen, nullIfEmpty(s.SampleRequestID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to record schema drift: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit schema drift: %w", err)
+	}
+	return added, nil
+}
+
+// GetSchemaDrift returns differences last seen at or after since, newest first,
+// optionally of a single method
+func (d *Database) GetSchemaDrift(method string, since time.Time) ([]types.SchemaDrift, error) {
+	rows, err := d.readDB().Query(`
+		SELECT method, path, kind, expected, observed, count, first_seen, last_seen, COALESCE(sample_request_id, '')
+		FROM schema_drift
+		WHERE (? = '' OR method = ?) AND last_seen >= ?
+		ORDER BY first_seen DESC, method, path
+	`, method, method, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema drift: %w", err)
+	}
+	defer rows.Close()
+
+	drift := []types.SchemaDrift{}
+	for rows.Next() {
+		var s types.SchemaDrift
+		if err := rows.Scan(&s.Method, &s.Path, &s.Kind, &s.Expected, &s.Observed, &s.Count, &s.FirstSeen, &s.LastSeen, &s.SampleRequestID); err != nil {
+			return nil, fmt.Errorf("failed to scan schema drift: %w", err)
+		}
+		drift = append(drift, s)
+	}
+	return drift, rows.Err()
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// ResponseSeries aggregates responses completed in [from, to) into buckets of
+// the given interval. An empty method matches all methods.
+func (d *Database) ResponseSeries(method string, from, to time.Time, interval time.Duration) ([]types.SeriesPoint, error) {
+	if interval <= 0 || !to.After(from) {
+		return nil, fmt.Errorf("invalid series range")
+	}
+
+	buckets := int((to.Sub(from) + interval - 1) / interval)
+	latencies := make([][]int64, buckets)
+	points := make([]types.SeriesPoint, buckets)
+	for i := range points {
+		points[i].Start = from.Add(time.Duration(i) * interval)
+	}
+
+	query := `
+		SELECT resp.timestamp, resp.process_time_ms, resp.error IS NOT NULL AND resp.error != ''
+		FROM audit_responses resp
+		JOIN audit_requests r ON r.request_id = resp.request_id
+		WHERE resp.timestamp >= ? AND resp.timestamp < ?`
+	args := []interface{}{from, to}
+	if method != "" {
+		query += " AND r.method = ?"
+		args = append(args, method)
+	}
+
+	rows, err := d.readDB().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query responses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timestamp time.Time
+		var latency int64
+		var failed bool
+		if err := rows.Scan(&timestamp, &latency, &failed); err != nil {
+			return nil, fmt.Errorf("failed to scan response: %w", err)
+		}
+
+		i := int(timestamp.Sub(from) / interval)
+		if i < 0 || i >= buckets {
+			continue
+		}
+		points[i].Count++
+		if failed {
+			points[i].Errors++
+		}
+		latencies[i] = append(latencies[i], latency)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read responses: %w", err)
+	}
+
+	for i, values := range latencies {
+		if len(values) == 0 {
+			continue
+		}
+		sort.Slice(values, func(a, b int) bool { return values[a] < values[b] })
+		var sum int64
+		for _, v := range values {
+			sum += v
+		}
+		points[i].AvgMs = float64(sum) / float64(len(values))
+		points[i].P50Ms = percentile(values, 0.50)
+		points[i].P95Ms = percentile(values, 0.95)
+		points[i].P99Ms = percentile(values, 0.99)
+		points[i].MaxMs = float64(values[len(values)-1])
+	}
+	return points, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p float64) float64 {
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return float64(sorted[rank])
+}
+
+// ListAnnotatedLogs returns annotated, not deleted, requests made in [from, to),
+// optionally only those carrying tag
+func (d *Database) ListAnnotatedLogs(from, to time.Time, tag string) ([]types.AnnotatedLog, error) {
+	rows, err := d.readDB().Query(`
+		SELECT r.request_id, r.method, r.timestamp, a.note, a.tags, a.resolved, a.updated_at
+		FROM audit_annotations a
+		JOIN audit_requests r ON r.request_id = a.request_id
+		WHERE a.deleted = 0 AND r.timestamp >= ? AND r.timestamp < ?
+		ORDER BY r.timestamp
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []types.AnnotatedLog
+	for rows.Next() {
+		var l types.AnnotatedLog
+		var note, tags sql.NullString
+		if err := rows.Scan(&l.RequestID, &l.Method, &l.Timestamp, &note, &tags, &l.Resolved, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		l.Note = note.String
+		if tags.Valid {
+			json.Unmarshal([]byte(tags.String), &l.Tags)
+		}
+		if tag != "" && !containsTag(l.Tags, tag) {
+			continue
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// LatencyCounts returns, for each start time, the number of responses completed since then
+// and how many of them took at most thresholdMs. An empty method matches all methods.
+func (d *Database) LatencyCounts(method string, thresholdMs int64, starts []time.Time) ([]types.LatencyCount, error) {
+	if len(starts) == 0 {
+		return nil, nil
+	}
+
+	earliest := starts[0]
+	columns := make([]string, 0, 2*len(starts))
+	args := make([]interface{}, 0, 3*len(starts)+2)
+	for _, start := range starts {
+		if start.Before(earliest) {
+			earliest = start
+		}
+		columns = append(columns,
+			"COALESCE(SUM(CASE WHEN resp.timestamp >= ? THEN 1 ELSE 0 END), 0)",
+			"COALESCE(SUM(CASE WHEN resp.timestamp >= ? AND resp.process_time_ms <= ? THEN 1 ELSE 0 END), 0)")
+		args = append(args, start, start, thresholdMs)
+	}
+
+	query := "SELECT " + strings.Join(columns, ", ") + `
+		FROM audit_responses resp
+		JOIN audit_requests r ON r.request_id = resp.request_id
+		WHERE resp.timestamp >= ?`
+	args = append(args, earliest)
+	if method != "" {
+		query += " AND r.method = ?"
+		args = append(args, method)
+	}
+
+	counts := make([]types.LatencyCount, len(starts))
+	dest := make([]interface{}, 0, 2*len(starts))
+	for i := range counts {
+		dest = append(dest, &counts[i].Total, &counts[i].Good)
+	}
+	if err := d.readDB().QueryRow(query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to count latencies: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,506 @@
+package database
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/clock"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// maxReplayAttempts is how often a spooled event may fail while later events
+// are accepted before it is moved to the dead-letter file
+const maxReplayAttempts = 3
+
+// Spool buffers audit events while the underlying store is failing (disk full,
+// database locked) and replays them in order once it recovers. Events are kept
+// in a bounded in-memory buffer and, when a file is configured, appended to an
+// NDJSON spool file so they survive restarts and buffer overflows. Events the
+// store keeps rejecting are moved to a <path>.dead file instead of blocking the queue.
+type Spool struct {
+	target   AuditWriter
+	path     string
+	capacity int
+	cipher   *PayloadCipher
+
+	mu         sync.Mutex
+	file       *os.File
+	buffer     []spoolRecord
+	pending    int
+	overflowed bool // buffer no longer holds every pending event; replay from file
+	dropped    int64
+	degraded   bool
+	lastError  string
+	lastFailed time.Time
+	clock      clock.Clock // Nil means the system clock
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// spoolRecord is one buffered event. Raw payloads are stored as bytes so that
+// bodies which are not valid JSON can still be spooled.
+type spoolRecord struct {
+	Type     string               `json:"type"`
+	Request  *types.AuditRequest  `json:"request,omitempty"`
+	Response *types.AuditResponse `json:"response,omitempty"`
+	spoolPayloads
+	Sealed   string `json:"sealed,omitempty"` // spoolPayloads encrypted with the store's cipher
+	Attempts int    `json:"attempts,omitempty"`
+}
+
+// spoolPayloads are the payload columns of a spooled event, written to the file
+// in the clear only when no cipher is configured
+type spoolPayloads struct {
+	RequestBody  []byte `json:"request_body,omitempty"`
+	Headers      []byte `json:"headers,omitempty"`
+	Extensions   []byte `json:"extensions,omitempty"`
+	ResponseBody []byte `json:"response_body,omitempty"`
+	Transformed  []byte `json:"transformed_response,omitempty"`
+	Debug        []byte `json:"debug,omitempty"`
+}
+
+// NewSpool creates a spool in front of target. An empty path keeps events in memory only.
+// Events left in the spool file by a previous run are replayed on the next attempt.
+func NewSpool(target AuditWriter, path string, capacity int) (*Spool, error) {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+
+	s := &Spool{
+		target:   target,
+		path:     path,
+		capacity: capacity,
+	}
+
+	if path != "" {
+		// Records are only counted here; they are decoded on replay, once SetEncryption has run
+		count, err := countLines(path)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			log.Printf("Found %d spooled audit events from a previous run", count)
+			s.pending = count
+			s.overflowed = true
+			s.degraded = true
+		}
+
+		s.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open spool file: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// InsertAuditRequest writes through to the target, spooling the request on failure
+func (s *Spool) InsertAuditRequest(req *types.AuditRequest) error {
+	record := spoolRecord{Type: "request", Request: req}
+	return s.write(record, func() error { return s.target.InsertAuditRequest(req) })
+}
+
+// InsertAuditResponse writes through to the target, spooling the response on failure
+func (s *Spool) InsertAuditResponse(resp *types.AuditResponse) error {
+	record := spoolRecord{Type: "response", Response: resp}
+	return s.write(record, func() error { return s.target.InsertAuditResponse(resp) })
+}
+
+// SetEncryption seals the payloads of spooled events with c, the cipher of the audit store
+func (s *Spool) SetEncryption(c *PayloadCipher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cipher = c
+}
+
+// SetClock replaces the time source of failure timestamps, e.g. with a clock.Fake in tests
+func (s *Spool) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Spool) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+func (s *Spool) write(record spoolRecord, insert func() error) error {
+	// Keep ordering: once events are pending, new ones queue behind them.
+	// The insert itself runs unlocked so a slow store does not serialize every call.
+	s.mu.Lock()
+	pending := s.pending
+	s.mu.Unlock()
+
+	if pending == 0 {
+		err := insert()
+		if err == nil {
+			return nil
+		}
+		s.mu.Lock()
+		s.markFailed(err)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueue(record)
+	return nil
+}
+
+func (s *Spool) markFailed(err error) {
+	if !s.degraded {
+		log.Printf("Audit storage degraded, spooling events: %v", err)
+	}
+	s.degraded = true
+	s.lastError = err.Error()
+	s.lastFailed = s.now()
+}
+
+// enqueue must be called with the lock held
+func (s *Spool) enqueue(record spoolRecord) {
+	if s.file != nil {
+		if err := s.appendToFile(record); err != nil {
+			log.Printf("Failed to append to spool file: %v", err)
+		}
+	}
+
+	if len(s.buffer) < s.capacity {
+		s.buffer = append(s.buffer, record)
+	} else if s.file != nil {
+		// The file still has every event; replay will read from it
+		s.overflowed = true
+	} else {
+		// Memory-only spool: drop the oldest event
+		s.buffer = append(s.buffer[1:], record)
+		s.dropped++
+		s.pending--
+	}
+	s.pending++
+}
+
+func (s *Spool) appendToFile(record spoolRecord) error {
+	line, err := encodeSpoolRecord(record, s.cipher)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// deadLetter appends records the store keeps rejecting to <path>.dead. A memory-only
+// spool logs and drops them.
+func (s *Spool) deadLetter(record spoolRecord, err error) {
+	log.Printf("Dead-lettering spooled %s event after %d failed attempts: %v", record.Type, record.Attempts, err)
+	if s.path == "" {
+		s.dropped++
+		return
+	}
+
+	line, encErr := encodeSpoolRecord(record, s.cipher)
+	if encErr == nil {
+		var f *os.File
+		if f, encErr = os.OpenFile(s.path+".dead", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600); encErr == nil {
+			_, encErr = f.Write(append(line, '\n'))
+			f.Close()
+		}
+	}
+	if encErr != nil {
+		log.Printf("Failed to write spool dead letter, dropping event: %v", encErr)
+		s.dropped++
+	}
+}
+
+// Replay writes pending events to the target in order, stopping at the first failure.
+// An event that fails while the event after it is accepted is retried on later replays
+// and dead-lettered once it has failed maxReplayAttempts times.
+func (s *Spool) Replay() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		return 0, nil
+	}
+
+	records := s.buffer
+	if s.overflowed {
+		var err error
+		if records, err = s.readFile(); err != nil {
+			return 0, err
+		}
+	}
+
+	replayed := 0
+	accepted := false // the store took an event on this pass, so it is up
+	var remaining []spoolRecord
+	var replayErr error
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		err := s.replayRecord(record)
+		if err == nil {
+			replayed++
+			accepted = true
+			continue
+		}
+
+		// Tell a bad event from an unavailable store by whether the store takes the next one
+		probed := false
+		if !accepted && i+1 < len(records) && s.replayRecord(records[i+1]) == nil {
+			replayed++
+			accepted, probed = true, true
+		}
+		rest := records[i+1:]
+		if probed {
+			rest = records[i+2:]
+		}
+
+		if accepted {
+			record.Attempts++
+			if record.Attempts >= maxReplayAttempts {
+				s.deadLetter(record, err)
+				if probed {
+					i++
+				}
+				continue
+			}
+		}
+		remaining = append([]spoolRecord{record}, rest...)
+		replayErr = err
+		break
+	}
+
+	if err := s.rewrite(remaining); err != nil {
+		log.Printf("Failed to rewrite spool file: %v", err)
+	}
+
+	s.pending = len(remaining)
+	s.overflowed = len(remaining) > s.capacity
+	if s.overflowed {
+		s.buffer = nil
+	} else {
+		s.buffer = append([]spoolRecord(nil), remaining...)
+	}
+
+	if replayErr != nil {
+		s.markFailed(replayErr)
+		return replayed, replayErr
+	}
+
+	if s.degraded {
+		log.Printf("Audit storage recovered, replayed %d spooled events", replayed)
+	}
+	s.degraded = false
+	s.lastError = ""
+	return replayed, nil
+}
+
+// replayRecord writes one spooled event to the target
+func (s *Spool) replayRecord(record spoolRecord) error {
+	var err error
+	switch record.Type {
+	case "request":
+		err = s.target.InsertAuditRequest(record.Request)
+	case "response":
+		err = s.target.InsertAuditResponse(record.Response)
+	}
+	// A request may have been committed before the failure was reported
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return nil
+	}
+	return err
+}
+
+// rewrite replaces the spool file contents with the remaining records
+func (s *Spool) rewrite(records []spoolRecord) error {
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := s.appendToFile(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Spool) readFile() ([]spoolRecord, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool file: %w", err)
+	}
+	defer f.Close()
+
+	var records []spoolRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record, err := s.decodeSpoolRecord(scanner.Bytes())
+		if err != nil {
+			log.Printf("Skipping corrupt spool record: %v", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// countLines returns the number of non-empty lines of the spool file at path
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open spool file: %w", err)
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// encodeSpoolRecord encodes a record as one spool file line, sealing its payloads when c is set
+func encodeSpoolRecord(record spoolRecord, c *PayloadCipher) ([]byte, error) {
+	// Move raw payloads into byte fields on copies so invalid JSON bodies survive encoding
+	if record.Request != nil {
+		req := *record.Request
+		record.RequestBody, record.Headers, record.spoolPayloads.Extensions = req.Request, req.Headers, req.Extensions
+		req.Request, req.Headers, req.Extensions = nil, nil, nil
+		record.Request = &req
+	}
+	if record.Response != nil {
+		resp := *record.Response
+		record.ResponseBody, record.Transformed, record.spoolPayloads.Debug = resp.Response, resp.TransformedResponse, resp.Debug
+		resp.Response, resp.TransformedResponse, resp.Debug = nil, nil, nil
+		record.Response = &resp
+	}
+
+	if c != nil {
+		payloads, err := json.Marshal(record.spoolPayloads)
+		if err != nil {
+			return nil, err
+		}
+		if record.Sealed, err = c.Seal(payloads); err != nil {
+			return nil, fmt.Errorf("failed to seal spool record: %w", err)
+		}
+		record.spoolPayloads = spoolPayloads{}
+	}
+	return json.Marshal(record)
+}
+
+// decodeSpoolRecord decodes a spool file line, opening sealed payloads with the spool's cipher
+func (s *Spool) decodeSpoolRecord(line []byte) (spoolRecord, error) {
+	var record spoolRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return record, err
+	}
+	if record.Sealed != "" {
+		if s.cipher == nil {
+			return record, fmt.Errorf("spool record is encrypted but no key is configured")
+		}
+		payloads, err := s.cipher.Open(record.Sealed)
+		if err != nil {
+			return record, fmt.Errorf("failed to open spool record: %w", err)
+		}
+		if err := json.Unmarshal(payloads, &record.spoolPayloads); err != nil {
+			return record, err
+		}
+		record.Sealed = ""
+	}
+
+	if record.Request != nil {
+		record.Request.Request = record.RequestBody
+		record.Request.Headers = record.Headers
+		record.Request.Extensions = record.spoolPayloads.Extensions
+	}
+	if record.Response != nil {
+		record.Response.Response = record.ResponseBody
+		record.Response.TransformedResponse = record.Transformed
+		record.Response.Debug = record.spoolPayloads.Debug
+	}
+	record.spoolPayloads = spoolPayloads{}
+	return record, nil
+}
+
+// Status reports whether the audit store is degraded and how many events are pending
+func (s *Spool) Status() types.StorageStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := types.StorageStatus{
+		Degraded:  s.degraded,
+		Pending:   s.pending,
+		Dropped:   s.dropped,
+		LastError: s.lastError,
+	}
+	if !s.lastFailed.IsZero() {
+		lastFailed := s.lastFailed
+		status.LastFailureAt = &lastFailed
+	}
+	return status
+}
+
+// Start periodically replays spooled events until Stop is called
+func (s *Spool) Start(interval time.Duration) {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.Replay(); err != nil {
+					log.Printf("Spool replay failed: %v", err)
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the replay loop, makes a final replay attempt, and closes the spool file
+func (s *Spool) Stop() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+
+	if _, err := s.Replay(); err != nil {
+		log.Printf("Final spool replay failed, %d events remain spooled: %v", s.Status().Pending, err)
+	}
+
+	if s.file != nil {
+		return s.file.Close()
+	}
+	return nil
+}
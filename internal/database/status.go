@@ -0,0 +1,47 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// upstreamFailed matches responses the upstream failed: 5xx answers, calls
+// without an answer, and answers that were not valid JSON-RPC. Calls the
+// client abandoned are not the upstream's fault.
+const upstreamFailed = "(resp.status_code >= 500 OR COALESCE(resp.failure_kind, '') NOT IN ('', '" + types.FailureClientCancelled + "') OR resp.malformed_upstream = 1)"
+
+// AvailabilityCounts returns, for each start time, the number of responses
+// completed since then and how many of them the upstream failed
+func (d *Database) AvailabilityCounts(starts []time.Time) ([]types.AvailabilityCount, error) {
+	if len(starts) == 0 {
+		return nil, nil
+	}
+
+	earliest := starts[0]
+	columns := make([]string, 0, 2*len(starts))
+	args := make([]interface{}, 0, 2*len(starts)+1)
+	for _, start := range starts {
+		if start.Before(earliest) {
+			earliest = start
+		}
+		columns = append(columns,
+			"COALESCE(SUM(CASE WHEN resp.timestamp >= ? THEN 1 ELSE 0 END), 0)",
+			"COALESCE(SUM(CASE WHEN resp.timestamp >= ? AND "+upstreamFailed+" THEN 1 ELSE 0 END), 0)")
+		args = append(args, start, start)
+	}
+	args = append(args, earliest)
+
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM audit_responses resp WHERE resp.timestamp >= ?"
+	counts := make([]types.AvailabilityCount, len(starts))
+	dest := make([]interface{}, 0, 2*len(starts))
+	for i := range counts {
+		dest = append(dest, &counts[i].Total, &counts[i].Failed)
+	}
+	if err := d.readDB().QueryRow(query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to count availability: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const createTagsTableSQL = `
+-- Tags promoted from request fields for indexed filtering
+CREATE TABLE IF NOT EXISTS audit_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    FOREIGN KEY (request_id) REFERENCES audit_requests(request_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_tags_name_value ON audit_tags(name, value);
+CREATE INDEX IF NOT EXISTS idx_audit_tags_request_id ON audit_tags(request_id);
+`
+
+// insertTags stores the tags of a request
+func insertTags(exec execer, requestID string, tags map[string]string) error {
+	for name, value := range tags {
+		_, err := exec.Exec("INSERT INTO audit_tags (request_id, name, value) VALUES (?, ?, ?)", requestID, name, value)
+		if err != nil {
+			return fmt.Errorf("failed to insert tag %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// loadTags returns the tags for the given request IDs
+func (d *Database) loadTags(requestIDs []string) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	if len(requestIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(requestIDs)), ",")
+	args := make([]interface{}, len(requestIDs))
+	for i, id := range requestIDs {
+		args[i] = id
+	}
+
+	rows, err := d.sqlDB().Query("SELECT request_id, name, value FROM audit_tags WHERE request_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var requestID, name, value string
+		if err := rows.Scan(&requestID, &name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		if result[requestID] == nil {
+			result[requestID] = make(map[string]string)
+		}
+		result[requestID][name] = value
+	}
+
+	return result, rows.Err()
+}
+
+// attachTags fills in the Tags field of audit logs
+func (d *Database) attachTags(logs []types.AuditLog) error {
+	ids := make([]string, len(logs))
+	for i, l := range logs {
+		ids[i] = l.RequestID
+	}
+
+	tags, err := d.loadTags(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range logs {
+		logs[i].Tags = tags[logs[i].RequestID]
+	}
+	return nil
+}
+
+// SearchAuditLogs retrieves audit logs matching a filter
+func (d *Database) SearchAuditLogs(filter types.AuditLogFilter, limit, offset int) ([]types.AuditLog, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Method != "" {
+		conditions = append(conditions, "method = ?")
+		args = append(args, filter.Method)
+	}
+
+	if filter.Tenant != "" {
+		conditions = append(conditions, "tenant = ?")
+		args = append(args, filter.Tenant)
+	}
+
+	if filter.BodyHash != "" {
+		conditions = append(conditions, "body_hash = ?")
+		args = append(args, filter.BodyHash)
+	}
+
+	for name, value := range filter.Tags {
+		conditions = append(conditions, "request_id IN (SELECT request_id FROM audit_tags WHERE name = ? AND value = ?)")
+		args = append(args, name, value)
+	}
+
+	for name, value := range filter.Headers {
+		conditions = append(conditions, "request_id IN (SELECT request_id FROM audit_headers WHERE name = ? AND value = ?)")
+		args = append(args, name, value)
+	}
+
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_logs`
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	query += `
+		ORDER BY timestamp DESC
+		LIMIT ? OFFSET ?
+	`
+	args = append(args, limit, offset)
+
+	logs, err := d.queryAuditLogs(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search audit logs: %w", err)
+	}
+
+	return logs, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetTenantStats summarizes the traffic of tenant, or of every tenant when
+// tenant is empty. Requests recorded without a tenant are left out.
+func (d *Database) GetTenantStats(tenant string) ([]types.TenantStats, error) {
+	where := "WHERE tenant IS NOT NULL AND tenant != ''"
+	var args []interface{}
+	if tenant != "" {
+		where = "WHERE tenant = ?"
+		args = append(args, tenant)
+	}
+
+	rows, err := d.readDB().Query(`
+		SELECT tenant, COUNT(*),
+			SUM(CASE WHEN status_code = 0 OR status_code >= 400 OR error != '' THEN 1 ELSE 0 END),
+			COALESCE(AVG(CASE WHEN response_id IS NOT NULL THEN process_time_ms END), 0),
+			MAX(timestamp)
+		FROM audit_logs
+		`+where+`
+		GROUP BY tenant`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenant stats: %w", err)
+	}
+	defer rows.Close()
+
+	byTenant := make(map[string]*types.TenantStats)
+	for rows.Next() {
+		s := types.TenantStats{Methods: make(map[string]int)}
+		var lastSeen string
+		if err := rows.Scan(&s.Tenant, &s.Requests, &s.Errors, &s.AvgLatencyMs, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant stats: %w", err)
+		}
+		s.LastSeen = parseSQLiteTime(lastSeen)
+		byTenant[s.Tenant] = &s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tenant stats: %w", err)
+	}
+
+	methodRows, err := d.readDB().Query(`
+		SELECT tenant, method, COUNT(*)
+		FROM audit_requests
+		`+where+`
+		GROUP BY tenant, method`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenant methods: %w", err)
+	}
+	defer methodRows.Close()
+
+	for methodRows.Next() {
+		var name, method string
+		var count int
+		if err := methodRows.Scan(&name, &method, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant methods: %w", err)
+		}
+		if s, ok := byTenant[name]; ok {
+			s.Methods[method] = count
+		}
+	}
+
+	stats := make([]types.TenantStats, 0, len(byTenant))
+	for _, s := range byTenant {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Requests > stats[j].Requests })
+	return stats, nil
+}
+
+// parseSQLiteTime parses a timestamp returned by an aggregate such as MAX(),
+// which the driver hands back as text instead of time.Time
+func parseSQLiteTime(value string) time.Time {
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
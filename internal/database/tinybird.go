@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/niki4smirn/golf/internal/types"
@@ -13,9 +16,15 @@ import (
 
 // TinybirdDatabase handles audit logging to Tinybird Cloud
 type TinybirdDatabase struct {
-	token   string
-	baseURL string
-	client  *http.Client
+	tokenMu    sync.RWMutex
+	token      string
+	baseURL    string
+	client     *http.Client
+	deadLetter *Database // Receives events Tinybird rejects, nil to only return the error
+
+	maxRowBytes         int // Events encoding larger are shrunk by fitEvent, 0 disables
+	oversizedCompressed int64
+	oversizedTruncated  int64
 }
 
 // NewTinybirdDatabase creates a new Tinybird database instance
@@ -26,55 +35,222 @@ func NewTinybirdDatabase(token string) *TinybirdDatabase {
 		client: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		maxRowBytes: DefaultTinybirdMaxRowBytes,
 	}
 }
 
-// InsertAuditRequest sends request data to Tinybird
-func (t *TinybirdDatabase) InsertAuditRequest(req *types.AuditRequest) error {
+// SetToken replaces the API token, e.g. after it was rotated
+func (t *TinybirdDatabase) SetToken(token string) {
+	t.tokenMu.Lock()
+	t.token = token
+	t.tokenMu.Unlock()
+}
+
+func (t *TinybirdDatabase) currentToken() string {
+	t.tokenMu.RLock()
+	defer t.tokenMu.RUnlock()
+	return t.token
+}
+
+// SetBaseURL points the client at a different Tinybird region or API host
+func (t *TinybirdDatabase) SetBaseURL(baseURL string) {
+	t.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// SetDeadLetter keeps events that cannot be delivered in db's tinybird_deadletter
+// table so they can be redriven once the datasource is fixed
+func (t *TinybirdDatabase) SetDeadLetter(db *Database) {
+	t.deadLetter = db
+}
+
+// requestEvent converts an audit request into an audit_requests datasource row
+func requestEvent(req *types.AuditRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              time.Now().UnixNano(),
+		"timestamp":       req.Timestamp.Format("2006-01-02 15:04:05.000"),
+		"method":          req.Method,
+		"request_id":      req.RequestID,
+		"ip_address":      req.IPAddress,
+		"user_agent":      req.UserAgent,
+		"request":         string(req.Request),
+		"headers":         string(req.Headers),
+		"http_method":     req.HTTPMethod,
+		"upstream_url":    req.UpstreamURL,
+		"api_key":         req.APIKey,
+		"tenant":          req.Tenant,
+		"tags":            req.Tags,
+		"content_type":    req.ContentType,
+		"body_encoding":   req.BodyEncoding,
+		"upstream_method": req.UpstreamMethod,
+		"audit_level":     req.AuditLevel,
+		"body_hash":       req.BodyHash,
+		"fingerprint":     req.Fingerprint,
+		"env":             req.Env,
+		"service":         req.Service,
+		"version":         req.Version,
+		"labels":          req.Labels,
+
+		"parent_request_id": req.ParentRequestID,
+		"request_bytes":     req.RequestBytes,
+		"extensions":        string(req.Extensions),
+	}
+}
+
+// responseEvent converts an audit response into an audit_responses datasource row
+func responseEvent(resp *types.AuditResponse) map[string]interface{} {
 	event := map[string]interface{}{
-		"id":         time.Now().UnixNano(),
-		"timestamp":  req.Timestamp.Format("2006-01-02 15:04:05.000"),
-		"method":     req.Method,
-		"request_id": req.RequestID,
-		"ip_address": req.IPAddress,
-		"user_agent": req.UserAgent,
-		"request":    string(req.Request),
-		"headers":    string(req.Headers),
+		"id":                 time.Now().UnixNano(),
+		"request_id":         resp.RequestID,
+		"timestamp":          resp.Timestamp.Format("2006-01-02 15:04:05.000"),
+		"response":           string(resp.Response),
+		"status_code":        resp.StatusCode,
+		"process_time_ms":    resp.ProcessTime,
+		"error":              resp.Error,
+		"malformed_upstream": resp.MalformedUpstream,
+		"rpc_error_code":     resp.RPCErrorCode,
+		"content_type":       resp.ContentType,
+		"body_encoding":      resp.BodyEncoding,
+		"queue_time_ms":      resp.QueueTime,
+		"upstream_time_ms":   resp.UpstreamTime,
+		"failure_kind":       resp.FailureKind,
+		"served_by":          resp.ServedBy,
+		"cache_status":       resp.Cache,
+		"cache_age_ms":       resp.CacheAgeMs,
+		"slow":               resp.Slow,
+		"pii":                resp.PII,
+		"response_bytes":     resp.ResponseBytes,
+		"debug":              string(resp.Debug),
+
+		"transformed_response": string(resp.TransformedResponse),
+		"response_hash":        resp.ResponseHash,
+		"metadata":             resp.Metadata,
+	}
+	if t := resp.Timing; t != nil {
+		event["dns_ms"] = t.DNSMs
+		event["connect_ms"] = t.ConnectMs
+		event["tls_ms"] = t.TLSMs
+		event["ttfb_ms"] = t.TTFBMs
+		event["read_ms"] = t.ReadMs
+		event["reused_conn"] = t.ReusedConn
 	}
+	return event
+}
 
-	return t.sendEvent("audit_requests", event)
+// InsertAuditRequest sends request data to Tinybird
+func (t *TinybirdDatabase) InsertAuditRequest(req *types.AuditRequest) error {
+	return t.deliver("audit_requests", requestEvent(req))
 }
 
 // InsertAuditResponse sends response data to Tinybird
 func (t *TinybirdDatabase) InsertAuditResponse(resp *types.AuditResponse) error {
-	event := map[string]interface{}{
-		"id":              time.Now().UnixNano(),
-		"request_id":      resp.RequestID,
-		"timestamp":       resp.Timestamp.Format("2006-01-02 15:04:05.000"),
-		"response":        string(resp.Response),
-		"status_code":     resp.StatusCode,
-		"process_time_ms": resp.ProcessTime,
-		"error":           resp.Error,
+	return t.deliver("audit_responses", responseEvent(resp))
+}
+
+// InsertAuditRequests sends a batch of requests in a single Events API call
+func (t *TinybirdDatabase) InsertAuditRequests(reqs []types.AuditRequest) error {
+	events := make([]map[string]interface{}, len(reqs))
+	for i := range reqs {
+		events[i] = requestEvent(&reqs[i])
 	}
+	return t.deliver("audit_requests", events...)
+}
 
-	return t.sendEvent("audit_responses", event)
+// InsertAuditResponses sends a batch of responses in a single Events API call
+func (t *TinybirdDatabase) InsertAuditResponses(resps []types.AuditResponse) error {
+	events := make([]map[string]interface{}, len(resps))
+	for i := range resps {
+		events[i] = responseEvent(&resps[i])
+	}
+	return t.deliver("audit_responses", events...)
 }
 
-// sendEvent sends an event to Tinybird Events API
-func (t *TinybirdDatabase) sendEvent(datasource string, event map[string]interface{}) error {
-	url := fmt.Sprintf("%s/v0/events?name=%s", t.baseURL, datasource)
+// TinybirdError is returned when the Events API rejects a batch
+type TinybirdError struct {
+	StatusCode int
+	Body       string
+}
 
-	jsonData, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+func (e *TinybirdError) Error() string {
+	return fmt.Sprintf("tinybird returned status: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// deliver sends events, moving them to the dead-letter table when that fails
+func (t *TinybirdDatabase) deliver(datasource string, events ...map[string]interface{}) error {
+	for _, event := range events {
+		t.fitEvent(datasource, event)
 	}
+	err := t.sendEvents(datasource, events...)
+	if err == nil || t.deadLetter == nil {
+		return err
+	}
+	if dlErr := t.DeadLetter(datasource, err, events...); dlErr != nil {
+		return fmt.Errorf("%w (%v)", err, dlErr)
+	}
+	return fmt.Errorf("%w (kept in dead-letter table)", err)
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+// DeadLetter stores events in the dead-letter table with the reason they were not delivered
+func (t *TinybirdDatabase) DeadLetter(datasource string, reason error, events ...map[string]interface{}) error {
+	if t.deadLetter == nil {
+		return fmt.Errorf("no dead-letter table configured")
+	}
+	raw := make([]json.RawMessage, len(events))
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		raw[i] = data
+	}
+	return t.deadLetter.InsertDeadLetters(datasource, raw, reason)
+}
+
+// DeadLetterRequest stores a request event without trying to send it
+func (t *TinybirdDatabase) DeadLetterRequest(req *types.AuditRequest, reason error) error {
+	return t.DeadLetter("audit_requests", reason, t.fitEvent("audit_requests", requestEvent(req)))
+}
+
+// DeadLetterResponse stores a response event without trying to send it
+func (t *TinybirdDatabase) DeadLetterResponse(resp *types.AuditResponse, reason error) error {
+	return t.DeadLetter("audit_responses", reason, t.fitEvent("audit_responses", responseEvent(resp)))
+}
+
+// sendEvents sends events to Tinybird Events API as NDJSON
+func (t *TinybirdDatabase) sendEvents(datasource string, events ...map[string]interface{}) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+	}
+	return t.postEvents(datasource, len(events), &body)
+}
+
+// sendRawEvents sends events that are already encoded as JSON objects
+func (t *TinybirdDatabase) sendRawEvents(datasource string, events []json.RawMessage) error {
+	var body bytes.Buffer
+	for _, event := range events {
+		body.Write(event)
+		body.WriteByte('\n')
+	}
+	return t.postEvents(datasource, len(events), &body)
+}
+
+// postEvents posts an NDJSON body of count events to the Events API
+func (t *TinybirdDatabase) postEvents(datasource string, count int, body *bytes.Buffer) error {
+	if count == 0 {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/v0/events?name=%s", t.baseURL, datasource)
+
+	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+t.token)
+	req.Header.Set("Authorization", "Bearer "+t.currentToken())
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := t.client.Do(req)
@@ -86,12 +262,76 @@ func (t *TinybirdDatabase) sendEvent(datasource string, event map[string]interfa
 	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
 		// Read response body for better error details
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("tinybird returned status: %d, body: %s", resp.StatusCode, string(body))
+		return &TinybirdError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
 }
 
+// query runs SQL against Tinybird's Query API and decodes the JSON rows
+func (t *TinybirdDatabase) query(sql string, rows interface{}) error {
+	form := url.Values{"q": {sql + " FORMAT JSON"}}
+
+	req, err := http.NewRequest("POST", t.baseURL+"/v0/sql", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create query request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+t.currentToken())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query tinybird: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("tinybird query returned status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	result := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode query result: %w", err)
+	}
+
+	return json.Unmarshal(result.Data, rows)
+}
+
+// quoteString escapes a value for use as a ClickHouse string literal
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// ExistingRequestIDs returns which of the given request IDs already exist in a datasource
+func (t *TinybirdDatabase) ExistingRequestIDs(datasource string, requestIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(requestIDs) == 0 {
+		return existing, nil
+	}
+
+	quoted := make([]string, len(requestIDs))
+	for i, id := range requestIDs {
+		quoted[i] = quoteString(id)
+	}
+
+	var rows []struct {
+		RequestID string `json:"request_id"`
+	}
+	sql := fmt.Sprintf("SELECT DISTINCT request_id FROM %s WHERE request_id IN (%s)", datasource, strings.Join(quoted, ","))
+	if err := t.query(sql, &rows); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		existing[row.RequestID] = true
+	}
+	return existing, nil
+}
+
 // Close is a no-op for Tinybird (HTTP-based)
 func (t *TinybirdDatabase) Close() error {
 	return nil
@@ -101,13 +341,29 @@ func (t *TinybirdDatabase) Close() error {
 func (t *TinybirdDatabase) InsertAuditLog(log *types.AuditLog) error {
 	// Insert request first
 	req := &types.AuditRequest{
-		Timestamp: log.Timestamp,
-		Method:    log.Method,
-		RequestID: log.RequestID,
-		IPAddress: log.IPAddress,
-		UserAgent: log.UserAgent,
-		Request:   log.Request,
-		Headers:   log.Headers,
+		Timestamp:      log.Timestamp,
+		Method:         log.Method,
+		RequestID:      log.RequestID,
+		IPAddress:      log.IPAddress,
+		UserAgent:      log.UserAgent,
+		Request:        log.Request,
+		Headers:        log.Headers,
+		HTTPMethod:     log.HTTPMethod,
+		UpstreamURL:    log.UpstreamURL,
+		APIKey:         log.APIKey,
+		Tenant:         log.Tenant,
+		Tags:           log.Tags,
+		ContentType:    log.ContentType,
+		BodyEncoding:   log.BodyEncoding,
+		UpstreamMethod: log.UpstreamMethod,
+		AuditLevel:     log.AuditLevel,
+		BodyHash:       log.BodyHash,
+		Fingerprint:    log.Fingerprint,
+		Deployment:     log.Deployment,
+
+		ParentRequestID: log.ParentRequestID,
+		RequestBytes:    log.RequestBytes,
+		Extensions:      log.Extensions,
 	}
 
 	if err := t.InsertAuditRequest(req); err != nil {
@@ -117,12 +373,29 @@ func (t *TinybirdDatabase) InsertAuditLog(log *types.AuditLog) error {
 	// Insert response if we have data
 	if log.StatusCode > 0 || log.Response != nil || log.Error != "" {
 		resp := &types.AuditResponse{
-			RequestID:   log.RequestID,
-			Timestamp:   log.Timestamp,
-			Response:    log.Response,
-			StatusCode:  log.StatusCode,
-			ProcessTime: log.ProcessTime,
-			Error:       log.Error,
+			RequestID:         log.RequestID,
+			Timestamp:         log.Timestamp,
+			Response:          log.Response,
+			StatusCode:        log.StatusCode,
+			ProcessTime:       log.ProcessTime,
+			Error:             log.Error,
+			MalformedUpstream: log.MalformedUpstream,
+			RPCErrorCode:      log.RPCErrorCode,
+			ContentType:       log.ResponseContentType,
+			BodyEncoding:      log.ResponseBodyEncoding,
+			QueueTime:         log.QueueTime,
+			UpstreamTime:      log.UpstreamTime,
+			FailureKind:       log.FailureKind,
+			ServedBy:          log.ServedBy,
+			Cache:             log.Cache,
+			CacheAgeMs:        log.CacheAgeMs,
+			ResponseBytes:     log.ResponseBytes,
+			Debug:             log.Debug,
+			Timing:            log.Timing,
+
+			TransformedResponse: log.TransformedResponse,
+			ResponseHash:        log.ResponseHash,
+			Metadata:            log.Metadata,
 		}
 
 		return t.InsertAuditResponse(resp)
@@ -130,29 +403,3 @@ func (t *TinybirdDatabase) InsertAuditLog(log *types.AuditLog) error {
 
 	return nil
 }
-
-// Note: Query methods would need to be implemented using Tinybird's Query API
-// For now, we'll keep SQLite for reads and use Tinybird for writes
-func (t *TinybirdDatabase) GetAuditRequests(limit, offset int) ([]types.AuditRequest, error) {
-	return nil, fmt.Errorf("read operations not implemented for Tinybird adapter")
-}
-
-func (t *TinybirdDatabase) GetAuditResponses(limit, offset int) ([]types.AuditResponse, error) {
-	return nil, fmt.Errorf("read operations not implemented for Tinybird adapter")
-}
-
-func (t *TinybirdDatabase) GetOrphanedRequests(limit, offset int) ([]types.AuditRequest, error) {
-	return nil, fmt.Errorf("read operations not implemented for Tinybird adapter")
-}
-
-func (t *TinybirdDatabase) GetAuditLogs(limit, offset int) ([]types.AuditLog, error) {
-	return nil, fmt.Errorf("read operations not implemented for Tinybird adapter")
-}
-
-func (t *TinybirdDatabase) GetAuditLogsByMethod(method string, limit, offset int) ([]types.AuditLog, error) {
-	return nil, fmt.Errorf("read operations not implemented for Tinybird adapter")
-}
-
-func (t *TinybirdDatabase) GetStats() (map[string]interface{}, error) {
-	return nil, fmt.Errorf("read operations not implemented for Tinybird adapter")
-}
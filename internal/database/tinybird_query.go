@@ -0,0 +1,460 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Reads over Tinybird's Query API, so a gateway can run without SQLite and
+// still serve the basic /audit endpoints.
+
+// chInt decodes ClickHouse integers, which the JSON format quotes when they are 64-bit
+type chInt int64
+
+func (n *chInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s: %w", data, err)
+	}
+	*n = chInt(v)
+	return nil
+}
+
+// tinybirdRequestColumns are decoded by tinybirdRequestRow
+const tinybirdRequestColumns = `id, toUnixTimestamp64Milli(timestamp) AS ts, method, request_id, ip_address,
+	user_agent, request, headers, http_method, upstream_url, api_key, tenant, tags, content_type,
+	body_encoding, upstream_method, audit_level, env, service, version, labels, body_hash, fingerprint, parent_request_id,
+	request_bytes, extensions, compressed_columns, truncated_columns`
+
+// tinybirdResponseColumns are decoded by tinybirdResponseRow
+const tinybirdResponseColumns = `id, request_id, toUnixTimestamp64Milli(timestamp) AS ts, response, status_code,
+	process_time_ms, error, malformed_upstream, rpc_error_code, content_type, body_encoding,
+	queue_time_ms, upstream_time_ms, failure_kind, slow, served_by, cache_status, cache_age_ms, response_bytes, debug,
+	dns_ms, connect_ms, tls_ms, ttfb_ms, read_ms, reused_conn, transformed_response, response_hash, metadata,
+	compressed_columns, truncated_columns`
+
+type tinybirdRequestRow struct {
+	ID             chInt             `json:"id"`
+	Timestamp      chInt             `json:"ts"`
+	Method         string            `json:"method"`
+	RequestID      string            `json:"request_id"`
+	IPAddress      string            `json:"ip_address"`
+	UserAgent      string            `json:"user_agent"`
+	Request        string            `json:"request"`
+	Headers        string            `json:"headers"`
+	HTTPMethod     string            `json:"http_method"`
+	UpstreamURL    string            `json:"upstream_url"`
+	APIKey         string            `json:"api_key"`
+	Tenant         string            `json:"tenant"`
+	Tags           map[string]string `json:"tags"`
+	ContentType    string            `json:"content_type"`
+	BodyEncoding   string            `json:"body_encoding"`
+	UpstreamMethod string            `json:"upstream_method"`
+	AuditLevel     string            `json:"audit_level"`
+	Env            string            `json:"env"`
+	Service        string            `json:"service"`
+	Version        string            `json:"version"`
+	Labels         map[string]string `json:"labels"`
+	BodyHash       string            `json:"body_hash"`
+	Fingerprint    string            `json:"fingerprint"`
+
+	ParentRequestID string `json:"parent_request_id"`
+	RequestBytes    chInt  `json:"request_bytes"`
+	Extensions      string `json:"extensions"`
+
+	CompressedColumns []string `json:"compressed_columns"`
+	TruncatedColumns  []string `json:"truncated_columns"`
+}
+
+func (row tinybirdRequestRow) auditRequest() types.AuditRequest {
+	req := types.AuditRequest{
+		ID:             int64(row.ID),
+		Timestamp:      time.UnixMilli(int64(row.Timestamp)),
+		Method:         row.Method,
+		RequestID:      row.RequestID,
+		IPAddress:      row.IPAddress,
+		UserAgent:      row.UserAgent,
+		Request:        restorePayload("request", row.Request, row.CompressedColumns, row.TruncatedColumns),
+		Headers:        restorePayload("headers", row.Headers, row.CompressedColumns, row.TruncatedColumns),
+		HTTPMethod:     row.HTTPMethod,
+		UpstreamURL:    row.UpstreamURL,
+		APIKey:         row.APIKey,
+		Tenant:         row.Tenant,
+		ContentType:    row.ContentType,
+		BodyEncoding:   row.BodyEncoding,
+		UpstreamMethod: row.UpstreamMethod,
+		AuditLevel:     row.AuditLevel,
+		BodyHash:       row.BodyHash,
+		Fingerprint:    row.Fingerprint,
+		Deployment:     types.Deployment{Env: row.Env, Service: row.Service, Version: row.Version},
+
+		ParentRequestID: row.ParentRequestID,
+		RequestBytes:    int64(row.RequestBytes),
+		Extensions:      restorePayload("extensions", row.Extensions, row.CompressedColumns, row.TruncatedColumns),
+	}
+	if len(row.Tags) > 0 {
+		req.Tags = row.Tags
+	}
+	if len(row.Labels) > 0 {
+		req.Labels = row.Labels
+	}
+	return req
+}
+
+type tinybirdResponseRow struct {
+	ID                chInt  `json:"id"`
+	RequestID         string `json:"request_id"`
+	Timestamp         chInt  `json:"ts"`
+	Response          string `json:"response"`
+	StatusCode        int    `json:"status_code"`
+	ProcessTime       int64  `json:"process_time_ms"`
+	Error             string `json:"error"`
+	MalformedUpstream bool   `json:"malformed_upstream"`
+	RPCErrorCode      *int   `json:"rpc_error_code"`
+	ContentType       string `json:"content_type"`
+	BodyEncoding      string `json:"body_encoding"`
+	QueueTime         int64  `json:"queue_time_ms"`
+	UpstreamTime      int64  `json:"upstream_time_ms"`
+	FailureKind       string `json:"failure_kind"`
+	Slow              bool   `json:"slow"`
+	ServedBy          string `json:"served_by"`
+	Cache             string `json:"cache_status"`
+	CacheAgeMs        int64  `json:"cache_age_ms"`
+	ResponseBytes     chInt  `json:"response_bytes"`
+	Debug             string `json:"debug"`
+
+	DNSMs      *float64 `json:"dns_ms"`
+	ConnectMs  *float64 `json:"connect_ms"`
+	TLSMs      *float64 `json:"tls_ms"`
+	TTFBMs     *float64 `json:"ttfb_ms"`
+	ReadMs     *float64 `json:"read_ms"`
+	ReusedConn *bool    `json:"reused_conn"`
+
+	TransformedResponse string            `json:"transformed_response"`
+	ResponseHash        string            `json:"response_hash"`
+	Metadata            map[string]string `json:"metadata"`
+
+	CompressedColumns []string `json:"compressed_columns"`
+	TruncatedColumns  []string `json:"truncated_columns"`
+}
+
+func (row tinybirdResponseRow) auditResponse() types.AuditResponse {
+	return types.AuditResponse{
+		ID:                int64(row.ID),
+		RequestID:         row.RequestID,
+		Timestamp:         time.UnixMilli(int64(row.Timestamp)),
+		Response:          restorePayload("response", row.Response, row.CompressedColumns, row.TruncatedColumns),
+		StatusCode:        row.StatusCode,
+		ProcessTime:       row.ProcessTime,
+		Error:             row.Error,
+		MalformedUpstream: row.MalformedUpstream,
+		RPCErrorCode:      row.RPCErrorCode,
+		ContentType:       row.ContentType,
+		BodyEncoding:      row.BodyEncoding,
+		QueueTime:         row.QueueTime,
+		UpstreamTime:      row.UpstreamTime,
+		FailureKind:       row.FailureKind,
+		Slow:              row.Slow,
+		ServedBy:          row.ServedBy,
+		Cache:             row.Cache,
+		CacheAgeMs:        row.CacheAgeMs,
+		ResponseBytes:     int64(row.ResponseBytes),
+		Debug:             restorePayload("debug", row.Debug, row.CompressedColumns, row.TruncatedColumns),
+		Timing:            row.timing(),
+
+		TransformedResponse: restorePayload("transformed_response", row.TransformedResponse, row.CompressedColumns, row.TruncatedColumns),
+		ResponseHash:        row.ResponseHash,
+		Metadata:            row.Metadata,
+	}
+}
+
+// timing returns the upstream timing of the row, nil for calls that were not timed
+func (row tinybirdResponseRow) timing() *types.UpstreamTiming {
+	if row.TTFBMs == nil {
+		return nil
+	}
+	timing := &types.UpstreamTiming{
+		DNSMs:     floatValue(row.DNSMs),
+		ConnectMs: floatValue(row.ConnectMs),
+		TLSMs:     floatValue(row.TLSMs),
+		TTFBMs:    *row.TTFBMs,
+		ReadMs:    floatValue(row.ReadMs),
+	}
+	if row.ReusedConn != nil {
+		timing.ReusedConn = *row.ReusedConn
+	}
+	return timing
+}
+
+// floatValue returns a nullable column value, 0 for NULL
+func floatValue(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+// rawJSON returns stored JSON text, or nil for empty columns
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+func (t *TinybirdDatabase) queryRequests(where string, limit, offset int) ([]types.AuditRequest, error) {
+	sql := fmt.Sprintf("SELECT %s FROM audit_requests %s ORDER BY timestamp DESC LIMIT %d OFFSET %d",
+		tinybirdRequestColumns, where, limit, offset)
+
+	var rows []tinybirdRequestRow
+	if err := t.query(sql, &rows); err != nil {
+		return nil, err
+	}
+
+	requests := make([]types.AuditRequest, len(rows))
+	for i, row := range rows {
+		requests[i] = row.auditRequest()
+	}
+	return requests, nil
+}
+
+func (t *TinybirdDatabase) queryResponses(where string, limit, offset int) ([]types.AuditResponse, error) {
+	sql := fmt.Sprintf("SELECT %s FROM audit_responses %s ORDER BY timestamp DESC LIMIT %d OFFSET %d",
+		tinybirdResponseColumns, where, limit, offset)
+
+	var rows []tinybirdResponseRow
+	if err := t.query(sql, &rows); err != nil {
+		return nil, err
+	}
+
+	responses := make([]types.AuditResponse, len(rows))
+	for i, row := range rows {
+		responses[i] = row.auditResponse()
+	}
+	return responses, nil
+}
+
+// GetAuditRequests retrieves requests, newest first
+func (t *TinybirdDatabase) GetAuditRequests(limit, offset int) ([]types.AuditRequest, error) {
+	return t.queryRequests("", limit, offset)
+}
+
+// GetAuditResponses retrieves responses, newest first
+func (t *TinybirdDatabase) GetAuditResponses(limit, offset int) ([]types.AuditResponse, error) {
+	return t.queryResponses("", limit, offset)
+}
+
+// GetOrphanedRequests retrieves requests that have no response
+func (t *TinybirdDatabase) GetOrphanedRequests(limit, offset int) ([]types.AuditRequest, error) {
+	return t.queryRequests("WHERE request_id NOT IN (SELECT request_id FROM audit_responses)", limit, offset)
+}
+
+// GetAuditLogs retrieves combined request/response logs, newest first
+func (t *TinybirdDatabase) GetAuditLogs(limit, offset int) ([]types.AuditLog, error) {
+	return t.queryAuditLogs("", limit, offset)
+}
+
+// GetAuditLogsByMethod retrieves combined logs of one JSON-RPC method
+func (t *TinybirdDatabase) GetAuditLogsByMethod(method string, limit, offset int) ([]types.AuditLog, error) {
+	return t.queryAuditLogs("WHERE method = "+quoteString(method), limit, offset)
+}
+
+// queryAuditLogs pages through requests and attaches their responses with a
+// second query, which is much cheaper than joining the datasources
+func (t *TinybirdDatabase) queryAuditLogs(where string, limit, offset int) ([]types.AuditLog, error) {
+	requests, err := t.queryRequests(where, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return []types.AuditLog{}, nil
+	}
+
+	quoted := make([]string, len(requests))
+	for i, req := range requests {
+		quoted[i] = quoteString(req.RequestID)
+	}
+	responses, err := t.queryResponses("WHERE request_id IN ("+strings.Join(quoted, ",")+")", len(requests), 0)
+	if err != nil {
+		return nil, err
+	}
+	byRequest := make(map[string]types.AuditResponse, len(responses))
+	for _, resp := range responses {
+		byRequest[resp.RequestID] = resp
+	}
+
+	logs := make([]types.AuditLog, len(requests))
+	for i, req := range requests {
+		logs[i] = types.AuditLog{
+			ID:             req.ID,
+			Timestamp:      req.Timestamp,
+			Method:         req.Method,
+			RequestID:      req.RequestID,
+			IPAddress:      req.IPAddress,
+			UserAgent:      req.UserAgent,
+			Request:        req.Request,
+			Headers:        req.Headers,
+			HTTPMethod:     req.HTTPMethod,
+			UpstreamURL:    req.UpstreamURL,
+			APIKey:         req.APIKey,
+			Tenant:         req.Tenant,
+			Tags:           req.Tags,
+			ContentType:    req.ContentType,
+			BodyEncoding:   req.BodyEncoding,
+			UpstreamMethod: req.UpstreamMethod,
+			AuditLevel:     req.AuditLevel,
+			BodyHash:       req.BodyHash,
+			Fingerprint:    req.Fingerprint,
+			Deployment:     req.Deployment,
+
+			ParentRequestID: req.ParentRequestID,
+			RequestBytes:    req.RequestBytes,
+			Extensions:      req.Extensions,
+		}
+		if resp, ok := byRequest[req.RequestID]; ok {
+			logs[i].Response = resp.Response
+			logs[i].StatusCode = resp.StatusCode
+			logs[i].ProcessTime = resp.ProcessTime
+			logs[i].Error = resp.Error
+			logs[i].MalformedUpstream = resp.MalformedUpstream
+			logs[i].RPCErrorCode = resp.RPCErrorCode
+			logs[i].ResponseID = resp.ID
+			logs[i].ResponseContentType = resp.ContentType
+			logs[i].ResponseBodyEncoding = resp.BodyEncoding
+			logs[i].QueueTime = resp.QueueTime
+			logs[i].UpstreamTime = resp.UpstreamTime
+			logs[i].FailureKind = resp.FailureKind
+			logs[i].ServedBy = resp.ServedBy
+			logs[i].Cache = resp.Cache
+			logs[i].CacheAgeMs = resp.CacheAgeMs
+			logs[i].ResponseBytes = resp.ResponseBytes
+			logs[i].Debug = resp.Debug
+			logs[i].Timing = resp.Timing
+			logs[i].TransformedResponse = resp.TransformedResponse
+			logs[i].ResponseHash = resp.ResponseHash
+			logs[i].Metadata = resp.Metadata
+		}
+	}
+	return logs, nil
+}
+
+// GetStats computes the same statistics as the SQLite store
+func (t *TinybirdDatabase) GetStats() (*types.Stats, error) {
+	stats := &types.Stats{}
+
+	var requestTotals []struct {
+		Total    chInt `json:"total"`
+		LastHour chInt `json:"last_hour"`
+		Orphaned chInt `json:"orphaned"`
+	}
+	err := t.query(`SELECT count() AS total,
+		countIf(timestamp > now() - INTERVAL 1 HOUR) AS last_hour,
+		countIf(request_id NOT IN (SELECT request_id FROM audit_responses)) AS orphaned
+		FROM audit_requests`, &requestTotals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request counts: %w", err)
+	}
+	if len(requestTotals) > 0 {
+		stats.TotalRequests = int(requestTotals[0].Total)
+		stats.RequestsLastHour = int(requestTotals[0].LastHour)
+		stats.OrphanedRequests = int(requestTotals[0].Orphaned)
+	}
+
+	var responseTotals []struct {
+		Total       chInt   `json:"total"`
+		Errors      chInt   `json:"errors"`
+		Malformed   chInt   `json:"malformed"`
+		Slow        chInt   `json:"slow"`
+		AvgResponse float64 `json:"avg_response"`
+	}
+	err = t.query(`SELECT count() AS total,
+		countIf(error != '') AS errors,
+		countIf(malformed_upstream) AS malformed,
+		countIf(slow) AS slow,
+		ifNotFinite(avgIf(process_time_ms, process_time_ms > 0), 0) AS avg_response
+		FROM audit_responses`, &responseTotals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response counts: %w", err)
+	}
+	if len(responseTotals) > 0 {
+		totals := responseTotals[0]
+		stats.TotalResponses = int(totals.Total)
+		stats.ErrorCount = int(totals.Errors)
+		stats.MalformedUpstream = int(totals.Malformed)
+		stats.SlowRequests = int(totals.Slow)
+		stats.AvgResponseTimeMs = totals.AvgResponse
+		if totals.Total > 0 {
+			stats.ErrorRate = float64(totals.Errors) / float64(totals.Total) * 100
+		}
+	}
+
+	if stats.Methods, err = t.topCounts("method", "audit_requests", ""); err != nil {
+		return nil, fmt.Errorf("failed to query method stats: %w", err)
+	}
+	if stats.StatusCodes, err = t.topCounts("toString(status_code)", "audit_responses", ""); err != nil {
+		return nil, fmt.Errorf("failed to query status stats: %w", err)
+	}
+	if stats.RPCErrorCodes, err = t.topCounts("toString(rpc_error_code)", "audit_responses", "WHERE rpc_error_code IS NOT NULL"); err != nil {
+		return nil, fmt.Errorf("failed to query RPC error codes: %w", err)
+	}
+
+	var timing []struct {
+		Calls   chInt   `json:"calls"`
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"`
+		TLS     float64 `json:"tls"`
+		TTFB    float64 `json:"ttfb"`
+		Read    float64 `json:"read"`
+		Reused  float64 `json:"reused"`
+	}
+	err = t.query(`SELECT count() AS calls,
+		ifNotFinite(avg(dns_ms), 0) AS dns, ifNotFinite(avg(connect_ms), 0) AS connect,
+		ifNotFinite(avg(tls_ms), 0) AS tls, ifNotFinite(avg(ttfb_ms), 0) AS ttfb,
+		ifNotFinite(avg(read_ms), 0) AS read, ifNotFinite(avg(reused_conn) * 100, 0) AS reused
+		FROM audit_responses WHERE ttfb_ms IS NOT NULL`, &timing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query upstream timing: %w", err)
+	}
+	if len(timing) > 0 && timing[0].Calls > 0 {
+		row := timing[0]
+		stats.Timing = &types.TimingStats{
+			Calls:          int(row.Calls),
+			AvgDNSMs:       row.DNS,
+			AvgConnectMs:   row.Connect,
+			AvgTLSMs:       row.TLS,
+			AvgTTFBMs:      row.TTFB,
+			AvgReadMs:      row.Read,
+			ReusedConnRate: row.Reused,
+		}
+	}
+
+	return stats, nil
+}
+
+// topCounts returns the ten most frequent values of expr
+func (t *TinybirdDatabase) topCounts(expr, datasource, where string) (map[string]int, error) {
+	var rows []struct {
+		Key   string `json:"key"`
+		Count chInt  `json:"count"`
+	}
+	sql := fmt.Sprintf("SELECT %s AS key, count() AS count FROM %s %s GROUP BY key ORDER BY count DESC LIMIT 10", expr, datasource, where)
+	if err := t.query(sql, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Key] = int(row.Count)
+	}
+	return counts, nil
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Tinybird rejects rows above its size limit, which loses the call from the
+// audit trail without any sign at the proxy. Events above the row cap have
+// their payload columns gzipped, and cut short if that is not enough, and are
+// flagged oversized so the loss shows in the datasource and in /metrics.
+
+// DefaultTinybirdMaxRowBytes is the encoded size above which events are shrunk
+const DefaultTinybirdMaxRowBytes = 1 << 20
+
+// tinybirdCompressMinBytes is the smallest payload column worth compressing
+const tinybirdCompressMinBytes = 1024
+
+// tinybirdPayloadColumns are the columns of each datasource that may be compressed or truncated
+var tinybirdPayloadColumns = map[string][]string{
+	"audit_requests":  {"request", "headers", "extensions"},
+	"audit_responses": {"response", "transformed_response", "debug"},
+}
+
+// TinybirdOversized counts events that exceeded the row size cap
+type TinybirdOversized struct {
+	Compressed int64 // Fitted by compressing payload columns
+	Truncated  int64 // Payload columns had to be cut, losing the end of the bodies
+}
+
+// SetMaxRowBytes sets the encoded event size above which payload columns are
+// compressed and then truncated. Zero sends events as they are.
+func (t *TinybirdDatabase) SetMaxRowBytes(n int) {
+	t.maxRowBytes = n
+}
+
+// Oversized returns how many events exceeded the row size cap since the gateway started
+func (t *TinybirdDatabase) Oversized() TinybirdOversized {
+	return TinybirdOversized{
+		Compressed: atomic.LoadInt64(&t.oversizedCompressed),
+		Truncated:  atomic.LoadInt64(&t.oversizedTruncated),
+	}
+}
+
+// fitEvent shrinks an event of datasource to the row cap. Compressed columns
+// hold base64 gzip data and are listed in compressed_columns; truncated
+// columns hold the start of the payload text and are listed in truncated_columns.
+func (t *TinybirdDatabase) fitEvent(datasource string, event map[string]interface{}) map[string]interface{} {
+	size := eventSize(event)
+	if t.maxRowBytes <= 0 || size <= t.maxRowBytes {
+		return event
+	}
+	event["oversized"] = true
+
+	columns := append([]string(nil), tinybirdPayloadColumns[datasource]...)
+	originals := make(map[string]string, len(columns))
+	var compressed []string
+	for _, column := range columns {
+		text, _ := event[column].(string)
+		originals[column] = text
+		if len(text) < tinybirdCompressMinBytes {
+			continue
+		}
+		packed, err := gzipBytes([]byte(text))
+		if err != nil {
+			continue
+		}
+		if encoded := base64.StdEncoding.EncodeToString(packed); len(encoded) < len(text) {
+			event[column] = encoded
+			compressed = append(compressed, column)
+		}
+	}
+	event["compressed_columns"] = compressed
+	if size = eventSize(event); size <= t.maxRowBytes {
+		atomic.AddInt64(&t.oversizedCompressed, 1)
+		return event
+	}
+
+	// Cut the largest columns first, as text so what remains is readable
+	sort.SliceStable(columns, func(i, j int) bool {
+		return len(event[columns[i]].(string)) > len(event[columns[j]].(string))
+	})
+	var truncated []string
+	for _, column := range columns {
+		if size <= t.maxRowBytes {
+			break
+		}
+		if originals[column] == "" {
+			continue
+		}
+		current := event[column].(string)
+		event[column] = truncateText(originals[column], len(current)-(size-t.maxRowBytes))
+		compressed = removeColumn(compressed, column)
+		truncated = append(truncated, column)
+		// Escaping can make the text longer than counted, cut again until it fits
+		for size = eventSize(event); size > t.maxRowBytes && event[column] != ""; size = eventSize(event) {
+			current = event[column].(string)
+			event[column] = truncateText(current, len(current)-(size-t.maxRowBytes))
+		}
+	}
+	event["compressed_columns"] = compressed
+	event["truncated_columns"] = truncated
+	atomic.AddInt64(&t.oversizedTruncated, 1)
+	return event
+}
+
+// eventSize returns the size of an event encoded as an NDJSON line
+func eventSize(event map[string]interface{}) int {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0
+	}
+	return len(data) + 1
+}
+
+// truncateText returns the first n bytes of s, cut back to a whole UTF-8 character
+func truncateText(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func removeColumn(columns []string, column string) []string {
+	kept := columns[:0]
+	for _, c := range columns {
+		if c != column {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func hasColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// restorePayload reads a payload column back: compressed columns are
+// decompressed, and truncated ones returned as a JSON string since the text
+// no longer parses
+func restorePayload(column, text string, compressed, truncated []string) json.RawMessage {
+	switch {
+	case text == "":
+		return nil
+	case hasColumn(compressed, column):
+		packed, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return json.RawMessage(strconv.Quote("failed to decode compressed payload: " + err.Error()))
+		}
+		plain, err := gunzipBytes(packed)
+		if err != nil {
+			return json.RawMessage(strconv.Quote("failed to decompress payload: " + err.Error()))
+		}
+		return json.RawMessage(plain)
+	case hasColumn(truncated, column):
+		quoted, _ := json.Marshal(text)
+		return quoted
+	}
+	return rawJSON(text)
+}
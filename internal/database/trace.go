@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// maxTraceDepth bounds the recursion over parent links, which callers set freely
+const maxTraceDepth = 64
+
+// maxTraceCalls caps the number of calls returned for one trace
+const maxTraceCalls = 5000
+
+// GetTrace returns every call of the tree containing requestID, oldest first:
+// the outermost recorded ancestor reached through parent_request_id links and
+// all of its descendants. It returns no logs if requestID was not recorded.
+func (d *Database) GetTrace(requestID string) ([]types.AuditLog, error) {
+	query := `
+		WITH RECURSIVE
+		ancestors(request_id, parent_request_id, depth) AS (
+			SELECT request_id, parent_request_id, 0 FROM audit_requests WHERE request_id = ?
+			UNION
+			SELECT r.request_id, r.parent_request_id, a.depth + 1
+			FROM audit_requests r JOIN ancestors a ON r.request_id = a.parent_request_id
+			WHERE a.depth < ?
+		),
+		root AS (
+			SELECT request_id FROM ancestors ORDER BY depth DESC LIMIT 1
+		),
+		tree(request_id, depth) AS (
+			SELECT request_id, 0 FROM root
+			UNION
+			SELECT r.request_id, t.depth + 1
+			FROM audit_requests r JOIN tree t ON r.parent_request_id = t.request_id
+			WHERE t.depth < ?
+		)
+		SELECT ` + auditLogColumns + ` FROM audit_logs
+		WHERE request_id IN (SELECT request_id FROM tree)
+		ORDER BY timestamp ASC, id ASC
+		LIMIT ?
+	`
+
+	logs, err := d.queryAuditLogs(query, requestID, maxTraceDepth, maxTraceDepth, maxTraceCalls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace: %w", err)
+	}
+	return logs, nil
+}
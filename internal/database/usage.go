@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// forwardedCondition matches calls the gateway sent to a target, or still in
+// flight. Calls it refused itself (policy, quota, size, strict audit or busy
+// rejections) never reached the upstream and don't consume quota or get billed.
+const forwardedCondition = `(resp.request_id IS NULL OR json_extract(resp.metadata, '$.upstream') = 'sent')`
+
+// usageQuery counts forwarded requests per key or tenant in the current day and month
+const usageQuery = `
+	SELECT r.%[1]s,
+		   COALESCE(SUM(CASE WHEN r.timestamp >= ? THEN 1 ELSE 0 END), 0) as daily,
+		   COUNT(*) as monthly
+	FROM audit_requests r
+	LEFT JOIN audit_responses resp ON r.request_id = resp.request_id
+	WHERE r.timestamp >= ?
+	  AND r.%[1]s IS NOT NULL AND r.%[1]s != ''
+	  AND %[3]s
+	%[2]s
+	GROUP BY r.%[1]s
+`
+
+// GetKeyUsage returns daily and monthly call counts per API key name
+func (d *Database) GetKeyUsage(dayStart, monthStart time.Time) (map[string]types.UsageCount, error) {
+	return d.getUsage("api_key", "", dayStart, monthStart)
+}
+
+// GetTenantUsage returns daily and monthly call counts per tenant
+func (d *Database) GetTenantUsage(dayStart, monthStart time.Time) (map[string]types.UsageCount, error) {
+	return d.getUsage("tenant", "", dayStart, monthStart)
+}
+
+// CountKeyUsage returns the usage of a single API key
+func (d *Database) CountKeyUsage(apiKey string, dayStart, monthStart time.Time) (types.UsageCount, error) {
+	usage, err := d.getUsage("api_key", "AND r.api_key = ?", dayStart, monthStart, apiKey)
+	if err != nil {
+		return types.UsageCount{}, err
+	}
+	return usage[apiKey], nil
+}
+
+// CountTenantUsage returns the usage of a single tenant
+func (d *Database) CountTenantUsage(tenant string, dayStart, monthStart time.Time) (types.UsageCount, error) {
+	usage, err := d.getUsage("tenant", "AND r.tenant = ?", dayStart, monthStart, tenant)
+	if err != nil {
+		return types.UsageCount{}, err
+	}
+	return usage[tenant], nil
+}
+
+// getUsage groups usage by column, which must be a trusted column name
+func (d *Database) getUsage(column, filter string, dayStart, monthStart time.Time, args ...interface{}) (map[string]types.UsageCount, error) {
+	query := fmt.Sprintf(usageQuery, column, filter, forwardedCondition)
+
+	rows, err := d.sqlDB().Query(query, append([]interface{}{dayStart, monthStart}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s usage: %w", column, err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]types.UsageCount)
+	for rows.Next() {
+		var name string
+		var count types.UsageCount
+		if err := rows.Scan(&name, &count.Daily, &count.Monthly); err != nil {
+			return nil, fmt.Errorf("failed to scan usage row: %w", err)
+		}
+		usage[name] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,298 @@
+// Package events fans audit records out to in-process subscribers such as
+// storage backends and webhooks, so the gateway does not depend on concrete sinks.
+package events
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Event carries exactly one of an audit request or an audit response
+type Event struct {
+	Request  *types.AuditRequest
+	Response *types.AuditResponse
+
+	Durability string // Audit durability of the call, see types.DurabilityStrict; empty means standard
+}
+
+// Handler receives published events; errors are logged with the subscriber name
+type Handler func(Event) error
+
+type subscription struct {
+	id      int
+	name    string
+	handler Handler
+}
+
+// Bus delivers every published event to all subscribers
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID int
+	queues map[string]*queue // Queued subscriptions by name
+}
+
+// queue is the buffer of a queued subscription and its counters
+type queue struct {
+	events     chan Event
+	dropped    int64 // Events discarded while the queue was full
+	overflowed int64 // Events passed to the overflow handler while the queue was full
+
+	flush   chan chan struct{} // Requests to write the events queued so far, nil for unbatched queues
+	stopped chan struct{}      // Closed once a batched queue's writer has returned
+}
+
+// QueueStatus reports the backlog of a queued subscription
+type QueueStatus struct {
+	Name       string
+	Depth      int // Events waiting for the subscriber
+	Capacity   int
+	Dropped    int64
+	Overflowed int64
+}
+
+// New creates a bus without subscribers
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers handler, called synchronously and in subscription order
+// for every event. The returned function removes the subscription.
+func (b *Bus) Subscribe(name string, handler Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.subs = append(b.subs, subscription{id: id, name: name, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subs {
+			if s.id == id {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SubscribeAsync registers handler behind a queue of the given size, for sinks
+// that must not slow down the proxy. Events are dropped while the queue is full.
+func (b *Bus) SubscribeAsync(name string, size int, handler Handler) (unsubscribe func()) {
+	return b.SubscribeQueued(name, size, handler, nil)
+}
+
+// SubscribeQueued is SubscribeAsync for sinks that cannot lose events: events
+// arriving while the queue is full are passed to overflow on the publishing
+// goroutine instead of being dropped. A nil overflow drops them.
+func (b *Bus) SubscribeQueued(name string, size int, handler, overflow Handler) (unsubscribe func()) {
+	q := &queue{events: make(chan Event, size)}
+	b.mu.Lock()
+	if b.queues == nil {
+		b.queues = make(map[string]*queue)
+	}
+	b.queues[name] = q
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case event := <-q.events:
+				if err := handler(event); err != nil {
+					log.Printf("Audit subscriber %s failed: %v", name, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	remove := b.Subscribe(name, func(event Event) error {
+		select {
+		case q.events <- event:
+		default:
+			if overflow != nil {
+				atomic.AddInt64(&q.overflowed, 1)
+				return overflow(event)
+			}
+			atomic.AddInt64(&q.dropped, 1)
+			log.Printf("Audit subscriber %s is falling behind, dropping event", name)
+		}
+		return nil
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			remove()
+			close(done)
+			b.mu.Lock()
+			if b.queues[name] == q {
+				delete(b.queues, name)
+			}
+			b.mu.Unlock()
+		})
+	}
+}
+
+// SubscribeBatched is SubscribeQueued for sinks writing many events at once:
+// only events accepted by filter are queued, and handler receives those waiting,
+// up to maxBatch at a time. The returned function hands the events still queued
+// to handler before removing the subscription.
+func (b *Bus) SubscribeBatched(name string, size, maxBatch int, filter func(Event) bool, handler func([]Event) error, overflow Handler) (unsubscribe func()) {
+	q := &queue{events: make(chan Event, size), flush: make(chan chan struct{}), stopped: make(chan struct{})}
+	b.mu.Lock()
+	if b.queues == nil {
+		b.queues = make(map[string]*queue)
+	}
+	b.queues[name] = q
+	b.mu.Unlock()
+
+	// next adds up to maxBatch-1 of the events waiting behind first
+	next := func(first Event) []Event {
+		batch := []Event{first}
+		for len(batch) < maxBatch {
+			select {
+			case event := <-q.events:
+				batch = append(batch, event)
+			default:
+				return batch
+			}
+		}
+		return batch
+	}
+	write := func(batch []Event) {
+		if err := handler(batch); err != nil {
+			log.Printf("Audit subscriber %s failed to write %d events: %v", name, len(batch), err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(q.stopped)
+		for {
+			select {
+			case event := <-q.events:
+				write(next(event))
+			case flushed := <-q.flush:
+				// The batch being written, if any, is done by now
+				for len(q.events) > 0 {
+					write(next(<-q.events))
+				}
+				close(flushed)
+			case <-done:
+				for {
+					select {
+					case event := <-q.events:
+						write(next(event))
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	remove := b.Subscribe(name, func(event Event) error {
+		if filter != nil && !filter(event) {
+			return nil
+		}
+		select {
+		case q.events <- event:
+		default:
+			if overflow != nil {
+				atomic.AddInt64(&q.overflowed, 1)
+				return overflow(event)
+			}
+			atomic.AddInt64(&q.dropped, 1)
+			log.Printf("Audit subscriber %s is falling behind, dropping event", name)
+		}
+		return nil
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			remove()
+			close(done)
+			<-q.stopped
+			b.mu.Lock()
+			if b.queues[name] == q {
+				delete(b.queues, name)
+			}
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Flush waits until the events queued for the batched subscription name when
+// it is called have been written. Unknown and unbatched subscriptions return at once.
+func (b *Bus) Flush(name string) {
+	b.mu.RLock()
+	q := b.queues[name]
+	b.mu.RUnlock()
+	if q == nil || q.flush == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	select {
+	case q.flush <- flushed:
+		<-flushed
+	case <-q.stopped:
+	}
+}
+
+// Queues reports the backlog of every queued subscription, ordered by name
+func (b *Bus) Queues() []QueueStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	statuses := make([]QueueStatus, 0, len(b.queues))
+	for name, q := range b.queues {
+		statuses = append(statuses, QueueStatus{
+			Name:       name,
+			Depth:      len(q.events),
+			Capacity:   cap(q.events),
+			Dropped:    atomic.LoadInt64(&q.dropped),
+			Overflowed: atomic.LoadInt64(&q.overflowed),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Publish delivers event to every subscriber
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		if err := s.handler(event); err != nil {
+			log.Printf("Audit subscriber %s failed: %v", s.name, err)
+		}
+	}
+}
+
+// Writer is the write side of an audit store, see database.AuditWriter
+type Writer interface {
+	InsertAuditRequest(req *types.AuditRequest) error
+	InsertAuditResponse(resp *types.AuditResponse) error
+}
+
+// WriterHandler stores events through writer
+func WriterHandler(writer Writer) Handler {
+	return func(event Event) error {
+		if event.Request != nil {
+			return writer.InsertAuditRequest(event.Request)
+		}
+		return writer.InsertAuditResponse(event.Response)
+	}
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/accesslog"
+)
+
+// SetAccessLog also writes every proxied request to an access log in the
+// Common or Combined Log Format. Management endpoints are not logged.
+func (g *Gateway) SetAccessLog(writer *accesslog.Writer) {
+	g.accessLog = writer
+}
+
+// accessLogged wraps a proxy handler so its requests are written to the access log
+func (g *Gateway) accessLogged(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if g.accessLog == nil {
+			next(w, r)
+			return
+		}
+		start := g.now()
+		rec := &accessRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		user := ""
+		if client := g.identifyClient(r); client != nil {
+			user = client.Name
+		}
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		g.accessLog.Log(accesslog.Entry{
+			RemoteHost: getClientIP(r),
+			User:       user,
+			Time:       start,
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		})
+	}
+}
+
+// accessRecorder remembers the status code and body size written through it
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (a *accessRecorder) WriteHeader(status int) {
+	if a.status == 0 {
+		a.status = status
+	}
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *accessRecorder) Write(p []byte) (int, error) {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+	n, err := a.ResponseWriter.Write(p)
+	a.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController flush streamed responses
+func (a *accessRecorder) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
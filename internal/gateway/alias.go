@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// replaceMethod returns body with the "method" member of the top-level JSON-RPC object
+// replaced, leaving every other byte untouched
+func replaceMethod(body []byte, method string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("request is not a JSON object")
+	}
+
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		start := dec.InputOffset()
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if keyTok != "method" {
+			continue
+		}
+
+		end := dec.InputOffset()
+		offset := bytes.Index(body[start:end], value)
+		if offset < 0 {
+			return nil, fmt.Errorf("method value not found")
+		}
+		encoded, err := json.Marshal(method)
+		if err != nil {
+			return nil, err
+		}
+
+		valueStart := int(start) + offset
+		result := make([]byte, 0, len(body)-len(value)+len(encoded))
+		result = append(result, body[:valueStart]...)
+		result = append(result, encoded...)
+		result = append(result, body[valueStart+len(value):]...)
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("request has no method")
+}
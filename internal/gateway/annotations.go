@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// AnnotateAuditLog updates the note, tags, resolved flag or soft-delete flag of
+// an audit entry. Soft-deleted entries disappear from audit log listings and
+// are restored by patching deleted back to false.
+func (g *Gateway) AnnotateAuditLog(w http.ResponseWriter, r *http.Request) {
+	requestID := mux.Vars(r)["request_id"]
+
+	var req types.AnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid annotation: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Deleted != nil && !g.hasRole(r, types.RoleAdmin) {
+		http.Error(w, "Deleting audit entries requires the admin role", http.StatusForbidden)
+		return
+	}
+
+	annotation, err := g.db.GetAnnotation(requestID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve annotation: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if annotation == nil {
+		annotation = &types.Annotation{}
+	}
+
+	if req.Note != nil {
+		annotation.Note = *req.Note
+	}
+	if req.Tags != nil {
+		annotation.Tags = *req.Tags
+	}
+	if req.Resolved != nil {
+		annotation.Resolved = *req.Resolved
+	}
+	if req.Deleted != nil {
+		annotation.Deleted = *req.Deleted
+	}
+	annotation.UpdatedAt = g.now()
+
+	err = g.db.SaveAnnotation(requestID, annotation)
+	if errors.Is(err, database.ErrRequestNotFound) {
+		http.Error(w, fmt.Sprintf("Audit log %s not found", requestID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(annotation)
+}
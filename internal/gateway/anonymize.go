@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/anonymize"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// credentialHeaders are blanked in anonymized exports; nothing useful survives pseudonymizing a secret
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// ipHeaders carry client addresses and are pseudonymized address by address
+var ipHeaders = []string{"X-Forwarded-For", "X-Real-Ip", "Forwarded"}
+
+// exportAnonymizer pseudonymizes exported audit records
+type exportAnonymizer struct {
+	*anonymize.Anonymizer
+	requestPaths  []string            // Paths into request bodies
+	responsePaths []string            // Paths into response bodies
+	headers       []string            // Canonical names of extra headers
+	tags          map[string]struct{} // Extra tag names
+}
+
+// SetAnonymization configures anonymized exports. The same key gives the same
+// pseudonyms in every export, so datasets exported at different times can be
+// joined; without a key a random one is used until the gateway restarts.
+func (g *Gateway) SetAnonymization(cfg *config.Anonymize, key []byte) {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Printf("Failed to generate anonymization key: %v", err)
+			return
+		}
+	}
+
+	a := &exportAnonymizer{Anonymizer: anonymize.New(key), tags: make(map[string]struct{})}
+	if cfg != nil {
+		for _, field := range cfg.Fields {
+			root, path, _ := strings.Cut(field, ".")
+			switch root {
+			case "request":
+				a.requestPaths = append(a.requestPaths, path)
+			case "response":
+				a.responsePaths = append(a.responsePaths, path)
+			case "headers":
+				a.headers = append(a.headers, http.CanonicalHeaderKey(path))
+			case "tags":
+				a.tags[path] = struct{}{}
+			}
+		}
+	}
+	g.anonymizer = a
+}
+
+// record returns a pseudonymized copy of an exported record
+func (a *exportAnonymizer) record(record types.AuditRecord) types.AuditRecord {
+	if record.Request != nil {
+		req := *record.Request
+		a.request(&req)
+		record.Request = &req
+	}
+	if record.Response != nil {
+		resp := *record.Response
+		if resp.BodyEncoding == "" {
+			resp.Response = a.JSON(resp.Response, a.responsePaths)
+		}
+		if len(resp.TransformedResponse) > 0 {
+			resp.TransformedResponse = a.JSON(resp.TransformedResponse, a.responsePaths)
+		}
+		if len(resp.Debug) > 0 {
+			resp.Debug = a.debug(resp.Debug)
+		}
+		// Pseudonymized consistently, so duplicates stay countable
+		resp.ResponseHash = a.String(resp.ResponseHash)
+		record.Response = &resp
+	}
+	return record
+}
+
+func (a *exportAnonymizer) request(req *types.AuditRequest) {
+	req.IPAddress = a.IP(req.IPAddress)
+	req.Fingerprint = a.String(req.Fingerprint)
+	req.APIKey = a.String(req.APIKey)
+	req.Tenant = a.String(req.Tenant)
+
+	if len(req.Tags) > 0 {
+		tags := make(map[string]string, len(req.Tags))
+		for name, value := range req.Tags {
+			if _, ok := a.tags[name]; ok {
+				value = a.String(value)
+			}
+			tags[name] = value
+		}
+		req.Tags = tags
+	}
+
+	if len(req.Headers) > 0 {
+		req.Headers = a.headerValues(req.Headers)
+	}
+	if len(req.IndexedHeaders) > 0 {
+		// A copy, the record shares the map with the caller
+		indexed := make(map[string]string, len(req.IndexedHeaders))
+		for name, value := range req.IndexedHeaders {
+			indexed[name] = value
+		}
+		a.anonymizeHeaders(indexed)
+		req.IndexedHeaders = indexed
+	}
+
+	// Extensions are top-level request members, request.<member> paths cover them too
+	if len(req.Extensions) > 0 {
+		req.Extensions = a.JSON(req.Extensions, a.requestPaths)
+	}
+
+	if req.BodyEncoding == "" && len(req.Request) > 0 {
+		req.Request = a.JSON(req.Request, a.requestPaths)
+		// The hash of the original body would let a reader confirm guesses of it
+		req.BodyHash = types.BodyHash(req.Request)
+	} else {
+		req.BodyHash = a.String(req.BodyHash)
+	}
+}
+
+// debug anonymizes the headers of a debug capture like those of the request
+func (a *exportAnonymizer) debug(raw json.RawMessage) json.RawMessage {
+	var capture types.DebugCapture
+	if err := json.Unmarshal(raw, &capture); err != nil {
+		return nil
+	}
+	for _, headers := range []map[string]string{capture.RequestHeaders, capture.ResponseHeaders} {
+		a.anonymizeHeaders(headers)
+	}
+	data, err := json.Marshal(capture)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// headerValues blanks credentials and pseudonymizes client addresses and configured headers
+func (a *exportAnonymizer) headerValues(raw json.RawMessage) json.RawMessage {
+	var headers map[string]string
+	if err := json.Unmarshal(raw, &headers); err != nil {
+		return raw
+	}
+	a.anonymizeHeaders(headers)
+
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
+// anonymizeHeaders rewrites header values in place
+func (a *exportAnonymizer) anonymizeHeaders(headers map[string]string) {
+	for _, name := range credentialHeaders {
+		if _, ok := headers[name]; ok {
+			headers[name] = redactedValue
+		}
+	}
+	for _, name := range ipHeaders {
+		value, ok := headers[name]
+		if !ok {
+			continue
+		}
+		addrs := strings.Split(value, ",")
+		for i, addr := range addrs {
+			addrs[i] = a.IP(strings.TrimSpace(addr))
+		}
+		headers[name] = strings.Join(addrs, ", ")
+	}
+	for _, name := range a.headers {
+		if value, ok := headers[name]; ok {
+			headers[name] = a.String(value)
+		}
+	}
+}
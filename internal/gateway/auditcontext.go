@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Metadata keys of the decisions the gateway itself records in the audit context
+const (
+	metaRoute       = "route"       // Name of the matched route
+	metaPolicy      = "policy"      // allow, or deny when the client policy, validation or a quota rejected the call
+	metaRejection   = "rejection"   // Why a denied call was rejected
+	metaAlias       = "alias"       // Method name sent upstream for aliased methods
+	metaTransform   = "transform"   // applied when the method's response transform rewrote the result
+	metaCache       = "cache"       // hit or miss for calls to cached methods
+	metaFailover    = "failover"    // Secondary target that answered after the primary failed
+	metaOverride    = "override"    // Target an admin sent the call to instead of the route's
+	metaRule        = "rule"        // Routing rule that sent the call to its target instead of the route's
+	metaDurability  = "durability"  // strict or relaxed on routes not using standard audit durability
+	metaFanOut      = "fanout"      // Number of batch items sent to the target concurrently
+	metaTransfer    = "transfer"    // chunked when the client sent the body with chunked transfer coding
+	metaIdempotency = "idempotency" // claimed, replayed, in_progress or mismatch for calls with an Idempotency-Key
+	metaScript      = "script"      // allow, deny, rewrite or error for calls on routes with a script
+	metaMCP         = "mcp"         // initialize, session, missing, unknown, deleted or terminated on routes tracking MCP sessions
+	metaMCPSession  = "mcp_session" // Session an initialize call started
+	metaBurst       = "burst"       // Burst capture that raised the call to full-body with debug timing
+	metaUpstream    = "upstream"    // sent once the call is forwarded to a target; quotas and billing count only these
+
+	// Responses too large to keep whole in the audit trail, see max_response_capture_bytes
+	metaBodySHA256   = "body_sha256"   // Hex SHA-256 of the whole body as the target sent it
+	metaBodyCaptured = "body_captured" // Bytes of the body kept in the audit response, out of response_bytes
+)
+
+// AuditContext collects key/value metadata about a call while it passes
+// through the proxy pipeline: policy decisions, transformations applied, cache
+// status and whatever middleware or in-process handlers add. It is stored with
+// the audit response, so the audit record is the one place to see why the
+// gateway handled a call the way it did. A nil AuditContext ignores writes.
+type AuditContext struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+type auditContextKey struct{}
+
+// WithAuditContext returns a context carrying the audit context of ctx, or a new one
+func WithAuditContext(ctx context.Context) (context.Context, *AuditContext) {
+	if audit := AuditContextFrom(ctx); audit != nil {
+		return ctx, audit
+	}
+	audit := &AuditContext{}
+	return context.WithValue(ctx, auditContextKey{}, audit), audit
+}
+
+// AuditContextFrom returns the audit context of the call ctx belongs to, nil outside of one
+func AuditContextFrom(ctx context.Context) *AuditContext {
+	audit, _ := ctx.Value(auditContextKey{}).(*AuditContext)
+	return audit
+}
+
+// Set records value under key, replacing an earlier value
+func (a *AuditContext) Set(key, value string) {
+	if a == nil || key == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.values == nil {
+		a.values = make(map[string]string)
+	}
+	a.values[key] = value
+}
+
+// Get returns the value recorded under key
+func (a *AuditContext) Get(key string) string {
+	if a == nil {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.values[key]
+}
+
+// Values returns a copy of the recorded metadata, nil when there is none
+func (a *AuditContext) Values() map[string]string {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.values) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(a.values))
+	for k, v := range a.values {
+		values[k] = v
+	}
+	return values
+}
+
+// trackAuditContext attaches an audit context to the request and registers it
+// under the request ID, where recordResponse picks it up; the returned
+// function unregisters it once the call is answered
+func (g *Gateway) trackAuditContext(r *http.Request, requestID string) (*http.Request, *AuditContext, func()) {
+	ctx, audit := WithAuditContext(r.Context())
+	g.auditContexts.Store(requestID, audit)
+	return r.WithContext(ctx), audit, func() { g.auditContexts.Delete(requestID) }
+}
+
+// auditMetadata returns the metadata recorded for a call in flight
+func (g *Gateway) auditMetadata(requestID string) map[string]string {
+	audit, ok := g.auditContexts.Load(requestID)
+	if !ok {
+		return nil
+	}
+	return audit.(*AuditContext).Values()
+}
@@ -0,0 +1,283 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Cookies of dashboard sign-ins
+const (
+	sessionCookie = "golf_session" // Session of a signed-in user
+	loginCookie   = "golf_login"   // State of a sign-in in progress, binding the callback to the browser that started it
+)
+
+// loginTTL is how long a user has to complete a sign-in at the provider
+const loginTTL = 10 * time.Minute
+
+// maxPendingLogins bounds the sign-ins in progress, which anyone may start
+const maxPendingLogins = 10000
+
+// AuthProvider signs users in to the dashboard and management API, e.g. with
+// OIDC. The gateway keeps the sign-ins in progress and the sessions, and maps
+// the groups of the user the provider names to a role.
+type AuthProvider interface {
+	// Start returns where to send the browser for the sign-in identified by
+	// state, and a verifier handed back to Finish
+	Start(ctx context.Context, state string) (loginURL, verifier string, err error)
+	// Finish completes the sign-in the provider sent the browser back from
+	Finish(ctx context.Context, r *http.Request, verifier string) (*types.Identity, error)
+}
+
+// authState holds the sign-in provider, the sign-ins in progress and the sessions
+type authState struct {
+	provider AuthProvider
+	signIn   config.SignIn
+
+	mu       sync.Mutex
+	logins   map[string]*pendingLogin      // By state
+	sessions map[string]*types.AuthSession // By session cookie value
+}
+
+type pendingLogin struct {
+	verifier string
+	returnTo string
+	expires  time.Time
+}
+
+// SetAuthProvider lets users sign in through provider at /auth/login and use
+// the dashboard and management API with the role their groups map to
+func (g *Gateway) SetAuthProvider(provider AuthProvider, signIn config.SignIn) {
+	g.auth = &authState{
+		provider: provider,
+		signIn:   signIn,
+		logins:   make(map[string]*pendingLogin),
+		sessions: make(map[string]*types.AuthSession),
+	}
+}
+
+// authSession returns the session of a signed-in user, nil without one. Requests
+// changing something must come from the gateway's own pages: browsers send the
+// cookie along with requests other sites make them send.
+func (g *Gateway) authSession(r *http.Request) *types.AuthSession {
+	if g.auth == nil {
+		return nil
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	if r.Method != "GET" && r.Method != "HEAD" && r.Method != "OPTIONS" && !sameOrigin(r) {
+		return nil
+	}
+
+	g.auth.mu.Lock()
+	defer g.auth.mu.Unlock()
+	session, ok := g.auth.sessions[cookie.Value]
+	if !ok {
+		return nil
+	}
+	if g.now().After(session.ExpiresAt) {
+		delete(g.auth.sessions, cookie.Value)
+		return nil
+	}
+	return session
+}
+
+// sameOrigin reports whether the Origin, or else the Referer, of a request is the gateway itself
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(origin)
+	return origin != "" && err == nil && u.Host == r.Host
+}
+
+// roleOf returns the highest role the user's groups map to, or the default role
+func (a *authState) roleOf(identity *types.Identity) string {
+	role := a.signIn.DefaultRole
+	for _, group := range identity.Groups {
+		if mapped := a.signIn.Groups[group]; roleRanks[mapped] > roleRanks[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// Login sends the browser to the provider's login page. ?return= names the
+// gateway page to come back to, the dashboard by default.
+func (g *Gateway) Login(w http.ResponseWriter, r *http.Request) {
+	if g.auth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	returnTo := r.URL.Query().Get("return")
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		returnTo = "/"
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	loginURL, verifier, err := g.auth.provider.Start(r.Context(), state)
+	if err != nil {
+		log.Printf("Failed to start sign-in: %v", err)
+		http.Error(w, fmt.Sprintf("Sign-in is unavailable: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	now := g.now()
+	g.auth.mu.Lock()
+	for s, login := range g.auth.logins {
+		if now.After(login.expires) {
+			delete(g.auth.logins, s)
+		}
+	}
+	full := len(g.auth.logins) >= maxPendingLogins
+	if !full {
+		g.auth.logins[state] = &pendingLogin{verifier: verifier, returnTo: returnTo, expires: now.Add(loginTTL)}
+	}
+	g.auth.mu.Unlock()
+	if full {
+		http.Error(w, "Too many sign-ins in progress, try again later", http.StatusServiceUnavailable)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name: loginCookie, Value: state, Path: "/auth/", MaxAge: int(loginTTL.Seconds()),
+		HttpOnly: true, Secure: secureRequest(r), SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, loginURL, http.StatusFound)
+}
+
+// AuthCallback completes a sign-in, starts the user's session and returns to
+// the page the sign-in started from
+func (g *Gateway) AuthCallback(w http.ResponseWriter, r *http.Request) {
+	if g.auth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	state := r.URL.Query().Get("state")
+	cookie, err := r.Cookie(loginCookie)
+	if state == "" || err != nil || cookie.Value != state {
+		http.Error(w, "Sign-in was not started in this browser, start again at /auth/login", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/auth/", MaxAge: -1})
+
+	g.auth.mu.Lock()
+	login, ok := g.auth.logins[state]
+	delete(g.auth.logins, state)
+	g.auth.mu.Unlock()
+	if !ok || g.now().After(login.expires) {
+		http.Error(w, "Sign-in expired, start again at /auth/login", http.StatusBadRequest)
+		return
+	}
+
+	identity, err := g.auth.provider.Finish(r.Context(), r, login.verifier)
+	if err != nil {
+		log.Printf("Sign-in failed: %v", err)
+		http.Error(w, fmt.Sprintf("Sign-in failed: %v", err), http.StatusUnauthorized)
+		return
+	}
+	role := g.auth.roleOf(identity)
+	if role == "" {
+		log.Printf("Refused sign-in of %s: no group maps to a role", identity.User)
+		http.Error(w, fmt.Sprintf("%s is in no group with access to the gateway", identity.User), http.StatusForbidden)
+		return
+	}
+
+	id, err := randomToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := g.now()
+	session := &types.AuthSession{
+		User:      identity.User,
+		Role:      role,
+		Groups:    identity.Groups,
+		ExpiresAt: now.Add(g.auth.signIn.SessionTTLDuration()),
+	}
+	g.auth.mu.Lock()
+	for key, s := range g.auth.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(g.auth.sessions, key)
+		}
+	}
+	g.auth.sessions[id] = session
+	g.auth.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name: sessionCookie, Value: id, Path: "/", Expires: session.ExpiresAt,
+		HttpOnly: true, Secure: secureRequest(r), SameSite: http.SameSiteLaxMode,
+	})
+	g.recordAdminAction(types.AdminAction{
+		Timestamp:  now,
+		Actor:      identity.User,
+		Role:       role,
+		HTTPMethod: r.Method,
+		Path:       r.URL.Path,
+		StatusCode: http.StatusFound,
+		IPAddress:  getClientIP(r),
+	})
+	http.Redirect(w, r, login.returnTo, http.StatusFound)
+}
+
+// Logout ends the caller's session
+func (g *Gateway) Logout(w http.ResponseWriter, r *http.Request) {
+	if g.auth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil && sameOrigin(r) {
+		g.auth.mu.Lock()
+		delete(g.auth.sessions, cookie.Value)
+		g.auth.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAuthSession returns the signed-in user, 401 without a session
+func (g *Gateway) GetAuthSession(w http.ResponseWriter, r *http.Request) {
+	if g.auth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	session := g.authSession(r)
+	if session == nil {
+		http.Error(w, "Not signed in, sign in at /auth/login", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// secureRequest reports whether the browser reached the gateway over HTTPS,
+// directly or through a proxy, so cookies are only sent back over HTTPS
+func secureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// isBatch reports whether body is a JSON array, a JSON-RPC batch
+func isBatch(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && body[0] == '['
+}
+
+// rpcShapeTags returns the batch.size and notifications tags of a JSON-RPC
+// body, nil for a single call with an id or a body that is not JSON-RPC
+func rpcShapeTags(body []byte) map[string]string {
+	type call struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+
+	var calls []call
+	batch := body[0] == '['
+	if batch {
+		if json.Unmarshal(body, &calls) != nil || len(calls) == 0 {
+			return nil
+		}
+	} else {
+		var single call
+		if json.Unmarshal(body, &single) != nil {
+			return nil
+		}
+		calls = append(calls, single)
+	}
+
+	tags := make(map[string]string)
+	if batch {
+		tags[types.BatchSizeTag] = strconv.Itoa(len(calls))
+	}
+	notifications := 0
+	for _, c := range calls {
+		if c.Method != "" && c.ID == nil {
+			notifications++
+		}
+	}
+	if notifications > 0 {
+		tags[types.NotificationsTag] = strconv.Itoa(notifications)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
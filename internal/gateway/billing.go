@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/niki4smirn/golf/internal/billing"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// SetBilling sets the prices of the invoices at /billing; nil lists usage at zero cost
+func (g *Gateway) SetBilling(cfg *config.Billing) {
+	g.billing = cfg
+}
+
+// GetInvoices returns the invoice of every API key with usage in a month.
+// Query params: month (YYYY-MM, default current), format (json or csv).
+func (g *Gateway) GetInvoices(w http.ResponseWriter, r *http.Request) {
+	g.serveInvoices(w, r, "")
+}
+
+// GetInvoice returns the invoice of one API key, with zero usage if it made no calls
+func (g *Gateway) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	g.serveInvoices(w, r, mux.Vars(r)["api_key"])
+}
+
+func (g *Gateway) serveInvoices(w http.ResponseWriter, r *http.Request, apiKey string) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, fmt.Sprintf("Invalid format %q, use json or csv", format), http.StatusBadRequest)
+		return
+	}
+
+	now := g.now()
+	start, end, err := billing.Month(query.Get("month"), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	usage, err := g.usageDB().GetBillingUsage(apiKey, start, end)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute billing usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if apiKey != "" && len(usage) == 0 {
+		usage = append(usage, types.BillingUsage{APIKey: apiKey, Methods: map[string]int64{}})
+	}
+	invoices := billing.Invoices(g.billing, usage, start, end, now)
+
+	period := start.Format(billing.PeriodLayout)
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=invoices-%s.csv", period))
+		billing.WriteCSV(w, invoices)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if apiKey != "" {
+		json.NewEncoder(w).Encode(invoices[0])
+		return
+	}
+
+	resp := types.InvoicesResponse{Period: period, Currency: config.DefaultBillingCurrency, Invoices: invoices, Count: len(invoices)}
+	if g.billing != nil {
+		resp.Currency = g.billing.Currency
+	}
+	for _, invoice := range invoices {
+		resp.Total += invoice.Total
+	}
+	resp.Total = math.Round(resp.Total*100) / 100
+	json.NewEncoder(w).Encode(resp)
+}
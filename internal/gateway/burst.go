@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Bounds of the duration of a burst capture
+const (
+	defaultBurstDuration = 10 * time.Minute
+	maxBurstDuration     = 24 * time.Hour
+)
+
+// burstFilterKeys are the call attributes a burst filter may match
+var burstFilterKeys = map[string]bool{"method": true, "route": true, "api_key": true, "tenant": true, "ip": true}
+
+// burstCaptures are the burst captures started through /admin/capture. Each
+// ends on its own at its deadline; expired captures are dropped when next seen.
+type burstCaptures struct {
+	mu     sync.Mutex
+	active []*burst
+}
+
+type burst struct {
+	types.BurstCapture
+	terms map[string]string // Filter key -> value, a trailing * matches a prefix
+}
+
+// burstCall is what a burst filter matches a call on
+type burstCall struct {
+	method, route, apiKey, tenant, ip string
+}
+
+// parseBurstFilter reads a filter such as method:getUserInfo,api_key:mobile
+func parseBurstFilter(filter string) (map[string]string, error) {
+	terms := make(map[string]string)
+	for _, term := range strings.Split(filter, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" || !burstFilterKeys[key] {
+			return nil, fmt.Errorf("invalid filter term %q, expected method, route, api_key, tenant or ip, e.g. method:getUserInfo", term)
+		}
+		terms[key] = value
+	}
+	return terms, nil
+}
+
+func (b *burst) matches(call burstCall) bool {
+	for key, want := range b.terms {
+		var got string
+		switch key {
+		case "method":
+			got = call.method
+		case "route":
+			got = call.route
+		case "api_key":
+			got = call.apiKey
+		case "tenant":
+			got = call.tenant
+		case "ip":
+			got = call.ip
+		}
+		if prefix, ok := strings.CutSuffix(want, "*"); ok {
+			if !strings.HasPrefix(got, prefix) {
+				return false
+			}
+		} else if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the ID of an active burst capture covering the call, empty when none does
+func (c *burstCaptures) match(now time.Time, call burstCall) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune(now)
+	for _, b := range c.active {
+		if b.matches(call) {
+			b.Matched++
+			return b.ID
+		}
+	}
+	return ""
+}
+
+// prune drops the captures past their deadline
+func (c *burstCaptures) prune(now time.Time) {
+	kept := c.active[:0]
+	for _, b := range c.active {
+		if now.Before(b.Until) {
+			kept = append(kept, b)
+			continue
+		}
+		log.Printf("Burst capture %s (%s) ended after %d calls", b.ID, b.Filter, b.Matched)
+	}
+	c.active = kept
+}
+
+// list returns the active captures in the order they were started
+func (c *burstCaptures) list(now time.Time) []types.BurstCapture {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune(now)
+	captures := make([]types.BurstCapture, len(c.active))
+	for i, b := range c.active {
+		captures[i] = b.BurstCapture
+	}
+	return captures
+}
+
+// burstFor returns the burst capture raising the detail of a call, empty when none covers it
+func (g *Gateway) burstFor(r *http.Request, route *config.Route, method string, client *config.APIKey) string {
+	call := burstCall{method: method, route: route.Name, ip: getClientIP(r)}
+	if client != nil {
+		call.apiKey, call.tenant = client.Name, client.Tenant
+	}
+	return g.bursts.match(g.now(), call)
+}
+
+// StartBurstCapture records the calls matching ?filter= with full bodies and
+// debug timing for ?duration= (default 10m), e.g. while an incident is
+// investigated, then drops back to the configured audit levels on its own
+func (g *Gateway) StartBurstCapture(w http.ResponseWriter, r *http.Request) {
+	duration := defaultBurstDuration
+	if s := r.URL.Query().Get("duration"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > maxBurstDuration {
+			http.Error(w, fmt.Sprintf("Invalid duration %q, expected up to %s, e.g. 10m", s, maxBurstDuration), http.StatusBadRequest)
+			return
+		}
+		duration = d
+	}
+	filter := r.URL.Query().Get("filter")
+	terms, err := parseBurstFilter(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := randomToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, actor := g.callerRole(r)
+	now := g.now()
+	b := &burst{
+		BurstCapture: types.BurstCapture{
+			ID:        "burst_" + id[:12],
+			Filter:    filter,
+			StartedAt: now,
+			Until:     now.Add(duration),
+			StartedBy: actor,
+		},
+		terms: terms,
+	}
+	started := b.BurstCapture
+	g.bursts.mu.Lock()
+	g.bursts.active = append(g.bursts.active, b)
+	g.bursts.mu.Unlock()
+	log.Printf("Burst capture %s started by %s for %s: %s", started.ID, actor, duration, filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(started)
+}
+
+// StopBurstCapture ends the capture ?id= early, or all of them without one
+func (g *Gateway) StopBurstCapture(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	g.bursts.mu.Lock()
+	kept := g.bursts.active[:0]
+	stopped := 0
+	for _, b := range g.bursts.active {
+		if id != "" && b.ID != id {
+			kept = append(kept, b)
+			continue
+		}
+		stopped++
+		log.Printf("Burst capture %s stopped after %d calls", b.ID, b.Matched)
+	}
+	g.bursts.active = kept
+	g.bursts.mu.Unlock()
+
+	if id != "" && stopped == 0 {
+		http.Error(w, fmt.Sprintf("Burst capture %s is not active", id), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBurstCaptures lists the active burst captures
+func (g *Gateway) GetBurstCaptures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.BurstCapturesResponse{Captures: g.bursts.list(g.now())})
+}
@@ -0,0 +1,213 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// maxCacheEntries bounds the response cache. Expired entries are evicted
+// first, then the oldest ones.
+const maxCacheEntries = 10000
+
+// responseCache holds the results of successful calls to methods routes cache
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is the result of one call, with what invalidation matches on
+type cacheEntry struct {
+	method   string
+	tenant   string
+	params   interface{} // Decoded params of the call
+	result   json.RawMessage
+	storedAt time.Time
+	expires  time.Time
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*cacheEntry)}
+}
+
+// cacheKey identifies a call by target, tenant, method and params. Params are
+// re-encoded so that object key order does not matter.
+func cacheKey(target, tenant, method string, params interface{}) (string, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode params: %w", err)
+	}
+	return target + "\x00" + tenant + "\x00" + method + "\x00" + string(encoded), nil
+}
+
+// get returns the live entry for key, nil on a miss
+func (c *responseCache) get(key string, now time.Time) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry
+}
+
+// put stores entry under key, evicting entries to stay within maxCacheEntries
+func (c *responseCache) put(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCacheEntries {
+		c.evict(entry.storedAt)
+	}
+	c.entries[key] = entry
+}
+
+// evict drops expired entries, or the oldest one when none has expired
+func (c *responseCache) evict(now time.Time) {
+	var oldestKey string
+	var oldest *cacheEntry
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == nil || entry.storedAt.Before(oldest.storedAt) {
+			oldestKey, oldest = key, entry
+		}
+	}
+	if len(c.entries) >= maxCacheEntries && oldest != nil {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// invalidate drops the entries matching method, tenant and params, where
+// empty matchers match everything, and returns how many were dropped and kept
+func (c *responseCache) invalidate(method, tenant string, params interface{}) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	invalidated := 0
+	for key, entry := range c.entries {
+		if method != "" && entry.method != method {
+			continue
+		}
+		if tenant != "" && entry.tenant != tenant {
+			continue
+		}
+		if params != nil && !paramsMatch(params, entry.params) {
+			continue
+		}
+		delete(c.entries, key)
+		invalidated++
+	}
+	return invalidated, len(c.entries)
+}
+
+// paramsMatch reports whether value contains matcher: objects match when every
+// field of matcher matches, arrays when matcher is a matching prefix, and
+// other values when they are equal
+func paramsMatch(matcher, value interface{}) bool {
+	switch m := matcher.(type) {
+	case map[string]interface{}:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, field := range m {
+			item, ok := v[key]
+			if !ok || !paramsMatch(field, item) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		v, ok := value.([]interface{})
+		if !ok || len(m) > len(v) {
+			return false
+		}
+		for i := range m {
+			if !paramsMatch(m[i], v[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(matcher, value)
+}
+
+// cacheableResult returns the result of a successful JSON-RPC response body,
+// nil for errors and anything else that must not be cached
+func cacheableResult(body []byte) json.RawMessage {
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+	if len(response.Result) == 0 || (len(response.Error) > 0 && string(response.Error) != "null") {
+		return nil
+	}
+	return response.Result
+}
+
+// serveCached answers a call from the cache without contacting the upstream
+func (g *Gateway) serveCached(w http.ResponseWriter, call *proxyCall, entry *cacheEntry) {
+	responseBody, err := json.Marshal(types.JSONRPCResponse{ID: call.id, JSONRPC: "2.0", Result: entry.result})
+	if err != nil {
+		errorMsg := "Failed to marshal cached response"
+		rpcErr := failureError(call.route, config.ErrorInternal, -32603, "Internal error", errorMsg)
+		g.writeRPCError(w, call.id, rpcErr, errorMsg, call.requestID, call.startTime, http.StatusInternalServerError, "")
+		return
+	}
+
+	now := g.now()
+	auditResponse := &types.AuditResponse{
+		RequestID:   call.requestID,
+		Timestamp:   now,
+		StatusCode:  http.StatusOK,
+		ProcessTime: g.since(call.startTime).Milliseconds(),
+		ContentType: "application/json",
+		Cache:       types.CacheHit,
+		CacheAgeMs:  now.Sub(entry.storedAt).Milliseconds(),
+	}
+	g.auditResponseBody(auditResponse, call, responseBody)
+	g.recordResponse(auditResponse)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Age", strconv.FormatInt(int64(now.Sub(entry.storedAt).Seconds()), 10))
+	w.Header().Set("X-Cache", types.CacheHit)
+	g.stampResponse(w, call.requestID, responseBody)
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseBody)
+}
+
+// InvalidateCache drops cached responses matching the method, params and tenant in the body
+func (g *Gateway) InvalidateCache(w http.ResponseWriter, r *http.Request) {
+	var req types.CacheInvalidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	var params interface{}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	invalidated, remaining := g.cache.invalidate(req.Method, req.Tenant, params)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.CacheInvalidateResponse{Invalidated: invalidated, Remaining: remaining})
+}
@@ -0,0 +1,15 @@
+package gateway
+
+import (
+	"github.com/niki4smirn/golf/internal/capture"
+	"github.com/niki4smirn/golf/internal/events"
+)
+
+// captureQueueSize is how many events wait for the capture file before new ones are dropped
+const captureQueueSize = 10000
+
+// SetCapture also writes every call to a HAR or NDJSON capture file. Events
+// are queued and dropped while the queue is full, so a slow disk never holds up the proxy.
+func (g *Gateway) SetCapture(writer *capture.Writer) {
+	g.bus.SubscribeAsync("capture", captureQueueSize, g.timed("capture", events.WriterHandler(writer)))
+}
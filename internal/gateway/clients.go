@@ -0,0 +1,276 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// methodDeniedCode is the JSON-RPC error code returned for methods on a client's denylist
+const methodDeniedCode = -32003
+
+// keyPrefixLength is how much of a client key is kept in clear text for identification
+const keyPrefixLength = 12
+
+// SetAdminToken enables the /admin API for callers presenting token as a bearer token
+func (g *Gateway) SetAdminToken(token string) {
+	g.adminToken = token
+}
+
+// LoadClients refreshes the in-memory copy of database-managed clients
+func (g *Gateway) LoadClients() error {
+	if g.db == nil {
+		return nil
+	}
+	clients, err := g.db.ListClients()
+	if err != nil {
+		return err
+	}
+
+	byHash := make(map[string]config.APIKey, len(clients))
+	for _, c := range clients {
+		byHash[c.KeyHash] = config.APIKey{Name: c.Name, Tenant: c.Tenant, ClientPolicy: c.ClientPolicy}
+	}
+
+	g.clientsMu.Lock()
+	g.clients = byHash
+	g.clientsMu.Unlock()
+	return nil
+}
+
+// lookupClient finds a database-managed client by its secret key
+func (g *Gateway) lookupClient(key string) *config.APIKey {
+	g.clientsMu.RLock()
+	defer g.clientsMu.RUnlock()
+
+	if apiKey, ok := g.clients[hashKey(key)]; ok {
+		return &apiKey
+	}
+	return nil
+}
+
+// reloadClients refreshes the client cache after a change, logging failures
+func (g *Gateway) reloadClients() {
+	if err := g.LoadClients(); err != nil {
+		log.Printf("Failed to reload clients: %v", err)
+	}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateClientKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return "golf_" + hex.EncodeToString(buf), nil
+}
+
+// setClientKey stores the hash and prefix of key on the client
+func setClientKey(c *types.Client, key string) {
+	c.KeyHash = hashKey(key)
+	c.KeyPrefix = key
+	if len(key) > keyPrefixLength {
+		c.KeyPrefix = key[:keyPrefixLength]
+	}
+}
+
+// requireAdmin rejects requests without the admin bearer token or an API key with the admin role
+func (g *Gateway) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return g.requireRole(types.RoleAdmin, next)
+}
+
+// isAdmin reports whether the request carries the configured admin bearer token
+func (g *Gateway) isAdmin(r *http.Request) bool {
+	if g.adminToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(g.adminToken)) == 1
+}
+
+// validateClientRequest checks a create or update request against the configured keys
+func (g *Gateway) validateClientRequest(req *types.ClientRequest) error {
+	if err := req.ClientPolicy.Validate(); err != nil {
+		return err
+	}
+	if g.rotator != nil && (req.Quota.Daily > 0 || req.Quota.Monthly > 0) {
+		return fmt.Errorf("quotas cannot be used while the audit database is rotated")
+	}
+	for _, k := range g.configuredKeys() {
+		if k.Name == req.Name {
+			return fmt.Errorf("client %q is defined in the config file", req.Name)
+		}
+		if req.Key != "" && k.Key == req.Key {
+			return fmt.Errorf("key is already used by a configured client")
+		}
+	}
+	return nil
+}
+
+// ListClients returns all database-managed clients
+func (g *Gateway) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := g.db.ListClients()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve clients: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if clients == nil {
+		clients = []types.Client{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.ClientsResponse{Clients: clients, Count: len(clients)})
+}
+
+// GetClient returns a single client
+func (g *Gateway) GetClient(w http.ResponseWriter, r *http.Request) {
+	client, err := g.db.GetClient(mux.Vars(r)["name"])
+	if errors.Is(err, database.ErrClientNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve client: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(client)
+}
+
+// CreateClient onboards a new client, generating a key unless one is supplied
+func (g *Gateway) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req types.ClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid client: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "Invalid client: name is required", http.StatusBadRequest)
+		return
+	}
+	if err := g.validateClientRequest(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid client: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	key := req.Key
+	if key == "" {
+		var err error
+		if key, err = generateClientKey(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	client := types.Client{Name: req.Name, Tenant: req.Tenant, ClientPolicy: req.ClientPolicy}
+	setClientKey(&client, key)
+
+	if existing, _ := g.db.GetClient(req.Name); existing != nil {
+		http.Error(w, fmt.Sprintf("Client %q already exists", req.Name), http.StatusConflict)
+		return
+	}
+	if err := g.db.CreateClient(&client); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create client: %v", err), http.StatusConflict)
+		return
+	}
+	g.reloadClients()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(types.ClientResponse{Client: client, Key: key})
+}
+
+// UpdateClient replaces the tenant and policy of a client, rotating its key when one is supplied
+func (g *Gateway) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	var req types.ClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid client: %v", err), http.StatusBadRequest)
+		return
+	}
+	req.Name = mux.Vars(r)["name"]
+	if err := g.validateClientRequest(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid client: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	client, err := g.db.GetClient(req.Name)
+	if errors.Is(err, database.ErrClientNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve client: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	client.Tenant = req.Tenant
+	client.ClientPolicy = req.ClientPolicy
+	if req.Key != "" {
+		setClientKey(client, req.Key)
+	}
+
+	if err := g.db.UpdateClient(client); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update client: %v", err), http.StatusInternalServerError)
+		return
+	}
+	g.reloadClients()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.ClientResponse{Client: *client, Key: req.Key})
+}
+
+// DeleteClient removes a client; its key stops working immediately
+func (g *Gateway) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	err := g.db.DeleteClient(mux.Vars(r)["name"])
+	if errors.Is(err, database.ErrClientNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete client: %v", err), http.StatusInternalServerError)
+		return
+	}
+	g.reloadClients()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// rejection describes why a call was refused before being forwarded
+type rejection struct {
+	code       int
+	message    string
+	reason     string
+	statusCode int
+}
+
+// checkPolicy applies the client's method denylist and rate limit
+func (g *Gateway) checkPolicy(client *config.APIKey, method string, now time.Time) *rejection {
+	if client == nil {
+		return nil
+	}
+	if containsString(client.DeniedMethods, method) {
+		return &rejection{methodDeniedCode, "Method not allowed", fmt.Sprintf("method %s is not allowed for api key %s", method, client.Name), http.StatusForbidden}
+	}
+	if client.RateLimit != nil && !g.limiter.allow(client.Name, *client.RateLimit, now) {
+		return &rejection{quotaExceededCode, "Rate limit exceeded", fmt.Sprintf("rate limit of %g requests per second exceeded for api key %s", client.RateLimit.RequestsPerSecond, client.Name), http.StatusTooManyRequests}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Callers label their own traffic with tags like experiment=b,team=payments,
+// sent in the X-Golf-Tags header or, where headers cannot be set, the
+// golf_tags query parameter
+const (
+	tagsHeader = "X-Golf-Tags"
+	tagsParam  = "golf_tags"
+)
+
+// Limits on caller tags, keeping the tags table from growing with free text
+const (
+	maxClientTags     = 16
+	maxTagValueLength = 128
+)
+
+var tagNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// reservedTags are set by the gateway itself and cannot be sent by callers
+var reservedTags = map[string]bool{
+	types.SlowTag:          true,
+	types.PIIRequestTag:    true,
+	types.PIIResponseTag:   true,
+	types.BatchSizeTag:     true,
+	types.NotificationsTag: true,
+	types.MCPSessionTag:    true,
+}
+
+// clientTags parses the tags a caller attached to a call. The header and the
+// query parameter may both be used; the header wins for a name given twice.
+func clientTags(r *http.Request) (map[string]string, error) {
+	var lists []string
+	if values, ok := r.URL.Query()[tagsParam]; ok {
+		lists = append(lists, values...)
+	}
+	lists = append(lists, r.Header.Values(tagsHeader)...)
+	if len(lists) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]string)
+	for _, list := range lists {
+		for _, pair := range strings.Split(list, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(pair, "=")
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			if !ok || value == "" {
+				return nil, fmt.Errorf("tag %q must be name=value", pair)
+			}
+			if !tagNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("tag name %q must be 1-64 letters, digits, '_', '.' or '-'", name)
+			}
+			if reservedTags[name] || strings.HasPrefix(name, types.ExtensionTagPrefix) {
+				return nil, fmt.Errorf("tag name %q is reserved", name)
+			}
+			if len(value) > maxTagValueLength {
+				return nil, fmt.Errorf("tag %s exceeds %d characters", name, maxTagValueLength)
+			}
+			tags[name] = value
+		}
+	}
+	if len(tags) > maxClientTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxClientTags)
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return tags, nil
+}
+
+// withoutTagsParam removes the golf_tags parameter so it is not forwarded upstream
+func withoutTagsParam(rawQuery string) string {
+	if !strings.Contains(rawQuery, tagsParam) {
+		return rawQuery
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	if _, ok := query[tagsParam]; !ok {
+		return rawQuery
+	}
+	query.Del(tagsParam)
+	return query.Encode()
+}
+
+// mergeTags adds caller tags to the extracted ones; configured extractions win on conflicts
+func mergeTags(extracted, client map[string]string) map[string]string {
+	if len(client) == 0 {
+		return extracted
+	}
+	if extracted == nil {
+		extracted = make(map[string]string, len(client))
+	}
+	for name, value := range client {
+		if _, ok := extracted[name]; !ok {
+			extracted[name] = value
+		}
+	}
+	return extracted
+}
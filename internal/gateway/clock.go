@@ -0,0 +1,28 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/niki4smirn/golf/internal/clock"
+)
+
+// SetClock replaces the time source of audit timestamps, latencies and the
+// time windows of the reporting endpoints, e.g. with a clock.Fake in tests.
+// Network deadlines and request IDs keep using the system clock.
+func (g *Gateway) SetClock(c clock.Clock) {
+	g.clock = c
+}
+
+func (g *Gateway) now() time.Time {
+	if g.clock == nil {
+		return time.Now()
+	}
+	return g.clock.Now()
+}
+
+func (g *Gateway) since(t time.Time) time.Duration {
+	if g.clock == nil {
+		return time.Since(t)
+	}
+	return g.clock.Since(t)
+}
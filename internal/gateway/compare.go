@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetAuditCompare compares the method mix, error rate and latency of two time
+// windows, e.g. before and after a deploy. Query params: windowA and windowB,
+// each <start>/<end> or <start>/<duration> with RFC3339 times, or a duration
+// for the window ending now.
+func (g *Gateway) GetAuditCompare(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := g.now()
+
+	fromA, toA, err := parseWindow(query.Get("windowA"), now)
+	if err != nil {
+		http.Error(w, "Invalid windowA: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	fromB, toB, err := parseWindow(query.Get("windowB"), now)
+	if err != nil {
+		http.Error(w, "Invalid windowB: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	windowA, methodsA, err := g.db.WindowStats(fromA, toA)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to summarize windowA: %v", err), http.StatusInternalServerError)
+		return
+	}
+	windowB, methodsB, err := g.db.WindowStats(fromB, toB)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to summarize windowB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := types.CompareResponse{
+		WindowA: *windowA,
+		WindowB: *windowB,
+		Change: types.WindowChange{
+			Calls:     windowB.Calls - windowA.Calls,
+			ErrorRate: windowB.ErrorRate - windowA.ErrorRate,
+			AvgMs:     windowB.AvgMs - windowA.AvgMs,
+			P50Ms:     windowB.P50Ms - windowA.P50Ms,
+			P95Ms:     windowB.P95Ms - windowA.P95Ms,
+			P99Ms:     windowB.P99Ms - windowA.P99Ms,
+		},
+		Methods: []types.MethodComparison{},
+	}
+	if windowA.Calls > 0 {
+		percent := float64(windowB.Calls-windowA.Calls) / float64(windowA.Calls) * 100
+		response.Change.CallsPercent = &percent
+	}
+
+	for _, method := range methodNames(methodsA, methodsB) {
+		a, b := methodsA[method], methodsB[method]
+		if a == nil {
+			a = &types.MethodWindowStats{}
+		}
+		if b == nil {
+			b = &types.MethodWindowStats{}
+		}
+		if g.suppressed(a.Calls) || g.suppressed(b.Calls) {
+			response.SuppressedBuckets++
+			continue
+		}
+
+		m := types.MethodComparison{
+			Method:          method,
+			CallsA:          a.Calls,
+			CallsB:          b.Calls,
+			ShareA:          share(a.Calls, windowA.Calls),
+			ShareB:          share(b.Calls, windowB.Calls),
+			ErrorRateA:      a.ErrorRate,
+			ErrorRateB:      b.ErrorRate,
+			ErrorRateChange: b.ErrorRate - a.ErrorRate,
+			AvgMsA:          a.AvgMs,
+			AvgMsB:          b.AvgMs,
+			P95MsA:          a.P95Ms,
+			P95MsB:          b.P95Ms,
+			P95MsChange:     b.P95Ms - a.P95Ms,
+		}
+		m.ShareChange = m.ShareB - m.ShareA
+		switch {
+		case a.Calls == 0:
+			m.Status = "added"
+		case b.Calls == 0:
+			m.Status = "removed"
+		}
+		response.Methods = append(response.Methods, m)
+	}
+	sort.SliceStable(response.Methods, func(i, j int) bool {
+		return math.Abs(response.Methods[i].ShareChange) > math.Abs(response.Methods[j].ShareChange)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseWindow parses <start>/<end>, <start>/<duration> or a duration ending at now
+func parseWindow(s string, now time.Time) (time.Time, time.Time, error) {
+	if s == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected <start>/<end>, <start>/<duration> or a duration such as 1h")
+	}
+	// An unescaped + of a UTC offset arrives as a space
+	s = strings.ReplaceAll(s, " ", "+")
+	start, end, ok := strings.Cut(s, "/")
+	if !ok {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("expected <start>/<end>, <start>/<duration> or a duration such as 1h")
+		}
+		return now.Add(-d), now, nil
+	}
+
+	from, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start, expected RFC3339")
+	}
+	to, err := time.Parse(time.RFC3339, end)
+	if err != nil {
+		d, durErr := time.ParseDuration(end)
+		if durErr != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end, expected RFC3339 or a duration")
+		}
+		to = from.Add(d)
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end must be after start")
+	}
+	return from, to, nil
+}
+
+// methodNames returns the methods called in either window, sorted
+func methodNames(a, b map[string]*types.MethodWindowStats) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for method := range a {
+		names = append(names, method)
+	}
+	for method := range b {
+		if _, ok := a[method]; !ok {
+			names = append(names, method)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// share returns n as a percentage of total
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// maxTrackedMethods bounds the methods with their own concurrency counters;
+// calls to further methods are counted under otherMethods
+const maxTrackedMethods = 1000
+
+// otherMethods collects the concurrency of methods beyond maxTrackedMethods
+const otherMethods = "(other)"
+
+// concurrencyTracker counts the calls of each method waiting for or holding
+// an upstream slot, with their peaks since the gateway started
+type concurrencyTracker struct {
+	mu      sync.Mutex
+	methods map[string]*methodConcurrency
+}
+
+type methodConcurrency struct {
+	inFlight, peakInFlight int
+	queued, peakQueued     int
+	peakAt                 time.Time // When peakInFlight was reached
+}
+
+// counters returns the counters of method; t.mu must be held
+func (t *concurrencyTracker) counters(method string) *methodConcurrency {
+	if t.methods == nil {
+		t.methods = make(map[string]*methodConcurrency)
+	}
+	c, ok := t.methods[method]
+	if !ok {
+		if len(t.methods) >= maxTrackedMethods {
+			return t.counters(otherMethods)
+		}
+		c = &methodConcurrency{}
+		t.methods[method] = c
+	}
+	return c
+}
+
+// queue counts a call of method waiting for a free upstream slot until the returned function is called
+func (t *concurrencyTracker) queue(method string) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.counters(method)
+	c.queued++
+	if c.queued > c.peakQueued {
+		c.peakQueued = c.queued
+	}
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		c.queued--
+	}
+}
+
+// start counts a call of method in flight upstream until the returned function is called
+func (t *concurrencyTracker) start(method string, now time.Time) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.counters(method)
+	c.inFlight++
+	if c.inFlight > c.peakInFlight {
+		c.peakInFlight = c.inFlight
+		c.peakAt = now
+	}
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		c.inFlight--
+	}
+}
+
+// snapshot returns the counters of every method seen so far
+func (t *concurrencyTracker) snapshot() map[string]types.MethodConcurrency {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.methods) == 0 {
+		return nil
+	}
+	methods := make(map[string]types.MethodConcurrency, len(t.methods))
+	for method, c := range t.methods {
+		methods[method] = types.MethodConcurrency{
+			InFlight:     c.inFlight,
+			PeakInFlight: c.peakInFlight,
+			PeakAt:       c.peakAt,
+			Queued:       c.queued,
+			PeakQueued:   c.peakQueued,
+		}
+	}
+	return methods
+}
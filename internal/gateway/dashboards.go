@@ -0,0 +1,223 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// validateDashboard checks a dashboard, including the metrics of its charts
+func validateDashboard(dashboard *types.Dashboard) error {
+	if err := dashboard.Validate(); err != nil {
+		return err
+	}
+	for i, p := range dashboard.Panels {
+		if p.Type != types.PanelChart {
+			continue
+		}
+		if metric, _, _ := strings.Cut(p.Metric, ":"); grafanaMetrics[metric] == nil {
+			return fmt.Errorf("panel #%d: unknown metric %q", i+1, p.Metric)
+		}
+	}
+	return nil
+}
+
+// ListDashboards returns all stored dashboards
+func (g *Gateway) ListDashboards(w http.ResponseWriter, r *http.Request) {
+	dashboards, err := g.db.ListDashboards()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve dashboards: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if dashboards == nil {
+		dashboards = []types.Dashboard{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.DashboardsResponse{Dashboards: dashboards, Count: len(dashboards)})
+}
+
+// GetDashboard returns the layout of a single dashboard
+func (g *Gateway) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	dashboard, err := g.db.GetDashboard(mux.Vars(r)["name"])
+	if errors.Is(err, database.ErrDashboardNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve dashboard: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
+}
+
+// SaveDashboard creates a dashboard or replaces its title and panels
+func (g *Gateway) SaveDashboard(w http.ResponseWriter, r *http.Request) {
+	var dashboard types.Dashboard
+	if err := json.NewDecoder(r.Body).Decode(&dashboard); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid dashboard: %v", err), http.StatusBadRequest)
+		return
+	}
+	dashboard.Name = mux.Vars(r)["name"]
+	if err := validateDashboard(&dashboard); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid dashboard: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	created, err := g.db.SaveDashboard(&dashboard)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save dashboard: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(dashboard)
+}
+
+// DeleteDashboard removes a dashboard
+func (g *Gateway) DeleteDashboard(w http.ResponseWriter, r *http.Request) {
+	err := g.db.DeleteDashboard(mux.Vars(r)["name"])
+	if errors.Is(err, database.ErrDashboardNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete dashboard: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveCustomDashboard renders a stored dashboard; the page loads its layout
+// and the data of every panel from the audit API
+func serveCustomDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(customDashboard))
+}
+
+const customDashboard = `<!DOCTYPE html>
+<html>
+<head>
+    <title>JSON-RPC Gateway - Dashboard</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 40px; background: #f5f5f5; }
+        .container { max-width: 1200px; margin: 0 auto; background: white; padding: 30px; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
+        h1 { color: #333; border-bottom: 3px solid #007cba; padding-bottom: 10px; }
+        .stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 20px; margin: 20px 0; }
+        .stat-card { background: #e7f3ff; padding: 20px; border-radius: 8px; text-align: center; }
+        .stat-number { font-size: 2em; font-weight: bold; color: #007cba; }
+        .logs { border-collapse: collapse; width: 100%; font-size: 14px; }
+        .logs td, .logs th { text-align: left; padding: 6px 10px; border-bottom: 1px solid #eee; }
+        .chart { width: 100%; height: 160px; background: #f8f9fa; border-radius: 5px; }
+        .error { color: #c0392b; }
+        a { color: #007cba; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1 id="title">Dashboard</h1>
+        <div class="stats" id="stats"></div>
+        <div id="panels"></div>
+        <p><a href="/">← Gateway</a></p>
+    </div>
+
+    <script>
+        const escapeHTML = s => String(s == null ? '' : s).replace(/[&<>"]/g, c => ({'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;'}[c]));
+        const parseWindow = s => {
+            let ms = 0;
+            (s || '1h').replace(/(\d+(?:\.\d+)?)(ms|s|m|h)/g, (_, n, unit) => { ms += n * {ms: 1, s: 1e3, m: 6e4, h: 36e5}[unit]; });
+            return ms || 36e5;
+        };
+        const name = decodeURIComponent(location.pathname.split('/').pop());
+
+        function section(title) {
+            const div = document.createElement('div');
+            div.innerHTML = '<h2>' + escapeHTML(title) + '</h2><div class="body">Loading…</div>';
+            document.getElementById('panels').appendChild(div);
+            return div.querySelector('.body');
+        }
+
+        function statPanel(panel, stats) {
+            const card = document.createElement('div');
+            card.className = 'stat-card';
+            card.innerHTML = '<div class="stat-number">-</div><div>' + escapeHTML(panel.title) + '</div>';
+            document.getElementById('stats').appendChild(card);
+            stats.then(data => {
+                const value = data[panel.stat] || 0;
+                card.querySelector('.stat-number').textContent = Number.isInteger(value) ? value : value.toFixed(2);
+            }).catch(() => {});
+        }
+
+        function searchPanel(panel) {
+            const body = section(panel.title);
+            const query = new URLSearchParams(panel.query || {});
+            query.set('limit', panel.limit || 20);
+            query.set('preview', 'true');
+            fetch('/audit/logs?' + query)
+                .then(r => r.ok ? r.json() : r.text().then(t => Promise.reject(t)))
+                .then(data => {
+                    let html = '<table class="logs"><tr><th>Time</th><th>Method</th><th>Status</th><th>Duration</th><th>Request ID</th></tr>';
+                    (data.logs || []).forEach(l => {
+                        html += '<tr><td>' + new Date(l.timestamp).toLocaleString() + '</td><td>' + escapeHTML(l.method) + '</td><td' +
+                            (l.error || l.status_code >= 400 ? ' class="error"' : '') + '>' + (l.status_code || 'pending') + '</td><td>' +
+                            l.process_time_ms + 'ms</td><td><a href="/audit/logs/' + encodeURIComponent(l.request_id) + '">' +
+                            escapeHTML(l.request_id) + '</a></td></tr>';
+                    });
+                    body.innerHTML = html + '</table>';
+                })
+                .catch(err => { body.innerHTML = '<span class="error">' + escapeHTML(err) + '</span>'; });
+        }
+
+        function chartPanel(panel) {
+            const body = section(panel.title + ' (' + (panel.window || '1h') + ')');
+            const to = new Date(), from = new Date(to - parseWindow(panel.window));
+            fetch('/grafana/query', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({range: {from: from, to: to}, maxDataPoints: 120, targets: [{target: panel.metric}]})
+            })
+                .then(r => r.ok ? r.json() : r.text().then(t => Promise.reject(t)))
+                .then(series => {
+                    const points = series.length ? series[0].datapoints : [];
+                    if (!points.length) {
+                        body.textContent = 'No data';
+                        return;
+                    }
+                    const max = Math.max(1, ...points.map(p => p[0]));
+                    const x = t => (t - from) / (to - from) * 1000, y = v => 150 - v / max * 140;
+                    const line = points.map(p => x(p[1]).toFixed(1) + ',' + y(p[0]).toFixed(1)).join(' ');
+                    body.innerHTML = '<svg class="chart" viewBox="0 0 1000 160" preserveAspectRatio="none">' +
+                        '<polyline fill="none" stroke="#007cba" stroke-width="2" points="' + line + '"/>' +
+                        '<text x="4" y="14" font-size="12" fill="#666">max ' + (Math.round(max * 100) / 100) + '</text></svg>';
+                })
+                .catch(err => { body.innerHTML = '<span class="error">' + escapeHTML(err) + '</span>'; });
+        }
+
+        fetch('/audit/dashboards/' + encodeURIComponent(name))
+            .then(r => r.ok ? r.json() : r.text().then(t => Promise.reject(t)))
+            .then(dashboard => {
+                document.title = 'JSON-RPC Gateway - ' + (dashboard.title || dashboard.name);
+                document.getElementById('title').textContent = dashboard.title || dashboard.name;
+                const stats = fetch('/audit/stats').then(r => r.json());
+                dashboard.panels.forEach(panel => {
+                    if (panel.type === 'stat') statPanel(panel, stats);
+                    else if (panel.type === 'search') searchPanel(panel);
+                    else if (panel.type === 'chart') chartPanel(panel);
+                });
+            })
+            .catch(err => { document.getElementById('panels').innerHTML = '<p class="error">' + escapeHTML(err) + '</p>'; });
+    </script>
+</body>
+</html>`
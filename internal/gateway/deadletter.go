@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/niki4smirn/golf/internal/events"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// tinybirdQueueSize is how many events wait for Tinybird before new ones go straight to the dead-letter table
+const tinybirdQueueSize = 10000
+
+// deadLetterEvent moves an event the Tinybird queue has no room for to the dead-letter table
+func (g *Gateway) deadLetterEvent(event events.Event) error {
+	reason := fmt.Errorf("tinybird delivery queue full")
+	if event.Request != nil {
+		return g.tinybirdDB.DeadLetterRequest(event.Request, reason)
+	}
+	return g.tinybirdDB.DeadLetterResponse(event.Response, reason)
+}
+
+// GetDeadLetters lists Tinybird events that could not be delivered, newest
+// first, optionally of one ?datasource=audit_requests|audit_responses
+func (g *Gateway) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, offset := 100, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	datasource := r.URL.Query().Get("datasource")
+
+	deadLetters, err := g.db.GetDeadLetters(datasource, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve dead letters: %v", err), http.StatusInternalServerError)
+		return
+	}
+	totals, err := g.db.CountDeadLetters()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count dead letters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.DeadLettersResponse{
+		DeadLetters: deadLetters,
+		Totals:      totals,
+		Limit:       limit,
+		Offset:      offset,
+		Count:       len(deadLetters),
+	})
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// debugHeader asks for debug capture of a single call; honored for the admin token and clients with allow_debug
+const debugHeader = "X-Golf-Debug"
+
+// SetDebugSettings changes the log level and global debug capture, taking effect on the next request
+func (g *Gateway) SetDebugSettings(settings types.DebugSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	g.debugSettings.Store(settings)
+	return nil
+}
+
+// DebugSettings returns the current log level and debug capture
+func (g *Gateway) DebugSettings() types.DebugSettings {
+	if settings, ok := g.debugSettings.Load().(types.DebugSettings); ok {
+		return settings
+	}
+	return types.DebugSettings{LogLevel: types.LogLevelInfo}
+}
+
+// LogLevel returns the current log level, one of the types.LogLevel* constants
+func (g *Gateway) LogLevel() string {
+	return g.DebugSettings().LogLevel
+}
+
+// debugf logs only at the debug log level
+func (g *Gateway) debugf(format string, args ...interface{}) {
+	if g.LogLevel() == types.LogLevelDebug {
+		log.Printf(format, args...)
+	}
+}
+
+// wantsDebug reports whether a call should be captured in detail: capture is
+// on globally, or a trusted caller asked for it with the X-Golf-Debug header
+func (g *Gateway) wantsDebug(r *http.Request, client *config.APIKey) bool {
+	if g.DebugSettings().Capture {
+		return true
+	}
+	switch strings.ToLower(r.Header.Get(debugHeader)) {
+	case "1", "true":
+	default:
+		return false
+	}
+	return g.isAdmin(r) || (client != nil && client.AllowDebug)
+}
+
+// GetDebugSettings returns the current log level and debug capture
+func (g *Gateway) GetDebugSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.DebugSettings())
+}
+
+// UpdateDebugSettings changes the log level and debug capture without a restart.
+// Fields missing from the body keep their current value.
+func (g *Gateway) UpdateDebugSettings(w http.ResponseWriter, r *http.Request) {
+	settings := g.DebugSettings()
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := g.SetDebugSettings(settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Debug settings changed by %s: log level %s, capture %t", getClientIP(r), settings.LogLevel, settings.Capture)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// debugCapture collects the detail of one captured call
+type debugCapture struct {
+	credentialHeader string // Header carrying the gateway API key, masked
+	authHeader       string // Header of the injected upstream credential, masked
+	redaction        string
+	requestHeaders   http.Header
+}
+
+// record encodes the capture for the audit response. It returns nil for calls not captured.
+func (d *debugCapture) record(resp *http.Response, timing *types.UpstreamTiming) json.RawMessage {
+	if d == nil {
+		return nil
+	}
+	capture := types.DebugCapture{Timing: timing}
+	if d.requestHeaders != nil {
+		capture.RequestHeaders = flattenHeaders(d.requestHeaders)
+		redactHeaders(capture.RequestHeaders, d.redaction, d.credentialHeader)
+		if _, ok := capture.RequestHeaders[d.authHeader]; ok {
+			capture.RequestHeaders[d.authHeader] = redactedValue
+		}
+	}
+	if resp != nil {
+		capture.ResponseHeaders = flattenHeaders(resp.Header)
+		redactHeaders(capture.ResponseHeaders, d.redaction, "")
+	}
+
+	data, err := json.Marshal(capture)
+	if err != nil {
+		log.Printf("Failed to encode debug capture: %v", err)
+		return nil
+	}
+	return data
+}
+
+// flattenHeaders joins repeated header values the way HTTP allows combining them
+func flattenHeaders(header http.Header) map[string]string {
+	flat := make(map[string]string, len(header))
+	for name, values := range header {
+		flat[name] = strings.Join(values, ", ")
+	}
+	return flat
+}
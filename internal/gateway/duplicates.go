@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Defaults of a cache candidate: a method called often enough whose answers mostly repeat
+const (
+	defaultCandidateMinCalls = 20
+	defaultCandidateMinRatio = 0.5
+)
+
+// GetResponseDuplicates reports how often each method returned identical
+// responses, flagging the ones worth caching. Query params: window (default
+// 24h), min_calls and min_ratio (the thresholds of a cache candidate).
+func (g *Gateway) GetResponseDuplicates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	window := 24 * time.Hour
+	if s := query.Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid window, expected a duration such as 6h", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	minCalls := defaultCandidateMinCalls
+	if s := query.Get("min_calls"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid min_calls, expected a positive integer", http.StatusBadRequest)
+			return
+		}
+		minCalls = n
+	}
+	minRatio := defaultCandidateMinRatio
+	if s := query.Get("min_ratio"); s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < 0 || f > 1 {
+			http.Error(w, "Invalid min_ratio, expected a number between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		minRatio = f
+	}
+
+	to := g.now()
+	from := to.Add(-window)
+	methods, err := g.db.GetResponseDuplicates(from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve response duplicates: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := types.ResponseDuplicatesResponse{From: from, To: to, MinCalls: minCalls, MinRatio: minRatio, Methods: []types.ResponseDuplicates{}}
+	for _, m := range methods {
+		if g.suppressed(m.Calls) {
+			response.SuppressedBuckets++
+			continue
+		}
+		m.Candidate = m.Calls >= minCalls && m.DuplicateRatio >= minRatio
+		response.Methods = append(response.Methods, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/events"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// auditUnavailableCode is the JSON-RPC error code of strict calls refused because their request could not be committed
+const auditUnavailableCode = -32008
+
+const (
+	relaxedQueue     = "store-relaxed" // Bus subscription writing the events of relaxed calls
+	relaxedQueueSize = 10000           // Events of relaxed calls waiting to be written
+	relaxedBatchSize = 256             // Events written in one transaction
+)
+
+// durabilityState counts the writes of the strict and relaxed durability modes
+type durabilityState struct {
+	mode           string // Default of routes without their own, empty means standard
+	stopRelaxed    func()
+	strictWrites   int64
+	strictFailures int64
+	relaxedBatches int64
+}
+
+// SetDurability sets the audit durability of routes without their own, see
+// types.DurabilityStrict. Strict routes need an audit store able to commit
+// requests durably; relaxed ones start a queue written in batches, which
+// StopRelaxedWrites flushes. Call it after SetRoutes and SetSpool.
+func (g *Gateway) SetDurability(mode string) error {
+	if err := config.ValidateDurability(mode); err != nil {
+		return err
+	}
+	g.durability.mode = mode
+
+	var strict, relaxed bool
+	for i := range g.routes {
+		switch g.durabilityOf(&g.routes[i]) {
+		case types.DurabilityStrict:
+			strict = true
+		case types.DurabilityRelaxed:
+			relaxed = true
+		}
+	}
+	if _, ok := g.writer.(database.DurableWriter); strict && (!ok || g.db == nil) {
+		return fmt.Errorf("strict durability needs the SQLite audit database")
+	}
+	if relaxed && g.durability.stopRelaxed == nil {
+		// Events overflowing the queue are written on the proxy path instead of being lost
+		g.durability.stopRelaxed = g.bus.SubscribeBatched(relaxedQueue, relaxedQueueSize, relaxedBatchSize,
+			func(event events.Event) bool { return event.Durability == types.DurabilityRelaxed },
+			g.writeBatch, g.storeEvent)
+	}
+	return nil
+}
+
+// StopRelaxedWrites writes the events still queued for relaxed routes and stops queuing
+func (g *Gateway) StopRelaxedWrites() {
+	if g.durability.stopRelaxed != nil {
+		g.durability.stopRelaxed()
+		g.durability.stopRelaxed = nil
+	}
+}
+
+// durabilityOf returns the audit durability of calls on route
+func (g *Gateway) durabilityOf(route *config.Route) string {
+	switch {
+	case route.Durability != "":
+		return route.Durability
+	case g.durability.mode != "":
+		return g.durability.mode
+	}
+	return types.DurabilityStandard
+}
+
+// storeEvent writes an event through the standard audit write path
+func (g *Gateway) storeEvent(event events.Event) error {
+	return events.WriterHandler(g.writer)(event)
+}
+
+// writtenElsewhere reports whether the standard write path must skip an event:
+// the requests of strict calls are committed before forwarding, and relaxed
+// events are written by their queue
+func writtenElsewhere(event events.Event) bool {
+	switch event.Durability {
+	case types.DurabilityStrict:
+		return event.Request != nil
+	case types.DurabilityRelaxed:
+		return true
+	}
+	return false
+}
+
+// writeDurable commits the request of a strict call before it is forwarded
+func (g *Gateway) writeDurable(auditRequest *types.AuditRequest) error {
+	start := g.now()
+	durable, ok := g.writer.(database.DurableWriter)
+	err := fmt.Errorf("audit store cannot commit requests durably")
+	if ok {
+		err = durable.InsertAuditRequestDurable(auditRequest)
+	}
+	g.pipeline.observe("store", g.since(start), err)
+	if err != nil {
+		atomic.AddInt64(&g.durability.strictFailures, 1)
+		return err
+	}
+	atomic.AddInt64(&g.durability.strictWrites, 1)
+	return nil
+}
+
+// writeBatch writes the queued events of relaxed calls in one transaction when the store supports it
+func (g *Gateway) writeBatch(batch []events.Event) error {
+	start := g.now()
+	var err error
+	if writer, ok := g.writer.(database.BatchWriter); ok {
+		records := make([]types.AuditRecord, len(batch))
+		for i, event := range batch {
+			if event.Request != nil {
+				records[i] = types.AuditRecord{Type: types.RecordRequest, Request: event.Request}
+			} else {
+				records[i] = types.AuditRecord{Type: types.RecordResponse, Response: event.Response}
+			}
+		}
+		err = writer.InsertAuditBatch(records)
+	} else {
+		for _, event := range batch {
+			if writeErr := g.storeEvent(event); writeErr != nil {
+				log.Printf("Failed to write relaxed audit event: %v", writeErr)
+				err = writeErr
+			}
+		}
+	}
+	g.pipeline.observe("store-relaxed", g.since(start), err)
+	atomic.AddInt64(&g.durability.relaxedBatches, 1)
+	return err
+}
+
+// durabilityStatus reports the durability modes in use and their counters
+func (g *Gateway) durabilityStatus() *types.DurabilityStatus {
+	status := &types.DurabilityStatus{
+		Mode:           types.DurabilityStandard,
+		StrictWrites:   atomic.LoadInt64(&g.durability.strictWrites),
+		StrictFailures: atomic.LoadInt64(&g.durability.strictFailures),
+		RelaxedBatches: atomic.LoadInt64(&g.durability.relaxedBatches),
+	}
+	if g.durability.mode != "" {
+		status.Mode = g.durability.mode
+	}
+	for i := range g.routes {
+		if mode := g.durabilityOf(&g.routes[i]); mode != status.Mode {
+			if status.Routes == nil {
+				status.Routes = make(map[string]string)
+			}
+			status.Routes[g.routes[i].Name] = mode
+		}
+	}
+	for _, q := range g.bus.Queues() {
+		if q.Name == relaxedQueue {
+			status.RelaxedQueued = q.Depth
+		}
+	}
+	return status
+}
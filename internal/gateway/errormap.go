@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// upstreamErrorCode is the JSON-RPC error code of upstream HTTP errors on strict-spec routes without a mapping for their status
+const upstreamErrorCode = -32603
+
+// maxErrorBody is how much of an upstream error body is kept in the JSON-RPC error data
+const maxErrorBody = 512
+
+// failureMessages are the JSON-RPC error messages of failures mapped to a configured code
+var failureMessages = map[string]string{
+	config.ErrorTimeout:     "Upstream timeout",
+	config.ErrorConnection:  "Upstream unreachable",
+	config.ErrorUnavailable: "Upstream unavailable",
+	config.ErrorBusy:        "Upstream busy",
+	config.ErrorAgedOut:     "Request aged out",
+	config.ErrorInternal:    "Internal error",
+}
+
+// failureError returns the JSON-RPC error of a gateway failure: the route's
+// configured code if it maps the failure, else code and message
+func failureError(route *config.Route, failure string, code int, message string, data interface{}) *types.JSONRPCError {
+	if route != nil {
+		if mapped, ok := route.ErrorCode(failure); ok {
+			code, message = mapped, failureMessages[failure]
+		}
+	}
+	return &types.JSONRPCError{Code: code, Message: message, Data: data}
+}
+
+// wrapUpstreamError answers an upstream HTTP error whose body is not a JSON-RPC
+// response with a JSON-RPC error, if the route maps its status or is strict-spec.
+// It reports whether the call was answered.
+func (g *Gateway) wrapUpstreamError(w http.ResponseWriter, call *proxyCall, status int, body []byte) bool {
+	if status < 400 || call.route == nil {
+		return false
+	}
+	if reason, _ := inspectUpstreamResponse(body); reason == "" && len(body) > 0 {
+		return false
+	}
+
+	code, ok := call.route.UpstreamErrorCode(status)
+	if !ok && !call.route.StrictSpec {
+		return false
+	}
+	if !ok {
+		code = upstreamErrorCode
+	}
+
+	text := strings.TrimSpace(string(body))
+	if len(text) > maxErrorBody {
+		text = text[:maxErrorBody]
+	}
+	rpcErr := &types.JSONRPCError{
+		Code:    code,
+		Message: fmt.Sprintf("Upstream error: %d %s", status, http.StatusText(status)),
+		Data:    types.UpstreamErrorData{Status: status, Body: text, RequestID: call.requestID},
+	}
+	errorMsg := fmt.Sprintf("upstream returned HTTP %d: %s", status, text)
+	g.writeRPCError(w, call.id, rpcErr, errorMsg, call.requestID, call.startTime, status, "")
+	return true
+}
+
+// strictWriter answers JSON errors with HTTP 200, for routes in strict-spec mode.
+// Callers pass the failure status as usual, so audit rows keep it.
+type strictWriter struct {
+	http.ResponseWriter
+}
+
+func (w strictWriter) WriteHeader(status int) {
+	if status >= 400 && types.IsJSONContentType(w.Header().Get("Content-Type")) {
+		status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController flush streamed responses
+func (w strictWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
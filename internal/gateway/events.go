@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"github.com/niki4smirn/golf/internal/events"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// eventUpstreamFailure is delivered to webhooks when a call fails to reach its target
+const eventUpstreamFailure = "upstream.failure"
+
+// initBus creates the audit event bus with the primary store as its first subscriber
+func (g *Gateway) initBus() {
+	g.bus = events.New()
+	g.pipeline = newPipelineMetrics()
+	// Resolve the writer per event so SetSpool takes effect on the existing subscription
+	store := g.timed("store", g.storeEvent)
+	g.bus.Subscribe("store", func(event events.Event) error {
+		if writtenElsewhere(event) {
+			return nil
+		}
+		return store(event)
+	})
+	g.bus.Subscribe("webhooks", g.notifyUpstreamFailure)
+}
+
+// Subscribe adds an in-process sink receiving every audit request and response.
+// Handlers run on the proxy path; use SubscribeAsync for slow sinks.
+func (g *Gateway) Subscribe(name string, handler events.Handler) (unsubscribe func()) {
+	return g.bus.Subscribe(name, handler)
+}
+
+// SubscribeAsync adds a sink behind a queue of size events, dropping events while it is full
+func (g *Gateway) SubscribeAsync(name string, size int, handler events.Handler) (unsubscribe func()) {
+	return g.bus.SubscribeAsync(name, size, handler)
+}
+
+// notifyUpstreamFailure alerts webhooks of responses recording a failed upstream call
+func (g *Gateway) notifyUpstreamFailure(event events.Event) error {
+	if len(g.webhooks) == 0 || event.Response == nil || event.Response.FailureKind == "" ||
+		event.Response.FailureKind == types.FailureClientCancelled {
+		return nil
+	}
+	failure := *event.Response
+	failure.Response = nil
+	g.notify(eventUpstreamFailure, failure)
+	return nil
+}
@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const (
+	exportBatchSize        = 1000   // Rows read from the database per query
+	defaultExportChunkSize = 100000 // Ids per manifest chunk
+)
+
+// exportTables maps the table names of the export API to the audit tables
+var exportTables = map[string]string{
+	"requests":  "audit_requests",
+	"responses": "audit_responses",
+}
+
+// ExportAuditLogs streams the rows of one table as NDJSON audit records, the
+// format accepted by /audit/import, or with format=parquet as a Parquet file.
+// Rows come in id order and each record carries its id, so an interrupted
+// export resumes with after_id set to the last id received. until_id and
+// limit bound the stream. With anonymize=true client identities and the
+// configured fields are replaced with stable pseudonyms, see SetAnonymization.
+func (g *Gateway) ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	table := query.Get("table")
+	if table == "" {
+		table = "requests"
+	}
+	if _, ok := exportTables[table]; !ok {
+		http.Error(w, fmt.Sprintf("Invalid table %q, use requests or responses", table), http.StatusBadRequest)
+		return
+	}
+
+	afterID, untilID, limit := int64(0), int64(math.MaxInt64), int64(math.MaxInt64)
+	for name, value := range map[string]*int64{"after_id": &afterID, "until_id": &untilID, "limit": &limit} {
+		if s := query.Get(name); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				http.Error(w, fmt.Sprintf("Invalid %s %q", name, s), http.StatusBadRequest)
+				return
+			}
+			*value = n
+		}
+	}
+
+	anonymize := query.Get("anonymize") == "true"
+	if anonymize && g.anonymizer == nil {
+		http.Error(w, "Anonymized exports are not configured", http.StatusNotImplemented)
+		return
+	}
+
+	out := bufio.NewWriter(w)
+	var sink exportSink
+	switch format := query.Get("format"); format {
+	case "", "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		sink = &ndjsonSink{encoder: json.NewEncoder(out)}
+	case "parquet":
+		w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%s-%d.parquet", table, afterID))
+		sink = newParquetSink(out, table)
+	default:
+		http.Error(w, fmt.Sprintf("Invalid format %q, use ndjson or parquet", format), http.StatusBadRequest)
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+
+	var exported int64
+	for exported < limit {
+		batch := int(min(limit-exported, exportBatchSize))
+		records, lastID, err := g.exportBatch(table, afterID, untilID, batch)
+		if err != nil {
+			// Headers are gone once rows were sent; the client sees a short stream and resumes
+			log.Printf("Failed to export audit %s after id %d: %v", table, afterID, err)
+			if exported == 0 {
+				http.Error(w, fmt.Sprintf("Failed to export audit %s: %v", table, err), http.StatusInternalServerError)
+			}
+			return
+		}
+		if anonymize {
+			for i := range records {
+				records[i] = g.anonymizer.record(records[i])
+			}
+		}
+		if err := sink.write(records); err != nil {
+			log.Printf("Failed to export audit %s after id %d: %v", table, afterID, err)
+			return
+		}
+		if err := out.Flush(); err != nil {
+			return // Client went away
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		exported += int64(len(records))
+		afterID = lastID
+		if len(records) < batch {
+			break
+		}
+		if r.Context().Err() != nil {
+			return
+		}
+	}
+	if err := sink.close(); err != nil {
+		log.Printf("Failed to finish audit %s export: %v", table, err)
+		return
+	}
+	out.Flush()
+}
+
+// exportSink encodes exported audit records
+type exportSink interface {
+	write(records []types.AuditRecord) error
+	close() error
+}
+
+// ndjsonSink writes one audit record per line
+type ndjsonSink struct {
+	encoder *json.Encoder
+}
+
+func (s *ndjsonSink) write(records []types.AuditRecord) error {
+	for _, record := range records {
+		if err := s.encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ndjsonSink) close() error {
+	return nil
+}
+
+// exportBatch reads up to limit rows of table after afterID and returns them
+// as audit records along with the id of the last row
+func (g *Gateway) exportBatch(table string, afterID, untilID int64, limit int) ([]types.AuditRecord, int64, error) {
+	var records []types.AuditRecord
+	lastID := afterID
+
+	if table == "requests" {
+		requests, err := g.db.GetAuditRequestsBetween(afterID, untilID, limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range requests {
+			records = append(records, types.AuditRecord{Type: types.RecordRequest, Request: &requests[i]})
+			lastID = requests[i].ID
+		}
+		return records, lastID, nil
+	}
+
+	responses, err := g.db.GetAuditResponsesBetween(afterID, untilID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range responses {
+		records = append(records, types.AuditRecord{Type: types.RecordResponse, Response: &responses[i]})
+		lastID = responses[i].ID
+	}
+	return records, lastID, nil
+}
+
+// GetExportManifest splits the audit tables into id-range chunks for
+// resumable, parallel exports
+func (g *Gateway) GetExportManifest(w http.ResponseWriter, r *http.Request) {
+	chunkSize := int64(defaultExportChunkSize)
+	if s := r.URL.Query().Get("chunk_size"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid chunk_size %q", s), http.StatusBadRequest)
+			return
+		}
+		chunkSize = n
+	}
+	anonymize := r.URL.Query().Get("anonymize") == "true"
+
+	manifest := types.ExportManifest{GeneratedAt: g.now(), ChunkSize: chunkSize, Tables: []types.ExportTable{}, Chunks: []types.ExportChunk{}}
+	for _, name := range []string{"requests", "responses"} {
+		minID, maxID, rows, err := g.db.GetIDRange(exportTables[name])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to build export manifest: %v", err), http.StatusInternalServerError)
+			return
+		}
+		manifest.Tables = append(manifest.Tables, types.ExportTable{Name: name, MinID: minID, MaxID: maxID, Rows: rows})
+		if rows == 0 {
+			continue
+		}
+
+		// Rows added after the manifest was generated are left for the next one
+		for after := minID - 1; after < maxID; after += chunkSize {
+			until := min(after+chunkSize, maxID)
+			query := url.Values{}
+			query.Set("table", name)
+			query.Set("after_id", strconv.FormatInt(after, 10))
+			query.Set("until_id", strconv.FormatInt(until, 10))
+			if anonymize {
+				query.Set("anonymize", "true")
+			}
+			manifest.Chunks = append(manifest.Chunks, types.ExportChunk{
+				Table:   name,
+				AfterID: after,
+				UntilID: until,
+				URL:     "/audit/export?" + query.Encode(),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manifest)
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/niki4smirn/golf/internal/parquet"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Parquet columns of exported requests and responses. Empty strings are
+// exported as nulls; JSON bodies, headers, tags and labels as JSON text.
+var (
+	parquetRequestColumns = []parquet.Column{
+		{Name: "id", Type: parquet.Int64},
+		{Name: "timestamp", Type: parquet.Timestamp},
+		{Name: "method", Type: parquet.String},
+		{Name: "request_id", Type: parquet.String},
+		{Name: "ip_address", Type: parquet.String, Optional: true},
+		{Name: "user_agent", Type: parquet.String, Optional: true},
+		{Name: "http_method", Type: parquet.String, Optional: true},
+		{Name: "upstream_url", Type: parquet.String, Optional: true},
+		{Name: "api_key", Type: parquet.String, Optional: true},
+		{Name: "tenant", Type: parquet.String, Optional: true},
+		{Name: "content_type", Type: parquet.String, Optional: true},
+		{Name: "body_encoding", Type: parquet.String, Optional: true},
+		{Name: "upstream_method", Type: parquet.String, Optional: true},
+		{Name: "audit_level", Type: parquet.String, Optional: true},
+		{Name: "body_hash", Type: parquet.String, Optional: true},
+		{Name: "fingerprint", Type: parquet.String, Optional: true},
+		{Name: "parent_request_id", Type: parquet.String, Optional: true},
+		{Name: "request_bytes", Type: parquet.Int64},
+		{Name: "env", Type: parquet.String, Optional: true},
+		{Name: "service", Type: parquet.String, Optional: true},
+		{Name: "version", Type: parquet.String, Optional: true},
+		{Name: "labels", Type: parquet.String, Optional: true},
+		{Name: "tags", Type: parquet.String, Optional: true},
+		{Name: "headers", Type: parquet.String, Optional: true},
+		{Name: "request", Type: parquet.String, Optional: true},
+		{Name: "extensions", Type: parquet.String, Optional: true},
+	}
+	parquetResponseColumns = []parquet.Column{
+		{Name: "id", Type: parquet.Int64},
+		{Name: "request_id", Type: parquet.String},
+		{Name: "timestamp", Type: parquet.Timestamp},
+		{Name: "status_code", Type: parquet.Int32},
+		{Name: "process_time_ms", Type: parquet.Int64},
+		{Name: "queue_time_ms", Type: parquet.Int64},
+		{Name: "upstream_time_ms", Type: parquet.Int64},
+		{Name: "error", Type: parquet.String, Optional: true},
+		{Name: "malformed_upstream", Type: parquet.Bool},
+		{Name: "rpc_error_code", Type: parquet.Int32, Optional: true},
+		{Name: "content_type", Type: parquet.String, Optional: true},
+		{Name: "body_encoding", Type: parquet.String, Optional: true},
+		{Name: "failure_kind", Type: parquet.String, Optional: true},
+		{Name: "served_by", Type: parquet.String, Optional: true},
+		{Name: "cache", Type: parquet.String, Optional: true},
+		{Name: "cache_age_ms", Type: parquet.Int64},
+		{Name: "response_bytes", Type: parquet.Int64},
+		{Name: "debug", Type: parquet.String, Optional: true},
+		{Name: "dns_ms", Type: parquet.Double, Optional: true},
+		{Name: "connect_ms", Type: parquet.Double, Optional: true},
+		{Name: "tls_ms", Type: parquet.Double, Optional: true},
+		{Name: "ttfb_ms", Type: parquet.Double, Optional: true},
+		{Name: "read_ms", Type: parquet.Double, Optional: true},
+		{Name: "reused_conn", Type: parquet.Bool, Optional: true},
+		{Name: "response", Type: parquet.String, Optional: true},
+		{Name: "transformed_response", Type: parquet.String, Optional: true},
+		{Name: "response_hash", Type: parquet.String, Optional: true},
+		{Name: "metadata", Type: parquet.String, Optional: true},
+	}
+)
+
+// parquetSink writes audit records of one table as a Parquet file
+type parquetSink struct {
+	writer *parquet.Writer
+}
+
+func newParquetSink(w io.Writer, table string) *parquetSink {
+	columns := parquetRequestColumns
+	if table == "responses" {
+		columns = parquetResponseColumns
+	}
+	return &parquetSink{writer: parquet.NewWriter(w, columns)}
+}
+
+func (s *parquetSink) write(records []types.AuditRecord) error {
+	for _, record := range records {
+		var err error
+		if req := record.Request; req != nil {
+			err = s.writer.Write(
+				req.ID, req.Timestamp, req.Method, req.RequestID,
+				optional(req.IPAddress), optional(req.UserAgent), optional(req.HTTPMethod), optional(req.UpstreamURL),
+				optional(req.APIKey), optional(req.Tenant), optional(req.ContentType), optional(req.BodyEncoding),
+				optional(req.UpstreamMethod), optional(req.AuditLevel), optional(req.BodyHash), optional(req.Fingerprint),
+				optional(req.ParentRequestID), req.RequestBytes,
+				optional(req.Env), optional(req.Service), optional(req.Version),
+				optionalJSON(req.Labels), optionalJSON(req.Tags), optionalRaw(req.Headers), optionalRaw(req.Request),
+				optionalRaw(req.Extensions),
+			)
+		} else if resp := record.Response; resp != nil {
+			var rpcErrorCode interface{}
+			if resp.RPCErrorCode != nil {
+				rpcErrorCode = *resp.RPCErrorCode
+			}
+			var dns, connect, tls, ttfb, read, reused interface{}
+			if t := resp.Timing; t != nil {
+				dns, connect, tls, ttfb, read, reused = t.DNSMs, t.ConnectMs, t.TLSMs, t.TTFBMs, t.ReadMs, t.ReusedConn
+			}
+			err = s.writer.Write(
+				resp.ID, resp.RequestID, resp.Timestamp, resp.StatusCode,
+				resp.ProcessTime, resp.QueueTime, resp.UpstreamTime,
+				optional(resp.Error), resp.MalformedUpstream, rpcErrorCode,
+				optional(resp.ContentType), optional(resp.BodyEncoding), optional(resp.FailureKind),
+				optional(resp.ServedBy), optional(resp.Cache), resp.CacheAgeMs, resp.ResponseBytes,
+				optionalRaw(resp.Debug), dns, connect, tls, ttfb, read, reused,
+				optionalRaw(resp.Response), optionalRaw(resp.TransformedResponse), optional(resp.ResponseHash),
+				optionalJSON(resp.Metadata),
+			)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *parquetSink) close() error {
+	return s.writer.Close()
+}
+
+// optional maps empty strings to null
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func optionalRaw(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return []byte(raw)
+}
+
+func optionalJSON(m map[string]string) interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+	encoded, _ := json.Marshal(m)
+	return encoded
+}
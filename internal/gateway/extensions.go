@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// standardMembers are the members of a JSON-RPC 2.0 request; anything else a
+// client sends at the top level is an extension
+var standardMembers = map[string]bool{"jsonrpc": true, "id": true, "method": true, "params": true}
+
+// SetExtensions configures which extension members are indexed and which are masked
+func (g *Gateway) SetExtensions(extensions *config.Extensions) {
+	g.extensions = extensions
+}
+
+// requestExtensions splits the non-standard top-level members off a single
+// JSON-RPC request, e.g. meta or auth, which the typed request drops. It
+// returns them as a JSON object for the extensions column, masked as the
+// redaction level and the redact list require, and the tags of the indexed
+// members. The body itself is forwarded untouched.
+func (g *Gateway) requestExtensions(body []byte, redaction string) (json.RawMessage, map[string]string) {
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, nil
+	}
+	extensions := make(map[string]json.RawMessage)
+	for name, value := range message {
+		if !standardMembers[name] {
+			extensions[name] = value
+		}
+	}
+	if len(extensions) == 0 {
+		return nil, nil
+	}
+
+	masked, _ := json.Marshal(redactedValue)
+	var redact []string
+	if g.extensions != nil {
+		redact = g.extensions.Redact
+	}
+	for name := range extensions {
+		if redaction == types.RedactionPayload || containsString(redact, name) {
+			extensions[name] = masked
+		}
+	}
+
+	var tags map[string]string
+	if g.extensions != nil && len(g.extensions.Index) > 0 {
+		var doc interface{}
+		data, _ := json.Marshal(extensions)
+		json.Unmarshal(data, &doc)
+		for _, path := range g.extensions.Index {
+			value, ok := lookupPath(doc, path)
+			if !ok || value == redactedValue {
+				continue
+			}
+			if len(value) > maxTagValueLength {
+				value = value[:maxTagValueLength]
+			}
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			tags[types.ExtensionTagPrefix+path] = value
+		}
+	}
+
+	encoded, err := json.Marshal(extensions)
+	if err != nil {
+		return nil, tags
+	}
+	return encoded, tags
+}
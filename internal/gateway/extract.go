@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/config"
+)
+
+// SetExtractions configures which request fields are promoted to audit tags
+func (g *Gateway) SetExtractions(extractions []config.Extraction) {
+	g.extractions = extractions
+}
+
+// extractTags applies the configured extraction rules to a request body
+func (g *Gateway) extractTags(body []byte, method string) map[string]string {
+	if len(g.extractions) == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for _, e := range g.extractions {
+		if len(e.Methods) > 0 && !containsString(e.Methods, method) {
+			continue
+		}
+		if value, ok := lookupPath(doc, e.Path); ok {
+			tags[e.Tag] = value
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// lookupPath resolves a dotted path with optional array indexes ($.params.items[0].id)
+// and returns the value as a string. Objects and arrays are returned as JSON.
+func lookupPath(doc interface{}, path string) (string, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	current := doc
+
+	for _, segment := range strings.Split(path, ".") {
+		name := segment
+		var indexes []int
+		if i := strings.Index(segment, "["); i != -1 {
+			name = segment[:i]
+			for _, part := range strings.Split(segment[i:], "[")[1:] {
+				idx, err := strconv.Atoi(strings.TrimSuffix(part, "]"))
+				if err != nil {
+					return "", false
+				}
+				indexes = append(indexes, idx)
+			}
+		}
+
+		if name != "" {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return "", false
+			}
+			if current, ok = obj[name]; !ok {
+				return "", false
+			}
+		}
+
+		for _, idx := range indexes {
+			arr, ok := current.([]interface{})
+			if !ok || idx < 0 || idx >= len(arr) {
+				return "", false
+			}
+			current = arr[idx]
+		}
+	}
+
+	switch v := current.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v), true
+		}
+		return string(data), true
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// batchItems splits a batch call for fan-out, or returns nil when the route
+// forwards batches whole or the body is not a batch of several calls
+func batchItems(route *config.Route, body []byte) []json.RawMessage {
+	if route == nil || route.BatchConcurrency <= 1 {
+		return nil
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		return nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil || len(items) < 2 {
+		return nil
+	}
+	return items
+}
+
+// itemResult is the outcome of one batch item sent on its own
+type itemResult struct {
+	body   []byte // Response to the item, nil for notifications
+	header http.Header
+	err    error          // Set when the target produced no response
+	timer  *upstreamTimer // Phases of the item's exchange with the target
+}
+
+// fanOut sends the items of a batch to the target concurrently, at most
+// route.BatchConcurrency at a time, and assembles their responses in batch
+// order into one response. Items the target failed to answer get a JSON-RPC
+// error; only when every item failed is the error returned for the whole call.
+// The call's timer gets the phases of the slowest item, the one answering last.
+func (g *Gateway) fanOut(ctx context.Context, r *http.Request, call *proxyCall, items []json.RawMessage) (*http.Response, error) {
+	results := make([]itemResult, len(items))
+	slots := make(chan struct{}, call.route.BatchConcurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item json.RawMessage) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i].err = ctx.Err()
+				return
+			}
+			defer func() { <-slots }()
+			results[i] = g.sendItem(ctx, r, call, i, item)
+		}(i, item)
+	}
+	wg.Wait()
+	for _, result := range results {
+		if result.timer != nil && result.timer.firstByte.After(call.timer.firstByte) {
+			*call.timer = *result.timer
+		}
+	}
+
+	var buf bytes.Buffer
+	var header http.Header
+	var firstErr error
+	failed, answered := 0, 0
+	buf.WriteByte('[')
+	for i, result := range results {
+		body := result.body
+		if result.err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			body = itemError(call.route, items[i], result.err)
+		}
+		if body == nil {
+			continue
+		}
+		if header == nil && result.header != nil {
+			header = result.header
+		}
+		if answered > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(body)
+		answered++
+	}
+	buf.WriteByte(']')
+
+	if failed == len(items) {
+		return nil, firstErr
+	}
+	if answered == 0 {
+		// Only notifications, which get no response
+		return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: http.NoBody}, nil
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+	header = header.Clone()
+	header.Set("Content-Type", "application/json")
+	header.Del("Content-Length")
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(buf.Bytes())),
+		ContentLength: int64(buf.Len()),
+	}, nil
+}
+
+// sendItem sends one batch item, failing over to the secondary target like whole calls do
+func (g *Gateway) sendItem(ctx context.Context, r *http.Request, call *proxyCall, i int, item json.RawMessage) itemResult {
+	// Items share the call's settings but time their own exchange; only the
+	// first item's request headers are captured for debugging
+	itemCall := *call
+	itemCall.timer = &upstreamTimer{now: g.now}
+	if i > 0 {
+		itemCall.debug = nil
+	}
+
+	resp, err := g.send(ctx, r, &itemCall, upstream{url: call.upstreamURL, tcp: call.tcp}, item)
+	if call.secondary != nil && ctx.Err() == nil && shouldFailover(resp, err) {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = g.send(ctx, r, &itemCall, *call.secondary, item)
+	}
+	if err != nil {
+		return itemResult{err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := readBody(resp.Body, resp.ContentLength)
+	if err != nil {
+		return itemResult{err: fmt.Errorf("failed to read response: %w", err)}
+	}
+	// Items are reassembled as JSON, so event streams give up their payload
+	body = bytes.TrimSpace(types.UnwrapSSE(body))
+	if len(body) == 0 && resp.StatusCode < 400 {
+		return itemResult{header: resp.Header, timer: itemCall.timer}
+	}
+	if reason, _ := inspectUpstreamResponse(body); reason != "" || len(body) == 0 || body[0] != '{' {
+		// Not a single JSON-RPC response, e.g. an HTTP error page
+		if len(body) > maxErrorBody {
+			body = body[:maxErrorBody]
+		}
+		return itemResult{body: upstreamItemError(call.route, item, resp.StatusCode, body), header: resp.Header, timer: itemCall.timer}
+	}
+	return itemResult{body: body, header: resp.Header, timer: itemCall.timer}
+}
+
+// itemError is the response to a batch item the target produced no response for
+func itemError(route *config.Route, item json.RawMessage, err error) []byte {
+	rpcErr := failureError(route, config.ErrorConnection, -32603, "Internal error", fmt.Sprintf("Failed to forward request: %v", err))
+	if isTimeout(err) {
+		rpcErr = failureError(route, config.ErrorTimeout, upstreamTimeoutCode, "Upstream timeout", err.Error())
+	}
+	return itemResponse(item, rpcErr)
+}
+
+// upstreamItemError is the response to a batch item the target answered with something other than a JSON-RPC response
+func upstreamItemError(route *config.Route, item json.RawMessage, status int, body []byte) []byte {
+	code := upstreamErrorCode
+	if mapped, ok := route.UpstreamErrorCode(status); ok {
+		code = mapped
+	}
+	return itemResponse(item, &types.JSONRPCError{
+		Code:    code,
+		Message: "Upstream error",
+		Data:    "status " + strconv.Itoa(status) + ": " + string(body),
+	})
+}
+
+// itemResponse answers a batch item with an error, or returns nil for notifications
+func itemResponse(item json.RawMessage, rpcErr *types.JSONRPCError) []byte {
+	var req types.JSONRPCRequest
+	if err := json.Unmarshal(item, &req); err == nil && req.ID == nil {
+		return nil
+	}
+	data, _ := json.Marshal(types.JSONRPCResponse{ID: req.ID, JSONRPC: "2.0", Error: rpcErr})
+	return data
+}
@@ -0,0 +1,26 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// ListDatabaseFiles lists the files of a rotating audit database
+func (g *Gateway) ListDatabaseFiles(w http.ResponseWriter, r *http.Request) {
+	if g.rotator == nil {
+		http.Error(w, "Database rotation is disabled, start the gateway with -rotate or -rotate-size-mb", http.StatusNotFound)
+		return
+	}
+
+	files, err := g.rotator.Files()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list database files: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.DatabaseFilesResponse{Files: files, Count: len(files)})
+}
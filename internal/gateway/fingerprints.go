@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetSeenClients lists the distinct clients calling the gateway by fingerprint,
+// to discover consumers nobody knew about. ?unidentified=true lists only
+// clients calling without an API key.
+func (g *Gateway) GetSeenClients(w http.ResponseWriter, r *http.Request) {
+	limit, offset := 100, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	unidentified := r.URL.Query().Get("unidentified") == "true"
+
+	clients, err := g.db.GetSeenClients(unidentified, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve clients: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.SeenClientsResponse{Clients: clients, Limit: limit, Offset: offset, Count: len(clients)})
+}
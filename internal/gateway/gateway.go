@@ -2,170 +2,891 @@ package gateway
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/niki4smirn/golf/internal/accesslog"
+	"github.com/niki4smirn/golf/internal/clock"
+	"github.com/niki4smirn/golf/internal/config"
 	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/events"
+	"github.com/niki4smirn/golf/internal/requestid"
+	"github.com/niki4smirn/golf/internal/script"
 	"github.com/niki4smirn/golf/internal/types"
 )
 
 // Gateway handles JSON-RPC requests and audit logging
 type Gateway struct {
-	db         *database.Database
-	tinybirdDB *database.TinybirdDatabase
-	targetURL  string
-	httpClient *http.Client
+	db          *database.Database     // SQLite store, nil when running Tinybird-only
+	store       database.AuditDatabase // Serves the basic audit reads, db or Tinybird
+	writer      database.AuditWriter   // Audit write path, either db or a spool in front of it
+	spool       *database.Spool
+	maintenance *database.Maintenance
+	deployment  types.Deployment // Stamped on every recorded request
+	tinybirdDB  *database.TinybirdDatabase
+	bus         *events.Bus // Every audit request and response is published here
+	pipeline    *pipelineMetrics
+	concurrency concurrencyTracker        // Calls per method waiting for or holding an upstream slot
+	scripts     map[string]*script.Script // Scripts by route name, see LoadScripts
+	routes      []config.Route
+	httpClient  *http.Client
+
+	targetLimiters map[string]*targetLimiter // Concurrency limits by target URL
+	tcpPools       map[string]*tcpPool       // Connections to tcp:// targets by target URL
+
+	apiKeysMu    sync.RWMutex
+	apiKeys      map[string]config.APIKey
+	tenantQuotas map[string]config.Quota
+	extractions  []config.Extraction
+
+	responseHeaders *config.HeaderFilter // Default filter for routes without their own
+	targetOverrides []string             // Hosts or URL prefixes X-Golf-Target may name, see SetTargetOverrides
+	indexedHeaders  []string             // Canonical names of the request headers stored in audit_headers
+	extensions      *config.Extensions   // Indexed and masked extension members, nil keeps extensions unindexed
+
+	rotator *database.Rotator  // Rotates the SQLite file, nil when disabled
+	usage   *database.Database // Counts calls for quotas and billing, nil uses db
+
+	openRPC       *openRPCCatalog // Target's OpenRPC document, nil when not configured
+	validateCalls bool            // Reject calls not matching openRPC
+
+	pii *piiScanner // Flags likely PII in audited bodies, nil when disabled
+
+	adminToken string
+	signingKey []byte // HMAC key for response signatures, nil when disabled
+	clientsMu  sync.RWMutex
+	clients    map[string]config.APIKey // Database-managed clients by key hash
+	limiter    clientLimiter            // Local token buckets, or shared ones with SetRedis
+	cache      *responseCache           // Answers of methods routes cache, see config.Route.MethodCacheTTLs
+
+	idempotency idempotencyStore // Answers of calls sent with an Idempotency-Key, see config.Route.IdempotencyTTL
+
+	mcpSessions mcpSessionStore // Active sessions of routes tracking MCP, see config.Route.MCP
+
+	slos     []config.SLO
+	sloStop  chan struct{}
+	webhooks []config.Webhook
+
+	orphanStop chan struct{} // Stops the orphan resolver, nil when it is not running
+
+	auditContexts sync.Map // *AuditContext of calls in flight by request ID
+
+	maxRequestBytes    int64 // Request body limit of routes without their own, 0 disables
+	maxResponseCapture int64 // Response bytes kept in the audit trail on routes without their own limit, 0 keeps bodies whole
+
+	malformedUpstream int64 // Malformed upstream responses seen since startup
+
+	clock     clock.Clock // Nil means the system clock
+	startedAt time.Time
+
+	statusPage  config.StatusPage
+	statusMu    sync.Mutex
+	statusCache *types.StatusPage // Last /status result, recomputed after statusCacheTTL
+
+	correlation *config.Correlation // Where calls name their parent call, nil disables parent links
+
+	anonymizer *exportAnonymizer // Pseudonymizes exports requested with anonymize=true
+
+	responseSchemas map[string]*jsonSchema // Expected results by method
+	openRPCSchemas  bool                   // Fall back to the OpenRPC document's result schemas
+	driftQueue      chan types.SchemaDrift // Differences waiting to be recorded, nil when not checking
+
+	billing *config.Billing // Invoice prices, nil bills nothing
+
+	debugSettings atomic.Value // types.DebugSettings, changed at runtime through /admin/debug
+
+	requestIDs requestid.Generator // Nil uses the timestamp format
+
+	statsOnly bool // Aggregation-only mode, see SetStatsOnly
+	minBucket int  // Smallest count published in stats-only mode
+	rbac      bool // Every management endpoint requires a role, see SetRBAC
+
+	auth *authState // Dashboard sign-in through an identity provider, see SetAuthProvider
+
+	bursts burstCaptures // Temporary full-body and debug capture started through /admin/capture/start
+
+	durability durabilityState // When audit rows reach the disk, see SetDurability
+
+	latency latencyHistograms // Answer times per route with exemplars, exposed on /metrics
+
+	accessLog *accesslog.Writer // Proxied requests in the Common or Combined Log Format, see SetAccessLog
+
+	startup startupState // Dependencies checked on startup, see SetStartupStep
 }
 
-// New creates a new Gateway instance
+// New creates a new Gateway instance forwarding /rpc and /mcp to targetURL
 func New(db *database.Database, targetURL string) *Gateway {
-	return &Gateway{
-		db:        db,
-		targetURL: targetURL,
+	g := &Gateway{
+		db:     db,
+		store:  db,
+		writer: db,
+		routes: config.DefaultRoutes(targetURL),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		limiter:     newRateLimiter(),
+		idempotency: newLocalIdempotency(),
+		cache:       newResponseCache(),
+		startedAt:   time.Now(),
 	}
+	g.initBus()
+	return g
+}
+
+// NewWithStore creates a Gateway recording to and reading from store without
+// SQLite, e.g. Tinybird-only. Endpoints that need SQLite respond 501.
+func NewWithStore(store database.AuditDatabase, targetURL string) *Gateway {
+	g := &Gateway{
+		store:  store,
+		writer: store,
+		routes: config.DefaultRoutes(targetURL),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter:     newRateLimiter(),
+		idempotency: newLocalIdempotency(),
+		cache:       newResponseCache(),
+		startedAt:   time.Now(),
+	}
+	g.initBus()
+	return g
+}
+
+// NewProxy creates a Gateway that only forwards and audits calls to targetURL, for embedding
+// in other servers. Quotas, client policies and the management endpoints require New.
+func NewProxy(writer database.AuditWriter, targetURL string) *Gateway {
+	g := &Gateway{
+		writer: writer,
+		routes: []config.Route{{Name: "proxy", Path: "/", Target: targetURL, HTTPMethods: []string{"POST"}}},
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter:     newRateLimiter(),
+		idempotency: newLocalIdempotency(),
+		cache:       newResponseCache(),
+		startedAt:   time.Now(),
+	}
+	g.initBus()
+	return g
+}
+
+// SetHTTPClient replaces the client used to call upstream targets
+func (g *Gateway) SetHTTPClient(client *http.Client) {
+	g.httpClient = client
 }
 
-// SetTinybirdLogger adds Tinybird logging capability
+// SetTinybirdLogger adds Tinybird logging capability. Events are sent from a
+// queue; with SQLite enabled, events Tinybird rejects or that overflow the
+// queue are kept in the dead-letter table instead of being lost.
 func (g *Gateway) SetTinybirdLogger(tinybirdDB *database.TinybirdDatabase) {
 	g.tinybirdDB = tinybirdDB
+	var overflow events.Handler
+	if g.db != nil {
+		tinybirdDB.SetDeadLetter(g.db)
+		overflow = g.deadLetterEvent
+	}
+	g.bus.SubscribeQueued("tinybird", tinybirdQueueSize, g.timed("tinybird", events.WriterHandler(tinybirdDB)), overflow)
+}
+
+// SetSpool buffers audit writes in spool while the database is unavailable
+func (g *Gateway) SetSpool(spool *database.Spool) {
+	g.spool = spool
+	g.writer = spool
+}
+
+// SetMaintenance reports the results of maintenance runs in the health check
+func (g *Gateway) SetMaintenance(maintenance *database.Maintenance) {
+	g.maintenance = maintenance
+}
+
+// SetRotator exposes the files of a rotating audit database at /audit/files
+func (g *Gateway) SetRotator(rotator *database.Rotator) {
+	g.rotator = rotator
+}
+
+// SetUsageStore counts the calls of quotas and billing in db, e.g. the cold
+// database of a hot/cold pair, whose history outlives the pruned hot file
+func (g *Gateway) SetUsageStore(db *database.Database) {
+	g.usage = db
+}
+
+// usageDB returns the database quotas and billing count calls in
+func (g *Gateway) usageDB() *database.Database {
+	if g.usage != nil {
+		return g.usage
+	}
+	return g.db
+}
+
+// SetDeployment sets the environment, service, version and labels recorded with every call
+func (g *Gateway) SetDeployment(deployment types.Deployment) {
+	g.deployment = deployment
+}
+
+// SetRequestIDGenerator selects how request IDs are generated
+func (g *Gateway) SetRequestIDGenerator(gen requestid.Generator) {
+	g.requestIDs = gen
+}
+
+func (g *Gateway) newRequestID() string {
+	if g.requestIDs == nil {
+		return types.NewRequestID()
+	}
+	return g.requestIDs.NewID()
+}
+
+// SetRoutes replaces the default routes with the configured ones
+func (g *Gateway) SetRoutes(routes []config.Route) {
+	if len(routes) > 0 {
+		g.routes = routes
+	}
+	g.targetLimiters = buildTargetLimiters(g.routes)
+	g.tcpPools = buildTCPPools(g.routes)
+}
+
+// matchRoute finds the route with the longest path prefix matching the request path
+func (g *Gateway) matchRoute(path string) *config.Route {
+	var best *config.Route
+	for i := range g.routes {
+		route := &g.routes[i]
+		if path != route.Path && !strings.HasPrefix(path, strings.TrimSuffix(route.Path, "/")+"/") {
+			continue
+		}
+		if best == nil || len(route.Path) > len(best.Path) {
+			best = route
+		}
+	}
+	return best
 }
 
 // ProxyJSONRPC handles incoming JSON-RPC requests, forwards them, and logs everything
 func (g *Gateway) ProxyJSONRPC(w http.ResponseWriter, r *http.Request) {
-	startTime := time.Now()
+	startTime := g.now()
 
 	// Generate a unique request ID for tracking
-	requestID := generateRequestID()
+	requestID := g.newRequestID()
+
+	route := g.matchRoute(r.URL.Path)
+	if route == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Decisions about the call are collected here and stored with its audit response
+	r, audit, untrack := g.trackAuditContext(r, requestID)
+	defer untrack()
+	audit.Set(metaRoute, route.Name)
+	durability := g.durabilityOf(route)
+	if durability != types.DurabilityStandard {
+		audit.Set(metaDurability, durability)
+	}
 
-	// Read the request body
-	body, err := io.ReadAll(r.Body)
+	if route.StrictSpec {
+		w = strictWriter{w}
+	}
+
+	// Calls in the GET query form are handled as the equivalent POST, audited with the method the client used
+	httpMethod := r.Method
+	if route.HTTPGet && r.Method == http.MethodOptions {
+		answerOptions(w, route)
+		return
+	}
+	if isQueryCall(r, route) {
+		converted, err := queryCallRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r = converted
+	}
+
+	// Tags the caller attached to label its own traffic
+	callerTags, err := clientTags(r)
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	r.Body.Close()
+
+	// Resolve the upstream URL, preserving the path suffix and query string
+	rawQuery := withoutTagsParam(r.URL.RawQuery)
+	upstreamURL, upstreamErr := route.UpstreamURL(r.URL.Path, rawQuery)
+	secondaryURL, secondaryErr := route.SecondaryURL(r.URL.Path, rawQuery)
+	if secondaryErr != nil {
+		log.Printf("Failover disabled for route %s: %v", route.Name, secondaryErr)
+	}
+
+	// Admin callers may send a single call to another backend, e.g. staging, through the same audit pipeline
+	override, status, err := g.targetOverride(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if override != "" {
+		upstreamURL, upstreamErr, secondaryURL = override, nil, ""
+		audit.Set(metaOverride, override)
+		log.Printf("Request %s routed to %s by %s", requestID, override, targetHeader)
+	}
+
+	// Read the request body, chunked or not, within the route's size limit
+	body, status, err := g.readRequestBody(w, r, route)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if isChunked(r) {
+		audit.Set(metaTransfer, "chunked")
+	}
 
 	// Parse JSON-RPC request to extract method
+	contentType := r.Header.Get("Content-Type")
 	var jsonRPCReq types.JSONRPCRequest
 	var method string = "unknown"
 	if err := json.Unmarshal(body, &jsonRPCReq); err == nil && jsonRPCReq.Method != "" {
 		method = jsonRPCReq.Method
+	} else if !types.IsJSONContentType(contentType) {
+		// Connect and other binary protocols carry the method in the path
+		method = methodFromPath(strings.TrimPrefix(r.URL.Path, route.Path))
+	}
+
+	// Rename aliased methods to what the upstream expects
+	forwardBody := body
+	var upstreamMethod string
+	if alias, ok := route.MethodAliases[method]; ok && jsonRPCReq.Method != "" {
+		if renamed, err := replaceMethod(body, alias); err != nil {
+			log.Printf("Failed to rename method %s: %v", method, err)
+		} else {
+			forwardBody, upstreamMethod = renamed, alias
+			audit.Set(metaAlias, alias)
+		}
+	}
+
+	// Send calls matching one of the route's rules to the rule's target
+	limitedTarget := route.Target
+	var rule *config.RoutingRule
+	if override == "" && upstreamErr == nil {
+		rule = matchRule(route, r, method, body)
+	}
+	if rule != nil {
+		if ruleURL, err := route.RuleURL(rule, r.URL.Path, rawQuery); err != nil {
+			log.Printf("Rule %s of route %s not applied: %v", rule.Name, route.Name, err)
+			rule = nil
+		} else {
+			upstreamURL, secondaryURL, limitedTarget = ruleURL, "", rule.Target
+			audit.Set(metaRule, rule.Name)
+		}
+	}
+
+	// Identify the calling API key, if keys are configured
+	client := g.identifyClient(r)
+
+	// Check the client's policy and usage quotas before the request itself is recorded
+	rejected := g.checkPolicy(client, method, startTime)
+	if rejected == nil && jsonRPCReq.Method != "" {
+		rejected = g.validateCall(method, jsonRPCReq.Params)
+	}
+	// Let the route's script deny, tag or rewrite the call, or each call of a batch
+	cacheMethod, cacheParams := method, jsonRPCReq.Params
+	if rejected == nil && isBatch(body) {
+		var scriptTags map[string]string
+		forwardBody, scriptTags, rejected = g.runBatchScript(r, route, client, forwardBody)
+		callerTags = mergeTags(scriptTags, callerTags)
+	} else if rejected == nil {
+		var result *script.Result
+		result, rejected = g.runScript(r, route, client, method, jsonRPCReq.Params)
+		if result != nil {
+			callerTags = mergeTags(result.Tags, callerTags)
+			if result.Method != "" || result.Params != nil {
+				if rewritten, err := rewriteCall(forwardBody, result); err != nil {
+					log.Printf("Failed to apply script rewrite of %s: %v", requestID, err)
+				} else {
+					forwardBody = rewritten
+					if result.Method != "" {
+						upstreamMethod, cacheMethod = result.Method, result.Method
+					}
+					if result.Params != nil {
+						// Decoded like the call's own params, which cache invalidation matches on
+						var params interface{}
+						json.Unmarshal(result.Params, &params)
+						cacheParams = params
+					}
+				}
+			}
+		}
+	}
+	// Follow MCP sessions, refusing calls outside of one where the route requires it
+	var mcp *mcpCall
+	if rejected == nil {
+		mcp, rejected = g.checkMCPSession(r, route, client, method, body)
+	}
+	if rejected == nil {
+		quotaReason, err := g.checkQuota(client, startTime)
+		if err != nil {
+			log.Printf("Failed to check quota: %v", err)
+		}
+		if quotaReason != "" {
+			rejected = &rejection{quotaExceededCode, "Quota exceeded", quotaReason, http.StatusTooManyRequests}
+		}
+	}
+
+	if rejected != nil {
+		audit.Set(metaPolicy, "deny")
+		audit.Set(metaRejection, rejected.reason)
+	} else {
+		audit.Set(metaPolicy, "allow")
+	}
+
+	redaction := types.RedactionNone
+	if client != nil {
+		redaction = client.Redaction
+	}
+	auditLevel := route.AuditLevelFor(method)
+	burst := g.burstFor(r, route, method, client)
+	if burst != "" {
+		auditLevel = types.AuditLevelFullBody
+		audit.Set(metaBurst, burst)
 	}
 
 	// Capture headers
-	headers := make(map[string]string)
-	for key, values := range r.Header {
-		if len(values) > 0 {
-			headers[key] = values[0] // Take first value for simplicity
+	credentialHeader, _ := presentedKey(r)
+	var headersJSON []byte
+	if auditLevel != types.AuditLevelMetadata {
+		headers := make(map[string]string, len(r.Header))
+		for key, values := range r.Header {
+			if len(values) > 0 {
+				headers[key] = values[0] // Take first value for simplicity
+			}
+		}
+		redactHeaders(headers, redaction, credentialHeader)
+		if route.UpstreamAuth != nil && override == "" {
+			// Record that a credential was injected without storing it
+			headers[route.UpstreamAuth.HeaderName()] = redactedValue
+		}
+		headersJSON, _ = json.Marshal(headers)
+	}
+	indexedHeaders := g.indexedHeaderValues(r, redaction, credentialHeader)
+
+	// Redact once for both the body hash and the stored body
+	auditedBody := redactPayload(body, redaction, "params")
+	bodyHash := types.BodyHash(auditedBody)
+	// Tags are stored in the clear, so they come from the redacted body
+	tags := mergeTags(g.extractTags(auditedBody, method), callerTags)
+	if mcp != nil && mcp.tracked {
+		// The session the gateway tracked wins over any tag extracted from the call
+		tags = mergeTags(map[string]string{types.MCPSessionTag: mcp.sessionID}, tags)
+	}
+	if shape := rpcShapeTags(body); shape != nil {
+		tags = mergeTags(tags, shape)
+	}
+	extensions, extensionTags := g.requestExtensions(body, redaction)
+	tags = mergeTags(tags, extensionTags)
+	if g.pii != nil {
+		var kinds string
+		if auditedBody, kinds = g.pii.scan(auditedBody); kinds != "" {
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			tags[types.PIIRequestTag] = kinds
 		}
 	}
-	headersJSON, _ := json.Marshal(headers)
 
 	// Store the request immediately - this ensures we capture everything even if processing fails
+	clientIP := getClientIP(r)
 	auditRequest := &types.AuditRequest{
-		Timestamp: startTime,
-		Method:    method,
-		RequestID: requestID,
-		IPAddress: getClientIP(r),
-		UserAgent: r.UserAgent(),
-		Request:   json.RawMessage(body),
-		Headers:   json.RawMessage(headersJSON),
+		Timestamp:   startTime,
+		Method:      method,
+		RequestID:   requestID,
+		IPAddress:   clientIP,
+		UserAgent:   r.UserAgent(),
+		Headers:     json.RawMessage(headersJSON),
+		HTTPMethod:  httpMethod,
+		UpstreamURL: upstreamURL,
+		Tags:        tags,
+		ContentType: contentType,
+
+		UpstreamMethod: upstreamMethod,
+		AuditLevel:     auditLevel,
+		BodyHash:       bodyHash,
+		Deployment:     g.deployment,
+		RequestBytes:   int64(len(body)),
+		IndexedHeaders: indexedHeaders,
+	}
+	if auditLevel == types.AuditLevelFullBody {
+		auditRequest.Request, auditRequest.BodyEncoding = types.EncodeBody(auditedBody, contentType)
+	}
+	if auditLevel != types.AuditLevelMetadata {
+		auditRequest.Extensions = extensions
+	}
+	if client != nil {
+		auditRequest.APIKey = client.Name
+		auditRequest.Tenant = client.Tenant
+	}
+	auditRequest.Fingerprint = types.Fingerprint(clientIP, r.UserAgent(), auditRequest.APIKey, r.Header)
+	auditRequest.ParentRequestID = g.parentRequestID(r, body)
+
+	// Log the request immediately; strict routes refuse calls whose request could not be committed
+	if err := g.recordRequest(auditRequest, durability); err != nil && rejected == nil {
+		log.Printf("Refusing %s on strict route %s: %v", requestID, route.Name, err)
+		errorMsg := fmt.Sprintf("audit request could not be committed: %v", err)
+		rpcErr := failureError(route, config.ErrorInternal, auditUnavailableCode, "Audit unavailable", errorMsg)
+		g.writeRPCError(w, jsonRPCReq.ID, rpcErr, errorMsg, requestID, startTime, http.StatusServiceUnavailable, "")
+		return
 	}
 
-	// Log the request immediately
-	if err := g.db.InsertAuditRequest(auditRequest); err != nil {
-		log.Printf("Failed to insert audit request: %v", err)
-		// Continue processing even if audit logging fails
+	// Reject denied methods and calls over the rate limit or quota
+	if rejected != nil {
+		g.handleRPCError(w, jsonRPCReq.ID, rejected.code, rejected.message, rejected.reason, requestID, startTime, rejected.statusCode)
+		return
 	}
 
-	// Also log to Tinybird if configured
-	if g.tinybirdDB != nil {
-		if err := g.tinybirdDB.InsertAuditRequest(auditRequest); err != nil {
-			log.Printf("Failed to insert audit request to Tinybird: %v", err)
+	// Forward the request to the target service
+	if upstreamErr != nil {
+		rpcErr := failureError(route, config.ErrorUnavailable, -32603, "Internal error", upstreamErr.Error())
+		g.writeRPCError(w, jsonRPCReq.ID, rpcErr, upstreamErr.Error(), requestID, startTime, http.StatusServiceUnavailable, "")
+		return
+	}
+
+	call := &proxyCall{
+		requestID:   requestID,
+		method:      method,
+		id:          jsonRPCReq.ID,
+		startTime:   startTime,
+		route:       route,
+		upstreamURL: upstreamURL,
+		redaction:   redaction,
+		auditLevel:  auditLevel,
+		headers:     route.ResponseHeaders,
+		auth:        route.UpstreamAuth,
+		timeout:     g.callTimeout(route.TimeoutFor(method)),
+		slow:        route.SlowThresholdFor(method),
+		tcp:         g.tcpPools[route.Target],
+		timer:       &upstreamTimer{now: g.now},
+		mcp:         mcp,
+	}
+	if override != "" {
+		// The route's credential and TCP pool belong to its own target
+		call.auth, call.tcp = nil, nil
+	}
+	if rule != nil {
+		// Rule targets are HTTP
+		call.tcp = nil
+	}
+	if secondaryURL != "" {
+		call.secondary = &upstream{url: secondaryURL, tcp: g.tcpPools[route.Secondary]}
+	}
+	if call.headers == nil {
+		call.headers = g.responseHeaders
+	}
+	if transform, ok := route.ResponseTransforms[method]; ok {
+		call.transform = &transform
+	}
+	if g.wantsDebug(r, client) || burst != "" {
+		call.debug = &debugCapture{redaction: redaction}
+		call.debug.credentialHeader, _ = presentedKey(r)
+		if call.auth != nil {
+			call.debug.authHeader = http.CanonicalHeaderKey(call.auth.HeaderName())
 		}
 	}
 
-	// Forward the request to the target service
-	if g.targetURL == "" {
-		g.handleError(w, "No target URL configured", requestID, startTime, http.StatusServiceUnavailable)
+	// Answer retries of calls sent with an Idempotency-Key from their first answer
+	idempotent, handled := g.beginIdempotent(w, r, call, client, body)
+	if handled {
 		return
 	}
+	if idempotent != nil {
+		w = idempotent
+		defer g.finishIdempotent(idempotent)
+	}
+
+	// Answer single calls of cached methods from the cache
+	if ttl := route.CacheTTLFor(cacheMethod); ttl > 0 && override == "" && jsonRPCReq.Method != "" && jsonRPCReq.ID != nil {
+		tenant := ""
+		if client != nil {
+			tenant = client.Tenant
+		}
+		// Keyed by the call as forwarded, which a script may have rewritten per client
+		if key, err := cacheKey(upstreamURL, tenant, cacheMethod, cacheParams); err != nil {
+			log.Printf("Not caching %s: %v", requestID, err)
+		} else if entry := g.cache.get(key, startTime); entry != nil {
+			audit.Set(metaCache, types.CacheHit)
+			g.serveCached(w, call, entry)
+			return
+		} else {
+			call.cacheKey, call.cacheTTL = key, ttl
+			call.cached = &cacheEntry{method: cacheMethod, tenant: tenant, params: cacheParams}
+		}
+	}
+
+	// Refuse calls that already took longer than the route allows, e.g. behind a slow script
+	maxAge := route.MaxAgeDuration()
+	var ageBudget time.Duration
+	if maxAge > 0 {
+		if ageBudget = maxAge - g.since(startTime); ageBudget <= 0 {
+			g.handleAgedOut(w, call, maxAge)
+			return
+		}
+	}
+
+	// Wait for a free slot when the target has a concurrency limit
+	if limiter := g.targetLimiters[limitedTarget]; limiter != nil {
+		dequeue := g.concurrency.queue(method)
+		wait, err := limiter.acquire(r.Context(), ageBudget, g.now)
+		dequeue()
+		call.queueTime = wait
+		if err != nil && r.Context().Err() != nil {
+			g.handleClientCancel(w, call, err)
+			return
+		}
+		if errors.Is(err, errAgedOut) {
+			g.handleAgedOut(w, call, maxAge)
+			return
+		}
+		if err != nil {
+			w.Header().Set("Retry-After", "1")
+			errorMsg := fmt.Sprintf("%v for route %s", err, route.Name)
+			rpcErr := failureError(route, config.ErrorBusy, upstreamBusyCode, "Upstream busy", errorMsg)
+			g.writeRPCError(w, jsonRPCReq.ID, rpcErr, errorMsg, requestID, startTime, http.StatusServiceUnavailable, "")
+			return
+		}
+		defer limiter.release()
+	}
 
-	g.forwardRequest(w, r, body, requestID, startTime)
+	defer g.concurrency.start(method, g.now())()
+	g.forwardRequest(w, r, call, forwardBody)
+}
+
+// send forwards the request body to target over TCP or HTTP
+func (g *Gateway) send(ctx context.Context, r *http.Request, call *proxyCall, target upstream, requestBody []byte) (*http.Response, error) {
+	call.timer.reset()
+	if target.tcp != nil {
+		return target.tcp.do(ctx, requestBody)
+	}
+	return g.forwardHTTP(ctx, r, call, target.url, requestBody)
 }
 
-func (g *Gateway) forwardRequest(w http.ResponseWriter, r *http.Request, requestBody []byte, requestID string, startTime time.Time) {
-	// Create a new request to forward
-	req, err := http.NewRequest("POST", g.targetURL, bytes.NewReader(requestBody))
+// shouldFailover reports whether a call may be retried on the secondary target:
+// the primary could not be reached or answered with a server error. Timeouts
+// are not retried since they already used up the call's deadline.
+func shouldFailover(resp *http.Response, err error) bool {
 	if err != nil {
-		g.handleError(w, "Failed to create forward request", requestID, startTime, http.StatusInternalServerError)
-		return
+		return !isTimeout(err)
 	}
+	return resp.StatusCode >= 500
+}
 
-	// Copy all original headers
-	for key, values := range r.Header {
-		for _, value := range values {
-			req.Header.Add(key, value)
-		}
+// forwardHTTP sends the request to an HTTP target, keeping the client's HTTP method
+func (g *Gateway) forwardHTTP(ctx context.Context, r *http.Request, call *proxyCall, targetURL string, requestBody []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(call.timer.trace(ctx), r.Method, targetURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create forward request: %w", err)
+	}
+	if isChunked(r) {
+		// Keep the client's framing, so targets reading bodies as they arrive
+		// get the chunks instead of waiting for a declared length
+		req.ContentLength = -1
+	}
+
+	// Copy the original headers, except hop-by-hop ones
+	copyRequestHeaders(req.Header, r.Header)
+	req.Header.Del(targetHeader)
+	req.Header.Del(tagsHeader)
+	// The caller's gateway credential authorized the call, it is not for the upstream
+	for _, name := range gatewayCredentialHeaders {
+		req.Header.Del(name)
+	}
+	if call.auth != nil {
+		req.Header.Set(call.auth.HeaderName(), call.auth.HeaderValue())
 	}
 
 	// Add gateway-specific headers
 	req.Header.Set("X-Forwarded-For", getClientIP(r))
-	req.Header.Set("X-Request-ID", requestID)
+	req.Header.Set("X-Request-ID", call.requestID)
 	req.Header.Set("X-Gateway", "golf-audit-gateway")
+	if call.debug != nil {
+		call.debug.requestHeaders = req.Header.Clone()
+	}
+
+	return g.httpClient.Do(req)
+}
+
+// proxyCall carries the state of one proxied call into forwardRequest
+type proxyCall struct {
+	requestID   string
+	method      string
+	id          interface{} // JSON-RPC id of the call, echoed in gateway errors
+	startTime   time.Time
+	route       *config.Route // Error codes and strict-spec mode of the matched route
+	upstreamURL string
+	redaction   string
+	auditLevel  string
+	headers     *config.HeaderFilter // Upstream response headers passed on to the client
+	auth        *config.UpstreamAuth // Credential injected into the upstream request
+	queueTime   time.Duration        // Time spent waiting for a free upstream slot
+	timeout     time.Duration        // Deadline of the upstream call
+	slow        time.Duration        // Calls taking longer are tagged slow, 0 disables
+	tcp         *tcpPool             // Set for raw TCP targets instead of HTTP
+	secondary   *upstream            // Retried when the target fails, nil without failover
+	cacheKey    string               // Set when a successful answer should be cached
+	cacheTTL    time.Duration
+	cached      *cacheEntry // Entry stored under cacheKey, without its result yet
+
+	debug *debugCapture  // Set when the call is captured in detail
+	timer *upstreamTimer // Phases of the HTTP exchange with the target
+
+	transform *config.ResponseTransform // Rewrites the result for clients, nil when the method has none
+
+	mcp *mcpCall // Session state on routes tracking MCP sessions, nil elsewhere
+}
+
+// upstream is a target a call can be sent to
+type upstream struct {
+	url string
+	tcp *tcpPool // Set for raw TCP targets instead of HTTP
+}
 
-	// Forward the request
-	resp, err := g.httpClient.Do(req)
+func (g *Gateway) forwardRequest(w http.ResponseWriter, r *http.Request, call *proxyCall, requestBody []byte) {
+	requestID, startTime := call.requestID, call.startTime
+
+	ctx := r.Context()
+	if call.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, call.timeout)
+		defer cancel()
+	}
+
+	// Forward the request, failing over to the secondary target if the primary is down
+	AuditContextFrom(ctx).Set(metaUpstream, "sent")
+	upstreamStart := g.now()
+	var resp *http.Response
+	var err error
+	servedBy := ""
+	if items := batchItems(call.route, requestBody); items != nil {
+		// Batches the target would answer serially are sent item by item, each failing over on its own
+		AuditContextFrom(ctx).Set(metaFanOut, strconv.Itoa(len(items)))
+		resp, err = g.fanOut(ctx, r, call, items)
+	} else {
+		resp, err = g.send(ctx, r, call, upstream{url: call.upstreamURL, tcp: call.tcp}, requestBody)
+		if call.secondary != nil && ctx.Err() == nil && shouldFailover(resp, err) {
+			reason := err
+			if resp != nil {
+				reason = fmt.Errorf("status %d", resp.StatusCode)
+				resp.Body.Close()
+			}
+			log.Printf("Target %s failed for %s (%v), retrying on %s", call.upstreamURL, requestID, reason, call.secondary.url)
+			resp, err = g.send(ctx, r, call, *call.secondary, requestBody)
+			servedBy = call.secondary.url
+			AuditContextFrom(ctx).Set(metaFailover, servedBy)
+		}
+	}
 	if err != nil {
-		g.handleError(w, fmt.Sprintf("Failed to forward request: %v", err), requestID, startTime, http.StatusBadGateway)
+		g.handleUpstreamFailure(w, r, call, err)
 		return
 	}
 	defer resp.Body.Close()
 
-	// Read the response
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		g.handleError(w, "Failed to read response", requestID, startTime, http.StatusInternalServerError)
+	// MCP servers answer with event streams, passed on as events arrive
+	if call.mcp != nil && isEventStream(resp) {
+		g.streamEvents(ctx, w, r, call, resp, upstreamStart, servedBy)
 		return
 	}
 
-	// Store the response
+	// Read the response; bodies over the capture limit go to the client as they arrive
+	var responseBody []byte
+	if limit := g.responseCaptureLimit(call.route); limit > 0 && g.streamsLargeResponses(call) {
+		head, more, err := readCappedBody(resp.Body, resp.ContentLength, limit)
+		if err != nil {
+			g.handleUpstreamFailure(w, r, call, fmt.Errorf("failed to read response: %w", err))
+			return
+		}
+		if more {
+			g.streamLargeResponse(w, call, resp, head, upstreamStart, servedBy)
+			return
+		}
+		responseBody = head
+	} else if responseBody, err = readBody(resp.Body, resp.ContentLength); err != nil {
+		g.handleUpstreamFailure(w, r, call, fmt.Errorf("failed to read response: %w", err))
+		return
+	}
+	timing := call.timer.timing(g.now())
+
+	// Answer upstream HTTP errors without a JSON-RPC body with a JSON-RPC error where configured
+	if g.wrapUpstreamError(w, call, resp.StatusCode, responseBody) {
+		return
+	}
+
+	// Store the response, keeping binary bodies recoverable
 	auditResponse := &types.AuditResponse{
-		RequestID:   requestID,
-		Timestamp:   time.Now(),
-		Response:    json.RawMessage(responseBody),
-		StatusCode:  resp.StatusCode,
-		ProcessTime: time.Since(startTime).Milliseconds(),
+		RequestID:    requestID,
+		Timestamp:    g.now(),
+		StatusCode:   resp.StatusCode,
+		ProcessTime:  g.since(startTime).Milliseconds(),
+		QueueTime:    call.queueTime.Milliseconds(),
+		UpstreamTime: g.since(upstreamStart).Milliseconds(),
+		ContentType:  resp.Header.Get("Content-Type"),
+		ServedBy:     servedBy,
+		Timing:       timing,
+	}
+	auditResponse.Slow = call.slow > 0 && g.since(startTime) > call.slow
+	auditResponse.Debug = call.debug.record(resp, timing)
+	g.auditResponseBody(auditResponse, call, responseBody)
+	target := call.upstreamURL
+	if servedBy != "" {
+		target = servedBy
 	}
+	g.debugf("Upstream %s answered %s (%s) with %d in %dms", target, requestID, call.method, resp.StatusCode, auditResponse.UpstreamTime)
 
-	if err := g.db.InsertAuditResponse(auditResponse); err != nil {
-		log.Printf("Failed to insert audit response: %v", err)
+	// Validate JSON-RPC upstream bodies and classify errors
+	if types.IsJSONContentType(r.Header.Get("Content-Type")) {
+		g.classifyUpstreamResponse(auditResponse, responseBody)
+	}
+	if resp.StatusCode == http.StatusOK {
+		g.checkResponseSchema(call, auditResponse.Timestamp, responseBody)
 	}
 
-	// Also log to Tinybird if configured
-	if g.tinybirdDB != nil {
-		if err := g.tinybirdDB.InsertAuditResponse(auditResponse); err != nil {
-			log.Printf("Failed to insert audit response to Tinybird: %v", err)
+	// Rewrite the result for older clients; the audit keeps both bodies
+	if call.transform != nil {
+		if transformed := transformResponse(call.transform, responseBody); transformed != nil {
+			responseBody = transformed
+			auditResponse.ResponseBytes = int64(len(transformed))
+			AuditContextFrom(ctx).Set(metaTransform, "applied")
+			if call.auditLevel == types.AuditLevelFullBody {
+				auditResponse.TransformedResponse = redactPayload(transformed, call.redaction, "result")
+			}
 		}
 	}
 
-	// Forward response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
+	// Cache successful answers of cached methods
+	if call.cacheKey != "" {
+		auditResponse.Cache = types.CacheMiss
+		AuditContextFrom(ctx).Set(metaCache, types.CacheMiss)
+		if result := cacheableResult(responseBody); resp.StatusCode == http.StatusOK && result != nil {
+			call.cached.result = result
+			call.cached.storedAt = auditResponse.Timestamp
+			call.cached.expires = auditResponse.Timestamp.Add(call.cacheTTL)
+			g.cache.put(call.cacheKey, call.cached)
 		}
 	}
 
+	g.observeMCP(ctx, r, call, resp, responseBody)
+	g.recordResponse(auditResponse)
+
+	// Forward end-to-end response headers allowed by the route
+	copyResponseHeaders(w, resp, call.headers, len(responseBody))
+	if call.cacheKey != "" {
+		w.Header().Set("X-Cache", types.CacheMiss)
+	}
+	g.stampResponse(w, requestID, responseBody)
+
 	// Send the response
 	w.WriteHeader(resp.StatusCode)
 	w.Write(responseBody)
@@ -173,6 +894,25 @@ func (g *Gateway) forwardRequest(w http.ResponseWriter, r *http.Request, request
 	// Response logging is already done above
 }
 
+// auditResponseBody redacts, hashes, scans and stores the response body as the call's audit level allows
+func (g *Gateway) auditResponseBody(auditResponse *types.AuditResponse, call *proxyCall, responseBody []byte) {
+	auditResponse.ResponseBytes = int64(len(responseBody))
+	auditedResponse := redactPayload(responseBody, call.redaction, "result")
+	auditResponse.ResponseHash = types.ResponseHash(auditedResponse)
+	if limit := g.responseCaptureLimit(call.route); limit > 0 && int64(len(responseBody)) > limit {
+		sum := sha256.New()
+		sum.Write(responseBody)
+		g.auditCappedResponse(auditResponse, call, responseBody[:limit], sum, int64(len(responseBody)))
+		return
+	}
+	if g.pii != nil {
+		auditedResponse, auditResponse.PII = g.pii.scan(auditedResponse)
+	}
+	if call.auditLevel == types.AuditLevelFullBody {
+		auditResponse.Response, auditResponse.BodyEncoding = types.EncodeBody(auditedResponse, auditResponse.ContentType)
+	}
+}
+
 func (g *Gateway) sendResponse(w http.ResponseWriter, response types.JSONRPCResponse, requestID string, startTime time.Time, statusCode int) {
 	responseBody, err := json.Marshal(response)
 	if err != nil {
@@ -183,37 +923,39 @@ func (g *Gateway) sendResponse(w http.ResponseWriter, response types.JSONRPCResp
 	// Store the response
 	auditResponse := &types.AuditResponse{
 		RequestID:   requestID,
-		Timestamp:   time.Now(),
+		Timestamp:   g.now(),
 		Response:    json.RawMessage(responseBody),
 		StatusCode:  statusCode,
-		ProcessTime: time.Since(startTime).Milliseconds(),
-	}
+		ProcessTime: g.since(startTime).Milliseconds(),
 
-	if err := g.db.InsertAuditResponse(auditResponse); err != nil {
-		log.Printf("Failed to insert audit response: %v", err)
+		ResponseBytes: int64(len(responseBody)),
 	}
 
-	// Also log to Tinybird if configured
-	if g.tinybirdDB != nil {
-		if err := g.tinybirdDB.InsertAuditResponse(auditResponse); err != nil {
-			log.Printf("Failed to insert audit response to Tinybird: %v", err)
-		}
-	}
+	g.recordResponse(auditResponse)
 
 	w.Header().Set("Content-Type", "application/json")
+	g.stampResponse(w, requestID, responseBody)
 	w.WriteHeader(statusCode)
 	w.Write(responseBody)
 }
 
 func (g *Gateway) handleError(w http.ResponseWriter, errorMsg string, requestID string, startTime time.Time, statusCode int) {
+	g.handleRPCError(w, nil, -32603, "Internal error", errorMsg, requestID, startTime, statusCode)
+}
+
+// handleRPCError sends a JSON-RPC error with the given code and records it as the audit response
+func (g *Gateway) handleRPCError(w http.ResponseWriter, id interface{}, code int, message string, errorMsg string, requestID string, startTime time.Time, statusCode int) {
+	rpcErr := &types.JSONRPCError{Code: code, Message: message, Data: errorMsg}
+	g.writeRPCError(w, id, rpcErr, errorMsg, requestID, startTime, statusCode, "")
+}
+
+// writeRPCError sends rpcErr and records it as the audit response, with the
+// failure kind of the upstream call if it failed
+func (g *Gateway) writeRPCError(w http.ResponseWriter, id interface{}, rpcErr *types.JSONRPCError, errorMsg string, requestID string, startTime time.Time, statusCode int, failureKind string) {
 	errorResp := types.JSONRPCResponse{
-		ID:      nil,
+		ID:      id,
 		JSONRPC: "2.0",
-		Error: &types.JSONRPCError{
-			Code:    -32603,
-			Message: "Internal error",
-			Data:    errorMsg,
-		},
+		Error:   rpcErr,
 	}
 
 	responseBody, _ := json.Marshal(errorResp)
@@ -221,33 +963,55 @@ func (g *Gateway) handleError(w http.ResponseWriter, errorMsg string, requestID
 	// Store the error response
 	auditResponse := &types.AuditResponse{
 		RequestID:   requestID,
-		Timestamp:   time.Now(),
+		Timestamp:   g.now(),
 		Response:    json.RawMessage(responseBody),
 		StatusCode:  statusCode,
-		ProcessTime: time.Since(startTime).Milliseconds(),
+		ProcessTime: g.since(startTime).Milliseconds(),
 		Error:       errorMsg,
-	}
+		FailureKind: failureKind,
 
-	if err := g.db.InsertAuditResponse(auditResponse); err != nil {
-		log.Printf("Failed to insert audit response: %v", err)
+		ResponseBytes: int64(len(responseBody)),
 	}
 
-	// Also log to Tinybird if configured
-	if g.tinybirdDB != nil {
-		if err := g.tinybirdDB.InsertAuditResponse(auditResponse); err != nil {
-			log.Printf("Failed to insert audit response to Tinybird: %v", err)
-		}
-	}
+	g.recordResponse(auditResponse)
 
 	w.Header().Set("Content-Type", "application/json")
+	g.stampResponse(w, requestID, responseBody)
 	w.WriteHeader(statusCode)
 	w.Write(responseBody)
 }
 
-// logRequest is no longer needed as we store requests and responses separately
+// recordRequest publishes an audit request to the storage and other subscribers
+func (g *Gateway) recordRequest(auditRequest *types.AuditRequest, durability string) error {
+	var err error
+	if durability == types.DurabilityStrict {
+		if err = g.writeDurable(auditRequest); err != nil {
+			// Keep the row of the refused call through the standard write path
+			durability = types.DurabilityStandard
+		}
+	}
+	g.bus.Publish(events.Event{Request: auditRequest, Durability: durability})
+	return err
+}
+
+// recordResponse publishes an audit response to the storage and other
+// subscribers, along with the metadata collected for the call
+func (g *Gateway) recordResponse(auditResponse *types.AuditResponse) {
+	if auditResponse.Metadata == nil {
+		auditResponse.Metadata = g.auditMetadata(auditResponse.RequestID)
+	}
+	g.latency.observe(auditResponse.Metadata[metaRoute], auditResponse)
+	g.bus.Publish(events.Event{Response: auditResponse, Durability: auditResponse.Metadata[metaDurability]})
+}
 
 // GetAuditRequests returns audit requests with pagination
 func (g *Gateway) GetAuditRequests(w http.ResponseWriter, r *http.Request) {
+	format, ok := negotiateFormat(r)
+	if !ok {
+		notAcceptable(w)
+		return
+	}
+
 	limit := 50
 	offset := 0
 
@@ -263,17 +1027,22 @@ func (g *Gateway) GetAuditRequests(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	requests, err := g.db.GetAuditRequests(limit, offset)
+	requests, err := g.store.GetAuditRequests(limit, offset)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to retrieve audit requests: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]interface{}{
-		"requests": requests,
-		"limit":    limit,
-		"offset":   offset,
-		"count":    len(requests),
+	if format != formatJSON {
+		writeRows(w, format, requests)
+		return
+	}
+
+	response := types.AuditRequestsResponse{
+		Requests: requests,
+		Limit:    limit,
+		Offset:   offset,
+		Count:    len(requests),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -282,6 +1051,12 @@ func (g *Gateway) GetAuditRequests(w http.ResponseWriter, r *http.Request) {
 
 // GetAuditResponses returns audit responses with pagination
 func (g *Gateway) GetAuditResponses(w http.ResponseWriter, r *http.Request) {
+	format, ok := negotiateFormat(r)
+	if !ok {
+		notAcceptable(w)
+		return
+	}
+
 	limit := 50
 	offset := 0
 
@@ -297,17 +1072,22 @@ func (g *Gateway) GetAuditResponses(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	responses, err := g.db.GetAuditResponses(limit, offset)
+	responses, err := g.store.GetAuditResponses(limit, offset)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to retrieve audit responses: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]interface{}{
-		"responses": responses,
-		"limit":     limit,
-		"offset":    offset,
-		"count":     len(responses),
+	if format != formatJSON {
+		writeRows(w, format, responses)
+		return
+	}
+
+	response := types.AuditResponsesResponse{
+		Responses: responses,
+		Limit:     limit,
+		Offset:    offset,
+		Count:     len(responses),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -331,17 +1111,17 @@ func (g *Gateway) GetOrphanedRequests(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	requests, err := g.db.GetOrphanedRequests(limit, offset)
+	requests, err := g.store.GetOrphanedRequests(limit, offset)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to retrieve orphaned requests: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]interface{}{
-		"orphaned_requests": requests,
-		"limit":             limit,
-		"offset":            offset,
-		"count":             len(requests),
+	response := types.OrphanedRequestsResponse{
+		OrphanedRequests: requests,
+		Limit:            limit,
+		Offset:           offset,
+		Count:            len(requests),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -350,6 +1130,12 @@ func (g *Gateway) GetOrphanedRequests(w http.ResponseWriter, r *http.Request) {
 
 // GetAuditLogs returns audit logs with pagination (backward compatibility - combined view)
 func (g *Gateway) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	format, ok := negotiateFormat(r)
+	if !ok {
+		notAcceptable(w)
+		return
+	}
+
 	limit := 50
 	offset := 0
 
@@ -366,39 +1152,91 @@ func (g *Gateway) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	method := r.URL.Query().Get("method")
+	bodyHash := r.URL.Query().Get("body_hash")
+
+	// Tag filters are passed as tag.<name>=<value>, indexed extension members as ext.<path>=<value>
+	tags := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		if strings.HasPrefix(key, "tag.") && len(values) > 0 {
+			tags[strings.TrimPrefix(key, "tag.")] = values[0]
+		}
+		if strings.HasPrefix(key, types.ExtensionTagPrefix) && len(values) > 0 {
+			tags[key] = values[0]
+		}
+	}
+
+	// Indexed header filters are passed as header.<name>=<value>
+	headers, err := g.headerFilters(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	var logs []types.AuditLog
-	var err error
 
-	if method != "" {
-		logs, err = g.db.GetAuditLogsByMethod(method, limit, offset)
+	if len(tags) > 0 || len(headers) > 0 || bodyHash != "" {
+		if g.db == nil {
+			http.Error(w, "Tag, header and body_hash filters require the SQLite audit database", http.StatusNotImplemented)
+			return
+		}
+		logs, err = g.db.SearchAuditLogs(types.AuditLogFilter{Method: method, BodyHash: bodyHash, Tags: tags, Headers: headers}, limit, offset)
+	} else if method != "" {
+		logs, err = g.store.GetAuditLogsByMethod(method, limit, offset)
 	} else {
-		logs, err = g.db.GetAuditLogs(limit, offset)
+		logs, err = g.store.GetAuditLogs(limit, offset)
 	}
 
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to retrieve audit logs: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if err := previewLogs(r, logs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	response := map[string]interface{}{
-		"logs":   logs,
-		"limit":  limit,
-		"offset": offset,
-		"count":  len(logs),
+	if format != formatJSON {
+		writeRows(w, format, logs)
+		return
+	}
+
+	response := types.AuditLogsResponse{
+		Logs:   logs,
+		Limit:  limit,
+		Offset: offset,
+		Count:  len(logs),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetAuditLog returns the combined audit log of one request
+func (g *Gateway) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	requestID := mux.Vars(r)["request_id"]
+	auditLog, err := g.db.GetAuditLog(requestID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if auditLog == nil {
+		http.Error(w, fmt.Sprintf("Audit log %s not found", requestID), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auditLog)
+}
+
 // GetStats returns statistics about the audit logs
 func (g *Gateway) GetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := g.db.GetStats()
+	stats, err := g.store.GetStats()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to retrieve stats: %v", err), http.StatusInternalServerError)
 		return
 	}
+	g.suppressStats(stats)
+	stats.Concurrency = g.concurrency.snapshot()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
@@ -406,36 +1244,175 @@ func (g *Gateway) GetStats(w http.ResponseWriter, r *http.Request) {
 
 // HealthCheck endpoint
 func (g *Gateway) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	health := map[string]interface{}{
-		"status":    "healthy",
-		"timestamp": time.Now(),
-		"version":   "1.0.0",
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.Health())
+}
+
+// Health reports the gateway status served at /health
+func (g *Gateway) Health() types.HealthResponse {
+	health := types.HealthResponse{
+		Status:    "healthy",
+		Timestamp: g.now(),
+		Version:   "1.0.0",
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(health)
+	// Report degraded storage while audit events are spooled
+	if g.spool != nil {
+		storage := g.spool.Status()
+		health.Storage = &storage
+		if storage.Degraded {
+			health.Status = "degraded"
+		}
+	}
+
+	// Report the last database maintenance run
+	if g.maintenance != nil {
+		status := g.maintenance.Status()
+		health.Maintenance = &status
+		if status.IntegrityOK != nil && !*status.IntegrityOK {
+			health.Status = "degraded"
+		}
+	}
+
+	// Report load of targets with a concurrency limit
+	if len(g.targetLimiters) > 0 {
+		health.Targets = g.targetStatuses()
+	}
+
+	// Report the audit pipeline itself, degraded when a queue is about to overflow
+	pipeline := g.PipelineStatus()
+	health.Pipeline = &pipeline
+	if queuesDegraded(pipeline) {
+		health.Status = "degraded"
+	}
+	health.Durability = g.durabilityStatus()
+	health.Startup, health.Ready = g.startupSteps()
+	return health
 }
 
 // SetupRoutes configures the HTTP routes
 func (g *Gateway) SetupRoutes() *mux.Router {
-	r := mux.NewRouter()
+	return g.Router(config.ServeAll)
+}
 
-	// JSON-RPC endpoint
-	r.HandleFunc("/rpc", g.ProxyJSONRPC).Methods("POST", "OPTIONS")
-	r.HandleFunc("/mcp", g.ProxyJSONRPC).Methods("POST", "OPTIONS")
+// Router returns the routes of a listener serving proxy, management or all endpoints.
+// /health and its liveness and readiness probes are served on every listener.
+func (g *Gateway) Router(serve string) *mux.Router {
+	r := mux.NewRouter()
 
-	// Management endpoints
-	r.HandleFunc("/audit/logs", g.GetAuditLogs).Methods("GET")            // Combined view (backward compatibility)
-	r.HandleFunc("/audit/requests", g.GetAuditRequests).Methods("GET")    // Requests only
-	r.HandleFunc("/audit/responses", g.GetAuditResponses).Methods("GET")  // Responses only
-	r.HandleFunc("/audit/orphaned", g.GetOrphanedRequests).Methods("GET") // Failed/orphaned requests
-	r.HandleFunc("/audit/stats", g.GetStats).Methods("GET")
+	if serve != config.ServeManagement {
+		g.addProxyRoutes(r)
+	}
 	r.HandleFunc("/health", g.HealthCheck).Methods("GET")
+	r.HandleFunc("/health/live", g.LiveCheck).Methods("GET")   // Liveness: the process serves requests
+	r.HandleFunc("/health/ready", g.ReadyCheck).Methods("GET") // Readiness: 503 until every startup step succeeded
+	r.HandleFunc("/status", g.GetStatus).Methods("GET")        // Public availability summary
+	if serve != config.ServeProxy {
+		g.addManagementRoutes(r)
+	}
+
+	return r
+}
+
+// requireSQLite rejects endpoints that only the SQLite store supports when running without it
+func (g *Gateway) requireSQLite(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if g.db == nil {
+			http.Error(w, "This endpoint requires the SQLite audit database", http.StatusNotImplemented)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// addProxyRoutes registers the JSON-RPC endpoints, including any path suffix forwarded to the target
+func (g *Gateway) addProxyRoutes(r *mux.Router) {
+	for _, route := range g.routes {
+		methods := append([]string{"OPTIONS"}, route.HTTPMethods...)
+		proxy := g.accessLogged(g.ProxyJSONRPC)
+		r.HandleFunc(route.Path, proxy).Methods(methods...)
+		r.PathPrefix(strings.TrimSuffix(route.Path, "/") + "/").HandlerFunc(proxy).Methods(methods...)
+	}
+}
+
+// addManagementRoutes registers the audit, admin and dashboard endpoints
+func (g *Gateway) addManagementRoutes(r *mux.Router) {
+	r.HandleFunc("/audit/logs", g.allowRead(types.RoleOperator, g.GetAuditLogs)).Methods("GET")                                       // Combined view (backward compatibility)
+	r.HandleFunc("/audit/logs/since", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetAuditLogsSince))).Methods("GET")           // Incremental pull by cursor
+	r.HandleFunc("/audit/logs/{request_id}", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetAuditLog))).Methods("GET")          // Single request/response pair
+	r.HandleFunc("/audit/logs/{request_id}", g.requireRole(types.RoleOperator, g.requireSQLite(g.AnnotateAuditLog))).Methods("PATCH") // Annotate or soft-delete
+	r.HandleFunc("/audit/requests", g.allowRead(types.RoleOperator, g.GetAuditRequests)).Methods("GET")                               // Requests only
+	r.HandleFunc("/audit/responses", g.allowRead(types.RoleOperator, g.GetAuditResponses)).Methods("GET")                             // Responses only
+	r.HandleFunc("/audit/orphaned", g.allowRead(types.RoleOperator, g.GetOrphanedRequests)).Methods("GET")                            // Failed/orphaned requests
+	r.HandleFunc("/audit/slow", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetSlowLogs))).Methods("GET")                       // Calls over their slow threshold
+	r.HandleFunc("/audit/pii", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetPIIReport))).Methods("GET")                       // Methods leaking likely PII
+	r.HandleFunc("/audit/schema-drift", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetSchemaDrift))).Methods("GET")            // Results differing from their schemas
+	r.HandleFunc("/audit/clients", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetSeenClients))).Methods("GET")                 // Distinct callers by fingerprint
+	r.HandleFunc("/audit/trace/{request_id}", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetTrace))).Methods("GET")            // Tree of nested calls
+	r.HandleFunc("/audit/mcp/sessions", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetMCPSessions))).Methods("GET")            // MCP sessions and their lifecycle
+	r.HandleFunc("/audit/mcp/sessions/{id}", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetMCPSession))).Methods("GET")        // One MCP session with its calls
+	r.HandleFunc("/audit/stats", g.allowRead(types.RoleViewer, g.GetStats)).Methods("GET")
+	r.HandleFunc("/audit/files", g.allowRead(types.RoleViewer, g.requireSQLite(g.ListDatabaseFiles))).Methods("GET")                // Files of a rotating database
+	r.HandleFunc("/audit/stats/heatmap", g.allowRead(types.RoleViewer, g.requireSQLite(g.GetLatencyHeatmap))).Methods("GET")        // Time x latency histogram
+	r.HandleFunc("/audit/stats/duplicates", g.allowRead(types.RoleViewer, g.requireSQLite(g.GetResponseDuplicates))).Methods("GET") // Identical responses per method
+	r.HandleFunc("/audit/stats/params", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetParamStats))).Methods("GET")           // Param cardinality and top values per method
+	r.HandleFunc("/audit/compare", g.allowRead(types.RoleViewer, g.requireSQLite(g.GetAuditCompare))).Methods("GET")                // Two time windows side by side
+	r.HandleFunc("/audit/usage", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetUsage))).Methods("GET")                       // Quota consumption per key/tenant
+	r.HandleFunc("/audit/slo", g.allowRead(types.RoleViewer, g.requireSQLite(g.GetSLO))).Methods("GET")                             // Latency objective compliance
+	r.HandleFunc("/audit/export", g.allowRead(types.RoleOperator, g.requireSQLite(g.ExportAuditLogs))).Methods("GET")               // NDJSON stream resumable by id
+	r.HandleFunc("/audit/export/manifest", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetExportManifest))).Methods("GET")    // Id-range chunks of a full export
+	r.HandleFunc("/audit/import", g.requireAdmin(g.requireSQLite(g.ImportAuditLogs))).Methods("POST")                               // Merge NDJSON audit records
+	r.HandleFunc("/metrics", g.allowRead(types.RoleViewer, g.GetMetrics)).Methods("GET")                                            // Audit pipeline health for Prometheus
+	r.HandleFunc("/openapi.json", g.OpenAPI).Methods("GET")                                                                         // OpenAPI 3 description of the management API
+	r.HandleFunc("/openrpc.json", g.OpenRPCDocument).Methods("GET")                                                                 // Target's OpenRPC document
+	r.HandleFunc("/openrpc/methods", g.allowRead(types.RoleViewer, g.GetMethodCatalog)).Methods("GET")                              // Documented methods with call counts
+
+	// Grafana SimpleJSON datasource, point the datasource URL at /grafana
+	r.HandleFunc("/grafana", g.GrafanaTest).Methods("GET")
+	r.HandleFunc("/grafana/", g.GrafanaTest).Methods("GET")
+	r.HandleFunc("/grafana/search", g.allowRead(types.RoleViewer, g.requireSQLite(g.GrafanaSearch))).Methods("POST")
+	r.HandleFunc("/grafana/query", g.allowRead(types.RoleViewer, g.requireSQLite(g.GrafanaQuery))).Methods("POST")
+	r.HandleFunc("/grafana/annotations", g.allowRead(types.RoleOperator, g.requireSQLite(g.GrafanaAnnotations))).Methods("POST")
+
+	// Monthly per-key invoices for internal chargeback
+	r.HandleFunc("/billing/invoices", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetInvoices))).Methods("GET")
+	r.HandleFunc("/billing/invoices/{api_key}", g.allowRead(types.RoleOperator, g.requireSQLite(g.GetInvoice))).Methods("GET")
+
+	// Tenant views, scoped by the caller's API key; admins see all tenants
+	r.HandleFunc("/tenant", serveTenantDashboard).Methods("GET")
+	r.HandleFunc("/tenant/logs", g.allowRead(types.RoleOperator, g.requireRawAccess(g.requireSQLite(g.GetTenantLogs)))).Methods("GET")
+	r.HandleFunc("/tenant/stats", g.requireSQLite(g.GetTenantStats)).Methods("GET")
+
+	// Admin endpoints, enabled with an admin token
+	r.HandleFunc("/admin/clients", g.requireAdmin(g.requireSQLite(g.ListClients))).Methods("GET")
+	r.HandleFunc("/admin/clients", g.requireAdmin(g.requireSQLite(g.CreateClient))).Methods("POST")
+	r.HandleFunc("/admin/clients/{name}", g.requireAdmin(g.requireSQLite(g.GetClient))).Methods("GET")
+	r.HandleFunc("/admin/clients/{name}", g.requireAdmin(g.requireSQLite(g.UpdateClient))).Methods("PUT")
+	r.HandleFunc("/admin/clients/{name}", g.requireAdmin(g.requireSQLite(g.DeleteClient))).Methods("DELETE")
+	r.HandleFunc("/admin/cache/invalidate", g.requireRole(types.RoleOperator, g.InvalidateCache)).Methods("POST")
+	r.HandleFunc("/admin/tinybird/deadletter", g.requireAdmin(g.requireSQLite(g.GetDeadLetters))).Methods("GET")
+	r.HandleFunc("/admin/debug", g.requireAdmin(g.GetDebugSettings)).Methods("GET")
+	r.HandleFunc("/admin/debug", g.requireAdmin(g.UpdateDebugSettings)).Methods("PUT")
+	r.HandleFunc("/admin/actions", g.requireAdmin(g.requireSQLite(g.GetAdminActions))).Methods("GET")
+	r.HandleFunc("/admin/capture", g.requireAdmin(g.GetBurstCaptures)).Methods("GET")
+	r.HandleFunc("/admin/capture/start", g.requireAdmin(g.StartBurstCapture)).Methods("POST")
+	r.HandleFunc("/admin/capture/stop", g.requireAdmin(g.StopBurstCapture)).Methods("POST")
+
+	// Sign-in through the identity provider, see SetAuthProvider
+	r.HandleFunc("/auth/login", g.Login).Methods("GET")
+	r.HandleFunc("/auth/callback", g.AuthCallback).Methods("GET")
+	r.HandleFunc("/auth/logout", g.Logout).Methods("POST")
+	r.HandleFunc("/auth/me", g.GetAuthSession).Methods("GET")
+
+	// Dashboards defined by operators
+	r.HandleFunc("/audit/dashboards", g.allowRead(types.RoleViewer, g.requireSQLite(g.ListDashboards))).Methods("GET")
+	r.HandleFunc("/audit/dashboards/{name}", g.allowRead(types.RoleViewer, g.requireSQLite(g.GetDashboard))).Methods("GET")
+	r.HandleFunc("/audit/dashboards/{name}", g.requireRole(types.RoleOperator, g.requireSQLite(g.SaveDashboard))).Methods("PUT")
+	r.HandleFunc("/audit/dashboards/{name}", g.requireRole(types.RoleOperator, g.requireSQLite(g.DeleteDashboard))).Methods("DELETE")
+	r.HandleFunc("/dashboards/{name}", serveCustomDashboard).Methods("GET")
 
 	// Serve static dashboard
 	r.PathPrefix("/").Handler(http.HandlerFunc(serveDashboard))
-
-	return r
 }
 
 // Utility functions
@@ -461,8 +1438,16 @@ func getClientIP(r *http.Request) string {
 	return ip
 }
 
-func generateRequestID() string {
-	return fmt.Sprintf("req_%d_%d", time.Now().UnixNano(), time.Now().Unix()%1000)
+// methodFromPath derives a method name such as acme.v1.UserService/GetUser from a request path
+func methodFromPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 {
+		if segments[0] != "" {
+			return segments[0]
+		}
+		return "unknown"
+	}
+	return strings.Join(segments[len(segments)-2:], "/")
 }
 
 // Simple dashboard
@@ -488,11 +1473,21 @@ func serveDashboard(w http.ResponseWriter, r *http.Request) {
         .stat-number { font-size: 2em; font-weight: bold; color: #007cba; }
         .button { display: inline-block; background: #007cba; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; margin: 5px; }
         .button:hover { background: #005a8b; }
+        .heatmap { border-collapse: collapse; font-size: 11px; }
+        .heatmap td, .heatmap th { width: 22px; height: 18px; text-align: center; padding: 0; }
+        .heatmap th { font-weight: normal; color: #666; white-space: nowrap; padding-right: 6px; }
+        .methods { border-collapse: collapse; width: 100%; font-size: 14px; }
+        .methods td, .methods th { text-align: left; padding: 6px 10px; border-bottom: 1px solid #eee; vertical-align: top; }
+        .methods .deprecated { text-decoration: line-through; color: #999; }
+        .try label { display: block; margin: 10px 0 4px; font-weight: bold; }
+        .try input, .try textarea { width: 100%; box-sizing: border-box; padding: 8px; font-family: monospace; }
+        .try button { background: #007cba; color: white; padding: 10px 20px; border: none; border-radius: 5px; cursor: pointer; margin-top: 10px; }
+        .try .row { display: grid; grid-template-columns: 1fr 2fr 1fr; gap: 10px; }
     </style>
 </head>
 <body>
     <div class="container">
-        <h1>🚀 JSON-RPC Gateway</h1>
+        <h1>🚀 JSON-RPC Gateway <small id="session" style="float: right; font-size: 14px; font-weight: normal;"></small></h1>
         
         <div class="stats">
             <div class="stat-card">
@@ -505,10 +1500,26 @@ func serveDashboard(w http.ResponseWriter, r *http.Request) {
             </div>
         </div>
 
+        <h2>🔥 Latency Heatmap (last hour)</h2>
+        <table class="heatmap" id="heatmap"></table>
+
+        <div id="candidates" style="display: none;">
+            <h2>♻️ Cache Candidates (last 24h)</h2>
+            <table class="methods" id="candidateMethods"></table>
+        </div>
+
+        <div id="catalog" style="display: none;">
+            <h2>📖 Methods <small id="catalogTitle"></small></h2>
+            <table class="methods" id="methods"></table>
+        </div>
+
         <div style="margin: 20px 0;">
             <a href="/audit/logs" class="button">📋 View Logs</a>
             <a href="/audit/stats" class="button">📊 Statistics</a>
+            <a href="/tenant" class="button">🏢 Tenant View</a>
             <a href="/health" class="button">❤️ Health Check</a>
+            <a href="/openapi.json" class="button">📘 OpenAPI</a>
+            <span id="dashboards"></span>
         </div>
 
         <h2>📡 API Endpoints</h2>
@@ -520,7 +1531,13 @@ func serveDashboard(w http.ResponseWriter, r *http.Request) {
 
         <div class="endpoint">
             <span class="method">GET</span> <strong>/audit/logs</strong><br>
-            Retrieve audit logs with pagination. Query params: limit, offset, method
+            Retrieve audit logs with pagination. Query params: limit, offset, method, tag.&lt;name&gt;, header.&lt;name&gt; (indexed_headers only), ext.&lt;path&gt; (extensions.index only), format.
+            Send Accept: text/csv or application/jsonl for CSV or JSON Lines, also on /audit/requests and /audit/responses.
+        </div>
+
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/audit/slow</strong><br>
+            Calls that exceeded their route's slow threshold. Query params: limit, offset, method
         </div>
 
         <div class="endpoint">
@@ -528,6 +1545,65 @@ func serveDashboard(w http.ResponseWriter, r *http.Request) {
             Get statistics about requests and methods.
         </div>
 
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/audit/stats/heatmap</strong><br>
+            Latency histogram per time bucket. Query params: window, interval, method, buckets
+        </div>
+
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/audit/stats/duplicates</strong><br>
+            Identical responses per method, flagging cache candidates. Query params: window, min_calls, min_ratio
+        </div>
+
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/audit/stats/params</strong><br>
+            Distinct values and most frequent values of each param per method, e.g. hot userIds of getUserInfo. Query params: window, method, top, limit
+        </div>
+
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/audit/compare</strong><br>
+            Method mix, error rate and latency of two time windows side by side, e.g. before and after a deploy. Query params: windowA, windowB (start/end, start/duration or a duration ending now)
+        </div>
+
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/audit/mcp/sessions</strong><br>
+            MCP sessions with protocol version, client and server info, and whether they are active, expired or ended. Query params: route, api_key, status, limit, offset. /audit/mcp/sessions/{id} adds the calls made in the session
+        </div>
+
+        <div class="endpoint">
+            <span class="method">PUT</span> <strong>/audit/dashboards/{name}</strong><br>
+            Save a dashboard of search, stat and chart panels, shown at /dashboards/{name}. Operator role.
+        </div>
+
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/metrics</strong><br>
+            Prometheus metrics of the audit pipeline: queue depths, insert latency, failures, dropped events, database size, calls in flight and queued per method, and call latency per route. OpenMetrics scrapes carry the request IDs of sampled calls as exemplars.
+        </div>
+
+        <div class="endpoint">
+            <span class="method">GET</span> <strong>/openrpc.json</strong><br>
+            OpenRPC document of the target, from the config file or rpc.discover.
+        </div>
+
+        <h2>▶️ Try it</h2>
+        <form class="try" id="try">
+            <div class="row">
+                <div><label for="tryPath">Route</label><input id="tryPath" value="/rpc"></div>
+                <div><label for="tryMethod">Method</label><input id="tryMethod" list="tryMethods" placeholder="Pick an observed or documented method"></div>
+                <div><label for="tryKey">API key (optional)</label><input id="tryKey" type="password"></div>
+            </div>
+            <datalist id="tryMethods"></datalist>
+            <label for="tryParams">Params (JSON)</label>
+            <textarea id="tryParams" rows="5">{}</textarea>
+            <button>Send through the gateway</button>
+        </form>
+        <div id="tryResult" style="display: none;">
+            <h3>Response <small id="tryStatus"></small></h3>
+            <pre id="tryResponse"></pre>
+            <h3>Audit entry</h3>
+            <pre id="tryAudit"></pre>
+        </div>
+
         <h2>🧪 Test JSON-RPC Request</h2>
         <pre>curl -X POST http://localhost:8080/rpc \
   -H "Content-Type: application/json" \
@@ -551,6 +1627,28 @@ func serveDashboard(w http.ResponseWriter, r *http.Request) {
     </div>
 
     <script>
+        // Signed-in user, when sign-in through an identity provider is configured
+        fetch('/auth/me')
+            .then(r => {
+                const session = document.getElementById('session');
+                if (r.status === 401) {
+                    session.innerHTML = '<a href="/auth/login">Sign in</a>';
+                    return;
+                }
+                if (!r.ok) {
+                    return;
+                }
+                return r.json().then(data => {
+                    session.textContent = data.user + ' (' + data.role + ') ';
+                    const logout = document.createElement('a');
+                    logout.href = '#';
+                    logout.textContent = 'Sign out';
+                    logout.onclick = () => fetch('/auth/logout', {method: 'POST'}).then(() => location.reload());
+                    session.appendChild(logout);
+                });
+            })
+            .catch(() => {});
+
         // Load stats
         fetch('/audit/stats')
             .then(r => r.json())
@@ -562,6 +1660,125 @@ func serveDashboard(w http.ResponseWriter, r *http.Request) {
                 document.getElementById('totalRequests').textContent = '0';
                 document.getElementById('recentRequests').textContent = '0';
             });
+
+        // Latency heatmap: rows are latency buckets (slowest on top), columns are 5 minute buckets
+        fetch('/audit/stats/heatmap?window=1h&interval=5m')
+            .then(r => r.json())
+            .then(data => {
+                const labels = data.latency_buckets_ms.map(b => '≤' + b + 'ms').concat(['slower']);
+                const max = Math.max(1, ...data.counts.flat());
+                let html = '';
+                for (let j = labels.length - 1; j >= 0; j--) {
+                    html += '<tr><th>' + labels[j] + '</th>';
+                    data.counts.forEach((row, i) => {
+                        const n = row[j];
+                        const alpha = n ? 0.15 + 0.85 * n / max : 0;
+                        const title = new Date(data.time_buckets[i]).toLocaleTimeString() + ': ' + n;
+                        html += '<td title="' + title + '" style="background: rgba(0,124,186,' + alpha + ')"></td>';
+                    });
+                    html += '</tr>';
+                }
+                document.getElementById('heatmap').innerHTML = html;
+            })
+            .catch(() => {});
+
+        // Methods mostly returning identical responses, hidden when there are none
+        fetch('/audit/stats/duplicates')
+            .then(r => r.ok ? r.json() : Promise.reject())
+            .then(data => {
+                const candidates = data.methods.filter(m => m.candidate);
+                if (!candidates.length) return;
+                const percent = x => Math.round(x * 100) + '%';
+                let html = '<tr><th>Method</th><th>Calls</th><th>Distinct responses</th><th>Duplicates</th><th>Most common</th><th>Cached</th></tr>';
+                candidates.forEach(m => {
+                    html += '<tr><td><span class="method">' + escapeHTML(m.method) + '</span></td><td>' + m.calls + '</td><td>' +
+                        m.distinct_responses + '</td><td>' + percent(m.duplicate_ratio) + '</td><td>' +
+                        percent(m.top_response_share) + '</td><td>' + m.cached_calls + '</td></tr>';
+                });
+                document.getElementById('candidateMethods').innerHTML = html;
+                document.getElementById('candidates').style.display = 'block';
+            })
+            .catch(() => {});
+
+        // Links to the dashboards defined by operators
+        fetch('/audit/dashboards')
+            .then(r => r.ok ? r.json() : Promise.reject())
+            .then(data => {
+                document.getElementById('dashboards').innerHTML = data.dashboards.map(d =>
+                    '<a href="/dashboards/' + encodeURIComponent(d.name) + '" class="button">📈 ' + escapeHTML(d.title || d.name) + '</a>').join('');
+            })
+            .catch(() => {});
+
+        // Try it: methods come from the OpenRPC document and from observed traffic
+        const tryParams = {};
+        function addTryMethods(names) {
+            const list = document.getElementById('tryMethods');
+            const known = new Set(Array.from(list.options).map(o => o.value));
+            names.filter(n => n && !known.has(n)).forEach(n => {
+                const option = document.createElement('option');
+                option.value = n;
+                list.appendChild(option);
+            });
+        }
+        fetch('/audit/stats').then(r => r.json()).then(data => addTryMethods(Object.keys(data.methods || {}))).catch(() => {});
+        document.getElementById('tryMethod').addEventListener('change', e => {
+            const params = tryParams[e.target.value];
+            if (params) {
+                document.getElementById('tryParams').value = JSON.stringify(Object.fromEntries(params.map(p => [p.name, null])), null, 2);
+            }
+        });
+        document.getElementById('try').addEventListener('submit', e => {
+            e.preventDefault();
+            let params;
+            try {
+                params = JSON.parse(document.getElementById('tryParams').value || 'null');
+            } catch (err) {
+                alert('Params are not valid JSON: ' + err.message);
+                return;
+            }
+            const call = {jsonrpc: '2.0', id: Date.now(), method: document.getElementById('tryMethod').value};
+            if (params !== null) call.params = params;
+            const headers = {'Content-Type': 'application/json'};
+            const key = document.getElementById('tryKey').value;
+            if (key) headers['X-API-Key'] = key;
+
+            document.getElementById('tryResult').style.display = 'block';
+            document.getElementById('tryAudit').textContent = '';
+            fetch(document.getElementById('tryPath').value, {method: 'POST', headers: headers, body: JSON.stringify(call)})
+                .then(r => r.text().then(body => {
+                    document.getElementById('tryStatus').textContent = r.status + ' ' + r.statusText;
+                    try { body = JSON.stringify(JSON.parse(body), null, 2); } catch (err) {}
+                    document.getElementById('tryResponse').textContent = body;
+                    const requestID = r.headers.get('X-Request-ID');
+                    if (!requestID) {
+                        document.getElementById('tryAudit').textContent = 'No request ID returned, is the route served on this address?';
+                        return;
+                    }
+                    return fetch('/audit/logs/' + encodeURIComponent(requestID))
+                        .then(a => a.ok ? a.json().then(entry => JSON.stringify(entry, null, 2)) : a.text())
+                        .then(text => { document.getElementById('tryAudit').textContent = text; });
+                }))
+                .catch(err => { document.getElementById('tryResponse').textContent = 'Request failed: ' + err; });
+        });
+
+        // Method catalog from the OpenRPC document, hidden when none is configured
+        const escapeHTML = s => String(s || '').replace(/[&<>"]/g, c => ({'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;'}[c]));
+        fetch('/openrpc/methods')
+            .then(r => r.ok ? r.json() : Promise.reject())
+            .then(data => {
+                let html = '<tr><th>Method</th><th>Params</th><th>Calls</th></tr>';
+                addTryMethods(data.methods.map(m => m.name));
+                data.methods.forEach(m => {
+                    tryParams[m.name] = m.params;
+                    const params = m.params.map(p => escapeHTML(p.name) + (p.required ? '' : '?')).join(', ');
+                    html += '<tr><td><span class="method' + (m.deprecated ? ' deprecated' : '') + '">' + escapeHTML(m.name) + '</span><br>' +
+                        escapeHTML(m.summary || m.description) + '</td><td>' + params + '</td><td>' + m.calls + '</td></tr>';
+                });
+                document.getElementById('methods').innerHTML = html;
+                document.getElementById('catalogTitle').textContent = [data.title, data.version].filter(Boolean).join(' ');
+                document.getElementById('catalog').style.display = 'block';
+            })
+            .catch(() => {});
     </script>
 </body>
 </html>`
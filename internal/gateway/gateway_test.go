@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/clock"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// newTestGateway returns a gateway on a fresh SQLite file, reading the time from c
+func newTestGateway(t *testing.T, c clock.Clock) (*Gateway, *database.Database) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetClock(c)
+
+	g := New(db, "http://127.0.0.1:1")
+	g.SetClock(c)
+	return g, db
+}
+
+// loadConfig parses a config file holding raw, so routes are normalized as in production
+func loadConfig(t *testing.T, raw string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(raw), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// writeFile writes content to a file in a test directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// recordingUpstream answers every JSON-RPC call with its method as the result
+// and remembers the bodies and headers it received
+type recordingUpstream struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	bodies  []string
+	headers []http.Header
+}
+
+func newRecordingUpstream(t *testing.T) *recordingUpstream {
+	u := &recordingUpstream{}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		u.mu.Lock()
+		u.bodies = append(u.bodies, string(body))
+		u.headers = append(u.headers, r.Header.Clone())
+		u.mu.Unlock()
+
+		type call struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		answer := func(c call) map[string]interface{} {
+			return map[string]interface{}{"jsonrpc": "2.0", "id": c.ID, "result": c.Method}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			var calls []call
+			json.Unmarshal(body, &calls)
+			answers := make([]interface{}, len(calls))
+			for i, c := range calls {
+				answers[i] = answer(c)
+			}
+			json.NewEncoder(w).Encode(answers)
+			return
+		}
+		var c call
+		json.Unmarshal(body, &c)
+		json.NewEncoder(w).Encode(answer(c))
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// received returns the bodies the upstream got so far
+func (u *recordingUpstream) received() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.bodies...)
+}
+
+// lastHeaders returns the headers of the latest call the upstream got
+func (u *recordingUpstream) lastHeaders() http.Header {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.headers) == 0 {
+		return nil
+	}
+	return u.headers[len(u.headers)-1]
+}
+
+// serve sends a request through the gateway's router and returns the recorded response
+func serve(g *Gateway, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	g.SetupRoutes().ServeHTTP(w, r)
+	return w
+}
+
+// bearer returns the headers of a call authenticated with token
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+// testStart is the fake time tests start at, unless they need another
+var testStart = time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+// recordCall stores a call of apiKey made at ts, answered unless status is 0
+func recordCall(t *testing.T, db *database.Database, requestID, apiKey string, ts time.Time, status int, metadata map[string]string) {
+	t.Helper()
+	err := db.InsertAuditRequest(&types.AuditRequest{
+		RequestID: requestID,
+		Timestamp: ts,
+		Method:    "eth_call",
+		Request:   json.RawMessage(`{}`),
+		APIKey:    apiKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == 0 {
+		return
+	}
+	err = db.InsertAuditResponse(&types.AuditResponse{
+		RequestID:  requestID,
+		Timestamp:  ts,
+		StatusCode: status,
+		Metadata:   metadata,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
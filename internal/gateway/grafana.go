@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Grafana SimpleJSON/Infinity datasource endpoints. Targets are a metric name,
+// optionally followed by :<method> to only count one JSON-RPC method.
+
+// grafanaMetrics maps target metric names to their value in a series point.
+// ok is false for points that have no value, e.g. latency without traffic.
+var grafanaMetrics = map[string]func(p types.SeriesPoint) (value float64, ok bool){
+	"requests": func(p types.SeriesPoint) (float64, bool) { return float64(p.Count), true },
+	"errors":   func(p types.SeriesPoint) (float64, bool) { return float64(p.Errors), true },
+	"error_rate": func(p types.SeriesPoint) (float64, bool) {
+		return float64(p.Errors) / float64(p.Count) * 100, p.Count > 0
+	},
+	"latency_avg": func(p types.SeriesPoint) (float64, bool) { return p.AvgMs, p.Count > 0 },
+	"latency_p50": func(p types.SeriesPoint) (float64, bool) { return p.P50Ms, p.Count > 0 },
+	"latency_p95": func(p types.SeriesPoint) (float64, bool) { return p.P95Ms, p.Count > 0 },
+	"latency_p99": func(p types.SeriesPoint) (float64, bool) { return p.P99Ms, p.Count > 0 },
+	"latency_max": func(p types.SeriesPoint) (float64, bool) { return p.MaxMs, p.Count > 0 },
+}
+
+// maxGrafanaPoints caps the buckets of a series when Grafana sends no maxDataPoints
+const maxGrafanaPoints = 1000
+
+// GrafanaTest answers Grafana's datasource connection test
+func (g *Gateway) GrafanaTest(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// GrafanaSearch lists the available targets, filtered by the target substring
+func (g *Gateway) GrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var req types.GrafanaSearchRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid search: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	metrics := make([]string, 0, len(grafanaMetrics))
+	for name := range grafanaMetrics {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+
+	// Offer per-method variants of each metric for the busiest methods
+	var methods []string
+	if stats, err := g.db.GetStats(); err == nil {
+		g.suppressStats(stats)
+		for method := range stats.Methods {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+	}
+
+	targets := []string{}
+	for _, metric := range metrics {
+		candidates := []string{metric}
+		for _, method := range methods {
+			candidates = append(candidates, metric+":"+method)
+		}
+		for _, target := range candidates {
+			if strings.Contains(target, req.Target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+// GrafanaQuery returns time series of the requested targets
+func (g *Gateway) GrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req types.GrafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	from, to := req.Range.From, req.Range.To
+	if !to.After(from) {
+		http.Error(w, "Invalid query: range.to must be after range.from", http.StatusBadRequest)
+		return
+	}
+
+	maxPoints := req.MaxDataPoints
+	if maxPoints <= 0 || maxPoints > maxGrafanaPoints {
+		maxPoints = maxGrafanaPoints
+	}
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if minimum := to.Sub(from) / time.Duration(maxPoints); interval < minimum {
+		interval = minimum
+	}
+	interval = interval.Truncate(time.Second)
+	if interval < time.Second {
+		interval = time.Second
+	}
+	from = from.Truncate(interval)
+
+	series := make([]types.GrafanaSeries, 0, len(req.Targets))
+	points := make(map[string][]types.SeriesPoint) // By method, shared by targets
+	for _, target := range req.Targets {
+		if target.Target == "" {
+			continue
+		}
+		metric, method, _ := strings.Cut(target.Target, ":")
+		value, ok := grafanaMetrics[metric]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown target %q", target.Target), http.StatusBadRequest)
+			return
+		}
+
+		if _, done := points[method]; !done {
+			p, err := g.db.ResponseSeries(method, from, to, interval)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to query series: %v", err), http.StatusInternalServerError)
+				return
+			}
+			g.suppressSeries(p)
+			points[method] = p
+		}
+
+		s := types.GrafanaSeries{Target: target.Target, Datapoints: [][2]float64{}}
+		for _, p := range points[method] {
+			if v, ok := value(p); ok {
+				s.Datapoints = append(s.Datapoints, [2]float64{v, float64(p.Start.UnixMilli())})
+			}
+		}
+		series = append(series, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+// GrafanaAnnotations returns operator annotations of audit entries in the range
+func (g *Gateway) GrafanaAnnotations(w http.ResponseWriter, r *http.Request) {
+	var req types.GrafanaAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid annotation query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	logs, err := g.db.ListAnnotatedLogs(req.Range.From, req.Range.To, req.Annotation.Query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve annotations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	annotations := make([]types.GrafanaAnnotation, len(logs))
+	for i, l := range logs {
+		title := l.Method + " " + l.RequestID
+		if l.Resolved {
+			title += " (resolved)"
+		}
+		annotations[i] = types.GrafanaAnnotation{
+			Annotation: req.Annotation,
+			Time:       l.Timestamp.UnixMilli(),
+			Title:      title,
+			Text:       l.Note,
+			Tags:       l.Tags,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(annotations)
+}
@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/config"
+)
+
+// hopHeaders apply to a single connection and must not be forwarded by proxies (RFC 9110 section 7.6.1).
+// Content-Length is recomputed because bodies are re-buffered.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Content-Length",
+}
+
+// SetResponseHeaderFilter sets the default filter for upstream response headers
+func (g *Gateway) SetResponseHeaderFilter(filter *config.HeaderFilter) {
+	g.responseHeaders = filter
+}
+
+// isHopHeader reports whether name is hop-by-hop, either always or because the Connection header lists it
+func isHopHeader(name string, connection []string) bool {
+	for _, h := range hopHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	for _, value := range connection {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyRequestHeaders copies end-to-end headers of the client request to the upstream request
+func copyRequestHeaders(dst, src http.Header) {
+	connection := src.Values("Connection")
+	for key, values := range src {
+		if isHopHeader(key, connection) {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// copyResponseHeaders copies end-to-end upstream headers that pass filter to the client
+// response and sets Content-Length for the re-buffered body, unless bodyLength is
+// negative for streamed bodies
+func copyResponseHeaders(w http.ResponseWriter, resp *http.Response, filter *config.HeaderFilter, bodyLength int) {
+	connection := resp.Header.Values("Connection")
+	for key, values := range resp.Header {
+		if isHopHeader(key, connection) || !filter.Allows(key) {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if bodyLength >= 0 && resp.StatusCode >= 200 && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
+		w.Header().Set("Content-Length", strconv.Itoa(bodyLength))
+	}
+}
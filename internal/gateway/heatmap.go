@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// defaultLatencyBuckets are the heatmap's latency upper bounds in milliseconds
+var defaultLatencyBuckets = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// maxHeatmapRows caps the number of time buckets a single request can ask for
+const maxHeatmapRows = 1440
+
+// GetLatencyHeatmap returns a time × latency histogram of completed calls.
+// Query params: window (default 1h), interval (default window/60), method,
+// buckets (comma-separated latency bounds in ms).
+func (g *Gateway) GetLatencyHeatmap(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	window := time.Hour
+	if s := query.Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid window, expected a duration such as 6h", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	interval := (window / 60).Truncate(time.Second)
+	if s := query.Get("interval"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid interval, expected a duration such as 1m", http.StatusBadRequest)
+			return
+		}
+		interval = d
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if window/interval > maxHeatmapRows {
+		http.Error(w, fmt.Sprintf("Too many time buckets, at most %d are allowed", maxHeatmapRows), http.StatusBadRequest)
+		return
+	}
+
+	bounds := defaultLatencyBuckets
+	if s := query.Get("buckets"); s != "" {
+		var err error
+		if bounds, err = parseLatencyBuckets(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Align buckets to the interval so consecutive polls line up
+	to := g.now().Truncate(interval).Add(interval)
+	from := to.Add(-window)
+	method := query.Get("method")
+
+	counts, err := g.db.LatencyHeatmap(method, from, to, interval, bounds)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute heatmap: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := types.HeatmapResponse{
+		Method:          method,
+		From:            from,
+		To:              to,
+		IntervalSeconds: int64(interval / time.Second),
+		TimeBuckets:     make([]time.Time, len(counts)),
+		LatencyBuckets:  bounds,
+		Counts:          counts,
+	}
+	for i, row := range counts {
+		response.TimeBuckets[i] = from.Add(time.Duration(i) * interval)
+		for _, n := range row {
+			response.Total += n
+		}
+	}
+	g.suppressHeatmap(&response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseLatencyBuckets parses a comma-separated list of latency bounds in milliseconds
+func parseLatencyBuckets(s string) ([]int64, error) {
+	var bounds []int64
+	for _, part := range strings.Split(s, ",") {
+		bound, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || bound < 0 {
+			return nil, fmt.Errorf("invalid latency bucket %q", part)
+		}
+		bounds = append(bounds, bound)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	return bounds, nil
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// isQueryCall reports whether r is a JSON-RPC call in the GET query form of a route accepting it
+func isQueryCall(r *http.Request, route *config.Route) bool {
+	return route.HTTPGet && r.Method == http.MethodGet && r.URL.Query().Get("method") != ""
+}
+
+// queryCallRequest converts a call in the GET query form into the equivalent
+// POST, so it is forwarded and audited like any other call. The query string
+// is consumed and not passed upstream.
+func queryCallRequest(r *http.Request) (*http.Request, error) {
+	query := r.URL.Query()
+	call := types.JSONRPCRequest{JSONRPC: "2.0", Method: query.Get("method")}
+
+	if encoded := query.Get("params"); encoded != "" {
+		params, err := decodeQueryParams(encoded)
+		if err != nil {
+			return nil, err
+		}
+		call.Params = params
+	}
+	if id := query.Get("id"); id != "" {
+		// Numbers stay numbers; anything else is a string id
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(id), &parsed); err == nil {
+			switch parsed.(type) {
+			case float64, string:
+				call.ID = parsed
+			}
+		}
+		if call.ID == nil {
+			call.ID = id
+		}
+	}
+
+	body, err := json.Marshal(call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode call: %w", err)
+	}
+
+	converted := r.Clone(r.Context())
+	converted.Method = http.MethodPost
+	converted.URL = &url.URL{Path: r.URL.Path, RawPath: r.URL.RawPath}
+	converted.RequestURI = ""
+	converted.Body = io.NopCloser(bytes.NewReader(body))
+	converted.ContentLength = int64(len(body))
+	converted.Header.Set("Content-Type", "application/json")
+	return converted, nil
+}
+
+// decodeQueryParams decodes the params of the GET form: base64 of the JSON
+// params, standard or URL-safe, padded or not. Plain JSON is accepted too.
+func decodeQueryParams(encoded string) (json.RawMessage, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(encoded); err == nil && json.Valid(decoded) {
+			return decoded, nil
+		}
+	}
+	if trimmed := strings.TrimSpace(encoded); json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed), nil
+	}
+	return nil, fmt.Errorf("params must be base64-encoded JSON")
+}
+
+// answerOptions tells clients which HTTP methods the route accepts
+func answerOptions(w http.ResponseWriter, route *config.Route) {
+	methods := append([]string{http.MethodOptions}, route.HTTPMethods...)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,305 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/redis"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// idempotencyHeader carries the key clients retry a call under
+const idempotencyHeader = "Idempotency-Key"
+
+// idempotencyConflictCode is the JSON-RPC error code of retries the stored answer cannot serve
+const idempotencyConflictCode = -32007
+
+const (
+	maxIdempotencyKey     = 255             // Longest accepted Idempotency-Key
+	maxIdempotentBody     = 1 << 20         // Larger answers are not stored, so retries call the target again
+	idempotencyPendingTTL = 5 * time.Minute // Lifetime of the claim of a call in flight, in case the gateway dies
+)
+
+// idempotentAnswer is what is stored under an Idempotency-Key: the hash of
+// the call first sent with it and, once answered, its response
+type idempotentAnswer struct {
+	BodyHash    string `json:"body_hash"`
+	Pending     bool   `json:"pending,omitempty"` // The first call is still in flight
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// idempotencyStore keeps the answers of calls sent with an Idempotency-Key
+type idempotencyStore interface {
+	// claim marks a call with bodyHash as in flight under key, or returns
+	// what an earlier call stored there
+	claim(key, bodyHash string, ttl time.Duration, now time.Time) (*idempotentAnswer, error)
+	// store replaces the claim with the call's answer
+	store(key string, answer *idempotentAnswer, ttl time.Duration, now time.Time)
+	// release drops the claim of a call whose answer is not kept, so it can be retried
+	release(key string)
+}
+
+// localIdempotency keeps answers in memory, for a single gateway
+type localIdempotency struct {
+	mu        sync.Mutex
+	entries   map[string]localAnswer
+	nextSweep time.Time
+}
+
+type localAnswer struct {
+	answer  idempotentAnswer
+	expires time.Time
+}
+
+func newLocalIdempotency() *localIdempotency {
+	return &localIdempotency{entries: make(map[string]localAnswer)}
+}
+
+func (s *localIdempotency) claim(key, bodyHash string, ttl time.Duration, now time.Time) (*idempotentAnswer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		for k, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+	if entry, ok := s.entries[key]; ok && !now.After(entry.expires) {
+		answer := entry.answer
+		return &answer, nil
+	}
+	s.entries[key] = localAnswer{answer: idempotentAnswer{BodyHash: bodyHash, Pending: true}, expires: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *localIdempotency) store(key string, answer *idempotentAnswer, ttl time.Duration, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = localAnswer{answer: *answer, expires: now.Add(ttl)}
+}
+
+func (s *localIdempotency) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// redisIdempotency shares answers between replicas through Redis, falling
+// back to memory while Redis is down
+type redisIdempotency struct {
+	backend *redisBackend
+	local   *localIdempotency
+}
+
+func (s *redisIdempotency) key(key string) string {
+	return s.backend.prefix + "idempotency:" + key
+}
+
+func (s *redisIdempotency) claim(key, bodyHash string, ttl time.Duration, now time.Time) (*idempotentAnswer, error) {
+	if !s.backend.available(now) {
+		return s.local.claim(key, bodyHash, ttl, now)
+	}
+	pending, _ := json.Marshal(idempotentAnswer{BodyHash: bodyHash, Pending: true})
+
+	ctx, cancel := s.backend.context()
+	defer cancel()
+	// A claim expiring between SET and GET is claimed again on the second round
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := s.backend.client.String(ctx, "SET", s.key(key), string(pending), "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+		if err == nil {
+			s.backend.succeeded()
+			return nil, nil
+		}
+		if !errors.Is(err, redis.ErrNil) {
+			s.backend.failed(err, now)
+			return s.local.claim(key, bodyHash, ttl, now)
+		}
+
+		stored, err := s.backend.client.String(ctx, "GET", s.key(key))
+		if errors.Is(err, redis.ErrNil) {
+			continue
+		}
+		if err != nil {
+			s.backend.failed(err, now)
+			return s.local.claim(key, bodyHash, ttl, now)
+		}
+		s.backend.succeeded()
+		var answer idempotentAnswer
+		if err := json.Unmarshal([]byte(stored), &answer); err != nil {
+			return nil, err
+		}
+		return &answer, nil
+	}
+	return nil, nil
+}
+
+func (s *redisIdempotency) store(key string, answer *idempotentAnswer, ttl time.Duration, now time.Time) {
+	if !s.backend.available(now) {
+		s.local.store(key, answer, ttl, now)
+		return
+	}
+	data, _ := json.Marshal(answer)
+	ctx, cancel := s.backend.context()
+	defer cancel()
+	if _, err := s.backend.client.String(ctx, "SET", s.key(key), string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10)); err != nil {
+		s.backend.failed(err, now)
+		s.local.store(key, answer, ttl, now)
+	}
+}
+
+func (s *redisIdempotency) release(key string) {
+	s.local.release(key)
+	ctx, cancel := s.backend.context()
+	defer cancel()
+	if _, err := s.backend.client.Do(ctx, "DEL", s.key(key)); err != nil {
+		log.Printf("Failed to release idempotency key: %v", err)
+	}
+}
+
+// idempotentCall records the answer of a call claimed under an Idempotency-Key
+// while it is written to the client
+type idempotentCall struct {
+	http.ResponseWriter
+	key      string
+	bodyHash string
+	ttl      time.Duration
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (c *idempotentCall) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *idempotentCall) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.overflow {
+		if c.body.Len()+len(p) > maxIdempotentBody {
+			c.overflow = true
+			c.body.Reset()
+		} else {
+			c.body.Write(p)
+		}
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController flush streamed answers
+func (c *idempotentCall) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// idempotencyKey scopes a client's key to the route and API key, so clients cannot read each other's answers
+func idempotencyKey(route *config.Route, client *config.APIKey, key string) string {
+	name := ""
+	if client != nil {
+		name = client.Name
+	}
+	sum := sha256.Sum256([]byte(route.Name + "\x00" + name + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// beginIdempotent handles the Idempotency-Key of a call on a route keeping
+// answers. Retries of answered calls are served the stored answer and retries
+// of calls in flight or with another body are refused, reporting true. Other
+// calls are claimed and get a writer recording their answer for finishIdempotent.
+func (g *Gateway) beginIdempotent(w http.ResponseWriter, r *http.Request, call *proxyCall, client *config.APIKey, body []byte) (*idempotentCall, bool) {
+	key := r.Header.Get(idempotencyHeader)
+	ttl := call.route.IdempotencyTTLDuration()
+	if key == "" || ttl == 0 {
+		return nil, false
+	}
+	audit := AuditContextFrom(r.Context())
+	if len(key) > maxIdempotencyKey {
+		errorMsg := "Idempotency-Key must be at most " + strconv.Itoa(maxIdempotencyKey) + " characters"
+		g.handleRPCError(w, call.id, -32600, "Invalid Request", errorMsg, call.requestID, call.startTime, http.StatusBadRequest)
+		return nil, true
+	}
+
+	scoped := idempotencyKey(call.route, client, key)
+	bodyHash := types.BodyHash(body)
+	pendingTTL := idempotencyPendingTTL
+	if ttl < pendingTTL {
+		pendingTTL = ttl
+	}
+	stored, err := g.idempotency.claim(scoped, bodyHash, pendingTTL, g.now())
+	if err != nil {
+		log.Printf("Ignoring Idempotency-Key of %s: %v", call.requestID, err)
+		return nil, false
+	}
+
+	switch {
+	case stored == nil:
+		audit.Set(metaIdempotency, "claimed")
+		return &idempotentCall{ResponseWriter: w, key: scoped, bodyHash: bodyHash, ttl: ttl}, false
+	case stored.BodyHash != bodyHash:
+		audit.Set(metaIdempotency, "mismatch")
+		errorMsg := "Idempotency-Key was already used with a different request"
+		g.handleRPCError(w, call.id, idempotencyConflictCode, "Idempotency key reused", errorMsg, call.requestID, call.startTime, http.StatusUnprocessableEntity)
+	case stored.Pending:
+		audit.Set(metaIdempotency, "in_progress")
+		w.Header().Set("Retry-After", "1")
+		errorMsg := "a call with this Idempotency-Key is still in progress"
+		g.handleRPCError(w, call.id, idempotencyConflictCode, "Idempotency key in use", errorMsg, call.requestID, call.startTime, http.StatusConflict)
+	default:
+		audit.Set(metaIdempotency, "replayed")
+		g.replayIdempotent(w, call, stored)
+	}
+	return nil, true
+}
+
+// finishIdempotent stores the answer of a claimed call, or releases the key
+// when the answer is a failure worth retrying or too large to keep
+func (g *Gateway) finishIdempotent(c *idempotentCall) {
+	if c.status == 0 || c.status >= 500 || c.status == http.StatusTooManyRequests || c.status == statusClientClosedRequest || c.overflow {
+		g.idempotency.release(c.key)
+		return
+	}
+	g.idempotency.store(c.key, &idempotentAnswer{
+		BodyHash:    c.bodyHash,
+		Status:      c.status,
+		ContentType: c.Header().Get("Content-Type"),
+		Body:        bytes.Clone(c.body.Bytes()),
+	}, c.ttl, g.now())
+}
+
+// replayIdempotent answers a retried call with the stored answer, audited like any other response
+func (g *Gateway) replayIdempotent(w http.ResponseWriter, call *proxyCall, answer *idempotentAnswer) {
+	auditResponse := &types.AuditResponse{
+		RequestID:   call.requestID,
+		Timestamp:   g.now(),
+		StatusCode:  answer.Status,
+		ProcessTime: g.since(call.startTime).Milliseconds(),
+		ContentType: answer.ContentType,
+	}
+	g.auditResponseBody(auditResponse, call, answer.Body)
+	g.recordResponse(auditResponse)
+
+	if answer.ContentType != "" {
+		w.Header().Set("Content-Type", answer.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	g.stampResponse(w, call.requestID, answer.Body)
+	w.WriteHeader(answer.Status)
+	w.Write(answer.Body)
+}
@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const (
+	importBatchSize = 500
+	importMaxLine   = 16 << 20 // Longest accepted NDJSON line
+	importMaxErrors = 100      // Line errors reported back to the caller
+)
+
+// ImportAuditLogs merges NDJSON audit records into the database, skipping request IDs
+// that are already stored. Each line is a types.AuditRecord.
+func (g *Gateway) ImportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	var response types.ImportResponse
+	statusCode := http.StatusOK
+
+	lineError := func(line int, format string, args ...interface{}) {
+		response.Failed++
+		if len(response.Errors) < importMaxErrors {
+			response.Errors = append(response.Errors, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+		}
+	}
+
+	var batch []types.AuditRecord
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := g.db.ImportAuditRecords(batch)
+		if err != nil {
+			return err
+		}
+		response.Requests += result.Requests
+		response.Responses += result.Responses
+		response.Duplicates += result.Duplicates
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), importMaxLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record types.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			lineError(line, "invalid JSON: %v", err)
+			continue
+		}
+		if err := validateRecord(record); err != nil {
+			lineError(line, "%v", err)
+			continue
+		}
+
+		batch = append(batch, record)
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				lineError(line, "failed to store batch: %v", err)
+				statusCode = http.StatusInternalServerError
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		lineError(line+1, "failed to read body: %v", err)
+		statusCode = http.StatusBadRequest
+	}
+	if statusCode == http.StatusOK {
+		if err := flush(); err != nil {
+			lineError(line, "failed to store batch: %v", err)
+			statusCode = http.StatusInternalServerError
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateRecord checks that a record carries the payload matching its type
+func validateRecord(record types.AuditRecord) error {
+	switch record.Type {
+	case types.RecordRequest:
+		if record.Request == nil || record.Request.RequestID == "" {
+			return fmt.Errorf("request record without request.request_id")
+		}
+		if record.Request.Timestamp.IsZero() {
+			return fmt.Errorf("request %s has no timestamp", record.Request.RequestID)
+		}
+	case types.RecordResponse:
+		if record.Response == nil || record.Response.RequestID == "" {
+			return fmt.Errorf("response record without response.request_id")
+		}
+		if record.Response.Timestamp.IsZero() {
+			return fmt.Errorf("response %s has no timestamp", record.Response.RequestID)
+		}
+	default:
+		return fmt.Errorf("unknown record type %q", record.Type)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// headerFilterPrefix marks /audit/logs query params filtering on an indexed header, e.g. header.X-Api-Version=2
+const headerFilterPrefix = "header."
+
+// SetIndexedHeaders configures which request headers are stored in the
+// indexed audit_headers table besides the headers blob
+func (g *Gateway) SetIndexedHeaders(names []string) {
+	g.indexedHeaders = names
+}
+
+// indexedHeaderValues returns the values of the indexed headers a call sent,
+// redacted like the stored headers. They are kept at every audit level so
+// metadata-only routes stay searchable.
+func (g *Gateway) indexedHeaderValues(r *http.Request, redaction, credentialHeader string) map[string]string {
+	var values map[string]string
+	for _, name := range g.indexedHeaders {
+		value := r.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if len(value) > maxTagValueLength {
+			value = value[:maxTagValueLength]
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[name] = value
+	}
+	redactHeaders(values, redaction, credentialHeader)
+	return values
+}
+
+// headerFilters reads the header.<name>=<value> params of an audit log query.
+// Only indexed headers can be filtered on; the headers blob is not searched.
+func (g *Gateway) headerFilters(query url.Values) (map[string]string, error) {
+	filters := make(map[string]string)
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, headerFilterPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		name = http.CanonicalHeaderKey(name)
+		if !containsString(g.indexedHeaders, name) {
+			return nil, fmt.Errorf("header %s is not indexed, add it to indexed_headers to filter on it", name)
+		}
+		filters[name] = values[0]
+	}
+	return filters, nil
+}
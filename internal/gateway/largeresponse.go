@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// SetMaxResponseCapture bounds the response bytes kept in the audit trail on
+// routes without their own max_response_capture_bytes, 0 keeps bodies whole
+func (g *Gateway) SetMaxResponseCapture(n int64) {
+	g.maxResponseCapture = n
+}
+
+// responseCaptureLimit returns the most response bytes audited on route, 0 when unbounded
+func (g *Gateway) responseCaptureLimit(route *config.Route) int64 {
+	if route != nil && route.MaxResponseCaptureBytes > 0 {
+		return route.MaxResponseCaptureBytes
+	}
+	return g.maxResponseCapture
+}
+
+// streamsLargeResponses reports whether responses over the capture limit can
+// go to the client as they arrive. Transforms, caching, MCP session tracking
+// and signatures need the whole body, so those calls buffer it and only the
+// audit trail is capped.
+func (g *Gateway) streamsLargeResponses(call *proxyCall) bool {
+	return call.transform == nil && call.cacheKey == "" && call.mcp == nil && len(g.signingKey) == 0
+}
+
+// readCappedBody reads a response body of up to limit bytes. Larger bodies
+// return their first limit+1 bytes with more set, the rest left to read.
+func readCappedBody(body io.Reader, sizeHint, limit int64) ([]byte, bool, error) {
+	head, err := readBody(io.LimitReader(body, limit+1), min(sizeHint, limit+1))
+	return head, err == nil && int64(len(head)) > limit, err
+}
+
+// streamLargeResponse passes a response over the capture limit on to the
+// client as it arrives, hashing and counting it on the way, then records the
+// audit response with the first bytes of the body, its SHA-256 and its size.
+// The row is written even when the stream breaks off, with the hash of what
+// was read.
+func (g *Gateway) streamLargeResponse(w http.ResponseWriter, call *proxyCall, resp *http.Response, head []byte, upstreamStart time.Time, servedBy string) {
+	limit := g.responseCaptureLimit(call.route)
+	length := -1
+	if resp.ContentLength >= 0 {
+		length = int(resp.ContentLength)
+	}
+	copyResponseHeaders(w, resp, call.headers, length)
+	w.Header().Set(types.RequestIDHeader, call.requestID)
+	w.WriteHeader(resp.StatusCode)
+
+	sum := sha256.New()
+	sum.Write(head)
+	size, streamErr := int64(len(head)), error(nil)
+	if _, err := w.Write(head); err != nil {
+		streamErr = fmt.Errorf("client went away: %w", err)
+	} else {
+		n, err := io.Copy(w, io.TeeReader(resp.Body, sum))
+		size += n
+		if err != nil {
+			streamErr = err
+		}
+	}
+	timing := call.timer.timing(g.now())
+
+	auditResponse := &types.AuditResponse{
+		RequestID:    call.requestID,
+		Timestamp:    g.now(),
+		StatusCode:   resp.StatusCode,
+		ProcessTime:  g.since(call.startTime).Milliseconds(),
+		QueueTime:    call.queueTime.Milliseconds(),
+		UpstreamTime: g.since(upstreamStart).Milliseconds(),
+		ContentType:  resp.Header.Get("Content-Type"),
+		ServedBy:     servedBy,
+		Timing:       timing,
+	}
+	auditResponse.Slow = call.slow > 0 && g.since(call.startTime) > call.slow
+	auditResponse.Debug = call.debug.record(resp, timing)
+	g.auditCappedResponse(auditResponse, call, head[:min(int64(len(head)), limit)], sum, size)
+	if streamErr != nil {
+		auditResponse.Error = fmt.Sprintf("response stream ended after %d bytes: %v", size, streamErr)
+	}
+	g.debugf("Upstream streamed %d bytes for %s (%s) in %dms, keeping %d in the audit trail", size, call.requestID, call.method, auditResponse.UpstreamTime, limit)
+
+	g.recordResponse(auditResponse)
+}
+
+// auditCappedResponse stores the first bytes of a response body too large to
+// keep whole, redacted and scanned as usual, and records the SHA-256 and size
+// of the whole body so the response can still be verified against the target's
+func (g *Gateway) auditCappedResponse(auditResponse *types.AuditResponse, call *proxyCall, captured []byte, sum hash.Hash, size int64) {
+	// Drop a character cut in half so text bodies stay text
+	last := len(captured) - 1
+	for last > 0 && len(captured)-last < utf8.UTFMax && !utf8.RuneStart(captured[last]) {
+		last--
+	}
+	if last >= 0 && !utf8.FullRune(captured[last:]) {
+		captured = captured[:last]
+	}
+
+	auditResponse.ResponseBytes = size
+	auditedResponse := redactPayload(bytes.Clone(captured), call.redaction, "result")
+	if g.pii != nil {
+		auditedResponse, auditResponse.PII = g.pii.scan(auditedResponse)
+	}
+	if call.auditLevel == types.AuditLevelFullBody {
+		auditResponse.Response, auditResponse.BodyEncoding = types.EncodeBody(auditedResponse, auditResponse.ContentType)
+	}
+
+	if audit, ok := g.auditContexts.Load(call.requestID); ok {
+		audit.(*AuditContext).Set(metaBodySHA256, hex.EncodeToString(sum.Sum(nil)))
+		audit.(*AuditContext).Set(metaBodyCaptured, strconv.Itoa(len(captured)))
+	}
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// callBuckets are the upper bounds of the call duration histogram in seconds
+var callBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// latencyHistograms counts how long the gateway took to answer the calls of
+// each route. Every bucket keeps the request ID of its latest call as an
+// OpenMetrics exemplar, so a latency spike in Grafana links to the audit row
+// of a call that caused it.
+type latencyHistograms struct {
+	mu     sync.Mutex
+	routes map[string]*latencyHistogram
+}
+
+type latencyHistogram struct {
+	count     int64
+	sum       float64
+	buckets   []int64     // Calls per callBuckets bound and +Inf, not cumulative
+	exemplars []*exemplar // Latest call of each bucket, nil until one arrives
+}
+
+// exemplar is a call standing for a histogram bucket
+type exemplar struct {
+	requestID string
+	seconds   float64
+	at        time.Time
+}
+
+// observe records the answer of a call on route
+func (l *latencyHistograms) observe(route string, resp *types.AuditResponse) {
+	if route == "" {
+		return
+	}
+	seconds := float64(resp.ProcessTime) / 1000
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.routes == nil {
+		l.routes = make(map[string]*latencyHistogram)
+	}
+	h, ok := l.routes[route]
+	if !ok {
+		h = &latencyHistogram{
+			buckets:   make([]int64, len(callBuckets)+1),
+			exemplars: make([]*exemplar, len(callBuckets)+1),
+		}
+		l.routes[route] = h
+	}
+	i := sort.SearchFloat64s(callBuckets, seconds)
+	h.count++
+	h.sum += seconds
+	h.buckets[i]++
+	h.exemplars[i] = &exemplar{requestID: resp.RequestID, seconds: seconds, at: resp.Timestamp}
+}
+
+// snapshot copies the histogram of every route, ordered by route name
+func (l *latencyHistograms) snapshot() ([]string, []latencyHistogram) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	names := make([]string, 0, len(l.routes))
+	for name := range l.routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	histograms := make([]latencyHistogram, len(names))
+	for i, name := range names {
+		h := l.routes[name]
+		histograms[i] = latencyHistogram{
+			count:     h.count,
+			sum:       h.sum,
+			buckets:   append([]int64(nil), h.buckets...),
+			exemplars: append([]*exemplar(nil), h.exemplars...),
+		}
+	}
+	return names, histograms
+}
@@ -0,0 +1,467 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// JSON-RPC error codes of calls refused for their MCP session, as MCP servers answer them
+const (
+	mcpSessionRequiredCode = -32000
+	mcpSessionNotFoundCode = -32001
+)
+
+// MCP methods the gateway follows sessions by
+const (
+	mcpInitialize  = "initialize"
+	mcpInitialized = "notifications/initialized"
+)
+
+const (
+	maxMCPSessions   = 100000      // Active sessions kept in memory, the least recently used are dropped beyond
+	mcpSaveInterval  = time.Minute // Activity of a session is written to SQLite at most this often
+	maxStreamCapture = 1 << 20     // Bytes of an event stream kept for the audit response
+)
+
+// mcpCall is the session state of a call on a route tracking MCP sessions
+type mcpCall struct {
+	sessionID  string               // Mcp-Session-Id the client sent, empty without one
+	tracked    bool                 // sessionID is a known, active session
+	initialize *mcpInitializeParams // Set for initialize calls
+	client     *config.APIKey
+	ip         string
+}
+
+type mcpInitializeParams struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	ClientInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"clientInfo"`
+}
+
+type mcpInitializeResult struct {
+	Result *struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+	} `json:"result"`
+}
+
+// mcpSessionStore holds the active MCP sessions; sessions it does not know are
+// looked up in SQLite, e.g. after a restart
+type mcpSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*mcpSession
+}
+
+type mcpSession struct {
+	types.MCPSession
+	savedAt time.Time // When the session was last written to SQLite
+}
+
+// get returns a copy of the session with the given id
+func (s *mcpSessionStore) get(id string) (types.MCPSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return types.MCPSession{}, false
+	}
+	return session.MCPSession, true
+}
+
+// put starts holding a session saved at savedAt, making room for it when full
+func (s *mcpSessionStore) put(session types.MCPSession, savedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*mcpSession)
+	}
+	if _, ok := s.sessions[session.ID]; !ok && len(s.sessions) >= maxMCPSessions {
+		s.evict(savedAt)
+	}
+	s.sessions[session.ID] = &mcpSession{MCPSession: session, savedAt: savedAt}
+}
+
+// evict drops expired sessions, or the least recently used one when none are; s.mu must be held
+func (s *mcpSessionStore) evict(now time.Time) {
+	var oldest *mcpSession
+	for id, session := range s.sessions {
+		if session.StatusAt(now) != types.MCPSessionActive {
+			delete(s.sessions, id)
+		} else if oldest == nil || session.LastSeenAt.Before(oldest.LastSeenAt) {
+			oldest = session
+		}
+	}
+	if len(s.sessions) >= maxMCPSessions && oldest != nil {
+		delete(s.sessions, oldest.ID)
+	}
+}
+
+// touch records a call in the session, returning a copy to save when its
+// activity was not written for mcpSaveInterval or the client finished initializing
+func (s *mcpSessionStore) touch(id, method string, ttl time.Duration, now time.Time) (types.MCPSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return types.MCPSession{}, false
+	}
+	session.Calls++
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(ttl)
+	save := now.Sub(session.savedAt) >= mcpSaveInterval || ttl < 2*mcpSaveInterval
+	if method == mcpInitialized && !session.Initialized {
+		session.Initialized = true
+		save = true
+	}
+	if save {
+		session.savedAt = now
+	}
+	return session.MCPSession, save
+}
+
+// end stops holding the session and returns it marked as ended
+func (s *mcpSessionStore) end(id, reason string, now time.Time) (types.MCPSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return types.MCPSession{}, false
+	}
+	delete(s.sessions, id)
+	session.EndedAt = &now
+	session.EndReason = reason
+	session.Status = types.MCPSessionEnded
+	return session.MCPSession, true
+}
+
+// checkMCPSession follows the session of a call on a route tracking MCP
+// sessions, refusing calls outside a known, active one when the route requires it
+func (g *Gateway) checkMCPSession(r *http.Request, route *config.Route, client *config.APIKey, method string, body []byte) (*mcpCall, *rejection) {
+	if route.MCP == nil {
+		return nil, nil
+	}
+	audit := AuditContextFrom(r.Context())
+	call := &mcpCall{sessionID: r.Header.Get(types.MCPSessionHeader), client: client, ip: getClientIP(r)}
+
+	if method == mcpInitialize && call.sessionID == "" {
+		var req struct {
+			Params mcpInitializeParams `json:"params"`
+		}
+		json.Unmarshal(body, &req)
+		call.initialize = &req.Params
+		audit.Set(metaMCP, "initialize")
+		return call, nil
+	}
+	if call.sessionID == "" {
+		if !route.MCP.RequireSession {
+			return call, nil
+		}
+		audit.Set(metaMCP, "missing")
+		return call, &rejection{mcpSessionRequiredCode, "Bad Request", types.MCPSessionHeader + " header is required", http.StatusBadRequest}
+	}
+
+	now := g.now()
+	if reason := g.mcpSessionProblem(call.sessionID, route, client, now); reason != "" {
+		audit.Set(metaMCP, "unknown")
+		if !route.MCP.RequireSession {
+			return call, nil
+		}
+		return call, &rejection{mcpSessionNotFoundCode, "Session not found", reason, http.StatusNotFound}
+	}
+
+	call.tracked = true
+	audit.Set(metaMCP, "session")
+	if session, save := g.mcpSessions.touch(call.sessionID, method, route.MCP.SessionTTLDuration(), now); save {
+		g.saveMCPSession(&session)
+	}
+	return call, nil
+}
+
+// mcpSessionProblem explains why a call may not use the session, empty when it may
+func (g *Gateway) mcpSessionProblem(id string, route *config.Route, client *config.APIKey, now time.Time) string {
+	session, ok := g.mcpSessions.get(id)
+	if !ok && g.db != nil {
+		stored, err := g.db.GetMCPSession(id)
+		if err != nil && !errors.Is(err, database.ErrMCPSessionNotFound) {
+			log.Printf("Failed to look up MCP session %s: %v", id, err)
+		}
+		if stored != nil {
+			session, ok = *stored, true
+			if stored.StatusAt(now) == types.MCPSessionActive {
+				g.mcpSessions.put(session, now)
+			}
+		}
+	}
+
+	apiKey := ""
+	if client != nil {
+		apiKey = client.Name
+	}
+	switch {
+	case !ok || session.Route != route.Name:
+		return fmt.Sprintf("unknown MCP session %s", id)
+	case session.APIKey != apiKey:
+		// Sessions of other clients look unknown, so they cannot be probed for
+		return fmt.Sprintf("unknown MCP session %s", id)
+	case session.StatusAt(now) == types.MCPSessionEnded:
+		return fmt.Sprintf("MCP session %s was %s", id, session.EndReason)
+	case session.StatusAt(now) == types.MCPSessionExpired:
+		return fmt.Sprintf("MCP session %s expired", id)
+	}
+	return ""
+}
+
+// observeMCP starts and ends sessions as the upstream answers calls on routes tracking them
+func (g *Gateway) observeMCP(ctx context.Context, r *http.Request, call *proxyCall, resp *http.Response, body []byte) {
+	m := call.mcp
+	if m == nil {
+		return
+	}
+	audit := AuditContextFrom(ctx)
+	now := g.now()
+
+	switch {
+	case m.initialize != nil:
+		// Stateless servers answer without a session
+		id := resp.Header.Get(types.MCPSessionHeader)
+		if id == "" || resp.StatusCode != http.StatusOK {
+			return
+		}
+		session := types.MCPSession{
+			ID:                  id,
+			Route:               call.route.Name,
+			Status:              types.MCPSessionActive,
+			ProtocolVersion:     m.initialize.ProtocolVersion,
+			ClientName:          m.initialize.ClientInfo.Name,
+			ClientVersion:       m.initialize.ClientInfo.Version,
+			IPAddress:           m.ip,
+			InitializeRequestID: call.requestID,
+			CreatedAt:           now,
+			LastSeenAt:          now,
+			ExpiresAt:           now.Add(call.route.MCP.SessionTTLDuration()),
+		}
+		var result mcpInitializeResult
+		if json.Unmarshal(bytes.TrimSpace(types.UnwrapSSE(body)), &result) == nil && result.Result != nil {
+			// The server may answer with an older version than the client asked for
+			if result.Result.ProtocolVersion != "" {
+				session.ProtocolVersion = result.Result.ProtocolVersion
+			}
+			session.ServerName = result.Result.ServerInfo.Name
+			session.ServerVersion = result.Result.ServerInfo.Version
+		}
+		if m.client != nil {
+			session.APIKey, session.Tenant = m.client.Name, m.client.Tenant
+		}
+		g.mcpSessions.put(session, now)
+		g.saveMCPSession(&session)
+		audit.Set(metaMCPSession, id)
+		g.debugf("MCP session %s started on route %s by %s %s, protocol %s", id, session.Route,
+			session.ClientName, session.ClientVersion, session.ProtocolVersion)
+
+	case !m.tracked:
+	case r.Method == http.MethodDelete && resp.StatusCode < 300:
+		g.endMCPSession(ctx, m.sessionID, types.MCPEndDeleted, now)
+	case resp.StatusCode == http.StatusNotFound:
+		g.endMCPSession(ctx, m.sessionID, types.MCPEndTerminated, now)
+	}
+}
+
+// endMCPSession marks a session as ended for reason
+func (g *Gateway) endMCPSession(ctx context.Context, id, reason string, now time.Time) {
+	session, ok := g.mcpSessions.end(id, reason, now)
+	if !ok {
+		return
+	}
+	g.saveMCPSession(&session)
+	AuditContextFrom(ctx).Set(metaMCP, reason)
+	g.debugf("MCP session %s %s after %d calls", id, reason, session.Calls)
+}
+
+// saveMCPSession writes a session to SQLite when the gateway has it
+func (g *Gateway) saveMCPSession(session *types.MCPSession) {
+	if g.db == nil {
+		return
+	}
+	if err := g.db.SaveMCPSession(session); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// isEventStream reports whether the upstream answered with a server-sent event stream
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// streamEvents passes an upstream event stream on to the client as it arrives
+// instead of buffering it, so MCP servers can send progress and requests of
+// their own before the response. The audit response, with the first
+// maxStreamCapture bytes of the stream, is recorded once the stream ends.
+// Streams are not signed since their body is unknown when headers are sent.
+func (g *Gateway) streamEvents(ctx context.Context, w http.ResponseWriter, r *http.Request, call *proxyCall, resp *http.Response, upstreamStart time.Time, servedBy string) {
+	copyResponseHeaders(w, resp, call.headers, -1)
+	w.Header().Set(types.RequestIDHeader, call.requestID)
+	w.WriteHeader(resp.StatusCode)
+	flusher := http.NewResponseController(w)
+	flusher.Flush()
+
+	var captured bytes.Buffer
+	var streamed int64
+	sum := sha256.New()
+	var streamErr error
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if room := maxStreamCapture - captured.Len(); room > 0 {
+				captured.Write(buf[:min(n, room)])
+			}
+			streamed += int64(n)
+			sum.Write(buf[:n])
+			if _, err := w.Write(buf[:n]); err != nil {
+				streamErr = fmt.Errorf("client went away: %w", err)
+				break
+			}
+			flusher.Flush()
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			streamErr = err
+			break
+		}
+	}
+	timing := call.timer.timing(g.now())
+
+	auditResponse := &types.AuditResponse{
+		RequestID:    call.requestID,
+		Timestamp:    g.now(),
+		StatusCode:   resp.StatusCode,
+		ProcessTime:  g.since(call.startTime).Milliseconds(),
+		QueueTime:    call.queueTime.Milliseconds(),
+		UpstreamTime: g.since(upstreamStart).Milliseconds(),
+		ContentType:  resp.Header.Get("Content-Type"),
+		ServedBy:     servedBy,
+		Timing:       timing,
+	}
+	auditResponse.Debug = call.debug.record(resp, timing)
+	if limit := g.responseCaptureLimit(call.route); streamed > int64(captured.Len()) || (limit > 0 && streamed > limit) {
+		kept := captured.Bytes()
+		if limit > 0 && int64(len(kept)) > limit {
+			kept = kept[:limit]
+		}
+		g.auditCappedResponse(auditResponse, call, kept, sum, streamed)
+	} else {
+		g.auditResponseBody(auditResponse, call, captured.Bytes())
+	}
+	if streamErr != nil {
+		auditResponse.Error = fmt.Sprintf("event stream ended early: %v", streamErr)
+	}
+	g.debugf("Upstream streamed %d bytes of events for %s (%s) in %dms", streamed, call.requestID, call.method, auditResponse.UpstreamTime)
+
+	g.observeMCP(ctx, r, call, resp, captured.Bytes())
+	g.recordResponse(auditResponse)
+}
+
+// GetMCPSessions lists the MCP sessions seen on routes tracking them, most
+// recently used first. Query params: route, api_key, status (active, expired
+// or ended), limit and offset.
+func (g *Gateway) GetMCPSessions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, offset := 100, 0
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	filter := types.MCPSessionFilter{Route: query.Get("route"), APIKey: query.Get("api_key"), Status: query.Get("status")}
+	switch filter.Status {
+	case "", types.MCPSessionActive, types.MCPSessionExpired, types.MCPSessionEnded:
+	default:
+		http.Error(w, "status must be active, expired or ended", http.StatusBadRequest)
+		return
+	}
+
+	sessions, err := g.db.ListMCPSessions(filter, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve MCP sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for i := range sessions {
+		g.freshMCPSession(&sessions[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.MCPSessionsResponse{Sessions: sessions, Limit: limit, Offset: offset, Count: len(sessions)})
+}
+
+// GetMCPSession returns one MCP session with the calls made in it
+func (g *Gateway) GetMCPSession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	session, err := g.db.GetMCPSession(id)
+	if errors.Is(err, database.ErrMCPSessionNotFound) {
+		http.Error(w, fmt.Sprintf("MCP session %s not found", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve MCP session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	g.freshMCPSession(session)
+
+	calls, err := g.db.SearchAuditLogs(types.AuditLogFilter{Tags: map[string]string{types.MCPSessionTag: id}}, limit, offset)
+	if calls == nil {
+		calls = []types.AuditLog{}
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve calls of MCP session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := previewLogs(r, calls); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.MCPSessionResponse{Session: *session, Calls: calls, Limit: limit, Offset: offset})
+}
+
+// freshMCPSession replaces the activity of a stored session with the one held
+// in memory, which is written to SQLite only every mcpSaveInterval
+func (g *Gateway) freshMCPSession(session *types.MCPSession) {
+	if held, ok := g.mcpSessions.get(session.ID); ok && session.EndedAt == nil {
+		*session = held
+		session.Status = held.StatusAt(g.now())
+	}
+}
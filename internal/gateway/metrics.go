@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// openMetricsType is the content type of the OpenMetrics exposition, which Prometheus
+// asks for to scrape exemplars
+const openMetricsType = "application/openmetrics-text"
+
+// metricsBuffer collects a metrics exposition in the Prometheus text or OpenMetrics format
+type metricsBuffer struct {
+	bytes.Buffer
+	openMetrics bool
+}
+
+// GetMetrics exposes the health of the audit pipeline in the Prometheus text
+// format: queue depths, insert latency and failures per backend, dropped
+// events and the size of the database on disk, along with the calls in flight
+// and queued per method and target, and the time taken to answer calls per
+// route. Scrapers accepting OpenMetrics also get exemplars with the request ID
+// of the latest call in each latency bucket.
+func (g *Gateway) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	status := g.PipelineStatus()
+	b := metricsBuffer{openMetrics: strings.Contains(r.Header.Get("Accept"), openMetricsType)}
+
+	metric(&b, "golf_audit_queue_depth", "gauge", "Audit events waiting in a sink queue")
+	for _, q := range status.Queues {
+		fmt.Fprintf(&b, "golf_audit_queue_depth{queue=%q} %d\n", q.Name, q.Depth)
+	}
+	metric(&b, "golf_audit_queue_capacity", "gauge", "Size of a sink queue")
+	for _, q := range status.Queues {
+		fmt.Fprintf(&b, "golf_audit_queue_capacity{queue=%q} %d\n", q.Name, q.Capacity)
+	}
+	metric(&b, "golf_audit_queue_dropped_total", "counter", "Audit events discarded because a sink queue was full")
+	for _, q := range status.Queues {
+		fmt.Fprintf(&b, "golf_audit_queue_dropped_total{queue=%q} %d\n", q.Name, q.Dropped)
+	}
+	metric(&b, "golf_audit_queue_overflowed_total", "counter", "Audit events sent to the dead-letter table because a sink queue was full")
+	for _, q := range status.Queues {
+		fmt.Fprintf(&b, "golf_audit_queue_overflowed_total{queue=%q} %d\n", q.Name, q.Overflowed)
+	}
+
+	names, backends := g.pipeline.snapshot()
+	metric(&b, "golf_audit_insert_duration_seconds", "histogram", "Time taken to write an audit event to a backend")
+	for i, backend := range backends {
+		var cumulative int64
+		for j, bound := range insertBuckets {
+			cumulative += backend.buckets[j]
+			fmt.Fprintf(&b, "golf_audit_insert_duration_seconds_bucket{backend=%q,le=%q} %d\n",
+				names[i], strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "golf_audit_insert_duration_seconds_bucket{backend=%q,le=\"+Inf\"} %d\n", names[i], backend.inserts)
+		fmt.Fprintf(&b, "golf_audit_insert_duration_seconds_sum{backend=%q} %g\n", names[i], backend.total.Seconds())
+		fmt.Fprintf(&b, "golf_audit_insert_duration_seconds_count{backend=%q} %d\n", names[i], backend.inserts)
+	}
+	metric(&b, "golf_audit_insert_failures_total", "counter", "Audit writes a backend rejected, e.g. Tinybird errors")
+	for i, backend := range backends {
+		fmt.Fprintf(&b, "golf_audit_insert_failures_total{backend=%q} %d\n", names[i], backend.failures)
+	}
+
+	if g.tinybirdDB != nil {
+		oversized := g.tinybirdDB.Oversized()
+		metric(&b, "golf_tinybird_oversized_events_total", "counter", "Tinybird events above the row size cap, fitted by compressing or by truncating their payloads")
+		fmt.Fprintf(&b, "golf_tinybird_oversized_events_total{action=\"compressed\"} %d\n", oversized.Compressed)
+		fmt.Fprintf(&b, "golf_tinybird_oversized_events_total{action=\"truncated\"} %d\n", oversized.Truncated)
+	}
+
+	metric(&b, "golf_audit_dropped_events_total", "counter", "Audit events lost from full queues and the spool")
+	fmt.Fprintf(&b, "golf_audit_dropped_events_total %d\n", status.DroppedEvents)
+	if g.spool != nil {
+		storage := g.spool.Status()
+		degraded := 0
+		if storage.Degraded {
+			degraded = 1
+		}
+		metric(&b, "golf_audit_spool_pending", "gauge", "Audit events spooled while the database is unavailable")
+		fmt.Fprintf(&b, "golf_audit_spool_pending %d\n", storage.Pending)
+		metric(&b, "golf_audit_storage_degraded", "gauge", "1 while audit events are being spooled")
+		fmt.Fprintf(&b, "golf_audit_storage_degraded %d\n", degraded)
+	}
+	if g.db != nil {
+		metric(&b, "golf_audit_database_bytes", "gauge", "Size of the active SQLite file")
+		fmt.Fprintf(&b, "golf_audit_database_bytes %d\n", status.DatabaseBytes)
+		metric(&b, "golf_audit_wal_bytes", "gauge", "Size of the SQLite write-ahead log")
+		fmt.Fprintf(&b, "golf_audit_wal_bytes %d\n", status.WALBytes)
+	}
+
+	targets := g.targetStatuses()
+	if len(targets) > 0 {
+		metric(&b, "golf_target_in_flight", "gauge", "Calls holding a slot of a target with max_in_flight")
+		for _, t := range targets {
+			fmt.Fprintf(&b, "golf_target_in_flight{route=%q,target=%q} %d\n", t.Route, t.Target, t.InFlight)
+		}
+		metric(&b, "golf_target_queued", "gauge", "Calls waiting for a free slot of a target with max_in_flight")
+		for _, t := range targets {
+			fmt.Fprintf(&b, "golf_target_queued{route=%q,target=%q} %d\n", t.Route, t.Target, t.Queued)
+		}
+	}
+
+	methods := g.concurrency.snapshot()
+	names = make([]string, 0, len(methods))
+	for method := range methods {
+		names = append(names, method)
+	}
+	sort.Strings(names)
+	metric(&b, "golf_method_in_flight", "gauge", "Calls of a method being forwarded upstream")
+	for _, method := range names {
+		fmt.Fprintf(&b, "golf_method_in_flight{method=%q} %d\n", method, methods[method].InFlight)
+	}
+	metric(&b, "golf_method_peak_in_flight", "gauge", "Highest number of calls of a method forwarded upstream at once since the gateway started")
+	for _, method := range names {
+		fmt.Fprintf(&b, "golf_method_peak_in_flight{method=%q} %d\n", method, methods[method].PeakInFlight)
+	}
+	metric(&b, "golf_method_queued", "gauge", "Calls of a method waiting for a free upstream slot")
+	for _, method := range names {
+		fmt.Fprintf(&b, "golf_method_queued{method=%q} %d\n", method, methods[method].Queued)
+	}
+	metric(&b, "golf_method_peak_queued", "gauge", "Highest number of calls of a method waiting for a free upstream slot at once since the gateway started")
+	for _, method := range names {
+		fmt.Fprintf(&b, "golf_method_peak_queued{method=%q} %d\n", method, methods[method].PeakQueued)
+	}
+
+	routes, histograms := g.latency.snapshot()
+	metric(&b, "golf_call_duration_seconds", "histogram", "Time taken to answer a call, by route")
+	for i, h := range histograms {
+		var cumulative int64
+		for j := range h.buckets {
+			cumulative += h.buckets[j]
+			le := "+Inf"
+			if j < len(callBuckets) {
+				le = strconv.FormatFloat(callBuckets[j], 'g', -1, 64)
+			}
+			fmt.Fprintf(&b, "golf_call_duration_seconds_bucket{route=%q,le=%q} %d", routes[i], le, cumulative)
+			if e := h.exemplars[j]; e != nil && b.openMetrics {
+				fmt.Fprintf(&b, " # {request_id=%q} %g %.3f", e.requestID, e.seconds, float64(e.at.UnixMilli())/1000)
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "golf_call_duration_seconds_sum{route=%q} %g\n", routes[i], h.sum)
+		fmt.Fprintf(&b, "golf_call_duration_seconds_count{route=%q} %d\n", routes[i], h.count)
+	}
+
+	if b.openMetrics {
+		b.WriteString("# EOF\n")
+		w.Header().Set("Content-Type", openMetricsType+"; version=1.0.0; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	}
+	w.Write(b.Bytes())
+}
+
+// metric writes the HELP and TYPE lines of a metric family. OpenMetrics names
+// counter families without the _total suffix of their samples.
+func metric(b *metricsBuffer, name, kind, help string) {
+	if b.openMetrics && kind == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
@@ -0,0 +1,202 @@
+package gateway
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats the audit list endpoints can answer in
+const (
+	formatJSON  = "json"
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+// formatMediaTypes maps the media types of the Accept header to formats
+var formatMediaTypes = map[string]string{
+	"application/json":        formatJSON,
+	"text/csv":                formatCSV,
+	"application/jsonl":       formatJSONL,
+	"application/x-jsonlines": formatJSONL,
+	"application/x-ndjson":    formatJSONL,
+	"*/*":                     formatJSON,
+	"application/*":           formatJSON,
+	"text/*":                  formatCSV,
+}
+
+// negotiateFormat picks the format of an audit list from ?format= or the
+// Accept header, JSON when neither asks for anything. It reports false when
+// no acceptable format is supported.
+func negotiateFormat(r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case formatJSON, formatCSV, formatJSONL:
+			return format, true
+		case "ndjson":
+			return formatJSONL, true
+		}
+		return "", false
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+	type candidate struct {
+		format string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		format, ok := formatMediaTypes[strings.ToLower(strings.TrimSpace(mediaType))]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{format, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	// Ties keep the order of the header
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].format, true
+}
+
+// notAcceptable answers a request whose Accept header names no supported format
+func notAcceptable(w http.ResponseWriter) {
+	http.Error(w, "Supported formats are application/json, text/csv and application/jsonl", http.StatusNotAcceptable)
+}
+
+// writeRows writes a slice of audit rows as CSV with a header line, or as
+// JSON Lines with one object per line
+func writeRows(w http.ResponseWriter, format string, rows interface{}) {
+	w.Header().Set("Vary", "Accept")
+	items := reflect.ValueOf(rows)
+
+	if format == formatJSONL {
+		w.Header().Set("Content-Type", "application/jsonl")
+		encoder := json.NewEncoder(w)
+		for i := 0; i < items.Len(); i++ {
+			if err := encoder.Encode(items.Index(i).Interface()); err != nil {
+				log.Printf("Failed to write JSON Lines row: %v", err)
+				return
+			}
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	columns := csvColumns(items.Type().Elem())
+	cw := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	cw.Write(header)
+	record := make([]string, len(columns))
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		for j, c := range columns {
+			record[j] = csvCell(item.FieldByIndex(c.index))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Failed to write CSV rows: %v", err)
+	}
+}
+
+// csvColumn is an exported field of a row type, named by its JSON key
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvColumns lists the fields of t in declaration order, flattening embedded
+// structs the way encoding/json does, so columns follow the JSON keys
+func csvColumns(t reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for _, c := range csvColumns(field.Type) {
+				columns = append(columns, csvColumn{c.name, append([]int{i}, c.index...)})
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name, []int{i}})
+	}
+	return columns
+}
+
+// csvCell formats a field value; nested values are written as JSON
+func csvCell(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	case v.Type() == rawMessageType:
+		return string(v.Bytes())
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Map, reflect.Slice, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprintf("%v", v.Interface())
+	}
+	return string(data)
+}
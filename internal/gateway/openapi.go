@@ -0,0 +1,426 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// apiOperation describes one management endpoint in the OpenAPI document
+type apiOperation struct {
+	method      string
+	path        string
+	summary     string
+	params      []apiParam
+	request     interface{} // Zero value of the request body type, if any
+	requestType string      // Request body content type, defaults to application/json
+	response    interface{} // Zero value of the response type
+	contentType string      // Defaults to application/json
+}
+
+// apiParam describes a query parameter
+type apiParam struct {
+	name        string
+	typ         string
+	description string
+}
+
+var paginationParams = []apiParam{
+	{"limit", "integer", "Maximum number of rows (1-1000, default 50)"},
+	{"offset", "integer", "Number of rows to skip"},
+}
+
+// formatParam selects the format of audit lists, also negotiable with the Accept header
+var formatParam = apiParam{"format", "string", "json (default), csv or jsonl; overrides the Accept header"}
+
+// managementAPI lists the documented management endpoints
+func managementAPI() []apiOperation {
+	return []apiOperation{
+		{
+			method: "get", path: "/audit/logs", summary: "Combined request/response audit logs",
+			params: append([]apiParam{
+				{"method", "string", "Filter by JSON-RPC method"},
+				{"tag.{name}", "string", "Filter by a tag value, extracted from the body or sent by the caller in X-Golf-Tags"},
+				{"header.{name}", "string", "Filter by the value of a request header listed in indexed_headers"},
+				{"ext.{path}", "string", "Filter by the value of a request extension member listed in extensions.index, e.g. ext.meta.client"},
+				{"body_hash", "string", "Only requests whose canonical body hashes to this value"},
+				formatParam,
+			}, append(previewParams, paginationParams...)...),
+			response: types.AuditLogsResponse{},
+		},
+		{
+			method: "get", path: "/audit/logs/since", summary: "Audit logs created or completed after a cursor",
+			params: []apiParam{
+				{"cursor", "string", "next_cursor from the previous call"},
+				{"since", "string", "RFC3339 start time when no cursor is given"},
+				{"limit", "integer", "Maximum rows per list (1-1000, default 500)"},
+				previewParams[0], previewParams[1],
+			},
+			response: types.AuditLogsSinceResponse{},
+		},
+		{method: "get", path: "/audit/logs/{request_id}", summary: "Audit log of a single request", response: types.AuditLog{}},
+		{method: "get", path: "/audit/trace/{request_id}", summary: "Tree of nested calls containing a request, linked through the correlation config", response: types.TraceResponse{}},
+		{
+			method: "get", path: "/audit/mcp/sessions", summary: "MCP sessions of routes tracking them, most recently used first",
+			params: []apiParam{
+				{"route", "string", "Only sessions of this route"},
+				{"api_key", "string", "Only sessions of this API key name"},
+				{"status", "string", "active, expired or ended"},
+				{"limit", "integer", "Maximum number of sessions (default 100)"},
+				{"offset", "integer", "Number of sessions to skip"},
+			},
+			response: types.MCPSessionsResponse{},
+		},
+		{method: "get", path: "/audit/mcp/sessions/{id}", summary: "One MCP session with the calls made in it", params: append(previewParams, paginationParams...), response: types.MCPSessionResponse{}},
+		{
+			method: "patch", path: "/audit/logs/{request_id}", summary: "Annotate or soft-delete an audit log (operator role required)",
+			request: types.AnnotationRequest{}, response: types.Annotation{},
+		},
+		{method: "get", path: "/audit/requests", summary: "Audit requests", params: append([]apiParam{formatParam}, paginationParams...), response: types.AuditRequestsResponse{}},
+		{method: "get", path: "/audit/responses", summary: "Audit responses", params: append([]apiParam{formatParam}, paginationParams...), response: types.AuditResponsesResponse{}},
+		{method: "get", path: "/audit/orphaned", summary: "Requests without a response", params: paginationParams, response: types.OrphanedRequestsResponse{}},
+		{method: "get", path: "/audit/stats", summary: "Audit statistics", response: types.Stats{}},
+		{
+			method: "get", path: "/audit/stats/heatmap", summary: "Latency heatmap (time bucket x latency bucket)",
+			params: []apiParam{
+				{"window", "string", "Time range ending now, e.g. 6h (default 1h)"},
+				{"interval", "string", "Time bucket width (default window/60)"},
+				{"method", "string", "Only count this JSON-RPC method"},
+				{"buckets", "string", "Comma-separated latency bounds in milliseconds"},
+			},
+			response: types.HeatmapResponse{},
+		},
+		{
+			method: "get", path: "/audit/stats/duplicates", summary: "Identical responses per method and cache candidates",
+			params: []apiParam{
+				{"window", "string", "Time range ending now, e.g. 6h (default 24h)"},
+				{"min_calls", "integer", "Fewest calls of a cache candidate (default 20)"},
+				{"min_ratio", "number", "Lowest share of duplicate calls of a cache candidate (default 0.5)"},
+			},
+			response: types.ResponseDuplicatesResponse{},
+		},
+		{
+			method: "get", path: "/audit/stats/params", summary: "Param cardinality and most frequent values per method",
+			params: []apiParam{
+				{"window", "string", "Time range ending now, e.g. 6h (default 24h)"},
+				{"method", "string", "Only report this JSON-RPC method"},
+				{"top", "integer", "Most frequent values per param (default 10, max 100)"},
+				{"limit", "integer", "Newest calls read (default 10000, max 100000)"},
+			},
+			response: types.ParamStatsResponse{},
+		},
+		{
+			method: "get", path: "/audit/compare", summary: "Method mix, error rate and latency of two time windows, e.g. before and after a deploy",
+			params: []apiParam{
+				{"windowA", "string", "<start>/<end> or <start>/<duration> with RFC3339 times, or a duration for the window ending now"},
+				{"windowB", "string", "Second window, in the same formats as windowA"},
+			},
+			response: types.CompareResponse{},
+		},
+		{
+			method: "get", path: "/audit/usage", summary: "Quota consumption per API key and tenant",
+			params:   []apiParam{{"key", "string", "Only report this API key name"}},
+			response: types.UsageResponse{},
+		},
+		{
+			method: "get", path: "/audit/slow", summary: "Calls exceeding their slow threshold, newest first",
+			params:   append([]apiParam{{"method", "string", "Filter by JSON-RPC method"}}, append(previewParams, paginationParams...)...),
+			response: types.AuditLogsResponse{},
+		},
+		{
+			method: "get", path: "/audit/clients", summary: "Distinct clients by fingerprint (IP, user agent, header names and API key)",
+			params: []apiParam{
+				{"unidentified", "boolean", "Only clients calling without an API key"},
+				{"limit", "integer", "Maximum number of clients (default 100)"},
+				{"offset", "integer", "Number of clients to skip"},
+			},
+			response: types.SeenClientsResponse{},
+		},
+		{method: "get", path: "/audit/pii", summary: "Methods whose requests or responses were flagged for likely PII", response: types.PIIReport{}},
+		{
+			method: "get", path: "/audit/schema-drift", summary: "How upstream results differed from the registered response schemas",
+			params: []apiParam{
+				{"window", "string", "Differences seen within this duration (default 24h)"},
+				{"method", "string", "Only this method"},
+			},
+			response: types.SchemaDriftResponse{},
+		},
+		{method: "get", path: "/audit/files", summary: "Files of a rotating SQLite audit database", response: types.DatabaseFilesResponse{}},
+		{method: "get", path: "/audit/slo", summary: "Latency objective compliance and burn rates", response: types.SLOResponse{}},
+		{
+			method: "get", path: "/audit/export", summary: "Audit records of one table in id order as NDJSON or Parquet, resumable with after_id",
+			params: []apiParam{
+				{"table", "string", "requests (default) or responses"},
+				{"after_id", "integer", "Only rows with a greater id"},
+				{"until_id", "integer", "Only rows up to this id"},
+				{"limit", "integer", "Maximum number of rows"},
+				{"format", "string", "ndjson (default) or parquet"},
+				{"anonymize", "boolean", "Replace client IPs, fingerprints, key names, tenants and configured fields with stable pseudonyms"},
+			},
+			response: types.AuditRecord{}, contentType: "application/x-ndjson",
+		},
+		{
+			method: "get", path: "/audit/export/manifest", summary: "Id-range chunks covering a full export",
+			params: []apiParam{
+				{"chunk_size", "integer", "Ids per chunk (default 100000)"},
+				{"anonymize", "boolean", "Chunk URLs request anonymized exports"},
+			},
+			response: types.ExportManifest{},
+		},
+		{
+			method: "post", path: "/audit/import", summary: "Merge NDJSON audit records, deduplicated on request_id (admin)",
+			request: types.AuditRecord{}, requestType: "application/x-ndjson", response: types.ImportResponse{},
+		},
+		{method: "get", path: "/health", summary: "Health check", response: types.HealthResponse{}},
+		{method: "get", path: "/health/live", summary: "Liveness probe, 200 while the gateway serves requests"},
+		{method: "get", path: "/health/ready", summary: "Readiness probe, 503 until the database and targets were reached on startup", response: types.HealthResponse{}},
+		{
+			method: "get", path: "/status", summary: "Public uptime, upstream availability and incident summary",
+			params:   []apiParam{{"format", "string", "html for an embeddable page (default JSON, or HTML for browsers)"}},
+			response: types.StatusPage{},
+		},
+		{method: "get", path: "/openrpc/methods", summary: "Methods of the target's OpenRPC document with audited call counts", response: types.MethodCatalogResponse{}},
+		{method: "post", path: "/grafana/search", summary: "Grafana SimpleJSON metric targets", request: types.GrafanaSearchRequest{}, response: []string{}},
+		{method: "post", path: "/grafana/query", summary: "Grafana SimpleJSON time series", request: types.GrafanaQueryRequest{}, response: []types.GrafanaSeries{}},
+		{method: "post", path: "/grafana/annotations", summary: "Grafana SimpleJSON annotations from annotated audit entries", request: types.GrafanaAnnotationRequest{}, response: []types.GrafanaAnnotation{}},
+		{
+			method: "get", path: "/billing/invoices", summary: "Priced monthly usage of every API key (calls, body bytes, upstream compute)",
+			params: []apiParam{
+				{"month", "string", "YYYY-MM in UTC (default current month)"},
+				{"format", "string", "json (default) or csv with one row per invoice line"},
+			},
+			response: types.InvoicesResponse{},
+		},
+		{
+			method: "get", path: "/billing/invoices/{api_key}", summary: "Monthly invoice of one API key",
+			params: []apiParam{
+				{"month", "string", "YYYY-MM in UTC (default current month)"},
+				{"format", "string", "json (default) or csv"},
+			},
+			response: types.Invoice{},
+		},
+		{
+			method: "get", path: "/tenant/logs", summary: "Audit logs of the caller's tenant, or any tenant with the admin role (operator role required)",
+			params:   append([]apiParam{{"tenant", "string", "Tenant to show (admin only)"}, {"method", "string", "Filter by JSON-RPC method"}}, append(previewParams, paginationParams...)...),
+			response: types.AuditLogsResponse{},
+		},
+		{
+			method: "get", path: "/tenant/stats", summary: "Traffic summary of the caller's tenant, or all tenants with the admin role",
+			params:   []apiParam{{"tenant", "string", "Tenant to show (admin only)"}},
+			response: types.TenantStatsResponse{},
+		},
+		{method: "get", path: "/admin/clients", summary: "Database-managed API clients", response: types.ClientsResponse{}},
+		{method: "post", path: "/admin/clients", summary: "Onboard an API client", request: types.ClientRequest{}, response: types.ClientResponse{}},
+		{method: "get", path: "/admin/clients/{name}", summary: "API client", response: types.Client{}},
+		{method: "put", path: "/admin/clients/{name}", summary: "Update an API client policy or rotate its key", request: types.ClientRequest{}, response: types.ClientResponse{}},
+		{method: "delete", path: "/admin/clients/{name}", summary: "Remove an API client"},
+		{method: "get", path: "/audit/dashboards", summary: "Dashboards defined by operators", response: types.DashboardsResponse{}},
+		{method: "get", path: "/audit/dashboards/{name}", summary: "Dashboard layout", response: types.Dashboard{}},
+		{method: "put", path: "/audit/dashboards/{name}", summary: "Create or replace a dashboard, shown at /dashboards/{name}", request: types.Dashboard{}, response: types.Dashboard{}},
+		{method: "delete", path: "/audit/dashboards/{name}", summary: "Remove a dashboard"},
+		{method: "post", path: "/admin/cache/invalidate", summary: "Drop cached responses matching a method, params and tenant", request: types.CacheInvalidateRequest{}, response: types.CacheInvalidateResponse{}},
+		{
+			method: "get", path: "/admin/tinybird/deadletter", summary: "Tinybird events that could not be delivered, redriven with the redrive-tinybird command",
+			params: []apiParam{
+				{"datasource", "string", "Only events of this datasource, audit_requests or audit_responses"},
+				{"limit", "integer", "Maximum number of events (default 100)"},
+				{"offset", "integer", "Number of events to skip"},
+			},
+			response: types.DeadLettersResponse{},
+		},
+		{method: "get", path: "/admin/debug", summary: "Log level and debug capture", response: types.DebugSettings{}},
+		{method: "put", path: "/admin/debug", summary: "Change the log level or debug capture without a restart", request: types.DebugSettings{}, response: types.DebugSettings{}},
+		{
+			method: "get", path: "/admin/actions", summary: "Privileged management calls: changes and admin endpoint use",
+			params: []apiParam{
+				{"actor", "string", "Only calls by this API key name, or admin-token"},
+				{"limit", "integer", "Maximum number of actions (default 100)"},
+				{"offset", "integer", "Number of actions to skip"},
+			},
+			response: types.AdminActionsResponse{},
+		},
+		{method: "get", path: "/admin/capture", summary: "Active burst captures", response: types.BurstCapturesResponse{}},
+		{
+			method: "post", path: "/admin/capture/start", summary: "Record matching calls with full bodies and debug timing for a while, e.g. during an incident",
+			params: []apiParam{
+				{"duration", "string", "How long the capture lasts, up to 24h (default 10m)"},
+				{"filter", "string", "Comma-separated method, route, api_key, tenant or ip terms, e.g. method:getUserInfo; a trailing * matches a prefix"},
+			},
+			response: types.BurstCapture{},
+		},
+		{
+			method: "post", path: "/admin/capture/stop", summary: "End a burst capture early",
+			params: []apiParam{{"id", "string", "Capture to end, all of them when omitted"}},
+		},
+		{
+			method: "get", path: "/auth/login", summary: "Sign in through the identity provider, redirecting to its login page",
+			params: []apiParam{{"return", "string", "Gateway page to return to after signing in (default /)"}},
+		},
+		{method: "get", path: "/auth/me", summary: "User signed in with the session cookie", response: types.AuthSession{}},
+		{method: "post", path: "/auth/logout", summary: "End the session of the signed-in user"},
+	}
+}
+
+// OpenAPI serves an OpenAPI 3 description of the management API
+func (g *Gateway) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildOpenAPI(managementAPI()))
+}
+
+func buildOpenAPI(operations []apiOperation) map[string]interface{} {
+	schemas := make(map[string]interface{})
+	paths := make(map[string]interface{})
+
+	for _, op := range operations {
+		params := make([]interface{}, 0, len(op.params))
+		for _, segment := range strings.Split(op.path, "/") {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				params = append(params, map[string]interface{}{
+					"name":     strings.Trim(segment, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		for _, p := range op.params {
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          "query",
+				"description": p.description,
+				"schema":      map[string]interface{}{"type": p.typ},
+			})
+		}
+
+		contentType := op.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		responses := map[string]interface{}{"204": map[string]interface{}{"description": "No Content"}}
+		if op.response != nil {
+			responses = map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "OK",
+					"content": map[string]interface{}{
+						contentType: map[string]interface{}{"schema": schemaFor(reflect.TypeOf(op.response), schemas)},
+					},
+				},
+			}
+		}
+
+		item, _ := paths[op.path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[op.path] = item
+		}
+		operation := map[string]interface{}{
+			"summary":    op.summary,
+			"parameters": params,
+			"responses":  responses,
+		}
+		if op.request != nil {
+			requestType := op.requestType
+			if requestType == "" {
+				requestType = "application/json"
+			}
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					requestType: map[string]interface{}{"schema": schemaFor(reflect.TypeOf(op.request), schemas)},
+				},
+			}
+		}
+		item[op.method] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "golf JSON-RPC audit gateway management API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaFor derives a JSON schema from a Go type, registering named structs as components
+func schemaFor(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		return schemaFor(t.Elem(), schemas)
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{"description": "Arbitrary JSON"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, done := schemas[t.Name()]; done {
+			return ref
+		}
+		schemas[t.Name()] = map[string]interface{}{} // Placeholder for recursive types
+
+		properties := make(map[string]interface{})
+		var required []string
+		addProperties(t, schemas, properties, &required)
+
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		schemas[t.Name()] = schema
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// addProperties collects the JSON properties of a struct, inlining embedded structs like encoding/json
+func addProperties(t reflect.Type, schemas map[string]interface{}, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addProperties(field.Type, schemas, properties, required)
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
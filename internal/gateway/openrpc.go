@@ -0,0 +1,239 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// JSON-RPC error codes returned when calls are validated against the OpenRPC document
+const (
+	methodNotFoundCode = -32601
+	invalidParamsCode  = -32602
+)
+
+// openRPCMethod is the part of an OpenRPC method object the gateway uses
+type openRPCMethod struct {
+	Name        string              `json:"name"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description"`
+	Deprecated  bool                `json:"deprecated"`
+	Params      []types.MethodParam `json:"params"`
+	Result      *struct {
+		Schema json.RawMessage `json:"schema"`
+	} `json:"result"`
+
+	resultSchema *jsonSchema // Parsed Result.Schema, nil when missing or not understood
+}
+
+// openRPCCatalog is a parsed OpenRPC document
+type openRPCCatalog struct {
+	raw  json.RawMessage
+	info struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+	methods map[string]*openRPCMethod
+}
+
+// parseOpenRPC reads an OpenRPC document
+func parseOpenRPC(data []byte) (*openRPCCatalog, error) {
+	var doc struct {
+		OpenRPC string `json:"openrpc"`
+		Info    struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Methods []openRPCMethod `json:"methods"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenRPC document: %w", err)
+	}
+	if doc.OpenRPC == "" {
+		return nil, fmt.Errorf("failed to parse OpenRPC document: missing openrpc version")
+	}
+
+	catalog := &openRPCCatalog{raw: json.RawMessage(data), methods: make(map[string]*openRPCMethod, len(doc.Methods))}
+	catalog.info = doc.Info
+	for i := range doc.Methods {
+		m := &doc.Methods[i]
+		if m.Result != nil && len(m.Result.Schema) > 0 {
+			var schema jsonSchema
+			if err := json.Unmarshal(m.Result.Schema, &schema); err == nil {
+				m.resultSchema = &schema
+			}
+		}
+		catalog.methods[m.Name] = m
+	}
+	return catalog, nil
+}
+
+// LoadOpenRPC loads the target's OpenRPC document from a file or with rpc.discover
+func (g *Gateway) LoadOpenRPC(cfg *config.OpenRPC) error {
+	if cfg == nil {
+		return nil
+	}
+
+	var data []byte
+	var err error
+	if cfg.Document != "" {
+		data, err = os.ReadFile(cfg.Document)
+		if err != nil {
+			return fmt.Errorf("failed to read OpenRPC document: %w", err)
+		}
+	} else {
+		data, err = g.discoverOpenRPC()
+		if err != nil {
+			return err
+		}
+	}
+
+	catalog, err := parseOpenRPC(data)
+	if err != nil {
+		return err
+	}
+	g.openRPC = catalog
+	g.validateCalls = cfg.Validate
+	return nil
+}
+
+// discoverOpenRPC calls rpc.discover on the first route's target
+func (g *Gateway) discoverOpenRPC() ([]byte, error) {
+	if len(g.routes) == 0 {
+		return nil, fmt.Errorf("failed to discover OpenRPC document: no routes")
+	}
+	route := g.routes[0]
+	upstreamURL, err := route.UpstreamURL(route.Path, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OpenRPC document: %w", err)
+	}
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"rpc.discover"}`)
+	req, err := http.NewRequest(http.MethodPost, upstreamURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rpc.discover request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if route.UpstreamAuth != nil {
+		req.Header.Set(route.UpstreamAuth.HeaderName(), route.UpstreamAuth.HeaderValue())
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call rpc.discover: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rpc.discover response: %w", err)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage     `json:"result"`
+		Error  *types.JSONRPCError `json:"error"`
+	}
+	if err := json.Unmarshal(data, &rpcResp); err != nil {
+		return nil, fmt.Errorf("failed to parse rpc.discover response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("rpc.discover failed: %s", rpcResp.Error.Message)
+	}
+	return rpcResp.Result, nil
+}
+
+// validateCall checks a call against the OpenRPC document. Only the presence
+// of the method and of its required params is checked, not their schemas.
+func (g *Gateway) validateCall(method string, params interface{}) *rejection {
+	if !g.validateCalls || g.openRPC == nil || strings.HasPrefix(method, "rpc.") {
+		return nil
+	}
+
+	m, ok := g.openRPC.methods[method]
+	if !ok {
+		return &rejection{methodNotFoundCode, "Method not found", fmt.Sprintf("method %s is not in the OpenRPC document", method), http.StatusNotFound}
+	}
+
+	var missing []string
+	switch p := params.(type) {
+	case map[string]interface{}:
+		for _, param := range m.Params {
+			if _, ok := p[param.Name]; param.Required && !ok {
+				missing = append(missing, param.Name)
+			}
+		}
+	case []interface{}:
+		for i, param := range m.Params {
+			if param.Required && i >= len(p) {
+				missing = append(missing, param.Name)
+			}
+		}
+	default:
+		for _, param := range m.Params {
+			if param.Required {
+				missing = append(missing, param.Name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return &rejection{invalidParamsCode, "Invalid params", fmt.Sprintf("method %s is missing required params: %s", method, strings.Join(missing, ", ")), http.StatusBadRequest}
+	}
+	return nil
+}
+
+// OpenRPCDocument serves the target's OpenRPC document
+func (g *Gateway) OpenRPCDocument(w http.ResponseWriter, r *http.Request) {
+	if g.openRPC == nil {
+		http.Error(w, "No OpenRPC document configured", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(g.openRPC.raw)
+}
+
+// GetMethodCatalog lists the documented methods with their audited call counts
+func (g *Gateway) GetMethodCatalog(w http.ResponseWriter, r *http.Request) {
+	if g.openRPC == nil {
+		http.Error(w, "No OpenRPC document configured", http.StatusNotFound)
+		return
+	}
+
+	calls := map[string]int{}
+	if stats, err := g.store.GetStats(); err == nil {
+		calls = stats.Methods
+	}
+
+	methods := make([]types.MethodDoc, 0, len(g.openRPC.methods))
+	for _, m := range g.openRPC.methods {
+		doc := types.MethodDoc{
+			Name:        m.Name,
+			Summary:     m.Summary,
+			Description: m.Description,
+			Deprecated:  m.Deprecated,
+			Params:      m.Params,
+			Calls:       calls[m.Name],
+		}
+		if doc.Params == nil {
+			doc.Params = []types.MethodParam{}
+		}
+		methods = append(methods, doc)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.MethodCatalogResponse{
+		Title:   g.openRPC.info.Title,
+		Version: g.openRPC.info.Version,
+		Methods: methods,
+		Count:   len(methods),
+	})
+}
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// StartOrphanResolver closes requests left without a response for longer than
+// grace, checking every interval. They get a synthetic response with failure
+// kind unresolved, so calls lost in a crash stop counting as orphaned and the
+// orphan count only reflects calls that are actually in flight. Calls this
+// gateway is still serving, such as MCP event streams, are never resolved,
+// and grace is raised above the longest upstream deadline of any route.
+func (g *Gateway) StartOrphanResolver(grace, interval time.Duration) {
+	if grace <= 0 || g.db == nil {
+		return
+	}
+	g.orphanStop = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		warned := time.Duration(-1)
+		for {
+			select {
+			case <-g.orphanStop:
+				return
+			case <-ticker.C:
+				// Responses waiting in the spool would be mistaken for lost ones
+				if g.spool != nil && g.spool.Status().Pending > 0 {
+					continue
+				}
+				effective := grace
+				if longest := g.longestCallTime(); longest >= grace {
+					effective = longest + interval
+					if warned != longest {
+						log.Printf("Orphan grace period %s does not exceed the %s a call may take, resolving after %s", grace, longest, effective)
+						warned = longest
+					}
+				}
+				resolved, err := g.resolveOrphans(effective)
+				if err != nil {
+					log.Printf("Failed to resolve orphaned requests: %v", err)
+					continue
+				}
+				if resolved > 0 {
+					log.Printf("Marked %d orphaned requests older than %s as unresolved", resolved, effective)
+				}
+			}
+		}
+	}()
+}
+
+// resolveOrphans resolves the requests made more than grace ago that have no
+// response and are not being served
+func (g *Gateway) resolveOrphans(grace time.Duration) (int64, error) {
+	cutoff := g.now().Add(-grace)
+	// Calls answered since are no longer in flight but their responses
+	// may still be queued for a relaxed batch, so flush after listing them
+	inFlight := g.callsInFlight()
+	g.bus.Flush(relaxedQueue)
+	reason := fmt.Sprintf("no response recorded within %s, the gateway likely stopped before the call completed", grace)
+	return g.db.ResolveOrphans(cutoff, reason, inFlight)
+}
+
+// longestCallTime returns the longest a call of any route may wait for a free
+// upstream slot and then for its answer
+func (g *Gateway) longestCallTime() time.Duration {
+	longest := g.httpClient.Timeout
+	for i := range g.routes {
+		route := &g.routes[i]
+		longest = max(longest, route.QueueTimeoutDuration()+g.callTimeout(route.LongestTimeout()))
+	}
+	return longest
+}
+
+// callsInFlight returns the request IDs of the calls being served
+func (g *Gateway) callsInFlight() []string {
+	var ids []string
+	g.auditContexts.Range(func(key, _ interface{}) bool {
+		ids = append(ids, key.(string))
+		return true
+	})
+	return ids
+}
+
+// StopOrphanResolver stops the background orphan resolution
+func (g *Gateway) StopOrphanResolver() {
+	if g.orphanStop != nil {
+		close(g.orphanStop)
+		g.orphanStop = nil
+	}
+}
@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/clock"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+func TestResolveOrphansCutoff(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), 0)
+	g, db := newTestGateway(t, fake)
+	grace := 5 * time.Minute
+
+	recordCall(t, db, "lost", "", fake.Now().Add(-10*time.Minute), 0, nil)
+	recordCall(t, db, "recent", "", fake.Now().Add(-2*time.Minute), 0, nil)
+	recordCall(t, db, "answered", "", fake.Now().Add(-10*time.Minute), 200, nil)
+	recordCall(t, db, "streaming", "", fake.Now().Add(-10*time.Minute), 0, nil)
+	_, _, done := g.trackAuditContext(httptest.NewRequest("POST", "/", nil), "streaming")
+	defer done()
+
+	resolved, err := g.resolveOrphans(grace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != 1 {
+		t.Fatalf("resolved %d requests, want only the lost one", resolved)
+	}
+	assertFailure(t, g, "lost", types.FailureUnresolved)
+	assertFailure(t, g, "recent", "")
+
+	// Once the grace period of the recent call has passed it is resolved too
+	fake.Advance(3*time.Minute + time.Second)
+	if resolved, err = g.resolveOrphans(grace); err != nil || resolved != 1 {
+		t.Fatalf("resolved %d requests (%v), want the recent one", resolved, err)
+	}
+	assertFailure(t, g, "recent", types.FailureUnresolved)
+	assertFailure(t, g, "streaming", "")
+}
+
+// assertFailure checks the failure kind recorded for a call, "" meaning no response
+func assertFailure(t *testing.T, g *Gateway, requestID, want string) {
+	t.Helper()
+	entry, err := g.db.GetAuditLog(requestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatalf("%s not found", requestID)
+	}
+	if entry.FailureKind != want {
+		t.Errorf("%s has failure kind %q, want %q", requestID, entry.FailureKind, want)
+	}
+}
@@ -0,0 +1,16 @@
+package gateway
+
+import (
+	"github.com/niki4smirn/golf/internal/events"
+	"github.com/niki4smirn/golf/internal/otlp"
+)
+
+// otlpQueueSize is how many events wait for the OTLP exporter before new ones are dropped
+const otlpQueueSize = 10000
+
+// SetOTLPExporter sends every audit request and response to an OpenTelemetry
+// collector. Events are queued and dropped while the queue is full, so a slow
+// collector never holds up the proxy.
+func (g *Gateway) SetOTLPExporter(exporter *otlp.Exporter) {
+	g.bus.SubscribeAsync("otlp", otlpQueueSize, g.timed("otlp", events.WriterHandler(exporter)))
+}
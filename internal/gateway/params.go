@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Defaults and bounds of the param statistics
+const (
+	defaultParamTop    = 10
+	maxParamTop        = 100
+	defaultParamSample = 10000
+	maxParamSample     = 100000
+)
+
+// GetParamStats reports per method the cardinality and most frequent values of
+// each param, e.g. the userId values getUserInfo is called with most, to spot
+// abusive callers and hot keys. Query params: window (default 24h), method,
+// top (values per param, default 10) and limit (newest calls read, default 10000).
+func (g *Gateway) GetParamStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	window := 24 * time.Hour
+	if s := query.Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid window, expected a duration such as 6h", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	top := defaultParamTop
+	if s := query.Get("top"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxParamTop {
+			http.Error(w, fmt.Sprintf("Invalid top, expected an integer between 1 and %d", maxParamTop), http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+	limit := defaultParamSample
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxParamSample {
+			http.Error(w, fmt.Sprintf("Invalid limit, expected an integer between 1 and %d", maxParamSample), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	to := g.now()
+	from := to.Add(-window)
+	methods, sampled, err := g.db.GetParamStats(query.Get("method"), from, to, top, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve param statistics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := types.ParamStatsResponse{From: from, To: to, Sampled: sampled, Limit: limit, Methods: []types.MethodParamStats{}}
+	for _, m := range methods {
+		if g.suppressed(m.Calls) {
+			response.SuppressedBuckets++
+			continue
+		}
+		for i := range m.Params {
+			values := m.Params[i].TopValues[:0]
+			for _, v := range m.Params[i].TopValues {
+				if g.suppressed(v.Count) {
+					response.SuppressedBuckets++
+					continue
+				}
+				values = append(values, v)
+			}
+			m.Params[i].TopValues = values
+		}
+		response.Methods = append(response.Methods, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
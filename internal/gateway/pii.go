@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Patterns of likely PII. They favour precision: phone numbers need a country
+// code or separators, and card numbers must pass the Luhn check.
+var (
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	creditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	phonePattern      = regexp.MustCompile(`\+\d{1,3}[\s.-]?\(?\d{1,4}\)?(?:[\s.-]?\d{2,4}){2,4}\b|\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`)
+)
+
+// piiScanner flags, and optionally masks, likely PII in audited bodies
+type piiScanner struct {
+	detectors []string // Applied in this order, cards before phones so card numbers are not also reported as phones
+	redact    bool
+}
+
+// SetPII enables the PII scanner, nil disables it
+func (g *Gateway) SetPII(cfg *config.PII) {
+	if cfg == nil {
+		g.pii = nil
+		return
+	}
+	scanner := &piiScanner{redact: cfg.Redact}
+	for _, d := range []string{config.PIIEmail, config.PIICreditCard, config.PIIPhone} {
+		if containsString(cfg.Detectors, d) {
+			scanner.detectors = append(scanner.detectors, d)
+		}
+	}
+	g.pii = scanner
+}
+
+// scan returns the kinds of PII found in body as a sorted, comma-separated
+// list, and the body to store: masked when redaction is on, otherwise body itself
+func (p *piiScanner) scan(body []byte) ([]byte, string) {
+	if len(body) == 0 || !utf8.Valid(body) {
+		return body, ""
+	}
+
+	found := make(map[string]bool)
+	var stored []byte
+	if json.Valid(body) {
+		stored = p.scanJSON(body, found)
+	} else {
+		stored = []byte(p.scanText(string(body), found))
+	}
+	if len(found) == 0 {
+		return body, ""
+	}
+
+	kinds := make([]string, 0, len(found))
+	for kind := range found {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	if !p.redact {
+		stored = body
+	}
+	return stored, strings.Join(kinds, ",")
+}
+
+// scanJSON scans the string values of a JSON document, plus bare numbers that
+// look like card numbers, and returns the document with matches masked
+func (p *piiScanner) scanJSON(body []byte, found map[string]bool) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+
+	before := len(found)
+	masked := p.walk(value, found)
+	if !p.redact || len(found) == before {
+		return body
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(masked); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func (p *piiScanner) walk(value interface{}, found map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = p.walk(item, found)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = p.walk(item, found)
+		}
+	case string:
+		return p.scanText(v, found)
+	case json.Number:
+		if containsString(p.detectors, config.PIICreditCard) && isCardNumber(v.String()) {
+			found[config.PIICreditCard] = true
+			return piiMask(config.PIICreditCard)
+		}
+	}
+	return value
+}
+
+// scanText records the kinds of PII in s and returns s with matches masked
+func (p *piiScanner) scanText(s string, found map[string]bool) string {
+	for _, kind := range p.detectors {
+		var pattern *regexp.Regexp
+		switch kind {
+		case config.PIIEmail:
+			pattern = emailPattern
+		case config.PIICreditCard:
+			pattern = creditCardPattern
+		case config.PIIPhone:
+			pattern = phonePattern
+		}
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if kind == config.PIICreditCard && !isCardNumber(match) {
+				return match
+			}
+			found[kind] = true
+			return piiMask(kind)
+		})
+	}
+	return s
+}
+
+func piiMask(kind string) string {
+	return fmt.Sprintf("[REDACTED:%s]", kind)
+}
+
+// isCardNumber reports whether s holds 13 to 19 digits passing the Luhn check
+func isCardNumber(s string) bool {
+	digits := make([]int, 0, 19)
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, int(c-'0'))
+		case c == ' ' || c == '-':
+		default:
+			return false
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// GetPIIReport lists the methods whose requests or responses were flagged for likely PII
+func (g *Gateway) GetPIIReport(w http.ResponseWriter, r *http.Request) {
+	methods, err := g.db.GetPIIReport()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve PII report: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.PIIReport{Enabled: g.pii != nil, Methods: methods})
+}
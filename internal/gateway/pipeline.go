@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/events"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// insertBuckets are the upper bounds of the audit insert latency histogram in seconds
+var insertBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5}
+
+// queueDegradedRatio marks the health degraded once a queue is this full
+const queueDegradedRatio = 0.9
+
+// pipelineMetrics counts the writes of every audit storage backend
+type pipelineMetrics struct {
+	mu       sync.Mutex
+	backends map[string]*backendMetrics
+}
+
+type backendMetrics struct {
+	inserts   int64
+	failures  int64
+	total     time.Duration
+	buckets   []int64 // Inserts per insertBuckets bound, not cumulative
+	lastError string
+}
+
+func newPipelineMetrics() *pipelineMetrics {
+	return &pipelineMetrics{backends: make(map[string]*backendMetrics)}
+}
+
+// observe records one insert into backend taking d
+func (m *pipelineMetrics) observe(backend string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.backends[backend]
+	if !ok {
+		b = &backendMetrics{buckets: make([]int64, len(insertBuckets))}
+		m.backends[backend] = b
+	}
+	b.inserts++
+	b.total += d
+	if i := sort.SearchFloat64s(insertBuckets, d.Seconds()); i < len(insertBuckets) {
+		b.buckets[i]++
+	}
+	if err != nil {
+		b.failures++
+		b.lastError = err.Error()
+	}
+}
+
+// snapshot copies the counters of every backend, ordered by name
+func (m *pipelineMetrics) snapshot() ([]string, []backendMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.backends))
+	for name := range m.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	backends := make([]backendMetrics, len(names))
+	for i, name := range names {
+		backends[i] = *m.backends[name]
+		backends[i].buckets = append([]int64(nil), m.backends[name].buckets...)
+	}
+	return names, backends
+}
+
+// timed wraps a storage subscriber so its inserts are counted under backend
+func (g *Gateway) timed(backend string, handler events.Handler) events.Handler {
+	return func(event events.Event) error {
+		start := g.now()
+		err := handler(event)
+		g.pipeline.observe(backend, g.since(start), err)
+		return err
+	}
+}
+
+// PipelineStatus reports queue depths, insert counters and database size of the audit pipeline
+func (g *Gateway) PipelineStatus() types.PipelineStatus {
+	var status types.PipelineStatus
+	for _, q := range g.bus.Queues() {
+		status.Queues = append(status.Queues, types.AuditQueueStatus{
+			Name:       q.Name,
+			Depth:      q.Depth,
+			Capacity:   q.Capacity,
+			Dropped:    q.Dropped,
+			Overflowed: q.Overflowed,
+		})
+		status.DroppedEvents += q.Dropped
+	}
+	if g.spool != nil {
+		status.DroppedEvents += g.spool.Status().Dropped
+	}
+
+	names, backends := g.pipeline.snapshot()
+	status.Backends = make([]types.BackendStatus, len(names))
+	for i, b := range backends {
+		status.Backends[i] = types.BackendStatus{Name: names[i], Inserts: b.inserts, Failures: b.failures, LastError: b.lastError}
+		if b.inserts > 0 {
+			status.Backends[i].AvgInsertMs = float64(b.total.Microseconds()) / float64(b.inserts) / 1000
+		}
+	}
+
+	if g.db != nil {
+		var err error
+		if status.DatabaseBytes, status.WALBytes, err = g.db.FileSizes(); err != nil {
+			log.Printf("Failed to read database size: %v", err)
+		}
+	}
+	return status
+}
+
+// queuesDegraded reports whether an audit queue is close to dropping events
+func queuesDegraded(status types.PipelineStatus) bool {
+	for _, q := range status.Queues {
+		if q.Capacity > 0 && float64(q.Depth) >= queueDegradedRatio*float64(q.Capacity) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer keeps unusually large bodies from pinning memory in the pool
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// readBody reads r through a pooled buffer and returns an exactly sized copy.
+// Unlike io.ReadAll it allocates once per body instead of once per growth
+// step; the copy is needed because bodies outlive the call in audit subscribers.
+// Bodies outgrowing the pool keep the buffer they were read into.
+func readBody(r io.Reader, sizeHint int64) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if sizeHint > 0 && sizeHint <= maxPooledBuffer {
+		buf.Grow(int(sizeHint))
+	}
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	if buf.Cap() > maxPooledBuffer {
+		// Too large to go back to the pool, so the body keeps the buffer
+		// instead of holding a second copy of a large payload
+		return buf.Bytes(), nil
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
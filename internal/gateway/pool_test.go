@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// benchmarkBody is a JSON-RPC call of a typical size
+var benchmarkBody = bytes.Repeat([]byte(`{"jsonrpc":"2.0","method":"eth_call","params":[{"to":"0x0","data":"0x00"}],"id":1}`), 64)
+
+func BenchmarkReadBody(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkBody)))
+	for i := 0; i < b.N; i++ {
+		if _, err := readBody(bytes.NewReader(benchmarkBody), int64(len(benchmarkBody))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkReadAll is the io.ReadAll baseline readBody replaced
+func BenchmarkReadAll(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkBody)))
+	for i := 0; i < b.N; i++ {
+		if _, err := io.ReadAll(bytes.NewReader(benchmarkBody)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Bounds of the preview_bytes parameter of audit lists
+const (
+	defaultPreviewBytes = 256
+	maxPreviewBytes     = 65536
+)
+
+// previewParams document the preview mode of audit lists
+var previewParams = []apiParam{
+	{"preview", "boolean", "Replace request and response bodies with truncated snippets and their sizes"},
+	{"preview_bytes", "integer", "Snippet length with preview=true (1-65536, default 256)"},
+}
+
+// previewLogs replaces the bodies of logs with snippets when the request asks
+// for preview=true, so lists of multi-megabyte calls stay small. The full
+// bodies remain available from the single-record endpoint.
+func previewLogs(r *http.Request, logs []types.AuditLog) error {
+	query := r.URL.Query()
+	if query.Get("preview") == "" {
+		return nil
+	}
+	enabled, err := strconv.ParseBool(query.Get("preview"))
+	if err != nil {
+		return fmt.Errorf("invalid preview %q, expected true or false", query.Get("preview"))
+	}
+	if !enabled {
+		return nil
+	}
+	size := defaultPreviewBytes
+	if s := query.Get("preview_bytes"); s != "" {
+		size, err = strconv.Atoi(s)
+		if err != nil || size < 1 || size > maxPreviewBytes {
+			return fmt.Errorf("invalid preview_bytes %q, expected 1-%d", s, maxPreviewBytes)
+		}
+	}
+
+	for i := range logs {
+		l := &logs[i]
+		p := &types.BodyPreview{RequestSize: len(l.Request), ResponseSize: len(l.Response)}
+		p.Request, p.RequestTruncated = snippet(l.Request, size)
+		p.Response, p.ResponseTruncated = snippet(l.Response, size)
+		l.Preview = p
+		l.Request, l.Response, l.TransformedResponse, l.Debug = nil, nil, nil, nil
+	}
+	return nil
+}
+
+// snippet returns the first size bytes of body, cut back to a whole UTF-8 character
+func snippet(body []byte, size int) (string, bool) {
+	if len(body) <= size {
+		return string(body), false
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return string(body[:cut]), true
+}
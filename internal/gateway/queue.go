@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// upstreamBusyCode is the JSON-RPC error code returned when a target has no free slot
+const upstreamBusyCode = -32004
+
+// requestAgedOutCode is the JSON-RPC error code returned for calls older than their route's max_age
+const requestAgedOutCode = -32009
+
+var (
+	errQueueFull    = errors.New("upstream queue is full")
+	errQueueTimeout = errors.New("timed out waiting for a free upstream slot")
+	errAgedOut      = errors.New("aged out waiting for a free upstream slot")
+)
+
+// targetLimiter bounds the calls in flight to one target, letting a limited
+// number of callers wait for a free slot
+type targetLimiter struct {
+	name     string
+	slots    chan struct{}
+	maxQueue int64
+	timeout  time.Duration
+	waiting  int64
+}
+
+// buildTargetLimiters creates one limiter per target with a max_in_flight setting.
+// Routes sharing a target share the limiter of the first route that configures one.
+func buildTargetLimiters(routes []config.Route) map[string]*targetLimiter {
+	limiters := make(map[string]*targetLimiter)
+	for _, route := range routes {
+		if route.MaxInFlight <= 0 {
+			continue
+		}
+		if _, ok := limiters[route.Target]; ok {
+			continue
+		}
+		limiters[route.Target] = &targetLimiter{
+			name:     route.Name,
+			slots:    make(chan struct{}, route.MaxInFlight),
+			maxQueue: int64(route.MaxQueue),
+			timeout:  route.QueueTimeoutDuration(),
+		}
+	}
+	return limiters
+}
+
+// acquire takes a slot, waiting in the queue if needed, and returns how long
+// it waited. A call with an age budget, the time left before it ages out,
+// gives up with errAgedOut once it is spent rather than at the queue timeout.
+func (l *targetLimiter) acquire(ctx context.Context, ageBudget time.Duration, now func() time.Time) (time.Duration, error) {
+	select {
+	case l.slots <- struct{}{}:
+		return 0, nil
+	default:
+	}
+
+	if atomic.AddInt64(&l.waiting, 1) > l.maxQueue {
+		atomic.AddInt64(&l.waiting, -1)
+		return 0, errQueueFull
+	}
+	defer atomic.AddInt64(&l.waiting, -1)
+
+	start := now()
+	timeout, expired := l.timeout, errQueueTimeout
+	if ageBudget > 0 && ageBudget < timeout {
+		timeout, expired = ageBudget, errAgedOut
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return now().Sub(start), nil
+	case <-timer.C:
+		return now().Sub(start), expired
+	case <-ctx.Done():
+		return now().Sub(start), ctx.Err()
+	}
+}
+
+// handleAgedOut refuses a call older than its route's max_age instead of sending it to the target
+func (g *Gateway) handleAgedOut(w http.ResponseWriter, call *proxyCall, maxAge time.Duration) {
+	age := g.since(call.startTime)
+	data := types.AgedOutErrorData{
+		MaxAgeMs:  maxAge.Milliseconds(),
+		AgeMs:     age.Milliseconds(),
+		QueueMs:   call.queueTime.Milliseconds(),
+		RequestID: call.requestID,
+	}
+	w.Header().Set("Retry-After", "1")
+	rpcErr := failureError(call.route, config.ErrorAgedOut, requestAgedOutCode, "Request aged out", data)
+	errorMsg := fmt.Sprintf("call aged out after %s, over the %s max_age of route %s (%s waiting for a free upstream slot)",
+		age.Round(time.Millisecond), maxAge, call.route.Name, call.queueTime.Round(time.Millisecond))
+	g.writeRPCError(w, call.id, rpcErr, errorMsg, call.requestID, call.startTime, http.StatusServiceUnavailable, types.FailureAgedOut)
+}
+
+// release frees a slot taken by acquire
+func (l *targetLimiter) release() {
+	<-l.slots
+}
+
+// targetStatuses reports the current load of all limited targets
+func (g *Gateway) targetStatuses() []types.TargetStatus {
+	statuses := make([]types.TargetStatus, 0, len(g.targetLimiters))
+	for target, l := range g.targetLimiters {
+		statuses = append(statuses, types.TargetStatus{
+			Route:       l.name,
+			Target:      target,
+			InFlight:    len(l.slots),
+			MaxInFlight: cap(l.slots),
+			Queued:      int(atomic.LoadInt64(&l.waiting)),
+			MaxQueue:    int(l.maxQueue),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Target < statuses[j].Target })
+	return statuses
+}
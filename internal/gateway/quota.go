@@ -0,0 +1,186 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// quotaExceededCode is the JSON-RPC error code returned when a quota is exhausted
+const quotaExceededCode = -32005
+
+// SetAPIKeys configures the API keys clients identify themselves with; it is
+// called again when keys read from a secrets provider rotate
+func (g *Gateway) SetAPIKeys(keys []config.APIKey) {
+	apiKeys := make(map[string]config.APIKey, len(keys))
+	for _, k := range keys {
+		apiKeys[k.Key] = k
+	}
+	g.apiKeysMu.Lock()
+	g.apiKeys = apiKeys
+	g.apiKeysMu.Unlock()
+}
+
+// configuredKeys returns the API keys from the config file by key
+func (g *Gateway) configuredKeys() map[string]config.APIKey {
+	g.apiKeysMu.RLock()
+	defer g.apiKeysMu.RUnlock()
+	return g.apiKeys
+}
+
+// SetTenantQuotas configures quotas shared by all keys of a tenant
+func (g *Gateway) SetTenantQuotas(quotas map[string]config.Quota) {
+	g.tenantQuotas = quotas
+}
+
+// presentedKey returns the API key sent with the request and the header that carried it.
+// Keys are read from X-API-Key or an Authorization bearer token.
+func presentedKey(r *http.Request) (string, string) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return "X-Api-Key", key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return "Authorization", strings.TrimPrefix(auth, "Bearer ")
+	}
+	return "", ""
+}
+
+// identifyClient returns the configured or database-managed API key presented by the request, if any
+func (g *Gateway) identifyClient(r *http.Request) *config.APIKey {
+	_, key := presentedKey(r)
+	if key == "" {
+		return nil
+	}
+
+	if apiKey, ok := g.configuredKeys()[key]; ok {
+		return &apiKey
+	}
+	return g.lookupClient(key)
+}
+
+// quotaPeriods returns the start of the current day and month in local time
+func quotaPeriods(now time.Time) (time.Time, time.Time) {
+	now = now.Local()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return dayStart, monthStart
+}
+
+// checkQuota returns a non-empty reason if the client has exhausted its key or tenant quota
+func (g *Gateway) checkQuota(client *config.APIKey, now time.Time) (string, error) {
+	if client == nil || g.db == nil {
+		return "", nil
+	}
+
+	dayStart, monthStart := quotaPeriods(now)
+
+	if client.Quota.Daily > 0 || client.Quota.Monthly > 0 {
+		usage, err := g.usageDB().CountKeyUsage(client.Name, dayStart, monthStart)
+		if err != nil {
+			return "", err
+		}
+		if reason := exceededReason("api key "+client.Name, client.Quota, usage.Daily, usage.Monthly); reason != "" {
+			return reason, nil
+		}
+	}
+
+	if quota, ok := g.tenantQuotas[client.Tenant]; ok && client.Tenant != "" {
+		usage, err := g.usageDB().CountTenantUsage(client.Tenant, dayStart, monthStart)
+		if err != nil {
+			return "", err
+		}
+		if reason := exceededReason("tenant "+client.Tenant, quota, usage.Daily, usage.Monthly); reason != "" {
+			return reason, nil
+		}
+	}
+
+	return "", nil
+}
+
+func exceededReason(subject string, quota config.Quota, daily, monthly int) string {
+	if quota.Daily > 0 && daily >= quota.Daily {
+		return fmt.Sprintf("daily quota of %d calls exceeded for %s", quota.Daily, subject)
+	}
+	if quota.Monthly > 0 && monthly >= quota.Monthly {
+		return fmt.Sprintf("monthly quota of %d calls exceeded for %s", quota.Monthly, subject)
+	}
+	return ""
+}
+
+// GetUsage returns current quota consumption per API key and tenant
+func (g *Gateway) GetUsage(w http.ResponseWriter, r *http.Request) {
+	dayStart, monthStart := quotaPeriods(g.now())
+
+	keyUsage, err := g.usageDB().GetKeyUsage(dayStart, monthStart)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	tenantUsage, err := g.usageDB().GetTenantUsage(dayStart, monthStart)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Include configured keys without traffic and keys seen in the audit log
+	keys := make(map[string]types.UsageEntry)
+	for _, k := range g.configuredKeys() {
+		keys[k.Name] = types.UsageEntry{Name: k.Name, Tenant: k.Tenant, DailyLimit: k.Quota.Daily, MonthlyLimit: k.Quota.Monthly}
+	}
+	g.clientsMu.RLock()
+	for _, k := range g.clients {
+		keys[k.Name] = types.UsageEntry{Name: k.Name, Tenant: k.Tenant, DailyLimit: k.Quota.Daily, MonthlyLimit: k.Quota.Monthly}
+	}
+	g.clientsMu.RUnlock()
+	for name, usage := range keyUsage {
+		entry := keys[name]
+		entry.Name = name
+		entry.DailyUsed = usage.Daily
+		entry.MonthlyUsed = usage.Monthly
+		keys[name] = entry
+	}
+
+	tenants := make(map[string]types.UsageEntry)
+	for name, quota := range g.tenantQuotas {
+		tenants[name] = types.UsageEntry{Name: name, DailyLimit: quota.Daily, MonthlyLimit: quota.Monthly}
+	}
+	for name, usage := range tenantUsage {
+		entry := tenants[name]
+		entry.Name = name
+		entry.DailyUsed = usage.Daily
+		entry.MonthlyUsed = usage.Monthly
+		tenants[name] = entry
+	}
+
+	if key := r.URL.Query().Get("key"); key != "" {
+		keys = map[string]types.UsageEntry{key: keys[key]}
+	}
+
+	response := types.UsageResponse{
+		Keys:       sortedUsage(keys),
+		Tenants:    sortedUsage(tenants),
+		DayStart:   dayStart,
+		MonthStart: monthStart,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func sortedUsage(entries map[string]types.UsageEntry) []types.UsageEntry {
+	result := make([]types.UsageEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.Name != "" {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/clock"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+func TestQuotaPeriods(t *testing.T) {
+	now := time.Date(2026, 3, 15, 13, 45, 0, 0, time.Local)
+	dayStart, monthStart := quotaPeriods(now)
+	if want := time.Date(2026, 3, 15, 0, 0, 0, 0, time.Local); !dayStart.Equal(want) {
+		t.Errorf("day starts at %s, want %s", dayStart, want)
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local); !monthStart.Equal(want) {
+		t.Errorf("month starts at %s, want %s", monthStart, want)
+	}
+}
+
+func TestQuotaResetsWithTheClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 3, 31, 12, 0, 0, 0, time.Local), 0)
+	g, db := newTestGateway(t, fake)
+	client := &config.APIKey{Name: "svc", ClientPolicy: types.ClientPolicy{Quota: types.Quota{Daily: 2, Monthly: 3}}}
+	forwarded := map[string]string{metaUpstream: "sent"}
+
+	check := func(want bool) {
+		t.Helper()
+		reason, err := g.checkQuota(client, g.now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exceeded := reason != ""; exceeded != want {
+			t.Fatalf("at %s: exceeded = %v (%q), want %v", g.now(), exceeded, reason, want)
+		}
+	}
+
+	recordCall(t, db, "yesterday", "svc", fake.Now().Add(-24*time.Hour), 200, forwarded)
+	recordCall(t, db, "today-1", "svc", fake.Now().Add(-time.Hour), 200, forwarded)
+	// Refused by the gateway, never reached the upstream
+	recordCall(t, db, "refused", "svc", fake.Now().Add(-time.Hour), 403, map[string]string{metaPolicy: "deny"})
+	check(false)
+
+	recordCall(t, db, "today-2", "svc", fake.Now().Add(-time.Minute), 200, forwarded)
+	check(true) // daily and monthly limits reached
+
+	// The next day is also the next month: both counts start over
+	fake.Advance(12 * time.Hour)
+	check(false)
+	for i := 0; i < 2; i++ {
+		recordCall(t, db, "april-"+strconv.Itoa(i), "svc", fake.Now(), 200, forwarded)
+	}
+	check(true)
+}
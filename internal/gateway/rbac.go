@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// roleRanks orders the management roles; unknown and empty roles rank 0
+var roleRanks = map[string]int{types.RoleViewer: 1, types.RoleOperator: 2, types.RoleAdmin: 3}
+
+// SetRBAC makes every management endpoint require a role. Without it, callers
+// without one may still read everything below the admin role, as before roles.
+func (g *Gateway) SetRBAC(enabled bool) {
+	g.rbac = enabled
+}
+
+// callerRole returns the management role of the request and the name recorded
+// for its actions: admin for the admin token, else the role of its API key or
+// of the user signed in through the identity provider
+func (g *Gateway) callerRole(r *http.Request) (role, actor string) {
+	if g.isAdmin(r) {
+		return types.RoleAdmin, "admin-token"
+	}
+	if client := g.identifyClient(r); client != nil {
+		return client.Role, client.Name
+	}
+	if session := g.authSession(r); session != nil {
+		return session.Role, session.User
+	}
+	return "", ""
+}
+
+// hasRole reports whether the request may read what role may
+func (g *Gateway) hasRole(r *http.Request, role string) bool {
+	caller, _ := g.callerRole(r)
+	return roleRanks[caller] >= roleRanks[role] || (caller == "" && g.anonymousReads(role))
+}
+
+// anonymousReads reports whether callers without a role may read endpoints
+// needing role: everything but admin endpoints, none with -rbac, and only
+// aggregates in stats-only mode
+func (g *Gateway) anonymousReads(role string) bool {
+	if g.rbac || role == types.RoleAdmin {
+		return false
+	}
+	return !g.statsOnly || role == types.RoleViewer
+}
+
+// allowRead rejects callers below role from an endpoint that changes nothing
+func (g *Gateway) allowRead(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !g.hasRole(r, role) {
+			caller, _ := g.callerRole(r)
+			denyRole(w, caller, role)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireRole rejects callers below role from a privileged endpoint, and
+// records every call that gets through as an admin action
+func (g *Gateway) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, actor := g.callerRole(r)
+		if roleRanks[caller] < roleRanks[role] {
+			if caller == "" && g.adminToken == "" && !g.rbac {
+				http.Error(w, "Admin API is disabled, start the gateway with -admin-token", http.StatusForbidden)
+				return
+			}
+			denyRole(w, caller, role)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		g.recordAdminAction(types.AdminAction{
+			Timestamp:  g.now(),
+			Actor:      actor,
+			Role:       caller,
+			HTTPMethod: r.Method,
+			Path:       r.URL.Path,
+			StatusCode: recorder.status,
+			IPAddress:  getClientIP(r),
+		})
+	}
+}
+
+// denyRole answers a caller lacking the role an endpoint requires
+func denyRole(w http.ResponseWriter, caller, role string) {
+	if caller == "" {
+		http.Error(w, fmt.Sprintf("This endpoint requires the %s role", role), http.StatusUnauthorized)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Role %q may not use this endpoint, it requires the %s role", caller, role), http.StatusForbidden)
+}
+
+// recordAdminAction logs a privileged call and stores it when SQLite is available
+func (g *Gateway) recordAdminAction(action types.AdminAction) {
+	log.Printf("Admin action by %s (%s): %s %s -> %d", action.Actor, action.Role, action.HTTPMethod, action.Path, action.StatusCode)
+	if g.db == nil {
+		return
+	}
+	if err := g.db.InsertAdminAction(&action); err != nil {
+		log.Printf("Failed to record admin action: %v", err)
+	}
+}
+
+// GetAdminActions lists recorded privileged calls, newest first, optionally of one actor
+func (g *Gateway) GetAdminActions(w http.ResponseWriter, r *http.Request) {
+	limit, offset := 100, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	actions, err := g.db.GetAdminActions(r.URL.Query().Get("actor"), limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve admin actions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.AdminActionsResponse{
+		Actions: actions,
+		Limit:   limit,
+		Offset:  offset,
+		Count:   len(actions),
+	})
+}
+
+// statusRecorder remembers the status code written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes streaming writes through, for exports
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/niki4smirn/golf/internal/clock"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// newRBACGateway returns a gateway with the admin token admin-secret and one key per role
+func newRBACGateway(t *testing.T, rbac bool) *Gateway {
+	t.Helper()
+	g, _ := newTestGateway(t, clock.NewFake(testStart, 0))
+	g.SetAdminToken("admin-secret")
+	g.SetRBAC(rbac)
+	g.SetAPIKeys([]config.APIKey{
+		{Key: "viewer-key", Name: "viewer", Tenant: "acme", ClientPolicy: types.ClientPolicy{Role: types.RoleViewer}},
+		{Key: "operator-key", Name: "operator", Tenant: "acme", ClientPolicy: types.ClientPolicy{Role: types.RoleOperator}},
+		{Key: "admin-key", Name: "admin", ClientPolicy: types.ClientPolicy{Role: types.RoleAdmin}},
+		{Key: "proxy-key", Name: "proxy", Tenant: "acme"},
+	})
+	return g
+}
+
+func TestRoleGates(t *testing.T) {
+	key := func(k string) http.Header { return http.Header{"X-Api-Key": {k}} }
+	tests := []struct {
+		name   string
+		rbac   bool
+		method string
+		path   string
+		header http.Header
+		want   int
+	}{
+		// Viewer endpoints
+		{"anonymous stats", false, "GET", "/audit/stats", nil, http.StatusOK},
+		{"anonymous stats with rbac", true, "GET", "/audit/stats", nil, http.StatusUnauthorized},
+		{"viewer stats with rbac", true, "GET", "/audit/stats", key("viewer-key"), http.StatusOK},
+		{"proxy-only key stats with rbac", true, "GET", "/audit/stats", key("proxy-key"), http.StatusUnauthorized},
+
+		// Operator reads of individual calls
+		{"viewer logs", true, "GET", "/audit/logs", key("viewer-key"), http.StatusForbidden},
+		{"operator logs", true, "GET", "/audit/logs", key("operator-key"), http.StatusOK},
+		{"viewer tenant logs", true, "GET", "/tenant/logs", key("viewer-key"), http.StatusForbidden},
+		{"operator tenant logs", true, "GET", "/tenant/logs", key("operator-key"), http.StatusOK},
+		{"operator picks another tenant", true, "GET", "/tenant/logs?tenant=other", key("operator-key"), http.StatusForbidden},
+		{"admin key picks a tenant", true, "GET", "/tenant/logs?tenant=other", key("admin-key"), http.StatusOK},
+
+		// Privileged actions
+		{"viewer invalidates cache", false, "POST", "/admin/cache/invalidate", key("viewer-key"), http.StatusForbidden},
+		{"operator invalidates cache", false, "POST", "/admin/cache/invalidate", key("operator-key"), http.StatusOK},
+		{"anonymous lists clients", false, "GET", "/admin/clients", nil, http.StatusUnauthorized},
+		{"operator lists clients", false, "GET", "/admin/clients", key("operator-key"), http.StatusForbidden},
+		{"admin key lists clients", false, "GET", "/admin/clients", key("admin-key"), http.StatusOK},
+		{"admin token lists clients", false, "GET", "/admin/clients", bearer("admin-secret"), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newRBACGateway(t, tt.rbac)
+			body := ""
+			if tt.method == "POST" {
+				body = `{"method":"eth_chainId"}`
+			}
+			w := serve(g, tt.method, tt.path, body, tt.header)
+			if w.Code != tt.want {
+				t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// redactedValue replaces masked header values and payload fields in audit records
+const redactedValue = "[REDACTED]"
+
+// redactHeaders masks header values according to the redaction level.
+// The header carrying the gateway API key is always masked.
+func redactHeaders(headers map[string]string, level string, credentialHeader string) {
+	for name := range headers {
+		if level == types.RedactionHeaders || level == types.RedactionPayload || name == credentialHeader {
+			headers[name] = redactedValue
+		}
+	}
+}
+
+// redactPayload masks the given top-level members of a JSON-RPC message.
+// Bodies that are not JSON objects are masked entirely.
+func redactPayload(body []byte, level string, fields ...string) []byte {
+	if level != types.RedactionPayload {
+		return body
+	}
+
+	masked, _ := json.Marshal(redactedValue)
+
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return masked
+	}
+
+	for _, field := range fields {
+		if _, ok := message[field]; ok {
+			message[field] = masked
+		}
+	}
+
+	// Keep error codes and messages, mask only the error data
+	if raw, ok := message["error"]; ok {
+		var rpcErr map[string]json.RawMessage
+		if json.Unmarshal(raw, &rpcErr) == nil {
+			if _, ok := rpcErr["data"]; ok {
+				rpcErr["data"] = masked
+				message["error"], _ = json.Marshal(rpcErr)
+			}
+		}
+	}
+
+	result, err := json.Marshal(message)
+	if err != nil {
+		return masked
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/redis"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// redisRetry is how long stores keep to their local state after Redis failed
+const redisRetry = 5 * time.Second
+
+// redisBackend is the Redis server replicas share rate limits and idempotency
+// keys through. While it is unreachable, callers fall back to their local
+// state and try Redis again after redisRetry.
+type redisBackend struct {
+	client *redis.Client
+	prefix string // Namespaces the gateway's keys, e.g. golf:
+
+	mu        sync.Mutex
+	down      bool
+	downUntil time.Time
+}
+
+// SetRedis shares rate limits and idempotency keys with other replicas
+// through client, keeping its keys under prefix
+func (g *Gateway) SetRedis(client *redis.Client, prefix string) {
+	backend := &redisBackend{client: client, prefix: prefix}
+	g.limiter = &redisRateLimiter{backend: backend, local: newRateLimiter()}
+	g.idempotency = &redisIdempotency{backend: backend, local: newLocalIdempotency()}
+}
+
+// available reports whether Redis should be tried
+func (b *redisBackend) available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.down || !now.Before(b.downUntil)
+}
+
+// failed switches to local state until the retry time, logging the start of an outage once
+func (b *redisBackend) failed(err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.down {
+		log.Printf("Redis %s unavailable, using local rate limits and idempotency keys: %v", b.client.Addr(), err)
+	}
+	b.down = true
+	b.downUntil = now.Add(redisRetry)
+}
+
+// succeeded ends an outage
+func (b *redisBackend) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.down {
+		log.Printf("Redis %s available again", b.client.Addr())
+		b.down = false
+	}
+}
+
+// context bounds one Redis round trip
+func (b *redisBackend) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Second)
+}
+
+// clientLimiter decides whether a client may make another call under its rate limit
+type clientLimiter interface {
+	allow(name string, limit types.RateLimit, now time.Time) bool
+}
+
+// tokenBucketScript is rateLimiter's token bucket run atomically in Redis, so
+// all replicas draw from one bucket per client
+var tokenBucketScript = redis.NewScript(`
+local rate, burst, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens, last = tonumber(bucket[1]), tonumber(bucket[2])
+if tokens == nil then
+  tokens, last = burst, now
+end
+if now > last then
+  tokens = math.min(burst, tokens + (now - last) / 1000 * rate)
+  last = now
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(last))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return allowed
+`)
+
+// redisRateLimiter keeps token buckets in Redis, falling back to local buckets while Redis is down
+type redisRateLimiter struct {
+	backend *redisBackend
+	local   *rateLimiter
+}
+
+func (l *redisRateLimiter) allow(name string, limit types.RateLimit, now time.Time) bool {
+	if !l.backend.available(now) || limit.RequestsPerSecond <= 0 {
+		return l.local.allow(name, limit, now)
+	}
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(limit.RequestsPerSecond))
+	}
+
+	ctx, cancel := l.backend.context()
+	defer cancel()
+	reply, err := tokenBucketScript.Run(ctx, l.backend.client, []string{l.backend.prefix + "ratelimit:" + name},
+		strconv.FormatFloat(limit.RequestsPerSecond, 'f', -1, 64),
+		strconv.FormatFloat(burst, 'f', -1, 64),
+		strconv.FormatInt(now.UnixMilli(), 10))
+	if err != nil {
+		l.backend.failed(err, now)
+		return l.local.allow(name, limit, now)
+	}
+	l.backend.succeeded()
+	allowed, _ := reply.(int64)
+	return allowed == 1
+}
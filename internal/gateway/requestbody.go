@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/config"
+)
+
+// SetMaxRequestBytes bounds request bodies on routes without their own max_request_bytes, 0 disables
+func (g *Gateway) SetMaxRequestBytes(n int64) {
+	g.maxRequestBytes = n
+}
+
+// requestBodyLimit returns the largest request body accepted on route, 0 when unbounded
+func (g *Gateway) requestBodyLimit(route *config.Route) int64 {
+	if route.MaxRequestBytes > 0 {
+		return route.MaxRequestBytes
+	}
+	return g.maxRequestBytes
+}
+
+// isChunked reports whether the client sent its body with chunked transfer coding
+func isChunked(r *http.Request) bool {
+	for _, coding := range r.TransferEncoding {
+		if coding == "chunked" {
+			return true
+		}
+	}
+	return false
+}
+
+// readRequestBody reads the client's body up to the route's limit, returning
+// the HTTP status to answer with when it cannot be read. Declared lengths over
+// the limit are refused before reading; chunked bodies, whose length is only
+// known at the end, are cut off as soon as they pass it rather than buffered
+// whole first.
+func (g *Gateway) readRequestBody(w http.ResponseWriter, r *http.Request, route *config.Route) ([]byte, int, error) {
+	limit := g.requestBodyLimit(route)
+	if limit > 0 {
+		if r.ContentLength > limit {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("request body of %d bytes exceeds the limit of %d bytes", r.ContentLength, limit)
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+
+	body, err := readBody(r.Body, r.ContentLength)
+	r.Body.Close()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds the limit of %d bytes", limit)
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("failed to read request body")
+	}
+	return body, 0, nil
+}
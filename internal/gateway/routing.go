@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/config"
+)
+
+// matchRule returns the first of the route's rules the call matches, nil when none does
+func matchRule(route *config.Route, r *http.Request, method string, body []byte) *config.RoutingRule {
+	if len(route.Rules) == 0 {
+		return nil
+	}
+	call := parsedCall{body: body}
+	for i := range route.Rules {
+		if call.matches(&route.Rules[i], r, method) {
+			return &route.Rules[i]
+		}
+	}
+	return nil
+}
+
+// parsedCall decodes the request body once, when the first rule needs it
+type parsedCall struct {
+	body    []byte
+	decoded bool
+	doc     interface{}
+}
+
+func (c *parsedCall) document() interface{} {
+	if !c.decoded {
+		c.decoded = true
+		json.Unmarshal(c.body, &c.doc)
+	}
+	return c.doc
+}
+
+func (c *parsedCall) matches(rule *config.RoutingRule, r *http.Request, method string) bool {
+	size := int64(len(c.body))
+	if size < rule.MinBytes || (rule.MaxBytes > 0 && size > rule.MaxBytes) {
+		return false
+	}
+	if len(rule.Methods) > 0 && !containsString(rule.Methods, method) {
+		return false
+	}
+	for name, value := range rule.Headers {
+		if r.Header.Get(name) != value {
+			return false
+		}
+	}
+	if rule.MinBatch > 0 {
+		items, _ := c.document().([]interface{})
+		if len(items) < rule.MinBatch {
+			return false
+		}
+	}
+	for path, want := range rule.Params {
+		if got, ok := lookupPath(c.document(), path); !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
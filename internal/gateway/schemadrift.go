@@ -0,0 +1,320 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+const (
+	eventSchemaDrift = "schema.drift" // Delivered to webhooks the first time a difference is seen
+
+	schemaDriftQueueSize = 1000 // Observations waiting to be written, dropped beyond this
+	maxDriftValueLength  = 100  // Longer observed values are truncated in the report
+)
+
+// jsonSchema is the subset of JSON Schema checked against upstream results.
+// Keywords it does not know, such as $ref or oneOf, accept any value.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+}
+
+// schemaTypes is the type keyword, a single name or a list of names
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = names
+	return nil
+}
+
+// allows reports whether a value of the given JSON type matches
+func (t schemaTypes) allows(observed string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, name := range t {
+		if name == observed || (name == "number" && observed == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON Schema type of a decoded value
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// SetResponseSchemas registers the expected result of methods. Nil disables the checks.
+func (g *Gateway) SetResponseSchemas(cfg *config.ResponseSchemas) error {
+	if cfg == nil {
+		g.responseSchemas, g.openRPCSchemas = nil, false
+		return nil
+	}
+
+	schemas := make(map[string]*jsonSchema, len(cfg.Methods))
+	for method, raw := range cfg.Methods {
+		var schema jsonSchema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return fmt.Errorf("invalid response schema of %s: %w", method, err)
+		}
+		schemas[method] = &schema
+	}
+	g.responseSchemas, g.openRPCSchemas = schemas, cfg.OpenRPC
+
+	if g.db != nil && g.driftQueue == nil {
+		g.driftQueue = make(chan types.SchemaDrift, schemaDriftQueueSize)
+		go g.recordSchemaDrift()
+	}
+	return nil
+}
+
+// responseSchema returns the registered result schema of method, or nil
+func (g *Gateway) responseSchema(method string) *jsonSchema {
+	if schema, ok := g.responseSchemas[method]; ok {
+		return schema
+	}
+	if g.openRPCSchemas && g.openRPC != nil {
+		if m, ok := g.openRPC.methods[method]; ok {
+			return m.resultSchema
+		}
+	}
+	return nil
+}
+
+// checkResponseSchema compares the result of a successful upstream response
+// with the method's schema and queues the differences for the drift report
+func (g *Gateway) checkResponseSchema(call *proxyCall, seen time.Time, body []byte) {
+	if g.driftQueue == nil {
+		return
+	}
+	schema := g.responseSchema(call.method)
+	if schema == nil {
+		return
+	}
+
+	var resp struct {
+		Result *json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Result == nil {
+		return // Errors and malformed bodies are reported elsewhere
+	}
+	var result interface{}
+	if err := json.Unmarshal(*resp.Result, &result); err != nil {
+		return
+	}
+
+	found := make(map[string]types.SchemaDrift)
+	schema.check(result, "result", found)
+	for _, drift := range found {
+		drift.Method = call.method
+		drift.Count = 1
+		drift.FirstSeen, drift.LastSeen = seen, seen
+		drift.SampleRequestID = call.requestID
+		select {
+		case g.driftQueue <- drift:
+		default:
+			log.Printf("Schema drift queue full, dropping %s %s of %s", drift.Kind, drift.Path, drift.Method)
+		}
+	}
+}
+
+// check adds the differences between v and the schema to found, keyed so
+// repeated differences in array elements are reported once
+func (s *jsonSchema) check(v interface{}, path string, found map[string]types.SchemaDrift) {
+	if s == nil {
+		return
+	}
+	add := func(drift types.SchemaDrift) {
+		found[drift.Path+"\x00"+drift.Kind+"\x00"+drift.Observed] = drift
+	}
+
+	observed := jsonType(v)
+	if !s.Type.allows(observed) {
+		add(types.SchemaDrift{Path: path, Kind: types.DriftTypeChange, Expected: strings.Join(s.Type, "|"), Observed: observed})
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(v, s.Enum) {
+		add(types.SchemaDrift{Path: path, Kind: types.DriftInvalidValue, Expected: truncatedJSON(s.Enum), Observed: truncatedJSON(v)})
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				expected := ""
+				if prop := s.Properties[name]; prop != nil {
+					expected = strings.Join(prop.Type, "|")
+				}
+				add(types.SchemaDrift{Path: path + "." + name, Kind: types.DriftMissingField, Expected: expected})
+			}
+		}
+		for name, value := range v {
+			if prop, ok := s.Properties[name]; ok {
+				prop.check(value, path+"."+name, found)
+				continue
+			}
+			additional := strings.TrimSpace(string(s.AdditionalProperties))
+			if strings.HasPrefix(additional, "{") {
+				var schema jsonSchema
+				if json.Unmarshal(s.AdditionalProperties, &schema) == nil {
+					schema.check(value, path+"."+name, found)
+				}
+				continue
+			}
+			// Open objects without declared members, and explicitly open ones, accept anything
+			if s.Properties != nil && additional != "true" {
+				add(types.SchemaDrift{Path: path + "." + name, Kind: types.DriftNewField, Observed: jsonType(value)})
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			s.Items.check(item, path+"[]", found)
+		}
+	}
+}
+
+func inEnum(v interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(v, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncatedJSON encodes a value for the report, cutting long values short
+func truncatedJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	if len(data) > maxDriftValueLength {
+		return string(data[:maxDriftValueLength]) + "..."
+	}
+	return string(data)
+}
+
+// recordSchemaDrift writes queued observations, merging those waiting together
+// into one transaction, and announces differences seen for the first time
+func (g *Gateway) recordSchemaDrift() {
+	for first := range g.driftQueue {
+		merged := map[string]*types.SchemaDrift{}
+		var order []string
+		add := func(drift types.SchemaDrift) {
+			key := drift.Method + "\x00" + drift.Path + "\x00" + drift.Kind + "\x00" + drift.Observed
+			if m, ok := merged[key]; ok {
+				m.Count++
+				m.LastSeen, m.SampleRequestID = drift.LastSeen, drift.SampleRequestID
+				return
+			}
+			merged[key] = &drift
+			order = append(order, key)
+		}
+
+		add(first)
+	drain:
+		for len(order) < schemaDriftQueueSize {
+			select {
+			case drift := <-g.driftQueue:
+				add(drift)
+			default:
+				break drain
+			}
+		}
+
+		batch := make([]types.SchemaDrift, len(order))
+		for i, key := range order {
+			batch[i] = *merged[key]
+		}
+		added, err := g.db.RecordSchemaDrift(batch)
+		if err != nil {
+			log.Printf("Failed to record schema drift: %v", err)
+			continue
+		}
+		for _, drift := range added {
+			log.Printf("Schema drift in %s: %s %s (expected %q, observed %q, request %s)",
+				drift.Method, drift.Kind, drift.Path, drift.Expected, drift.Observed, drift.SampleRequestID)
+			g.notify(eventSchemaDrift, drift)
+		}
+	}
+}
+
+// GetSchemaDrift reports how upstream results differed from the registered schemas.
+// Query params: window (default 24h), method.
+func (g *Gateway) GetSchemaDrift(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	window := 24 * time.Hour
+	if s := query.Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid window, expected a duration such as 6h", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	since := g.now().Add(-window)
+
+	drift, err := g.db.GetSchemaDrift(query.Get("method"), since)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve schema drift: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	methods := make([]string, 0, len(g.responseSchemas))
+	for method := range g.responseSchemas {
+		methods = append(methods, method)
+	}
+	if g.openRPCSchemas && g.openRPC != nil {
+		for name, m := range g.openRPC.methods {
+			if _, ok := g.responseSchemas[name]; !ok && m.resultSchema != nil {
+				methods = append(methods, name)
+			}
+		}
+	}
+	sort.Strings(methods)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.SchemaDriftResponse{
+		Methods: methods,
+		Since:   since,
+		Drift:   drift,
+		Count:   len(drift),
+	})
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/script"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// LoadScripts compiles the scripts of the configured routes, replacing those loaded before
+func (g *Gateway) LoadScripts() error {
+	scripts := make(map[string]*script.Script)
+	for _, route := range g.routes {
+		if route.Script == "" {
+			continue
+		}
+		s, err := script.Load(route.Script, route.ScriptTimeoutDuration())
+		if err != nil {
+			return fmt.Errorf("route %s: %w", route.Name, err)
+		}
+		scripts[route.Name] = s
+		log.Printf("Route %s runs script %s", route.Name, s.Path())
+	}
+	g.scripts = scripts
+	return nil
+}
+
+// runScript lets the route's script decide about a call. It returns the
+// script's result, or the rejection of calls the script denied or failed on.
+func (g *Gateway) runScript(r *http.Request, route *config.Route, client *config.APIKey, method string, params interface{}) (*script.Result, *rejection) {
+	s := g.scripts[route.Name]
+	if s == nil {
+		return nil, nil
+	}
+	result, rejected := g.scriptDecision(s, r, route, client, method, params)
+	rewrote := result != nil && (result.Method != "" || result.Params != nil)
+	setScriptOutcome(AuditContextFrom(r.Context()), rewrote, rejected)
+	return result, rejected
+}
+
+// runBatchScript lets the route's script decide about every call of a batch.
+// One denied or failing item rejects the whole batch; items the script
+// rewrote are replaced in the returned body. Tags of all items are merged.
+func (g *Gateway) runBatchScript(r *http.Request, route *config.Route, client *config.APIKey, body []byte) ([]byte, map[string]string, *rejection) {
+	s := g.scripts[route.Name]
+	if s == nil {
+		return body, nil, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return body, nil, nil
+	}
+
+	var tags map[string]string
+	rewrote := false
+	for i, item := range items {
+		var call types.JSONRPCRequest
+		method := "unknown"
+		if err := json.Unmarshal(item, &call); err == nil && call.Method != "" {
+			method = call.Method
+		}
+		result, rejected := g.scriptDecision(s, r, route, client, method, call.Params)
+		if rejected != nil {
+			rejected.reason = fmt.Sprintf("batch item %d (%s): %s", i+1, method, rejected.reason)
+			setScriptOutcome(AuditContextFrom(r.Context()), false, rejected)
+			return nil, nil, rejected
+		}
+		tags = mergeTags(tags, result.Tags)
+		if result.Method == "" && result.Params == nil {
+			continue
+		}
+		rewritten, err := rewriteCall(item, result)
+		if err != nil {
+			log.Printf("Failed to apply script rewrite of batch item %d: %v", i+1, err)
+			continue
+		}
+		items[i], rewrote = rewritten, true
+	}
+	if rewrote {
+		rewritten, err := json.Marshal(items)
+		if err != nil {
+			return nil, nil, &rejection{-32603, "Internal error", fmt.Sprintf("failed to apply script rewrite: %v", err), http.StatusInternalServerError}
+		}
+		body = rewritten
+	}
+	setScriptOutcome(AuditContextFrom(r.Context()), rewrote, nil)
+	return body, tags, nil
+}
+
+// scriptDecision runs s on one call and turns denials and failures into rejections
+func (g *Gateway) scriptDecision(s *script.Script, r *http.Request, route *config.Route, client *config.APIKey, method string, params interface{}) (*script.Result, *rejection) {
+	// Scripts decide on calls, they have no use for the caller's secrets
+	headers := r.Header.Clone()
+	for _, name := range credentialHeaders {
+		if headers.Get(name) != "" {
+			headers.Set(name, redactedValue)
+		}
+	}
+	call := script.Call{
+		Route:   route.Name,
+		Method:  method,
+		Params:  params,
+		Headers: headers,
+		IP:      getClientIP(r),
+	}
+	if client != nil {
+		call.Client = &script.Client{Name: client.Name, Tenant: client.Tenant, Role: client.Role}
+	}
+	result, err := s.Run(r.Context(), call)
+	if err != nil {
+		log.Printf("Script of route %s failed on %s: %v", route.Name, method, err)
+		return nil, &rejection{-32603, "Internal error", fmt.Sprintf("script of route %s failed: %v", route.Name, err), http.StatusInternalServerError}
+	}
+	if result.Deny != "" {
+		return nil, &rejection{methodDeniedCode, "Call not allowed", result.Deny, http.StatusForbidden}
+	}
+	return result, nil
+}
+
+// setScriptOutcome records allow, deny, rewrite or error for a call on a scripted route
+func setScriptOutcome(audit *AuditContext, rewrote bool, rejected *rejection) {
+	switch {
+	case rejected != nil && rejected.code == methodDeniedCode:
+		audit.Set(metaScript, "deny")
+	case rejected != nil:
+		audit.Set(metaScript, "error")
+	case rewrote:
+		audit.Set(metaScript, "rewrite")
+	default:
+		audit.Set(metaScript, "allow")
+	}
+}
+
+// rewriteCall replaces the method and params of a single call as a script asked
+func rewriteCall(body []byte, result *script.Result) ([]byte, error) {
+	if result.Method != "" {
+		renamed, err := replaceMethod(body, result.Method)
+		if err != nil {
+			return nil, err
+		}
+		body = renamed
+	}
+	if result.Params == nil {
+		return body, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("request is not a JSON object")
+	}
+	fields["params"] = result.Params
+	return json.Marshal(fields)
+}
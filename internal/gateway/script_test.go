@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/niki4smirn/golf/internal/clock"
+)
+
+// policyScript denies eth_sendRawTransaction and sends legacy_blockNumber upstream as eth_blockNumber
+const policyScript = `
+function on_request(call)
+  if call.method == "eth_sendRawTransaction" then
+    return {deny = "writes are not allowed"}
+  end
+  if call.method == "legacy_blockNumber" then
+    return {method = "eth_blockNumber", params = {"latest"}, tags = {rewritten = "yes"}}
+  end
+  return nil
+end
+`
+
+// newScriptedGateway returns a gateway whose /rpc route runs policyScript and caches eth_blockNumber
+func newScriptedGateway(t *testing.T) (*Gateway, *recordingUpstream) {
+	t.Helper()
+	upstream := newRecordingUpstream(t)
+	scriptPath := writeFile(t, "policy.lua", policyScript)
+	cfg := loadConfig(t, `{"routes": [{
+		"name": "rpc", "path": "/rpc", "target": "`+upstream.URL+`",
+		"script": "`+scriptPath+`",
+		"method_cache_ttls": {"eth_blockNumber": "1m"}
+	}]}`)
+
+	g, _ := newTestGateway(t, clock.NewFake(testStart, 0))
+	g.SetRoutes(cfg.Routes)
+	if err := g.LoadScripts(); err != nil {
+		t.Fatal(err)
+	}
+	return g, upstream
+}
+
+func TestScriptDeniesCall(t *testing.T) {
+	g, upstream := newScriptedGateway(t)
+
+	w := serve(g, "POST", "/rpc", `{"jsonrpc":"2.0","method":"eth_sendRawTransaction","params":["0x00"],"id":1}`, nil)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want 403: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), "writes are not allowed") {
+		t.Errorf("response does not carry the script's reason: %s", w.Body)
+	}
+	if got := upstream.received(); len(got) != 0 {
+		t.Errorf("denied call reached the upstream: %v", got)
+	}
+}
+
+func TestScriptRewritesCall(t *testing.T) {
+	g, upstream := newScriptedGateway(t)
+
+	w := serve(g, "POST", "/rpc", `{"jsonrpc":"2.0","method":"legacy_blockNumber","id":1}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	got := upstream.received()
+	if len(got) != 1 {
+		t.Fatalf("upstream got %d calls, want 1", len(got))
+	}
+	var sent struct {
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}
+	if err := json.Unmarshal([]byte(got[0]), &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Method != "eth_blockNumber" || len(sent.Params) != 1 || sent.Params[0] != "latest" {
+		t.Errorf("upstream got %s, want the rewritten call", got[0])
+	}
+
+	// The answer was cached under the call actually sent, so asking for it directly hits the cache
+	w = serve(g, "POST", "/rpc", `{"jsonrpc":"2.0","method":"eth_blockNumber","params":["latest"],"id":2}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if got := upstream.received(); len(got) != 1 {
+		t.Errorf("upstream got %d calls, want the second answered from the cache", len(got))
+	}
+}
+
+func TestScriptRunsOnBatchItems(t *testing.T) {
+	g, upstream := newScriptedGateway(t)
+
+	// One denied item refuses the whole batch
+	w := serve(g, "POST", "/rpc", `[
+		{"jsonrpc":"2.0","method":"eth_chainId","id":1},
+		{"jsonrpc":"2.0","method":"eth_sendRawTransaction","params":["0x00"],"id":2}
+	]`, nil)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want 403: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), "batch item 2") {
+		t.Errorf("rejection does not name the denied item: %s", w.Body)
+	}
+	if got := upstream.received(); len(got) != 0 {
+		t.Fatalf("denied batch reached the upstream: %v", got)
+	}
+
+	// Rewrites apply item by item
+	w = serve(g, "POST", "/rpc", `[
+		{"jsonrpc":"2.0","method":"eth_chainId","id":1},
+		{"jsonrpc":"2.0","method":"legacy_blockNumber","id":2}
+	]`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	got := upstream.received()
+	if len(got) != 1 {
+		t.Fatalf("upstream got %d calls, want the batch once", len(got))
+	}
+	if !strings.Contains(got[0], `"eth_chainId"`) || !strings.Contains(got[0], `"eth_blockNumber"`) || strings.Contains(got[0], "legacy_blockNumber") {
+		t.Errorf("upstream got %s, want the second item rewritten", got[0])
+	}
+}
@@ -0,0 +1,22 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// SetSigningKey signs every proxied response with key, so downstream consumers
+// can verify it passed through the gateway and look it up in the audit trail
+func (g *Gateway) SetSigningKey(key []byte) {
+	g.signingKey = key
+}
+
+// stampResponse sets the audit request ID of a proxied response, so callers can
+// look the call up, and its signature when signing is enabled
+func (g *Gateway) stampResponse(w http.ResponseWriter, requestID string, body []byte) {
+	w.Header().Set(types.RequestIDHeader, requestID)
+	if len(g.signingKey) > 0 {
+		w.Header().Set(types.SignatureHeader, types.ResponseSignature(g.signingKey, requestID, body))
+	}
+}
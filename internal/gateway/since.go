@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetAuditLogsSince returns audit rows created or completed after a cursor, so
+// collectors can pull the audit trail incrementally. Start with ?since=<RFC3339>
+// (or no parameters for the full history) and pass next_cursor back as ?cursor=.
+func (g *Gateway) GetAuditLogsSince(w http.ResponseWriter, r *http.Request) {
+	limit := 500
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+
+	var cursor database.LogCursor
+	var err error
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+		cursor, err = database.ParseLogCursor(cursorStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			http.Error(w, "Invalid since timestamp, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+		if cursor, err = g.db.CursorAt(since); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to resolve cursor: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	logs, updates, next, hasMore, err := g.db.GetAuditLogsSince(cursor, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve audit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := previewLogs(r, logs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	previewLogs(r, updates)
+
+	// Always return arrays so collectors can iterate without nil checks
+	if logs == nil {
+		logs = []types.AuditLog{}
+	}
+	if updates == nil {
+		updates = []types.AuditLog{}
+	}
+
+	response := types.AuditLogsSinceResponse{
+		Logs:       logs,
+		Updates:    updates,
+		NextCursor: next.String(),
+		HasMore:    hasMore,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Multiwindow burn-rate alerting: a breach needs both the long and the short window over the threshold
+const (
+	fastBurnThreshold = 14.4 // Spends 2% of a 30 day budget in one hour
+	slowBurnThreshold = 6    // Spends 5% of a 30 day budget in six hours
+)
+
+// Webhook events fired by the SLO monitor
+const (
+	eventSLOBurnRate  = "slo.burn_rate"
+	eventSLORecovered = "slo.recovered"
+)
+
+// SetSLOs configures the latency objectives tracked by the gateway
+func (g *Gateway) SetSLOs(slos []config.SLO) {
+	g.slos = slos
+}
+
+// evaluateSLOs computes compliance and burn rates of all objectives
+func (g *Gateway) evaluateSLOs(now time.Time) ([]types.SLOStatus, error) {
+	statuses := make([]types.SLOStatus, 0, len(g.slos))
+	for _, slo := range g.slos {
+		starts := []time.Time{
+			now.Add(-slo.WindowDuration()),
+			now.Add(-time.Hour), now.Add(-5 * time.Minute),
+			now.Add(-6 * time.Hour), now.Add(-30 * time.Minute),
+		}
+		counts, err := g.db.LatencyCounts(slo.Method, slo.ThresholdMs, starts)
+		if err != nil {
+			return nil, fmt.Errorf("slo %s: %w", slo.Name, err)
+		}
+
+		budget := 1 - slo.Objective
+		window := counts[0]
+		status := types.SLOStatus{
+			Name:         slo.Name,
+			Method:       slo.Method,
+			ThresholdMs:  slo.ThresholdMs,
+			Objective:    slo.Objective,
+			Window:       slo.Window,
+			Total:        window.Total,
+			Good:         window.Good,
+			Compliance:   1,
+			FastBurnRate: burnRate(counts[1], budget),
+			SlowBurnRate: burnRate(counts[3], budget),
+		}
+		if window.Total > 0 {
+			status.Compliance = float64(window.Good) / float64(window.Total)
+		}
+		status.ErrorBudgetRemaining = 1 - burnRate(window, budget)
+
+		switch {
+		case status.FastBurnRate > fastBurnThreshold && burnRate(counts[2], budget) > fastBurnThreshold:
+			status.Alert = "fast_burn"
+		case status.SlowBurnRate > slowBurnThreshold && burnRate(counts[4], budget) > slowBurnThreshold:
+			status.Alert = "slow_burn"
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// burnRate is how fast the error budget is being spent, 1 meaning exactly on budget
+func burnRate(c types.LatencyCount, budget float64) float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Total-c.Good) / float64(c.Total) / budget
+}
+
+// StartSLOMonitor evaluates objectives every interval and notifies webhooks when an alert starts or clears
+func (g *Gateway) StartSLOMonitor(interval time.Duration) {
+	if len(g.slos) == 0 || g.db == nil {
+		return
+	}
+	g.sloStop = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		alerts := make(map[string]string)
+		for {
+			select {
+			case <-g.sloStop:
+				return
+			case now := <-ticker.C:
+				statuses, err := g.evaluateSLOs(now)
+				if err != nil {
+					log.Printf("Failed to evaluate SLOs: %v", err)
+					continue
+				}
+				for _, status := range statuses {
+					previous := alerts[status.Name]
+					alerts[status.Name] = status.Alert
+					switch {
+					case status.Alert != "" && status.Alert != previous:
+						log.Printf("ALERT: SLO %s %s (fast %.1fx, slow %.1fx)", status.Name, status.Alert, status.FastBurnRate, status.SlowBurnRate)
+						g.notify(eventSLOBurnRate, status)
+					case status.Alert == "" && previous != "":
+						log.Printf("SLO %s recovered", status.Name)
+						g.notify(eventSLORecovered, status)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// StopSLOMonitor stops the background SLO evaluation
+func (g *Gateway) StopSLOMonitor() {
+	if g.sloStop != nil {
+		close(g.sloStop)
+		g.sloStop = nil
+	}
+}
+
+// GetSLO returns the current status of all configured objectives
+func (g *Gateway) GetSLO(w http.ResponseWriter, r *http.Request) {
+	now := g.now()
+	statuses, err := g.evaluateSLOs(now)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to evaluate SLOs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.SLOResponse{SLOs: statuses, EvaluatedAt: now})
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// GetSlowLogs returns calls tagged slow=true, newest first
+func (g *Gateway) GetSlowLogs(w http.ResponseWriter, r *http.Request) {
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	filter := types.AuditLogFilter{
+		Method: r.URL.Query().Get("method"),
+		Tags:   map[string]string{types.SlowTag: "true"},
+	}
+	logs, err := g.db.SearchAuditLogs(filter, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve slow requests: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := previewLogs(r, logs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if logs == nil {
+		logs = []types.AuditLog{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.AuditLogsResponse{Logs: logs, Limit: limit, Offset: offset, Count: len(logs)})
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// startupState tracks the dependencies checked on startup. The gateway is
+// ready once each of them succeeded; it stays live while they are retried.
+type startupState struct {
+	mu    sync.Mutex
+	steps []types.StartupStep
+}
+
+// SetStartupStep records the progress of a startup step, adding it when new
+func (g *Gateway) SetStartupStep(step types.StartupStep) {
+	g.startup.mu.Lock()
+	defer g.startup.mu.Unlock()
+	for i := range g.startup.steps {
+		if g.startup.steps[i].Name == step.Name {
+			g.startup.steps[i] = step
+			return
+		}
+	}
+	g.startup.steps = append(g.startup.steps, step)
+}
+
+// startupSteps returns the startup steps and whether every one succeeded
+func (g *Gateway) startupSteps() ([]types.StartupStep, bool) {
+	g.startup.mu.Lock()
+	defer g.startup.mu.Unlock()
+	steps := make([]types.StartupStep, len(g.startup.steps))
+	copy(steps, g.startup.steps)
+	for _, step := range steps {
+		if step.Status != types.StartupOK {
+			return steps, false
+		}
+	}
+	return steps, true
+}
+
+// Targets returns the distinct upstream targets of the routes, primary and secondary
+func (g *Gateway) Targets() []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, route := range g.routes {
+		for _, target := range []string{route.Target, route.Secondary} {
+			if target != "" && !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// CheckTarget reports whether a connection to target can be opened. Only the
+// connection is tested, the target may still fail calls.
+func CheckTarget(ctx context.Context, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid target URL: %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// LiveCheck answers 200 while the gateway serves requests, for liveness
+// probes; it does not wait on startup steps or dependencies
+func (g *Gateway) LiveCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
+}
+
+// ReadyCheck answers 200 once every startup step succeeded and 503 before,
+// for readiness probes, with the health report as body
+func (g *Gateway) ReadyCheck(w http.ResponseWriter, r *http.Request) {
+	health := g.Health()
+	w.Header().Set("Content-Type", "application/json")
+	if !health.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
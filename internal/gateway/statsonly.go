@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// DefaultMinBucketSize is the smallest count published in stats-only mode unless configured
+const DefaultMinBucketSize = 5
+
+// SetStatsOnly enables aggregation-only mode: stats and time series stay open
+// to everyone, with buckets counting fewer than minBucket calls suppressed so
+// they can't single out a caller, while endpoints returning individual calls
+// require the operator role.
+func (g *Gateway) SetStatsOnly(minBucket int) {
+	if minBucket <= 0 {
+		minBucket = DefaultMinBucketSize
+	}
+	g.statsOnly = true
+	g.minBucket = minBucket
+}
+
+// requireRawAccess guards endpoints returning individual calls, which
+// stats-only mode reserves for operators and admins
+func (g *Gateway) requireRawAccess(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if g.statsOnly && !g.hasRole(r, types.RoleOperator) {
+			http.Error(w, "Raw audit data is disabled in stats-only mode, only aggregates are available", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// suppressed reports whether a bucket of n calls is too small to publish
+func (g *Gateway) suppressed(n int) bool {
+	return g.statsOnly && n > 0 && n < g.minBucket
+}
+
+// suppressStats drops breakdown entries counting too few calls
+func (g *Gateway) suppressStats(stats *types.Stats) {
+	counts := []map[string]int{stats.Methods, stats.StatusCodes, stats.RPCErrorCodes}
+	if stats.Batches != nil {
+		counts = append(counts, stats.Batches.Sizes)
+	}
+	for _, counts := range counts {
+		for key, n := range counts {
+			if g.suppressed(n) {
+				delete(counts, key)
+				stats.SuppressedBuckets++
+			}
+		}
+	}
+}
+
+// suppressHeatmap empties cells counting too few calls; the total only covers published cells
+func (g *Gateway) suppressHeatmap(heatmap *types.HeatmapResponse) {
+	heatmap.Total = 0
+	for _, row := range heatmap.Counts {
+		for i, n := range row {
+			if g.suppressed(n) {
+				row[i] = 0
+				heatmap.SuppressedCells++
+			}
+			heatmap.Total += row[i]
+		}
+	}
+}
+
+// suppressSeries empties time series points counting too few calls, and their
+// error counts when those alone are too few
+func (g *Gateway) suppressSeries(points []types.SeriesPoint) {
+	for i, p := range points {
+		if g.suppressed(p.Count) {
+			points[i] = types.SeriesPoint{Start: p.Start}
+		} else if g.suppressed(p.Errors) {
+			points[i].Errors = 0
+		}
+	}
+}
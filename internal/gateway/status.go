@@ -0,0 +1,212 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// statusCacheTTL bounds how often the public /status page queries the audit database
+const statusCacheTTL = 30 * time.Second
+
+// Incident detection over recent calls
+const (
+	outageWindow      = 5 * time.Minute
+	outageMinCalls    = 3   // Fewer calls are not enough to declare an outage
+	outageFailureRate = 0.5 // Share of failed calls in outageWindow meaning the upstream is down
+	errorsWindow      = 15 * time.Minute
+)
+
+// Status page incident kinds
+const (
+	incidentUpstreamDown    = "upstream_down"
+	incidentElevatedErrors  = "elevated_errors"
+	incidentSLOBurn         = "slo_burn"
+	incidentStorageDegraded = "storage_degraded"
+)
+
+// SetStatusPage configures the public /status page, nil restores the defaults
+func (g *Gateway) SetStatusPage(cfg *config.StatusPage) {
+	g.statusPage = config.StatusPage{}
+	if cfg != nil {
+		g.statusPage = *cfg
+	}
+	if g.statusPage.Objective == 0 {
+		g.statusPage.Objective = config.DefaultAvailabilityObjective
+	}
+	if g.statusPage.Title == "" {
+		g.statusPage.Title = "API status"
+	}
+
+	g.statusMu.Lock()
+	g.statusCache = nil
+	g.statusMu.Unlock()
+}
+
+// GetStatus serves a public summary of gateway uptime, upstream availability
+// and current incidents, as JSON or, for browsers and ?format=html, as an
+// HTML page that can be embedded in an iframe
+func (g *Gateway) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := g.status()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "html" || (format == "" && strings.Contains(r.Header.Get("Accept"), "text/html")) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := statusTemplate.Execute(w, statusView{StatusPage: status, Title: g.statusPage.Title}); err != nil {
+			log.Printf("Failed to render status page: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(status)
+}
+
+// status returns the cached status page, recomputing it when stale
+func (g *Gateway) status() (*types.StatusPage, error) {
+	g.statusMu.Lock()
+	defer g.statusMu.Unlock()
+
+	now := g.now()
+	if g.statusCache != nil && now.Sub(g.statusCache.Timestamp) < statusCacheTTL {
+		return g.statusCache, nil
+	}
+	status, err := g.computeStatus(now)
+	if err != nil {
+		return nil, err
+	}
+	g.statusCache = status
+	return status, nil
+}
+
+func (g *Gateway) computeStatus(now time.Time) (*types.StatusPage, error) {
+	objective := g.statusPage.Objective
+	if objective == 0 {
+		objective = config.DefaultAvailabilityObjective
+	}
+	status := &types.StatusPage{
+		Status:        "operational",
+		Timestamp:     now,
+		StartedAt:     g.startedAt,
+		UptimeSeconds: int64(now.Sub(g.startedAt).Seconds()),
+		Objective:     objective,
+		Incidents:     []types.StatusIncident{},
+	}
+	incident := func(kind, format string, args ...interface{}) {
+		status.Incidents = append(status.Incidents, types.StatusIncident{Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if g.db != nil {
+		windows := []struct {
+			name   string
+			window time.Duration
+		}{{"24h", 24 * time.Hour}, {"7d", 7 * 24 * time.Hour}}
+		starts := []time.Time{now.Add(-outageWindow), now.Add(-errorsWindow)}
+		for _, w := range windows {
+			starts = append(starts, now.Add(-w.window))
+		}
+		counts, err := g.db.AvailabilityCounts(starts)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, w := range windows {
+			c := counts[2+i]
+			window := types.StatusWindow{Window: w.name, Total: c.Total, Failed: c.Failed, Availability: 1, ErrorBudgetRemaining: 1}
+			if c.Total > 0 {
+				window.Availability = 1 - failureRate(c)
+				window.ErrorBudgetRemaining = 1 - failureRate(c)/(1-objective)
+			}
+			status.Windows = append(status.Windows, window)
+		}
+
+		// Elevated errors spend the budget as fast as a paging SLO alert, see slo.go
+		if c := counts[0]; c.Total >= outageMinCalls && failureRate(c) >= outageFailureRate {
+			incident(incidentUpstreamDown, "%d of the last %d calls failed", c.Failed, c.Total)
+		} else if c := counts[1]; c.Failed > 0 && failureRate(c)/(1-objective) > fastBurnThreshold {
+			incident(incidentElevatedErrors, "%.1f%% of calls failed in the last %s", 100*failureRate(c), errorsWindow)
+		}
+
+		if len(g.slos) > 0 {
+			slos, err := g.evaluateSLOs(now)
+			if err != nil {
+				return nil, err
+			}
+			for _, slo := range slos {
+				if slo.Alert != "" {
+					incident(incidentSLOBurn, "Latency objective %s is burning its error budget (%s)", slo.Name, slo.Alert)
+				}
+			}
+		}
+	}
+
+	if g.spool != nil && g.spool.Status().Degraded {
+		incident(incidentStorageDegraded, "Audit storage is unavailable, calls are buffered")
+	}
+
+	for _, i := range status.Incidents {
+		if i.Kind == incidentUpstreamDown {
+			status.Status = "outage"
+			break
+		}
+		status.Status = "degraded"
+	}
+	return status, nil
+}
+
+func failureRate(c types.AvailabilityCount) float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Failed) / float64(c.Total)
+}
+
+type statusView struct {
+	*types.StatusPage
+	Title string
+}
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.3f%%", 100*f) },
+	"uptime":  func(s int64) string { return (time.Duration(s) * time.Second).String() },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="60">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 0; padding: 20px; color: #333; }
+h1 { font-size: 20px; margin: 0 0 12px; }
+.banner { padding: 12px 16px; border-radius: 6px; color: white; font-weight: 600; margin-bottom: 16px; }
+.operational { background: #2e7d32; } .degraded { background: #ef6c00; } .outage { background: #c62828; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 16px; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #eee; }
+.muted { color: #777; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="banner {{.Status}}">{{if eq .Status "operational"}}All systems operational{{else if eq .Status "outage"}}Service outage{{else}}Degraded performance{{end}}</div>
+{{range .Incidents}}<p>&#9888; {{.Message}}</p>
+{{end}}{{if .Windows}}<table>
+<tr><th>Window</th><th>Availability</th><th>Calls</th><th>Error budget left</th></tr>
+{{range .Windows}}<tr><td>{{.Window}}</td><td>{{percent .Availability}}</td><td>{{.Total}}</td><td>{{percent .ErrorBudgetRemaining}}</td></tr>
+{{end}}</table>
+{{end}}<p class="muted">Objective {{percent .Objective}} &middot; up {{uptime .UptimeSeconds}} &middot; updated {{.Timestamp.UTC.Format "2006-01-02 15:04:05 MST"}}</p>
+</body>
+</html>
+`))
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// targetHeader routes a single call to another backend; honored for admin callers only
+const targetHeader = "X-Golf-Target"
+
+// gatewayCredentialHeaders carry the caller's gateway key or admin token and
+// are never forwarded; routes authenticate upstream with their upstream_auth
+var gatewayCredentialHeaders = []string{"Authorization", "X-Api-Key"}
+
+// SetTargetOverrides configures the hosts or URL prefixes X-Golf-Target may
+// name, see config.Config.TargetOverrides. Without any the header is refused.
+func (g *Gateway) SetTargetOverrides(allowed []string) {
+	g.targetOverrides = allowed
+}
+
+// targetOverride returns the backend URL a caller asked for with the
+// X-Golf-Target header, or "" when the header is absent. Callers without the
+// admin role and targets not on the allowlist get a 403 status, malformed
+// URLs a 400.
+func (g *Gateway) targetOverride(r *http.Request) (string, int, error) {
+	target := r.Header.Get(targetHeader)
+	if target == "" {
+		return "", 0, nil
+	}
+	if !g.hasRole(r, types.RoleAdmin) {
+		return "", http.StatusForbidden, fmt.Errorf("%s requires the admin role", targetHeader)
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return "", http.StatusBadRequest, fmt.Errorf("%s must be an absolute http or https URL", targetHeader)
+	}
+	if !overrideAllowed(g.targetOverrides, u) {
+		return "", http.StatusForbidden, fmt.Errorf("%s %s is not in target_overrides", targetHeader, u.Redacted())
+	}
+	return u.String(), 0, nil
+}
+
+// overrideAllowed reports whether u matches an allowlist entry: a bare host
+// matches any URL on that host, a URL prefix needs the same scheme and host
+// and a path at or below its own
+func overrideAllowed(allowed []string, u *url.URL) bool {
+	for _, entry := range allowed {
+		if !strings.Contains(entry, "://") {
+			if strings.EqualFold(u.Host, entry) {
+				return true
+			}
+			continue
+		}
+		prefix, err := url.Parse(entry)
+		if err != nil || prefix.Scheme != u.Scheme || !strings.EqualFold(prefix.Host, u.Host) {
+			continue
+		}
+		base := strings.TrimSuffix(prefix.Path, "/")
+		if u.Path == base || strings.HasPrefix(u.Path, base+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/niki4smirn/golf/internal/clock"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+func TestOverrideAllowed(t *testing.T) {
+	allowed := []string{"staging.internal", "https://canary.example.com/v1"}
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"http://staging.internal/rpc", true},
+		{"https://STAGING.internal", true},
+		{"http://staging.internal:8545/rpc", false}, // Another port is another host
+		{"https://canary.example.com/v1", true},
+		{"https://canary.example.com/v1/rpc", true},
+		{"https://canary.example.com/v10", false},
+		{"http://canary.example.com/v1", false}, // Scheme must match
+		{"https://evil.example.com/v1", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := overrideAllowed(allowed, u); got != tt.want {
+			t.Errorf("overrideAllowed(%s) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestTargetOverride(t *testing.T) {
+	upstream := newRecordingUpstream(t)
+	canary := newRecordingUpstream(t)
+	g, _ := newTestGateway(t, clock.NewFake(testStart, 0))
+	g.SetRoutes(config.DefaultRoutes(upstream.URL))
+	g.SetAdminToken("admin-secret")
+	g.SetAPIKeys([]config.APIKey{{Key: "operator-key", Name: "ops", ClientPolicy: types.ClientPolicy{Role: types.RoleOperator}}})
+	g.SetTargetOverrides([]string{canary.URL})
+	call := `{"jsonrpc":"2.0","method":"eth_chainId","id":1}`
+
+	withTarget := func(h http.Header, target string) http.Header {
+		h.Set(targetHeader, target)
+		return h
+	}
+
+	// Only admins may redirect calls
+	w := serve(g, "POST", "/rpc", call, withTarget(http.Header{"X-Api-Key": {"operator-key"}}, canary.URL))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("operator override: status %d, want 403", w.Code)
+	}
+
+	// Admins only to allowed targets
+	w = serve(g, "POST", "/rpc", call, withTarget(bearer("admin-secret"), "https://elsewhere.example.com"))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("override outside the allowlist: status %d, want 403", w.Code)
+	}
+	if n := len(upstream.received()) + len(canary.received()); n != 0 {
+		t.Fatalf("refused overrides reached a target %d times", n)
+	}
+
+	w = serve(g, "POST", "/rpc", call, withTarget(bearer("admin-secret"), canary.URL))
+	if w.Code != http.StatusOK {
+		t.Fatalf("allowed override: status %d: %s", w.Code, w.Body)
+	}
+	if len(canary.received()) != 1 || len(upstream.received()) != 0 {
+		t.Fatalf("override went to the route's target instead of %s", canary.URL)
+	}
+	if h := canary.lastHeaders(); h.Get("Authorization") != "" || h.Get(targetHeader) != "" {
+		t.Errorf("admin token or %s forwarded to the override target: %v", targetHeader, h)
+	}
+}
+
+func TestGatewayCredentialsNotForwarded(t *testing.T) {
+	upstream := newRecordingUpstream(t)
+	g, _ := newTestGateway(t, clock.NewFake(testStart, 0))
+	g.SetRoutes(config.DefaultRoutes(upstream.URL))
+	g.SetAPIKeys([]config.APIKey{{Key: "client-key", Name: "svc"}})
+
+	for _, h := range []http.Header{{"X-Api-Key": {"client-key"}}, bearer("client-key")} {
+		w := serve(g, "POST", "/rpc", `{"jsonrpc":"2.0","method":"eth_chainId","id":1}`, h)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		got := upstream.lastHeaders()
+		if got.Get("X-Api-Key") != "" || got.Get("Authorization") != "" {
+			t.Errorf("gateway key forwarded upstream: %v", got)
+		}
+	}
+}
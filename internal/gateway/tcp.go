@@ -0,0 +1,228 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+)
+
+// tcpIdleTimeout drops pooled connections the target has probably closed by now
+const tcpIdleTimeout = 30 * time.Second
+
+// maxTCPMessageSize bounds a single framed response
+const maxTCPMessageSize = 64 << 20
+
+// tcpPool keeps connections to a raw TCP JSON-RPC target. Each connection
+// carries one call at a time, so responses never need to be matched by id.
+type tcpPool struct {
+	addr    string
+	framing string
+	maxIdle int
+
+	mu   sync.Mutex
+	idle []*tcpConn
+}
+
+type tcpConn struct {
+	net.Conn
+	reader   *bufio.Reader
+	idleFrom time.Time
+}
+
+// buildTCPPools creates one pool per tcp:// target or secondary. Routes
+// sharing a target share the pool of the first route.
+func buildTCPPools(routes []config.Route) map[string]*tcpPool {
+	pools := make(map[string]*tcpPool)
+	for _, route := range routes {
+		for _, target := range []string{route.Target, route.Secondary} {
+			if !strings.HasPrefix(target, "tcp://") {
+				continue
+			}
+			if _, ok := pools[target]; ok {
+				continue
+			}
+			pools[target] = &tcpPool{
+				addr:    strings.TrimPrefix(target, "tcp://"),
+				framing: route.Framing,
+				maxIdle: route.MaxIdleConns,
+			}
+		}
+	}
+	return pools
+}
+
+// get returns an idle connection or dials a new one
+func (p *tcpPool) get(ctx context.Context) (*tcpConn, error) {
+	p.mu.Lock()
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if time.Since(conn.idleFrom) < tcpIdleTimeout {
+			p.mu.Unlock()
+			return conn, nil
+		}
+		conn.Close()
+	}
+	p.mu.Unlock()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// put returns a healthy connection to the pool
+func (p *tcpPool) put(conn *tcpConn) {
+	conn.SetDeadline(time.Time{})
+	conn.idleFrom = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) >= p.maxIdle {
+		conn.Close()
+		return
+	}
+	p.idle = append(p.idle, conn)
+}
+
+// do sends body to the target and wraps the reply in an HTTP response, so it
+// can be audited and returned like the answer of an HTTP target.
+// Notifications get no reply and are answered with 204 No Content.
+func (p *tcpPool) do(ctx context.Context, body []byte) (*http.Response, error) {
+	conn, err := p.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Honour the call deadline and abort when the client goes away
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	reply, err := p.exchange(conn, body, isNotification(body))
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w: %v", ctx.Err(), err)
+		}
+		return nil, err
+	}
+	p.put(conn)
+
+	if reply == nil {
+		return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: http.NoBody}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(reply)),
+	}, nil
+}
+
+// exchange writes one framed message and reads the framed reply, unless the
+// message is a notification
+func (p *tcpPool) exchange(conn *tcpConn, body []byte, notification bool) ([]byte, error) {
+	if p.framing == config.FramingContentLength {
+		if _, err := fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+			return nil, fmt.Errorf("failed to write to %s: %w", p.addr, err)
+		}
+		if notification {
+			return nil, nil
+		}
+		return readContentLength(conn.reader)
+	}
+
+	// A newline ends the message, so the document itself must be on one line
+	var line bytes.Buffer
+	if err := json.Compact(&line, body); err != nil {
+		return nil, fmt.Errorf("request is not valid JSON: %w", err)
+	}
+	line.WriteByte('\n')
+	if _, err := conn.Write(line.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write to %s: %w", p.addr, err)
+	}
+	if notification {
+		return nil, nil
+	}
+	return readLine(conn.reader)
+}
+
+// readLine reads one newline-terminated message, skipping blank lines
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	for {
+		var message []byte
+		for {
+			chunk, isPrefix, err := reader.ReadLine()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response: %w", err)
+			}
+			message = append(message, chunk...)
+			if len(message) > maxTCPMessageSize {
+				return nil, fmt.Errorf("response exceeds %d bytes", maxTCPMessageSize)
+			}
+			if !isPrefix {
+				break
+			}
+		}
+		if len(bytes.TrimSpace(message)) > 0 {
+			return message, nil
+		}
+	}
+}
+
+// readContentLength reads a header block followed by Content-Length bytes of body
+func readContentLength(reader *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response headers: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 || length > maxTCPMessageSize {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// isNotification reports whether body is a JSON-RPC notification, or a batch
+// of them, which the target does not answer
+func isNotification(body []byte) bool {
+	var batch []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &batch); err == nil {
+		for _, call := range batch {
+			if _, ok := call["id"]; ok {
+				return false
+			}
+		}
+		return len(batch) > 0
+	}
+
+	var call map[string]json.RawMessage
+	if err := json.Unmarshal(body, &call); err != nil {
+		return false
+	}
+	_, hasID := call["id"]
+	_, hasMethod := call["method"]
+	return hasMethod && !hasID
+}
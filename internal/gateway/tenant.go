@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// tenantScopeAll is the scope of the admin view across every tenant
+const tenantScopeAll = "all"
+
+// tenantScope returns the tenant whose traffic the caller may see. API keys
+// are limited to their own tenant; admins see all tenants, or the one picked
+// with ?tenant=. It writes the error response when ok is false.
+func (g *Gateway) tenantScope(w http.ResponseWriter, r *http.Request) (tenant string, ok bool) {
+	if g.hasRole(r, types.RoleAdmin) {
+		return r.URL.Query().Get("tenant"), true
+	}
+
+	client := g.identifyClient(r)
+	if client == nil {
+		http.Error(w, "An API key or the admin token is required", http.StatusUnauthorized)
+		return "", false
+	}
+	if client.Tenant == "" {
+		http.Error(w, fmt.Sprintf("API key %s does not belong to a tenant", client.Name), http.StatusForbidden)
+		return "", false
+	}
+	if requested := r.URL.Query().Get("tenant"); requested != "" && requested != client.Tenant {
+		http.Error(w, fmt.Sprintf("API key %s cannot read tenant %s", client.Name, requested), http.StatusForbidden)
+		return "", false
+	}
+	return client.Tenant, true
+}
+
+// GetTenantLogs returns the caller's audit logs, newest first
+func (g *Gateway) GetTenantLogs(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := g.tenantScope(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	filter := types.AuditLogFilter{Method: r.URL.Query().Get("method"), Tenant: tenant}
+	logs, err := g.db.SearchAuditLogs(filter, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve audit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := previewLogs(r, logs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if logs == nil {
+		logs = []types.AuditLog{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.AuditLogsResponse{Logs: logs, Limit: limit, Offset: offset, Count: len(logs)})
+}
+
+// GetTenantStats summarizes the caller's traffic, or every tenant's for the admin
+func (g *Gateway) GetTenantStats(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := g.tenantScope(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := g.db.GetTenantStats(tenant)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve tenant stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	scope := tenant
+	if scope == "" {
+		scope = tenantScopeAll
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.TenantStatsResponse{Scope: scope, Tenants: stats})
+}
+
+// serveTenantDashboard serves the per-tenant dashboard. The page asks for an
+// API key, or the admin token for the all-tenants view, and sends it with
+// every call, so no tenant data is embedded in the page itself.
+func serveTenantDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(tenantDashboard))
+}
+
+const tenantDashboard = `<!DOCTYPE html>
+<html>
+<head>
+    <title>JSON-RPC Gateway - Tenant</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 40px; background: #f5f5f5; }
+        .container { max-width: 1200px; margin: 0 auto; background: white; padding: 30px; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
+        h1 { color: #333; border-bottom: 3px solid #007cba; padding-bottom: 10px; }
+        .stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 20px; margin: 20px 0; }
+        .stat-card { background: #e7f3ff; padding: 20px; border-radius: 8px; text-align: center; }
+        .stat-number { font-size: 2em; font-weight: bold; color: #007cba; }
+        .button { background: #007cba; color: white; padding: 8px 16px; border: none; border-radius: 5px; cursor: pointer; }
+        .logs { border-collapse: collapse; width: 100%; font-size: 14px; }
+        .logs td, .logs th { text-align: left; padding: 6px 10px; border-bottom: 1px solid #eee; }
+        .error { color: #c0392b; }
+        input { padding: 8px; width: 360px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>JSON-RPC Gateway <small id="scope"></small></h1>
+        <form id="login">
+            <input type="password" id="key" placeholder="API key, or admin token for all tenants">
+            <select id="kind"><option value="key">API key</option><option value="admin">Admin token</option></select>
+            <button class="button">Show</button>
+        </form>
+        <p id="message" class="error"></p>
+        <div id="tenants"></div>
+        <h2>Recent calls</h2>
+        <table class="logs" id="logs"></table>
+    </div>
+
+    <script>
+        const escapeHTML = s => String(s == null ? '' : s).replace(/[&<>"]/g, c => ({'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;'}[c]));
+
+        // Credentials stay in this browser tab only
+        function headers() {
+            const key = sessionStorage.getItem('golfKey') || '';
+            return sessionStorage.getItem('golfKind') === 'admin' ? {'Authorization': 'Bearer ' + key} : {'X-API-Key': key};
+        }
+
+        function get(path) {
+            return fetch(path, {headers: headers()}).then(r => r.ok ? r.json() : r.text().then(t => Promise.reject(t)));
+        }
+
+        function load() {
+            if (!sessionStorage.getItem('golfKey')) return;
+            const tenant = new URLSearchParams(location.search).get('tenant');
+            const query = tenant ? '?tenant=' + encodeURIComponent(tenant) : '';
+            get('/tenant/stats' + query).then(data => {
+                document.getElementById('message').textContent = '';
+                document.getElementById('scope').textContent = data.scope === 'all' ? '(all tenants)' : '(' + data.scope + ')';
+                document.getElementById('tenants').innerHTML = data.tenants.map(t =>
+                    '<h2><a href="?tenant=' + encodeURIComponent(t.tenant) + '">' + escapeHTML(t.tenant) + '</a></h2><div class="stats">' +
+                    '<div class="stat-card"><div class="stat-number">' + t.requests + '</div><div>Requests</div></div>' +
+                    '<div class="stat-card"><div class="stat-number">' + t.errors + '</div><div>Errors</div></div>' +
+                    '<div class="stat-card"><div class="stat-number">' + Math.round(t.avg_latency_ms) + 'ms</div><div>Avg latency</div></div>' +
+                    '</div>').join('') || '<p>No traffic yet.</p>';
+            }).catch(err => { document.getElementById('message').textContent = err; });
+            get('/tenant/logs' + query).then(data => {
+                let html = '<tr><th>Time</th><th>Tenant</th><th>Key</th><th>Method</th><th>Status</th><th>Latency</th></tr>';
+                data.logs.forEach(l => {
+                    html += '<tr><td>' + new Date(l.timestamp).toLocaleString() + '</td><td>' + escapeHTML(l.tenant) + '</td><td>' + escapeHTML(l.api_key) +
+                        '</td><td>' + escapeHTML(l.method) + '</td><td>' + l.status_code + '</td><td>' + l.process_time_ms + 'ms</td></tr>';
+                });
+                document.getElementById('logs').innerHTML = html;
+            }).catch(() => {});
+        }
+
+        document.getElementById('login').addEventListener('submit', e => {
+            e.preventDefault();
+            sessionStorage.setItem('golfKey', document.getElementById('key').value);
+            sessionStorage.setItem('golfKind', document.getElementById('kind').value);
+            load();
+        });
+        load();
+    </script>
+</body>
+</html>`
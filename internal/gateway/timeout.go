@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// upstreamTimeoutCode is the JSON-RPC error code returned when a call exceeds its deadline
+const upstreamTimeoutCode = -32006
+
+// statusClientClosedRequest is recorded for calls the client abandoned, after nginx's 499
+const statusClientClosedRequest = 499
+
+// callTimeout returns the deadline applied to an upstream call: the route's
+// timeout for the method, capped by the HTTP client timeout
+func (g *Gateway) callTimeout(routeTimeout time.Duration) time.Duration {
+	clientTimeout := g.httpClient.Timeout
+	if routeTimeout > 0 && (clientTimeout <= 0 || routeTimeout < clientTimeout) {
+		return routeTimeout
+	}
+	return clientTimeout
+}
+
+// isTimeout reports whether err was caused by a deadline rather than a broken connection
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// handleUpstreamFailure answers a call whose upstream produced no response.
+// Timeouts get a structured error advertising the deadline; connection errors
+// keep the generic Bad Gateway. Calls aborted because the client went away
+// are recorded as cancelled rather than blamed on the upstream.
+func (g *Gateway) handleUpstreamFailure(w http.ResponseWriter, r *http.Request, call *proxyCall, err error) {
+	if r.Context().Err() != nil {
+		g.handleClientCancel(w, call, err)
+		return
+	}
+	if isTimeout(err) {
+		data := types.TimeoutErrorData{
+			TimeoutMs: call.timeout.Milliseconds(),
+			ElapsedMs: g.since(call.startTime).Milliseconds(),
+			RequestID: call.requestID,
+		}
+		rpcErr := failureError(call.route, config.ErrorTimeout, upstreamTimeoutCode, "Upstream timeout", data)
+		errorMsg := fmt.Sprintf("upstream call exceeded its %s deadline: %v", call.timeout, err)
+		g.writeRPCError(w, call.id, rpcErr, errorMsg, call.requestID, call.startTime, http.StatusGatewayTimeout, types.FailureTimeout)
+		return
+	}
+
+	errorMsg := fmt.Sprintf("Failed to forward request: %v", err)
+	rpcErr := failureError(call.route, config.ErrorConnection, -32603, "Internal error", errorMsg)
+	g.writeRPCError(w, call.id, rpcErr, errorMsg, call.requestID, call.startTime, http.StatusBadGateway, types.FailureConnection)
+}
+
+// handleClientCancel records a call whose client disconnected before it was
+// answered. The error is still written, though nobody is left to read it.
+func (g *Gateway) handleClientCancel(w http.ResponseWriter, call *proxyCall, err error) {
+	errorMsg := fmt.Sprintf("client cancelled the request after %s: %v", g.since(call.startTime).Round(time.Millisecond), err)
+	rpcErr := &types.JSONRPCError{Code: -32603, Message: "Request cancelled", Data: errorMsg}
+	g.writeRPCError(w, call.id, rpcErr, errorMsg, call.requestID, call.startTime, statusClientClosedRequest, types.FailureClientCancelled)
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// upstreamTimer times the phases of one upstream HTTP exchange
+type upstreamTimer struct {
+	now func() time.Time
+
+	dnsStart     time.Time
+	dns          time.Duration
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	tls          time.Duration
+	wrote        time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+// reset forgets an earlier attempt, such as the primary target before a failover
+func (t *upstreamTimer) reset() {
+	*t = upstreamTimer{now: t.now}
+}
+
+// trace returns ctx instrumented to time the request sent with it
+func (t *upstreamTimer) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.dnsStart = t.now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.dns = t.now().Sub(t.dnsStart) },
+		ConnectStart: func(string, string) {
+			// Dual-stack dialing may race several connections, time from the first
+			if t.connectStart.IsZero() {
+				t.connectStart = t.now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.connect = t.now().Sub(t.connectStart) },
+		TLSHandshakeStart:    func() { t.tlsStart = t.now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tls = t.now().Sub(t.tlsStart) },
+		GotConn:              func(info httptrace.GotConnInfo) { t.reused = info.Reused },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.wrote = t.now() },
+		GotFirstResponseByte: func() { t.firstByte = t.now() },
+	})
+}
+
+// timing returns the phases of an exchange whose body was read at readDone,
+// or nil when no HTTP response arrived
+func (t *upstreamTimer) timing(readDone time.Time) *types.UpstreamTiming {
+	if t == nil || t.firstByte.IsZero() {
+		return nil
+	}
+	timing := &types.UpstreamTiming{
+		DNSMs:      milliseconds(t.dns),
+		ConnectMs:  milliseconds(t.connect),
+		TLSMs:      milliseconds(t.tls),
+		ReadMs:     milliseconds(readDone.Sub(t.firstByte)),
+		ReusedConn: t.reused,
+	}
+	if !t.wrote.IsZero() {
+		timing.TTFBMs = milliseconds(t.firstByte.Sub(t.wrote))
+	}
+	return timing
+}
+
+// milliseconds converts d keeping microsecond precision
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
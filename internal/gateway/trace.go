@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// SetCorrelation configures where calls name their parent call. Nil disables parent links.
+func (g *Gateway) SetCorrelation(cfg *config.Correlation) {
+	if cfg == nil {
+		g.correlation = nil
+		return
+	}
+	correlation := *cfg
+	if correlation.Header == "" && correlation.Path == "" {
+		correlation.Header = config.DefaultParentHeader
+	}
+	g.correlation = &correlation
+}
+
+// parentRequestID returns the request ID of the call that triggered this one, if the caller named it
+func (g *Gateway) parentRequestID(r *http.Request, body []byte) string {
+	if g.correlation == nil {
+		return ""
+	}
+	if g.correlation.Header != "" {
+		if parent := strings.TrimSpace(r.Header.Get(g.correlation.Header)); parent != "" {
+			return parent
+		}
+	}
+	if g.correlation.Path != "" {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err == nil {
+			if parent, ok := lookupPath(doc, g.correlation.Path); ok {
+				return parent
+			}
+		}
+	}
+	return ""
+}
+
+// GetTrace returns the call tree containing a request: its outermost recorded
+// ancestor and every nested call, linked through the configured correlation field
+func (g *Gateway) GetTrace(w http.ResponseWriter, r *http.Request) {
+	requestID := mux.Vars(r)["request_id"]
+
+	logs, err := g.db.GetTrace(requestID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve trace: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(logs) == 0 {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+
+	nodes := make(map[string]*types.TraceNode, len(logs))
+	for _, log := range logs {
+		nodes[log.RequestID] = &types.TraceNode{AuditLog: log}
+	}
+
+	// Logs are oldest first, so children keep their call order
+	response := types.TraceResponse{RequestID: requestID, Calls: len(logs)}
+	for _, log := range logs {
+		node := nodes[log.RequestID]
+		if parent, ok := nodes[log.ParentRequestID]; ok && parent != node {
+			parent.Children = append(parent.Children, node)
+		} else if response.Root == nil {
+			response.Root = node
+		}
+	}
+	response.Depth = traceDepth(response.Root)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// traceDepth returns the number of levels below node
+func traceDepth(node *types.TraceNode) int {
+	depth := 0
+	for _, child := range node.Children {
+		if d := traceDepth(child) + 1; d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/niki4smirn/golf/internal/config"
+)
+
+// transformResponse applies a response transform to the result of a JSON-RPC
+// response body. It returns nil when the body has no result to rewrite, so
+// errors and batches pass through unchanged.
+func transformResponse(t *config.ResponseTransform, body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // Keep large integers exact
+	var response map[string]interface{}
+	if err := decoder.Decode(&response); err != nil {
+		return nil
+	}
+	result, ok := response["result"]
+	if !ok || result == nil {
+		return nil
+	}
+
+	for path, name := range t.Rename {
+		parents, field := transformTargets(result, path)
+		for _, obj := range parents {
+			if value, ok := obj[field]; ok {
+				delete(obj, field)
+				obj[name] = value
+			}
+		}
+	}
+	for path, value := range t.Defaults {
+		parents, field := transformTargets(result, path)
+		for _, obj := range parents {
+			if _, ok := obj[field]; !ok {
+				obj[field] = value
+			}
+		}
+	}
+	for _, path := range t.Strip {
+		parents, field := transformTargets(result, path)
+		for _, obj := range parents {
+			delete(obj, field)
+		}
+	}
+
+	transformed, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Failed to encode transformed response: %v", err)
+		return nil
+	}
+	return transformed
+}
+
+// transformTargets returns the objects holding the last field of path within
+// result, one per array element where the path goes through items[], and the
+// field name
+func transformTargets(result interface{}, path string) ([]map[string]interface{}, string) {
+	segments := strings.Split(strings.TrimPrefix(path, "$."), ".")
+	field := segments[len(segments)-1]
+
+	current := []interface{}{result}
+	for _, segment := range segments[:len(segments)-1] {
+		name := strings.TrimSuffix(segment, "[]")
+		var next []interface{}
+		for _, value := range current {
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, ok := obj[name]
+			if !ok {
+				continue
+			}
+			if name == segment {
+				next = append(next, child)
+			} else if items, ok := child.([]interface{}); ok {
+				next = append(next, items...)
+			}
+		}
+		current = next
+	}
+
+	parents := make([]map[string]interface{}, 0, len(current))
+	for _, value := range current {
+		if obj, ok := value.(map[string]interface{}); ok {
+			parents = append(parents, obj)
+		}
+	}
+	return parents, field
+}
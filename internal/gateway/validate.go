@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// inspectUpstreamResponse checks that an upstream body is a valid JSON-RPC 2.0
+// response (or batch of responses). It returns a non-empty reason when the body
+// is malformed, and the error code when the response is a single JSON-RPC error.
+func inspectUpstreamResponse(body []byte) (string, *int) {
+	data := bytes.TrimSpace(types.UnwrapSSE(body))
+
+	// Notifications legitimately produce an empty body
+	if len(data) == 0 {
+		return "", nil
+	}
+
+	if !json.Valid(data) {
+		return "response is not valid JSON", nil
+	}
+
+	if data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return fmt.Sprintf("invalid batch response: %v", err), nil
+		}
+		if len(batch) == 0 {
+			return "empty batch response", nil
+		}
+		for i, item := range batch {
+			if reason, _ := inspectSingleResponse(item); reason != "" {
+				return fmt.Sprintf("batch item %d: %s", i, reason), nil
+			}
+		}
+		return "", nil
+	}
+
+	return inspectSingleResponse(data)
+}
+
+func inspectSingleResponse(data []byte) (string, *int) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "response is not a JSON object", nil
+	}
+
+	var version string
+	if err := json.Unmarshal(fields["jsonrpc"], &version); err != nil || version != "2.0" {
+		return `missing or invalid "jsonrpc": "2.0" member`, nil
+	}
+
+	if _, ok := fields["id"]; !ok {
+		return `missing "id" member`, nil
+	}
+
+	_, hasResult := fields["result"]
+	rawError, hasError := fields["error"]
+	if hasResult == hasError {
+		return `response must contain exactly one of "result" or "error"`, nil
+	}
+
+	if hasError {
+		var rpcErr types.JSONRPCError
+		if err := json.Unmarshal(rawError, &rpcErr); err != nil {
+			return fmt.Sprintf("invalid error object: %v", err), nil
+		}
+		return "", &rpcErr.Code
+	}
+
+	return "", nil
+}
+
+// classifyUpstreamResponse tags the audit response and raises an alert for malformed bodies
+func (g *Gateway) classifyUpstreamResponse(auditResponse *types.AuditResponse, body []byte) {
+	reason, code := inspectUpstreamResponse(body)
+	auditResponse.RPCErrorCode = code
+
+	if reason != "" {
+		auditResponse.MalformedUpstream = true
+		total := atomic.AddInt64(&g.malformedUpstream, 1)
+		log.Printf("ALERT: malformed upstream response for %s (HTTP %d, %d total): %s",
+			auditResponse.RequestID, auditResponse.StatusCode, total, reason)
+	}
+}
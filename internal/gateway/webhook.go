@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// SetWebhooks configures endpoints notified of gateway events
+func (g *Gateway) SetWebhooks(webhooks []config.Webhook) {
+	g.webhooks = webhooks
+}
+
+// notify delivers an event to all subscribed webhooks in the background
+func (g *Gateway) notify(event string, data interface{}) {
+	body, err := json.Marshal(types.WebhookEvent{Event: event, Timestamp: g.now(), Data: data})
+	if err != nil {
+		log.Printf("Failed to marshal %s webhook: %v", event, err)
+		return
+	}
+
+	for _, hook := range g.webhooks {
+		if !hook.Wants(event) {
+			continue
+		}
+		go func(url string) {
+			if err := postWebhook(url, body); err != nil {
+				log.Printf("Failed to deliver %s webhook to %s: %v", event, url, err)
+			}
+		}(hook.URL)
+	}
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+func postWebhook(url string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golf-audit-gateway")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
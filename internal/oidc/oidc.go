@@ -0,0 +1,250 @@
+// Package oidc signs users in with an OpenID Connect provider through the
+// authorization code flow with PKCE, and verifies the ID tokens it returns
+// against the provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// keysRefreshInterval is the least time between two reads of the provider's
+// keys, which are read again when a token is signed with an unknown key
+const keysRefreshInterval = time.Minute
+
+// clockSkew is how far token times may be off the gateway's clock
+const clockSkew = time.Minute
+
+// discovery is the part of the provider's openid-configuration the flow needs
+type discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Provider signs users in with the issuer of cfg
+type Provider struct {
+	cfg    *config.OIDC
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *discovery             // Nil until read, read again after a failure
+	keys        map[string]interface{} // *rsa.PublicKey or *ecdsa.PublicKey by key ID
+	keysFetched time.Time
+}
+
+// New creates a provider for cfg. The issuer's configuration is read on the first sign-in.
+func New(cfg *config.OIDC) *Provider {
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Start returns the URL of the provider's login page for a sign-in
+// identified by state, and the verifier Finish needs to complete it
+func (p *Provider) Start(ctx context.Context, state string) (string, string, error) {
+	d, err := p.configuration(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate verifier: %w", err)
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(buf)
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce(verifier)},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode(), verifier, nil
+}
+
+// Finish exchanges the authorization code the provider sent the browser back
+// with for an ID token and returns the user it names
+func (p *Provider) Finish(ctx context.Context, r *http.Request, verifier string) (*types.Identity, error) {
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		if description := query.Get("error_description"); description != "" {
+			e += ": " + description
+		}
+		return nil, fmt.Errorf("provider refused the sign-in: %s", e)
+	}
+	code := query.Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("callback has no authorization code")
+	}
+
+	d, err := p.configuration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawToken, err := p.exchange(ctx, d, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.verify(ctx, d, rawToken, nonce(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	identity := &types.Identity{Groups: stringList(claims[p.cfg.GroupsClaim])}
+	for _, claim := range []string{p.cfg.UserClaim, "sub"} {
+		if user, ok := claims[claim].(string); ok && user != "" {
+			identity.User = user
+			break
+		}
+	}
+	if identity.User == "" {
+		return nil, fmt.Errorf("ID token names no user")
+	}
+	return identity, nil
+}
+
+// configuration reads the issuer's openid-configuration once
+func (p *Provider) configuration(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d discovery
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("failed to read OIDC configuration: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("provider names issuer %q, expected %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC configuration lacks authorization, token or jwks endpoint")
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// exchange redeems the authorization code at the token endpoint for the raw ID token
+func (p *Provider) exchange(ctx context.Context, d *discovery, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	secret := p.cfg.ClientSecretValue()
+	basic := secret != "" && (len(d.TokenAuthMethods) == 0 || containsString(d.TokenAuthMethods, "client_secret_basic"))
+	if secret != "" && !basic {
+		form.Set("client_secret", secret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(secret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, body)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", fmt.Errorf("token response has no id_token, is the openid scope granted?")
+	}
+	return tokens.IDToken, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// challenge is the PKCE S256 code challenge of verifier
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// nonce binds the ID token to the sign-in; derived from the verifier so the
+// gateway only keeps one secret per sign-in
+func nonce(verifier string) string {
+	sum := sha256.Sum256([]byte("nonce:" + verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// stringList reads a claim holding a list of strings or a single string
+func stringList(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+// Package otlp exports audit request/response pairs to an OpenTelemetry
+// collector as spans or log records, over OTLP/HTTP with JSON encoding, so
+// gateway traffic lands in Tempo, Loki or any other OTLP backend.
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// maxPending bounds the requests waiting for their response; the oldest is
+// exported alone when a new one arrives at the limit
+const maxPending = 10000
+
+// Exporter pairs audit requests with their responses and exports each call
+// once the response arrives, in batches. It implements database.AuditWriter.
+type Exporter struct {
+	cfg    config.OTLP
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	pending map[string]*types.AuditRequest // Requests waiting for their response by request ID
+	order   []string                       // Request IDs of pending in arrival order
+	batch   []call
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewExporter creates an exporter sending to cfg.Endpoint and starts flushing
+// partial batches every flush interval. Close flushes what is left.
+func NewExporter(cfg config.OTLP) *Exporter {
+	e := &Exporter{
+		cfg:     cfg,
+		url:     strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/" + cfg.Signal,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		pending: make(map[string]*types.AuditRequest),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// InsertAuditRequest holds the request until its response arrives
+func (e *Exporter) InsertAuditRequest(req *types.AuditRequest) error {
+	e.mu.Lock()
+	var evicted *types.AuditRequest
+	if len(e.pending) >= maxPending {
+		evicted = e.evictOldest()
+	}
+	e.pending[req.RequestID] = req
+	e.order = append(e.order, req.RequestID)
+	e.mu.Unlock()
+
+	if evicted != nil {
+		return e.add(call{req: evicted})
+	}
+	return nil
+}
+
+// InsertAuditResponse exports the response with its request
+func (e *Exporter) InsertAuditResponse(resp *types.AuditResponse) error {
+	e.mu.Lock()
+	req := e.pending[resp.RequestID]
+	delete(e.pending, resp.RequestID)
+	e.mu.Unlock()
+
+	return e.add(call{req: req, resp: resp})
+}
+
+// evictOldest removes the longest pending request; e.mu must be held
+func (e *Exporter) evictOldest() *types.AuditRequest {
+	for len(e.order) > 0 {
+		id := e.order[0]
+		e.order = e.order[1:]
+		if req, ok := e.pending[id]; ok {
+			delete(e.pending, id)
+			return req
+		}
+	}
+	return nil
+}
+
+// add queues a call, sending the batch once it is full
+func (e *Exporter) add(c call) error {
+	e.mu.Lock()
+	e.batch = append(e.batch, c)
+	// Drop ids of answered requests from the front so order stays bounded
+	for len(e.order) > 0 {
+		if _, ok := e.pending[e.order[0]]; ok {
+			break
+		}
+		e.order = e.order[1:]
+	}
+	if len(e.order) > 2*maxPending {
+		// A request that is never answered keeps the front; compact behind it
+		order := make([]string, 0, len(e.pending))
+		for _, id := range e.order {
+			if _, ok := e.pending[id]; ok {
+				order = append(order, id)
+			}
+		}
+		e.order = order
+	}
+	if len(e.batch) < e.cfg.BatchSize {
+		e.mu.Unlock()
+		return nil
+	}
+	batch := e.batch
+	e.batch = nil
+	e.mu.Unlock()
+
+	return e.send(batch)
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.FlushIntervalDuration())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Flush(); err != nil {
+				log.Printf("OTLP export failed: %v", err)
+			}
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// Flush sends the calls collected so far
+func (e *Exporter) Flush() error {
+	e.mu.Lock()
+	batch := e.batch
+	e.batch = nil
+	e.mu.Unlock()
+	return e.send(batch)
+}
+
+// Close stops the flush loop and sends the last batch. Requests still waiting
+// for a response are not exported.
+func (e *Exporter) Close() error {
+	close(e.stop)
+	<-e.done
+	return e.Flush()
+}
+
+// send posts calls as one export request
+func (e *Exporter) send(batch []call) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	res := resource{Attributes: []keyValue{stringAttr("service.name", e.cfg.ServiceName)}}
+	sc := scope{Name: "golf"}
+	var payload interface{}
+	if e.cfg.Signal == config.OTLPLogs {
+		now := time.Now()
+		records := make([]logRecord, len(batch))
+		for i, c := range batch {
+			records[i] = c.logRecord(e.cfg.IncludeBodies, now)
+		}
+		payload = logsRequest{ResourceLogs: []resourceLogs{{
+			Resource:  res,
+			ScopeLogs: []scopeLogs{{Scope: sc, LogRecords: records}},
+		}}}
+	} else {
+		spans := make([]span, len(batch))
+		for i, c := range batch {
+			spans[i] = c.span(e.cfg.IncludeBodies)
+		}
+		payload = tracesRequest{ResourceSpans: []resourceSpans{{
+			Resource:   res,
+			ScopeSpans: []scopeSpans{{Scope: sc, Spans: spans}},
+		}}}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode OTLP %s: %w", e.cfg.Signal, err)
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golf-audit-gateway")
+	for name, value := range e.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %d calls: %w", len(batch), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("collector rejected %d calls with status %d: %s", len(batch), resp.StatusCode, detail)
+	}
+	return nil
+}
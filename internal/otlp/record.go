@@ -0,0 +1,344 @@
+package otlp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// OTLP span kind and status codes, and log severities
+const (
+	spanKindServer  = 2
+	statusCodeError = 2
+
+	severityInfo  = 9
+	severityError = 17
+)
+
+// The OTLP/HTTP JSON encoding of the parts of the protocol the exporter uses.
+// 64-bit integers are strings and trace and span ids hex, as the encoding requires.
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes"`
+	Status            spanStatus `json:"status"`
+}
+
+type spanStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes"`
+	TraceID              string     `json:"traceId"`
+	SpanID               string     `json:"spanId"`
+}
+
+type tracesRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type logsRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+func stringAttr(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int64) keyValue {
+	s := strconv.FormatInt(value, 10)
+	return keyValue{Key: key, Value: anyValue{IntValue: &s}}
+}
+
+func boolAttr(key string, value bool) keyValue {
+	return keyValue{Key: key, Value: anyValue{BoolValue: &value}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// call is an audit request paired with its response. Either may be nil when
+// the other was exported alone.
+type call struct {
+	req  *types.AuditRequest
+	resp *types.AuditResponse
+}
+
+func (c call) requestID() string {
+	if c.req != nil {
+		return c.req.RequestID
+	}
+	return c.resp.RequestID
+}
+
+// bounds returns when the call started and ended
+func (c call) bounds() (time.Time, time.Time) {
+	switch {
+	case c.req != nil && c.resp != nil:
+		return c.req.Timestamp, c.req.Timestamp.Add(time.Duration(c.resp.ProcessTime) * time.Millisecond)
+	case c.req != nil:
+		return c.req.Timestamp, c.req.Timestamp
+	}
+	return c.resp.Timestamp.Add(-time.Duration(c.resp.ProcessTime) * time.Millisecond), c.resp.Timestamp
+}
+
+// failed reports whether the call ended in an error, and its message
+func (c call) failed() (bool, string) {
+	if c.resp == nil {
+		return true, "no response recorded"
+	}
+	r := c.resp
+	if r.StatusCode == 0 || r.StatusCode >= 500 || r.RPCErrorCode != nil || r.FailureKind != "" {
+		message := r.Error
+		if message == "" && r.RPCErrorCode != nil {
+			message = fmt.Sprintf("JSON-RPC error %d", *r.RPCErrorCode)
+		}
+		return true, message
+	}
+	return false, ""
+}
+
+// ids returns the trace, span and parent span ids of the call. A W3C
+// traceparent header sent by the caller places the span in the caller's
+// trace; otherwise calls naming a parent call join the parent's trace.
+func (c call) ids() (traceID, spanID, parentSpanID string) {
+	spanID = hashID(c.requestID())[:16]
+	if c.req == nil {
+		return hashID(c.requestID()), spanID, ""
+	}
+	if trace, parent, ok := traceparent(c.req.Headers); ok {
+		return trace, spanID, parent
+	}
+	if parent := c.req.ParentRequestID; parent != "" {
+		return hashID(parent), spanID, hashID(parent)[:16]
+	}
+	return hashID(c.requestID()), spanID, ""
+}
+
+// hashID derives a 16 byte hex id from a request ID
+func hashID(requestID string) string {
+	sum := sha256.Sum256([]byte(requestID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// traceparent extracts the trace and parent span ids of a W3C traceparent header
+func traceparent(headers json.RawMessage) (string, string, bool) {
+	if len(headers) == 0 {
+		return "", "", false
+	}
+	var values map[string]string
+	if err := json.Unmarshal(headers, &values); err != nil {
+		return "", "", false
+	}
+	for name, value := range values {
+		if !strings.EqualFold(name, "traceparent") {
+			continue
+		}
+		parts := strings.Split(strings.TrimSpace(value), "-")
+		if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+			return "", "", false
+		}
+		if _, err := hex.DecodeString(parts[1] + parts[2]); err != nil {
+			return "", "", false
+		}
+		return strings.ToLower(parts[1]), strings.ToLower(parts[2]), true
+	}
+	return "", "", false
+}
+
+// attributes describes the call with semantic convention names where one
+// exists and golf.* names for the rest
+func (c call) attributes(includeBodies bool) []keyValue {
+	attrs := []keyValue{
+		stringAttr("rpc.system", "jsonrpc"),
+		stringAttr("golf.request_id", c.requestID()),
+	}
+	if req := c.req; req != nil {
+		attrs = append(attrs, stringAttr("rpc.method", req.Method))
+		if req.HTTPMethod != "" {
+			attrs = append(attrs, stringAttr("http.request.method", req.HTTPMethod))
+		}
+		if req.IPAddress != "" {
+			attrs = append(attrs, stringAttr("client.address", req.IPAddress))
+		}
+		if req.UserAgent != "" {
+			attrs = append(attrs, stringAttr("user_agent.original", req.UserAgent))
+		}
+		if req.UpstreamURL != "" {
+			attrs = append(attrs, stringAttr("golf.upstream_url", req.UpstreamURL))
+		}
+		if req.APIKey != "" {
+			attrs = append(attrs, stringAttr("golf.api_key", req.APIKey))
+		}
+		if req.Tenant != "" {
+			attrs = append(attrs, stringAttr("golf.tenant", req.Tenant))
+		}
+		if req.ParentRequestID != "" {
+			attrs = append(attrs, stringAttr("golf.parent_request_id", req.ParentRequestID))
+		}
+		if req.Env != "" {
+			attrs = append(attrs, stringAttr("deployment.environment", req.Env))
+		}
+		if req.Version != "" {
+			attrs = append(attrs, stringAttr("service.version", req.Version))
+		}
+		attrs = append(attrs, intAttr("rpc.request.size", req.RequestBytes))
+		names := make([]string, 0, len(req.Tags))
+		for name := range req.Tags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			attrs = append(attrs, stringAttr("golf.tag."+name, req.Tags[name]))
+		}
+		if includeBodies && len(req.Request) > 0 {
+			attrs = append(attrs, stringAttr("golf.request", string(req.Request)))
+		}
+	}
+	if resp := c.resp; resp != nil {
+		attrs = append(attrs,
+			intAttr("http.response.status_code", int64(resp.StatusCode)),
+			intAttr("rpc.response.size", resp.ResponseBytes),
+			intAttr("golf.process_time_ms", resp.ProcessTime),
+		)
+		if resp.RPCErrorCode != nil {
+			attrs = append(attrs, intAttr("rpc.jsonrpc.error_code", int64(*resp.RPCErrorCode)))
+		}
+		if resp.UpstreamTime > 0 {
+			attrs = append(attrs, intAttr("golf.upstream_time_ms", resp.UpstreamTime))
+		}
+		if resp.QueueTime > 0 {
+			attrs = append(attrs, intAttr("golf.queue_time_ms", resp.QueueTime))
+		}
+		if resp.FailureKind != "" {
+			attrs = append(attrs, stringAttr("golf.failure_kind", resp.FailureKind))
+		}
+		if resp.Cache != "" {
+			attrs = append(attrs, stringAttr("golf.cache", resp.Cache))
+		}
+		if resp.ServedBy != "" {
+			attrs = append(attrs, stringAttr("golf.served_by", resp.ServedBy))
+		}
+		if resp.Slow {
+			attrs = append(attrs, boolAttr("golf.slow", true))
+		}
+		if includeBodies && len(resp.Response) > 0 {
+			attrs = append(attrs, stringAttr("golf.response", string(resp.Response)))
+		}
+	}
+	return attrs
+}
+
+func (c call) name() string {
+	if c.req != nil {
+		return c.req.Method
+	}
+	return "unknown"
+}
+
+// span converts the call to an OTLP span
+func (c call) span(includeBodies bool) span {
+	traceID, spanID, parentSpanID := c.ids()
+	start, end := c.bounds()
+	s := span{
+		TraceID:           traceID,
+		SpanID:            spanID,
+		ParentSpanID:      parentSpanID,
+		Name:              c.name(),
+		Kind:              spanKindServer,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        c.attributes(includeBodies),
+	}
+	if failed, message := c.failed(); failed {
+		s.Status = spanStatus{Code: statusCodeError, Message: message}
+	}
+	return s
+}
+
+// logRecord converts the call to an OTLP log record timestamped when the call started
+func (c call) logRecord(includeBodies bool, observed time.Time) logRecord {
+	traceID, spanID, _ := c.ids()
+	start, _ := c.bounds()
+	body := c.name() + " has no recorded response"
+	if c.resp != nil {
+		body = fmt.Sprintf("%s answered %d in %dms", c.name(), c.resp.StatusCode, c.resp.ProcessTime)
+	}
+	record := logRecord{
+		TimeUnixNano:         unixNano(start),
+		ObservedTimeUnixNano: unixNano(observed),
+		SeverityNumber:       severityInfo,
+		SeverityText:         "INFO",
+		Attributes:           c.attributes(includeBodies),
+		TraceID:              traceID,
+		SpanID:               spanID,
+	}
+	if failed, message := c.failed(); failed {
+		record.SeverityNumber, record.SeverityText = severityError, "ERROR"
+		if message != "" {
+			body += ": " + message
+		}
+	}
+	record.Body = anyValue{StringValue: &body}
+	return record
+}
@@ -0,0 +1,427 @@
+// Package parquet writes flat tables as Apache Parquet files, enough for
+// DuckDB, Spark and pandas to load audit exports without a JSON parse. Column
+// chunks are split into PLAIN-encoded, gzip-compressed data pages of about PageSize.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Column types
+const (
+	Bool      = iota // bool
+	Int32            // int32, int
+	Int64            // int64, int
+	String           // string, []byte; UTF-8 text
+	Timestamp        // time.Time, stored as milliseconds since the epoch in UTC
+	Double           // float64
+)
+
+// Column describes one column of the table
+type Column struct {
+	Name     string
+	Type     int
+	Optional bool // Accepts nil values
+}
+
+// Parquet physical types, converted types, encodings and codecs
+const (
+	physicalBoolean   = 0
+	physicalInt32     = 1
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+)
+
+const magic = "PAR1"
+
+// RowGroupSize is the number of rows buffered before they are written out as a row group
+const RowGroupSize = 50000
+
+// PageSize is the encoded size at which a column chunk starts a new data page,
+// keeping page sizes within the int32 fields of the page header for large bodies
+const PageSize = 1 << 20
+
+// Writer writes rows to a Parquet file. Rows are buffered and written one
+// row group at a time; Close writes the footer, without which the file is
+// unreadable.
+type Writer struct {
+	w       io.Writer
+	offset  int64
+	columns []Column
+	values  [][]interface{} // Buffered values by column
+	rows    int64
+
+	rowGroups []rowGroup
+	err       error
+}
+
+type rowGroup struct {
+	rows   int64
+	size   int64
+	chunks []columnChunk
+}
+
+type columnChunk struct {
+	offset           int64
+	values           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// NewWriter starts a Parquet file with the given columns on w
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	pw := &Writer{w: w, columns: columns, values: make([][]interface{}, len(columns))}
+	pw.write([]byte(magic))
+	return pw
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+}
+
+// Write buffers one row, with a value for every column in order
+func (w *Writer) Write(row ...interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("row has %d values, want %d", len(row), len(w.columns))
+	}
+	for i, value := range row {
+		if value == nil && !w.columns[i].Optional {
+			return fmt.Errorf("column %s is required", w.columns[i].Name)
+		}
+		w.values[i] = append(w.values[i], value)
+	}
+	if len(w.values[0]) >= RowGroupSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered rows as a row group
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.columns) == 0 || len(w.values[0]) == 0 {
+		return nil
+	}
+
+	group := rowGroup{rows: int64(len(w.values[0]))}
+	for i, column := range w.columns {
+		chunk, err := w.writeChunk(column, w.values[i])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", column.Name, err)
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.uncompressedSize
+		w.values[i] = w.values[i][:0]
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.rows += group.rows
+	return w.err
+}
+
+// writeChunk writes the values of one column as data pages of about PageSize
+func (w *Writer) writeChunk(column Column, values []interface{}) (columnChunk, error) {
+	chunk := columnChunk{offset: w.offset, values: int64(len(values))}
+	for start := 0; start < len(values); {
+		end, size := start, 0
+		for end < len(values) && (end == start || size < PageSize) {
+			size += encodedSize(column.Type, values[end])
+			end++
+		}
+
+		uncompressed, compressed, err := w.writePage(column, values[start:end])
+		if err != nil {
+			return columnChunk{}, err
+		}
+		chunk.uncompressedSize += uncompressed
+		chunk.compressedSize += compressed
+		start = end
+	}
+	return chunk, nil
+}
+
+// encodedSize estimates the PLAIN-encoded size of a value, for splitting pages
+func encodedSize(typ int, value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return 4 + len(v)
+	case []byte:
+		return 4 + len(v)
+	}
+	switch typ {
+	case Int32:
+		return 4
+	case Int64, Timestamp, Double:
+		return 8
+	}
+	return 1
+}
+
+// writePage writes values as one data page, returning its size with the header before and after compression
+func (w *Writer) writePage(column Column, values []interface{}) (int64, int64, error) {
+	var page bytes.Buffer
+	if column.Optional {
+		writeDefinitionLevels(&page, values)
+	}
+	if column.Type == Bool {
+		packed, err := packBools(values)
+		if err != nil {
+			return 0, 0, err
+		}
+		page.Write(packed)
+	} else {
+		for _, value := range values {
+			if value == nil {
+				continue
+			}
+			if err := writePlain(&page, column.Type, value); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if page.Len() > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("value of %d bytes does not fit in a page", page.Len())
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(page.Bytes())
+	if err := gz.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	var header thriftWriter
+	header.structBegin()
+	header.i32(1, 0) // DATA_PAGE
+	header.i32(2, int32(page.Len()))
+	header.i32(3, int32(compressed.Len()))
+	header.structField(5)
+	header.i32(1, int32(len(values)))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.structEnd()
+	header.structEnd()
+
+	w.write(header.buf.Bytes())
+	w.write(compressed.Bytes())
+	return int64(header.buf.Len() + page.Len()), int64(header.buf.Len() + compressed.Len()), nil
+}
+
+// writeDefinitionLevels writes 1 for present and 0 for nil values as
+// length-prefixed RLE runs of bit width 1
+func writeDefinitionLevels(page *bytes.Buffer, values []interface{}) {
+	var levels bytes.Buffer
+	var varint [binary.MaxVarintLen64]byte
+	for i := 0; i < len(values); {
+		present := values[i] != nil
+		run := 1
+		for i+run < len(values) && (values[i+run] != nil) == present {
+			run++
+		}
+		n := binary.PutUvarint(varint[:], uint64(run)<<1)
+		levels.Write(varint[:n])
+		if present {
+			levels.WriteByte(1)
+		} else {
+			levels.WriteByte(0)
+		}
+		i += run
+	}
+	binary.Write(page, binary.LittleEndian, uint32(levels.Len()))
+	page.Write(levels.Bytes())
+}
+
+// writePlain appends the PLAIN encoding of one value other than a boolean
+func writePlain(page *bytes.Buffer, typ int, value interface{}) error {
+	switch typ {
+	case Int32:
+		switch v := value.(type) {
+		case int32:
+			binary.Write(page, binary.LittleEndian, v)
+		case int:
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return fmt.Errorf("%d overflows int32", v)
+			}
+			binary.Write(page, binary.LittleEndian, int32(v))
+		default:
+			return fmt.Errorf("want int32, got %T", value)
+		}
+	case Int64:
+		switch v := value.(type) {
+		case int64:
+			binary.Write(page, binary.LittleEndian, v)
+		case int:
+			binary.Write(page, binary.LittleEndian, int64(v))
+		default:
+			return fmt.Errorf("want int64, got %T", value)
+		}
+	case String:
+		var b []byte
+		switch v := value.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		default:
+			return fmt.Errorf("want string, got %T", value)
+		}
+		binary.Write(page, binary.LittleEndian, uint32(len(b)))
+		page.Write(b)
+	case Timestamp:
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("want time.Time, got %T", value)
+		}
+		binary.Write(page, binary.LittleEndian, t.UnixMilli())
+	case Double:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("want float64, got %T", value)
+		}
+		binary.Write(page, binary.LittleEndian, math.Float64bits(v))
+	default:
+		return fmt.Errorf("unknown column type %d", typ)
+	}
+	return nil
+}
+
+// packBools bit-packs the non-nil booleans of values, least significant bit first
+func packBools(values []interface{}) ([]byte, error) {
+	var packed []byte
+	i := 0
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("want bool, got %T", v)
+		}
+		if i%8 == 0 {
+			packed = append(packed, 0)
+		}
+		if b {
+			packed[i/8] |= 1 << (i % 8)
+		}
+		i++
+	}
+	return packed, nil
+}
+
+// Close flushes the buffered rows and writes the footer
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var meta thriftWriter
+	meta.structBegin()
+	meta.i32(1, 1) // Format version
+
+	meta.list(2, thriftStruct, len(w.columns)+1)
+	meta.structBegin()
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(w.columns)))
+	meta.structEnd()
+	for _, column := range w.columns {
+		physical, converted := column.types()
+		meta.structBegin()
+		meta.i32(1, physical)
+		if column.Optional {
+			meta.i32(3, 1) // OPTIONAL
+		} else {
+			meta.i32(3, 0) // REQUIRED
+		}
+		meta.string(4, column.Name)
+		if converted >= 0 {
+			meta.i32(6, converted)
+		}
+		meta.structEnd()
+	}
+
+	meta.i64(3, w.rows)
+
+	meta.list(4, thriftStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		meta.structBegin()
+		meta.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			physical, _ := w.columns[i].types()
+			meta.structBegin()
+			meta.i64(2, chunk.offset)
+			meta.structField(3)
+			meta.i32(1, physical)
+			meta.list(2, thriftI32, 2)
+			meta.varint(encodingPlain)
+			meta.varint(encodingRLE)
+			meta.list(3, thriftBinary, 1)
+			meta.binary(w.columns[i].Name)
+			meta.i32(4, codecGzip)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.uncompressedSize)
+			meta.i64(7, chunk.compressedSize)
+			meta.i64(9, chunk.offset)
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.i64(2, group.size)
+		meta.i64(3, group.rows)
+		meta.structEnd()
+	}
+
+	meta.string(6, "golf audit gateway")
+	meta.structEnd()
+
+	w.write(meta.buf.Bytes())
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.buf.Len()))
+	w.write(length[:])
+	w.write([]byte(magic))
+	return w.err
+}
+
+// types returns the physical and converted type of the column, -1 for none
+func (c Column) types() (int32, int32) {
+	switch c.Type {
+	case Bool:
+		return physicalBoolean, -1
+	case Int32:
+		return physicalInt32, -1
+	case Int64:
+		return physicalInt64, -1
+	case String:
+		return physicalByteArray, convertedUTF8
+	case Timestamp:
+		return physicalInt64, convertedTimestampMillis
+	case Double:
+		return physicalDouble, -1
+	}
+	return physicalByteArray, -1
+}
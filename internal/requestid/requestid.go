@@ -0,0 +1,198 @@
+// Package requestid generates the IDs linking an audit request to its response.
+// Besides the original timestamp format it offers UUIDv4, and the sortable
+// UUIDv7, ULID and snowflake formats downstream systems index by.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Generator returns a new unique request ID on every call. Implementations
+// are safe for concurrent use, and time-ordered ones never go backwards
+// within a process, even when the clock does.
+type Generator interface {
+	NewID() string
+}
+
+// New returns the generator of the configured strategy, the timestamp one when cfg is nil
+func New(cfg *config.RequestIDs) (Generator, error) {
+	if cfg == nil {
+		return Timestamp{}, nil
+	}
+	switch cfg.Strategy {
+	case config.RequestIDTimestamp:
+		return Timestamp{}, nil
+	case config.RequestIDUUIDv4:
+		return UUIDv4{}, nil
+	case config.RequestIDUUIDv7:
+		return &UUIDv7{}, nil
+	case config.RequestIDULID:
+		return &ULID{}, nil
+	case config.RequestIDSnowflake:
+		return NewSnowflake(cfg.Node)
+	}
+	return nil, fmt.Errorf("unknown request ID strategy %q", cfg.Strategy)
+}
+
+// Timestamp generates the original req_<unix nanos>_<n> IDs
+type Timestamp struct{}
+
+func (Timestamp) NewID() string {
+	return types.NewRequestID()
+}
+
+// UUIDv4 generates random UUIDs (RFC 9562 version 4)
+type UUIDv4 struct{}
+
+func (UUIDv4) NewID() string {
+	var b [16]byte
+	randomBytes(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+// UUIDv7 generates time-ordered UUIDs (RFC 9562 version 7). IDs of the same
+// millisecond are ordered by a 12-bit counter in rand_a.
+type UUIDv7 struct {
+	mu      sync.Mutex
+	lastMs  int64
+	counter uint16
+}
+
+func (u *UUIDv7) NewID() string {
+	u.mu.Lock()
+	ms := time.Now().UnixMilli()
+	if ms > u.lastMs {
+		u.lastMs, u.counter = ms, 0
+	} else if u.counter++; u.counter > 0xfff {
+		// Counter exhausted, borrow the next millisecond
+		u.lastMs, u.counter = u.lastMs+1, 0
+	}
+	ms, counter := u.lastMs, u.counter
+	u.mu.Unlock()
+
+	var b [16]byte
+	randomBytes(b[8:])
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	binary.BigEndian.PutUint16(b[6:8], 0x7000|counter)
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b [16]byte) string {
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// crockford is the ULID alphabet, Crockford's base32 without I, L, O and U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates lexicographically sortable identifiers: a 48-bit millisecond
+// timestamp and 80 random bits, incremented for IDs of the same millisecond
+type ULID struct {
+	mu      sync.Mutex
+	lastMs  int64
+	entropy [10]byte
+}
+
+func (u *ULID) NewID() string {
+	u.mu.Lock()
+	ms := time.Now().UnixMilli()
+	if ms > u.lastMs {
+		u.lastMs = ms
+		randomBytes(u.entropy[:])
+	} else if !increment(u.entropy[:]) {
+		// Entropy overflowed, borrow the next millisecond
+		u.lastMs++
+		randomBytes(u.entropy[:])
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint16(b[0:2], uint16(u.lastMs>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(u.lastMs))
+	copy(b[6:], u.entropy[:])
+	u.mu.Unlock()
+
+	// 128 bits as 26 characters of 5 bits, the first holding only 3
+	var s [26]byte
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+// increment adds one to a big-endian number, reporting false on overflow
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SnowflakeEpoch is the start of snowflake timestamps, leaving 69 years of 41-bit milliseconds
+var SnowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates 63-bit integers of 41 bits of milliseconds since
+// SnowflakeEpoch, a 10-bit node number and a 12-bit sequence. Instances of a
+// deployment must use distinct nodes.
+type Snowflake struct {
+	node     int64
+	mu       sync.Mutex
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake returns a snowflake generator for node 0 to config.MaxSnowflakeNode
+func NewSnowflake(node int) (*Snowflake, error) {
+	if node < 0 || node > config.MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node must be between 0 and %d", config.MaxSnowflakeNode)
+	}
+	return &Snowflake{node: int64(node)}, nil
+}
+
+func (s *Snowflake) NewID() string {
+	s.mu.Lock()
+	ms := time.Since(SnowflakeEpoch).Milliseconds()
+	if ms > s.lastMs {
+		s.lastMs, s.sequence = ms, 0
+	} else if s.sequence++; s.sequence > 0xfff {
+		// Sequence exhausted, borrow the next millisecond
+		s.lastMs, s.sequence = s.lastMs+1, 0
+	}
+	id := s.lastMs<<22 | s.node<<12 | s.sequence
+	s.mu.Unlock()
+	return strconv.FormatInt(id, 10)
+}
+
+// randomBytes fills b from the system's secure random source
+func randomBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("requestid: no random source: %v", err))
+	}
+}
@@ -0,0 +1,353 @@
+// Package script runs operator-supplied Lua policies on proxied calls. A
+// script defines a global function on_request(call), called once per call
+// with a table of
+//
+//	call.route    name of the matched route
+//	call.method   JSON-RPC method
+//	call.params   params decoded from JSON, nil when absent
+//	call.headers  request headers by lower-case name, first value only;
+//	              the gateway redacts credentials such as authorization
+//	call.client   {name=, tenant=, role=} of the API key, nil for anonymous calls
+//	call.ip       client IP address
+//
+// It returns nil to let the call through unchanged, or a table with any of
+//
+//	deny    reason the call is rejected for
+//	tags    {name = value} attached to the audit record
+//	method  method sent upstream instead
+//	params  params sent upstream instead
+//
+// Scripts run without the io, os and package libraries and are stopped when
+// they take longer than their time budget. Every call gets fresh globals:
+// the script's top level runs again before on_request, so nothing a script
+// stores in a global, or in the string, table and math libraries, is seen
+// by a later call.
+package script
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// DefaultTimeout is the time budget of one on_request call
+const DefaultTimeout = 50 * time.Millisecond
+
+// entryPoint is the global function scripts define
+const entryPoint = "on_request"
+
+// Script is a compiled script, safe for concurrent use
+type Script struct {
+	path    string
+	proto   *lua.FunctionProto
+	timeout time.Duration
+	states  sync.Pool // *lua.LState with the libraries opened
+}
+
+// Call is what a script sees of a call
+type Call struct {
+	Route   string
+	Method  string
+	Params  interface{} // Decoded with encoding/json
+	Headers http.Header
+	Client  *Client // nil for anonymous calls
+	IP      string
+}
+
+// Client identifies the API key of a call
+type Client struct {
+	Name   string
+	Tenant string
+	Role   string
+}
+
+// Result is a script's decision about a call
+type Result struct {
+	Deny   string            // Reason the call is rejected for, empty to let it through
+	Tags   map[string]string // Added to the audit record
+	Method string            // Sent upstream instead of the call's method, empty keeps it
+	Params json.RawMessage   // Sent upstream instead of the call's params, nil keeps them
+}
+
+// Load compiles the script at path and checks that it defines on_request
+func Load(path string, timeout time.Duration) (*Script, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	chunk, err := parse.Parse(bytes.NewReader(source), path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script %s: %w", path, err)
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile script %s: %w", path, err)
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	s := &Script{path: path, proto: proto, timeout: timeout}
+	L, err := s.newState()
+	if err != nil {
+		return nil, err
+	}
+	s.states.Put(L)
+	return s, nil
+}
+
+// Path returns the file the script was loaded from
+func (s *Script) Path() string {
+	return s.path
+}
+
+// unsafeGlobals are removed from every state. Besides loading code, getfenv
+// and setfenv would reach the state's shared globals from a call's own.
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "getfenv", "setfenv"}
+
+// newState creates an interpreter with the safe libraries and checks that
+// the script defines on_request
+func (s *Script) newState() (*lua.LState, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// Scripts must not read files or load code from elsewhere
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	// Keep getmetatable("") from handing out the shared string library
+	if mt, ok := L.GetMetatable(lua.LString("")).(*lua.LTable); ok {
+		mt.RawSetString("__metatable", lua.LFalse)
+	}
+
+	if _, err := s.load(L); err != nil {
+		L.Close()
+		return nil, err
+	}
+	return L, nil
+}
+
+// load runs the script's top level in fresh globals and returns on_request
+func (s *Script) load(L *lua.LState) (*lua.LFunction, error) {
+	env := freshGlobals(L)
+	chunk := L.NewFunctionFromProto(s.proto)
+	chunk.Env = env
+	L.Push(chunk)
+	if err := L.PCall(0, 0, nil); err != nil {
+		return nil, fmt.Errorf("failed to run script %s: %w", s.path, err)
+	}
+	fn, ok := env.RawGetString(entryPoint).(*lua.LFunction)
+	if !ok {
+		return nil, fmt.Errorf("script %s does not define %s(call)", s.path, entryPoint)
+	}
+	return fn, nil
+}
+
+// freshGlobals returns a globals table for one call: the state's globals with
+// the library tables copied, so a call can change neither for later ones
+func freshGlobals(L *lua.LState) *lua.LTable {
+	env := L.NewTable()
+	L.G.Global.ForEach(func(k, v lua.LValue) {
+		if lib, ok := v.(*lua.LTable); ok && lib != L.G.Global {
+			copied := L.NewTable()
+			lib.ForEach(func(name, fn lua.LValue) { copied.RawSet(name, fn) })
+			v = copied
+		}
+		env.RawSet(k, v)
+	})
+	env.RawSetString("_G", env)
+	return env
+}
+
+// Run calls on_request with the call and returns its decision
+func (s *Script) Run(ctx context.Context, call Call) (*Result, error) {
+	L, _ := s.states.Get().(*lua.LState)
+	if L == nil {
+		var err error
+		if L, err = s.newState(); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	fn, err := s.load(L)
+	if err == nil {
+		err = L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, callTable(L, call))
+	}
+	if err != nil {
+		// A stopped script may have left the state in the middle of anything
+		L.Close()
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%s exceeded its time budget of %v", entryPoint, s.timeout)
+		}
+		if apiErr, ok := err.(*lua.ApiError); ok {
+			// Leave out the stack trace
+			return nil, fmt.Errorf("%s", apiErr.Object)
+		}
+		return nil, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	L.RemoveContext()
+	s.states.Put(L)
+
+	return result(ret)
+}
+
+// callTable builds the table passed to on_request
+func callTable(L *lua.LState, call Call) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("route", lua.LString(call.Route))
+	t.RawSetString("method", lua.LString(call.Method))
+	t.RawSetString("ip", lua.LString(call.IP))
+
+	if call.Params != nil {
+		t.RawSetString("params", toLua(L, call.Params))
+	}
+
+	headers := L.NewTable()
+	for name, values := range call.Headers {
+		if len(values) > 0 {
+			headers.RawSetString(strings.ToLower(name), lua.LString(values[0]))
+		}
+	}
+	t.RawSetString("headers", headers)
+
+	if call.Client != nil {
+		client := L.NewTable()
+		client.RawSetString("name", lua.LString(call.Client.Name))
+		client.RawSetString("tenant", lua.LString(call.Client.Tenant))
+		client.RawSetString("role", lua.LString(call.Client.Role))
+		t.RawSetString("client", client)
+	}
+	return t
+}
+
+// result reads the table returned by on_request
+func result(ret lua.LValue) (*Result, error) {
+	if ret == lua.LNil {
+		return &Result{}, nil
+	}
+	t, ok := ret.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("%s must return a table or nil, got %s", entryPoint, ret.Type())
+	}
+
+	res := &Result{}
+	switch deny := t.RawGetString("deny"); deny {
+	case lua.LNil, lua.LFalse:
+	case lua.LTrue:
+		res.Deny = "denied by script"
+	default:
+		res.Deny = deny.String()
+	}
+	if method := t.RawGetString("method"); method != lua.LNil {
+		s, ok := method.(lua.LString)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("method must be a non-empty string")
+		}
+		res.Method = string(s)
+	}
+	if params := t.RawGetString("params"); params != lua.LNil {
+		data, err := json.Marshal(fromLua(params))
+		if err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+		res.Params = data
+	}
+	if tags := t.RawGetString("tags"); tags != lua.LNil {
+		tagTable, ok := tags.(*lua.LTable)
+		if !ok {
+			return nil, fmt.Errorf("tags must be a table")
+		}
+		res.Tags = make(map[string]string)
+		tagTable.ForEach(func(k, v lua.LValue) {
+			res.Tags[k.String()] = v.String()
+		})
+	}
+	return res, nil
+}
+
+// toLua converts a value decoded from JSON to Lua. JSON null becomes nil,
+// so null object members and array elements are dropped.
+func toLua(L *lua.LState, v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []interface{}:
+		t := L.CreateTable(len(v), 0)
+		for _, item := range v {
+			t.Append(toLua(L, item))
+		}
+		return t
+	case map[string]interface{}:
+		t := L.CreateTable(0, len(v))
+		for key, item := range v {
+			t.RawSetString(key, toLua(L, item))
+		}
+		return t
+	}
+	return lua.LNil
+}
+
+// fromLua converts a Lua value for encoding as JSON. Tables with only the keys
+// 1..n become arrays, other tables objects; an empty table is an empty object.
+func fromLua(v lua.LValue) interface{} {
+	switch v := v.(type) {
+	case lua.LBool:
+		return bool(v)
+	case lua.LNumber:
+		return float64(v)
+	case lua.LString:
+		return string(v)
+	case *lua.LTable:
+		if n := v.Len(); n > 0 && countKeys(v) == n {
+			items := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				items = append(items, fromLua(v.RawGetInt(i)))
+			}
+			return items
+		}
+		object := make(map[string]interface{})
+		v.ForEach(func(k, item lua.LValue) {
+			object[k.String()] = fromLua(item)
+		})
+		return object
+	}
+	return nil
+}
+
+// countKeys returns the number of keys of t
+func countKeys(t *lua.LTable) int {
+	n := 0
+	t.ForEach(func(lua.LValue, lua.LValue) { n++ })
+	return n
+}
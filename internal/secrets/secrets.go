@@ -0,0 +1,244 @@
+// Package secrets resolves secret references such as
+// vault:secret/data/golf#tinybird_token, env:UPSTREAM_TOKEN or
+// file:/run/secrets/token, and keeps their consumers up to date as the
+// secrets rotate: leased secrets are renewed before they expire and the
+// others are read again periodically.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret is a value read from a provider. Secrets with a TTL are leased and
+// expire unless renewed.
+type Secret struct {
+	Value     string
+	LeaseID   string
+	TTL       time.Duration
+	Renewable bool
+}
+
+// Provider reads secrets by the part of a reference after its scheme
+type Provider interface {
+	Read(ctx context.Context, path string) (Secret, error)
+}
+
+// Renewer is a provider whose leases can be extended
+type Renewer interface {
+	Renew(ctx context.Context, leaseID string) (time.Duration, error)
+}
+
+// Maintainer is a provider with credentials of its own to keep alive, such as a login token
+type Maintainer interface {
+	Maintain(ctx context.Context) error
+}
+
+// requestTimeout bounds every provider call
+const requestTimeout = 10 * time.Second
+
+// watch is a reference whose value is pushed to a consumer when it changes
+type watch struct {
+	ref    string
+	secret Secret
+	next   time.Time // When the lease is renewed or the secret read again
+	apply  func(string)
+}
+
+// Manager resolves references through the registered providers and keeps the
+// watched ones fresh in the background
+type Manager struct {
+	refresh   time.Duration
+	providers map[string]Provider
+
+	mu      sync.Mutex
+	watches []*watch
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewManager creates a manager with the env and file providers, reading
+// secrets without a lease again every refresh interval
+func NewManager(refresh time.Duration) *Manager {
+	return &Manager{
+		refresh: refresh,
+		providers: map[string]Provider{
+			"env":  envProvider{},
+			"file": fileProvider{},
+		},
+	}
+}
+
+// Register adds a provider for references starting with scheme:
+func (m *Manager) Register(scheme string, p Provider) {
+	m.providers[scheme] = p
+}
+
+// IsRef reports whether value is a reference to a registered provider rather than a literal secret
+func (m *Manager) IsRef(value string) bool {
+	scheme, _, ok := strings.Cut(value, ":")
+	return ok && m.providers[scheme] != nil
+}
+
+// Resolve reads the secret a reference points to
+func (m *Manager) Resolve(ref string) (Secret, error) {
+	scheme, path, ok := strings.Cut(ref, ":")
+	p := m.providers[scheme]
+	if !ok || p == nil {
+		return Secret{}, fmt.Errorf("secret reference %q has no known provider", ref)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	secret, err := p.Read(ctx, path)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to read secret %s: %w", ref, err)
+	}
+	if secret.Value == "" {
+		return Secret{}, fmt.Errorf("secret %s is empty", ref)
+	}
+	return secret, nil
+}
+
+// Watch resolves a reference, passes its value to apply and calls apply again
+// whenever the value changes while the manager runs
+func (m *Manager) Watch(ref string, apply func(string)) error {
+	secret, err := m.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	apply(secret.Value)
+
+	m.mu.Lock()
+	m.watches = append(m.watches, &watch{ref: ref, secret: secret, next: m.nextCheck(secret), apply: apply})
+	m.mu.Unlock()
+	return nil
+}
+
+// nextCheck schedules leased secrets at two thirds of their TTL and the others after the refresh interval
+func (m *Manager) nextCheck(secret Secret) time.Time {
+	if secret.TTL > 0 {
+		return time.Now().Add(secret.TTL * 2 / 3)
+	}
+	return time.Now().Add(m.refresh)
+}
+
+// Start checks the watched secrets and the providers' own credentials every
+// interval, or every refresh interval when shorter, in the background
+func (m *Manager) Start(interval time.Duration) {
+	if m.refresh < interval {
+		interval = m.refresh
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.Run()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background checks
+func (m *Manager) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+		<-m.done
+	}
+}
+
+// Run keeps the providers logged in, then renews or reads again every watched secret that is due
+func (m *Manager) Run() {
+	for scheme, p := range m.providers {
+		if maintainer, ok := p.(Maintainer); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			if err := maintainer.Maintain(ctx); err != nil {
+				log.Printf("Failed to maintain %s secrets provider: %v", scheme, err)
+			}
+			cancel()
+		}
+	}
+
+	m.mu.Lock()
+	due := make([]*watch, 0, len(m.watches))
+	now := time.Now()
+	for _, w := range m.watches {
+		if !now.Before(w.next) {
+			due = append(due, w)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, w := range due {
+		m.refreshWatch(w)
+	}
+}
+
+// refreshWatch renews the lease of a secret when possible and reads it again otherwise
+func (m *Manager) refreshWatch(w *watch) {
+	scheme, _, _ := strings.Cut(w.ref, ":")
+	if renewer, ok := m.providers[scheme].(Renewer); ok && w.secret.Renewable && w.secret.LeaseID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		ttl, err := renewer.Renew(ctx, w.secret.LeaseID)
+		cancel()
+		if err == nil && ttl > 0 {
+			w.secret.TTL = ttl
+			w.next = m.nextCheck(w.secret)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to renew lease of secret %s, reading it again: %v", w.ref, err)
+		}
+	}
+
+	secret, err := m.Resolve(w.ref)
+	if err != nil {
+		// Keep the current value and retry on the next check
+		log.Printf("Failed to refresh secret: %v", err)
+		return
+	}
+	if secret.Value != w.secret.Value {
+		log.Printf("Secret %s changed, applying the new value", w.ref)
+		w.apply(secret.Value)
+	}
+	w.secret = secret
+	w.next = m.nextCheck(secret)
+}
+
+// envProvider reads secrets from environment variables
+type envProvider struct{}
+
+func (envProvider) Read(_ context.Context, name string) (Secret, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return Secret{}, fmt.Errorf("environment variable %s is not set", name)
+	}
+	return Secret{Value: value}, nil
+}
+
+// fileProvider reads secrets from files, trimming surrounding whitespace, so
+// secrets mounted by Kubernetes or written by a Vault agent are picked up
+// when the file is replaced
+type fileProvider struct{}
+
+func (fileProvider) Read(_ context.Context, path string) (Secret, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Secret{}, err
+	}
+	return Secret{Value: strings.TrimSpace(string(data))}, nil
+}
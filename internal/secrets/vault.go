@@ -0,0 +1,242 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/config"
+)
+
+// Vault reads secrets from HashiCorp Vault over its HTTP API. References are
+// written as vault:<path>#<field>, e.g. vault:secret/data/golf#tinybird_token
+// for a KV v2 secret or vault:database/creds/readonly#password for a dynamic
+// one; the field may be left out when the secret has a single one.
+type Vault struct {
+	cfg    config.Vault
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	ttl       time.Duration // Zero for tokens that never expire
+	renewable bool
+	issued    time.Time
+}
+
+// vaultResponse is the envelope of Vault API responses
+type vaultResponse struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// NewVault logs in to Vault with the configured token or AppRole
+func NewVault(cfg config.Vault) (*Vault, error) {
+	v := &Vault{
+		cfg:    cfg,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if err := v.login(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// login obtains a token, from an AppRole login or from the token file or
+// $VAULT_TOKEN, and looks up how long it lives
+func (v *Vault) login(ctx context.Context) error {
+	if v.cfg.RoleID != "" {
+		secretID, err := os.ReadFile(v.cfg.SecretIDFile)
+		if err != nil {
+			return fmt.Errorf("failed to read AppRole secret ID: %w", err)
+		}
+		body := map[string]string{"role_id": v.cfg.RoleID, "secret_id": strings.TrimSpace(string(secretID))}
+		resp, err := v.do(ctx, http.MethodPost, "auth/"+v.cfg.AuthPath+"/login", "", body)
+		if err != nil {
+			return fmt.Errorf("failed to log in with AppRole: %w", err)
+		}
+		if resp.Auth == nil || resp.Auth.ClientToken == "" {
+			return fmt.Errorf("failed to log in with AppRole: no token in response")
+		}
+		v.setToken(resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable)
+		return nil
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if v.cfg.TokenFile != "" {
+		data, err := os.ReadFile(v.cfg.TokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read Vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	resp, err := v.do(ctx, http.MethodGet, "auth/token/lookup-self", token, nil)
+	if err != nil {
+		return fmt.Errorf("failed to look up Vault token: %w", err)
+	}
+	ttl, _ := resp.Data["ttl"].(float64)
+	renewable, _ := resp.Data["renewable"].(bool)
+	v.setToken(token, int(ttl), renewable)
+	return nil
+}
+
+func (v *Vault) setToken(token string, ttlSeconds int, renewable bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.token = token
+	v.ttl = time.Duration(ttlSeconds) * time.Second
+	v.renewable = renewable
+	v.issued = time.Now()
+}
+
+func (v *Vault) currentToken() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.token
+}
+
+// Maintain renews the token once half of its TTL has passed, logging in again
+// when it cannot be renewed
+func (v *Vault) Maintain(ctx context.Context) error {
+	v.mu.Lock()
+	due := v.ttl > 0 && time.Since(v.issued) >= v.ttl/2
+	renewable := v.renewable
+	v.mu.Unlock()
+	if !due {
+		return nil
+	}
+
+	if renewable {
+		resp, err := v.do(ctx, http.MethodPost, "auth/token/renew-self", v.currentToken(), map[string]string{})
+		if err == nil && resp.Auth != nil {
+			v.setToken(v.currentToken(), resp.Auth.LeaseDuration, resp.Auth.Renewable)
+			return nil
+		}
+		if v.cfg.RoleID == "" {
+			return fmt.Errorf("failed to renew Vault token: %w", err)
+		}
+	}
+	// The token reached its maximum TTL or was issued without renewal; a token
+	// from a file may have been replaced by the agent managing it
+	return v.login(ctx)
+}
+
+// Read fetches a secret at path#field; KV v2 responses are unwrapped
+func (v *Vault) Read(ctx context.Context, ref string) (Secret, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	resp, err := v.do(ctx, http.MethodGet, path, v.currentToken(), nil)
+	if err != nil {
+		return Secret{}, err
+	}
+
+	data := resp.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, kv2 := data["metadata"]; kv2 {
+			data = inner
+		}
+	}
+	if field == "" {
+		if len(data) != 1 {
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return Secret{}, fmt.Errorf("secret %s has fields %s, name one with #<field>", path, strings.Join(keys, ", "))
+		}
+		for k := range data {
+			field = k
+		}
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return Secret{}, fmt.Errorf("secret %s has no field %q", path, field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		encoded, _ := json.Marshal(value)
+		s = string(encoded)
+	}
+	return Secret{
+		Value:     s,
+		LeaseID:   resp.LeaseID,
+		TTL:       time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable: resp.Renewable,
+	}, nil
+}
+
+// Renew extends the lease of a dynamic secret, returning its new TTL
+func (v *Vault) Renew(ctx context.Context, leaseID string) (time.Duration, error) {
+	resp, err := v.do(ctx, http.MethodPut, "sys/leases/renew", v.currentToken(), map[string]string{"lease_id": leaseID})
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.LeaseDuration) * time.Second, nil
+}
+
+// do calls the Vault API at /v1/<path>
+func (v *Vault) do(ctx context.Context, method, path, token string, body interface{}) (*vaultResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(v.cfg.Address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out vaultResponse
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		if len(out.Errors) > 0 {
+			return nil, fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.Join(out.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+	return &out, nil
+}
@@ -0,0 +1,827 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Stats summarizes the audit logs (GET /audit/stats)
+type Stats struct {
+	TotalRequests     int            `json:"total_requests"`
+	TotalResponses    int            `json:"total_responses"`
+	OrphanedRequests  int            `json:"orphaned_requests"`
+	Methods           map[string]int `json:"methods"`
+	StatusCodes       map[string]int `json:"status_codes,omitempty"`
+	RequestsLastHour  int            `json:"requests_last_hour"`
+	ErrorCount        int            `json:"error_count"`
+	ErrorRate         float64        `json:"error_rate"` // Percentage of responses with an error
+	AvgResponseTimeMs float64        `json:"avg_response_time_ms,omitempty"`
+	MalformedUpstream int            `json:"malformed_upstream"`        // Upstream responses that were not valid JSON-RPC
+	RPCErrorCodes     map[string]int `json:"rpc_error_codes,omitempty"` // Upstream JSON-RPC error code distribution
+	SlowRequests      int            `json:"slow_requests"`             // Calls exceeding their slow threshold
+	Notifications     int            `json:"notifications"`             // Calls without an id, sent alone or in batches
+
+	Batches *BatchStats `json:"batches,omitempty"` // JSON-RPC batches, omitted before the first one
+
+	Timing *TimingStats `json:"timing,omitempty"` // Upstream phase averages, omitted before any timed call
+
+	SuppressedBuckets int `json:"suppressed_buckets,omitempty"` // Breakdown entries withheld in stats-only mode
+
+	Concurrency map[string]MethodConcurrency `json:"concurrency,omitempty"` // Calls per method waiting for or holding an upstream slot, kept since the gateway started
+}
+
+// BatchStats describes the JSON-RPC batches clients sent
+type BatchStats struct {
+	Batches          int            `json:"batches"`             // Requests carrying a batch
+	Calls            int            `json:"calls"`               // Calls in those batches
+	AvgSize          float64        `json:"avg_size"`            // Calls per batch
+	MaxSize          int            `json:"max_size"`            // Largest batch
+	Sizes            map[string]int `json:"sizes"`               // Batches by size range, e.g. 2, 3-5, 6-10, 11-50, 51-100, 101+
+	AvgLatencyMs     float64        `json:"avg_latency_ms"`      // Time to answer a whole batch
+	AvgCallLatencyMs float64        `json:"avg_call_latency_ms"` // Time to answer a batch divided by its calls
+}
+
+// MethodConcurrency reports how many calls of a method the gateway has upstream at once
+type MethodConcurrency struct {
+	InFlight     int       `json:"in_flight"`
+	PeakInFlight int       `json:"peak_in_flight"` // Highest in_flight since the gateway started
+	PeakAt       time.Time `json:"peak_at"`        // When peak_in_flight was reached
+	Queued       int       `json:"queued"`         // Waiting for a free slot of a target with max_in_flight
+	PeakQueued   int       `json:"peak_queued"`
+}
+
+// TimingStats averages the phases of timed upstream HTTP exchanges
+type TimingStats struct {
+	Calls          int     `json:"calls"`
+	AvgDNSMs       float64 `json:"avg_dns_ms"`
+	AvgConnectMs   float64 `json:"avg_connect_ms"`
+	AvgTLSMs       float64 `json:"avg_tls_ms"`
+	AvgTTFBMs      float64 `json:"avg_ttfb_ms"`
+	AvgReadMs      float64 `json:"avg_read_ms"`
+	ReusedConnRate float64 `json:"reused_conn_rate"` // Percentage of calls on a kept-alive connection
+}
+
+// AuditLogsResponse is returned by GET /audit/logs
+type AuditLogsResponse struct {
+	Logs   []AuditLog `json:"logs"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+	Count  int        `json:"count"`
+}
+
+// AuditRequestsResponse is returned by GET /audit/requests
+type AuditRequestsResponse struct {
+	Requests []AuditRequest `json:"requests"`
+	Limit    int            `json:"limit"`
+	Offset   int            `json:"offset"`
+	Count    int            `json:"count"`
+}
+
+// AuditResponsesResponse is returned by GET /audit/responses
+type AuditResponsesResponse struct {
+	Responses []AuditResponse `json:"responses"`
+	Limit     int             `json:"limit"`
+	Offset    int             `json:"offset"`
+	Count     int             `json:"count"`
+}
+
+// OrphanedRequestsResponse is returned by GET /audit/orphaned
+type OrphanedRequestsResponse struct {
+	OrphanedRequests []AuditRequest `json:"orphaned_requests"`
+	Limit            int            `json:"limit"`
+	Offset           int            `json:"offset"`
+	Count            int            `json:"count"`
+}
+
+// HealthResponse is returned by GET /health
+type HealthResponse struct {
+	Status    string         `json:"status"` // healthy or degraded
+	Timestamp time.Time      `json:"timestamp"`
+	Version   string         `json:"version"`
+	Storage   *StorageStatus `json:"storage,omitempty"`
+	Targets   []TargetStatus `json:"targets,omitempty"`
+
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
+
+	Pipeline *PipelineStatus `json:"pipeline,omitempty"`
+
+	Durability *DurabilityStatus `json:"durability,omitempty"`
+
+	Ready   bool          `json:"ready"`             // Every startup step succeeded, see /health/ready
+	Startup []StartupStep `json:"startup,omitempty"` // Dependencies checked on startup
+}
+
+// Startup step statuses
+const (
+	StartupPending = "pending" // Not checked yet
+	StartupOK      = "ok"
+	StartupFailed  = "failed" // Last attempt failed, retried with backoff
+)
+
+// StartupStep reports a dependency the gateway waits on before it is ready,
+// e.g. the database or a target
+type StartupStep struct {
+	Name     string     `json:"name"`   // database, or target <url>
+	Status   string     `json:"status"` // One of the Startup* constants
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error,omitempty"` // Error of the last failed attempt
+	DoneAt   *time.Time `json:"done_at,omitempty"`
+}
+
+// DurabilityStatus reports when audit rows reach the disk, see DurabilityStrict
+type DurabilityStatus struct {
+	Mode   string            `json:"mode"`             // Default of routes without their own
+	Routes map[string]string `json:"routes,omitempty"` // Routes with another mode than the default
+
+	StrictWrites   int64 `json:"strict_writes"`   // Requests committed before forwarding
+	StrictFailures int64 `json:"strict_failures"` // Calls refused since their request could not be committed
+	RelaxedQueued  int   `json:"relaxed_queued"`  // Events waiting for the next batch
+	RelaxedBatches int64 `json:"relaxed_batches"` // Batches written
+}
+
+// TargetStatus reports the load of a target with a concurrency limit
+type TargetStatus struct {
+	Route       string `json:"route"`
+	Target      string `json:"target"`
+	InFlight    int    `json:"in_flight"`
+	MaxInFlight int    `json:"max_in_flight"`
+	Queued      int    `json:"queued"`
+	MaxQueue    int    `json:"max_queue"`
+}
+
+// StorageStatus describes the audit write path
+type StorageStatus struct {
+	Degraded      bool       `json:"degraded"`
+	Pending       int        `json:"pending_events"`
+	Dropped       int64      `json:"dropped_events"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// PipelineStatus reports the health of the audit pipeline itself, also exposed on /metrics
+type PipelineStatus struct {
+	Queues        []AuditQueueStatus `json:"queues,omitempty"`
+	Backends      []BackendStatus    `json:"backends"`
+	DroppedEvents int64              `json:"dropped_events"`           // Lost from full queues and the spool
+	DatabaseBytes int64              `json:"database_bytes,omitempty"` // Active SQLite file
+	WALBytes      int64              `json:"wal_bytes,omitempty"`
+}
+
+// AuditQueueStatus reports the backlog of an asynchronous audit sink
+type AuditQueueStatus struct {
+	Name       string `json:"name"`
+	Depth      int    `json:"depth"`
+	Capacity   int    `json:"capacity"`
+	Dropped    int64  `json:"dropped"`    // Discarded while the queue was full
+	Overflowed int64  `json:"overflowed"` // Sent to the dead-letter table while the queue was full
+}
+
+// BackendStatus counts the audit writes to one storage backend since startup
+type BackendStatus struct {
+	Name        string  `json:"name"`
+	Inserts     int64   `json:"inserts"`
+	Failures    int64   `json:"failures"`
+	AvgInsertMs float64 `json:"avg_insert_ms"`
+	LastError   string  `json:"last_error,omitempty"`
+}
+
+// UsageEntry reports consumption against a quota
+type UsageEntry struct {
+	Name         string `json:"name"`
+	Tenant       string `json:"tenant,omitempty"`
+	DailyUsed    int    `json:"daily_used"`
+	DailyLimit   int    `json:"daily_limit,omitempty"`
+	MonthlyUsed  int    `json:"monthly_used"`
+	MonthlyLimit int    `json:"monthly_limit,omitempty"`
+}
+
+// UsageResponse is returned by GET /audit/usage
+type UsageResponse struct {
+	Keys       []UsageEntry `json:"keys"`
+	Tenants    []UsageEntry `json:"tenants"`
+	DayStart   time.Time    `json:"day_start"`
+	MonthStart time.Time    `json:"month_start"`
+}
+
+// AuditLogsSinceResponse is returned by GET /audit/logs/since
+type AuditLogsSinceResponse struct {
+	Logs       []AuditLog `json:"logs"`    // Requests recorded after the cursor
+	Updates    []AuditLog `json:"updates"` // Earlier requests whose response arrived after the cursor
+	NextCursor string     `json:"next_cursor"`
+	HasMore    bool       `json:"has_more"`
+}
+
+// SLOStatus reports compliance and burn rates of a latency objective
+type SLOStatus struct {
+	Name                 string  `json:"name"`
+	Method               string  `json:"method,omitempty"`
+	ThresholdMs          int64   `json:"threshold_ms"`
+	Objective            float64 `json:"objective"`
+	Window               string  `json:"window"`
+	Total                int     `json:"total"`
+	Good                 int     `json:"good"`
+	Compliance           float64 `json:"compliance"`             // 1 when there is no traffic
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"` // Fraction of the window's budget left, negative when exhausted
+	FastBurnRate         float64 `json:"fast_burn_rate"`         // Burn rate over the last hour
+	SlowBurnRate         float64 `json:"slow_burn_rate"`         // Burn rate over the last 6 hours
+	Alert                string  `json:"alert,omitempty"`        // fast_burn or slow_burn while breached
+}
+
+// SLOResponse is returned by GET /audit/slo
+type SLOResponse struct {
+	SLOs        []SLOStatus `json:"slos"`
+	EvaluatedAt time.Time   `json:"evaluated_at"`
+}
+
+// LatencyCount counts completed calls and those within a latency threshold
+type LatencyCount struct {
+	Total int `json:"total"`
+	Good  int `json:"good"`
+}
+
+// AvailabilityCount counts completed calls and those the upstream failed
+type AvailabilityCount struct {
+	Total  int `json:"total"`
+	Failed int `json:"failed"`
+}
+
+// WebhookEvent is the body POSTed to configured webhooks
+type WebhookEvent struct {
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// ImportResponse is returned by POST /audit/import
+type ImportResponse struct {
+	Requests   int      `json:"requests"`   // Requests inserted
+	Responses  int      `json:"responses"`  // Responses inserted
+	Duplicates int      `json:"duplicates"` // Records skipped because their request_id was already stored
+	Failed     int      `json:"failed"`     // Lines that could not be parsed or stored
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// HeatmapResponse is returned by GET /audit/stats/heatmap. Counts[i][j] is the
+// number of responses completed in time bucket i whose latency falls in bucket j.
+type HeatmapResponse struct {
+	Method          string      `json:"method,omitempty"`
+	From            time.Time   `json:"from"`
+	To              time.Time   `json:"to"`
+	IntervalSeconds int64       `json:"interval_seconds"`
+	TimeBuckets     []time.Time `json:"time_buckets"`       // Start of each time bucket
+	LatencyBuckets  []int64     `json:"latency_buckets_ms"` // Inclusive upper bounds; a final bucket holds slower calls
+	Counts          [][]int     `json:"counts"`
+	Total           int         `json:"total"`
+
+	SuppressedCells int `json:"suppressed_cells,omitempty"` // Cells emptied in stats-only mode
+}
+
+// ResponseDuplicates counts how often one method returned identical responses
+type ResponseDuplicates struct {
+	Method            string  `json:"method"`
+	Calls             int     `json:"calls"`              // Successful calls with a response hash
+	DistinctResponses int     `json:"distinct_responses"` // Different response bodies among them
+	DuplicateCalls    int     `json:"duplicate_calls"`    // Calls answered with a body returned before
+	DuplicateRatio    float64 `json:"duplicate_ratio"`    // DuplicateCalls / Calls
+	TopResponseShare  float64 `json:"top_response_share"` // Share of calls answered with the most common body
+	CachedCalls       int     `json:"cached_calls"`       // Calls already answered from the gateway cache
+	Candidate         bool    `json:"candidate"`          // Worth caching: enough calls, mostly duplicates
+}
+
+// ResponseDuplicatesResponse is returned by GET /audit/stats/duplicates
+type ResponseDuplicatesResponse struct {
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	MinCalls int                  `json:"min_calls"` // Fewest calls of a cache candidate
+	MinRatio float64              `json:"min_ratio"` // Lowest duplicate ratio of a cache candidate
+	Methods  []ResponseDuplicates `json:"methods"`   // Most duplicate calls first
+
+	SuppressedBuckets int `json:"suppressed_buckets,omitempty"` // Methods left out in stats-only mode
+}
+
+// ParamValueCount is one of the most frequent values of a param
+type ParamValueCount struct {
+	Value string  `json:"value"` // As JSON, e.g. "alice" or 42; long values are cut short
+	Count int     `json:"count"`
+	Share float64 `json:"share"` // Count / calls passing the param
+}
+
+// ParamStats is the cardinality and most frequent values of one param of a method
+type ParamStats struct {
+	Name      string            `json:"name"`     // Field name, or [0], [1], ... for positional params
+	Calls     int               `json:"calls"`    // Calls passing the param
+	Distinct  int               `json:"distinct"` // Different values among them
+	TopValues []ParamValueCount `json:"top_values"`
+}
+
+// MethodParamStats summarizes the params passed to one method
+type MethodParamStats struct {
+	Method string       `json:"method"`
+	Calls  int          `json:"calls"` // Calls read with params
+	Params []ParamStats `json:"params"`
+}
+
+// ParamStatsResponse is returned by GET /audit/stats/params
+type ParamStatsResponse struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Sampled int                `json:"sampled"` // Calls read, the newest in the window
+	Limit   int                `json:"limit"`   // Most calls read; at the limit older calls are left out
+	Methods []MethodParamStats `json:"methods"` // Most calls first
+
+	SuppressedBuckets int `json:"suppressed_buckets,omitempty"` // Top values left out in stats-only mode
+}
+
+// WindowStats summarizes the responses completed in one time window
+type WindowStats struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Calls     int       `json:"calls"`
+	Errors    int       `json:"errors"`
+	ErrorRate float64   `json:"error_rate"` // Percentage of responses with an error
+	AvgMs     float64   `json:"avg_ms"`
+	P50Ms     float64   `json:"p50_ms"`
+	P95Ms     float64   `json:"p95_ms"`
+	P99Ms     float64   `json:"p99_ms"`
+}
+
+// MethodWindowStats summarizes the responses to one method in a time window
+type MethodWindowStats struct {
+	Calls     int
+	Errors    int
+	ErrorRate float64 // Percentage of responses with an error
+	AvgMs     float64
+	P95Ms     float64
+}
+
+// WindowChange is window B minus window A
+type WindowChange struct {
+	Calls        int      `json:"calls"`
+	CallsPercent *float64 `json:"calls_percent,omitempty"` // Relative change of calls, omitted when window A has none
+	ErrorRate    float64  `json:"error_rate"`              // In percentage points
+	AvgMs        float64  `json:"avg_ms"`
+	P50Ms        float64  `json:"p50_ms"`
+	P95Ms        float64  `json:"p95_ms"`
+	P99Ms        float64  `json:"p99_ms"`
+}
+
+// MethodComparison compares the calls to one method in two time windows
+type MethodComparison struct {
+	Method string `json:"method"`
+	Status string `json:"status,omitempty"` // added when only window B has calls, removed when only window A has
+
+	CallsA      int     `json:"calls_a"`
+	CallsB      int     `json:"calls_b"`
+	ShareA      float64 `json:"share_a"`      // Percentage of the window's calls
+	ShareB      float64 `json:"share_b"`      // Percentage of the window's calls
+	ShareChange float64 `json:"share_change"` // In percentage points
+
+	ErrorRateA      float64 `json:"error_rate_a"`
+	ErrorRateB      float64 `json:"error_rate_b"`
+	ErrorRateChange float64 `json:"error_rate_change"` // In percentage points
+
+	AvgMsA      float64 `json:"avg_ms_a"`
+	AvgMsB      float64 `json:"avg_ms_b"`
+	P95MsA      float64 `json:"p95_ms_a"`
+	P95MsB      float64 `json:"p95_ms_b"`
+	P95MsChange float64 `json:"p95_ms_change"`
+}
+
+// CompareResponse is returned by GET /audit/compare
+type CompareResponse struct {
+	WindowA WindowStats        `json:"window_a"`
+	WindowB WindowStats        `json:"window_b"`
+	Change  WindowChange       `json:"change"`
+	Methods []MethodComparison `json:"methods"` // Largest change in call share first
+
+	SuppressedBuckets int `json:"suppressed_buckets,omitempty"` // Methods left out in stats-only mode
+}
+
+// MaintenanceStatus reports the outcome of the last SQLite maintenance run
+type MaintenanceStatus struct {
+	Runs               int        `json:"runs"`
+	LastRunAt          *time.Time `json:"last_run_at,omitempty"`
+	DurationMs         int64      `json:"duration_ms"`
+	WALFrames          int        `json:"wal_frames"`          // Frames in the WAL before the checkpoint
+	CheckpointedFrames int        `json:"checkpointed_frames"` // Frames copied into the database
+	CheckpointBusy     bool       `json:"checkpoint_busy"`     // Readers or writers prevented a full checkpoint
+	FreedPages         int        `json:"freed_pages"`         // Pages returned to the filesystem by incremental vacuum
+	FreelistPages      int        `json:"freelist_pages"`      // Unused pages left after vacuuming
+	IntegrityOK        *bool      `json:"integrity_ok,omitempty"`
+	IntegrityErrors    []string   `json:"integrity_errors,omitempty"`
+	LastError          string     `json:"last_error,omitempty"`
+}
+
+// Annotation is an operator note attached to an audit entry
+type Annotation struct {
+	Note      string    `json:"note,omitempty"`
+	Tags      []string  `json:"tags,omitempty"` // e.g. incident-123
+	Resolved  bool      `json:"resolved"`
+	Deleted   bool      `json:"deleted"` // Soft-deleted entries are hidden from audit log listings
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AnnotationRequest is the body of PATCH /audit/logs/{request_id}; omitted fields are left unchanged
+type AnnotationRequest struct {
+	Note     *string   `json:"note,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+	Resolved *bool     `json:"resolved,omitempty"`
+	Deleted  *bool     `json:"deleted,omitempty"`
+}
+
+// SeriesPoint aggregates the responses completed in one time bucket
+type SeriesPoint struct {
+	Start  time.Time `json:"start"`
+	Count  int       `json:"count"`
+	Errors int       `json:"errors"`
+	AvgMs  float64   `json:"avg_ms"`
+	P50Ms  float64   `json:"p50_ms"`
+	P95Ms  float64   `json:"p95_ms"`
+	P99Ms  float64   `json:"p99_ms"`
+	MaxMs  float64   `json:"max_ms"`
+}
+
+// AnnotatedLog is an annotated audit entry with the time of its request
+type AnnotatedLog struct {
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Timestamp time.Time `json:"timestamp"`
+	Annotation
+}
+
+// GrafanaRange is the time range of Grafana SimpleJSON queries
+type GrafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// GrafanaSearchRequest is the body of POST /grafana/search
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaQueryRequest is the body of POST /grafana/query
+type GrafanaQueryRequest struct {
+	Range         GrafanaRange `json:"range"`
+	IntervalMs    int64        `json:"intervalMs"`
+	MaxDataPoints int          `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId,omitempty"`
+		Type   string `json:"type,omitempty"` // timeserie (default) or table
+	} `json:"targets"`
+}
+
+// GrafanaSeries is one time series returned by POST /grafana/query; datapoints are [value, unix ms]
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaAnnotationRequest is the body of POST /grafana/annotations
+type GrafanaAnnotationRequest struct {
+	Range      GrafanaRange `json:"range"`
+	Annotation struct {
+		Name  string `json:"name"`
+		Query string `json:"query,omitempty"` // Only annotations with this tag
+	} `json:"annotation"`
+}
+
+// GrafanaAnnotation is one annotation returned by POST /grafana/annotations
+type GrafanaAnnotation struct {
+	Annotation interface{} `json:"annotation"`
+	Time       int64       `json:"time"` // unix ms
+	Title      string      `json:"title"`
+	Text       string      `json:"text,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+}
+
+// MethodParam documents one parameter of a JSON-RPC method
+type MethodParam struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Required    bool            `json:"required,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+}
+
+// MethodDoc is a catalog entry built from the OpenRPC document
+type MethodDoc struct {
+	Name        string        `json:"name"`
+	Summary     string        `json:"summary,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Deprecated  bool          `json:"deprecated,omitempty"`
+	Params      []MethodParam `json:"params"`
+	Calls       int           `json:"calls"` // Audited calls of the method
+}
+
+// MethodCatalogResponse lists the methods documented by the target
+type MethodCatalogResponse struct {
+	Title   string      `json:"title,omitempty"`
+	Version string      `json:"version,omitempty"`
+	Methods []MethodDoc `json:"methods"`
+	Count   int         `json:"count"`
+}
+
+// DatabaseFile is one SQLite file of a rotating audit database
+type DatabaseFile struct {
+	Name       string    `json:"name"`
+	SizeBytes  int64     `json:"size_bytes"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Active     bool      `json:"active,omitempty"` // Receives new audit rows and serves the audit API
+}
+
+// DatabaseFilesResponse is returned by GET /audit/files
+type DatabaseFilesResponse struct {
+	Files []DatabaseFile `json:"files"`
+	Count int            `json:"count"`
+}
+
+// TenantStats summarizes the traffic of one tenant
+type TenantStats struct {
+	Tenant       string         `json:"tenant"`
+	Requests     int            `json:"requests"`
+	Errors       int            `json:"errors"` // Error responses and calls without a response
+	AvgLatencyMs float64        `json:"avg_latency_ms"`
+	LastSeen     time.Time      `json:"last_seen"`
+	Methods      map[string]int `json:"methods"` // Calls per method
+}
+
+// TenantStatsResponse is returned by GET /tenant/stats
+type TenantStatsResponse struct {
+	Scope   string        `json:"scope"` // The caller's tenant, or "all" for the admin view
+	Tenants []TenantStats `json:"tenants"`
+}
+
+// PIIMethod counts the calls of one method flagged for likely PII
+type PIIMethod struct {
+	Method        string         `json:"method"`
+	Calls         int            `json:"calls"`          // Calls with PII in the request or response
+	RequestKinds  map[string]int `json:"request_kinds"`  // Flagged requests per PII kind
+	ResponseKinds map[string]int `json:"response_kinds"` // Flagged responses per PII kind
+	LastSeen      time.Time      `json:"last_seen"`
+}
+
+// PIIReport is returned by GET /audit/pii
+type PIIReport struct {
+	Enabled bool        `json:"enabled"` // Whether the scanner is on; rows recorded while it was off are not flagged
+	Methods []PIIMethod `json:"methods"` // Most flagged calls first
+}
+
+// ExportTable describes the rows of one audit table available for export
+type ExportTable struct {
+	Name  string `json:"name"` // requests or responses
+	MinID int64  `json:"min_id"`
+	MaxID int64  `json:"max_id"`
+	Rows  int64  `json:"rows"`
+}
+
+// ExportChunk is an id range of one table, fetched with GET URL. Chunks are
+// stable, so a job can record the ones it finished and resume with the rest.
+type ExportChunk struct {
+	Table   string `json:"table"`
+	AfterID int64  `json:"after_id"` // Exclusive
+	UntilID int64  `json:"until_id"` // Inclusive
+	URL     string `json:"url"`
+}
+
+// ExportManifest is returned by GET /audit/export/manifest
+type ExportManifest struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	ChunkSize   int64         `json:"chunk_size"` // Ids per chunk; chunks hold fewer rows where ids were deleted
+	Tables      []ExportTable `json:"tables"`
+	Chunks      []ExportChunk `json:"chunks"` // Requests first, so importing chunks in order never sees a response before its request
+}
+
+// StatusWindow is the upstream availability over one window of the status page
+type StatusWindow struct {
+	Window               string  `json:"window"` // 1h, 24h or 7d
+	Total                int     `json:"total"`
+	Failed               int     `json:"failed"`       // 5xx, timeouts, unreachable and malformed upstream responses
+	Availability         float64 `json:"availability"` // 1 when there were no calls
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+}
+
+// StatusIncident is a condition currently affecting the gateway
+type StatusIncident struct {
+	Kind    string `json:"kind"` // upstream_down, elevated_errors, slo_burn, storage_degraded
+	Message string `json:"message"`
+}
+
+// StatusPage is returned by GET /status
+type StatusPage struct {
+	Status        string           `json:"status"` // operational, degraded or outage
+	Timestamp     time.Time        `json:"timestamp"`
+	StartedAt     time.Time        `json:"started_at"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Objective     float64          `json:"objective"`
+	Windows       []StatusWindow   `json:"windows,omitempty"` // Empty without the SQLite store
+	Incidents     []StatusIncident `json:"incidents"`
+}
+
+// CacheInvalidateRequest is the body of POST /admin/cache/invalidate. Empty
+// matchers match everything, so {} flushes the whole cache.
+type CacheInvalidateRequest struct {
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"` // Matches entries whose params contain these fields, e.g. {"userId": 42}
+	Tenant string          `json:"tenant,omitempty"`
+}
+
+// CacheInvalidateResponse reports the result of a cache invalidation
+type CacheInvalidateResponse struct {
+	Invalidated int `json:"invalidated"`
+	Remaining   int `json:"remaining"`
+}
+
+// Log levels of the gateway
+const (
+	LogLevelQuiet = "quiet" // Only errors and lifecycle messages, no access log
+	LogLevelInfo  = "info"  // Access log of every HTTP request
+	LogLevelDebug = "debug" // Also every upstream exchange with its timing
+)
+
+// DebugSettings are the log verbosity and debug capture, changed at runtime through /admin/debug
+type DebugSettings struct {
+	LogLevel string `json:"log_level"`
+	Capture  bool   `json:"capture"` // Capture every call in detail, not only those asking with X-Golf-Debug
+}
+
+// Validate checks the log level
+func (s DebugSettings) Validate() error {
+	switch s.LogLevel {
+	case LogLevelQuiet, LogLevelInfo, LogLevelDebug:
+		return nil
+	}
+	return fmt.Errorf("unknown log level %q, expected quiet, info or debug", s.LogLevel)
+}
+
+// BurstCapture temporarily records matching calls with full bodies and debug
+// timing, started with POST /admin/capture/start
+type BurstCapture struct {
+	ID        string    `json:"id"`
+	Filter    string    `json:"filter,omitempty"` // e.g. method:getUserInfo,api_key:mobile; empty matches every call
+	StartedAt time.Time `json:"started_at"`
+	Until     time.Time `json:"until"`
+	StartedBy string    `json:"started_by"`
+	Matched   int64     `json:"matched"` // Calls captured so far
+}
+
+// BurstCapturesResponse is returned by GET /admin/capture
+type BurstCapturesResponse struct {
+	Captures []BurstCapture `json:"captures"`
+}
+
+// SeenClient summarizes the calls of one client fingerprint
+type SeenClient struct {
+	Fingerprint string         `json:"fingerprint"`
+	IPAddress   string         `json:"ip_address"`
+	UserAgent   string         `json:"user_agent"`
+	APIKey      string         `json:"api_key,omitempty"` // Empty for callers without an API key
+	Tenant      string         `json:"tenant,omitempty"`
+	FirstSeen   time.Time      `json:"first_seen"`
+	LastSeen    time.Time      `json:"last_seen"`
+	Requests    int            `json:"requests"`
+	Methods     map[string]int `json:"methods"` // Request count per method
+}
+
+// SeenClientsResponse is returned by GET /audit/clients
+type SeenClientsResponse struct {
+	Clients []SeenClient `json:"clients"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+	Count   int          `json:"count"`
+}
+
+// DeadLetter is a Tinybird event that could not be delivered
+type DeadLetter struct {
+	ID            int64           `json:"id"`
+	Datasource    string          `json:"datasource"`
+	RequestID     string          `json:"request_id,omitempty"`
+	Event         json.RawMessage `json:"event"`
+	Reason        string          `json:"reason"`
+	StatusCode    int             `json:"status_code,omitempty"` // Tinybird's HTTP status, 0 when it was not reached
+	Attempts      int             `json:"attempts"`
+	CreatedAt     time.Time       `json:"created_at"`
+	LastAttemptAt time.Time       `json:"last_attempt_at"`
+}
+
+// DeadLettersResponse is returned by GET /admin/tinybird/deadletter
+type DeadLettersResponse struct {
+	DeadLetters []DeadLetter   `json:"dead_letters"`
+	Totals      map[string]int `json:"totals"` // Dead letters per datasource
+	Limit       int            `json:"limit"`
+	Offset      int            `json:"offset"`
+	Count       int            `json:"count"`
+}
+
+// AdminAction is a privileged management API call, recorded for accountability
+type AdminAction struct {
+	ID         int64     `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Actor      string    `json:"actor"` // API key name, or admin-token
+	Role       string    `json:"role"`
+	HTTPMethod string    `json:"http_method"`
+	Path       string    `json:"path"`
+	StatusCode int       `json:"status_code"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+}
+
+// AdminActionsResponse is returned by GET /admin/actions
+type AdminActionsResponse struct {
+	Actions []AdminAction `json:"actions"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+	Count   int           `json:"count"`
+}
+
+// AuthSession is returned by GET /auth/me for a user signed in through the identity provider
+type AuthSession struct {
+	User      string    `json:"user"`
+	Role      string    `json:"role"`
+	Groups    []string  `json:"groups,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TraceNode is a call and the nested calls it triggered
+type TraceNode struct {
+	AuditLog
+	Children []*TraceNode `json:"children,omitempty"`
+}
+
+// TraceResponse is returned by GET /audit/trace/{request_id}
+type TraceResponse struct {
+	RequestID string     `json:"request_id"` // Call the trace was requested for
+	Calls     int        `json:"calls"`      // Calls in the tree
+	Depth     int        `json:"depth"`      // Levels below the root
+	Root      *TraceNode `json:"root"`       // Outermost recorded ancestor of the call
+}
+
+// Kinds of schema drift
+const (
+	DriftNewField     = "new_field"     // Member the schema does not declare
+	DriftMissingField = "missing_field" // Required member absent
+	DriftTypeChange   = "type_change"   // Value of a different type than declared
+	DriftInvalidValue = "invalid_value" // Value outside the declared enum
+)
+
+// SchemaDrift is one way upstream results of a method differ from its registered schema
+type SchemaDrift struct {
+	Method          string    `json:"method"`
+	Path            string    `json:"path"` // Member in the response, e.g. result.items[].id
+	Kind            string    `json:"kind"` // One of the Drift* constants
+	Expected        string    `json:"expected,omitempty"`
+	Observed        string    `json:"observed,omitempty"`
+	Count           int64     `json:"count"` // Responses showing this difference
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	SampleRequestID string    `json:"sample_request_id,omitempty"` // Latest call showing it
+}
+
+// SchemaDriftResponse is returned by GET /audit/schema-drift
+type SchemaDriftResponse struct {
+	Methods []string      `json:"methods"` // Methods with a registered schema
+	Since   time.Time     `json:"since"`
+	Drift   []SchemaDrift `json:"drift"` // Newest differences first
+	Count   int           `json:"count"`
+}
+
+// BillingUsage is what one API key consumed in a billing period
+type BillingUsage struct {
+	APIKey        string           `json:"api_key"`
+	Tenant        string           `json:"tenant,omitempty"`
+	Calls         int64            `json:"calls"`
+	RequestBytes  int64            `json:"request_bytes"`
+	ResponseBytes int64            `json:"response_bytes"`
+	ComputeMs     int64            `json:"compute_ms"` // Time the upstream spent answering
+	Methods       map[string]int64 `json:"methods"`    // Calls by method
+}
+
+// InvoiceLine is one priced quantity of an invoice
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+// Invoice charges one API key for a calendar month (UTC)
+type Invoice struct {
+	Period      string        `json:"period"` // YYYY-MM
+	PeriodStart time.Time     `json:"period_start"`
+	PeriodEnd   time.Time     `json:"period_end"`
+	Final       bool          `json:"final"` // The month is over and the invoice will not change
+	Currency    string        `json:"currency"`
+	Usage       BillingUsage  `json:"usage"`
+	Lines       []InvoiceLine `json:"lines"`
+	Total       float64       `json:"total"`
+}
+
+// InvoicesResponse is returned by GET /billing/invoices
+type InvoicesResponse struct {
+	Period   string    `json:"period"`
+	Currency string    `json:"currency"`
+	Invoices []Invoice `json:"invoices"` // By API key name
+	Total    float64   `json:"total"`
+	Count    int       `json:"count"`
+}
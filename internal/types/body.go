@@ -0,0 +1,50 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// BodyEncodingBase64 marks payloads stored as a base64 JSON string
+const BodyEncodingBase64 = "base64"
+
+// IsJSONContentType reports whether a content type carries JSON (or is unspecified)
+func IsJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "text/event-stream"
+}
+
+// isTextContentType reports whether a content type carries human-readable text
+func isTextContentType(contentType string) bool {
+	if contentType == "" || IsJSONContentType(contentType) {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/xml" || mediaType == "application/x-www-form-urlencoded"
+}
+
+// EncodeBody prepares a payload for the audit log. JSON and text bodies are returned
+// unchanged; binary bodies (protobuf, octet streams, invalid UTF-8) are returned as a
+// base64 JSON string together with BodyEncodingBase64 so the bytes can be recovered.
+func EncodeBody(body []byte, contentType string) (json.RawMessage, string) {
+	if len(body) == 0 || json.Valid(body) {
+		return json.RawMessage(body), ""
+	}
+	if isTextContentType(contentType) && utf8.Valid(body) {
+		return json.RawMessage(body), ""
+	}
+
+	encoded, _ := json.Marshal(base64.StdEncoding.EncodeToString(body))
+	return json.RawMessage(encoded), BodyEncodingBase64
+}
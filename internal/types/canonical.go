@@ -0,0 +1,58 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// CanonicalJSON rewrites a JSON document with object keys sorted and
+// insignificant whitespace removed, so logically identical requests compare
+// equal byte for byte. Numbers keep their original literal form.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	// encoding/json sorts map keys; only HTML escaping has to be turned off
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// BodyHash returns the hex SHA-256 of the canonical form of a JSON body, or of
+// the raw bytes for bodies that are not JSON. Empty bodies have no hash.
+func BodyHash(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if canonical, err := CanonicalJSON(body); err == nil {
+		body = canonical
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// ResponseHash returns the BodyHash of a response body without its id and
+// jsonrpc members, so identical answers to calls with different ids compare
+// equal. Batches and bodies that are not JSON objects are hashed whole.
+func ResponseHash(body []byte) string {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		delete(envelope, "id")
+		delete(envelope, "jsonrpc")
+		if stripped, err := json.Marshal(envelope); err == nil {
+			body = stripped
+		}
+	}
+	return BodyHash(body)
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Redaction levels applied to audit records of a client
+const (
+	RedactionNone    = ""        // Store everything except gateway credentials
+	RedactionHeaders = "headers" // Mask all header values
+	RedactionPayload = "payload" // Mask headers, request params and response results
+)
+
+// Management API roles; each may do everything the previous one may
+const (
+	RoleViewer   = "viewer"   // Reads stats, time series and SLOs
+	RoleOperator = "operator" // Also reads, exports and annotates audit logs and clears the response cache
+	RoleAdmin    = "admin"    // Also soft-deletes and imports logs, changes settings and manages clients
+)
+
+// Identity is a user an identity provider signed in
+type Identity struct {
+	User   string   // Name recorded for the user's actions, e.g. an email address
+	Groups []string // Groups mapped to a management role
+}
+
+// Quota limits the number of calls per calendar day and month (0 means unlimited)
+type Quota struct {
+	Daily   int `json:"daily,omitempty"`
+	Monthly int `json:"monthly,omitempty"`
+}
+
+// RateLimit is a token bucket refilled at RequestsPerSecond up to Burst tokens
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst,omitempty"` // Defaults to RequestsPerSecond rounded up
+}
+
+// ClientPolicy holds per-client limits and audit settings
+type ClientPolicy struct {
+	Quota         Quota      `json:"quota,omitempty"`
+	RateLimit     *RateLimit `json:"rate_limit,omitempty"`
+	DeniedMethods []string   `json:"denied_methods,omitempty"` // JSON-RPC methods the client may not call
+	Redaction     string     `json:"redaction,omitempty"`      // One of the Redaction* levels
+
+	AllowDebug bool `json:"allow_debug,omitempty"` // May ask for debug capture with the X-Golf-Debug header
+
+	Role string `json:"role,omitempty"` // Management API role, one of the Role* constants; empty for proxy-only keys
+}
+
+// Validate checks the policy for unknown redaction levels and invalid rate limits
+func (p ClientPolicy) Validate() error {
+	switch p.Redaction {
+	case RedactionNone, "none", RedactionHeaders, RedactionPayload:
+	default:
+		return fmt.Errorf("unknown redaction level %q", p.Redaction)
+	}
+	if p.RateLimit != nil && p.RateLimit.RequestsPerSecond <= 0 {
+		return fmt.Errorf("rate limit must be positive")
+	}
+	if p.Quota.Daily < 0 || p.Quota.Monthly < 0 {
+		return fmt.Errorf("quota must not be negative")
+	}
+	switch p.Role {
+	case "", RoleViewer, RoleOperator, RoleAdmin:
+	default:
+		return fmt.Errorf("unknown role %q, expected viewer, operator or admin", p.Role)
+	}
+	return nil
+}
+
+// Client is an API client onboarded through the admin API and stored in the database
+type Client struct {
+	Name   string `json:"name"`
+	Tenant string `json:"tenant,omitempty"`
+	ClientPolicy
+	KeyHash   string    `json:"-"`          // SHA-256 of the secret key, the key itself is never stored
+	KeyPrefix string    `json:"key_prefix"` // First characters of the key, for identification
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ClientRequest creates or updates a client through the admin API
+type ClientRequest struct {
+	Name   string `json:"name"`
+	Key    string `json:"key,omitempty"` // Generated on create when empty, rotated on update when set
+	Tenant string `json:"tenant,omitempty"`
+	ClientPolicy
+}
+
+// ClientResponse is returned by the admin API; Key is only set when a key was created or rotated
+type ClientResponse struct {
+	Client
+	Key string `json:"key,omitempty"`
+}
+
+// ClientsResponse lists database-managed clients
+type ClientsResponse struct {
+	Clients []Client `json:"clients"`
+	Count   int      `json:"count"`
+}
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Dashboard panel types
+const (
+	PanelSearch = "search" // Latest calls matching audit query parameters
+	PanelStat   = "stat"   // A single number of /audit/stats
+	PanelChart  = "chart"  // A time series of the calls, as served to Grafana
+)
+
+// DashboardStats are the /audit/stats fields a stat panel can show
+var DashboardStats = []string{
+	"total_requests", "total_responses", "orphaned_requests", "requests_last_hour",
+	"error_count", "error_rate", "avg_response_time_ms", "malformed_upstream", "slow_requests",
+}
+
+var dashboardNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// DashboardPanel is one view of a dashboard; which fields apply depends on the type
+type DashboardPanel struct {
+	Title string `json:"title"`
+	Type  string `json:"type"` // One of the Panel* constants
+
+	Query map[string]string `json:"query,omitempty"` // Search: /audit/logs parameters, e.g. {"method": "getUser", "tag.team": "payments"}
+	Limit int               `json:"limit,omitempty"` // Search: rows shown (default 20)
+
+	Stat string `json:"stat,omitempty"` // Stat: one of DashboardStats
+
+	Metric string `json:"metric,omitempty"` // Chart: Grafana target, e.g. latency_p95 or errors:getUser
+	Window string `json:"window,omitempty"` // Chart: time covered up to now, e.g. 6h (default 1h)
+}
+
+// Dashboard is a layout of panels defined by operators and stored in the database
+type Dashboard struct {
+	Name      string           `json:"name"` // Lowercase slug, served at /dashboards/<name>
+	Title     string           `json:"title,omitempty"`
+	Panels    []DashboardPanel `json:"panels"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// Validate checks the name and the panels; chart metrics are checked by the gateway
+func (d Dashboard) Validate() error {
+	if !dashboardNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("name %q must be a lowercase slug of up to 64 letters, digits, '-' or '_'", d.Name)
+	}
+	if len(d.Panels) == 0 {
+		return fmt.Errorf("at least one panel is required")
+	}
+	for i, p := range d.Panels {
+		if p.Title == "" {
+			return fmt.Errorf("panel #%d: title is required", i+1)
+		}
+		switch p.Type {
+		case PanelSearch:
+			if p.Limit < 0 || p.Limit > 1000 {
+				return fmt.Errorf("panel #%d: limit must be between 1 and 1000, or 0 for the default", i+1)
+			}
+		case PanelStat:
+			if !containsStat(p.Stat) {
+				return fmt.Errorf("panel #%d: unknown stat %q", i+1, p.Stat)
+			}
+		case PanelChart:
+			if p.Metric == "" {
+				return fmt.Errorf("panel #%d: metric is required", i+1)
+			}
+			if p.Window != "" {
+				if window, err := time.ParseDuration(p.Window); err != nil || window <= 0 {
+					return fmt.Errorf("panel #%d: invalid window %q", i+1, p.Window)
+				}
+			}
+		default:
+			return fmt.Errorf("panel #%d: unknown type %q, expected search, stat or chart", i+1, p.Type)
+		}
+	}
+	return nil
+}
+
+func containsStat(stat string) bool {
+	for _, s := range DashboardStats {
+		if s == stat {
+			return true
+		}
+	}
+	return false
+}
+
+// DashboardsResponse lists the stored dashboards
+type DashboardsResponse struct {
+	Dashboards []Dashboard `json:"dashboards"`
+	Count      int         `json:"count"`
+}
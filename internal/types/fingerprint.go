@@ -0,0 +1,36 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// volatileHeaders vary between calls of the same client and are left out of
+// the header shape
+var volatileHeaders = map[string]bool{
+	"Content-Length":  true,
+	"X-Request-Id":    true,
+	"Traceparent":     true,
+	"Tracestate":      true,
+	"X-Amzn-Trace-Id": true,
+}
+
+// Fingerprint identifies a calling client by its IP address, user agent,
+// header shape (the sorted names of the headers it sends, not their values)
+// and API key name. It is stable across calls and reveals none of its inputs.
+func Fingerprint(ip, userAgent, apiKey string, header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		name = http.CanonicalHeaderKey(name)
+		if !volatileHeaders[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	sum := sha256.Sum256([]byte(ip + "\n" + userAgent + "\n" + apiKey + "\n" + strings.Join(names, ",")))
+	return hex.EncodeToString(sum[:8])
+}
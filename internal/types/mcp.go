@@ -0,0 +1,79 @@
+package types
+
+import "time"
+
+// MCPSessionHeader carries the session of MCP calls over Streamable HTTP
+const MCPSessionHeader = "Mcp-Session-Id"
+
+// MCPSessionTag is the request tag holding the MCP session a call was made in
+const MCPSessionTag = "mcp.session"
+
+// Lifecycle states of an MCP session
+const (
+	MCPSessionActive  = "active"
+	MCPSessionExpired = "expired" // Idle for longer than the route's session TTL
+	MCPSessionEnded   = "ended"   // Deleted by the client or terminated by the server
+)
+
+// Reasons an MCP session ended
+const (
+	MCPEndDeleted    = "deleted"    // The client sent DELETE with the session
+	MCPEndTerminated = "terminated" // The server answered 404 for the session
+)
+
+// MCPSession is an MCP session the gateway saw start with an initialize call
+type MCPSession struct {
+	ID                  string     `json:"id"`
+	Route               string     `json:"route"`
+	Status              string     `json:"status"`                     // active, expired or ended
+	ProtocolVersion     string     `json:"protocol_version,omitempty"` // Version the server answered with
+	ClientName          string     `json:"client_name,omitempty"`      // clientInfo of the initialize call
+	ClientVersion       string     `json:"client_version,omitempty"`
+	ServerName          string     `json:"server_name,omitempty"` // serverInfo of the initialize result
+	ServerVersion       string     `json:"server_version,omitempty"`
+	APIKey              string     `json:"api_key,omitempty"` // Only this key may use the session
+	Tenant              string     `json:"tenant,omitempty"`
+	IPAddress           string     `json:"ip_address,omitempty"`
+	InitializeRequestID string     `json:"initialize_request_id"`
+	Initialized         bool       `json:"initialized"` // The client sent notifications/initialized
+	Calls               int64      `json:"calls"`       // Calls made in the session after initialize
+	CreatedAt           time.Time  `json:"created_at"`
+	LastSeenAt          time.Time  `json:"last_seen_at"`
+	ExpiresAt           time.Time  `json:"expires_at"` // When the session expires unless used again
+	EndedAt             *time.Time `json:"ended_at,omitempty"`
+	EndReason           string     `json:"end_reason,omitempty"` // deleted or terminated
+}
+
+// StatusAt returns the lifecycle state of the session at t
+func (s *MCPSession) StatusAt(t time.Time) string {
+	switch {
+	case s.EndedAt != nil:
+		return MCPSessionEnded
+	case !t.Before(s.ExpiresAt):
+		return MCPSessionExpired
+	}
+	return MCPSessionActive
+}
+
+// MCPSessionFilter narrows a listing of MCP sessions; empty fields match all
+type MCPSessionFilter struct {
+	Route  string
+	APIKey string
+	Status string // active, expired or ended
+}
+
+// MCPSessionsResponse is returned by GET /audit/mcp/sessions
+type MCPSessionsResponse struct {
+	Sessions []MCPSession `json:"sessions"` // Most recently seen first
+	Limit    int          `json:"limit"`
+	Offset   int          `json:"offset"`
+	Count    int          `json:"count"`
+}
+
+// MCPSessionResponse is returned by GET /audit/mcp/sessions/{id}
+type MCPSessionResponse struct {
+	Session MCPSession `json:"session"`
+	Calls   []AuditLog `json:"calls"` // Calls tagged with the session, newest first
+	Limit   int        `json:"limit"`
+	Offset  int        `json:"offset"`
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// lastRequestNanos is the timestamp of the last ID, kept increasing so calls
+// arriving within the clock's resolution still get distinct IDs
+var lastRequestNanos int64
+
+// NewRequestID returns a unique id linking the audit request and response of a call
+func NewRequestID() string {
+	now := time.Now()
+	nanos := now.UnixNano()
+	for {
+		last := atomic.LoadInt64(&lastRequestNanos)
+		if nanos <= last {
+			nanos = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastRequestNanos, last, nanos) {
+			break
+		}
+		nanos = now.UnixNano()
+	}
+
+	var buf [48]byte
+	id := append(buf[:0], "req_"...)
+	id = strconv.AppendInt(id, nanos, 10)
+	id = append(id, '_')
+	id = strconv.AppendInt(id, now.Unix()%1000, 10)
+	return string(id)
+}
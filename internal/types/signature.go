@@ -0,0 +1,36 @@
+package types
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Headers carrying the response signature and the audit request ID it covers
+const (
+	SignatureHeader = "X-Gateway-Signature"
+	RequestIDHeader = "X-Request-ID"
+)
+
+// signaturePrefix names the algorithm, leaving room for others later
+const signaturePrefix = "sha256="
+
+// ResponseSignature returns the signature header value of a response: an
+// HMAC-SHA256 over the request ID, a newline and the response body
+func ResponseSignature(key []byte, requestID string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(requestID))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyResponseSignature reports whether signature was produced by
+// ResponseSignature with key over requestID and body
+func VerifyResponseSignature(key []byte, requestID string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(ResponseSignature(key, requestID, body)))
+}
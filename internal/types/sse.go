@@ -0,0 +1,37 @@
+package types
+
+import "strings"
+
+// UnwrapSSE removes the SSE wrapper from response data, returning the JSON carried in data: lines
+func UnwrapSSE(data []byte) []byte {
+	dataStr := string(data)
+
+	// Check if it's SSE format (starts with "event:" or "data:")
+	if !strings.HasPrefix(dataStr, "event:") && !strings.HasPrefix(dataStr, "data:") {
+		// Not SSE format, return as-is
+		return data
+	}
+
+	// Split by lines and extract JSON from data: lines
+	lines := strings.Split(dataStr, "\n")
+	var jsonData strings.Builder
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+
+		// Extract data from "data: " lines
+		if strings.HasPrefix(line, "data: ") {
+			jsonContent := strings.TrimPrefix(line, "data: ")
+			jsonData.WriteString(jsonContent)
+		}
+	}
+
+	result := jsonData.String()
+
+	// If no data was found, return original
+	if result == "" {
+		return data
+	}
+
+	return []byte(result)
+}
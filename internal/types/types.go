@@ -30,14 +30,37 @@ type JSONRPCError struct {
 
 // AuditRequest represents a logged request entry
 type AuditRequest struct {
-	ID        int64           `json:"id"`
-	Timestamp time.Time       `json:"timestamp"`
-	Method    string          `json:"method"`
-	RequestID string          `json:"request_id"`
-	IPAddress string          `json:"ip_address"`
-	UserAgent string          `json:"user_agent"`
-	Request   json.RawMessage `json:"request"`
-	Headers   json.RawMessage `json:"headers,omitempty"`
+	ID          int64             `json:"id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Method      string            `json:"method"`
+	RequestID   string            `json:"request_id"`
+	IPAddress   string            `json:"ip_address"`
+	UserAgent   string            `json:"user_agent"`
+	Request     json.RawMessage   `json:"request"`
+	Headers     json.RawMessage   `json:"headers,omitempty"`
+	HTTPMethod  string            `json:"http_method,omitempty"`
+	UpstreamURL string            `json:"upstream_url,omitempty"`
+	APIKey      string            `json:"api_key,omitempty"` // Configured key name, never the secret
+	Tenant      string            `json:"tenant,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+
+	ContentType  string `json:"content_type,omitempty"`
+	BodyEncoding string `json:"body_encoding,omitempty"` // base64 when Request holds a binary body as a string
+
+	UpstreamMethod string `json:"upstream_method,omitempty"` // Method sent upstream when Method was aliased
+	AuditLevel     string `json:"audit_level,omitempty"`     // One of the AuditLevel* constants
+	BodyHash       string `json:"body_hash,omitempty"`       // SHA-256 of the canonical request body, see BodyHash
+	Fingerprint    string `json:"fingerprint,omitempty"`     // Stable identity of the calling client, see Fingerprint
+
+	ParentRequestID string `json:"parent_request_id,omitempty"` // Call that triggered this one, see config.Correlation
+
+	RequestBytes int64 `json:"request_bytes,omitempty"` // Size of the body as received, kept at every audit level
+
+	IndexedHeaders map[string]string `json:"indexed_headers,omitempty"` // Values of the configured indexed_headers, kept at every audit level
+
+	Extensions json.RawMessage `json:"extensions,omitempty"` // Non-standard top-level members of the request, e.g. meta, see config.Extensions
+
+	Deployment
 }
 
 // AuditResponse represents a logged response entry
@@ -49,22 +72,118 @@ type AuditResponse struct {
 	StatusCode  int             `json:"status_code"`
 	ProcessTime int64           `json:"process_time_ms"` // in milliseconds
 	Error       string          `json:"error,omitempty"`
+
+	QueueTime    int64 `json:"queue_time_ms,omitempty"`    // Waiting for a free upstream slot
+	UpstreamTime int64 `json:"upstream_time_ms,omitempty"` // Waiting for the upstream response
+
+	MalformedUpstream bool `json:"malformed_upstream,omitempty"` // Upstream body was not a valid JSON-RPC response
+	RPCErrorCode      *int `json:"rpc_error_code,omitempty"`     // error.code of an upstream JSON-RPC error
+
+	ContentType  string `json:"content_type,omitempty"`
+	BodyEncoding string `json:"body_encoding,omitempty"` // base64 when Response holds a binary body as a string
+
+	FailureKind string `json:"failure_kind,omitempty"` // One of the Failure* constants when the call got no upstream answer
+	ServedBy    string `json:"served_by,omitempty"`    // Target that answered when the call failed over to a route's secondary
+
+	Cache      string `json:"cache,omitempty"`        // One of the Cache* constants for calls to cached methods
+	CacheAgeMs int64  `json:"cache_age_ms,omitempty"` // Age of the cached response served on a hit
+
+	Slow bool `json:"slow,omitempty"` // Exceeded the method's slow threshold, stored as the request tag slow=true
+
+	PII string `json:"pii,omitempty"` // Kinds of likely PII in the body, stored as the request tag pii.response
+
+	ResponseBytes int64 `json:"response_bytes,omitempty"` // Size of the body sent to the client, kept at every audit level
+
+	Debug json.RawMessage `json:"debug,omitempty"` // DebugCapture of calls made with debug capture on
+
+	Timing *UpstreamTiming `json:"timing,omitempty"` // Phases of the upstream HTTP exchange, nil for other calls
+
+	TransformedResponse json.RawMessage `json:"transformed_response,omitempty"` // Body sent to the client after the method's response transform; Response is the upstream body
+
+	ResponseHash string `json:"response_hash,omitempty"` // ResponseHash of the upstream body, recorded at every audit level
+
+	Metadata map[string]string `json:"metadata,omitempty"` // Decisions the gateway and its extensions made about the call, e.g. policy, transform and cache
 }
 
 // AuditLog represents a combined view of request and response for compatibility
 type AuditLog struct {
-	ID          int64           `json:"id"`
-	Timestamp   time.Time       `json:"timestamp"`
-	Method      string          `json:"method"`
-	RequestID   string          `json:"request_id"`
-	IPAddress   string          `json:"ip_address"`
-	UserAgent   string          `json:"user_agent"`
-	Request     json.RawMessage `json:"request"`
-	Response    json.RawMessage `json:"response,omitempty"`
-	StatusCode  int             `json:"status_code"`
-	ProcessTime int64           `json:"process_time_ms"` // in milliseconds
-	Error       string          `json:"error,omitempty"`
-	Headers     json.RawMessage `json:"headers,omitempty"`
+	ID          int64             `json:"id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Method      string            `json:"method"`
+	RequestID   string            `json:"request_id"`
+	IPAddress   string            `json:"ip_address"`
+	UserAgent   string            `json:"user_agent"`
+	Request     json.RawMessage   `json:"request"`
+	Response    json.RawMessage   `json:"response,omitempty"`
+	StatusCode  int               `json:"status_code"`
+	ProcessTime int64             `json:"process_time_ms"` // in milliseconds
+	Error       string            `json:"error,omitempty"`
+	Headers     json.RawMessage   `json:"headers,omitempty"`
+	HTTPMethod  string            `json:"http_method,omitempty"`
+	UpstreamURL string            `json:"upstream_url,omitempty"`
+	APIKey      string            `json:"api_key,omitempty"`
+	Tenant      string            `json:"tenant,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+
+	MalformedUpstream bool  `json:"malformed_upstream,omitempty"`
+	RPCErrorCode      *int  `json:"rpc_error_code,omitempty"`
+	ResponseID        int64 `json:"response_id,omitempty"` // audit_responses row id, 0 while pending
+
+	QueueTime    int64 `json:"queue_time_ms,omitempty"`
+	UpstreamTime int64 `json:"upstream_time_ms,omitempty"`
+
+	ContentType          string `json:"content_type,omitempty"`
+	BodyEncoding         string `json:"body_encoding,omitempty"`
+	UpstreamMethod       string `json:"upstream_method,omitempty"`
+	AuditLevel           string `json:"audit_level,omitempty"`
+	FailureKind          string `json:"failure_kind,omitempty"`
+	ServedBy             string `json:"served_by,omitempty"`
+	Cache                string `json:"cache,omitempty"`
+	CacheAgeMs           int64  `json:"cache_age_ms,omitempty"`
+	BodyHash             string `json:"body_hash,omitempty"`
+	Fingerprint          string `json:"fingerprint,omitempty"`
+	ParentRequestID      string `json:"parent_request_id,omitempty"`
+	ResponseContentType  string `json:"response_content_type,omitempty"`
+	ResponseBodyEncoding string `json:"response_body_encoding,omitempty"`
+
+	RequestBytes  int64 `json:"request_bytes,omitempty"`
+	ResponseBytes int64 `json:"response_bytes,omitempty"`
+
+	Debug  json.RawMessage `json:"debug,omitempty"`
+	Timing *UpstreamTiming `json:"timing,omitempty"`
+
+	TransformedResponse json.RawMessage `json:"transformed_response,omitempty"`
+	ResponseHash        string          `json:"response_hash,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	Extensions json.RawMessage `json:"extensions,omitempty"` // Non-standard top-level members of the request
+
+	Deployment
+
+	Annotation *Annotation `json:"annotation,omitempty"`
+
+	Preview *BodyPreview `json:"preview,omitempty"` // Set instead of the bodies when listed with preview=true
+}
+
+// BodyPreview holds the start of the bodies of an audit log listed with
+// preview=true; GET /audit/logs/{request_id} returns them in full
+type BodyPreview struct {
+	Request           string `json:"request,omitempty"`
+	RequestSize       int    `json:"request_size"` // Size of the stored request body in bytes
+	RequestTruncated  bool   `json:"request_truncated,omitempty"`
+	Response          string `json:"response,omitempty"`
+	ResponseSize      int    `json:"response_size"`
+	ResponseTruncated bool   `json:"response_truncated,omitempty"`
+}
+
+// AuditLogFilter narrows down audit log queries
+type AuditLogFilter struct {
+	Method   string
+	Tenant   string            // Only requests of this tenant
+	BodyHash string            // Only requests with this canonical body hash
+	Tags     map[string]string // Tag name -> exact value
+	Headers  map[string]string // Indexed header name -> exact value
 }
 
 // GatewayMetadata contains additional context for the audit log
@@ -75,3 +194,126 @@ type GatewayMetadata struct {
 	RequestSize  int               `json:"request_size"`
 	ResponseSize int               `json:"response_size"`
 }
+
+// UsageCount holds call counts for the current quota periods
+type UsageCount struct {
+	Daily   int `json:"daily"`
+	Monthly int `json:"monthly"`
+}
+
+// Deployment identifies the gateway instance that recorded a call, so audit
+// data from several gateways can be told apart in one analytics store
+type Deployment struct {
+	Env     string            `json:"env,omitempty"`
+	Service string            `json:"service,omitempty"`
+	Version string            `json:"version,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// Audit levels control how much of a call is recorded
+const (
+	AuditLevelMetadata = "metadata"  // Method, timing, status and errors only
+	AuditLevelHeaders  = "headers"   // Metadata plus request headers
+	AuditLevelFullBody = "full-body" // Headers plus request and response bodies (default)
+)
+
+// Audit durability modes control when the audit rows of a call reach the disk.
+// SQLite runs in WAL mode with synchronous=NORMAL, so ordinary commits survive
+// a crash of the gateway but are only fsynced at checkpoints; strict commits
+// use synchronous=FULL and are fsynced before the call is forwarded.
+const (
+	DurabilityStrict   = "strict"   // The request row is committed and fsynced before the call is forwarded, or the call is refused
+	DurabilityStandard = "standard" // Rows are written before the call proceeds, spooled while the store fails (default)
+	DurabilityRelaxed  = "relaxed"  // Rows are queued and written in batches off the proxy path; queued rows are lost on a crash
+)
+
+// SlowTag is the request tag set on calls exceeding their slow threshold
+const SlowTag = "slow"
+
+// Request tags describing how JSON-RPC calls arrived: the number of calls in a
+// batch, and the number of notifications (calls without an id) a request
+// carried, alone or in a batch
+const (
+	BatchSizeTag     = "batch.size"
+	NotificationsTag = "notifications"
+)
+
+// ExtensionTagPrefix starts the request tags promoted from the indexed
+// members of request extensions, e.g. ext.meta.client
+const ExtensionTagPrefix = "ext."
+
+// Request tags listing the kinds of likely PII found in the request and response bodies, e.g. email,phone
+const (
+	PIIRequestTag  = "pii.request"
+	PIIResponseTag = "pii.response"
+)
+
+// Cache outcomes of calls to cached methods
+const (
+	CacheHit  = "hit"  // Answered from the cache without calling the upstream
+	CacheMiss = "miss" // Forwarded to the upstream; a successful answer was cached
+)
+
+// Failure kinds of upstream calls that produced no response
+const (
+	FailureTimeout         = "timeout"          // The call exceeded its deadline
+	FailureConnection      = "connection"       // The upstream could not be reached or dropped the connection
+	FailureClientCancelled = "client_cancelled" // The client went away before the upstream answered
+	FailureAgedOut         = "aged_out"         // The call was older than its route's max_age and not sent
+	FailureUnresolved      = "unresolved"       // No response was recorded within the orphan grace period, e.g. the gateway stopped mid-call
+)
+
+// DebugCapture is the detail stored on the audit response of calls made with
+// debug capture on. Credential headers are masked as in the audited request.
+type DebugCapture struct {
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`  // As sent upstream, after the gateway's changes
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"` // As received from the upstream
+	Timing          *UpstreamTiming   `json:"timing,omitempty"`
+}
+
+// UpstreamTiming breaks an upstream HTTP exchange down into phases, in milliseconds,
+// telling a slow upstream (ttfb) from a slow network (dns, connect, read).
+// Phases skipped on a reused connection are 0.
+type UpstreamTiming struct {
+	DNSMs      float64 `json:"dns_ms"`
+	ConnectMs  float64 `json:"connect_ms"`
+	TLSMs      float64 `json:"tls_ms"`
+	TTFBMs     float64 `json:"ttfb_ms"` // From writing the request to the first response byte
+	ReadMs     float64 `json:"read_ms"` // From the first response byte to the end of the body
+	ReusedConn bool    `json:"reused_conn"`
+}
+
+// AgedOutErrorData is the data of the JSON-RPC error returned for calls older than their route's max_age
+type AgedOutErrorData struct {
+	MaxAgeMs  int64  `json:"max_age_ms"`
+	AgeMs     int64  `json:"age_ms"`
+	QueueMs   int64  `json:"queue_ms"` // Part of the age spent waiting for a free upstream slot
+	RequestID string `json:"request_id"`
+}
+
+// TimeoutErrorData is the data of the JSON-RPC error returned for calls exceeding their deadline
+type TimeoutErrorData struct {
+	TimeoutMs int64  `json:"timeout_ms"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+	RequestID string `json:"request_id"`
+}
+
+// UpstreamErrorData is the data of the JSON-RPC error wrapping an upstream HTTP error without a JSON-RPC body
+type UpstreamErrorData struct {
+	Status    int    `json:"status"`
+	Body      string `json:"body,omitempty"` // Start of the upstream body
+	RequestID string `json:"request_id"`
+}
+
+// Audit record types used in NDJSON import and export
+const (
+	RecordRequest  = "request"
+	RecordResponse = "response"
+)
+
+// AuditRecord is one line of an NDJSON audit import or export
+type AuditRecord struct {
+	Type     string         `json:"type"` // request or response
+	Request  *AuditRequest  `json:"request,omitempty"`
+	Response *AuditResponse `json:"response,omitempty"`
+}
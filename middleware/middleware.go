@@ -0,0 +1,88 @@
+// Package middleware embeds the gateway's JSON-RPC audit pipeline in other Go HTTP servers.
+//
+// Forward to a remote JSON-RPC server:
+//
+//	store, _ := middleware.OpenSQLite("audit.db")
+//	http.Handle("/rpc", middleware.AuditProxy("http://localhost:9000", store))
+//
+// Or audit an in-process handler:
+//
+//	http.Handle("/rpc", middleware.AuditHandler(rpcHandler, store))
+//
+// Handlers audited in-process, and middleware wrapping either handler, can
+// record their own decisions with the call, stored in the audit metadata:
+//
+//	middleware.AuditContextFrom(r.Context()).Set("authz", "granted")
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/niki4smirn/golf/internal/config"
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/gateway"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// Aliases of the gateway types so that audit stores can be implemented outside this module
+type (
+	AuditDatabase = database.AuditDatabase
+	AuditWriter   = database.AuditWriter
+	AuditRequest  = types.AuditRequest
+	AuditResponse = types.AuditResponse
+	AuditLog      = types.AuditLog
+	Stats         = types.Stats
+	Extraction    = config.Extraction
+	AuditContext  = gateway.AuditContext
+)
+
+// AuditContextFrom returns the audit context of the call being served, nil
+// outside of one; setting values on nil is a no-op
+func AuditContextFrom(ctx context.Context) *AuditContext {
+	return gateway.AuditContextFrom(ctx)
+}
+
+// WithAuditContext attaches an audit context to ctx, so middleware running
+// before AuditProxy or AuditHandler can record metadata for the call
+func WithAuditContext(ctx context.Context) (context.Context, *AuditContext) {
+	return gateway.WithAuditContext(ctx)
+}
+
+// OpenSQLite opens (or creates) the gateway's SQLite audit database
+func OpenSQLite(path string) (AuditDatabase, error) {
+	return database.New(path)
+}
+
+// Option customizes an audit proxy
+type Option func(*gateway.Gateway)
+
+// WithHTTPClient sets the client used to call the target (default 30s timeout)
+func WithHTTPClient(client *http.Client) Option {
+	return func(g *gateway.Gateway) {
+		g.SetHTTPClient(client)
+	}
+}
+
+// WithExtractions promotes request fields into audit tags
+func WithExtractions(extractions ...Extraction) Option {
+	return func(g *gateway.Gateway) {
+		g.SetExtractions(extractions)
+	}
+}
+
+// AuditProxy returns a handler that records every call in store and forwards it to targetURL.
+// The request path is appended to the target URL.
+func AuditProxy(targetURL string, store AuditWriter, opts ...Option) http.Handler {
+	g := gateway.NewProxy(store, targetURL)
+	for _, opt := range opts {
+		opt(g)
+	}
+	return http.HandlerFunc(g.ProxyJSONRPC)
+}
+
+// AuditHandler returns a handler that records every call in store and serves it with next
+func AuditHandler(next http.Handler, store AuditWriter, opts ...Option) http.Handler {
+	opts = append([]Option{WithHTTPClient(&http.Client{Transport: handlerTransport{next}})}, opts...)
+	return AuditProxy("http://in-process", store, opts...)
+}
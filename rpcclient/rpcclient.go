@@ -0,0 +1,396 @@
+// Package rpcclient is a JSON-RPC 2.0 client that can record its own calls in a
+// gateway audit store, so services calling upstreams directly get the same audit
+// trail as traffic going through the proxy.
+//
+//	store, _ := middleware.OpenSQLite("audit.db")
+//	c := rpcclient.New("http://localhost:9000", rpcclient.WithAudit(store))
+//
+//	var balance string
+//	err := c.Call(ctx, "eth_getBalance", []interface{}{addr, "latest"}, &balance)
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/niki4smirn/golf/internal/database"
+	"github.com/niki4smirn/golf/internal/types"
+)
+
+// AuditWriter receives the audit request and response of every call, see middleware.OpenSQLite
+type AuditWriter = database.AuditWriter
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// batchMethod is the audit method of batch calls, which carry several methods
+const batchMethod = "batch"
+
+// Error is a JSON-RPC error object returned by the server
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// HTTPError is returned when the server answers with a non-2xx status and no JSON-RPC response
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Client calls a JSON-RPC 2.0 server over HTTP
+type Client struct {
+	url        string
+	httpClient *http.Client
+	header     http.Header
+	audit      AuditWriter
+	auditLevel string
+	nextID     atomic.Int64
+}
+
+// Option customizes a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the client used for all calls (default 30s timeout)
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader sends a header with every call, e.g. an Authorization token
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.header.Set(name, value)
+	}
+}
+
+// WithAudit records every call in store, like the gateway does for proxied calls
+func WithAudit(store AuditWriter) Option {
+	return func(c *Client) {
+		c.audit = store
+	}
+}
+
+// WithAuditLevel sets how much of each call is recorded, one of the types.AuditLevel*
+// constants: metadata, headers or full-body (default)
+func WithAuditLevel(level string) Option {
+	return func(c *Client) {
+		c.auditLevel = level
+	}
+}
+
+// New creates a client for the JSON-RPC server at url
+func New(url string, opts ...Option) *Client {
+	c := &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		header:     make(http.Header),
+		auditLevel: types.AuditLevelFullBody,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// request is an outgoing JSON-RPC message; notifications have no id
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// response is an incoming JSON-RPC message
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func (c *Client) newRequest(method string, params interface{}, notification bool) request {
+	req := request{JSONRPC: "2.0", Method: method, Params: params}
+	if !notification {
+		id := c.nextID.Add(1)
+		req.ID = &id
+	}
+	return req
+}
+
+// Call invokes method with params and decodes the result into result, which may be
+// nil to discard it. Errors returned by the server are *Error values.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(c.newRequest(method, params, false))
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	respBody, err := c.send(ctx, method, body)
+	if err != nil {
+		return err
+	}
+
+	var resp response
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode result: %w", err)
+		}
+	}
+	return nil
+}
+
+// Notify sends a notification, a call without an id that the server does not answer
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	body, err := json.Marshal(c.newRequest(method, params, true))
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	_, err = c.send(ctx, method, body)
+	return err
+}
+
+// BatchElem is one call of a batch. After Batch returns, Error holds the
+// element's JSON-RPC error, or an error if the server did not answer it.
+type BatchElem struct {
+	Method       string
+	Params       interface{}
+	Result       interface{} // Decoded from the element's result unless nil
+	Notification bool        // Send without an id and expect no answer
+	Error        error
+}
+
+// Batch sends all elements in a single request. The returned error only reports
+// failures of the whole batch; per-call errors are set on the elements.
+func (c *Client) Batch(ctx context.Context, elems []BatchElem) error {
+	if len(elems) == 0 {
+		return nil
+	}
+
+	reqs := make([]request, len(elems))
+	byID := make(map[string]*BatchElem, len(elems))
+	for i := range elems {
+		reqs[i] = c.newRequest(elems[i].Method, elems[i].Params, elems[i].Notification)
+		if reqs[i].ID != nil {
+			byID[fmt.Sprint(*reqs[i].ID)] = &elems[i]
+		}
+	}
+
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	respBody, err := c.send(ctx, batchMethod, body)
+	if err != nil {
+		return err
+	}
+	if len(byID) == 0 {
+		return nil
+	}
+
+	var resps []response
+	if err := json.Unmarshal(respBody, &resps); err != nil {
+		// A single error object answers a batch the server could not parse
+		var single response
+		if json.Unmarshal(respBody, &single) == nil && single.Error != nil {
+			return single.Error
+		}
+		return fmt.Errorf("failed to decode batch response: %w", err)
+	}
+
+	for _, resp := range resps {
+		elem, ok := byID[string(resp.ID)]
+		if !ok {
+			continue
+		}
+		delete(byID, string(resp.ID))
+		switch {
+		case resp.Error != nil:
+			elem.Error = resp.Error
+		case elem.Result != nil && len(resp.Result) > 0:
+			if err := json.Unmarshal(resp.Result, elem.Result); err != nil {
+				elem.Error = fmt.Errorf("failed to decode result: %w", err)
+			}
+		}
+	}
+	for _, elem := range byID {
+		elem.Error = fmt.Errorf("no response for %s in batch", elem.Method)
+	}
+	return nil
+}
+
+// send posts a JSON-RPC body and returns the response body, auditing the exchange
+func (c *Client) send(ctx context.Context, method string, body []byte) ([]byte, error) {
+	startTime := time.Now()
+	requestID := types.NewRequestID()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range c.header {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	c.recordRequest(httpReq, requestID, method, body, startTime)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.recordFailure(requestID, startTime, err)
+		return nil, fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.recordFailure(requestID, startTime, err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	c.recordResponse(resp, requestID, respBody, startTime)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Servers may report JSON-RPC errors with an HTTP error status
+		var rpcResp response
+		if json.Unmarshal(respBody, &rpcResp) == nil && rpcResp.Error != nil {
+			return respBody, nil
+		}
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+	return respBody, nil
+}
+
+// recordRequest stores the audit request of a call, logging failures
+func (c *Client) recordRequest(httpReq *http.Request, requestID, method string, body []byte, startTime time.Time) {
+	if c.audit == nil {
+		return
+	}
+
+	auditRequest := &types.AuditRequest{
+		Timestamp:   startTime,
+		Method:      method,
+		RequestID:   requestID,
+		UserAgent:   httpReq.UserAgent(),
+		HTTPMethod:  httpReq.Method,
+		UpstreamURL: c.url,
+		ContentType: httpReq.Header.Get("Content-Type"),
+		AuditLevel:  c.auditLevel,
+		BodyHash:    types.BodyHash(body),
+
+		RequestBytes: int64(len(body)),
+	}
+
+	if c.auditLevel != types.AuditLevelMetadata {
+		headers := make(map[string]string, len(httpReq.Header))
+		for name, values := range httpReq.Header {
+			if len(values) > 0 {
+				headers[name] = values[0]
+			}
+		}
+		// Credentials are recorded as present without their value
+		for _, name := range []string{"Authorization", "X-Api-Key"} {
+			if _, ok := headers[name]; ok {
+				headers[name] = "[REDACTED]"
+			}
+		}
+		auditRequest.Headers, _ = json.Marshal(headers)
+	}
+	if c.auditLevel == types.AuditLevelFullBody {
+		auditRequest.Request = body
+	}
+
+	if err := c.audit.InsertAuditRequest(auditRequest); err != nil {
+		log.Printf("Failed to audit request %s: %v", requestID, err)
+	}
+}
+
+// recordResponse stores the audit response of an answered call
+func (c *Client) recordResponse(resp *http.Response, requestID string, body []byte, startTime time.Time) {
+	if c.audit == nil {
+		return
+	}
+
+	elapsed := time.Since(startTime).Milliseconds()
+	auditResponse := &types.AuditResponse{
+		RequestID:    requestID,
+		Timestamp:    time.Now(),
+		StatusCode:   resp.StatusCode,
+		ProcessTime:  elapsed,
+		UpstreamTime: elapsed,
+		ContentType:  resp.Header.Get("Content-Type"),
+
+		ResponseBytes: int64(len(body)),
+	}
+	if c.auditLevel == types.AuditLevelFullBody {
+		auditResponse.Response, auditResponse.BodyEncoding = types.EncodeBody(body, auditResponse.ContentType)
+	}
+
+	var rpcResp response
+	if err := json.Unmarshal(body, &rpcResp); err == nil && rpcResp.Error != nil {
+		code := rpcResp.Error.Code
+		auditResponse.RPCErrorCode = &code
+		auditResponse.Error = rpcResp.Error.Message
+	}
+
+	c.insertResponse(auditResponse)
+}
+
+// recordFailure stores the audit response of a call that got no answer
+func (c *Client) recordFailure(requestID string, startTime time.Time, err error) {
+	if c.audit == nil {
+		return
+	}
+
+	failureKind := types.FailureConnection
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		failureKind = types.FailureTimeout
+	}
+
+	c.insertResponse(&types.AuditResponse{
+		RequestID:   requestID,
+		Timestamp:   time.Now(),
+		ProcessTime: time.Since(startTime).Milliseconds(),
+		Error:       err.Error(),
+		FailureKind: failureKind,
+	})
+}
+
+func (c *Client) insertResponse(auditResponse *types.AuditResponse) {
+	if err := c.audit.InsertAuditResponse(auditResponse); err != nil {
+		log.Printf("Failed to audit response %s: %v", auditResponse.RequestID, err)
+	}
+}